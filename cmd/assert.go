@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	assertGolden    string
+	assertSessionID string
+	assertName      string
+	assertIgnore    []string
+	assertThreshold float64
+)
+
+// goldenMismatchError is returned when a session differs from its golden transcript
+type goldenMismatchError struct {
+	session    string
+	mismatches int
+}
+
+func (e *goldenMismatchError) Error() string {
+	return fmt.Sprintf("session %s differs from the golden transcript in %d place(s)", e.session, e.mismatches)
+}
+
+// assertCmd represents the assert command
+var assertCmd = &cobra.Command{
+	Use:   "assert",
+	Short: "Check a session against a golden transcript",
+	Long: `Compare a session with a golden transcript, such as a JSON export of a known good
+agent run, and exit with code 6 if they differ, for regression tests of agents.
+
+Messages are compared in order. Their text is compared with whitespace normalized
+and must be at least --threshold similar (1 requires equal text); actors,
+timestamps, the session ID, name and workspace must be equal unless --ignore leaves
+them out. Session fields the golden transcript leaves empty are not compared.
+
+The session is selected with --session-id (full or unique prefix) or --name, and is
+the golden transcript's own ID otherwise.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if assertGolden == "" {
+			return usageErrorf("--golden is required")
+		}
+		comparison, err := internal.NewGoldenComparison(assertIgnore, assertThreshold)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		golden, err := internal.ReadSessionFile(assertGolden)
+		if err != nil {
+			return &usageError{err: fmt.Errorf("failed to read golden transcript: %w", err)}
+		}
+
+		id := assertSessionID
+		if id == "" && assertName == "" {
+			if golden.ID == "" {
+				return usageErrorf("--session-id or --name is required: %s has no session ID", assertGolden)
+			}
+			id = golden.ID
+		}
+
+		sessions, err := loadStorageSessions()
+		if err != nil {
+			return err
+		}
+		refs := make([]internal.SessionRef, 0, len(sessions))
+		for _, session := range sessions {
+			refs = append(refs, internal.SessionRef{ID: session.ID, Name: session.Metadata.Name})
+		}
+		resolvedID, err := internal.ResolveSession(refs, id, assertName)
+		if err != nil {
+			return err
+		}
+		var session *internal.Session
+		for _, s := range sessions {
+			if s.ID == resolvedID {
+				session = s
+				break
+			}
+		}
+
+		out := commandOutput(cmd)
+		mismatches := comparison.Compare(golden, session)
+		if len(mismatches) == 0 {
+			_, _ = fmt.Fprintf(out, "✅ Session %s matches %s (%d message(s))\n", session.ID, assertGolden, len(session.Messages))
+			return nil
+		}
+		printGoldenMismatches(out, mismatches, comparison.Threshold)
+		return &goldenMismatchError{session: session.ID, mismatches: len(mismatches)}
+	},
+}
+
+// printGoldenMismatches reports each difference from the golden transcript, with long
+// texts shortened
+func printGoldenMismatches(out io.Writer, mismatches []internal.GoldenMismatch, threshold float64) {
+	for _, m := range mismatches {
+		where := "session"
+		if m.Message > 0 {
+			where = fmt.Sprintf("message %d", m.Message)
+		}
+		if m.Field == "text" {
+			_, _ = fmt.Fprintf(out, "❌ %s text: %.2f similar, want at least %.2f\n", where, m.Similarity, threshold)
+		} else {
+			_, _ = fmt.Fprintf(out, "❌ %s %s differs\n", where, m.Field)
+		}
+		_, _ = fmt.Fprintf(out, "   want: %s\n", goldenSnippet(m.Want))
+		_, _ = fmt.Fprintf(out, "   got:  %s\n", goldenSnippet(m.Got))
+	}
+}
+
+// goldenSnippet quotes the first line of a value, cut to 80 characters
+func goldenSnippet(value string) string {
+	line, _, more := strings.Cut(strings.TrimSpace(value), "\n")
+	if runes := []rune(line); len(runes) > 80 {
+		line, more = string(runes[:80]), true
+	}
+	if more {
+		line += "…"
+	}
+	return fmt.Sprintf("%q", line)
+}
+
+func init() {
+	rootCmd.AddCommand(assertCmd)
+	assertCmd.Flags().StringVar(&assertGolden, "golden", "", "Golden transcript: a JSON, YAML or JSONL export of the expected session")
+	assertCmd.Flags().StringVar(&assertSessionID, "session-id", "", "Session to check, by ID or unique ID prefix (default: the golden transcript's ID)")
+	assertCmd.Flags().StringVar(&assertName, "name", "", "Session to check, by name (fuzzy match)")
+	assertCmd.Flags().StringSliceVar(&assertIgnore, "ignore", nil, "Fields to leave out of the comparison: "+strings.Join(internal.GoldenIgnoreFields(), ", "))
+	assertCmd.Flags().Float64Var(&assertThreshold, "threshold", 1, "Least similarity (0-1) of each message's text to the golden one, by words changed; 1 requires equal text")
+}
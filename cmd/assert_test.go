@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestAssertCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+	defer func() {
+		assertGolden, assertSessionID, assertName, assertIgnore, assertThreshold = "", "", "", nil, 1
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("run1", []internal.Message{
+		{Actor: "user", Content: "Add a retry to the HTTP client"},
+		{Actor: "assistant", Content: "I added a retry with exponential backoff to the client."},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	// Record the golden transcript with export
+	out := testutil.CreateTempDir(t)
+	clearExportFlags()
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--format", "json", "--out", out, "--clear-cache"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	golden := filepath.Join(out, "session_run1.json")
+
+	var output bytes.Buffer
+	storagePaths = nil
+	rootCmd.SetArgs([]string{"assert", "--storage", dir, "--golden", golden})
+	rootCmd.SetOut(&output)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("assert against its own export error = %v\n%s", err, output.String())
+	}
+
+	// A reworded answer fails unless the threshold allows it
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(string(data), "with exponential backoff", "with jittered exponential backoff", 1)
+	if err := os.WriteFile(golden, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output.Reset()
+	storagePaths = nil
+	rootCmd.SetArgs([]string{"assert", "--storage", dir, "--golden", golden, "--session-id", "run"})
+	if err := rootCmd.Execute(); exitCode(err) != exitGoldenMismatch {
+		t.Fatalf("assert of a changed answer error = %v, want exit code %d", err, exitGoldenMismatch)
+	}
+	if !strings.Contains(output.String(), "message 2 text: 0.91 similar") {
+		t.Errorf("assert should report the changed message, got:\n%s", output.String())
+	}
+
+	storagePaths = nil
+	rootCmd.SetArgs([]string{"assert", "--storage", dir, "--golden", golden, "--threshold", "0.85", "--ignore", "timestamps,ids"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("assert --threshold 0.85 error = %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"assert", "--storage", dir},
+		{"assert", "--storage", dir, "--golden", golden, "--ignore", "provenance"},
+		{"assert", "--storage", dir, "--golden", golden, "--threshold", "2"},
+	} {
+		storagePaths, assertGolden, assertIgnore, assertThreshold = nil, "", nil, 1
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("%v error = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchRuns       int
+	benchFormat     string
+	benchCPUProfile string
+	benchMemProfile string
+)
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the time and memory each phase of the export pipeline takes",
+	Long: `Run the export pipeline over the storage without writing anything and report, for
+each phase, how many records it worked through, how long it took, records per
+second and the heap allocations it made:
+  • load: reading bubbles, composers, contexts, diffs and workspaces from storage
+  • parse: extracting the text and thinking of every bubble
+  • reconstruct: rebuilding conversations from the bubbles
+  • normalize: turning conversations into deduplicated sessions
+
+--runs repeats the pipeline and averages the results, which evens out disk caches
+and other noise. --cpuprofile and --memprofile write pprof profiles of the runs
+for 'go tool pprof'. The JSON format records the version and platform as well, so
+reports from different releases can be compared.
+
+Examples:
+  cursor-session bench --storage ~/ci/storage --runs 5
+  cursor-session bench --format json > bench-v1.2.json
+  cursor-session bench --cpuprofile cpu.out && go tool pprof -top cpu.out`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if benchFormat != "text" && benchFormat != "json" {
+			return usageErrorf("invalid --format %q (expected text or json)", benchFormat)
+		}
+		if benchRuns < 1 {
+			return usageErrorf("--runs must be at least 1, got %d", benchRuns)
+		}
+
+		backend, paths, cleanup, err := openStorageBackend()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if benchCPUProfile != "" {
+			f, err := os.Create(benchCPUProfile)
+			if err != nil {
+				return fmt.Errorf("failed to create CPU profile: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return fmt.Errorf("failed to start CPU profile: %w", err)
+			}
+		}
+		report, err := internal.RunBenchmark(backend, paths, benchRuns)
+		if benchCPUProfile != "" {
+			pprof.StopCPUProfile()
+		}
+		if err != nil {
+			return err
+		}
+		if benchMemProfile != "" {
+			if err := writeHeapProfile(benchMemProfile); err != nil {
+				return err
+			}
+		}
+		report.Version = version
+
+		if benchFormat == "json" {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode benchmark: %w", err)
+			}
+			_, err = fmt.Fprintln(out, string(data))
+			return err
+		}
+		printBenchmark(out, report)
+		return nil
+	},
+}
+
+// writeHeapProfile writes a pprof heap profile of what is still in use after a collection
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return nil
+}
+
+// printBenchmark prints the phases of a benchmark as a table, followed by their total
+func printBenchmark(out io.Writer, report *internal.BenchmarkReport) {
+	_, _ = fmt.Fprintln(statusOutput(out), sessionHeaderStyle.Render(fmt.Sprintf("⏱️  %d session(s), averaged over %d run(s) (%s, %s)", report.Sessions, report.Runs, report.GoVersion, report.Platform)))
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(w, "Phase\tRows\tTime\tRows/s\tAllocs\tAlloc bytes\t")
+	for _, phase := range append(report.Phases, report.Total) {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%.0f\t%d\t%d\t\n", phase.Name, phase.Rows, phase.Duration.Round(time.Microsecond), phase.RowsPerSecond, phase.Allocs, phase.AllocBytes)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 1, "How many times to run the pipeline; the results are averaged")
+	benchCmd.Flags().StringVar(&benchFormat, "format", "text", "Output format (text, json)")
+	benchCmd.Flags().StringVar(&benchCPUProfile, "cpuprofile", "", "Write a pprof CPU profile of the runs to this file")
+	benchCmd.Flags().StringVar(&benchMemProfile, "memprofile", "", "Write a pprof heap profile taken after the runs to this file")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestBenchCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		benchRuns, benchFormat, benchCPUProfile, benchMemProfile = 1, "text", "", ""
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("bench-session", []internal.Message{
+		{Actor: "user", Content: "Why does the build fail?"},
+		{Actor: "assistant", Content: "The linker flags point at a missing library."},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "session_bench-session.json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		storagePaths, benchRuns, benchFormat, benchCPUProfile, benchMemProfile = nil, 1, "text", "", ""
+		var out bytes.Buffer
+		rootCmd.SetArgs(append([]string{"bench", "--storage", dir}, args...))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	output, err := run("--runs", "2")
+	if err != nil {
+		t.Fatalf("bench error = %v", err)
+	}
+	for _, want := range []string{"averaged over 2 run(s)", "Rows/s", "load", "parse", "reconstruct", "normalize", "total"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("bench output should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	profiles := testutil.CreateTempDir(t)
+	cpu, mem := filepath.Join(profiles, "cpu.out"), filepath.Join(profiles, "mem.out")
+	output, err = run("--format", "json", "--cpuprofile", cpu, "--memprofile", mem)
+	if err != nil {
+		t.Fatalf("bench --format json error = %v", err)
+	}
+	var report internal.BenchmarkReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("bench --format json printed invalid JSON: %v\n%s", err, output)
+	}
+	if report.Sessions != 1 || len(report.Phases) != 4 || report.Version == "" {
+		t.Errorf("report = %+v, want one session over four phases", report)
+	}
+	for _, path := range []string{cpu, mem} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("profile %s was not written: %v", path, err)
+		}
+	}
+
+	if _, err := run("--runs", "0"); exitCode(err) != exitUsage {
+		t.Errorf("bench --runs 0 should be a usage error, got: %v", err)
+	}
+	if _, err := run("--format", "csv"); exitCode(err) != exitUsage {
+		t.Errorf("bench --format csv should be a usage error, got: %v", err)
+	}
+}
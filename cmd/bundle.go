@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOut        string
+	bundleSampleRows int
+)
+
+// bundleMaxDatabases caps the databases whose schema is dumped, since cursor-agent keeps
+// a store.db per session
+const bundleMaxDatabases = 10
+
+// bundleDir is the directory the bundle's files are written under in the archive
+const bundleDir = "cursor-session-bundle"
+
+// bundleFile is a file of the diagnostics bundle
+type bundleFile struct {
+	Name string
+	Data []byte
+}
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Collect sanitized diagnostics into an archive to attach to bug reports",
+	Long: `Collect what is needed to investigate a problem into a single archive:
+  • version.txt: the cursor-session version and platform
+  • paths.txt: the storage paths detected, as 'snoop' shows them
+  • schema/: the schema of each database, as 'inspect' shows it, with a few
+    redacted sample rows
+  • load.json: what loading the sessions read, skipped and warned about
+  • logs.txt: every diagnostic logged while loading, debug included
+
+Sample rows keep the keys and numbers of their JSON but not its text, and the home
+directory is replaced by ~ everywhere. Message text is never included, but review
+the archive before sharing it.
+
+The archive format follows the --out extension: .tar.gz, .tgz or .zip.
+
+Examples:
+  cursor-session bundle                          # Write debug-bundle.tar.gz
+  cursor-session bundle --out bug.zip --sample 1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if !export.IsArchivePath(bundleOut) {
+			return usageErrorf("invalid --out %q (expected a .tar.gz, .tgz or .zip file)", bundleOut)
+		}
+		if bundleSampleRows < 0 {
+			return usageErrorf("--sample must not be negative, got %d", bundleSampleRows)
+		}
+
+		files := collectBundle()
+		dest, err := export.CreateArchive(bundleOut)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			data := []byte(internal.SanitizeHome(string(file.Data)))
+			if err := dest.WriteFile(bundleDir+"/"+file.Name, data); err != nil {
+				_ = dest.Close()
+				return err
+			}
+		}
+		if err := dest.Close(); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(out, successStyle.Render(fmt.Sprintf("📦 Wrote %d file(s) to %s", len(files), bundleOut)))
+		_, _ = fmt.Fprintln(out, "   Review the archive before attaching it to a bug report")
+		return nil
+	},
+}
+
+// collectBundle gathers the files of the diagnostics bundle. Problems met along the way
+// are written into the files rather than returned, so a bundle is produced for the very
+// setups that fail.
+func collectBundle() []bundleFile {
+	// Render styles without colors so the files read well anywhere
+	internal.SetPlainOutput(true)
+	defer internal.SetPlainOutput(plain)
+
+	// Capture the logs, with the content they preview masked
+	var logs bytes.Buffer
+	internal.SetRedactLogs(true)
+	defer internal.SetRedactLogs(false)
+	stopCapture := internal.CaptureLogs(&logs)
+	defer stopCapture()
+
+	files := []bundleFile{{Name: "version.txt", Data: bundleVersionInfo()}}
+
+	var pathsOut bytes.Buffer
+	list, err := resolveStoragePaths()
+	if err != nil {
+		_, _ = fmt.Fprintf(&pathsOut, "❌ Failed to get storage paths: %v\n", err)
+	}
+	for _, paths := range list {
+		displayPathInfo(&pathsOut, paths)
+	}
+	files = append(files, bundleFile{Name: "paths.txt", Data: pathsOut.Bytes()})
+
+	databases := bundleDatabases(list)
+	if len(databases) > bundleMaxDatabases {
+		internal.LogInfo("Dumping the schema of %d of %d database(s)", bundleMaxDatabases, len(databases))
+		databases = databases[:bundleMaxDatabases]
+	}
+	for i, dbPath := range databases {
+		var schema bytes.Buffer
+		if err := dumpBundleSchema(&schema, dbPath); err != nil {
+			_, _ = fmt.Fprintf(&schema, "❌ %v\n", err)
+		}
+		name := fmt.Sprintf("schema/%02d-%s.txt", i+1, filepath.Base(dbPath))
+		files = append(files, bundleFile{Name: name, Data: schema.Bytes()})
+	}
+
+	if len(list) > 0 {
+		files = append(files, bundleFile{Name: "load.json", Data: bundleLoadReport(list)})
+	}
+
+	stopCapture()
+	return append(files, bundleFile{Name: "logs.txt", Data: logs.Bytes()})
+}
+
+// bundleVersionInfo describes the build and the platform
+func bundleVersionInfo() []byte {
+	var b bytes.Buffer
+	_, _ = fmt.Fprintf(&b, "cursor-session %s\n", version)
+	_, _ = fmt.Fprintf(&b, "commit: %s\n", commit)
+	_, _ = fmt.Fprintf(&b, "built: %s\n", date)
+	_, _ = fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+	_, _ = fmt.Fprintf(&b, "platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&b, "read strategy: %s\n", effectiveReadStrategy())
+	return b.Bytes()
+}
+
+// bundleDatabases lists the desktop and cursor-agent databases of the storage locations
+func bundleDatabases(list []internal.StoragePaths) []string {
+	var databases []string
+	for _, paths := range list {
+		if paths.GlobalStorageExists() {
+			databases = append(databases, paths.GetGlobalStorageDBPath())
+		}
+		storeDBs, err := paths.FindAgentStoreDBs()
+		if err != nil {
+			internal.LogWarn("Failed to find agent databases: %v", err)
+		}
+		databases = append(databases, storeDBs...)
+	}
+	return databases
+}
+
+// dumpBundleSchema writes the schema of every table of a database with redacted samples
+func dumpBundleSchema(out io.Writer, dbPath string) error {
+	db, err := internal.OpenDatabase(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database %s: %w", dbPath, err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tables, err := getTables(db)
+	if err != nil {
+		return fmt.Errorf("failed to get tables of %s: %w", dbPath, err)
+	}
+	_, _ = fmt.Fprintf(out, "📋 Database: %s\n", dbPath)
+	_, _ = fmt.Fprintf(out, "📊 Found %d table(s)\n\n", len(tables))
+	for _, tableName := range tables {
+		if err := inspectTable(out, db, tableName, bundleSampleRows, true); err != nil {
+			_, _ = fmt.Fprintf(out, "⚠️  Error inspecting table %s: %v\n", tableName, err)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// bundleLoad is what loading the sessions read, skipped and warned about
+type bundleLoad struct {
+	Error      string                  `json:"error,omitempty"`
+	Parse      internal.ParseStats     `json:"parse"`
+	StoreDBs   []internal.StoreDBStats `json:"store_dbs,omitempty"`
+	Warnings   []internal.Warning      `json:"warnings,omitempty"`
+	ParseRatio float64                 `json:"parse_failure_ratio"`
+}
+
+// bundleLoadReport loads the sessions of the storage, bypassing the cache, and reports
+// what was read
+func bundleLoadReport(list []internal.StoragePaths) []byte {
+	var load bundleLoad
+	if err := loadBundleSessions(list); err != nil {
+		load.Error = err.Error()
+	}
+	load.Parse = internal.GetParseStats()
+	load.ParseRatio = load.Parse.ParseFailureRatio()
+	load.StoreDBs = internal.GetStoreDBStats()
+	load.Warnings = internal.GetWarnings()
+
+	data, err := json.MarshalIndent(load, "", "  ")
+	if err != nil {
+		return []byte(fmt.Sprintf("failed to encode the load report: %v\n", err))
+	}
+	return append(data, '\n')
+}
+
+// loadBundleSessions reconstructs the sessions of the storage, honoring --read-strategy
+func loadBundleSessions(list []internal.StoragePaths) error {
+	if copyStorage(list...) {
+		var cleanup func() error
+		var err error
+		list, cleanup, err = internal.CopyStoragePathsList(list)
+		if err != nil {
+			return fmt.Errorf("failed to copy database files: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				internal.LogWarn("Failed to cleanup temporary files: %v", err)
+			}
+		}()
+	}
+
+	backend, err := internal.NewStorageBackendForPaths(list)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	conversations, err := internal.ReconstructConversations(backend)
+	if err != nil {
+		return err
+	}
+	internal.LogInfo("Reconstructed %d conversation(s)", len(conversations))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.Flags().StringVar(&bundleOut, "out", "debug-bundle.tar.gz", "Archive to write (.tar.gz, .tgz or .zip)")
+	bundleCmd.Flags().IntVar(&bundleSampleRows, "sample", 2, "Number of redacted sample rows to include per table")
+}
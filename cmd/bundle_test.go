@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// readTarGz returns the files of a .tar.gz archive by name
+func readTarGz(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Archive is not gzipped: %v", err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+}
+
+func TestBundleCommand(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	defer func() {
+		storagePaths = nil
+		bundleOut = "debug-bundle.tar.gz"
+		bundleSampleRows = 2
+	}()
+
+	chats := filepath.Join(home, ".cursor", "chats")
+	dbPath := filepath.Join(chats, "workspace-hash", "agent-session", "store.db")
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatalf("Failed to create session directory: %v", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE blobs (id TEXT PRIMARY KEY, data BLOB)`,
+		`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`,
+		`INSERT INTO blobs VALUES ('m1', '{"id":"m1","role":"user","content":"my password is hunter2"}')`,
+		`INSERT INTO blobs VALUES ('bad', 'not json')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to build fixture: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	storagePaths = nil
+	out := filepath.Join(testutil.CreateTempDir(t), "bundle.tar.gz")
+	rootCmd.SetArgs([]string{"bundle", "--storage", chats, "--out", out})
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("bundle error = %v", err)
+	}
+	if !strings.Contains(buf.String(), out) {
+		t.Errorf("bundle output = %q, want the archive path", buf.String())
+	}
+
+	files := readTarGz(t, out)
+	for _, name := range []string{"version.txt", "paths.txt", "schema/01-store.db.txt", "load.json", "logs.txt"} {
+		if _, ok := files["cursor-session-bundle/"+name]; !ok {
+			t.Errorf("bundle is missing %s (has %v)", name, files)
+		}
+	}
+
+	schema := files["cursor-session-bundle/schema/01-store.db.txt"]
+	for _, want := range []string{"Table: blobs", "data: BLOB", `"content":"<22 chars>"`, "<redacted 8 bytes>"} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("schema dump is missing %q:\n%s", want, schema)
+		}
+	}
+	if !strings.Contains(files["cursor-session-bundle/load.json"], `"blob_parse"`) {
+		t.Errorf("load.json should report the unreadable blob:\n%s", files["cursor-session-bundle/load.json"])
+	}
+	for name, content := range files {
+		if strings.Contains(content, "hunter2") {
+			t.Errorf("%s leaks message text", name)
+		}
+		if strings.Contains(content, home) {
+			t.Errorf("%s contains the home directory", name)
+		}
+	}
+}
+
+func TestBundleCommand_InvalidFlags(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		bundleOut = "debug-bundle.tar.gz"
+		bundleSampleRows = 2
+	}()
+
+	for _, args := range [][]string{
+		{"bundle", "--out", "bundle.txt"},
+		{"bundle", "--sample", "-1"},
+	} {
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if code := exitCode(rootCmd.Execute()); code != exitUsage {
+			t.Errorf("%v exit code = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
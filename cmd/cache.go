@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheConcurrency int
+	cacheForce       bool
+)
+
+// cacheCmd groups the commands managing the session cache
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the session cache",
+	Long: `Manage the cache of reconstructed sessions that list, show and export read
+when it is up to date. 'cursor-session paths cache' prints where it is kept.`,
+}
+
+// cacheBuildCmd represents the cache build command
+var cacheBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Reconstruct every session into the cache",
+	Long: `Reconstruct every session of the storage location and write the cache, without
+listing or exporting anything, so that later commands read the cache instead of
+the databases. Run it from a cron job to keep interactive commands fast:
+
+  */15 * * * * cursor-session cache build
+
+Nothing is rebuilt when the cache is already up to date, unless --force is given.
+--concurrency sets how many cursor-agent store.db files are parsed at once (default
+one per CPU); lower it to keep a background job from competing with the editor.
+Sessions combined from several --storage locations are never cached, so only one
+location can be given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if cacheConcurrency < 0 {
+			return usageErrorf("--concurrency must not be negative, got %d", cacheConcurrency)
+		}
+		if len(storagePaths) > 1 {
+			return usageErrorf("cache build reads one storage location, got %d; sessions combined from several are not cached", len(storagePaths))
+		}
+		internal.SetParseConcurrency(cacheConcurrency)
+		defer internal.SetParseConcurrency(0)
+
+		paths, err := resolveStoragePaths()
+		if err != nil {
+			return fmt.Errorf("failed to get storage paths: %w", err)
+		}
+		if len(paths) > 1 {
+			return usageErrorf("cache build reads one storage location, got %d with --all-builds; sessions combined from several are not cached", len(paths))
+		}
+		cacheKey := storageCacheKey(paths)
+		if cacheKey == "" {
+			return usageErrorf("sessions read from %s are not cached", paths[0].Location)
+		}
+
+		cacheDir, err := cacheDirectory()
+		if err != nil {
+			return err
+		}
+		cacheManager := internal.NewCacheManager(cacheDir)
+		if !cacheForce {
+			if valid, err := cacheManager.IsCacheValid(cacheKey); err == nil && valid {
+				if index, err := cacheManager.LoadIndex(); err == nil {
+					_, _ = fmt.Fprintf(out, "✅ Cache is up to date: %d session(s) from %s\n", len(index.Sessions), cacheKey)
+					return nil
+				}
+			}
+		}
+
+		backend, paths, cleanup, err := openStorageBackend()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		started := time.Now()
+		conversations, err := internal.ReconstructConversations(backend)
+		if err != nil {
+			return err
+		}
+		sessions := internal.NormalizeSessions(conversations, backend, paths, "")
+		if err := cacheManager.SaveSessions(sessions, cacheKey); err != nil {
+			return fmt.Errorf("failed to save cache: %w", err)
+		}
+
+		elapsed := time.Since(started).Round(100 * time.Millisecond)
+		_, _ = fmt.Fprintf(out, "✅ Cached %d session(s) from %s in %s\n", len(sessions), cacheKey, elapsed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheBuildCmd)
+	cacheBuildCmd.Flags().IntVar(&cacheConcurrency, "concurrency", 0, "How many cursor-agent store.db files to parse at once (0 for one per CPU)")
+	cacheBuildCmd.Flags().BoolVar(&cacheForce, "force", false, "Rebuild the cache even when it is up to date")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestCacheBuildCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		cacheConcurrency = 0
+		cacheForce = false
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"first-session", "second-session"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		storagePaths, cacheConcurrency, cacheForce = nil, 0, false
+		var out bytes.Buffer
+		rootCmd.SetArgs(append([]string{"cache", "build"}, args...))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	if out, err := run("--storage", dir, "--concurrency", "2"); err != nil || !strings.Contains(out, "Cached 2 session(s) from "+dir) {
+		t.Fatalf("cache build = %q, %v, want both sessions cached", out, err)
+	}
+	cacheManager := internal.NewCacheManager(testCacheDir(t))
+	if valid, _ := cacheManager.IsCacheValid(dir); !valid {
+		t.Error("cache build did not leave a valid cache")
+	}
+	if internal.ParseConcurrency() != runtime.NumCPU() {
+		t.Error("--concurrency was not reset after the command")
+	}
+
+	if out, err := run("--storage", dir); err != nil || !strings.Contains(out, "Cache is up to date: 2 session(s)") {
+		t.Errorf("cache build of an up to date cache = %q, %v", out, err)
+	}
+	if out, err := run("--storage", dir, "--force"); err != nil || !strings.Contains(out, "Cached 2 session(s)") {
+		t.Errorf("cache build --force = %q, %v, want the cache rebuilt", out, err)
+	}
+
+	if _, err := run("--storage", dir, "--concurrency", "-1"); exitCode(err) != exitUsage {
+		t.Errorf("cache build --concurrency -1 error = %v, want a usage error", err)
+	}
+	if _, err := run("--storage", dir, "--storage", testutil.CreateTempDir(t)); exitCode(err) != exitUsage {
+		t.Errorf("cache build with two storage locations error = %v, want a usage error", err)
+	}
+}
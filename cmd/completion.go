@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+// completionIndex returns the sessions in the cache index, for completing session IDs
+// and names. Completion must be fast and quiet, so the storage is never read and the
+// legacy cache is read where it is rather than moved.
+func completionIndex() []internal.SessionIndexEntry {
+	dirs, err := internal.ResolveAppDirs()
+	if err != nil {
+		return nil
+	}
+	for _, dir := range []string{dirs.CacheDir, dirs.LegacyCacheDir} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		index, err := internal.NewCacheManager(dir).LoadIndex()
+		if err == nil {
+			return index.Sessions
+		}
+	}
+	return nil
+}
+
+// completeSessionIDs completes the IDs of cached sessions starting with toComplete,
+// described by the session's name
+func completeSessionIDs(toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, entry := range completionIndex() {
+		if !strings.HasPrefix(entry.ID, toComplete) {
+			continue
+		}
+		description := entry.Name
+		if description == "" {
+			description = "Untitled"
+		}
+		completions = append(completions, entry.ID+"\t"+description)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSessionNames completes the names of cached sessions starting with toComplete,
+// ignoring case
+func completeSessionNames(toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := strings.ToLower(toComplete)
+	seen := make(map[string]bool)
+	var completions []string
+	for _, entry := range completionIndex() {
+		if entry.Name == "" || seen[entry.Name] || !strings.HasPrefix(strings.ToLower(entry.Name), prefix) {
+			continue
+		}
+		seen[entry.Name] = true
+		completions = append(completions, entry.Name)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// sessionIDArg completes a command's single session ID argument
+func sessionIDArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeSessionIDs(toComplete)
+}
+
+// sessionIDFlag completes a flag taking a session ID
+func sessionIDFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeSessionIDs(toComplete)
+}
+
+// sessionNameFlag completes a flag taking a session name
+func sessionNameFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeSessionNames(toComplete)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// complete runs the hidden completion command for args and returns the candidates it
+// printed, without the trailing directive line
+func complete(t *testing.T, args ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetArgs(append([]string{"__complete"}, args...))
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	defer rootCmd.SetOut(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("__complete %v error = %v", args, err)
+	}
+	var candidates []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line != "" && !strings.HasPrefix(line, ":") && !strings.HasPrefix(line, "Completion ended") {
+			candidates = append(candidates, line)
+		}
+	}
+	return candidates
+}
+
+func TestSessionCompletion(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	index := &internal.SessionIndex{Sessions: []internal.SessionIndexEntry{
+		{ID: "a1b2c3d4-0000", Name: "Fix flaky tests"},
+		{ID: "a1ffffff-0000"},
+		{ID: "b9999999-0000", Name: "fix login bug"},
+	}}
+	if err := internal.NewCacheManager(testCacheDir(t)).SaveIndex(index); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"show IDs", []string{"show", "a1"}, []string{"a1b2c3d4-0000\tFix flaky tests", "a1ffffff-0000\tUntitled"}},
+		{"show takes one ID", []string{"show", "a1b2c3d4-0000", ""}, nil},
+		{"export session ID", []string{"export", "--session-id", "b"}, []string{"b9999999-0000\tfix login bug"}},
+		{"names ignore case", []string{"export", "--name", "FIX"}, []string{"Fix flaky tests", "fix login bug"}},
+		{"show name", []string{"show", "--name", "fix l"}, []string{"fix login bug"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := complete(t, tt.args...)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("completions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionCompletion_NoCache(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	if got := complete(t, "show", ""); len(got) != 0 {
+		t.Errorf("completions without a cache = %q, want none", got)
+	}
+}
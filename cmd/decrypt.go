@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decryptIdentity string
+	decryptOut      string
+)
+
+// decryptCmd represents the decrypt command
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <file|directory|->...",
+	Short: "Decrypt files written by export --encrypt-recipient",
+	Long: `Decrypt the .age files written by 'export --encrypt-recipient' with an age identity
+file, such as the key.txt written by age-keygen.
+
+Each file is decrypted next to itself, or into --out, under its name without .age.
+A directory decrypts every .age file in it. With -, standard input is decrypted to
+standard output.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if decryptIdentity == "" {
+			return usageErrorf("--identity is required")
+		}
+		identities, err := export.ParseIdentityFile(decryptIdentity)
+		if err != nil {
+			return &usageError{err: err}
+		}
+
+		var files []string
+		for _, arg := range args {
+			if arg == "-" {
+				if len(args) > 1 {
+					return usageErrorf("- cannot be combined with other files")
+				}
+				return export.Decrypt(cmd.OutOrStdout(), cmd.InOrStdin(), identities)
+			}
+			info, err := os.Stat(arg)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", arg, err)
+			}
+			if !info.IsDir() {
+				files = append(files, arg)
+				continue
+			}
+			matches, err := filepath.Glob(filepath.Join(arg, "*"+export.EncryptedExtension))
+			if err != nil {
+				return err
+			}
+			if len(matches) == 0 {
+				internal.LogWarn("No %s files in %s", export.EncryptedExtension, arg)
+			}
+			files = append(files, matches...)
+		}
+
+		if decryptOut != "" {
+			if err := os.MkdirAll(decryptOut, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+		}
+
+		out := commandOutput(cmd)
+		for _, file := range files {
+			target, err := decryptFile(file, identities)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(out, "🔓 %s -> %s\n", file, target)
+		}
+		internal.PrintSuccess(fmt.Sprintf("Decrypted %d file(s)", len(files)))
+		return nil
+	},
+}
+
+// decryptFile decrypts an .age file next to itself, or into --out, and returns the path
+// it was written to. The file is written under a temporary name and renamed when
+// complete, so a wrong key leaves nothing behind. Decrypted files are readable by
+// their owner only, since they hold the plaintext the encryption was protecting.
+func decryptFile(path string, identities []age.Identity) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(path), export.EncryptedExtension)
+	if name == filepath.Base(path) {
+		name += ".decrypted"
+	}
+	dir := filepath.Dir(path)
+	if decryptOut != "" {
+		dir = decryptOut
+	}
+	target := filepath.Join(dir, name)
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp, err := os.CreateTemp(dir, ".decrypt_*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := export.Decrypt(tmp, src, identities); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		internal.LogWarn("Failed to set permissions on %s: %v", target, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return target, nil
+}
+
+func init() {
+	rootCmd.AddCommand(decryptCmd)
+	decryptCmd.Flags().StringVarP(&decryptIdentity, "identity", "i", "", "age identity file with the private key (AGE-SECRET-KEY-1...)")
+	decryptCmd.Flags().StringVarP(&decryptOut, "out", "o", "", "Directory to write decrypted files to (default: next to each file)")
+}
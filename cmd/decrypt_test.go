@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestExportAndDecrypt(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+	defer func() {
+		decryptIdentity = ""
+		decryptOut = ""
+	}()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(testutil.CreateTempDir(t), "key.txt")
+	if err := os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("secret", []internal.Message{{Actor: "user", Content: "proprietary code"}})
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	clearExportFlags()
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--clear-cache", "--report", "--encrypt-recipient", identity.Recipient().String()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	encrypted := filepath.Join(out, "session_secret.jsonl.age")
+	data, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatalf("export --encrypt-recipient should write %s: %v", encrypted, err)
+	}
+	if strings.Contains(string(data), "proprietary code") {
+		t.Error("Encrypted export contains the plaintext")
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_secret.jsonl")); err == nil {
+		t.Error("export --encrypt-recipient should not write the plaintext file")
+	}
+
+	// Exporting again checks the encrypted file: it is left alone while it is there
+	// and unchanged, and written again once it is gone
+	progress, err := internal.LoadExportState(filepath.Join(out, internal.ExportProgressFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress.SetFormat("jsonl encrypt-recipient=[" + identity.Recipient().String() + "]")
+	if progress.Files["secret"] != "session_secret.jsonl.age" || !progress.Written("secret", encrypted) {
+		t.Errorf("progress manifest = %+v, want the encrypted file with its hash", progress)
+	}
+	reexport := func() {
+		t.Helper()
+		clearExportFlags()
+		rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--encrypt-recipient", identity.Recipient().String()})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export error = %v", err)
+		}
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(encrypted, old, old); err != nil {
+		t.Fatal(err)
+	}
+	reexport()
+	if info, err := os.Stat(encrypted); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("exporting again should skip the unchanged encrypted session, stat = %v, %v", info, err)
+	}
+	if err := os.Remove(encrypted); err != nil {
+		t.Fatal(err)
+	}
+	reexport()
+	if _, err := os.Stat(encrypted); err != nil {
+		t.Errorf("exporting again should write %s: %v", encrypted, err)
+	}
+
+	// Decrypt the whole directory into another one
+	decrypted := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"decrypt", "--identity", keyFile, "--out", decrypted, out})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("decrypt error = %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(decrypted, "session_secret.jsonl"))
+	if err != nil || !strings.Contains(string(data), "proprietary code") {
+		t.Errorf("decrypted session = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(decrypted, "session_secret.jsonl")); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("decrypted session mode = %v, want -rw-------", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(decrypted, "export-report.json")); err != nil {
+		t.Errorf("decrypt should restore the report: %v", err)
+	}
+
+	// Standard input to standard output
+	encryptedData, _ := os.ReadFile(encrypted)
+	var stdout bytes.Buffer
+	rootCmd.SetArgs([]string{"decrypt", "-i", keyFile, "-"})
+	rootCmd.SetIn(bytes.NewReader(encryptedData))
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetIn(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("decrypt - error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "proprietary code") {
+		t.Errorf("decrypt - wrote %q", stdout.String())
+	}
+
+	// A wrong key fails and leaves nothing behind
+	other, _ := age.GenerateX25519Identity()
+	if err := os.WriteFile(keyFile, []byte(other.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	failed := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"decrypt", "-i", keyFile, "--out", failed, encrypted})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("decrypt with the wrong key should fail")
+	}
+	if entries, _ := os.ReadDir(failed); len(entries) != 0 {
+		t.Errorf("failed decrypt left %d file(s) behind", len(entries))
+	}
+
+	for _, args := range [][]string{
+		{"decrypt", encrypted},
+		{"export", "--storage", dir, "--encrypt-recipient", "age1notakey"},
+	} {
+		decryptIdentity, encryptRecipients, storagePaths = "", nil, nil
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("%v error = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorFix     bool
+	doctorOffline bool
+)
+
+// doctorStatus is the outcome of a single diagnostic
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorCheck is the result of one diagnostic, with a suggested remedy and an optional safe fix
+type doctorCheck struct {
+	Name   string
+	Status doctorStatus
+	Detail string
+	Remedy string
+	Fix    func() error // nil when there is no safe automatic fix
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose storage, database and cache problems and suggest fixes",
+	Long: `Run every diagnostic cursor-session knows about and propose a remedy for each problem:
+  • Storage path detection
+  • File permissions on the databases and the cache
+  • Write-ahead log (WAL) presence
+  • Database lock status
+  • Whether each cursor-agent store.db uses a table layout this version can read
+  • Cache consistency with the current storage
+  • Whether a newer release is available
+
+With --fix, safe fixes (such as clearing a stale cache) are applied automatically.
+The command exits with an error if any check fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+
+		cacheDir, err := cacheDirectory()
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(out, sectionStyle.Render("🩺 Cursor Session Doctor"))
+		_, _ = fmt.Fprintln(out)
+
+		checks := runDoctorChecks(cacheDir)
+		for i := range checks {
+			printDoctorCheck(out, &checks[i])
+			if doctorFix && checks[i].Status != doctorOK && checks[i].Fix != nil {
+				if err := checks[i].Fix(); err != nil {
+					_, _ = fmt.Fprintf(out, "   %s %v\n", errorStyle.Render("🔧 Fix failed:"), err)
+				} else {
+					_, _ = fmt.Fprintln(out, "   "+successStyle.Render("🔧 Fixed"))
+					checks[i].Status = doctorOK
+				}
+			}
+		}
+
+		var warnings, failures int
+		for _, check := range checks {
+			switch check.Status {
+			case doctorWarn:
+				warnings++
+			case doctorFail:
+				failures++
+			}
+		}
+
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, sectionStyle.Render("📊 Summary"))
+		_, _ = fmt.Fprintln(out)
+		switch {
+		case failures > 0:
+			_, _ = fmt.Fprintln(out, errorStyle.Render(fmt.Sprintf("❌ %d problem(s), %d warning(s)", failures, warnings)))
+			return fmt.Errorf("doctor found %d problem(s)", failures)
+		case warnings > 0:
+			_, _ = fmt.Fprintln(out, warningStyle.Render(fmt.Sprintf("⚠️  %d warning(s)", warnings)))
+			if !doctorFix {
+				_, _ = fmt.Fprintln(out, "   Run with --fix to apply safe fixes automatically")
+			}
+		default:
+			_, _ = fmt.Fprintln(out, successStyle.Render("✅ Everything looks good"))
+		}
+		return nil
+	},
+}
+
+// runDoctorChecks runs every diagnostic against the storage selected by --storage
+func runDoctorChecks(cacheDir string) []doctorCheck {
+	paths, err := internal.GetStoragePaths(primaryStoragePath("doctor"))
+	if err != nil {
+		return []doctorCheck{
+			{
+				Name:   "Storage paths",
+				Status: doctorFail,
+				Detail: err.Error(),
+				Remedy: "pass --storage with the path to your Cursor data, or run 'cursor-session snoop' to see where it looks",
+			},
+			checkDoctorVersion(),
+		}
+	}
+
+	dbPath := paths.GetGlobalStorageDBPath()
+	checks := []doctorCheck{checkDoctorPaths(paths)}
+	checks = append(checks, checkDoctorPermissions(paths)...)
+	if paths.GlobalStorageExists() {
+		checks = append(checks, checkDoctorWAL(dbPath), checkDoctorLock(dbPath))
+	}
+	if paths.HasAgentStorage() {
+		checks = append(checks, checkDoctorAgentSchemas(paths))
+	}
+	checks = append(checks, checkDoctorCache(cacheDir, storagePathsCacheKey(paths)), checkDoctorVersion())
+	return checks
+}
+
+// checkDoctorPaths reports which storage formats were found
+func checkDoctorPaths(paths internal.StoragePaths) doctorCheck {
+	check := doctorCheck{Name: "Storage paths"}
+
+	var found []string
+	if paths.ExportDir != "" {
+		found = append(found, "exported sessions in "+paths.ExportDir)
+	}
+	if paths.GlobalStorageExists() {
+		found = append(found, "desktop app database "+paths.GetGlobalStorageDBPath())
+	}
+	if paths.HasAgentStorage() {
+		found = append(found, "agent CLI storage "+paths.AgentStoragePath)
+	}
+	if len(found) == 0 {
+		check.Status = doctorFail
+		check.Detail = "no Cursor storage found"
+		check.Remedy = "open Cursor or run cursor-agent once to create it, pass --storage, or run 'cursor-session snoop' to see where it looks"
+		return check
+	}
+
+	check.Detail = strings.Join(found, "; ")
+	if len(storagePaths) == 0 {
+		if all, err := internal.DetectAllStoragePaths(); err == nil && len(all) > 1 {
+			check.Detail += fmt.Sprintf(" (%d Cursor builds installed, using the first)", len(all))
+			check.Remedy = "pass --storage to check another build, or --all-builds to combine their sessions"
+		}
+	}
+	return check
+}
+
+// checkDoctorPermissions verifies the database and agent storage can be read
+func checkDoctorPermissions(paths internal.StoragePaths) []doctorCheck {
+	var checks []doctorCheck
+
+	if paths.GlobalStorageExists() {
+		check := doctorCheck{Name: "Database permissions", Detail: "desktop app database is readable"}
+		dbPath := paths.GetGlobalStorageDBPath()
+		if f, err := os.Open(dbPath); err != nil {
+			check.Status = doctorFail
+			check.Detail = fmt.Sprintf("cannot read %s: %v", dbPath, err)
+			check.Remedy = fmt.Sprintf("make the database readable by your user (chmod u+r %s)", dbPath)
+		} else {
+			_ = f.Close()
+		}
+		checks = append(checks, check)
+	}
+
+	if paths.HasAgentStorage() {
+		check := doctorCheck{Name: "Agent storage permissions", Detail: "agent CLI storage is readable"}
+		if _, err := os.ReadDir(paths.AgentStoragePath); err != nil {
+			check.Status = doctorFail
+			check.Detail = fmt.Sprintf("cannot read %s: %v", paths.AgentStoragePath, err)
+			check.Remedy = fmt.Sprintf("make the directory readable by your user (chmod -R u+rX %s)", paths.AgentStoragePath)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkDoctorWAL reports uncheckpointed changes in the database's write-ahead log
+func checkDoctorWAL(dbPath string) doctorCheck {
+	check := doctorCheck{Name: "Write-ahead log", Detail: "no pending WAL changes"}
+	info, err := os.Stat(dbPath + "-wal")
+	if err != nil || info.Size() == 0 {
+		return check
+	}
+
+	check.Status = doctorWarn
+	check.Detail = fmt.Sprintf("%s-wal holds %d bytes of uncheckpointed changes (Cursor is probably running)", filepath.Base(dbPath), info.Size())
+	check.Remedy = "run with --copy to read a consistent snapshot that includes the latest messages"
+	return check
+}
+
+// checkDoctorLock opens the database read-only to detect locks held by Cursor
+func checkDoctorLock(dbPath string) doctorCheck {
+	check := doctorCheck{Name: "Database lock", Detail: "database opens read-only"}
+	err := internal.ProbeDatabase(dbPath)
+	switch {
+	case err == nil:
+	case internal.IsDatabaseLocked(err):
+		check.Status = doctorFail
+		check.Detail = "database is locked by another process"
+		check.Remedy = "close Cursor, or run with --copy"
+	default:
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Remedy = "run with --copy; if that also fails the database may be corrupted"
+	}
+	return check
+}
+
+// checkDoctorAgentSchemas detects the table layout of every cursor-agent store.db, failing
+// for layouts this version cannot read
+func checkDoctorAgentSchemas(paths internal.StoragePaths) doctorCheck {
+	check := doctorCheck{Name: "Agent storage schema"}
+	storeDBs, err := paths.FindAgentStoreDBs()
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = err.Error()
+		return check
+	}
+	if len(storeDBs) == 0 {
+		check.Detail = "no store.db files yet"
+		return check
+	}
+
+	schemas := make(map[string]int)
+	var unknown []string
+	for _, dbPath := range storeDBs {
+		db, err := internal.OpenDatabase(dbPath)
+		if err != nil {
+			unknown = append(unknown, fmt.Sprintf("%s: %v", dbPath, err))
+			continue
+		}
+		schema, err := internal.DetectAgentSchema(db, dbPath)
+		_ = db.Close()
+		if err != nil {
+			unknown = append(unknown, err.Error())
+			continue
+		}
+		schemas[fmt.Sprintf("%s (v%d)", schema.Name, schema.Version)]++
+	}
+
+	names := make([]string, 0, len(schemas))
+	for name, count := range schemas {
+		names = append(names, fmt.Sprintf("%d %s", count, name))
+	}
+	sort.Strings(names)
+	check.Detail = fmt.Sprintf("%d store.db file(s): %s", len(storeDBs), strings.Join(names, ", "))
+	if len(unknown) > 0 {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("%d of %d store.db file(s) cannot be read: %s", len(unknown), len(storeDBs), strings.Join(unknown, "; "))
+		check.Remedy = "upgrade cursor-session ('cursor-session upgrade'); if it is current, report the schema and tables above"
+	}
+	return check
+}
+
+// checkDoctorCache verifies the cache directory is writable and matches the current storage
+func checkDoctorCache(cacheDir, cacheKey string) doctorCheck {
+	check := doctorCheck{Name: "Cache", Detail: "cache matches the current storage"}
+
+	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+		check.Detail = "no cache yet; it is built on the next list, show or export"
+		return check
+	}
+
+	probe, err := os.CreateTemp(cacheDir, ".doctor-*")
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("cache directory is not writable: %v", err)
+		check.Remedy = fmt.Sprintf("make %s writable by your user", cacheDir)
+		return check
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	cacheManager := internal.NewCacheManager(cacheDir)
+	if reasons := cacheManager.StaleReasons(cacheKey); len(reasons) > 0 {
+		check.Status = doctorWarn
+		check.Detail = "cache is stale for this storage: " + strings.Join(reasons, "; ")
+		check.Remedy = "run 'cursor-session list --clear-cache'"
+		check.Fix = cacheManager.ClearCache
+	}
+	return check
+}
+
+// checkDoctorVersion compares the running version with the latest release
+func checkDoctorVersion() doctorCheck {
+	check := doctorCheck{Name: "Version"}
+	if doctorOffline {
+		check.Detail = "skipped (--offline)"
+		return check
+	}
+
+	current, err := parseCurrentVersion()
+	if err != nil {
+		check.Detail = fmt.Sprintf("skipped (%v)", err)
+		return check
+	}
+
+	release, err := fetchLatestRelease()
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("could not check for updates: %v", err)
+		return check
+	}
+
+	latest, err := semver.NewVersion(strings.TrimPrefix(release.TagName, "v"))
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("could not parse latest version %q: %v", release.TagName, err)
+		return check
+	}
+
+	if latest.GreaterThan(current) {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%s is available (running %s)", release.TagName, version)
+		check.Remedy = "run 'cursor-session upgrade'"
+		return check
+	}
+	check.Detail = fmt.Sprintf("%s is the latest release", version)
+	return check
+}
+
+// printDoctorCheck writes one check result with its remedy
+func printDoctorCheck(w io.Writer, check *doctorCheck) {
+	switch check.Status {
+	case doctorOK:
+		_, _ = fmt.Fprintf(w, "%s %s\n", successStyle.Render("✅ "+check.Name+":"), check.Detail)
+	case doctorWarn:
+		_, _ = fmt.Fprintf(w, "%s %s\n", warningStyle.Render("⚠️  "+check.Name+":"), check.Detail)
+	case doctorFail:
+		_, _ = fmt.Fprintf(w, "%s %s\n", errorStyle.Render("❌ "+check.Name+":"), check.Detail)
+	}
+	if check.Remedy != "" {
+		_, _ = fmt.Fprintf(w, "   %s %s\n", infoStyle.Render("→"), check.Remedy)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Apply safe fixes, such as clearing a stale cache")
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Skip the check for a newer release")
+}
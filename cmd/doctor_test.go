@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestDoctorCommand(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	defer func() {
+		storagePaths = nil
+		doctorFix = false
+		doctorOffline = false
+	}()
+
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "globalStorage", "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	if err := os.WriteFile(dbPath+"-wal", []byte("pending"), 0644); err != nil {
+		t.Fatalf("Failed to write WAL: %v", err)
+	}
+
+	// A cache built from another database is stale for this one
+	cacheManager := internal.NewCacheManager(testCacheDir(t))
+	if err := cacheManager.SaveIndex(&internal.SessionIndex{Metadata: internal.CacheMetadata{DatabasePath: "/elsewhere/state.vscdb", DatabaseModTime: time.Now()}}); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "reports problems",
+			args: []string{"doctor", "--storage", dbPath, "--offline"},
+			want: []string{
+				"Storage paths:",
+				"Database lock: database opens read-only",
+				"Write-ahead log: state.vscdb-wal holds 7 bytes",
+				"run with --copy",
+				"cache is stale for this storage: cache was built from /elsewhere/state.vscdb",
+				"cursor-session list --clear-cache",
+				"Version: skipped (--offline)",
+				"Run with --fix",
+			},
+		},
+		{
+			name: "fixes stale cache",
+			args: []string{"doctor", "--storage", dbPath, "--offline", "--fix"},
+			want: []string{"🔧 Fixed"},
+		},
+		{
+			name: "cache is clean after fix",
+			args: []string{"doctor", "--storage", dbPath, "--offline"},
+			want: []string{"Cache: cache matches the current storage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagePaths = nil // --storage appends to the slice across executions
+			doctorFix = false
+			var buf bytes.Buffer
+			rootCmd.SetArgs(tt.args)
+			rootCmd.SetOut(&buf)
+			rootCmd.SetErr(&bytes.Buffer{})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("doctor error = %v\n%s", err, buf.String())
+			}
+
+			output := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("Output should contain %q, got:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestDoctorCommand_MissingStorage(t *testing.T) {
+	defer func() {
+		storagePaths = nil
+		doctorOffline = false
+	}()
+
+	rootCmd.SetArgs([]string{"doctor", "--storage", filepath.Join(testutil.CreateTempDir(t), "missing"), "--offline"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("doctor should fail when the storage path does not exist")
+	}
+}
+
+func TestDoctorCommand_AgentSchema(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		doctorOffline = false
+	}()
+
+	// One store.db in the known layout and one from a cursor-agent this version can't read
+	chats := filepath.Join(testutil.CreateTempDir(t), "chats")
+	for session, stmts := range map[string][]string{
+		"known-session":  {`CREATE TABLE blobs (id TEXT PRIMARY KEY, data BLOB)`, `CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`},
+		"future-session": {`CREATE TABLE conversations (id TEXT, payload BLOB)`, `PRAGMA user_version = 9`},
+	} {
+		dbPath := filepath.Join(chats, "workspace-hash", session, "store.db")
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			t.Fatalf("Failed to create session directory: %v", err)
+		}
+		db, err := sql.Open("sqlite", dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open fixture: %v", err)
+		}
+		for _, stmt := range stmts {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("Failed to build fixture: %v", err)
+			}
+		}
+		_ = db.Close()
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetArgs([]string{"doctor", "--storage", chats, "--offline"})
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("doctor should fail when a store.db has an unknown schema")
+	}
+	for _, want := range []string{"Agent storage schema: 1 of 2 store.db file(s) cannot be read", "unknown cursor-agent schema v9", "tables: conversations"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
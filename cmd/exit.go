@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by cursor-session. They are documented in docs/USAGE.md, so
+// existing values must not change.
+const (
+	exitOK             = 0 // The command succeeded
+	exitError          = 1 // Any failure without a more specific code
+	exitUsage          = 2 // Invalid flags or arguments
+	exitNoStorage      = 3 // No Cursor storage was found
+	exitPartialFailure = 4 // --strict: too many records failed to parse or sessions came out empty
+	exitSecretsFound   = 5 // scan or export --fail-on-secrets found potential secrets
+	exitGoldenMismatch = 6 // assert found differences from the golden transcript
+)
+
+// defaultStrictThreshold is the share of failed records --strict tolerates by default
+const defaultStrictThreshold = 0.05
+
+// usageError marks an error caused by invalid flags or arguments
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf returns a usageError with a formatted message
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// partialFailureError is returned by --strict when a run read too little of the storage
+type partialFailureError struct {
+	stats     internal.ParseStats
+	threshold float64
+}
+
+func (e *partialFailureError) Error() string {
+	return fmt.Sprintf("strict mode: %d/%d records failed to parse (%.1f%%) and %d/%d sessions produced no messages (%.1f%%), threshold %.1f%%",
+		e.stats.ParseFailures, e.stats.Records, e.stats.ParseFailureRatio()*100,
+		e.stats.EmptySessions, e.stats.Sessions, e.stats.EmptySessionRatio()*100,
+		e.threshold*100)
+}
+
+// checkStrict returns a partialFailureError when --strict is set and the share of records
+// that failed to parse, or of sessions that produced no messages, exceeds --strict-threshold
+func checkStrict() error {
+	if !strict {
+		return nil
+	}
+	stats := internal.GetParseStats()
+	if stats.ParseFailureRatio() > strictThreshold || stats.EmptySessionRatio() > strictThreshold {
+		return &partialFailureError{stats: stats, threshold: strictThreshold}
+	}
+	return nil
+}
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	var usageErr *usageError
+	var partialErr *partialFailureError
+	var secretsErr *secretsFoundError
+	var mismatchErr *goldenMismatchError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, internal.ErrNoStorage):
+		return exitNoStorage
+	case errors.As(err, &partialErr):
+		return exitPartialFailure
+	case errors.As(err, &secretsErr):
+		return exitSecretsFound
+	case errors.As(err, &mismatchErr):
+		return exitGoldenMismatch
+	default:
+		return exitError
+	}
+}
+
+// exitReasons names each exit code in the --strict error summary
+var exitReasons = map[int]string{
+	exitError:          "error",
+	exitUsage:          "usage",
+	exitNoStorage:      "no_storage",
+	exitPartialFailure: "partial_failure",
+	exitSecretsFound:   "secrets_found",
+	exitGoldenMismatch: "golden_mismatch",
+}
+
+// strictSummary is the machine-readable error summary --strict writes to stderr on failure
+type strictSummary struct {
+	ExitCode  int                 `json:"exit_code"`
+	Reason    string              `json:"reason"`
+	Error     string              `json:"error"`
+	Threshold float64             `json:"threshold"`
+	Stats     internal.ParseStats `json:"stats"`
+}
+
+// writeStrictSummary writes the error summary for err as one line of JSON
+func writeStrictSummary(w io.Writer, err error) {
+	code := exitCode(err)
+	summary := strictSummary{
+		ExitCode:  code,
+		Reason:    exitReasons[code],
+		Error:     err.Error(),
+		Threshold: strictThreshold,
+		Stats:     internal.GetParseStats(),
+	}
+	data, marshalErr := json.Marshal(summary)
+	if marshalErr != nil {
+		return
+	}
+	_, _ = fmt.Fprintln(w, string(data))
+}
+
+// flagError marks flag parsing errors as usage errors
+func flagError(cmd *cobra.Command, err error) error {
+	return &usageError{err: err}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"general error", errors.New("boom"), exitError},
+		{"usage error", usageErrorf("bad flag"), exitUsage},
+		{"wrapped no storage", fmt.Errorf("failed to initialize storage: %w", internal.ErrNoStorage), exitNoStorage},
+		{"partial failure", &partialFailureError{}, exitPartialFailure},
+		{"secrets found", fmt.Errorf("export aborted: %w", &secretsFoundError{findings: 1, sessions: 1}), exitSecretsFound},
+		{"golden mismatch", &goldenMismatchError{session: "abc", mismatches: 2}, exitGoldenMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	defer func() {
+		strict = false
+		strictThreshold = defaultStrictThreshold
+		internal.ResetParseStats()
+	}()
+
+	internal.ResetParseStats()
+	internal.RecordParsed(10, 2)
+
+	strict = false
+	if err := checkStrict(); err != nil {
+		t.Errorf("checkStrict() without --strict = %v, want nil", err)
+	}
+
+	strict = true
+	strictThreshold = 0.1
+	err := checkStrict()
+	if exitCode(err) != exitPartialFailure {
+		t.Errorf("checkStrict() above threshold = %v, want a partial failure", err)
+	}
+
+	strictThreshold = 0.2
+	if err := checkStrict(); err != nil {
+		t.Errorf("checkStrict() at threshold = %v, want nil", err)
+	}
+
+	internal.RecordSession(true)
+	if err := checkStrict(); exitCode(err) != exitPartialFailure {
+		t.Errorf("checkStrict() with only empty sessions = %v, want a partial failure", err)
+	}
+}
+
+func TestWriteStrictSummary(t *testing.T) {
+	defer internal.ResetParseStats()
+	internal.ResetParseStats()
+	internal.RecordParsed(4, 1)
+
+	var buf bytes.Buffer
+	writeStrictSummary(&buf, &partialFailureError{stats: internal.GetParseStats(), threshold: 0.05})
+
+	var summary strictSummary
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("summary is not JSON: %v\n%s", err, buf.String())
+	}
+	if summary.ExitCode != exitPartialFailure || summary.Reason != "partial_failure" {
+		t.Errorf("summary = %+v, want exit code %d with reason partial_failure", summary, exitPartialFailure)
+	}
+	if summary.Stats.Records != 4 || summary.Stats.ParseFailures != 1 {
+		t.Errorf("summary stats = %+v, want 4 records with 1 parse failure", summary.Stats)
+	}
+}
+
+func TestRootCommand_UsageExitCodes(t *testing.T) {
+	markArgErrorsOnce.Do(func() { markArgErrors(rootCmd) })
+	defer func() {
+		busyTimeout = internal.DefaultBusyTimeout
+		strictThreshold = defaultStrictThreshold
+		copyDB, readStrategy = false, internal.ReadAuto
+		internal.SetReadStrategy(internal.ReadAuto)
+	}()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown flag", []string{"list", "--no-such-flag"}},
+		{"negative strict threshold", []string{"list", "--strict-threshold", "-1"}},
+		{"missing argument", []string{"tag"}},
+		{"unknown read strategy", []string{"list", "--read-strategy", "fast"}},
+		{"copy with another read strategy", []string{"list", "--copy", "--read-strategy", "snapshot"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strictThreshold = defaultStrictThreshold
+			rootCmd.SetArgs(tt.args)
+			var stdout, stderr bytes.Buffer
+			rootCmd.SetOut(&stdout)
+			rootCmd.SetErr(&stderr)
+
+			err := rootCmd.Execute()
+			if got := exitCode(err); got != exitUsage {
+				t.Errorf("exitCode(%v) = %d, want %d", err, got, exitUsage)
+			}
+		})
+	}
+}
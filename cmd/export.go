@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/iksnae/cursor-session/internal"
 	"github.com/iksnae/cursor-session/internal/export"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -16,30 +21,273 @@ var (
 	outputDir    string
 	workspace    string
 	sessionID    string
+	exportName   string
 	intermediary bool
 	clearCache   bool
+
+	mdFrontmatter bool
+	mdTOC         bool
+
+	exportSince       string
+	exportUntil       string
+	exportActor       string
+	skipEmptySessions bool
+	exportReport      bool
+	exportArchive     string
+	failOnSecrets     bool
+	maxMessageBytes   int
+	oversizeStrategy  string
+	exportThinking    string
+	exportSummary     bool
+	exportFilter      string
+	splitTurns        int
+	splitOnTask       bool
+	exportResume      bool
+	exportForce       bool
+	exportMetricsFile string
+	exportStatsd      string
+	filenameTemplate  string
+	exportGroupBy     string
+	exportOverwrite   bool
+	encryptRecipients []string
+	includeBranches   bool
+	includeInline     bool
+	includeFailures   bool
+	sinceLastRun      bool
+	lastRunFile       string
+	exportStream      bool
+	exportAtomic      bool
+	exportClipboard   bool
+	exportFields      string
 )
 
+// defaultOutputDir is the directory sessions are exported to without --out
+const defaultOutputDir = "./exports"
+
+// progressSaveInterval is how many exported sessions the progress manifest is saved after,
+// bounding what an interrupted export writes again when resumed
+const progressSaveInterval = 20
+
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export sessions to file",
-	Long: `Export chat sessions to various formats (jsonl, md, yaml, json).
+	Long: `Export chat sessions to various formats (jsonl, md, yaml, json, parquet, csv,
+mermaid, dot, specstory-md, events-jsonl). mermaid and dot draw each session as a
+diagram of its turns, tool invocations and edits. specstory-md writes SpecStory's
+history file layout, named like SpecStory's own files unless --filename-template is
+set, for tooling built around SpecStory. events-jsonl writes each session as a timeline
+of typed events: its messages interleaved with the git status snapshots and terminal
+files recorded with them and the edits they applied.
+
+You can export all sessions, filter by workspace, or export a specific session by
+ID (full or unique prefix) or by name (--name, fuzzy match).
+Use 'cursor-session list' to see available session IDs.
+
+--filter keeps the sessions matching an expression over session fields, such as
+'workspace=="api" && messages>10 && created>"2024-06-01"' (see docs/USAGE.md).
+
+--since, --until and --actor filter the messages inside each exported session;
+combine them with --skip-empty-sessions to leave out sessions with no matching messages.
+
+--max-message-bytes bounds the content of each message, for tools that choke on
+huge lines: longer messages are truncated with a marker, dropped, or moved to a
+session_<id>.message_<n>.txt sidecar file (--oversize-strategy).
+
+--thinking decides what becomes of the reasoning models record before replying:
+inline (the default) merges it into the content as a [thinking] section, which the
+Markdown export collapses; separate keeps it in a thinking field of each message
+(json, jsonl, yaml and md); strip leaves it out, dropping messages that held nothing
+else. --metrics-file and --statsd count its estimated tokens either way.
+
+--fields projects json and jsonl exports down to the fields consumers need, given as
+dotted paths into the session as the json export writes it: id,metadata.name,
+messages.actor,messages.content keeps the ID and name of each session and who said
+what. name and messages.text are short for metadata.name and messages.content. In
+jsonl, each line keeps the fields under messages, and the other fields selected go
+into a session object on it.
+
+--split-turns and --split-on-task split long sessions into chunks of at most N turns,
+or where a user message starts a new task ("New task: ...", "Moving on, ..."), for
+training samples with a bounded context. Each chunk is exported as session
+<id>.chunk_<n>, with metadata.chunk linking it to the session it came from.
+
+Conversations forked by editing an earlier message are exported along their active
+branch, the one continued last. --include-branches also exports each alternate branch
+as session <id>.branch_<n>, with metadata.branch linking it to the session it forked from.
+
+--include-inline also exports the prompts sent outside the chat panel, such as Cmd-K
+edits, which Cursor records in each workspace's database apart from chats. Each is a
+session of type inline holding the prompt alone, timed when Cursor kept its time.
+
+Messages whose records fail to parse are left out, so a transcript can look complete
+when it is not. --include-parse-failures keeps a placeholder in their place, such as
+"[unparseable message: key=bubbleId:<composer>:<bubble>, reason=...]", from the actor
+of the missing message. The storage is read rather than the cache, which the
+placeholders are not saved to.
+
+Exports into a directory keep a progress manifest, .export-progress.json, recording
+for each format a content hash of every exported session and the SHA-256 of the file
+it was written to. Exporting again skips the sessions that have not changed since and
+whose file still holds what was written, so repeating an export, in md and json alike,
+writes nothing new, and an interrupted export picks up where it stopped. --force
+writes every session again.
 
-You can export all sessions, filter by workspace, or export a specific session by ID.
-Use 'cursor-session list' to see available session IDs.`,
+--since-last-run exports only the sessions created or updated since the start of the
+last successful --since-last-run export from the same storage, for scheduled harvests
+that should not upload the whole history every time. The runs are recorded in
+last-runs.json in the state directory (see 'cursor-session paths'), or --last-run-file.
+
+Sessions are written to session_<id>.<ext>, or to the name --filename-template builds
+from {id}, {short_id}, {name}, {workspace} and {date}; names and workspaces are turned
+into slugs. A name that is already taken, by another session of the export or by an
+existing file that is not an earlier export of the same session, gets a numeric suffix
+(name-2.ext) unless --overwrite is set.
+
+--group-by workspace writes the files of each workspace's sessions into their own
+directory, <out>/<workspace>/, named by a slug of the workspace's last path element
+(no-workspace for sessions without one), with an index.md and index.json listing its
+sessions.
+
+--encrypt-recipient encrypts every exported file with age to the given X25519 public
+key (age1...), adding .age to its name, so transcripts are never written in the clear.
+Repeat it to encrypt to several keys. Decrypt with 'cursor-session decrypt' or age.
+
+--summary writes one compact record per session instead of the transcripts, to
+summaries.jsonl, summaries.json or summaries.yaml: the first prompt, the final
+answer, message count, duration, files touched and tool usage counts.
+
+--stream writes a --format jsonl export to stdout instead of files, as sessions are
+reconstructed rather than once they are all loaded, so histories of any size can be
+piped into other tools in constant memory. Each session is written as a record of
+type "session" with its metadata, followed by a record of type "message" for each of
+its messages; every record carries the session_id. The cache is not used, and flags
+that need every session at once or write files (--out, --archive, --summary, --resume,
+--report, --group-by, ...) are rejected.
+
+--atomic writes the export into a staging directory next to --out (or a temporary
+file next to --archive) and renames it into place only once it is complete, adding a
+manifest.json that lists every file with its size and SHA-256 along with the counts
+of the export. A crash or a failed export leaves the previous output untouched, so a
+manifest.json in the output marks a complete export.
+
+--clipboard places the session given by --session-id or --name on the clipboard as
+Markdown instead of writing any files, whatever the --format. The --md-frontmatter,
+--md-toc and message filters apply; it fails when splitting or --include-branches
+leaves more than one session to copy.
+
+--metrics-file writes the counters of the run (sessions and messages exported, parse
+failures, duration) in the Prometheus textfile format, and --statsd pushes them to a
+statsd server, to monitor scheduled exports.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		started := time.Now()
+		messageFilter, err := internal.NewMessageFilter(exportSince, exportUntil, exportActor)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		messageLimit, err := internal.NewMessageLimit(maxMessageBytes, oversizeStrategy)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		thinkingMode, err := internal.NewThinkingMode(exportThinking)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		sessionSplit, err := internal.NewSessionSplit(splitTurns, splitOnTask)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		if !sessionSplit.IsZero() && intermediary {
+			return usageErrorf("--split-turns and --split-on-task cannot be combined with --intermediary")
+		}
+		sessionFilter, err := parseFilterFlag(exportFilter)
+		if err != nil {
+			return err
+		}
+		if exportSummary {
+			if !slices.Contains(internal.SummaryFormats, format) {
+				return usageErrorf("--summary supports --format %s, not %s", strings.Join(internal.SummaryFormats, ", "), format)
+			}
+			if intermediary {
+				return usageErrorf("--summary cannot be combined with --intermediary")
+			}
+		}
+		if (exportResume || exportForce) && (exportArchive != "" || exportSummary) {
+			return usageErrorf("--resume and --force cannot be combined with --archive or --summary")
+		}
+		if exportResume && exportForce {
+			return usageErrorf("--resume cannot be combined with --force")
+		}
+		if exportGroupBy != "" {
+			if !slices.Contains(export.GroupByOptions, exportGroupBy) {
+				return usageErrorf("unsupported --group-by %s (expected %s)", exportGroupBy, strings.Join(export.GroupByOptions, ", "))
+			}
+			if exportSummary {
+				return usageErrorf("--group-by cannot be combined with --summary")
+			}
+		}
+		templateSource := filenameTemplate
+		if format == "specstory-md" && templateSource == export.DefaultFilenameTemplate {
+			// SpecStory names its history files after their creation time and title
+			templateSource = export.SpecStoryFilenameTemplate
+		}
+		fileTemplate, err := export.ParseFilenameTemplate(templateSource)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		recipients, err := export.ParseRecipients(encryptRecipients)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		if exportArchive != "" && !export.IsArchivePath(exportArchive) {
+			return usageErrorf("unsupported --archive %s (expected .tar.gz, .tgz or .zip)", exportArchive)
+		}
+		var fields *export.FieldSelection
+		if exportFields != "" {
+			if !slices.Contains(export.FieldFormats, format) {
+				return usageErrorf("--fields supports --format %s, not %s", strings.Join(export.FieldFormats, ", "), format)
+			}
+			if exportSummary || exportClipboard {
+				return usageErrorf("--fields cannot be combined with --summary or --clipboard")
+			}
+			if fields, err = export.ParseFields(exportFields); err != nil {
+				return &usageError{err: err}
+			}
+		}
+		if exportStream {
+			if err := validateStreamFlags(); err != nil {
+				return err
+			}
+		}
+		if exportClipboard {
+			if sessionID == "" && exportName == "" {
+				return usageErrorf("--clipboard copies a single session: give it with --session-id or --name")
+			}
+			if exportArchive != "" || exportAtomic || exportStream || exportSummary || intermediary || len(encryptRecipients) > 0 {
+				return usageErrorf("--clipboard cannot be combined with --archive, --atomic, --stream, --summary, --intermediary or --encrypt-recipient")
+			}
+		}
+		var detectors []internal.SecretDetector
+		if failOnSecrets {
+			if detectors, err = internal.NewSecretDetectors(secretDetectors, entropyThreshold); err != nil {
+				return &usageError{err: err}
+			}
+		}
+
+		internal.SetIncludeParseFailures(includeFailures)
+		defer internal.SetIncludeParseFailures(false)
+
 		// Get paths (with optional custom storage location)
-		paths, err := internal.GetStoragePaths(storagePath)
+		paths, err := resolveStoragePaths()
 		if err != nil {
 			return fmt.Errorf("failed to get storage paths: %w", err)
 		}
 
-		// Copy database files to temp location if --copy flag is set
+		// Copy database files to temp location if --read-strategy (or --copy) asks for it
 		var cleanup func() error
-		if copyDB {
+		if copyStorage(paths...) {
 			var copyErr error
-			paths, cleanup, copyErr = internal.CopyStoragePaths(paths)
+			paths, cleanup, copyErr = internal.CopyStoragePathsList(paths)
 			if copyErr != nil {
 				return fmt.Errorf("failed to copy database files: %w", copyErr)
 			}
@@ -56,18 +304,28 @@ Use 'cursor-session list' to see available session IDs.`,
 		}
 
 		// Create storage backend (handles both desktop app and agent storage)
-		backend, err := internal.NewStorageBackend(paths)
+		backend, err := internal.NewStorageBackendForPaths(paths)
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 
+		// Write sessions to stdout as they are reconstructed, bypassing the cache
+		if exportStream {
+			stream := sessionStream{
+				messageFilter: messageFilter,
+				messageLimit:  messageLimit,
+				thinkingMode:  thinkingMode,
+				sessionSplit:  sessionSplit,
+				sessionFilter: sessionFilter,
+			}
+			return streamExport(commandOutput(cmd), backend, paths, stream, started)
+		}
+
 		// Initialize cache manager (always enabled)
-		// Store cache in user's home directory root
-		homeDir, err := os.UserHomeDir()
+		cacheDir, err := cacheDirectory()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
 		}
-		cacheDir := filepath.Join(homeDir, ".cursor-session-cache")
 		cacheManager := internal.NewCacheManager(cacheDir)
 
 		// Clear cache if requested
@@ -82,18 +340,18 @@ Use 'cursor-session list' to see available session IDs.`,
 		var sessions []*internal.Session
 
 		// Use appropriate cache key based on storage type
-		var cacheKey string
-		if paths.GlobalStorageExists() {
-			cacheKey = paths.GetGlobalStorageDBPath()
-		} else if paths.HasAgentStorage() {
-			cacheKey = paths.AgentStoragePath
-		} else {
-			cacheKey = "unknown"
-		}
+		cacheKey := storageCacheKey(paths)
 
-		// Try to load from cache
+		// Try to load from cache; a report needs the statistics of reading the storage, and
+		// the cache doesn't hold alternate branches or placeholders of unparseable messages
 		valid, err := cacheManager.IsCacheValid(cacheKey)
-		if err == nil && valid {
+		if exportReport {
+			internal.LogInfo("Reading storage for the export report")
+		} else if includeBranches {
+			internal.LogInfo("Reading storage for alternate branches")
+		} else if includeFailures {
+			internal.LogInfo("Reading storage for messages that failed to parse")
+		} else if err == nil && valid {
 			internal.LogInfo("Loading sessions from cache...")
 			sessions, err = cacheManager.LoadAllSessions()
 			if err == nil && len(sessions) > 0 {
@@ -104,6 +362,28 @@ Use 'cursor-session list' to see available session IDs.`,
 			}
 		}
 
+		// On a cache miss, a single session is reconstructed on its own, loading only its
+		// bubbles; its result isn't cached since the cache holds every session
+		singleSession := sessions == nil && sessionID != "" && exportName == "" && !exportReport && !includeBranches && !includeInline
+		if _, ok := backend.(internal.BubbleLoader); singleSession && ok {
+			err := internal.ShowProgressWithSteps(context.Background(), []internal.ProgressStep{
+				{
+					Message: "Loading session from storage",
+					Fn: func() error {
+						session, loadErr := internal.ReconstructSession(backend, paths, sessionID, workspace)
+						if loadErr != nil {
+							return loadErr
+						}
+						sessions = []*internal.Session{session}
+						return nil
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
 		// Reconstruct if cache miss
 		if sessions == nil {
 			var conversations []*internal.ReconstructedConversation
@@ -114,60 +394,28 @@ Use 'cursor-session list' to see available session IDs.`,
 					Message: "Loading data from storage",
 					Fn: func() error {
 						var loadErr error
-						bubbleChan, composerChan, contextChan, loadErr := internal.LoadDataAsyncFromBackend(backend)
-						if loadErr != nil {
-							return fmt.Errorf("failed to load data: %w", loadErr)
+						conversations, loadErr = internal.ReconstructConversations(backend)
+						if includeBranches {
+							conversations = internal.WithBranches(conversations)
 						}
-
-						// Reconstruct conversations
-						conversations, loadErr = internal.ReconstructAsync(bubbleChan, composerChan, contextChan)
-						if loadErr != nil {
-							return fmt.Errorf("failed to reconstruct conversations: %w", loadErr)
-						}
-						return nil
+						return loadErr
 					},
 				},
 				{
 					Message: "Processing and normalizing sessions",
 					Fn: func() error {
-						// Detect workspaces for association
-						workspaces, _ := internal.DetectWorkspaces(paths.BasePath)
-
-						// Load contexts for workspace association
-						var contexts map[string][]*internal.MessageContext
-						contexts, _ = backend.LoadMessageContexts()
-
-						// Normalize with workspace association
-						normalizer := internal.NewNormalizer()
-						sessions = make([]*internal.Session, 0, len(conversations))
-						for _, conv := range conversations {
-							// Try to associate with workspace
-							assignedWorkspace := workspace
-							if assignedWorkspace == "" {
-								assignedWorkspace = internal.AssociateComposerWithWorkspace(conv.ComposerID, contexts[conv.ComposerID], workspaces)
-							}
-
-							session, err := normalizer.NormalizeConversation(conv, assignedWorkspace)
-							if err != nil {
-								internal.LogWarn("Failed to normalize conversation %s: %v", conv.ComposerID, err)
-								continue
-							}
-							sessions = append(sessions, session)
-						}
-
-						// Log summary statistics
-						internal.LogInfo("Normalization complete: %d composers processed, %d sessions created", len(conversations), len(sessions))
-
-						// Deduplicate
-						deduplicator := internal.NewDeduplicator()
-						sessions = deduplicator.Deduplicate(sessions)
+						sessions = internal.NormalizeSessions(conversations, backend, paths, workspace)
 						return nil
 					},
 				},
 				{
 					Message: "Caching sessions",
 					Fn: func() error {
-						// Save to cache
+						// Sessions combined from several storage locations are not cached, nor
+						// are alternate branches or placeholders, which other commands don't expect
+						if cacheKey == "" || includeBranches || includeFailures {
+							return nil
+						}
 						if err := cacheManager.SaveSessions(sessions, cacheKey); err != nil {
 							internal.LogWarn("Failed to save cache: %v", err)
 						}
@@ -181,6 +429,18 @@ Use 'cursor-session list' to see available session IDs.`,
 			}
 		}
 
+		// Add the prompts sent outside the chat panel, which the cache doesn't hold
+		if includeInline {
+			sessions = append(sessions, internal.LoadInlineSessions(paths, sessions)...)
+		}
+
+		hideGeneratedTitles(sessions)
+		applySessionTags(sessions)
+
+		// Export in a stable order, so two exports of the same data write the same files in
+		// the same order
+		internal.SortSessions(sessions)
+
 		// Filter by workspace if specified
 		if workspace != "" {
 			filtered := make([]*internal.Session, 0)
@@ -192,76 +452,580 @@ Use 'cursor-session list' to see available session IDs.`,
 			sessions = filtered
 		}
 
-		// Filter by session ID if specified
-		if sessionID != "" {
-			filtered := make([]*internal.Session, 0)
+		// Filter by expression if specified
+		if sessionFilter != nil {
+			sessions = sessionFilter.FilterSessions(sessions)
+		}
+
+		// Filter by session ID (full or prefix) or name if specified, keeping the
+		// alternate branches of the session
+		if sessionID != "" || exportName != "" {
+			refs := make([]internal.SessionRef, 0, len(sessions))
 			for _, session := range sessions {
-				if session.ID == sessionID {
-					filtered = append(filtered, session)
-					break // Only one session should match
+				if session.Metadata.Branch == nil {
+					refs = append(refs, internal.SessionRef{ID: session.ID, Name: session.Metadata.Name})
 				}
 			}
-			if len(filtered) == 0 {
-				return fmt.Errorf("session not found: %s (use 'cursor-session list' to see available sessions)", sessionID)
+			resolvedID, err := internal.ResolveSession(refs, sessionID, exportName)
+			if err != nil {
+				return err
+			}
+
+			filtered := make([]*internal.Session, 0, 1)
+			for _, session := range sessions {
+				branch := session.Metadata.Branch
+				if session.ID == resolvedID || (branch != nil && branch.SessionID == resolvedID) {
+					filtered = append(filtered, session)
+				}
 			}
 			sessions = filtered
 		}
 
+		// Only export the sessions that changed since the last run from this storage
+		var lastRuns *internal.LastRuns
+		if sinceLastRun {
+			if lastRuns, err = loadLastRuns(); err != nil {
+				return err
+			}
+			if last, ok := lastRuns.Get(lastRunKey(paths)); ok {
+				sessions = internal.SessionsChangedSince(sessions, last)
+				internal.LogInfo("Exporting %d session(s) changed since the last run at %s", len(sessions), last.Format(time.RFC3339))
+			} else {
+				internal.LogInfo("No earlier run recorded for this storage, exporting every session")
+			}
+		}
+
+		// Filter messages within each session, split long sessions, write their thinking as
+		// asked, bound message size and move attached images out to their own files
+		filtered := make([]*internal.Session, 0, len(sessions))
+		sidecars := make(map[string][]internal.Sidecar)
+		attachments := make(map[string][]internal.Attachment)
+		thinkingTokens := make(map[string]int)
+		for _, session := range sessions {
+			if session == nil {
+				continue
+			}
+			session = messageFilter.Apply(session)
+			if skipEmptySessions && len(session.Messages) == 0 {
+				internal.LogDebug("Skipping session %s with no matching messages", session.ID)
+				continue
+			}
+			for _, chunk := range sessionSplit.Apply(session) {
+				thinkingTokens[chunk.ID] = internal.ThinkingTokens(chunk)
+				limited, files := messageLimit.Apply(thinkingMode.Apply(chunk))
+				if len(files) > 0 {
+					sidecars[chunk.ID] = files
+				}
+				limited, images := internal.DetachAttachments(limited)
+				if len(images) > 0 {
+					attachments[chunk.ID] = images
+				}
+				filtered = append(filtered, limited)
+			}
+		}
+		sessions = filtered
+
+		// Refuse to write anything if a session leaks a secret
+		if failOnSecrets {
+			if err := scanSessions(commandOutput(cmd), sessions, detectors); err != nil {
+				return fmt.Errorf("export aborted: %w", err)
+			}
+		}
+
+		// Put the session on the clipboard instead of writing any files
+		if exportClipboard {
+			if len(sessions) != 1 {
+				return fmt.Errorf("--clipboard copies a single session, but %d session(s) are left to export", len(sessions))
+			}
+			used, err := copySessionToClipboard(sessions[0], &export.MarkdownExporter{Frontmatter: mdFrontmatter, TOC: mdTOC})
+			if err != nil {
+				return err
+			}
+			internal.PrintSuccess(fmt.Sprintf("Copied session %s (%d message(s)) to the clipboard with %s", sessions[0].ID, len(sessions[0].Messages), used))
+			return nil
+		}
+
 		// Create exporter
 		exporter, err := export.NewExporter(format)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		switch e := exporter.(type) {
+		case *export.MarkdownExporter:
+			e.Frontmatter = mdFrontmatter
+			e.TOC = mdTOC
+		case *export.JSONExporter:
+			e.Fields = fields
+		case *export.JSONLExporter:
+			e.Fields = fields
+		}
+		if len(recipients) > 0 {
+			exporter = export.WithEncryption(exporter, recipients)
+		}
+
+		// Write into the output directory, or stream everything into one archive; --atomic
+		// stages either until the export is complete
+		destination, destinationName := export.NewDirDestination, outputDir
+		stage := export.StageDir
+		if exportArchive != "" {
+			destination, destinationName = export.CreateArchive, exportArchive
+			stage = export.StageArchive
+		}
+		var dest export.Destination
+		var staged *export.StagedExport
+		if exportAtomic {
+			staged, err = stage(destinationName)
+			dest = staged
+		} else {
+			dest, err = destination(destinationName)
+		}
 		if err != nil {
 			return err
 		}
+		// Files already in the output directory are read where the export is written
+		exportDir := outputDir
+		if staged != nil && exportArchive == "" {
+			exportDir = staged.Dir()
+		}
+		if len(recipients) > 0 {
+			dest = export.WithEncryptedFiles(dest, recipients)
+		}
+		closed := false
+		defer func() {
+			if !closed {
+				_ = dest.Close()
+			}
+		}()
 
-		// Ensure output directory exists
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+		// Load raw data for intermediary dumps (sessions may have come from the cache)
+		var rawComposers []*internal.RawComposer
+		var rawBubbles map[string]*internal.RawBubble
+		var rawContexts map[string][]*internal.MessageContext
+		if intermediary {
+			if rawComposers, err = backend.LoadComposers(); err != nil {
+				return fmt.Errorf("failed to load composers for intermediary format: %w", err)
+			}
+			if rawBubbles, err = backend.LoadBubbles(); err != nil {
+				return fmt.Errorf("failed to load bubbles for intermediary format: %w", err)
+			}
+			if rawContexts, err = backend.LoadMessageContexts(); err != nil {
+				return fmt.Errorf("failed to load contexts for intermediary format: %w", err)
+			}
 		}
 
 		// Export sessions with progress
-		ctx := context.Background()
-		err = internal.ShowProgress(ctx, fmt.Sprintf("Exporting %d session(s) to %s", len(sessions), outputDir), func() error {
+		var exportedIDs, failedIDs, skippedIDs []string
+		messagesExported, thinkingExported := 0, 0
+		if exportSummary {
+			if err := writeSummaries(dest, sessions, format); err != nil {
+				return err
+			}
 			for _, session := range sessions {
-				if session == nil {
-					internal.LogWarn("Skipping nil session")
-					continue
+				exportedIDs = append(exportedIDs, session.ID)
+				messagesExported += len(session.Messages)
+				thinkingExported += thinkingTokens[session.ID]
+			}
+		} else {
+			// Record each exported session in the progress manifest of the output directory
+			var progress *internal.ExportState
+			namer := export.NewFileNamer(fileTemplate, "", exportOverwrite, nil)
+			if exportArchive == "" {
+				progress = loadExportProgress(filepath.Join(exportDir, internal.ExportProgressFile), cmd.Flags())
+				namer = export.NewFileNamer(fileTemplate, exportDir, exportOverwrite, progress.Files)
+			}
+			var indexes *export.WorkspaceIndexes
+			if exportGroupBy == export.GroupByWorkspace {
+				namer.Group = func(session *internal.Session) string {
+					return export.WorkspaceDir(session.Workspace)
 				}
-				filename := fmt.Sprintf("session_%s.%s", session.ID, exporter.Extension())
-				filepath := filepath.Join(outputDir, filename)
+				indexes = export.NewWorkspaceIndexes()
+			}
 
-				file, err := os.Create(filepath)
-				if err != nil {
-					internal.LogError("Failed to create file %s: %v", filepath, err)
-					continue
-				}
+			ctx := context.Background()
+			err = internal.ShowProgress(ctx, fmt.Sprintf("Exporting %d session(s) to %s", len(sessions), destinationName), func() error {
+				unsaved := 0
+				for _, session := range sessions {
+					if session == nil {
+						internal.LogWarn("Skipping nil session")
+						continue
+					}
 
-				if err := exporter.Export(session, file); err != nil {
-					_ = file.Close()
-					internal.LogError("Failed to export session %s: %v", session.ID, err)
-					continue
+					// The name takes the extension of the exporter, .age included when it
+					// encrypts, so it is the file the session is written to and checked at
+					name := namer.Name(exporter, session)
+					written := filepath.Join(exportDir, filepath.FromSlash(name))
+					var fingerprint string
+					if progress != nil {
+						var changed bool
+						fingerprint, changed = progress.Changed(session)
+						if !exportForce && !changed && exportedTo(progress, exporter, session) == name && progress.Written(session.ID, written) {
+							internal.LogDebug("Skipping session %s, exported and unchanged", session.ID)
+							skippedIDs = append(skippedIDs, session.ID)
+							addToIndex(indexes, session, name)
+							continue
+						}
+					}
+
+					if err := dest.WriteSession(exporter, session, name); err != nil {
+						internal.LogError("Failed to export session %s: %v", session.ID, err)
+						failedIDs = append(failedIDs, session.ID)
+						continue
+					}
+					exportedIDs = append(exportedIDs, session.ID)
+					messagesExported += len(session.Messages)
+					thinkingExported += thinkingTokens[session.ID]
+					addToIndex(indexes, session, name)
+
+					if progress != nil {
+						progress.Mark(session.ID, fingerprint)
+						progress.SetFile(session.ID, name)
+						if hash, err := internal.HashFile(written); err == nil {
+							progress.SetHash(session.ID, hash)
+						} else {
+							internal.LogDebug("Failed to hash %s: %v", name, err)
+						}
+						if unsaved++; unsaved >= progressSaveInterval {
+							saveExportProgress(progress)
+							unsaved = 0
+						}
+					}
+
+					// Sidecars, images and dumps go next to the session's file
+					dir := path.Dir(name)
+					for _, sidecar := range sidecars[session.ID] {
+						if err := dest.WriteFile(path.Join(dir, sidecar.Name), sidecar.Content); err != nil {
+							internal.LogError("Failed to write %s: %v", sidecar.Name, err)
+						}
+					}
+					for _, att := range attachments[session.ID] {
+						data, err := att.Content()
+						if err == nil {
+							err = dest.WriteFile(path.Join(dir, att.File), data)
+						}
+						if err != nil {
+							internal.LogError("Failed to write image %s: %v", att.File, err)
+						}
+					}
+
+					if intermediary {
+						dump := internal.BuildIntermediaryDump(session.ID, rawComposers, rawBubbles, rawContexts)
+						dump.Workspace = session.Workspace
+						if err := writeIntermediaryDump(dest, dir, dump, format == "yaml"); err != nil {
+							internal.LogError("Failed to write intermediary format for session %s: %v", session.ID, err)
+						}
+					}
 				}
+				if progress != nil && unsaved > 0 {
+					saveExportProgress(progress)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if indexes != nil {
+				if err := writeWorkspaceIndexes(dest, indexes); err != nil {
+					return err
+				}
+			}
+		}
+
+		if exportReport {
+			// Sessions left in place as unchanged are part of the export all the same
+			report := internal.NewExportReport(format, destinationName, append(exportedIDs, skippedIDs...), failedIDs)
+			report.SessionsSkipped = len(skippedIDs)
+			report.AddExtraction(sessions)
+			data, err := report.JSON()
+			if err != nil {
+				return err
+			}
+			if err := dest.WriteFile(internal.ExportReportFile, data); err != nil {
+				return fmt.Errorf("failed to write export report: %w", err)
+			}
+			for _, warning := range report.Warnings {
+				internal.LogWarn("Export report: %s", warning)
+			}
+		}
 
-				if err := file.Close(); err != nil {
-					internal.LogWarn("Failed to close file %s: %v", filepath, err)
+		if staged != nil {
+			manifest := export.Manifest{
+				CreatedAt: time.Now().UTC(),
+				Format:    format,
+				Counts: export.ManifestCounts{
+					SessionsExported: len(exportedIDs) + len(skippedIDs),
+					SessionsSkipped:  len(skippedIDs),
+					SessionsFailed:   len(failedIDs),
+					MessagesExported: messagesExported,
+				},
+			}
+			if err := staged.Commit(manifest); err != nil {
+				return err
+			}
+		}
+		closed = true
+		if err := dest.Close(); err != nil {
+			return err
+		}
+
+		// The next run picks up from the start of this one, so sessions that changed while
+		// it ran are exported again; a run with failures is retried in full
+		if lastRuns != nil {
+			if len(failedIDs) > 0 {
+				internal.LogWarn("Not recording the run for --since-last-run: %d session(s) failed to export", len(failedIDs))
+			} else {
+				lastRuns.Set(lastRunKey(paths), started)
+				if err := lastRuns.Save(); err != nil {
+					return err
 				}
 			}
+		}
+
+		if exportMetricsFile != "" || exportStatsd != "" {
+			metrics := internal.ExportMetrics{
+				SessionsExported: len(exportedIDs),
+				SessionsFailed:   len(failedIDs),
+				MessagesExported: messagesExported,
+				ThinkingTokens:   thinkingExported,
+				ParseFailures:    internal.GetParseStats().ParseFailures,
+				Duration:         time.Since(started),
+				FinishedAt:       time.Now(),
+			}
+			if err := emitExportMetrics(metrics); err != nil {
+				return err
+			}
+		}
+
+		if quiet {
+			// The only result of a quiet export is where it went
+			_, _ = fmt.Fprintln(commandOutput(cmd), destinationName)
 			return nil
-		})
+		}
+		internal.PrintSuccess(fmt.Sprintf("Export complete: %d session(s) exported to %s", len(sessions)-len(skippedIDs), destinationName))
+		if len(skippedIDs) > 0 {
+			internal.PrintInfo(fmt.Sprintf("Skipped %d session(s) already exported and unchanged (--force writes them again)", len(skippedIDs)))
+		}
+		return nil
+	},
+}
+
+// lastRunKey returns the key the last run of an export from the storage locations is
+// recorded under: the cache keys of the locations, in order
+func lastRunKey(list []internal.StoragePaths) string {
+	keys := make([]string, 0, len(list))
+	for _, paths := range list {
+		keys = append(keys, storagePathsCacheKey(paths))
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
+
+// loadLastRuns loads the runs recorded in --last-run-file, by default in the state directory
+func loadLastRuns() (*internal.LastRuns, error) {
+	path := lastRunFile
+	if path == "" {
+		dirs, err := internal.ResolveAppDirs()
 		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dirs.StateDir, internal.LastRunFile)
+	}
+	return internal.LoadLastRuns(path)
+}
+
+// emitExportMetrics writes the metrics of the run to --metrics-file and pushes them to
+// --statsd
+func emitExportMetrics(metrics internal.ExportMetrics) error {
+	if exportMetricsFile != "" {
+		if err := internal.WriteMetricsFile(exportMetricsFile, metrics); err != nil {
+			return err
+		}
+		internal.LogInfo("Wrote export metrics to %s", exportMetricsFile)
+	}
+	if exportStatsd != "" {
+		if err := internal.PushStatsd(exportStatsd, metrics); err != nil {
 			return err
 		}
+		internal.LogInfo("Pushed export metrics to statsd at %s", exportStatsd)
+	}
+	return nil
+}
 
-		internal.PrintSuccess(fmt.Sprintf("Export complete: %d session(s) exported to %s", len(sessions), outputDir))
-		return nil
-	},
+// loadExportProgress loads the progress manifest of an export directory for the current
+// format and the options in flags. A manifest that can't be read is replaced, so every
+// session is exported again.
+func loadExportProgress(path string, flags *pflag.FlagSet) *internal.ExportState {
+	progress, err := internal.LoadExportState(path)
+	if err != nil {
+		internal.LogWarn("Failed to read the export progress manifest, exporting every session: %v", err)
+		progress = internal.NewExportState(path)
+	}
+	progress.SetFormat(exportOutputKey(flags))
+	return progress
+}
+
+// saveExportProgress saves the progress manifest; failing to save only costs a resumed
+// export some sessions it writes again
+func saveExportProgress(progress *internal.ExportState) {
+	if err := progress.Save(); err != nil {
+		internal.LogWarn("Failed to save the export progress manifest: %v", err)
+	}
+}
+
+// exportLayoutFlags are the flags that choose which sessions are exported, and where and
+// how they are read or reported, but not what the file of a session holds. Every other
+// flag, including any added later, is part of the output key, so a new option can at worst
+// write every session once more. Options that change the sessions themselves, such as
+// --since or --thinking, are also caught by the fingerprint of each session.
+var exportLayoutFlags = map[string]bool{
+	"archive": true, "atomic": true, "clear-cache": true, "clipboard": true,
+	"detectors": true, "entropy-threshold": true, "fail-on-secrets": true,
+	"filename-template": true, "filter": true, "force": true, "format": true,
+	"group-by": true, "last-run-file": true, "metrics-file": true, "name": true,
+	"out": true, "overwrite": true, "report": true, "resume": true, "session-id": true,
+	"since-last-run": true, "skip-empty-sessions": true, "statsd": true, "stream": true,
+	"summary": true, "workspace": true,
+
+	"busy-timeout": true, "copy": true, "log-file": true, "log-format": true,
+	"log-level": true, "max-open-dbs": true, "no-color": true, "plain": true,
+	"quiet": true, "read-strategy": true, "storage": true, "strict": true,
+	"strict-threshold": true, "verbose": true,
+}
+
+// exportOutputKey names the format and every option of flags set away from its default
+// that may change how an unchanged session is written, so files written with other
+// options are not skipped as unchanged
+func exportOutputKey(flags *pflag.FlagSet) string {
+	key := format
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if !exportLayoutFlags[flag.Name] && flag.Value.String() != flag.DefValue {
+			key += fmt.Sprintf(" %s=%s", flag.Name, flag.Value)
+		}
+	})
+	return key
+}
+
+// exportedTo returns the name of the file an earlier export wrote a session to. Manifests
+// written before file names were recorded used the default name.
+func exportedTo(progress *internal.ExportState, exporter export.Exporter, session *internal.Session) string {
+	if name, ok := progress.Files[session.ID]; ok {
+		return name
+	}
+	return export.SessionFileName(exporter, session)
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeSummaries writes the summary of every session into a single file in the export format
+func writeSummaries(dest export.Destination, sessions []*internal.Session, format string) error {
+	summaries := make([]*internal.SessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		summaries = append(summaries, internal.SummarizeSession(session))
+	}
+	data, err := internal.MarshalSummaries(summaries, format)
+	if err != nil {
+		return err
+	}
+	if err := dest.WriteFile(internal.SummaryFileName(format), data); err != nil {
+		return fmt.Errorf("failed to write summaries: %w", err)
+	}
+	return nil
+}
+
+// writeIntermediaryDump writes the raw data of a session next to its normalized export
+func writeIntermediaryDump(dest export.Destination, dir string, dump *internal.IntermediaryDump, asYAML bool) error {
+	var data []byte
+	var err error
+	ext := "json"
+	if asYAML {
+		data, err = dump.ToYAML()
+		ext = "yaml"
+	} else {
+		data, err = dump.ToJSON()
+	}
+	if err != nil {
+		return err
+	}
+
+	return dest.WriteFile(path.Join(dir, fmt.Sprintf("session_%s.intermediary.%s", dump.SessionID, ext)), data)
+}
+
+// addToIndex records a session written to name in the index of its workspace directory,
+// when the export is grouped by workspace
+func addToIndex(indexes *export.WorkspaceIndexes, session *internal.Session, name string) {
+	if indexes != nil {
+		indexes.Add(session, path.Base(name))
+	}
+}
+
+// writeWorkspaceIndexes writes index.json and index.md into each workspace directory of
+// the export
+func writeWorkspaceIndexes(dest export.Destination, indexes *export.WorkspaceIndexes) error {
+	for _, dir := range indexes.Dirs() {
+		index := indexes.Index(dir)
+		data, err := index.JSON()
+		if err != nil {
+			return err
+		}
+		name := path.Join(dir, export.WorkspaceIndexName)
+		if err := dest.WriteFile(name+".json", data); err != nil {
+			return fmt.Errorf("failed to write workspace index: %w", err)
+		}
+		if err := dest.WriteFile(name+".md", index.Markdown()); err != nil {
+			return fmt.Errorf("failed to write workspace index: %w", err)
+		}
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
-	exportCmd.Flags().StringVarP(&format, "format", "f", "jsonl", "Export format (jsonl, md, yaml, json)")
-	exportCmd.Flags().StringVarP(&outputDir, "out", "o", "./exports", "Output directory")
+	exportCmd.Flags().StringVarP(&format, "format", "f", "jsonl", "Export format (jsonl, md, yaml, json, parquet, csv, mermaid, dot, specstory-md, events-jsonl)")
+	exportCmd.Flags().StringVarP(&outputDir, "out", "o", defaultOutputDir, "Output directory")
 	exportCmd.Flags().StringVar(&workspace, "workspace", "", "Filter by workspace")
-	exportCmd.Flags().StringVar(&sessionID, "session-id", "", "Export a specific session by ID")
-	exportCmd.Flags().BoolVar(&intermediary, "intermediary", false, "Save intermediary format")
+	exportCmd.Flags().StringVar(&exportFilter, "filter", "", "Only export sessions matching a filter expression, e.g. 'messages>10 && workspace~\"api\"'")
+	exportCmd.Flags().StringVar(&sessionID, "session-id", "", "Export a specific session by ID or unique ID prefix")
+	exportCmd.Flags().StringVar(&exportName, "name", "", "Export a specific session by name (fuzzy match)")
+	exportCmd.Flags().BoolVar(&intermediary, "intermediary", false, "Also write the raw composer, bubbles and contexts of each session (YAML with --format yaml, otherwise JSON)")
 	exportCmd.Flags().BoolVar(&clearCache, "clear-cache", false, "Clear the cache before running")
+	exportCmd.Flags().BoolVar(&mdFrontmatter, "md-frontmatter", false, "Markdown: emit YAML frontmatter with session metadata")
+	exportCmd.Flags().BoolVar(&mdTOC, "md-toc", false, "Markdown: emit a table of contents linking to each message")
+	exportCmd.Flags().StringVar(&exportSince, "since", "", "Only export messages at or after this time (RFC3339 or YYYY-MM-DD)")
+	exportCmd.Flags().StringVar(&exportUntil, "until", "", "Only export messages at or before this time (RFC3339 or YYYY-MM-DD, inclusive of the whole day)")
+	exportCmd.Flags().StringVar(&exportActor, "actor", "all", "Only export messages from this actor (user, assistant, tool, terminal, system, all)")
+	exportCmd.Flags().BoolVar(&skipEmptySessions, "skip-empty-sessions", false, "Skip sessions with no messages left after filtering")
+	exportCmd.Flags().IntVar(&maxMessageBytes, "max-message-bytes", 0, "Bound the content of each message to this many bytes (0 for no limit)")
+	exportCmd.Flags().StringVar(&oversizeStrategy, "oversize-strategy", internal.OversizeTruncate, "What to do with messages over --max-message-bytes ("+strings.Join(internal.OversizeStrategies, ", ")+")")
+	exportCmd.Flags().StringVar(&exportThinking, "thinking", internal.ThinkingInline, "How to write the thinking of messages ("+strings.Join(internal.ThinkingModes, ", ")+")")
+	exportCmd.Flags().IntVar(&splitTurns, "split-turns", 0, "Split sessions into chunks of at most this many turns, each exported as its own session (0 for no limit)")
+	exportCmd.Flags().BoolVar(&splitOnTask, "split-on-task", false, "Start a new chunk where a user message opens a new task (\"New task: ...\", \"Moving on, ...\")")
+	exportCmd.Flags().BoolVar(&includeFailures, "include-parse-failures", false, "Keep a placeholder naming the record and the reason in place of each message that failed to parse, instead of leaving it out")
+	exportCmd.Flags().BoolVar(&includeBranches, "include-branches", false, "Also export the alternate branches of conversations forked by editing a message, as sessions <id>.branch_<n>")
+	exportCmd.Flags().BoolVar(&includeInline, "include-inline", false, "Also export the prompts sent outside the chat panel, such as Cmd-K edits, from the workspace databases as sessions of type inline")
+	exportCmd.Flags().StringVar(&filenameTemplate, "filename-template", export.DefaultFilenameTemplate, "Name of each exported file, without the extension, from {id}, {short_id}, {name}, {workspace}, {date} and {time}")
+	exportCmd.Flags().StringVar(&exportGroupBy, "group-by", "", "Group exported files into a directory per workspace, each with an index.md and index.json of its sessions (workspace)")
+	exportCmd.Flags().BoolVar(&exportOverwrite, "overwrite", false, "Replace files whose name is taken instead of adding a numeric suffix")
+	exportCmd.Flags().BoolVar(&exportSummary, "summary", false, "Write one summary record per session (first prompt, final answer, counts, files, tools) instead of transcripts; jsonl, json or yaml")
+	exportCmd.Flags().BoolVar(&exportResume, "resume", false, "Skip sessions an earlier export to the same directory already wrote and that have not changed since")
+	exportCmd.Flags().BoolVar(&exportForce, "force", false, "Write every session, including those an earlier export to the same directory already wrote unchanged")
+	_ = exportCmd.Flags().MarkDeprecated("resume", "unchanged sessions are skipped by default; use --force to write them again")
+	exportCmd.Flags().BoolVar(&exportReport, "report", false, "Write export-report.json with per-database read and export statistics to the output directory")
+	exportCmd.Flags().StringVar(&exportMetricsFile, "metrics-file", "", "Write the run's metrics (sessions and messages exported, parse failures, duration) to this Prometheus textfile")
+	exportCmd.Flags().StringVar(&exportStatsd, "statsd", "", "Push the run's metrics to the statsd server at this host:port over UDP")
+	exportCmd.Flags().StringSliceVar(&encryptRecipients, "encrypt-recipient", nil, "Encrypt every exported file with age to this X25519 public key (age1...); repeat for several keys")
+	exportCmd.Flags().BoolVar(&failOnSecrets, "fail-on-secrets", false, "Scan sessions for secrets first and export nothing if any are found (see scan)")
+	exportCmd.Flags().StringSliceVar(&secretDetectors, "detectors", nil, "With --fail-on-secrets: detectors to run (default all)")
+	exportCmd.Flags().Float64Var(&entropyThreshold, "entropy-threshold", internal.DefaultEntropyThreshold, "With --fail-on-secrets: entropy threshold of the high-entropy detector")
+	exportCmd.Flags().StringVar(&exportArchive, "archive", "", "Write every exported file into this .tar.gz, .tgz or .zip archive instead of the output directory")
+	exportCmd.Flags().BoolVar(&sinceLastRun, "since-last-run", false, "Only export sessions created or updated since the last successful --since-last-run export from the same storage")
+	exportCmd.Flags().BoolVar(&exportAtomic, "atomic", false, "Stage the export and move it into place only once complete, with a manifest.json listing its files, hashes and counts")
+	exportCmd.Flags().BoolVar(&exportClipboard, "clipboard", false, "Copy the session given by --session-id or --name to the clipboard as Markdown instead of writing files")
+	exportCmd.Flags().StringVar(&exportFields, "fields", "", "With --format json or jsonl: only write these comma-separated fields, such as id,metadata.name,messages.actor,messages.content")
+	exportCmd.Flags().BoolVar(&exportStream, "stream", false, "Write jsonl to stdout as sessions are reconstructed, a session record before the messages of each, in constant memory")
+	exportCmd.Flags().StringVar(&lastRunFile, "last-run-file", "", "File recording the last --since-last-run export of each storage (default last-runs.json in the state directory)")
+	_ = exportCmd.RegisterFlagCompletionFunc("session-id", sessionIDFlag)
+	_ = exportCmd.RegisterFlagCompletionFunc("name", sessionNameFlag)
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+)
+
+// validateStreamFlags checks that the flags of an export are compatible with --stream,
+// which cannot honor those that need every session at once or write files
+func validateStreamFlags() error {
+	if format != "jsonl" {
+		return usageErrorf("--stream requires --format jsonl, not %s", format)
+	}
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--out", outputDir != defaultOutputDir},
+		{"--archive", exportArchive != ""},
+		{"--atomic", exportAtomic},
+		{"--summary", exportSummary},
+		{"--resume", exportResume},
+		{"--force", exportForce},
+		{"--fields", exportFields != ""},
+		{"--since-last-run", sinceLastRun},
+		{"--report", exportReport},
+		{"--group-by", exportGroupBy != ""},
+		{"--filename-template", filenameTemplate != export.DefaultFilenameTemplate},
+		{"--encrypt-recipient", len(encryptRecipients) > 0},
+		{"--intermediary", intermediary},
+		{"--include-branches", includeBranches},
+		{"--include-inline", includeInline},
+		{"--fail-on-secrets", failOnSecrets},
+		{"--session-id", sessionID != ""},
+		{"--name", exportName != ""},
+	}
+	for _, conflict := range conflicts {
+		if conflict.set {
+			return usageErrorf("--stream cannot be combined with %s", conflict.flag)
+		}
+	}
+	if oversizeStrategy == internal.OversizeSidecar {
+		return usageErrorf("--stream cannot write sidecar files; use --oversize-strategy truncate or drop")
+	}
+	return nil
+}
+
+// sessionStream is how a streamed export prepares each session before writing it
+type sessionStream struct {
+	messageFilter internal.MessageFilter
+	messageLimit  internal.MessageLimit
+	thinkingMode  internal.ThinkingMode
+	sessionSplit  internal.SessionSplit
+	sessionFilter *internal.FilterExpr
+}
+
+// streamExport writes the sessions of the storage to out as a JSONL stream while they are
+// reconstructed, without the cache, so that memory use does not grow with the history
+func streamExport(out io.Writer, backend internal.StorageBackend, paths []internal.StoragePaths, stream sessionStream, started time.Time) error {
+	writer := export.NewJSONLStreamWriter(out)
+	tags := loadTagStore()
+
+	var metrics internal.ExportMetrics
+	err := internal.StreamSessions(backend, paths, workspace, func(session *internal.Session) error {
+		sessions := []*internal.Session{session}
+		hideGeneratedTitles(sessions)
+		if tags != nil {
+			tags.Apply(sessions)
+		}
+		if workspace != "" && session.Workspace != workspace {
+			return nil
+		}
+		if stream.sessionFilter != nil && !stream.sessionFilter.MatchSession(session) {
+			return nil
+		}
+
+		session = stream.messageFilter.Apply(session)
+		if skipEmptySessions && len(session.Messages) == 0 {
+			internal.LogDebug("Skipping session %s with no matching messages", session.ID)
+			return nil
+		}
+		for _, chunk := range stream.sessionSplit.Apply(session) {
+			thinking := internal.ThinkingTokens(chunk)
+			limited, _ := stream.messageLimit.Apply(stream.thinkingMode.Apply(chunk))
+			if err := writer.WriteSession(limited); err != nil {
+				return fmt.Errorf("failed to write session %s: %w", chunk.ID, err)
+			}
+			metrics.SessionsExported++
+			metrics.MessagesExported += len(limited.Messages)
+			metrics.ThinkingTokens += thinking
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	internal.LogInfo("Streamed %d session(s) with %d message(s)", metrics.SessionsExported, metrics.MessagesExported)
+
+	if exportMetricsFile != "" || exportStatsd != "" {
+		metrics.ParseFailures = internal.GetParseStats().ParseFailures
+		metrics.Duration = time.Since(started)
+		metrics.FinishedAt = time.Now()
+		return emitExportMetrics(metrics)
+	}
+	return nil
+}
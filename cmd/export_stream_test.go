@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestExportCommand_Stream(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	writeSessionFixtures(t, dir,
+		internal.CreateTestSessionWithMessages("stream-a", []internal.Message{
+			{Actor: "user", Content: "First question"},
+			{Actor: "assistant", Content: "First answer"},
+		}),
+		internal.CreateTestSessionWithMessages("stream-b", []internal.Message{
+			{Actor: "user", Content: "Second question"},
+		}),
+	)
+
+	var out bytes.Buffer
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--stream", "--actor", "user"})
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export --stream error = %v", err)
+	}
+
+	var types, ids []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record struct {
+			Type      string `json:"type"`
+			SessionID string `json:"session_id"`
+			Actor     string `json:"actor"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("stdout holds a line that is not JSON: %q", line)
+		}
+		if record.Type == "message" && record.Actor != "user" {
+			t.Errorf("streamed a message of %s despite --actor user", record.Actor)
+		}
+		types = append(types, record.Type)
+		ids = append(ids, record.SessionID)
+	}
+	if got := strings.Join(types, ","); got != "session,message,session,message" {
+		t.Errorf("record types = %s, want a session record before the messages of each", got)
+	}
+	if got := strings.Join(ids, ","); got != "stream-a,stream-a,stream-b,stream-b" {
+		t.Errorf("record session IDs = %s", got)
+	}
+}
+
+func TestExportCommand_StreamConflicts(t *testing.T) {
+	resetExportFlags(t)
+	for _, args := range [][]string{
+		{"--format", "md"},
+		{"--out", "elsewhere"},
+		{"--summary"},
+		{"--atomic"},
+		{"--oversize-strategy", "sidecar"},
+	} {
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--stream"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if code := exitCode(rootCmd.Execute()); code != exitUsage {
+			t.Errorf("export --stream %v exit code = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -1,10 +1,60 @@
 package cmd
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/iksnae/cursor-session/testutil"
+	"github.com/spf13/pflag"
 )
 
+// clearExportFlags resets --storage and every export flag to its default; rootCmd keeps
+// flag values between executions
+func clearExportFlags() {
+	storagePaths = nil
+	exportCmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+// resetExportFlags clears the export flags now and again when the test ends
+func resetExportFlags(t *testing.T) {
+	t.Helper()
+	clearExportFlags()
+	t.Cleanup(clearExportFlags)
+}
+
+// writeSessionFixtures writes each session into dir as an exported session_<id>.json, so
+// dir can be read with --storage
+func writeSessionFixtures(t *testing.T, dir string, sessions ...*internal.Session) {
+	t.Helper()
+	for _, session := range sessions {
+		if err := os.WriteFile(filepath.Join(dir, "session_"+session.ID+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+}
+
 func TestExportCommand(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -16,10 +66,22 @@ func TestExportCommand(t *testing.T) {
 			args:    []string{"export", "--format", "invalid"},
 			wantErr: true, // Invalid format should error
 		},
+		{
+			name:    "export with invalid actor",
+			args:    []string{"export", "--actor", "tool"},
+			wantErr: true,
+		},
+		{
+			name:    "export with invalid since",
+			args:    []string{"export", "--since", "yesterday"},
+			wantErr: true,
+		},
 		// Note: Other tests may succeed if a real database exists
 		// We test the flag parsing and error handling paths
 	}
 
+	resetExportFlags(t)
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			rootCmd.SetArgs(tt.args)
@@ -56,6 +118,10 @@ func TestExportCommand_FlagParsing(t *testing.T) {
 			name: "session-id flag",
 			args: []string{"export", "--session-id", "test-session-id"},
 		},
+		{
+			name: "name flag",
+			args: []string{"export", "--name", "fix flaky tests"},
+		},
 		{
 			name: "clear-cache flag",
 			args: []string{"export", "--clear-cache"},
@@ -78,3 +144,1321 @@ func TestExportCommand_FlagParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestExportCommand_MultipleStorage(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	resetExportFlags(t)
+
+	// Two copied database directories, as collected from separate CI jobs; commas in a
+	// path are part of it
+	var dirs []string
+	for _, id := range []string{"job1", "job2"} {
+		dir := filepath.Join(testutil.CreateTempDir(t), id+",attempt 2", "globalStorage")
+		dbPath := filepath.Join(dir, "state.vscdb")
+		testutil.CreateSQLiteFixture(t, dbPath)
+
+		db, err := sql.Open("sqlite", dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open fixture: %v", err)
+		}
+		testutil.InsertComposer(t, db, "composerData:"+id, fmt.Sprintf(`{"composerId":%q,"fullConversationHeadersOnly":[{"bubbleId":"%s-b1","type":1}]}`, id, id))
+		testutil.InsertBubble(t, db, "bubbleId:"+id+":"+id+"-b1", fmt.Sprintf(`{"bubbleId":"%s-b1","text":"hello from %s","type":1}`, id, id))
+		_ = db.Close()
+		dirs = append(dirs, dir)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "repeated", args: []string{"--storage", dirs[0], "--storage", dirs[1]}},
+		{name: "flag syntax", args: []string{"--storage=" + dirs[0], "--storage=" + dirs[1]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Flags persist between executions of the root command
+			clearExportFlags()
+			out := testutil.CreateTempDir(t)
+			rootCmd.SetArgs(append([]string{"export", "--format", "json", "--out", out}, tt.args...))
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("export error = %v", err)
+			}
+
+			for _, id := range []string{"job1", "job2"} {
+				if _, err := os.Stat(filepath.Join(out, "session_"+id+".json")); err != nil {
+					t.Errorf("session %s was not exported: %v", id, err)
+				}
+			}
+		})
+	}
+
+	// Sessions combined from several locations must not replace the single-storage cache
+	if _, err := os.Stat(filepath.Join(testCacheDir(t), "sessions.yaml")); !os.IsNotExist(err) {
+		t.Errorf("combined export should not write the cache index, stat error = %v", err)
+	}
+}
+
+func TestExportCommand_StorageArchive(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	// A globalStorage directory downloaded from CI as a tarball of its User directory
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	testutil.InsertComposer(t, db, "composerData:ci", `{"composerId":"ci","fullConversationHeadersOnly":[{"bubbleId":"b1","type":1}]}`)
+	testutil.InsertBubble(t, db, "bubbleId:ci:b1", `{"bubbleId":"b1","text":"hello from CI","type":1}`)
+	_ = db.Close()
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(testutil.CreateTempDir(t), "cursor-user.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	_ = tw.WriteHeader(&tar.Header{Name: "User/globalStorage/state.vscdb", Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(data)
+	_ = tw.Close()
+	_ = gzw.Close()
+	_ = f.Close()
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", archive, "--format", "json", "--out", out})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_ci.json")); err != nil {
+		t.Errorf("session in the archive was not exported: %v", err)
+	}
+
+	// The extracted copy is removed once the command is done
+	extracted := storagePaths[0]
+	if extracted == archive {
+		t.Fatal("--storage still names the archive, want the extracted copy")
+	}
+	if _, err := os.Stat(extracted); !os.IsNotExist(err) {
+		t.Errorf("extracted archive %s was left behind", extracted)
+	}
+}
+
+func TestExportCommand_Report(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	// A cursor-agent session directory: chats/<hash>/<session-id>/store.db
+	chats := filepath.Join(testutil.CreateTempDir(t), "chats")
+	dbPath := filepath.Join(chats, "workspace-hash", "agent-session", "store.db")
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatalf("Failed to create session directory: %v", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE blobs (id TEXT PRIMARY KEY, data BLOB)`,
+		`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`,
+		`INSERT INTO blobs VALUES ('m1', '{"id":"m1","role":"user","content":"report this"}')`,
+		`INSERT INTO blobs VALUES ('bad', 'not json')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to build fixture: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", chats, "--format", "json", "--out", out, "--report"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "export-report.json"))
+	if err != nil {
+		t.Fatalf("export-report.json was not written: %v", err)
+	}
+	var report struct {
+		SessionsExported int `json:"sessions_exported"`
+		Databases        []struct {
+			Path              string `json:"path"`
+			Blobs             int    `json:"blobs"`
+			BlobParseFailures int    `json:"blob_parse_failures"`
+		} `json:"databases"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("export-report.json is not valid JSON: %v", err)
+	}
+	if len(report.Databases) != 1 || report.Databases[0].Path != dbPath || report.Databases[0].Blobs != 2 || report.Databases[0].BlobParseFailures != 1 {
+		t.Errorf("report databases = %+v, want %s with 2 blobs and 1 parse failure", report.Databases, dbPath)
+	}
+}
+
+func TestExportCommand_Archive(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	archiveDir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSession("archived-session")
+	writeSessionFixtures(t, archiveDir, session)
+
+	out := filepath.Join(testutil.CreateTempDir(t), "unused")
+	archive := filepath.Join(testutil.CreateTempDir(t), "sessions.zip")
+	rootCmd.SetArgs([]string{"export", "--storage", archiveDir, "--format", "json", "--out", out, "--archive", archive})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("archive was not written: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	if len(r.File) != 1 || r.File[0].Name != "session_archived-session.json" {
+		t.Errorf("archive files = %v, want session_archived-session.json", r.File)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("--archive should not create the output directory, stat error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"export", "--storage", archiveDir, "--archive", "sessions.rar"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export --archive sessions.rar error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_Atomic(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"first", "second"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		writeSessionFixtures(t, dir, session)
+	}
+
+	parent := testutil.CreateTempDir(t)
+	out := filepath.Join(parent, "out")
+	run := func(args ...string) {
+		t.Helper()
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out, "--atomic"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+	}
+	readManifest := func(path string) export.Manifest {
+		t.Helper()
+		var manifest export.Manifest
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("manifest was not written: %v", err)
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatalf("failed to parse manifest: %v", err)
+		}
+		return manifest
+	}
+
+	run()
+	manifest := readManifest(filepath.Join(out, export.ManifestFile))
+	if manifest.Counts.SessionsExported != 2 || manifest.Counts.MessagesExported != 2 || manifest.Format != "jsonl" {
+		t.Errorf("manifest counts = %+v, format %s, want 2 sessions and 2 messages of jsonl", manifest.Counts, manifest.Format)
+	}
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	want := internal.ExportProgressFile + ",session_first.jsonl,session_second.jsonl"
+	if strings.Join(paths, ",") != want {
+		t.Errorf("manifest files = %v, want %s", paths, want)
+	}
+
+	// A resumed export reads the files of the previous one from the staging directory
+	run("--resume")
+	manifest = readManifest(filepath.Join(out, export.ManifestFile))
+	if manifest.Counts.SessionsSkipped != 2 || manifest.Counts.Files != 3 {
+		t.Errorf("resumed manifest counts = %+v, want 2 sessions skipped and 3 files", manifest.Counts)
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%s holds %d entries, want only the output directory", parent, len(entries))
+	}
+
+	// --out . stages next to the working directory instead of inside it
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(out); err != nil {
+		t.Fatal(err)
+	}
+	run("--out", ".")
+	manifest = readManifest(filepath.Join(out, export.ManifestFile))
+	if manifest.Counts.SessionsExported != 2 {
+		t.Errorf("manifest counts of --out . = %+v, want 2 sessions exported", manifest.Counts)
+	}
+	if entries, err := os.ReadDir(parent); err != nil || len(entries) != 1 {
+		t.Errorf("%s holds %v after --out ., want only the output directory", parent, entries)
+	}
+	if entries, err := os.ReadDir(out); err != nil || len(entries) != 4 {
+		t.Errorf("--out . left %v in the output directory, want the 3 exported files and the manifest", entries)
+	}
+
+	archive := filepath.Join(parent, "sessions.tar.gz")
+	run("--archive", archive)
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("archive was not written: %v", err)
+	}
+}
+
+func TestExportCommand_FailOnSecrets(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+	dir, _ := writeLeakySessions(t)
+
+	out := filepath.Join(testutil.CreateTempDir(t), "exports")
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--format", "json", "--out", out, "--fail-on-secrets"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); exitCode(err) != exitSecretsFound {
+		t.Fatalf("export --fail-on-secrets error = %v, want exit code %d", err, exitSecretsFound)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("export --fail-on-secrets wrote files despite findings, stat error = %v", err)
+	}
+}
+
+func TestExportCommand_MaxMessageBytes(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	toolOutput := strings.Repeat("line of tool output\n", 100)
+	session := internal.CreateTestSessionWithMessages("big-session", []internal.Message{
+		{Actor: "user", Content: "Run the tests"},
+		{Actor: "assistant", Content: toolOutput},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--max-message-bytes", "100", "--oversize-strategy", "sidecar"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	sidecar, err := os.ReadFile(filepath.Join(out, "session_big-session.message_2.txt"))
+	if err != nil || string(sidecar) != toolOutput {
+		t.Errorf("sidecar = %d bytes, %v, want the full tool output", len(sidecar), err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "session_big-session.jsonl"))
+	if err != nil {
+		t.Fatalf("export was not written: %v", err)
+	}
+	if strings.Contains(string(data), "line of tool output") || !strings.Contains(string(data), `"original_bytes":2000`) {
+		t.Errorf("export should reference the sidecar instead of the content, got:\n%s", data)
+	}
+
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--max-message-bytes", "100", "--oversize-strategy", "compress"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export --oversize-strategy compress error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_Thinking(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("thinking-session", []internal.Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Thinking: "The linker flags look wrong"},
+		{Actor: "assistant", Content: "Fixed the flags", Thinking: "Check the Makefile"},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	tests := []struct {
+		mode     string
+		want     []string
+		messages int
+	}{
+		{internal.ThinkingInline, []string{`"content":"[thinking]\nCheck the Makefile\n\nFixed the flags"`}, 3},
+		{internal.ThinkingSeparate, []string{`"content":"Fixed the flags","thinking":"Check the Makefile"`, `"thinking":"The linker flags look wrong"`}, 3},
+		{internal.ThinkingStrip, []string{`"content":"Fixed the flags"`}, 2},
+	}
+	for _, tt := range tests {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--format", "json", "--thinking", tt.mode})
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export --thinking %s error = %v", tt.mode, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, "session_thinking-session.json"))
+		if err != nil {
+			t.Fatalf("export was not written: %v", err)
+		}
+		var exported internal.Session
+		if err := json.Unmarshal(data, &exported); err != nil {
+			t.Fatalf("export is not a session: %v", err)
+		}
+		compact, _ := json.Marshal(exported.Messages)
+		for _, want := range tt.want {
+			if !strings.Contains(string(compact), want) {
+				t.Errorf("--thinking %s messages should contain %s, got:\n%s", tt.mode, want, compact)
+			}
+		}
+		if len(exported.Messages) != tt.messages {
+			t.Errorf("--thinking %s exported %d message(s), want %d", tt.mode, len(exported.Messages), tt.messages)
+		}
+		if tt.mode == internal.ThinkingStrip && strings.Contains(string(data), "Makefile") {
+			t.Errorf("--thinking strip should leave the thinking out, got:\n%s", data)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--thinking", "hidden"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export --thinking hidden error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_Fields(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("projected", []internal.Message{
+		{Actor: "user", Content: "Hello", Timestamp: "2024-06-03T09:00:00Z"},
+	})
+	session.Metadata.Name = "Greeting"
+	writeSessionFixtures(t, dir, session)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{"id":"projected","messages":[{"actor":"user","content":"Hello"}],"metadata":{"name":"Greeting"}}`},
+		{"jsonl", `{"actor":"user","content":"Hello","session":{"id":"projected","metadata":{"name":"Greeting"}}}`},
+	}
+	for _, tt := range tests {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--format", tt.format, "--fields", "id,name,messages.actor,messages.text"})
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export --format %s --fields error = %v", tt.format, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, "session_projected."+tt.format))
+		if err != nil {
+			t.Fatalf("export was not written: %v", err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			t.Fatalf("export is not JSON: %v\n%s", err, data)
+		}
+		if compact.String() != tt.want {
+			t.Errorf("--format %s --fields wrote %s, want %s", tt.format, compact.String(), tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--format", "md", "--fields", "id"},
+		{"--format", "json", "--fields", "title"},
+		{"--format", "json", "--fields", "id", "--summary"},
+	} {
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir}, args...))
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("export %v error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestExportCommand_Attachments(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	image := []byte("\x89PNG\r\n\x1a\n")
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("image-session", []internal.Message{
+		{Actor: "user", Content: "Why is this red?", Attachments: []internal.Attachment{
+			{Name: "image_1_1.png", MimeType: "image/png", Data: image},
+			{Name: "image_1_2.png", Source: filepath.Join(dir, "deleted.png")},
+		}},
+		{Actor: "assistant", Content: "The border is set twice"},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--format", "md"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	written, err := os.ReadFile(filepath.Join(out, "assets", "image-session", "image_1_1.png"))
+	if err != nil || string(written) != string(image) {
+		t.Errorf("image file = %q, %v, want the attached image", written, err)
+	}
+	markdown, err := os.ReadFile(filepath.Join(out, "session_image-session.md"))
+	if err != nil {
+		t.Fatalf("export was not written: %v", err)
+	}
+	for _, want := range []string{"![image_1_1.png](<assets/image-session/image_1_1.png>)", "*Image image_1_2.png not exported*"} {
+		if !strings.Contains(string(markdown), want) {
+			t.Errorf("markdown should contain %q, got:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestExportCommand_SingleSessionLoadsLazily(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	resetExportFlags(t)
+
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	dbPath := filepath.Join(dir, "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	for _, id := range []string{"alpha", "beta"} {
+		testutil.InsertComposer(t, db, "composerData:"+id, fmt.Sprintf(`{"composerId":%q,"fullConversationHeadersOnly":[{"bubbleId":"%s-b1","type":1}]}`, id, id))
+		testutil.InsertBubble(t, db, "bubbleId:"+id+":"+id+"-b1", fmt.Sprintf(`{"bubbleId":"%s-b1","text":"hello from %s","type":1}`, id, id))
+	}
+	_ = db.Close()
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--format", "json", "--out", out, "--session-id", "alp"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "session_alpha.json"))
+	if err != nil || !strings.Contains(string(data), "hello from alpha") {
+		t.Errorf("session alpha was not exported: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_beta.json")); !os.IsNotExist(err) {
+		t.Errorf("only the requested session should be exported, stat error = %v", err)
+	}
+	// A single session must not replace the cache of every session
+	if _, err := os.Stat(filepath.Join(testCacheDir(t), "sessions.yaml")); !os.IsNotExist(err) {
+		t.Errorf("single-session export should not write the cache index, stat error = %v", err)
+	}
+}
+
+func TestExportCommand_IncludeBranches(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	resetExportFlags(t)
+
+	// A conversation whose second message was edited, forking it
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	dbPath := filepath.Join(dir, "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	testutil.InsertComposer(t, db, "composerData:forked", `{"composerId":"forked","fullConversationHeadersOnly":[`+
+		`{"bubbleId":"u1","type":1},{"bubbleId":"a1","type":2},`+
+		`{"bubbleId":"u2","type":1},{"bubbleId":"a2","type":2},`+
+		`{"bubbleId":"u3","type":1,"parentBubbleId":"a1"},{"bubbleId":"a3","type":2}]}`)
+	for id, text := range map[string]string{
+		"u1": "write a parser", "a1": "here is a parser",
+		"u2": "make it faster", "a2": "original answer",
+		"u3": "make it streaming", "a3": "edited answer",
+	} {
+		testutil.InsertBubble(t, db, "bubbleId:forked:"+id, fmt.Sprintf(`{"bubbleId":%q,"text":%q,"timestamp":1000}`, id, text))
+	}
+	_ = db.Close()
+
+	for _, include := range []bool{false, true} {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		args := []string{"export", "--storage", dir, "--format", "json", "--out", out, "--session-id", "fork"}
+		if include {
+			args = append(args, "--include-branches")
+		}
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, "session_forked.json"))
+		if err != nil {
+			t.Fatalf("session was not exported: %v", err)
+		}
+		if !strings.Contains(string(data), "edited answer") || strings.Contains(string(data), "original answer") {
+			t.Errorf("export should follow the active branch, got:\n%s", data)
+		}
+
+		data, err = os.ReadFile(filepath.Join(out, "session_forked.branch_1.json"))
+		if !include {
+			if err == nil {
+				t.Error("alternate branches should only be exported with --include-branches")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("--include-branches should export the alternate branch: %v", err)
+		}
+		if !strings.Contains(string(data), "original answer") || !strings.Contains(string(data), `"session_id": "forked"`) {
+			t.Errorf("alternate branch = %s, want the original answer linked to forked", data)
+		}
+	}
+
+	// Alternate branches are not cached for other commands
+	if _, err := os.Stat(filepath.Join(testCacheDir(t), "sessions.yaml")); !os.IsNotExist(err) {
+		t.Errorf("export --include-branches should not write the cache index, stat error = %v", err)
+	}
+}
+
+func TestExportCommand_IncludeInline(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	base := testutil.CreateTempDir(t)
+	dir := filepath.Join(base, "globalStorage")
+	testutil.CreateSQLiteFixture(t, filepath.Join(dir, "state.vscdb"))
+	wsDir := testutil.CreateWorkspaceFixture(t, base, "ws1")
+	db, err := sql.Open("sqlite", filepath.Join(wsDir, "state.vscdb"))
+	if err != nil {
+		t.Fatalf("Failed to open workspace database: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)`,
+		`INSERT INTO ItemTable (key, value) VALUES ('aiService.generations', '[{"unixMs":1700000200000,"generationUUID":"g2","type":"cmdk","textDescription":"add error handling"}]')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to prepare workspace database: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	for _, include := range []bool{false, true} {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		args := []string{"export", "--storage", dir, "--format", "json", "--out", out}
+		if include {
+			args = append(args, "--include-inline")
+		}
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, "session_g2.json"))
+		if !include {
+			if err == nil {
+				t.Error("inline prompts should only be exported with --include-inline")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("--include-inline should export the inline prompt: %v", err)
+		}
+		if !strings.Contains(string(data), `"type": "inline"`) || !strings.Contains(string(data), "add error handling") {
+			t.Errorf("inline session = %s, want the prompt with type inline", data)
+		}
+	}
+}
+
+func TestExportCommand_Filter(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"kept", "dropped"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		writeSessionFixtures(t, dir, session)
+	}
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--filter", `id ~ "KEP" && messages > 0`})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export --filter error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_kept.jsonl")); err != nil {
+		t.Errorf("the matching session was not exported: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_dropped.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("the session not matching --filter was exported, stat error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--filter", "messages >"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export with an invalid --filter error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_Split(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("long", []internal.Message{
+		{Actor: "user", Content: "Add a flag"},
+		{Actor: "assistant", Content: "Done"},
+		{Actor: "user", Content: "Document it"},
+		{Actor: "assistant", Content: "Done"},
+		{Actor: "user", Content: "New task: fix CI"},
+		{Actor: "assistant", Content: "Fixed"},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--format", "json", "--split-on-task"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export --split-on-task error = %v", err)
+	}
+
+	for i, want := range []int{4, 2} {
+		data, err := os.ReadFile(filepath.Join(out, "session_long.chunk_"+strconv.Itoa(i+1)+".json"))
+		if err != nil {
+			t.Fatalf("chunk %d was not exported: %v", i+1, err)
+		}
+		var chunk internal.Session
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			t.Fatalf("chunk %d is not a JSON session: %v", i+1, err)
+		}
+		if len(chunk.Messages) != want || chunk.Metadata.Chunk == nil || chunk.Metadata.Chunk.SessionID != "long" {
+			t.Errorf("chunk %d has %d message(s) and chunk metadata %+v, want %d messages of long", i+1, len(chunk.Messages), chunk.Metadata.Chunk, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_long.json")); !os.IsNotExist(err) {
+		t.Errorf("a split session should only be exported as chunks, stat error = %v", err)
+	}
+
+	clearExportFlags()
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--split-turns", "2", "--intermediary"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export --split-turns --intermediary error = %v, want a usage error", err)
+	}
+	clearExportFlags()
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--split-turns", "-1"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export --split-turns -1 error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_SkipUnchanged(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	writeSession := func(id, content string) {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: content}})
+		writeSessionFixtures(t, dir, session)
+	}
+	writeSession("first", "Hello from first")
+	writeSession("second", "Hello from second")
+
+	out := testutil.CreateTempDir(t)
+	run := func(args ...string) {
+		t.Helper()
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out, "--clear-cache"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+	}
+	readExport := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(out, name))
+		return string(data)
+	}
+	// age sets the time of the files back, so the ones written again can be told apart
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	age := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if err := os.Chtimes(filepath.Join(out, name), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	written := func(name string) bool {
+		info, err := os.Stat(filepath.Join(out, name))
+		return err == nil && !info.ModTime().Equal(old)
+	}
+
+	run()
+	run("--format", "md")
+	progress, err := internal.LoadExportState(filepath.Join(out, internal.ExportProgressFile))
+	if err != nil || len(progress.Outputs) != 2 {
+		t.Fatalf("progress manifest = %+v, %v, want the sessions of 2 formats", progress, err)
+	}
+	progress.SetFormat("jsonl")
+	if len(progress.Sessions) != 2 || len(progress.Hashes) != 2 {
+		t.Fatalf("jsonl progress = %+v, want 2 sessions with their hashes", progress)
+	}
+
+	// Nothing changed, in either format
+	age("session_first.jsonl", "session_second.jsonl", "session_first.md", "session_second.md")
+	run()
+	run("--format", "md")
+	for _, name := range []string{"session_first.jsonl", "session_second.jsonl", "session_first.md", "session_second.md"} {
+		if written(name) {
+			t.Errorf("exporting again should skip unchanged %s", name)
+		}
+	}
+
+	// A changed session, a missing file and a file edited since are written again
+	writeSession("second", "Hello again from second")
+	if err := os.Remove(filepath.Join(out, "session_first.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "session_first.md"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	age("session_first.md")
+	run()
+	run("--format", "md")
+	if !strings.Contains(readExport("session_second.jsonl"), "Hello again") || !strings.Contains(readExport("session_second.md"), "Hello again") {
+		t.Error("exporting again should write the changed session in each format")
+	}
+	if !strings.Contains(readExport("session_first.jsonl"), "Hello from first") {
+		t.Errorf("exporting again should write a session whose file is missing, got %q", readExport("session_first.jsonl"))
+	}
+	if !strings.Contains(readExport("session_first.md"), "Hello from first") {
+		t.Errorf("exporting again should write a session whose file was edited, got %q", readExport("session_first.md"))
+	}
+
+	// Options that change how an unchanged session is written count as part of the format
+	age("session_first.md", "session_second.md")
+	run("--format", "md", "--md-toc")
+	if !written("session_first.md") || !written("session_second.md") {
+		t.Error("exporting with --md-toc should write every session again")
+	}
+
+	// So does a change to a session outside its messages
+	session := internal.CreateTestSessionWithMessages("first", []internal.Message{{Actor: "user", Content: "Hello from first"}})
+	session.Metadata.ParentID = "parent-session"
+	writeSessionFixtures(t, dir, session)
+	age("session_first.jsonl", "session_second.jsonl")
+	run()
+	if !written("session_first.jsonl") || written("session_second.jsonl") {
+		t.Error("exporting again should write only the session given a parent")
+	}
+
+	// --force writes every session, and the deprecated --resume still skips
+	age("session_first.jsonl", "session_second.jsonl")
+	run("--resume")
+	if written("session_first.jsonl") {
+		t.Error("--resume should skip the unchanged session")
+	}
+	run("--force")
+	if !written("session_first.jsonl") || !written("session_second.jsonl") {
+		t.Error("--force should write every session")
+	}
+
+	for _, args := range [][]string{
+		{"--resume", "--archive", filepath.Join(out, "out.zip")},
+		{"--force", "--summary"},
+		{"--force", "--resume"},
+	} {
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir}, args...))
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("export %v error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestExportCommand_DeterministicOrder(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for id, createdAt := range map[string]string{"b": "2025-01-02T00:00:00Z", "c": "2025-01-01T00:00:00Z", "a": "2025-01-02T00:00:00Z"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		session.Metadata.CreatedAt = createdAt
+		writeSessionFixtures(t, dir, session)
+	}
+
+	run := func() string {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--clear-cache", "--summary"})
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export --summary error = %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(out, "summaries.jsonl"))
+		if err != nil {
+			t.Fatalf("Failed to read summaries: %v", err)
+		}
+		return string(data)
+	}
+
+	first := run()
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(first), "\n") {
+		var summary internal.SessionSummary
+		if err := json.Unmarshal([]byte(line), &summary); err != nil {
+			t.Fatalf("Failed to parse summary %q: %v", line, err)
+		}
+		ids = append(ids, summary.ID)
+	}
+	if want := []string{"c", "a", "b"}; !slices.Equal(ids, want) {
+		t.Errorf("exported sessions = %v, want them ordered by creation time and then ID %v", ids, want)
+	}
+	if second := run(); second != first {
+		t.Errorf("two exports of the same data differ:\n%s\n%s", first, second)
+	}
+}
+
+func TestExportCommand_Summary(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("summarized", []internal.Message{
+		{Actor: "user", Content: "Run the tests", Timestamp: "2025-01-01T10:00:00Z"},
+		{Actor: "assistant", Content: "[Tool Call]\nTool: run_terminal_cmd", Timestamp: "2025-01-01T10:00:10Z"},
+		{Actor: "assistant", Content: "All tests pass.", Timestamp: "2025-01-01T10:01:00Z"},
+	})
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--summary"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "summaries.jsonl"))
+	if err != nil {
+		t.Fatalf("summaries were not written: %v", err)
+	}
+	var summary internal.SessionSummary
+	if err := json.Unmarshal(bytes.TrimSpace(data), &summary); err != nil {
+		t.Fatalf("summaries.jsonl is not one JSON record: %v\n%s", err, data)
+	}
+	if summary.ID != "summarized" || summary.FinalAnswer != "All tests pass." || summary.DurationSeconds != 60 || summary.ToolUsage["run_terminal_cmd"] != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_summarized.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("--summary should not write transcripts, stat error = %v", err)
+	}
+
+	// Summaries can't be written as Markdown
+	clearExportFlags()
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--summary", "--format", "md"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("--summary --format md error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_Metrics(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"first", "second"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{
+			{Actor: "user", Content: "Hello from " + id},
+			{Actor: "assistant", Content: "Hi"},
+		})
+		writeSessionFixtures(t, dir, session)
+	}
+
+	statsd, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = statsd.Close() }()
+
+	out := testutil.CreateTempDir(t)
+	metricsFile := filepath.Join(testutil.CreateTempDir(t), "cursor_session.prom")
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--clear-cache", "--metrics-file", metricsFile, "--statsd", statsd.LocalAddr().String()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	data, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatalf("Failed to read metrics file: %v", err)
+	}
+	for _, want := range []string{
+		"cursor_session_export_sessions_exported 2\n",
+		"cursor_session_export_messages_exported 4\n",
+		"cursor_session_export_parse_failures 0\n",
+		"cursor_session_export_duration_seconds ",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Metrics file should contain %q, got:\n%s", want, data)
+		}
+	}
+
+	buf := make([]byte, 1024)
+	_ = statsd.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := statsd.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read statsd packet: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), "cursor_session.export.sessions_exported:2|c\n") {
+		t.Errorf("statsd packet = %q", buf[:n])
+	}
+}
+
+func TestExportCommand_FilenameCollisions(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	// Both sessions are from the same workspace
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"first", "second"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		session.Workspace = "/src/api"
+		writeSessionFixtures(t, dir, session)
+	}
+
+	run := func(out string, args ...string) []string {
+		t.Helper()
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out, "--clear-cache"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+		files, _ := filepath.Glob(filepath.Join(out, "*.jsonl"))
+		for i := range files {
+			files[i] = filepath.Base(files[i])
+		}
+		return files
+	}
+
+	out := testutil.CreateTempDir(t)
+	if err := os.WriteFile(filepath.Join(out, "api.jsonl"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"api-2.jsonl", "api-3.jsonl", "api.jsonl"}
+	if files := run(out, "--filename-template", "{workspace}"); !slices.Equal(files, want) {
+		t.Errorf("export with colliding names wrote %v, want %v", files, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "api.jsonl")); string(data) != "mine\n" {
+		t.Errorf("export should not replace an unrelated file, got %q", data)
+	}
+
+	// Exporting again reuses the names recorded in the progress manifest
+	if files := run(out, "--filename-template", "{workspace}"); !slices.Equal(files, want) {
+		t.Errorf("second export wrote %v, want %v", files, want)
+	}
+
+	overwritten := testutil.CreateTempDir(t)
+	if files := run(overwritten, "--filename-template", "{workspace}", "--overwrite"); !slices.Equal(files, []string{"api.jsonl"}) {
+		t.Errorf("export --overwrite wrote %v, want [api.jsonl]", files)
+	}
+
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--filename-template", "{title}"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("export with an unknown placeholder error = %v, want a usage error", err)
+	}
+}
+
+func TestExportCommand_SpecStory(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("abc", []internal.Message{{Actor: "user", Content: "Fix the parser"}, {Actor: "assistant", Content: "Done"}})
+	session.Metadata.Name = "Fix the parser"
+	session.Metadata.CreatedAt = "2024-06-01T09:30:00Z"
+	writeSessionFixtures(t, dir, session)
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--format", "specstory-md", "--clear-cache"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export --format specstory-md error = %v", err)
+	}
+
+	// Files are named like SpecStory's own unless a template is given
+	data, err := os.ReadFile(filepath.Join(out, "2024-06-01_09-30Z-fix-the-parser.md"))
+	if err != nil {
+		t.Fatalf("export did not write the SpecStory file name: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "<!-- Generated by SpecStory -->") || !strings.Contains(string(data), "_**Assistant") {
+		t.Errorf("export wrote\n%s\nwant the SpecStory layout", data)
+	}
+}
+
+func TestExportCommand_GroupByWorkspace(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for id, ws := range map[string]string{"first": "/src/api", "second": "/src/api", "third": ""} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		session.Workspace = ws
+		writeSessionFixtures(t, dir, session)
+	}
+
+	out := testutil.CreateTempDir(t)
+	rootCmd.SetArgs([]string{"export", "--storage", dir, "--out", out, "--clear-cache", "--group-by", "workspace"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export --group-by workspace error = %v", err)
+	}
+
+	for _, name := range []string{
+		"api/session_first.jsonl",
+		"api/session_second.jsonl",
+		"api/index.md",
+		"no-workspace/session_third.jsonl",
+		"no-workspace/index.json",
+	} {
+		if !fileExists(filepath.Join(out, name)) {
+			t.Errorf("export --group-by workspace should write %s", name)
+		}
+	}
+	if fileExists(filepath.Join(out, "session_first.jsonl")) {
+		t.Error("export --group-by workspace should not write sessions into the output directory itself")
+	}
+
+	var index export.WorkspaceIndex
+	data, err := os.ReadFile(filepath.Join(out, "api", "index.json"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Failed to parse index: %v", err)
+	}
+	if index.Workspace != "/src/api" || len(index.Sessions) != 2 || index.Sessions[0].File != "session_first.jsonl" {
+		t.Errorf("api index = %+v, want the two /src/api sessions", index)
+	}
+
+	for _, args := range [][]string{{"--group-by", "date"}, {"--group-by", "workspace", "--summary"}} {
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out}, args...))
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("export %v error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestExportCommand_SinceLastRun(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	writeSession := func(id string, updated time.Time) {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		session.Metadata.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		writeSessionFixtures(t, dir, session)
+	}
+	writeSession("old", time.Now().Add(-48*time.Hour))
+	writeSession("recent", time.Now().Add(-time.Hour))
+
+	stateFile := filepath.Join(testutil.CreateTempDir(t), "runs.json")
+	run := func(args ...string) []string {
+		t.Helper()
+		out := testutil.CreateTempDir(t)
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out, "--clear-cache"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+		matches, _ := filepath.Glob(filepath.Join(out, "session_*.jsonl"))
+		var names []string
+		for _, m := range matches {
+			names = append(names, filepath.Base(m))
+		}
+		return names
+	}
+
+	// The first run exports everything and records when it started
+	if got := run("--since-last-run", "--last-run-file", stateFile); len(got) != 2 {
+		t.Fatalf("first --since-last-run exported %v, want both sessions", got)
+	}
+	runs, err := internal.LoadLastRuns(stateFile)
+	if err != nil || len(runs.Storage) != 1 {
+		t.Fatalf("last runs = %+v, %v, want one storage recorded", runs, err)
+	}
+
+	// Pretend the last run was half a day ago: only the session updated since is exported
+	for key := range runs.Storage {
+		runs.Set(key, time.Now().Add(-12*time.Hour))
+	}
+	if err := runs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := run("--since-last-run", "--last-run-file", stateFile); len(got) != 1 || got[0] != "session_recent.jsonl" {
+		t.Errorf("second --since-last-run exported %v, want only session_recent.jsonl", got)
+	}
+
+	// Nothing changed since the second run
+	if got := run("--since-last-run", "--last-run-file", stateFile); len(got) != 0 {
+		t.Errorf("third --since-last-run exported %v, want nothing", got)
+	}
+
+	// Without the flag every session is exported and the recorded run is left alone
+	before, _ := os.ReadFile(stateFile)
+	if got := run(); len(got) != 2 {
+		t.Errorf("export without --since-last-run exported %v, want both sessions", got)
+	}
+	if after, _ := os.ReadFile(stateFile); string(after) != string(before) {
+		t.Error("export without --since-last-run changed the recorded runs")
+	}
+}
+
+func TestExportCommand_Clipboard(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	clipboard := fakeClipboard(t)
+	resetExportFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"clip-one", "clip-two"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Prompt of " + id}, {Actor: "assistant", Content: "Done"}})
+		writeSessionFixtures(t, dir, session)
+	}
+
+	out := testutil.CreateTempDir(t)
+	run := func(args ...string) error {
+		clearExportFlags()
+		rootCmd.SetArgs(append([]string{"export", "--storage", dir, "--out", out, "--clipboard"}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		return rootCmd.Execute()
+	}
+
+	if err := run("--session-id", "clip-two", "--md-frontmatter"); err != nil {
+		t.Fatalf("export --clipboard error = %v", err)
+	}
+	data, err := os.ReadFile(clipboard)
+	if err != nil {
+		t.Fatalf("nothing was copied: %v", err)
+	}
+	if copied := string(data); !strings.HasPrefix(copied, "---\nid: clip-two\n") || !strings.Contains(copied, "Prompt of clip-two") {
+		t.Errorf("clipboard should hold the session as Markdown with frontmatter, got:\n%s", copied)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("export --clipboard should not write files, found %d", len(entries))
+	}
+
+	if err := run(); exitCode(err) != exitUsage {
+		t.Errorf("export --clipboard without a session should be a usage error, got: %v", err)
+	}
+	if err := run("--session-id", "clip-one", "--archive", filepath.Join(out, "a.zip")); exitCode(err) != exitUsage {
+		t.Errorf("export --clipboard --archive should be a usage error, got: %v", err)
+	}
+}
+
+func TestExportCommand_IncludeParseFailures(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+
+	// A conversation whose second message was written truncated
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	dbPath := filepath.Join(dir, "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	testutil.InsertComposer(t, db, "composerData:gappy", `{"composerId":"gappy","fullConversationHeadersOnly":[`+
+		`{"bubbleId":"u1","type":1},{"bubbleId":"a1","type":2},{"bubbleId":"u2","type":1}]}`)
+	testutil.InsertBubble(t, db, "bubbleId:gappy:u1", `{"bubbleId":"u1","text":"first question","timestamp":1000}`)
+	testutil.InsertBubble(t, db, "bubbleId:gappy:a1", `{"bubbleId":"a1","text":"an answer cut sh`)
+	testutil.InsertBubble(t, db, "bubbleId:gappy:u2", `{"bubbleId":"u2","text":"second question","timestamp":3000}`)
+	_ = db.Close()
+
+	for _, include := range []bool{false, true} {
+		clearExportFlags()
+		out := testutil.CreateTempDir(t)
+		args := []string{"export", "--storage", dir, "--format", "json", "--out", out}
+		if include {
+			args = append(args, "--include-parse-failures")
+		}
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export %v error = %v", args, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(out, "session_gappy.json"))
+		if err != nil {
+			t.Fatalf("session was not exported: %v", err)
+		}
+		var session internal.Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			t.Fatalf("failed to parse export: %v", err)
+		}
+		var contents []string
+		for _, msg := range session.Messages {
+			contents = append(contents, msg.Actor+": "+msg.Content)
+		}
+		if !include {
+			if len(contents) != 2 {
+				t.Errorf("export without --include-parse-failures = %q, want the two parsed messages", contents)
+			}
+			continue
+		}
+		if len(contents) != 3 || !strings.HasPrefix(contents[1], "assistant: [unparseable message: key=bubbleId:gappy:a1, reason=failed to parse bubble JSON") {
+			t.Errorf("export with --include-parse-failures = %q, want a placeholder for a1 between the questions", contents)
+		}
+	}
+
+	// The cache written by the first export is not replaced by one holding placeholders
+	data, err := os.ReadFile(filepath.Join(testCacheDir(t), "session_gappy.json"))
+	if err != nil {
+		t.Fatalf("export without --include-parse-failures should cache the session: %v", err)
+	}
+	if strings.Contains(string(data), "unparseable message") {
+		t.Error("export --include-parse-failures should not cache the placeholders")
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportdInterval   time.Duration
+	exportdOut        string
+	exportdFormat     string
+	exportdPIDFile    string
+	exportdHealthAddr string
+	exportdOnce       bool
+)
+
+// exportdStatus is the daemon state reported by the health endpoint
+type exportdStatus struct {
+	mu sync.Mutex
+
+	PID       int        `json:"pid"`
+	StartedAt time.Time  `json:"started_at"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Runs      int        `json:"runs"`
+	Exported  int        `json:"exported"`
+}
+
+// record updates the status after an export cycle
+func (s *exportdStatus) record(exported int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.LastRun = &now
+	s.Runs++
+	s.Exported += exported
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+}
+
+// ServeHTTP reports the status as JSON; a failed last cycle returns 503
+func (s *exportdStatus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.LastError != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// exportdCmd represents the exportd command
+var exportdCmd = &cobra.Command{
+	Use:   "exportd",
+	Short: "Continuously export new and changed sessions",
+	Long: `Run as a long-lived process that periodically reconstructs sessions and
+exports only the ones that are new or changed since the last cycle.
+
+Exported session fingerprints are kept in .exportd-state.json in the output
+directory, so restarting the daemon does not re-export unchanged sessions. An
+unchanged session whose file was deleted or edited is exported again.
+
+Use --health-addr to serve the daemon status as JSON on /healthz, and
+--pid-file to write the process ID for supervisors.
+
+Example:
+  cursor-session exportd --interval 30s --out /artifacts --health-addr :8080`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportdInterval <= 0 {
+			return usageErrorf("invalid interval: %s (must be positive)", exportdInterval)
+		}
+
+		exporter, err := export.NewExporter(exportdFormat)
+		if err != nil {
+			return &usageError{err: err}
+		}
+
+		if err := os.MkdirAll(exportdOut, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		state, err := internal.LoadExportState(filepath.Join(exportdOut, ".exportd-state.json"))
+		if err != nil {
+			return err
+		}
+		state.SetFormat(exportdFormat)
+
+		if exportdPIDFile != "" {
+			if err := os.WriteFile(exportdPIDFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+				return fmt.Errorf("failed to write PID file: %w", err)
+			}
+			defer func() { _ = os.Remove(exportdPIDFile) }()
+		}
+
+		status := &exportdStatus{PID: os.Getpid(), StartedAt: time.Now()}
+		if exportdHealthAddr != "" {
+			listener, err := net.Listen("tcp", exportdHealthAddr)
+			if err != nil {
+				return fmt.Errorf("failed to start health endpoint: %w", err)
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/healthz", status)
+			server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+			go func() { _ = server.Serve(listener) }()
+			defer func() { _ = server.Close() }()
+			internal.LogInfo("Health endpoint listening on http://%s/healthz", listener.Addr())
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		ticker := time.NewTicker(exportdInterval)
+		defer ticker.Stop()
+
+		for {
+			exported, err := runExportCycle(exporter, state)
+			status.record(exported, err)
+			if err != nil {
+				internal.LogError("Export cycle failed: %v", err)
+			} else {
+				internal.LogInfo("Exported %d new or changed session(s) to %s", exported, exportdOut)
+			}
+
+			if exportdOnce {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				internal.LogInfo("Stopping export daemon")
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// runExportCycle reconstructs all sessions and exports the ones whose fingerprint changed
+func runExportCycle(exporter export.Exporter, state *internal.ExportState) (int, error) {
+	paths, err := resolveStoragePaths()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get storage paths: %w", err)
+	}
+
+	// Copy database files so the running editor is not disturbed
+	if copyStorage(paths...) {
+		var cleanup func() error
+		paths, cleanup, err = internal.CopyStoragePathsList(paths)
+		if err != nil {
+			return 0, fmt.Errorf("failed to copy database files: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				internal.LogWarn("Failed to cleanup temporary files: %v", err)
+			}
+		}()
+	}
+
+	backend, err := internal.NewStorageBackendForPaths(paths)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	conversations, err := internal.ReconstructConversations(backend)
+	if err != nil {
+		return 0, err
+	}
+	sessions := internal.NormalizeSessions(conversations, backend, paths, "")
+	hideGeneratedTitles(sessions)
+	applySessionTags(sessions)
+
+	exported := 0
+	for _, session := range sessions {
+		// An unchanged session is written again when its file was deleted or edited
+		path := filepath.Join(exportdOut, export.SessionFileName(exporter, session))
+		fingerprint, changed := state.Changed(session)
+		if !changed && state.Written(session.ID, path) {
+			continue
+		}
+		if err := export.WriteSessionFile(exporter, exportdOut, session); err != nil {
+			internal.LogError("Failed to export session %s: %v", session.ID, err)
+			continue
+		}
+		state.Mark(session.ID, fingerprint)
+		if hash, err := internal.HashFile(path); err == nil {
+			state.SetHash(session.ID, hash)
+		} else {
+			internal.LogDebug("Failed to hash %s: %v", path, err)
+		}
+		exported++
+	}
+
+	if exported > 0 {
+		if err := state.Save(); err != nil {
+			return exported, fmt.Errorf("failed to save export state: %w", err)
+		}
+	}
+	return exported, nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportdCmd)
+	exportdCmd.Flags().DurationVar(&exportdInterval, "interval", 30*time.Second, "Time between export cycles")
+	exportdCmd.Flags().StringVarP(&exportdOut, "out", "o", "./exports", "Output directory")
+	exportdCmd.Flags().StringVarP(&exportdFormat, "format", "f", "jsonl", "Export format (jsonl, md, yaml, json, parquet, csv, mermaid, dot, specstory-md, events-jsonl)")
+	exportdCmd.Flags().StringVar(&exportdPIDFile, "pid-file", "", "Write the process ID to this file while running")
+	exportdCmd.Flags().StringVar(&exportdHealthAddr, "health-addr", "", "Serve daemon status on /healthz at this address (e.g. :8080)")
+	exportdCmd.Flags().BoolVar(&exportdOnce, "once", false, "Run a single export cycle and exit")
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestExportdCommand(t *testing.T) {
+	defer func() {
+		exportdInterval = 30 * time.Second
+		exportdFormat = "jsonl"
+	}()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "invalid format",
+			args:    []string{"exportd", "--format", "invalid", "--once"},
+			wantErr: true,
+		},
+		{
+			name:    "non-positive interval",
+			args:    []string{"exportd", "--interval", "0s", "--format", "jsonl", "--once"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd.SetArgs(tt.args)
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+
+			err := rootCmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("exportdCmd.Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportdStatus_NoRunYet(t *testing.T) {
+	status := &exportdStatus{PID: 42, StartedAt: time.Now()}
+	rec := httptest.NewRecorder()
+	status.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if _, ok := body["last_run"]; ok {
+		t.Errorf("body = %v, want no last_run before the first cycle", body)
+	}
+}
+
+func TestExportdStatus_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"healthy", nil, http.StatusOK},
+		{"last cycle failed", errors.New("database locked"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &exportdStatus{PID: 42, StartedAt: time.Now()}
+			status.record(3, tt.err)
+
+			rec := httptest.NewRecorder()
+			status.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if body["pid"] != float64(42) || body["exported"] != float64(3) || body["runs"] != float64(1) {
+				t.Errorf("body = %v, want pid 42, exported 3, runs 1", body)
+			}
+		})
+	}
+}
+
+func TestRunExportCycle_RewritesMissingFiles(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	originalOut := exportdOut
+	defer func() {
+		exportdOut = originalOut
+		storagePaths = nil
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("kept", []internal.Message{{Actor: "user", Content: "Hello"}})
+	writeSessionFixtures(t, dir, session)
+	storagePaths = []string{dir}
+	exportdOut = testutil.CreateTempDir(t)
+
+	exporter, err := export.NewExporter("jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := internal.NewExportState(filepath.Join(exportdOut, ".exportd-state.json"))
+	state.SetFormat("jsonl")
+	file := filepath.Join(exportdOut, "session_kept.jsonl")
+
+	cycle := func() int {
+		t.Helper()
+		exported, err := runExportCycle(exporter, state)
+		if err != nil {
+			t.Fatalf("runExportCycle() error = %v", err)
+		}
+		return exported
+	}
+	if got := cycle(); got != 1 {
+		t.Fatalf("first cycle exported %d session(s), want 1", got)
+	}
+	if got := cycle(); got != 0 {
+		t.Errorf("unchanged cycle exported %d session(s), want 0", got)
+	}
+
+	// A deleted or edited file is written again though the session is unchanged
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if got := cycle(); got != 1 {
+		t.Errorf("cycle after deleting the file exported %d session(s), want 1", got)
+	}
+	if err := os.WriteFile(file, []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := cycle(); got != 1 {
+		t.Errorf("cycle after editing the file exported %d session(s), want 1", got)
+	}
+	if data, err := os.ReadFile(file); err != nil || !strings.Contains(string(data), "Hello") {
+		t.Errorf("session file = %q, %v, want the session", data, err)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var extractOutput string
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract",
+	Short: "Save the raw storage data to a dump that 'reconstruct --from' reads",
+	Long: `Read the messages, conversations, contexts, diffs and file edits from storage,
+along with the workspaces they belong to, and save them to a single raw dump.
+
+This is the first phase of the export pipeline run one phase at a time. The dump
+can be reconstructed and normalized again and again without reading the databases:
+
+  cursor-session extract                         # Databases -> ./intermediary/raw.json
+  cursor-session reconstruct --from ./intermediary/raw.json
+  cursor-session normalize                       # -> ./sessions/session_<id>.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backend, paths, cleanup, err := openStorageBackend()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		var dump *internal.RawDump
+		err = internal.ShowProgress(context.Background(), "Extracting data from storage", func() error {
+			var extractErr error
+			dump, extractErr = internal.ExtractRawDump(backend, paths)
+			return extractErr
+		})
+		if err != nil {
+			return err
+		}
+		if err := internal.WriteRawDump(extractOutput, dump); err != nil {
+			return err
+		}
+
+		internal.PrintSuccess(fmt.Sprintf("Extraction complete: %d message(s) and %d conversation(s) saved to %s", len(dump.Bubbles), len(dump.Composers), extractOutput))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+	extractCmd.Flags().StringVarP(&extractOutput, "out", "o", "./intermediary/raw.json", "File to write the raw dump to")
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// runPhase runs a pipeline command, failing the test if it fails
+func runPhase(t *testing.T, args ...string) {
+	t.Helper()
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v error = %v", args, err)
+	}
+}
+
+func TestPipelinePhases(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		extractOutput = "./intermediary/raw.json"
+		reconstructOutput, reconstructFrom = "./intermediary", ""
+		normalizeFrom, normalizeRaw, normalizeOutput, normalizeWorkspace = "./intermediary", "", "./sessions", ""
+	}()
+
+	storage := testutil.CreateTempDir(t)
+	session := internal.CreateTestSession("phased")
+	if err := os.WriteFile(filepath.Join(storage, "session_phased.json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	work := testutil.CreateTempDir(t)
+	intermediary := filepath.Join(work, "intermediary")
+	sessions := filepath.Join(work, "sessions")
+	runPhase(t, "extract", "--storage", storage, "--out", filepath.Join(intermediary, "raw.json"))
+
+	// The later phases don't need the storage anymore
+	if err := os.RemoveAll(storage); err != nil {
+		t.Fatal(err)
+	}
+	storagePaths = nil
+	runPhase(t, "reconstruct", "--from", filepath.Join(intermediary, "raw.json"), "--out", intermediary)
+	if !fileExists(filepath.Join(intermediary, "conversation_phased.json")) {
+		t.Fatal("reconstruct --from did not write conversation_phased.json")
+	}
+	runPhase(t, "normalize", "--from", intermediary, "--out", sessions)
+
+	backend := internal.NewFileBackend(sessions)
+	conversations, err := internal.ReconstructConversations(backend)
+	if err != nil {
+		t.Fatalf("reading the normalized sessions failed: %v", err)
+	}
+	normalized := internal.NormalizeSessions(conversations, backend, nil, "")
+	if len(normalized) != 1 || normalized[0].ID != "phased" || len(normalized[0].Messages) != 2 || normalized[0].Workspace != "test-workspace" {
+		t.Fatalf("normalized sessions = %+v, want the phased session with its messages and workspace", normalized)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixtureOutput   string
+	fixtureSessions int
+	fixtureMessages int
+	fixtureBackend  string
+	fixtureSeed     uint64
+)
+
+// genFixtureCmd represents the gen-fixture command
+var genFixtureCmd = &cobra.Command{
+	Use:   "gen-fixture",
+	Short: "Generate synthetic Cursor storage for benchmarks and bug reports",
+	Long: `Write synthetic Cursor databases holding generated sessions, laid out as Cursor
+lays them out, to benchmark cursor-session or reproduce a bug without sharing real
+conversations:
+  • User/globalStorage/state.vscdb and User/workspaceStorage/<hash>: desktop sessions,
+    as JSON and binary values with rich text, thinking and tool calls
+  • chats/<hash>/<session id>/store.db: cursor-agent sessions, as JSON,
+    protobuf-framed, record-prefixed and text$uuid messages with hex-encoded metadata
+
+Every database is in WAL mode; the global database and every other store.db
+have their last rows only in the -wal file, as when Cursor is still running.
+--sessions are split between the desktop app and cursor-agent unless --backend
+picks one. The same --seed generates the same sessions, so a fixture can be
+described by its flags alone.
+
+Examples:
+  cursor-session gen-fixture --sessions 50 --messages 200 --out ./fixture
+  cursor-session bench --storage ./fixture/User/globalStorage --storage ./fixture/chats
+  cursor-session export --storage ./fixture/chats --format md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if fixtureSessions < 1 {
+			return usageErrorf("--sessions must be at least 1, got %d", fixtureSessions)
+		}
+		if fixtureMessages < 1 {
+			return usageErrorf("--messages must be at least 1, got %d", fixtureMessages)
+		}
+		switch fixtureBackend {
+		case testutil.FixtureDesktop, testutil.FixtureAgent, testutil.FixtureAll:
+		default:
+			return usageErrorf("unsupported --backend %s (expected %s, %s or %s)", fixtureBackend, testutil.FixtureDesktop, testutil.FixtureAgent, testutil.FixtureAll)
+		}
+
+		result, err := testutil.GenerateFixture(fixtureOutput, testutil.FixtureOptions{
+			Sessions: fixtureSessions,
+			Messages: fixtureMessages,
+			Backend:  fixtureBackend,
+			Seed:     fixtureSeed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate fixture: %w", err)
+		}
+
+		internal.PrintSuccess(fmt.Sprintf("Generated %d session(s) with %d message(s) in %s", result.Sessions(), result.Messages, fixtureOutput))
+		if result.GlobalStorage != "" {
+			_, _ = fmt.Fprintf(out, "  Desktop:      %d session(s), --storage %s\n", result.DesktopSessions, result.GlobalStorage)
+		}
+		if result.AgentStorage != "" {
+			_, _ = fmt.Fprintf(out, "  cursor-agent: %d session(s), --storage %s\n", result.AgentSessions, result.AgentStorage)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genFixtureCmd)
+	genFixtureCmd.Flags().StringVarP(&fixtureOutput, "out", "o", "./fixture", "Directory to write the fixture to")
+	genFixtureCmd.Flags().IntVar(&fixtureSessions, "sessions", 10, "Number of sessions to generate")
+	genFixtureCmd.Flags().IntVar(&fixtureMessages, "messages", 20, "Number of messages in each session")
+	genFixtureCmd.Flags().StringVar(&fixtureBackend, "backend", testutil.FixtureAll, "Storage to generate: desktop, agent or all")
+	genFixtureCmd.Flags().Uint64Var(&fixtureSeed, "seed", 1, "Seed of the generator; the same seed generates the same sessions")
+}
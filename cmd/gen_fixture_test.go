@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// TestGenFixtureCommand generates a fixture and exports it end to end, checking that
+// every generated session and message comes out of each backend
+func TestGenFixtureCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetExportFlags(t)
+	defer func() {
+		fixtureOutput, fixtureSessions, fixtureMessages, fixtureBackend, fixtureSeed = "./fixture", 10, 20, testutil.FixtureAll, 1
+	}()
+
+	dir := filepath.Join(testutil.CreateTempDir(t), "fixture")
+	var out bytes.Buffer
+	rootCmd.SetArgs([]string{"gen-fixture", "--sessions", "5", "--messages", "14", "--out", dir})
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("gen-fixture error = %v", err)
+	}
+
+	globalStorage := filepath.Join(dir, "User", "globalStorage")
+	chats := filepath.Join(dir, "chats")
+	if !bytes.Contains(out.Bytes(), []byte(globalStorage)) || !bytes.Contains(out.Bytes(), []byte(chats)) {
+		t.Errorf("gen-fixture should print the storage to read, got:\n%s", out.String())
+	}
+	if !fileExists(filepath.Join(globalStorage, "state.vscdb-wal")) {
+		t.Error("gen-fixture should leave rows of state.vscdb in its -wal")
+	}
+
+	tests := []struct {
+		storage  string
+		sessions int
+	}{
+		{globalStorage, 3},
+		{chats, 2},
+	}
+	for _, tt := range tests {
+		clearExportFlags()
+		exported := testutil.CreateTempDir(t)
+		rootCmd.SetArgs([]string{"export", "--storage", tt.storage, "--out", exported, "--clear-cache"})
+		rootCmd.SetOut(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("export --storage %s error = %v", tt.storage, err)
+		}
+
+		files, err := filepath.Glob(filepath.Join(exported, "session_*.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != tt.sessions {
+			t.Errorf("export --storage %s wrote %d session(s), want %d", tt.storage, len(files), tt.sessions)
+		}
+		for _, file := range files {
+			if lines := countLines(t, file); lines != 14 {
+				t.Errorf("%s has %d message(s), want 14", filepath.Base(file), lines)
+			}
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--sessions", "0"},
+		{"--messages", "-1"},
+		{"--backend", "web"},
+	} {
+		fixtureSessions, fixtureMessages, fixtureBackend = 10, 20, testutil.FixtureAll
+		rootCmd.SetArgs(append([]string{"gen-fixture", "--out", dir}, args...))
+		if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("gen-fixture %v error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+// countLines returns the number of lines of a file
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
 )
 
 func TestHealthcheckCommand(t *testing.T) {
@@ -58,3 +62,45 @@ func TestHealthcheckVerboseFlag(t *testing.T) {
 		t.Error("healthcheck command should have -v flag (shorthand for verbose)")
 	}
 }
+
+func TestHealthcheckCommand_Report(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	for _, name := range []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL", "CIRCLECI", "TRAVIS", "BUILDKITE", "TEAMCITY_VERSION", "TF_BUILD", "bamboo_buildKey"} {
+		t.Setenv(name, "")
+	}
+	defer func() {
+		storagePaths = nil
+		verbosity = 0
+	}()
+
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	testutil.CreateSQLiteFixture(t, filepath.Join(dir, "state.vscdb"))
+
+	// TestHealthcheckCommand leaves --help set
+	_ = healthcheckCmd.Flags().Set("help", "false")
+
+	var out bytes.Buffer
+	storagePaths = nil
+	rootCmd.SetArgs([]string{"healthcheck", "--storage", dir, "--verbose"})
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("healthcheck error = %v", err)
+	}
+	for _, want := range []string{"Desktop app storage found", "Type: Desktop app storage (globalStorage)", "Found 1 session(s)", "Test Conversation (ID: composer", "Health check passed!"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("healthcheck output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	// Without any storage the check fails outside CI
+	out.Reset()
+	storagePaths, verbosity = nil, 0
+	rootCmd.SetArgs([]string{"healthcheck"})
+	if err := rootCmd.Execute(); exitCode(err) != exitError {
+		t.Errorf("healthcheck without storage error = %v, want exit code %d", err, exitError)
+	}
+	if !strings.Contains(out.String(), "Failed to initialize storage backend") {
+		t.Errorf("healthcheck output should report the backend failure, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importOut   string
+	importForce bool
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <path>...",
+	Short: "Import exported sessions into a new Cursor database",
+	Long: `Write exported sessions back into a new desktop app database, to move chat
+history between machines or restore it from a backup.
+
+Each path is an exported session file (JSON, YAML or JSONL), an intermediary
+dump, or a directory of them. The sessions are written to
+<out>/globalStorage/state.vscdb in the layout Cursor uses, which can be read
+with --storage <out>/globalStorage or copied into Cursor's User directory.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backends := make([]internal.StorageBackend, 0, len(args))
+		for _, path := range args {
+			if _, err := os.Stat(path); err != nil {
+				return usageErrorf("cannot read %s: %w", path, err)
+			}
+			backends = append(backends, internal.NewFileBackend(path))
+		}
+
+		dbPath := filepath.Join(importOut, "globalStorage", "state.vscdb")
+		if _, err := os.Stat(dbPath); err == nil {
+			if !importForce {
+				return usageErrorf("%s already exists; use --force to replace it", dbPath)
+			}
+			if err := os.Remove(dbPath); err != nil {
+				return fmt.Errorf("failed to remove existing database: %w", err)
+			}
+		}
+
+		count, err := internal.WriteStateDB(dbPath, internal.NewMultiBackend(backends...))
+		if err != nil {
+			return fmt.Errorf("failed to import sessions: %w", err)
+		}
+		if count == 0 {
+			internal.PrintWarning(fmt.Sprintf("No sessions found to import; wrote an empty database to %s", dbPath))
+			return nil
+		}
+
+		internal.PrintSuccess(fmt.Sprintf("Imported %d session(s) into %s", count, dbPath))
+		internal.PrintInfo(fmt.Sprintf("Read them with: cursor-session list --storage %s", filepath.Dir(dbPath)))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importOut, "out", "o", "./cursor-import", "Directory to create globalStorage/state.vscdb in")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Replace an existing database in the output directory")
+}
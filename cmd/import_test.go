@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestImportCommand(t *testing.T) {
+	defer func() {
+		importOut = "./cursor-import"
+		importForce = false
+		importCmd.Flags().Lookup("force").Changed = false
+	}()
+
+	archive := testutil.CreateTempDir(t)
+	session := internal.CreateTestSession("imported-session")
+	if err := os.WriteFile(filepath.Join(archive, "session_imported-session.json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	out := testutil.CreateTempDir(t)
+	dbPath := filepath.Join(out, "globalStorage", "state.vscdb")
+
+	run := func(args ...string) error {
+		rootCmd.SetArgs(append([]string{"import", "--out", out}, args...))
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		return rootCmd.Execute()
+	}
+
+	if err := run(archive); err != nil {
+		t.Fatalf("import error = %v", err)
+	}
+	paths, err := internal.GetStoragePaths(filepath.Dir(dbPath))
+	if err != nil {
+		t.Fatalf("imported database is not a storage location: %v", err)
+	}
+	backend, err := internal.NewStorageBackend(paths)
+	if err != nil {
+		t.Fatalf("NewStorageBackend() error = %v", err)
+	}
+	composers, err := backend.LoadComposers()
+	if err != nil || len(composers) != 1 || composers[0].ComposerID != "imported-session" {
+		t.Errorf("imported composers = %v, %v, want imported-session", composers, err)
+	}
+
+	if err := run(archive); exitCode(err) != exitUsage {
+		t.Errorf("import over an existing database error = %v, want a usage error", err)
+	}
+	if err := run(archive, "--force"); err != nil {
+		t.Errorf("import --force error = %v", err)
+	}
+	if err := run(filepath.Join(archive, "missing")); exitCode(err) != exitUsage {
+		t.Errorf("import of a missing path error = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	infoName   string
+	infoFormat string
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info [session-id]",
+	Short: "Show a session's metadata without its messages",
+	Long: `Print the metadata of a session: its name, workspace, creation and update
+times, message count and estimated tokens, the databases it was read from, its
+git branch, tags and extraction quality.
+
+Unlike 'show', the transcript is not rendered. The session can be given as a
+full ID, a unique ID prefix, or looked up by name with --name.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: sessionIDArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		var sessionQuery string
+		if len(args) > 0 {
+			sessionQuery = args[0]
+		}
+		if sessionQuery == "" && infoName == "" {
+			return usageErrorf("a session ID or --name is required")
+		}
+		if infoFormat != "text" && infoFormat != "json" {
+			return usageErrorf("invalid --format %q (expected text or json)", infoFormat)
+		}
+
+		paths, err := resolveStoragePaths()
+		if err != nil {
+			return fmt.Errorf("failed to get storage paths: %w", err)
+		}
+		if copyStorage(paths...) {
+			var cleanup func() error
+			paths, cleanup, err = internal.CopyStoragePathsList(paths)
+			if err != nil {
+				return fmt.Errorf("failed to copy database files: %w", err)
+			}
+			defer func() {
+				if err := cleanup(); err != nil {
+					internal.LogWarn("Failed to cleanup temporary files: %v", err)
+				}
+			}()
+		}
+		backend, err := internal.NewStorageBackendForPaths(paths)
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		cacheDir, err := cacheDirectory()
+		if err != nil {
+			return err
+		}
+		cacheManager := internal.NewCacheManager(cacheDir)
+		cacheKey := storageCacheKey(paths)
+		valid, _ := cacheManager.IsCacheValid(cacheKey)
+		index, _ := cacheManager.LoadIndex()
+
+		// Resolve the query (full ID, ID prefix, or name) to a full session ID
+		refs, err := loadSessionRefs(index, valid, backend)
+		if err != nil {
+			return err
+		}
+		sessionID, err := internal.ResolveSession(refs, sessionQuery, infoName)
+		if err != nil {
+			return err
+		}
+
+		session, err := findSession(cacheManager, index, cacheKey, backend, paths, sessionID)
+		if err != nil {
+			return err
+		}
+		hideGeneratedTitles([]*internal.Session{session})
+		applySessionTags([]*internal.Session{session})
+
+		info := internal.NewSessionInfo(session)
+		if infoFormat == "json" {
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode session info: %w", err)
+			}
+			_, err = fmt.Fprintln(out, string(data))
+			return err
+		}
+		printSessionInfo(out, info)
+		return nil
+	},
+}
+
+// findSession loads a session without the others when it can: from the cache when its
+// index knows the session, or reconstructed alone when the backend loads single bubbles.
+// Otherwise every session is loaded.
+func findSession(cacheManager *internal.CacheManager, index *internal.SessionIndex, cacheKey string, backend internal.StorageBackend, paths []internal.StoragePaths, sessionID string) (*internal.Session, error) {
+	if index != nil && cacheKey != "" && index.Metadata.DatabasePath == cacheKey {
+		for _, entry := range index.Sessions {
+			if entry.ComposerID != sessionID {
+				continue
+			}
+			if session, err := cacheManager.LoadSession(entry.ID); err == nil {
+				return session, nil
+			}
+			internal.LogDebug("Failed to load session %s from the cache", entry.ID)
+		}
+	}
+
+	if _, ok := backend.(internal.BubbleLoader); ok {
+		return internal.ReconstructSession(backend, paths, sessionID, "")
+	}
+
+	sessions, err := loadSessions(backend, paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("session not found: %s", sessionID)
+}
+
+// printSessionInfo prints a session's metadata as aligned fields, leaving out those that
+// are unknown
+func printSessionInfo(out io.Writer, info internal.SessionInfo) {
+	name := info.Name
+	if name == "" {
+		name = "Untitled"
+	}
+	_, _ = fmt.Fprintln(out, sessionHeaderStyle.Render(fmt.Sprintf("💬 %s", name)))
+
+	field := func(label, value string) {
+		if value != "" {
+			_, _ = fmt.Fprintf(out, "%-14s%s\n", label+":", value)
+		}
+	}
+	field("ID", info.ID)
+	field("Workspace", info.Workspace)
+	field("Created", displayTimestamp(info.CreatedAt))
+	field("Updated", displayTimestamp(info.UpdatedAt))
+	field("Messages", fmt.Sprintf("%d", info.MessageCount))
+	field("Tokens", fmt.Sprintf("~%d (estimated)", info.EstimatedTokens))
+	if info.ThinkingTokens > 0 {
+		field("Thinking", fmt.Sprintf("~%d tokens (%d%% of the total)", info.ThinkingTokens, info.ThinkingTokens*100/info.EstimatedTokens))
+	}
+	field("Source", info.Source)
+	field("Databases", strings.Join(info.Sources, "\n"+strings.Repeat(" ", 14)))
+	field("Git branch", info.GitBranch)
+	field("Tags", strings.Join(info.Tags, ", "))
+	field("Resumed from", info.ParentID)
+	if info.Extraction != nil {
+		field("Extraction", info.Extraction.String())
+	}
+}
+
+// displayTimestamp formats an RFC3339 timestamp for display, or returns it unchanged
+func displayTimestamp(ts string) string {
+	if t, err := time.Parse(time.RFC3339, ts); err == nil {
+		return internal.FormatDisplayTime(t, time.DateTime)
+	}
+	return ts
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().StringVar(&infoName, "name", "", "Find the session by name (fuzzy match)")
+	infoCmd.Flags().StringVar(&infoFormat, "format", "text", "Output format (text, json)")
+	_ = infoCmd.RegisterFlagCompletionFunc("name", sessionNameFlag)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestInfoCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		infoName = ""
+		infoFormat = "text"
+	}()
+
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "globalStorage", "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	testutil.InsertComposer(t, db, "composerData:alpha", `{"composerId":"alpha","name":"Fix the build","createdAt":1740830400000,"fullConversationHeadersOnly":[{"bubbleId":"alpha-b1","type":1}]}`)
+	testutil.InsertBubble(t, db, "bubbleId:alpha:alpha-b1", `{"bubbleId":"alpha-b1","text":"Why does the build fail?","type":1}`)
+	_ = db.Close()
+
+	run := func(args ...string) (string, error) {
+		storagePaths, infoName, infoFormat = nil, "", "text"
+		var out bytes.Buffer
+		rootCmd.SetArgs(append([]string{"info"}, args...))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("alp", "--storage", dbPath)
+	if err != nil {
+		t.Fatalf("info error = %v", err)
+	}
+	for _, want := range []string{"Fix the build", "alpha", "Created:", "Messages:     1", "Tokens:       ~6 (estimated)", "Databases:", dbPath} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Why does the build fail?") {
+		t.Errorf("info should not print the messages, got:\n%s", out)
+	}
+
+	out, err = run("alpha", "--storage", dbPath, "--format", "json")
+	if err != nil {
+		t.Fatalf("info --format json error = %v", err)
+	}
+	var info internal.SessionInfo
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		t.Fatalf("info --format json output is not JSON: %v\n%s", err, out)
+	}
+	if info.ID != "alpha" || info.MessageCount != 1 || info.EstimatedTokens != 6 || info.CreatedAt != "2025-03-01T12:00:00Z" {
+		t.Errorf("info = %+v, want alpha with its creation time, message and token counts", info)
+	}
+
+	for _, args := range [][]string{{}, {"alpha", "--format", "yaml"}} {
+		if _, err := run(append(args, "--storage", dbPath)...); exitCode(err) != exitUsage {
+			t.Errorf("info %v error = %v, want a usage error", args, err)
+		}
+	}
+	if _, err := run("missing", "--storage", dbPath); err == nil {
+		t.Error("info of an unknown session should fail")
+	}
+}
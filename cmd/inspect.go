@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/iksnae/cursor-session/internal"
@@ -34,6 +35,7 @@ Examples:
   cursor-session inspect --storage /path/to/store.db       # Inspect specific database
   cursor-session inspect --format json --sample 5          # JSON output with 5 sample rows`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
 		var dbPath string
 		if len(args) > 0 {
 			dbPath = args[0]
@@ -41,7 +43,7 @@ Examples:
 
 		// If no path provided, try to auto-detect
 		if dbPath == "" {
-			paths, err := internal.GetStoragePaths(storagePath)
+			paths, err := internal.GetStoragePaths(primaryStoragePath("inspect"))
 			if err != nil {
 				return fmt.Errorf("failed to detect storage: %w", err)
 			}
@@ -49,7 +51,7 @@ Examples:
 			// Try desktop storage first
 			if paths.GlobalStorageExists() {
 				dbPath = paths.GetGlobalStorageDBPath()
-				fmt.Printf("📊 Inspecting desktop storage: %s\n\n", dbPath)
+				_, _ = fmt.Fprintf(out, "📊 Inspecting desktop storage: %s\n\n", dbPath)
 			} else if paths.HasAgentStorage() {
 				// Get first agent storage database
 				storeDBs, err := paths.FindAgentStoreDBs()
@@ -57,17 +59,17 @@ Examples:
 					return fmt.Errorf("no agent storage databases found")
 				}
 				dbPath = storeDBs[0]
-				fmt.Printf("📊 Inspecting agent storage: %s\n\n", dbPath)
+				_, _ = fmt.Fprintf(out, "📊 Inspecting agent storage: %s\n\n", dbPath)
 			} else {
 				return fmt.Errorf("no storage found - use --storage to specify a database path")
 			}
 		}
 
-		return inspectDatabase(dbPath)
+		return inspectDatabase(out, dbPath)
 	},
 }
 
-func inspectDatabase(dbPath string) error {
+func inspectDatabase(out io.Writer, dbPath string) error {
 	db, err := internal.OpenDatabase(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
@@ -81,19 +83,19 @@ func inspectDatabase(dbPath string) error {
 	}
 
 	if len(tables) == 0 {
-		fmt.Println("⚠️  No tables found in database")
+		_, _ = fmt.Fprintln(out, "⚠️  No tables found in database")
 		return nil
 	}
 
-	fmt.Printf("📋 Database: %s\n", dbPath)
-	fmt.Printf("📊 Found %d table(s)\n\n", len(tables))
+	_, _ = fmt.Fprintf(out, "📋 Database: %s\n", dbPath)
+	_, _ = fmt.Fprintf(out, "📊 Found %d table(s)\n\n", len(tables))
 
 	for _, tableName := range tables {
-		if err := inspectTable(db, tableName); err != nil {
-			fmt.Printf("⚠️  Error inspecting table %s: %v\n", tableName, err)
+		if err := inspectTable(out, db, tableName, inspectSampleRows, false); err != nil {
+			_, _ = fmt.Fprintf(out, "⚠️  Error inspecting table %s: %v\n", tableName, err)
 			continue
 		}
-		fmt.Println()
+		_, _ = fmt.Fprintln(out)
 	}
 
 	return nil
@@ -121,17 +123,19 @@ func getTables(db *sql.DB) ([]string, error) {
 	return tables, rows.Err()
 }
 
-func inspectTable(db *sql.DB, tableName string) error {
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("📦 Table: %s\n", tableName)
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+// inspectTable prints a table's row count, schema and first sampleRows rows, masked with
+// internal.RedactSample when redact is set
+func inspectTable(out io.Writer, db *sql.DB, tableName string, sampleRows int, redact bool) error {
+	_, _ = fmt.Fprintf(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	_, _ = fmt.Fprintf(out, "📦 Table: %s\n", tableName)
+	_, _ = fmt.Fprintf(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 
 	// Get row count
 	var rowCount int
 	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)).Scan(&rowCount); err != nil {
 		return fmt.Errorf("failed to get row count: %w", err)
 	}
-	fmt.Printf("📊 Rows: %d\n\n", rowCount)
+	_, _ = fmt.Fprintf(out, "📊 Rows: %d\n\n", rowCount)
 
 	// Get schema
 	columns, err := getTableSchema(db, tableName)
@@ -139,7 +143,7 @@ func inspectTable(db *sql.DB, tableName string) error {
 		return fmt.Errorf("failed to get schema: %w", err)
 	}
 
-	fmt.Printf("📐 Schema:\n")
+	_, _ = fmt.Fprintf(out, "📐 Schema:\n")
 	for _, col := range columns {
 		pk := ""
 		if col.PrimaryKey {
@@ -149,14 +153,14 @@ func inspectTable(db *sql.DB, tableName string) error {
 		if col.NotNull {
 			notNull = " NOT NULL"
 		}
-		fmt.Printf("  • %s: %s%s%s\n", col.Name, col.Type, notNull, pk)
+		_, _ = fmt.Fprintf(out, "  • %s: %s%s%s\n", col.Name, col.Type, notNull, pk)
 	}
-	fmt.Println()
+	_, _ = fmt.Fprintln(out)
 
 	// Show sample data
-	if rowCount > 0 && inspectSampleRows > 0 {
-		if err := showSampleData(db, tableName, columns, inspectSampleRows); err != nil {
-			fmt.Printf("⚠️  Error showing sample data: %v\n", err)
+	if rowCount > 0 && sampleRows > 0 {
+		if err := showSampleData(out, db, tableName, columns, sampleRows, redact); err != nil {
+			_, _ = fmt.Fprintf(out, "⚠️  Error showing sample data: %v\n", err)
 		}
 	}
 
@@ -194,7 +198,7 @@ func getTableSchema(db *sql.DB, tableName string) ([]ColumnInfo, error) {
 	return columns, rows.Err()
 }
 
-func showSampleData(db *sql.DB, tableName string, columns []ColumnInfo, limit int) error {
+func showSampleData(out io.Writer, db *sql.DB, tableName string, columns []ColumnInfo, limit int, redact bool) error {
 	if len(columns) == 0 {
 		return nil
 	}
@@ -212,7 +216,7 @@ func showSampleData(db *sql.DB, tableName string, columns []ColumnInfo, limit in
 	}
 	defer func() { _ = rows.Close() }()
 
-	fmt.Printf("📄 Sample Data (first %d rows):\n", limit)
+	_, _ = fmt.Fprintf(out, "📄 Sample Data (first %d rows):\n", limit)
 	rowNum := 0
 	for rows.Next() {
 		rowNum++
@@ -223,16 +227,24 @@ func showSampleData(db *sql.DB, tableName string, columns []ColumnInfo, limit in
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			fmt.Printf("  ⚠️  Row %d: error scanning: %v\n", rowNum, err)
+			_, _ = fmt.Fprintf(out, "  ⚠️  Row %d: error scanning: %v\n", rowNum, err)
 			continue
 		}
 
-		fmt.Printf("\n  Row %d:\n", rowNum)
+		_, _ = fmt.Fprintf(out, "\n  Row %d:\n", rowNum)
 		for i, col := range columns {
 			val := values[i]
 			var valStr string
 			if val == nil {
 				valStr = "<NULL>"
+			} else if redact {
+				if b, ok := val.([]byte); ok {
+					val = string(b)
+				}
+				valStr = internal.RedactSample(fmt.Sprintf("%v", val))
+				if len(valStr) > 200 {
+					valStr = valStr[:200] + "..."
+				}
 			} else {
 				valStr = fmt.Sprintf("%v", val)
 
@@ -244,7 +256,7 @@ func showSampleData(db *sql.DB, tableName string, columns []ColumnInfo, limit in
 						if json.Unmarshal(decoded, &metaData) == nil {
 							// Successfully decoded - show formatted JSON
 							if jsonBytes, err := json.MarshalIndent(metaData, "      ", "  "); err == nil {
-								fmt.Printf("    %s (hex-encoded JSON):\n%s\n", col.Name, string(jsonBytes))
+								_, _ = fmt.Fprintf(out, "    %s (hex-encoded JSON):\n%s\n", col.Name, string(jsonBytes))
 								continue
 							}
 						}
@@ -260,7 +272,7 @@ func showSampleData(db *sql.DB, tableName string, columns []ColumnInfo, limit in
 					valStr = strings.Split(valStr, "\n")[0] + "..."
 				}
 			}
-			fmt.Printf("    %s: %s\n", col.Name, valStr)
+			_, _ = fmt.Fprintf(out, "    %s: %s\n", col.Name, valStr)
 		}
 	}
 
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -16,9 +19,24 @@ import (
 
 // listCmd represents the list command
 var (
-	listClearCache bool
+	listClearCache    bool
+	listAllWorkspaces bool
+	listTag           string
+	listThread        bool
+	listBranch        string
+	listFilter        string
+	listCount         bool
+	listIndexOnly     bool
+	listFlags         []string
+	listPreview       bool
+	listGroupBy       string
+	listJSON          bool
+	listArchived      bool
 )
 
+// previewWidth is the width, in characters, of the prompt previews shown by list --preview
+const previewWidth = 60
+
 var (
 	// Styles
 	headerStyle = lipgloss.NewStyle().
@@ -44,24 +62,95 @@ var (
 	workspaceStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("135")).
 			Italic(true)
+
+	tagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
+
+	groupStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("75"))
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available sessions",
-	Long:  `List all available chat sessions from Cursor's globalStorage.`,
+	Long: `List all available chat sessions from Cursor's globalStorage.
+
+When the cache is up to date, sessions are listed from its index without opening
+the databases. --count prints only the number of sessions, reading just the session
+metadata when the cache is cold; --index-only lists from the cache index even if it
+is out of date, and never reads the storage.
+
+--filter takes an expression over session fields, for example
+  cursor-session list --filter 'workspace=="api" && messages>10 && created>"2024-06-01"'
+See docs/USAGE.md for the fields and operators.
+
+--flag lists the sessions raising a quality flag, such as aborted for sessions ending
+on an unanswered user message (see 'cursor-session stats --quality').
+
+--preview adds the start of each session's first user prompt and when the assistant
+last responded, to tell apart sessions Cursor left untitled.
+
+--group-by day, week or workspace splits the list into sections under a header giving
+the number of sessions and messages of each, such as "Yesterday, 2024-06-02".
+
+Pinned sessions (see 'cursor-session pin') are listed first, and archived sessions
+(see 'cursor-session archive') are left out unless --include-archived is given.
+
+--json prints the sessions as a JSON array of their index entries, with full IDs and
+the storage each was read from (backend and source_path), for scripts:
+  cursor-session list --json | jq -r '.[] | select(.message_count > 10) | .id'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if listThread && listAllWorkspaces {
+			return usageErrorf("--thread cannot be combined with --all-workspaces")
+		}
+		if listBranch != "" && listAllWorkspaces {
+			return usageErrorf("--branch cannot be combined with --all-workspaces")
+		}
+		if listFilter != "" && listAllWorkspaces {
+			return usageErrorf("--filter cannot be combined with --all-workspaces")
+		}
+		filter, err := parseFilterFlag(listFilter)
+		if err != nil {
+			return err
+		}
+		if listIndexOnly && (listAllWorkspaces || listThread || listClearCache) {
+			return usageErrorf("--index-only cannot be combined with --all-workspaces, --thread or --clear-cache")
+		}
+		qualityFlags, err := internal.NewQualityFlags(listFlags)
+		if err != nil {
+			return &usageError{err: err}
+		}
+		if len(qualityFlags) > 0 && (listAllWorkspaces || listThread || listIndexOnly) {
+			return usageErrorf("--flag cannot be combined with --all-workspaces, --thread or --index-only")
+		}
+		if listPreview && (listAllWorkspaces || listThread) {
+			return usageErrorf("--preview cannot be combined with --all-workspaces or --thread")
+		}
+		if listJSON && (listAllWorkspaces || listThread || listCount || listGroupBy != "") {
+			return usageErrorf("--json cannot be combined with --all-workspaces, --thread, --count or --group-by")
+		}
+		if listGroupBy != "" {
+			if !slices.Contains(internal.SessionGroupings, listGroupBy) {
+				return usageErrorf("unsupported --group-by %s (expected %s)", listGroupBy, strings.Join(internal.SessionGroupings, ", "))
+			}
+			if listAllWorkspaces || listThread || listCount {
+				return usageErrorf("--group-by cannot be combined with --all-workspaces, --thread or --count")
+			}
+		}
+
 		// Get paths (with optional custom storage location)
-		paths, err := internal.GetStoragePaths(storagePath)
+		paths, err := resolveStoragePaths()
 		if err != nil {
 			return fmt.Errorf("failed to get storage paths: %w", err)
 		}
 
-		// Copy database files to temp location if --copy flag is set
+		// Copy database files to temp location if --read-strategy (or --copy) asks for it
 		var cleanup func() error
-		if copyDB {
+		if copyStorage(paths...) {
 			var copyErr error
-			paths, cleanup, copyErr = internal.CopyStoragePaths(paths)
+			paths, cleanup, copyErr = internal.CopyStoragePathsList(paths)
 			if copyErr != nil {
 				return fmt.Errorf("failed to copy database files: %w", copyErr)
 			}
@@ -77,21 +166,66 @@ var listCmd = &cobra.Command{
 			}()
 		}
 
-		// Create storage backend (handles both desktop app and agent storage)
-		backend, err := internal.NewStorageBackend(paths)
-		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %w", err)
+		// Create storage backend (handles both desktop app and agent storage) only when the
+		// cache cannot answer, so a warm cache never opens the databases
+		openBackend := func() (internal.StorageBackend, error) {
+			backend, err := internal.NewStorageBackendForPaths(paths)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			return backend, nil
 		}
 
 		// Initialize cache manager (always enabled)
-		// Store cache in user's home directory root
-		homeDir, err := os.UserHomeDir()
+		cacheDir, err := cacheDirectory()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
 		}
-		cacheDir := filepath.Join(homeDir, ".cursor-session-cache")
 		cacheManager := internal.NewCacheManager(cacheDir)
 
+		// Tags live outside the index so they survive cache rebuilds
+		tags, err := cacheManager.LoadTags()
+		if err != nil {
+			internal.LogWarn("Failed to load tags: %v", err)
+		}
+		marks, err := cacheManager.LoadMarks()
+		if err != nil {
+			internal.LogWarn("Failed to load pinned and archived sessions: %v", err)
+		}
+
+		// Merge legacy chat pane sessions from workspaceStorage/*/state.vscdb.
+		// The cache index only covers composer sessions, so read storage directly.
+		if listAllWorkspaces {
+			backend, err := openBackend()
+			if err != nil {
+				// Workspace databases may still hold legacy chats
+				internal.LogWarn("No composer storage found, listing workspace chats only")
+				backend = nil
+			}
+			backends := []internal.StorageBackend{backend}
+			for _, p := range paths {
+				workspaceBackend, err := internal.NewWorkspaceStorageBackend(p)
+				if err != nil {
+					return fmt.Errorf("failed to initialize workspace storage: %w", err)
+				}
+				backends = append(backends, workspaceBackend)
+			}
+
+			multiBackend := internal.NewMultiBackend(backends...)
+			composers, err := multiBackend.LoadComposers()
+			if err != nil {
+				return fmt.Errorf("failed to load composers: %w", err)
+			}
+			titleComposers(multiBackend, composers)
+
+			composers = markComposers(filterComposersByTag(composers, tags, listTag), marks)
+			if listCount {
+				return printCount(out, len(composers))
+			}
+			displaySessionsFromComposers(out, composers, tags, marks)
+			return nil
+		}
+
 		// Clear cache if requested
 		if listClearCache {
 			if err := cacheManager.ClearCache(); err != nil {
@@ -101,15 +235,56 @@ var listCmd = &cobra.Command{
 			}
 		}
 
+		// Group resumed sessions into threads; this needs every session's messages
+		if listThread {
+			backend, err := openBackend()
+			if err != nil {
+				return err
+			}
+			sessions, err := loadSessions(backend, paths)
+			if err != nil {
+				return err
+			}
+			hideGeneratedTitles(sessions)
+			applySessionTags(sessions)
+			threads := filterThreadsByTag(internal.BuildThreads(sessions), listTag)
+			threads = filterThreadsByBranch(threads, listBranch)
+			threads = filterThreadsByExpr(threads, filter)
+			if listCount {
+				return printCount(out, len(threads))
+			}
+			displayThreads(out, threads)
+			return nil
+		}
+
+		// Quality flags are assessed from every session's messages
+		if len(qualityFlags) > 0 {
+			backend, err := openBackend()
+			if err != nil {
+				return err
+			}
+			sessions, err := loadSessions(backend, paths)
+			if err != nil {
+				return err
+			}
+			hideGeneratedTitles(sessions)
+			applySessionTags(sessions)
+			index := &internal.SessionIndex{}
+			for _, session := range internal.FilterSessionsByQuality(sessions, qualityFlags) {
+				index.Sessions = append(index.Sessions, internal.NewSessionIndexEntry(session))
+			}
+			return listIndex(out, index, tags, marks, filter)
+		}
+
 		// Use appropriate cache key based on storage type
-		var cacheKey string
-		if paths.GlobalStorageExists() {
-			cacheKey = paths.GetGlobalStorageDBPath()
-		} else if paths.HasAgentStorage() {
-			// Use agent storage path as cache key
-			cacheKey = paths.AgentStoragePath
-		} else {
-			cacheKey = "unknown"
+		cacheKey := storageCacheKey(paths)
+
+		if listIndexOnly {
+			index, err := loadIndexOnly(cacheManager, cacheKey)
+			if err != nil {
+				return err
+			}
+			return listIndex(out, index, tags, marks, filter)
 		}
 
 		// Try to load from cache
@@ -126,37 +301,304 @@ var listCmd = &cobra.Command{
 			}
 		}
 
-		// Load from storage if cache miss
-		if index == nil {
-			// Load composers
-			composers, err := backend.LoadComposers()
+		if index != nil {
+			return listIndex(out, index, tags, marks, filter)
+		}
+		backend, err := openBackend()
+		if err != nil {
+			return err
+		}
+
+		// Branches come from message contexts, which listing composers does not read, and
+		// filter expressions, previews, groups and JSON output need full index entries
+		if listBranch != "" || filter != nil || listPreview || listGroupBy != "" || listJSON {
+			sessions, err := loadSessions(backend, paths)
+			if err != nil {
+				return err
+			}
+			index = &internal.SessionIndex{}
+			for _, session := range sessions {
+				index.Sessions = append(index.Sessions, internal.NewSessionIndexEntry(session))
+			}
+			return listIndex(out, index, tags, marks, filter)
+		}
+
+		// Counting needs neither message content nor titles
+		if listCount {
+			composers, err := internal.LoadComposerMetadata(backend)
 			if err != nil {
 				return fmt.Errorf("failed to load composers: %w", err)
 			}
+			return printCount(out, len(markComposers(filterComposersByTag(composers, tags, listTag), marks)))
+		}
 
-			// Display sessions from storage
-			displaySessionsFromComposers(composers)
-			return nil
+		// Load composers from storage on a cache miss
+		composers, err := backend.LoadComposers()
+		if err != nil {
+			return fmt.Errorf("failed to load composers: %w", err)
 		}
+		titleComposers(backend, composers)
 
-		// Display sessions from cache index
-		displaySessionsFromIndex(index)
+		// Display sessions from storage
+		displaySessionsFromComposers(out, markComposers(filterComposersByTag(composers, tags, listTag), marks), tags, marks)
 		return nil
 	},
 }
 
-func displaySessionsFromComposers(composers []*internal.RawComposer) {
+// loadIndexOnly returns the cached session index of the storage location with the given
+// cache key, whether or not it is up to date, without reading the storage
+func loadIndexOnly(cacheManager *internal.CacheManager, cacheKey string) (*internal.SessionIndex, error) {
+	if cacheKey == "" {
+		return nil, usageErrorf("--index-only cannot be used with several storage locations, which are never cached")
+	}
+	index, err := cacheManager.LoadIndex()
+	if err != nil || index.Metadata.DatabasePath != cacheKey {
+		return nil, fmt.Errorf("no cached session index for %s; it is built the first time sessions are loaded, e.g. by show or export", cacheKey)
+	}
+	if valid, _ := cacheManager.IsCacheValid(cacheKey); !valid {
+		internal.LogWarn("The cached session index is out of date; run list without --index-only to see recent changes")
+	}
+	return index, nil
+}
+
+// listIndex prints the sessions of a cache index that match --tag, --branch and --filter,
+// pinned ones first and archived ones only with --include-archived, or their number with
+// --count
+func listIndex(out io.Writer, index *internal.SessionIndex, tags *internal.TagStore, marks *internal.SessionMarks, filter *internal.FilterExpr) error {
+	index = filterIndexByBranch(filterIndexByTag(index, tags, listTag), listBranch)
+	index = filterIndexByExpr(index, filter)
+	marked := *index
+	marked.Sessions = marks.Apply(index.Sessions, listArchived)
+	index = &marked
+	if listCount {
+		return printCount(out, len(index.Sessions))
+	}
+	if listJSON {
+		return printIndexJSON(out, index.Sessions)
+	}
+	displaySessionsFromIndex(out, index)
+	return nil
+}
+
+// printIndexJSON prints index entries as a JSON array, for scripts; names generated from
+// the conversation are left out with --no-generated-titles, as in the table
+func printIndexJSON(out io.Writer, entries []internal.SessionIndexEntry) error {
+	listed := make([]internal.SessionIndexEntry, len(entries))
+	for i, entry := range entries {
+		if entry.GeneratedName && noGeneratedTitles {
+			entry.Name, entry.GeneratedName = "", false
+		}
+		listed[i] = entry
+	}
+	data, err := json.MarshalIndent(listed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// printCount prints a number of sessions or threads on its own line, for scripts
+func printCount(out io.Writer, n int) error {
+	_, err := fmt.Fprintln(out, n)
+	return err
+}
+
+// formatCreated renders a creation time relative to now in the display time zone,
+// or with the --time-format layout if one was given
+func formatCreated(t time.Time) string {
+	if internal.HasDisplayLayout() {
+		return internal.FormatDisplayTime(t, "")
+	}
+
+	t = internal.DisplayTime(t)
+	diff := time.Since(t)
+	if diff < 24*time.Hour {
+		return t.Format("Today 15:04")
+	} else if diff < 7*24*time.Hour {
+		return t.Format("Mon 15:04")
+	} else if diff < 365*24*time.Hour {
+		return t.Format("Jan 02 15:04")
+	}
+	return t.Format("2006-01-02")
+}
+
+// filterComposersByTag keeps the composers carrying tag; an empty tag keeps all of them
+func filterComposersByTag(composers []*internal.RawComposer, tags *internal.TagStore, tag string) []*internal.RawComposer {
+	if tag == "" {
+		return composers
+	}
+	filtered := make([]*internal.RawComposer, 0, len(composers))
+	for _, composer := range composers {
+		if tags.HasTag(composer.ComposerID, tag) {
+			filtered = append(filtered, composer)
+		}
+	}
+	return filtered
+}
+
+// markComposers leaves out archived composers, unless --include-archived is given, and
+// moves pinned ones to the top
+func markComposers(composers []*internal.RawComposer, marks *internal.SessionMarks) []*internal.RawComposer {
+	marked := make([]*internal.RawComposer, 0, len(composers))
+	for _, composer := range composers {
+		if listArchived || !marks.IsArchived(composer.ComposerID) {
+			marked = append(marked, composer)
+		}
+	}
+	sort.SliceStable(marked, func(i, j int) bool {
+		return marks.IsPinned(marked[i].ComposerID) && !marks.IsPinned(marked[j].ComposerID)
+	})
+	return marked
+}
+
+// renderMarks renders the pin before the name of a pinned session and the label after
+// the name of an archived one
+func renderMarks(name string, pinned, archived bool) string {
+	if pinned {
+		name = "📌 " + name
+	}
+	if archived {
+		name += " " + dateStyle.Render("(archived)")
+	}
+	return name
+}
+
+// filterIndexByTag refreshes the tags of each index entry from the tag store and keeps the
+// entries carrying tag; an empty tag keeps all of them
+func filterIndexByTag(index *internal.SessionIndex, tags *internal.TagStore, tag string) *internal.SessionIndex {
+	filtered := *index
+	filtered.Sessions = make([]internal.SessionIndexEntry, 0, len(index.Sessions))
+	for _, entry := range index.Sessions {
+		if tags != nil {
+			entry.Tags = tags.Tags(entry.ID)
+		}
+		if tag == "" || tags.HasTag(entry.ID, tag) {
+			filtered.Sessions = append(filtered.Sessions, entry)
+		}
+	}
+	return &filtered
+}
+
+// filterIndexByBranch keeps the index entries whose sessions were on branch; an empty
+// branch keeps all of them
+func filterIndexByBranch(index *internal.SessionIndex, branch string) *internal.SessionIndex {
+	if branch == "" {
+		return index
+	}
+	filtered := *index
+	filtered.Sessions = make([]internal.SessionIndexEntry, 0, len(index.Sessions))
+	for _, entry := range index.Sessions {
+		if entry.Branch == branch {
+			filtered.Sessions = append(filtered.Sessions, entry)
+		}
+	}
+	return &filtered
+}
+
+// filterIndexByExpr keeps the index entries matching a filter expression; a nil filter
+// keeps all of them
+func filterIndexByExpr(index *internal.SessionIndex, filter *internal.FilterExpr) *internal.SessionIndex {
+	if filter == nil {
+		return index
+	}
+	filtered := *index
+	filtered.Sessions = make([]internal.SessionIndexEntry, 0, len(index.Sessions))
+	for _, entry := range index.Sessions {
+		if filter.Match(entry) {
+			filtered.Sessions = append(filtered.Sessions, entry)
+		}
+	}
+	return &filtered
+}
+
+// filterThreadsByTag keeps the threads with at least one session carrying tag; an empty
+// tag keeps all of them
+func filterThreadsByTag(threads []*internal.Thread, tag string) []*internal.Thread {
+	if tag == "" {
+		return threads
+	}
+	filtered := make([]*internal.Thread, 0, len(threads))
+	for _, thread := range threads {
+		for _, session := range thread.Sessions {
+			if hasTag(session.Metadata.Tags, tag) {
+				filtered = append(filtered, thread)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// filterThreadsByBranch keeps the threads with at least one session on branch; an empty
+// branch keeps all of them
+func filterThreadsByBranch(threads []*internal.Thread, branch string) []*internal.Thread {
+	if branch == "" {
+		return threads
+	}
+	filtered := make([]*internal.Thread, 0, len(threads))
+	for _, thread := range threads {
+		for _, session := range thread.Sessions {
+			if session.Metadata.Git.GetBranch() == branch {
+				filtered = append(filtered, thread)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// filterThreadsByExpr keeps the threads with at least one session matching a filter
+// expression; a nil filter keeps all of them
+func filterThreadsByExpr(threads []*internal.Thread, filter *internal.FilterExpr) []*internal.Thread {
+	if filter == nil {
+		return threads
+	}
+	filtered := make([]*internal.Thread, 0, len(threads))
+	for _, thread := range threads {
+		for _, session := range thread.Sessions {
+			if filter.MatchSession(session) {
+				filtered = append(filtered, thread)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// parseFilterFlag parses the expression of a --filter flag; an empty flag gives a nil filter
+func parseFilterFlag(value string) (*internal.FilterExpr, error) {
+	if value == "" {
+		return nil, nil
+	}
+	filter, err := internal.ParseFilterExpr(value)
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+	return filter, nil
+}
+
+// renderTags renders tags as #tag labels after a session name
+func renderTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + tagStyle.Render("#"+strings.Join(tags, " #"))
+}
+
+func displaySessionsFromComposers(out io.Writer, composers []*internal.RawComposer, tags *internal.TagStore, marks *internal.SessionMarks) {
+	status := statusOutput(out)
 	if len(composers) == 0 {
-		fmt.Println(headerStyle.Render("📋 No sessions found"))
+		_, _ = fmt.Fprintln(status, headerStyle.Render("📋 No sessions found"))
 		return
 	}
 
 	header := headerStyle.Render(fmt.Sprintf("📋 Found %d session(s)", len(composers)))
-	fmt.Println(header)
-	fmt.Println()
+	_, _ = fmt.Fprintln(status, header)
+	_, _ = fmt.Fprintln(status)
 
 	// Use tabwriter for aligned columns with better spacing
-	w := tabwriter.NewWriter(lipgloss.DefaultRenderer().Output(), 0, 0, 3, ' ', tabwriter.AlignRight)
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight)
 
 	// Header row - cleaner format
 	_, _ = fmt.Fprintln(w, titleStyle.Render("ID")+"\t"+titleStyle.Render("Name")+"\t"+titleStyle.Render("Messages")+"\t"+titleStyle.Render("Created")+"\t")
@@ -173,7 +615,7 @@ func displaySessionsFromComposers(composers []*internal.RawComposer) {
 			name = name[:47] + "..."
 		}
 		nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-		name = nameStyle.Render(name)
+		name = renderMarks(nameStyle.Render(name), marks.IsPinned(composer.ComposerID), marks.IsArchived(composer.ComposerID)) + renderTags(tags.Tags(composer.ComposerID))
 
 		msgCount := "0"
 		if len(composer.FullConversationHeadersOnly) > 0 {
@@ -182,18 +624,7 @@ func displaySessionsFromComposers(composers []*internal.RawComposer) {
 
 		created := ""
 		if composer.CreatedAt > 0 {
-			t := composer.GetCreatedAt()
-			now := time.Now()
-			diff := now.Sub(t)
-			if diff < 24*time.Hour {
-				created = dateStyle.Render(t.Format("Today 15:04"))
-			} else if diff < 7*24*time.Hour {
-				created = dateStyle.Render(t.Format("Mon 15:04"))
-			} else if diff < 365*24*time.Hour {
-				created = dateStyle.Render(t.Format("Jan 02 15:04"))
-			} else {
-				created = dateStyle.Render(t.Format("2006-01-02"))
-			}
+			created = dateStyle.Render(formatCreated(composer.GetCreatedAt()))
 		} else {
 			created = dateStyle.Render("—")
 		}
@@ -209,33 +640,56 @@ func displaySessionsFromComposers(composers []*internal.RawComposer) {
 	}
 
 	_ = w.Flush()
-	fmt.Println()
+	_, _ = fmt.Fprintln(status)
 	if len(composers) > 0 {
-		fmt.Println(idStyle.Render("💡 Tip: Use the full ID (e.g., ") +
-			lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Render(composers[0].ComposerID) +
+		_, _ = fmt.Fprintln(status, idStyle.Render("💡 Tip: Use the full ID (e.g., ")+
+			lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Render(composers[0].ComposerID)+
 			idStyle.Render(") with `cursor-session show <id>`"))
 	}
 }
 
-func displaySessionsFromIndex(index *internal.SessionIndex) {
+func displaySessionsFromIndex(out io.Writer, index *internal.SessionIndex) {
+	status := statusOutput(out)
 	if len(index.Sessions) == 0 {
-		fmt.Println(headerStyle.Render("📋 No sessions found"))
+		_, _ = fmt.Fprintln(status, headerStyle.Render("📋 No sessions found"))
 		return
 	}
 
 	header := headerStyle.Render(fmt.Sprintf("📋 Found %d session(s)", len(index.Sessions)))
-	fmt.Println(header)
-	fmt.Println()
+	_, _ = fmt.Fprintln(status, header)
+	_, _ = fmt.Fprintln(status)
+
+	// Sessions are listed group after group, each under its header
+	entries := index.Sessions
+	var groups []internal.SessionGroup
+	if listGroupBy != "" {
+		groups, _ = internal.GroupSessionIndex(index.Sessions, listGroupBy, time.Now())
+		entries = make([]internal.SessionIndexEntry, 0, len(index.Sessions))
+		for _, group := range groups {
+			entries = append(entries, group.Entries...)
+		}
+	}
 
-	// Use tabwriter for aligned columns with better spacing
-	w := tabwriter.NewWriter(lipgloss.DefaultRenderer().Output(), 0, 0, 3, ' ', tabwriter.AlignRight)
+	// Use tabwriter for aligned columns with better spacing; the rows are laid out together
+	// so columns line up across groups
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 3, ' ', tabwriter.AlignRight)
 
 	// Header row - cleaner format
-	_, _ = fmt.Fprintln(w, titleStyle.Render("ID")+"\t"+titleStyle.Render("Name")+"\t"+titleStyle.Render("Messages")+"\t"+titleStyle.Render("Created")+"\t"+titleStyle.Render("Workspace")+"\t")
-	_, _ = fmt.Fprintln(w, strings.Repeat("─", 120))
+	columns := titleStyle.Render("ID") + "\t" + titleStyle.Render("Name") + "\t" + titleStyle.Render("Messages") + "\t" + titleStyle.Render("Created") + "\t" + titleStyle.Render("Workspace") + "\t"
+	rule := 120
+	if listPreview {
+		columns += titleStyle.Render("Last Response") + "\t" + titleStyle.Render("Preview") + "\t"
+		rule += 20 + previewWidth
+	}
+	_, _ = fmt.Fprintln(w, columns)
+	_, _ = fmt.Fprintln(w, strings.Repeat("─", rule))
 
-	for _, entry := range index.Sessions {
+	for _, entry := range entries {
 		name := entry.Name
+		if entry.GeneratedName && noGeneratedTitles {
+			name = ""
+		}
 		if name == "" {
 			name = "Untitled"
 		}
@@ -245,7 +699,7 @@ func displaySessionsFromIndex(index *internal.SessionIndex) {
 			name = name[:47] + "..."
 		}
 		nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-		name = nameStyle.Render(name)
+		name = renderMarks(nameStyle.Render(name), entry.Pinned, entry.Archived) + renderTags(entry.Tags)
 
 		msgCount := countStyle.Render(strconv.Itoa(entry.MessageCount))
 
@@ -253,17 +707,7 @@ func displaySessionsFromIndex(index *internal.SessionIndex) {
 		if entry.CreatedAt != "" {
 			// Parse and format date
 			if t, err := time.Parse(time.RFC3339, entry.CreatedAt); err == nil {
-				now := time.Now()
-				diff := now.Sub(t)
-				if diff < 24*time.Hour {
-					created = dateStyle.Render(t.Format("Today 15:04"))
-				} else if diff < 7*24*time.Hour {
-					created = dateStyle.Render(t.Format("Mon 15:04"))
-				} else if diff < 365*24*time.Hour {
-					created = dateStyle.Render(t.Format("Jan 02 15:04"))
-				} else {
-					created = dateStyle.Render(t.Format("2006-01-02"))
-				}
+				created = dateStyle.Render(formatCreated(t))
 			} else {
 				created = dateStyle.Render(entry.CreatedAt[:10])
 			}
@@ -295,19 +739,152 @@ func displaySessionsFromIndex(index *internal.SessionIndex) {
 		}
 		id := idStyle.Render(shortID)
 
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", id, name, msgCount, created, workspace)
+		if !listPreview {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", id, name, msgCount, created, workspace)
+			continue
+		}
+
+		lastResponse := dateStyle.Render("—")
+		if t, err := time.Parse(time.RFC3339, entry.LastResponseAt); err == nil {
+			lastResponse = dateStyle.Render(formatCreated(t))
+		}
+		preview := dateStyle.Render("—")
+		if entry.Preview != "" {
+			preview = truncatePreview(entry.Preview)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", id, name, msgCount, created, workspace, lastResponse, preview)
 	}
 
 	_ = w.Flush()
-	fmt.Println()
+	writeGroupedTable(out, table.String(), groups)
+	_, _ = fmt.Fprintln(status)
 	if len(index.Sessions) > 0 {
-		fmt.Println(idStyle.Render("💡 Tip: Use the full ID (e.g., ") +
-			lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Render(index.Sessions[0].ComposerID) +
+		_, _ = fmt.Fprintln(status, idStyle.Render("💡 Tip: Use the full ID (e.g., ")+
+			lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Render(index.Sessions[0].ComposerID)+
 			idStyle.Render(") with `cursor-session show <id>`"))
 	}
 }
 
+// writeGroupedTable writes a table of a header row, a rule and one row per session, with
+// the header of each group before its rows; without groups the table is written as is
+func writeGroupedTable(out io.Writer, table string, groups []internal.SessionGroup) {
+	if len(groups) == 0 {
+		_, _ = io.WriteString(out, table)
+		return
+	}
+	lines := strings.SplitAfter(table, "\n")
+	_, _ = io.WriteString(out, lines[0]+lines[1])
+	row := 2
+	for _, group := range groups {
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, groupStyle.Render(fmt.Sprintf("▸ %s · %d session(s), %d message(s)", group.Label, len(group.Entries), group.Messages)))
+		for range group.Entries {
+			_, _ = io.WriteString(out, lines[row])
+			row++
+		}
+	}
+}
+
+// displayThreads lists threads with the sessions each one was resumed through
+func displayThreads(out io.Writer, threads []*internal.Thread) {
+	status := statusOutput(out)
+	if len(threads) == 0 {
+		_, _ = fmt.Fprintln(status, headerStyle.Render("📋 No sessions found"))
+		return
+	}
+
+	header := headerStyle.Render(fmt.Sprintf("📋 Found %d thread(s)", len(threads)))
+	_, _ = fmt.Fprintln(status, header)
+	_, _ = fmt.Fprintln(status)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(w, titleStyle.Render("ID")+"\t"+titleStyle.Render("Name")+"\t"+titleStyle.Render("Messages")+"\t"+titleStyle.Render("Created")+"\t")
+	_, _ = fmt.Fprintln(w, strings.Repeat("─", 100))
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	for _, thread := range threads {
+		first := thread.Sessions[0]
+		name := sessionDisplayName(first)
+		if len(thread.Sessions) > 1 {
+			name += fmt.Sprintf(" (%d sessions)", len(thread.Sessions))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
+			idStyle.Render(shortSessionID(thread.ID)),
+			nameStyle.Render(name)+renderTags(first.Metadata.Tags),
+			countStyle.Render(strconv.Itoa(thread.MessageCount())),
+			dateStyle.Render(formatSessionCreated(first)))
+
+		if len(thread.Sessions) == 1 {
+			continue
+		}
+		for _, session := range thread.Sessions {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
+				idStyle.Render("  └ "+shortSessionID(session.ID)),
+				dateStyle.Render(sessionDisplayName(session))+renderTags(session.Metadata.Tags),
+				dateStyle.Render(strconv.Itoa(len(session.Messages))),
+				dateStyle.Render(formatSessionCreated(session)))
+		}
+	}
+
+	_ = w.Flush()
+	_, _ = fmt.Fprintln(status)
+	_, _ = fmt.Fprintln(status, idStyle.Render("💡 Tip: Use any session ID with `cursor-session show --thread <id>` to read the whole thread"))
+}
+
+// truncatePreview shortens a prompt preview to the width of its column, at a word boundary
+func truncatePreview(preview string) string {
+	runes := []rune(preview)
+	if len(runes) <= previewWidth {
+		return preview
+	}
+	cut := string(runes[:previewWidth-3])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "..."
+}
+
+// sessionDisplayName returns a session's name truncated for listings, or "Untitled"
+func sessionDisplayName(session *internal.Session) string {
+	name := session.Metadata.Name
+	if name == "" {
+		name = "Untitled"
+	}
+	if len(name) > 50 {
+		name = name[:47] + "..."
+	}
+	return name
+}
+
+// shortSessionID returns the first 8 characters of a session ID
+func shortSessionID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// formatSessionCreated formats a session's creation time for listings, or "—" if unknown
+func formatSessionCreated(session *internal.Session) string {
+	if t, err := time.Parse(time.RFC3339, session.Metadata.CreatedAt); err == nil {
+		return formatCreated(t)
+	}
+	return "—"
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolVar(&listClearCache, "clear-cache", false, "Clear the cache before running")
+	listCmd.Flags().BoolVar(&listAllWorkspaces, "all-workspaces", false, "Include legacy chat pane sessions from workspaceStorage databases")
+	listCmd.Flags().StringVar(&listTag, "tag", "", "Only list sessions with this tag")
+	listCmd.Flags().BoolVar(&listThread, "thread", false, "Group resumed sessions into threads")
+	listCmd.Flags().StringVar(&listBranch, "branch", "", "Only list sessions recorded on this git branch")
+	listCmd.Flags().StringSliceVar(&listFlags, "flag", nil, "Only list sessions raising this quality flag ("+strings.Join(internal.QualityFlags, ", ")+"); repeat to require several")
+	listCmd.Flags().StringVar(&listFilter, "filter", "", "Only list sessions matching a filter expression, e.g. 'messages>10 && workspace~\"api\"'")
+	listCmd.Flags().BoolVar(&listCount, "count", false, "Print only the number of sessions")
+	listCmd.Flags().BoolVar(&listPreview, "preview", false, "Show the start of each session's first user prompt and when the assistant last responded")
+	listCmd.Flags().StringVar(&listGroupBy, "group-by", "", "Split the list into sections by the day or week sessions were created, or by workspace, with subtotals ("+strings.Join(internal.SessionGroupings, ", ")+")")
+	listCmd.Flags().BoolVar(&listArchived, "include-archived", false, "Also list archived sessions")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the sessions as a JSON array with full IDs and where each was read from")
+	listCmd.Flags().BoolVar(&listIndexOnly, "index-only", false, "List from the cache index, even if it is out of date, without reading the storage")
 }
@@ -2,12 +2,59 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
 )
 
+// clearListFlags resets --storage and the list flags to their defaults; rootCmd keeps flag
+// values between executions
+func clearListFlags() {
+	storagePaths = nil
+	listClearCache, listAllWorkspaces, listThread, listCount, listIndexOnly = false, false, false, false, false
+	listPreview, listJSON, listArchived = false, false, false
+	listTag, listBranch, listFilter, listGroupBy = "", "", "", ""
+	listFlags = nil
+}
+
+// resetListFlags clears the list flags now and again when the test ends
+func resetListFlags(t *testing.T) {
+	t.Helper()
+	clearListFlags()
+	t.Cleanup(clearListFlags)
+}
+
+// runList runs list with args and fresh flags, and returns what it printed
+func runList(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	clearListFlags()
+	var out bytes.Buffer
+	rootCmd.SetArgs(append([]string{"list"}, args...))
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// runListFromStorageAndCache runs list with args twice and checks the output of each run:
+// the first run reads the storage and fills the cache, the second reads the cache index
+func runListFromStorageAndCache(t *testing.T, args []string, check func(run, out string)) {
+	t.Helper()
+	for _, run := range []string{"storage", "cache"} {
+		out, err := runList(t, args...)
+		if err != nil {
+			t.Fatalf("list %v from %s error = %v", args, run, err)
+		}
+		check(run, out)
+	}
+}
+
 func TestListCommand_FlagParsing(t *testing.T) {
 	// Test that flags are parsed correctly
 	tests := []struct {
@@ -22,10 +69,15 @@ func TestListCommand_FlagParsing(t *testing.T) {
 			name: "list with clear-cache flag",
 			args: []string{"list", "--clear-cache"},
 		},
+		{
+			name: "list with all-workspaces flag",
+			args: []string{"list", "--all-workspaces"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			resetListFlags(t)
 			rootCmd.SetArgs(tt.args)
 			rootCmd.SetOut(&bytes.Buffer{})
 			rootCmd.SetErr(&bytes.Buffer{})
@@ -111,15 +163,9 @@ func TestDisplaySessionsFromComposers(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			// Capture output
-			originalOutput := lipgloss.DefaultRenderer().Output()
-			defer func() {
-				// Restore original output
-				_ = originalOutput
-			}()
 
 			// Test that function doesn't panic
-			displaySessionsFromComposers(tt.composers)
+			displaySessionsFromComposers(&buf, tt.composers, nil, nil)
 			_ = buf.String() // Just verify it doesn't panic
 		})
 	}
@@ -135,7 +181,7 @@ func TestDisplaySessionsFromIndex(t *testing.T) {
 			index: &internal.SessionIndex{
 				Sessions: []internal.SessionIndexEntry{},
 				Metadata: internal.CacheMetadata{
-					CacheVersion: "1.0",
+					CacheVersion: internal.CacheFormatVersion,
 				},
 			},
 		},
@@ -152,7 +198,7 @@ func TestDisplaySessionsFromIndex(t *testing.T) {
 					},
 				},
 				Metadata: internal.CacheMetadata{
-					CacheVersion: "1.0",
+					CacheVersion: internal.CacheFormatVersion,
 				},
 			},
 		},
@@ -161,7 +207,326 @@ func TestDisplaySessionsFromIndex(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Test that function doesn't panic
-			displaySessionsFromIndex(tt.index)
+			var buf bytes.Buffer
+			displaySessionsFromIndex(&buf, tt.index)
+		})
+	}
+}
+
+func TestFilterIndexByTag(t *testing.T) {
+	index := &internal.SessionIndex{
+		Sessions: []internal.SessionIndexEntry{
+			{ID: "session1", ComposerID: "session1", Tags: []string{"stale"}},
+			{ID: "session2", ComposerID: "session2"},
+		},
+	}
+	tags := &internal.TagStore{Sessions: map[string][]string{"session2": {"bug-hunt"}}}
+
+	all := filterIndexByTag(index, tags, "")
+	if len(all.Sessions) != 2 || all.Sessions[0].Tags != nil || all.Sessions[1].Tags[0] != "bug-hunt" {
+		t.Errorf("filterIndexByTag() without a tag = %+v, want both sessions with tags from the store", all.Sessions)
+	}
+
+	tagged := filterIndexByTag(index, tags, "Bug-Hunt")
+	if len(tagged.Sessions) != 1 || tagged.Sessions[0].ID != "session2" {
+		t.Errorf("filterIndexByTag() = %+v, want session2 only", tagged.Sessions)
+	}
+
+	if got := filterComposersByTag([]*internal.RawComposer{{ComposerID: "session1"}, {ComposerID: "session2"}}, tags, "bug-hunt"); len(got) != 1 || got[0].ComposerID != "session2" {
+		t.Errorf("filterComposersByTag() = %v, want session2 only", got)
+	}
+}
+
+func TestDisplayThreads(t *testing.T) {
+	original := internal.CreateTestSessionWithMessages("original-session", []internal.Message{
+		{Actor: "user", Content: "fix the bug"},
+		{Actor: "assistant", Content: "done"},
+	})
+	original.Metadata.Name = "Bug fix"
+	original.Metadata.CreatedAt = "2024-01-01T00:00:00Z"
+	resumed := internal.CreateTestSessionWithMessages("resumed-session", []internal.Message{
+		{Actor: "user", Content: "add tests"},
+	})
+	resumed.Metadata.ParentID = "original-session"
+	resumed.Metadata.CreatedAt = "2024-01-02T00:00:00Z"
+	resumed.Metadata.Tags = []string{"testing"}
+
+	threads := internal.BuildThreads([]*internal.Session{original, resumed})
+
+	var buf bytes.Buffer
+	displayThreads(&buf, threads)
+	output := buf.String()
+	for _, want := range []string{"1 thread(s)", "Bug fix (2 sessions)", "original", "resumed-"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("displayThreads() output missing %q:\n%s", want, output)
+		}
+	}
+
+	if got := filterThreadsByTag(threads, "testing"); len(got) != 1 {
+		t.Errorf("filterThreadsByTag(testing) = %d thread(s), want 1", len(got))
+	}
+	if got := filterThreadsByTag(threads, "other"); len(got) != 0 {
+		t.Errorf("filterThreadsByTag(other) = %d thread(s), want 0", len(got))
+	}
+}
+
+func TestListCommand_Branch(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for id, branch := range map[string]string{"feature-session": "feature/x", "main-session": "main"} {
+		session := internal.CreateTestSession(id)
+		session.Metadata.Git = &internal.GitInfo{Branch: branch}
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	runListFromStorageAndCache(t, []string{"--storage", dir, "--branch", "feature/x"}, func(run, out string) {
+		if !strings.Contains(out, "Found 1 session(s)") || !strings.Contains(out, "feature-") {
+			t.Errorf("list --branch from %s should only show feature-session, got:\n%s", run, out)
+		}
+	})
+
+	if _, err := runList(t, "--storage", dir, "--branch", "main", "--all-workspaces"); exitCode(err) != exitUsage {
+		t.Errorf("list --branch --all-workspaces error = %v, want a usage error", err)
+	}
+}
+
+func TestListCommand_Filter(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for id, workspace := range map[string]string{"api-session": "/src/api", "web-session": "/src/web"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		session.Workspace = workspace
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	runListFromStorageAndCache(t, []string{"--storage", dir, "--filter", `workspace ~ "api" && messages > 0`}, func(run, out string) {
+		if !strings.Contains(out, "Found 1 session(s)") || !strings.Contains(out, "api-sess") {
+			t.Errorf("list --filter from %s should only show api-session, got:\n%s", run, out)
+		}
+	})
+
+	if _, err := runList(t, "--storage", dir, "--filter", "colour == \"red\""); exitCode(err) != exitUsage {
+		t.Errorf("list --filter with an unknown field error = %v, want a usage error", err)
+	}
+	if _, err := runList(t, "--storage", dir, "--filter", "messages > 0", "--all-workspaces"); exitCode(err) != exitUsage {
+		t.Errorf("list --filter --all-workspaces error = %v, want a usage error", err)
+	}
+}
+
+func TestListCommand_CountAndIndexOnly(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	for _, id := range []string{"first-session", "second-session"} {
+		session := internal.CreateTestSessionWithMessages(id, []internal.Message{{Actor: "user", Content: "Hello from " + id}})
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		return runList(t, append([]string{"--storage", dir}, args...)...)
+	}
+
+	if out, err := run("--count"); err != nil || out != "2\n" {
+		t.Errorf("list --count on a cold cache = %q, %v, want 2", out, err)
+	}
+	if _, err := run("--index-only"); err == nil {
+		t.Error("list --index-only without a cached index succeeded, want an error")
+	}
+
+	// Loading the sessions builds the index
+	if out, err := run("--thread", "--count"); err != nil || out != "2\n" {
+		t.Errorf("list --thread --count = %q, %v, want 2", out, err)
+	}
+	if out, err := run("--index-only", "--count"); err != nil || out != "2\n" {
+		t.Errorf("list --index-only --count = %q, %v, want 2", out, err)
+	}
+	if out, err := run("--index-only"); err != nil || !strings.Contains(out, "Found 2 session(s)") {
+		t.Errorf("list --index-only = %q, %v, want both sessions", out, err)
+	}
+
+	if _, err := run("--index-only", "--clear-cache"); exitCode(err) != exitUsage {
+		t.Errorf("list --index-only --clear-cache error = %v, want a usage error", err)
+	}
+}
+
+func TestListCommand_QualityFlag(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	sessions := map[string][]internal.Message{
+		"aborted-session": {{Actor: "user", Content: "Is anyone there?"}},
+		"healthy-session": {
+			{Actor: "user", Content: "Hello"},
+			{Actor: "assistant", Content: "Hello! What would you like to work on?"},
+		},
+	}
+	for id, messages := range sessions {
+		session := internal.CreateTestSessionWithMessages(id, messages)
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	out, err := runList(t, "--storage", dir, "--flag", "aborted")
+	if err != nil {
+		t.Fatalf("list --flag error = %v", err)
+	}
+	if !strings.Contains(out, "Found 1 session(s)") || !strings.Contains(out, "aborted-") {
+		t.Errorf("list --flag aborted should only show aborted-session, got:\n%s", out)
+	}
+
+	if _, err := runList(t, "--storage", dir, "--flag", "boring"); exitCode(err) != exitUsage {
+		t.Errorf("list --flag with an unknown flag error = %v, want a usage error", err)
+	}
+}
+
+func TestListCommand_Preview(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	sessions := map[string][]internal.Message{
+		"login-session": {
+			{Actor: "user", Content: "Why does the   login form\nsubmit twice when I press enter?"},
+			{Actor: "assistant", Content: "The handler is bound twice.", Timestamp: "2024-06-01T10:00:00Z"},
+		},
+		"quiet-session": {{Actor: "user", Content: strings.Repeat("word ", 40)}},
+	}
+	for id, messages := range sessions {
+		session := internal.CreateTestSessionWithMessages(id, messages)
+		session.Metadata.Name = ""
+		if err := os.WriteFile(filepath.Join(dir, "session_"+id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	runListFromStorageAndCache(t, []string{"--storage", dir, "--preview", "--time-format", "date"}, func(run, got string) {
+		if !strings.Contains(got, "Preview") || !strings.Contains(got, "Why does the login form submit twice when I press enter?") {
+			t.Errorf("list --preview from %s should show the first prompt on one line, got:\n%s", run, got)
+		}
+		if !strings.Contains(got, "2024-06-01") {
+			t.Errorf("list --preview from %s should show when the assistant last responded, got:\n%s", run, got)
+		}
+		if !strings.Contains(got, strings.Repeat("word ", 10)+"word...\n") {
+			t.Errorf("list --preview from %s should truncate long prompts, got:\n%s", run, got)
+		}
+	})
+
+	if _, err := runList(t, "--storage", dir, "--preview", "--thread"); exitCode(err) != exitUsage {
+		t.Errorf("list --preview --thread error = %v, want a usage error", err)
+	}
+}
+
+func TestListCommand_GroupBy(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+	defer func() {
+		timezone, timeFormat, quiet = "", "", false
+		_ = internal.ConfigureTimeDisplay("", "")
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	sessions := []struct {
+		id, workspace, created string
+		messages               int
+	}{
+		{"api-first", "/home/me/api", "2024-06-03T09:00:00Z", 2},
+		{"web-first", "/home/me/web", "2024-06-03T15:00:00Z", 1},
+		{"api-second", "/home/me/api", "2024-06-05T09:00:00Z", 3},
+		{"api-third", "/home/me/api", "2024-06-12T09:00:00Z", 1},
+	}
+	for _, s := range sessions {
+		var messages []internal.Message
+		for i := 0; i < s.messages; i++ {
+			messages = append(messages, internal.Message{Actor: "user", Content: s.id + " message " + strconv.Itoa(i)})
+		}
+		session := internal.CreateTestSessionWithMessages(s.id, messages)
+		session.Workspace = s.workspace
+		session.Metadata.CreatedAt = s.created
+		if err := os.WriteFile(filepath.Join(dir, "session_"+s.id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	tests := []struct {
+		grouping string
+		want     []string
+	}{
+		{"day", []string{"2024-06-03 (Monday) · 2 session(s), 3 message(s)", "2024-06-05 (Wednesday) · 1 session(s), 3 message(s)", "2024-06-12 (Wednesday) · 1 session(s), 1 message(s)"}},
+		{"week", []string{"Week of 2024-06-03 · 3 session(s), 6 message(s)", "Week of 2024-06-10 · 1 session(s), 1 message(s)"}},
+		{"workspace", []string{"api · 3 session(s), 6 message(s)", "web · 1 session(s), 1 message(s)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grouping, func(t *testing.T) {
+			got, err := runList(t, "--storage", dir, "--group-by", tt.grouping, "--timezone", "UTC", "--quiet")
+			if err != nil {
+				t.Fatalf("list --group-by %s error = %v", tt.grouping, err)
+			}
+			last := -1
+			for _, header := range tt.want {
+				i := strings.Index(got, header)
+				if i < 0 || i < last {
+					t.Errorf("list --group-by %s should show %q after the previous header, got:\n%s", tt.grouping, header, got)
+				}
+				last = i
+			}
 		})
 	}
+
+	for _, args := range [][]string{{"--group-by", "month"}, {"--group-by", "day", "--count"}, {"--group-by", "day", "--thread"}} {
+		if _, err := runList(t, append([]string{"--storage", dir}, args...)...); exitCode(err) != exitUsage {
+			t.Errorf("list %v error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestListCommand_JSON(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+
+	dir := testutil.CreateTempDir(t)
+	id := "0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0"
+	session := internal.CreateTestSessionWithMessages(id, []internal.Message{
+		{Actor: "user", Content: "Rename the config loader"},
+		{Actor: "assistant", Content: "Renamed it to LoadConfig."},
+	})
+	session.Workspace = "/home/me/api"
+	path := filepath.Join(dir, "session_"+id+".json")
+	if err := os.WriteFile(path, testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	runListFromStorageAndCache(t, []string{"--storage", dir, "--json"}, func(run, out string) {
+		var entries []map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &entries); err != nil {
+			t.Fatalf("list --json from %s is not a JSON array: %v\n%s", run, err, out)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("list --json from %s listed %d sessions, want 1", run, len(entries))
+		}
+		want := map[string]interface{}{"id": id, "message_count": float64(2), "workspace": "/home/me/api", "backend": internal.BackendExportDir, "source_path": path}
+		for key, value := range want {
+			if entries[0][key] != value {
+				t.Errorf("list --json from %s %s = %v, want %v", run, key, entries[0][key], value)
+			}
+		}
+	})
+
+	for _, args := range [][]string{{"--json", "--count"}, {"--json", "--thread"}, {"--json", "--group-by", "day"}} {
+		if _, err := runList(t, append([]string{"--storage", dir}, args...)...); exitCode(err) != exitUsage {
+			t.Errorf("list %v error = %v, want a usage error", args, err)
+		}
+	}
 }
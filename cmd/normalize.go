@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	normalizeFrom      string
+	normalizeRaw       string
+	normalizeOutput    string
+	normalizeWorkspace string
+)
+
+// normalizeCmd represents the normalize command
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "Turn reconstructed conversations into sessions",
+	Long: `Normalize the conversation_<id>.json files written by 'reconstruct' into sessions,
+associating each with its workspace and removing duplicates, and write them to
+session_<id>.json files in --out.
+
+This is the last phase of the export pipeline run one phase at a time. The
+workspaces are taken from the raw dump written by 'extract' (raw.json in --from,
+or --raw); without one they are read from --storage. The sessions written can be
+listed and exported like any directory of exported sessions:
+
+  cursor-session normalize --from ./intermediary --out ./sessions
+  cursor-session export --storage ./sessions --format md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conversations, err := internal.ReadConversations(normalizeFrom)
+		if err != nil {
+			return err
+		}
+		if len(conversations) == 0 {
+			return fmt.Errorf("no %s files in %s - run 'cursor-session reconstruct' first", internal.ConversationFilePattern, normalizeFrom)
+		}
+
+		rawPath := normalizeRaw
+		if rawPath == "" {
+			if candidate := filepath.Join(normalizeFrom, "raw.json"); fileExists(candidate) {
+				rawPath = candidate
+			}
+		}
+
+		var sessions []*internal.Session
+		if rawPath != "" {
+			dump, err := internal.ReadRawDump(rawPath)
+			if err != nil {
+				return err
+			}
+			sessions = dump.NormalizeSessions(conversations, normalizeWorkspace)
+		} else {
+			internal.LogInfo("No raw dump in %s, reading workspaces from storage", normalizeFrom)
+			backend, paths, cleanup, err := openStorageBackend()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			sessions = internal.NormalizeSessions(conversations, backend, paths, normalizeWorkspace)
+		}
+
+		if err := os.MkdirAll(normalizeOutput, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		exporter, err := export.NewExporter("json")
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			if err := export.WriteSessionFile(exporter, normalizeOutput, session); err != nil {
+				return fmt.Errorf("failed to write session %s: %w", session.ID, err)
+			}
+		}
+
+		internal.PrintSuccess(fmt.Sprintf("Normalization complete: %d session(s) saved to %s", len(sessions), normalizeOutput))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(normalizeCmd)
+	normalizeCmd.Flags().StringVar(&normalizeFrom, "from", "./intermediary", "Directory of conversation_<id>.json files written by 'reconstruct'")
+	normalizeCmd.Flags().StringVar(&normalizeRaw, "raw", "", "Raw dump written by 'extract' to take workspaces from (default: raw.json in --from)")
+	normalizeCmd.Flags().StringVarP(&normalizeOutput, "out", "o", "./sessions", "Output directory for the session files")
+	normalizeCmd.Flags().StringVar(&normalizeWorkspace, "workspace", "", "Assign every session to this workspace")
+}
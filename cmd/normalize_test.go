@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestNormalizeCommand_NoConversations(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() { normalizeFrom = "./intermediary" }()
+
+	rootCmd.SetArgs([]string{"normalize", "--from", testutil.CreateTempDir(t)})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "reconstruct") {
+		t.Errorf("normalize error = %v, want a hint to run reconstruct first", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+// pathsCmd represents the paths command
+var pathsCmd = &cobra.Command{
+	Use:   "paths [cache|config|state|tags]",
+	Short: "Show where cursor-session keeps its files",
+	Long: `Show the directories cursor-session keeps its own files in: the session cache
+and tags, the configuration directory, and the state kept between runs.
+
+On Linux these follow the XDG base directory specification: $XDG_CACHE_HOME/cursor-session
+(~/.cache/cursor-session when unset), $XDG_CONFIG_HOME/cursor-session
+(~/.config/cursor-session) and $XDG_STATE_HOME/cursor-session
+(~/.local/state/cursor-session). On macOS they are ~/Library/Caches/cursor-session
+and ~/Library/Application Support/cursor-session. A cache left in ~/.cursor-session-cache
+by earlier versions is moved to the cache directory on first use.
+
+Name one path to print only it, for scripts:
+  rm -rf "$(cursor-session paths cache)"`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"cache", "config", "state", "tags"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs, err := internal.ResolveAppDirs()
+		if err != nil {
+			return err
+		}
+		cacheDir, err := cacheDirectory()
+		if err != nil {
+			return err
+		}
+		tagsPath := internal.NewCacheManager(cacheDir).GetTagsPath()
+
+		out := commandOutput(cmd)
+		if len(args) == 1 {
+			switch args[0] {
+			case "cache":
+				_, _ = fmt.Fprintln(out, cacheDir)
+			case "config":
+				_, _ = fmt.Fprintln(out, dirs.ConfigDir)
+			case "state":
+				_, _ = fmt.Fprintln(out, dirs.StateDir)
+			case "tags":
+				_, _ = fmt.Fprintln(out, tagsPath)
+			default:
+				return usageErrorf("unknown path %q (expected cache, config, state or tags)", args[0])
+			}
+			return nil
+		}
+
+		_, _ = fmt.Fprintf(out, "Cache:  %s\n", cacheDir)
+		_, _ = fmt.Fprintf(out, "Tags:   %s\n", tagsPath)
+		_, _ = fmt.Fprintf(out, "Config: %s\n", dirs.ConfigDir)
+		_, _ = fmt.Fprintf(out, "State:  %s\n", dirs.StateDir)
+		if cacheDir == dirs.LegacyCacheDir {
+			_, _ = fmt.Fprintf(out, "\nThe cache could not be moved to %s and is still read from its legacy location.\n", dirs.CacheDir)
+		} else if _, err := os.Stat(dirs.LegacyCacheDir); err == nil {
+			_, _ = fmt.Fprintf(out, "\nA legacy cache remains in %s; remove it once you no longer need it.\n", dirs.LegacyCacheDir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pathsCmd)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// testCacheDir returns the cache directory for the HOME the test has set
+func testCacheDir(t *testing.T) string {
+	t.Helper()
+	dirs, err := internal.ResolveAppDirs()
+	if err != nil {
+		t.Fatalf("ResolveAppDirs() error = %v", err)
+	}
+	return dirs.CacheDir
+}
+
+func TestPathsCommand(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	cacheHome := filepath.Join(home, "xdg-cache")
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+
+	// A cache left by an earlier version is moved on first use
+	legacy := filepath.Join(home, ".cursor-session-cache")
+	if err := os.MkdirAll(legacy, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "tags.yaml"), []byte("sessions: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"paths"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("paths error = %v", err)
+	}
+	cacheDir := testCacheDir(t)
+	if !strings.HasPrefix(cacheDir, cacheHome) && !strings.Contains(cacheDir, "Library") {
+		t.Errorf("cache directory %s should follow XDG_CACHE_HOME", cacheDir)
+	}
+	if !strings.Contains(buf.String(), "Cache:  "+cacheDir+"\n") || !strings.Contains(buf.String(), "Tags:   "+filepath.Join(cacheDir, "tags.yaml")+"\n") {
+		t.Errorf("paths output = %q, want the cache directory %s", buf.String(), cacheDir)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "tags.yaml")); err != nil {
+		t.Errorf("legacy cache was not moved: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy cache should be gone, stat error = %v", err)
+	}
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"paths", "cache"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("paths cache error = %v", err)
+	}
+	if buf.String() != cacheDir+"\n" {
+		t.Errorf("paths cache = %q, want %q", buf.String(), cacheDir+"\n")
+	}
+
+	buf.Reset()
+	stateHome := filepath.Join(home, "xdg-state")
+	t.Setenv("XDG_STATE_HOME", stateHome)
+	rootCmd.SetArgs([]string{"paths", "state"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("paths state error = %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); !strings.HasPrefix(got, stateHome) && !strings.Contains(got, "Library") {
+		t.Errorf("paths state = %q, want a directory under XDG_STATE_HOME", got)
+	}
+
+	rootCmd.SetArgs([]string{"paths", "data"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("paths data exit code = %d, want %d", exitCode(err), exitUsage)
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pinRemove     bool
+	archiveRemove bool
+)
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin <session-id>",
+	Short: "Pin a session to the top of the list",
+	Long: `Pin a session, or unpin it with --remove. Pinned sessions are listed first by
+'list', in their usual order among themselves.
+
+Pins are kept across cache rebuilds. The session can be given as a full ID or a
+unique ID prefix.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return markSession(cmd, args[0], func(marks *internal.SessionMarks, sessionID string) string {
+			if !pinRemove {
+				marks.SetPinned(sessionID, true)
+				return "📌 Pinned " + sessionID
+			}
+			if !marks.SetPinned(sessionID, false) {
+				return sessionID + " is not pinned"
+			}
+			return "Unpinned " + sessionID
+		})
+	},
+}
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive <session-id>",
+	Short: "Hide a session from the list",
+	Long: `Archive a session, or restore it with --remove. Archived sessions are left out of
+'list' unless --include-archived is given; they can still be shown and exported by
+ID.
+
+Archives are kept across cache rebuilds. The session can be given as a full ID or a
+unique ID prefix.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return markSession(cmd, args[0], func(marks *internal.SessionMarks, sessionID string) string {
+			if !archiveRemove {
+				marks.SetArchived(sessionID, true)
+				return "🗄️  Archived " + sessionID
+			}
+			if !marks.SetArchived(sessionID, false) {
+				return sessionID + " is not archived"
+			}
+			return "Restored " + sessionID
+		})
+	},
+}
+
+// markSession resolves the session given by query, applies mark to the stored session
+// marks and saves them, printing the message mark returns
+func markSession(cmd *cobra.Command, query string, mark func(marks *internal.SessionMarks, sessionID string) string) error {
+	out := commandOutput(cmd)
+	sessionID, cacheManager, err := resolveSessionArg(query)
+	if err != nil {
+		return err
+	}
+	marks, err := cacheManager.LoadMarks()
+	if err != nil {
+		return err
+	}
+	message := mark(marks, sessionID)
+	if err := marks.Save(); err != nil {
+		return fmt.Errorf("failed to save session marks: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, message)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(archiveCmd)
+	pinCmd.Flags().BoolVar(&pinRemove, "remove", false, "Unpin the session instead")
+	archiveCmd.Flags().BoolVar(&archiveRemove, "remove", false, "Restore the archived session instead")
+}
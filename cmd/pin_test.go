@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestPinAndArchiveCommands(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	resetListFlags(t)
+	defer func() {
+		pinRemove, archiveRemove = false, false
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	for _, s := range []struct{ id, created string }{
+		{"first-session", "2024-06-01T09:00:00Z"},
+		{"second-session", "2024-06-02T09:00:00Z"},
+		{"third-session", "2024-06-03T09:00:00Z"},
+	} {
+		session := internal.CreateTestSessionWithMessages(s.id, []internal.Message{{Actor: "user", Content: "Prompt of " + s.id}})
+		session.Metadata.Name = "Name of " + s.id
+		session.Metadata.CreatedAt = s.created
+		if err := os.WriteFile(filepath.Join(dir, "session_"+s.id+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		clearListFlags()
+		pinRemove, archiveRemove = false, false
+		var out bytes.Buffer
+		rootCmd.SetArgs(append(args, "--storage", dir))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v error = %v", args, err)
+		}
+		return out.String()
+	}
+	// order returns the session names in the order list printed them
+	order := func(output string) []string {
+		var names []string
+		for _, line := range strings.Split(output, "\n") {
+			if i := strings.Index(line, "Name of "); i >= 0 {
+				names = append(names, strings.Fields(line[i+len("Name of "):])[0])
+			}
+		}
+		return names
+	}
+
+	if got := run("pin", "third"); !strings.Contains(got, "Pinned third-session") {
+		t.Errorf("pin output = %q", got)
+	}
+	if got := run("archive", "second"); !strings.Contains(got, "Archived second-session") {
+		t.Errorf("archive output = %q", got)
+	}
+
+	runListFromStorageAndCache(t, []string{"--storage", dir}, func(source, output string) {
+		if got := strings.Join(order(output), ","); got != "third-session,first-session" {
+			t.Errorf("list from %s = %s, want the pinned session first and the archived one hidden:\n%s", source, got, output)
+		}
+		if !strings.Contains(output, "📌") {
+			t.Errorf("list from %s should mark the pinned session:\n%s", source, output)
+		}
+	})
+	output := run("list", "--include-archived")
+	if got := strings.Join(order(output), ","); got != "third-session,first-session,second-session" {
+		t.Errorf("list --include-archived = %s:\n%s", got, output)
+	}
+	if !strings.Contains(output, "(archived)") {
+		t.Errorf("list --include-archived should label the archived session:\n%s", output)
+	}
+	if got := run("list", "--json"); !strings.Contains(got, `"pinned": true`) || strings.Contains(got, "second-session") {
+		t.Errorf("list --json should mark the pinned session and leave out the archived one:\n%s", got)
+	}
+
+	if got := run("pin", "third", "--remove"); !strings.Contains(got, "Unpinned third-session") {
+		t.Errorf("pin --remove output = %q", got)
+	}
+	if got := run("pin", "third", "--remove"); !strings.Contains(got, "third-session is not pinned") {
+		t.Errorf("pin --remove of an unpinned session output = %q", got)
+	}
+	if got := run("archive", "second", "--remove"); !strings.Contains(got, "Restored second-session") {
+		t.Errorf("archive --remove output = %q", got)
+	}
+	if got := strings.Join(order(run("list")), ","); got != "first-session,second-session,third-session" {
+		t.Errorf("list after unpinning and restoring = %s", got)
+	}
+
+	marks, err := internal.NewCacheManager(testCacheDir(t)).LoadMarks()
+	if err != nil {
+		t.Fatalf("LoadMarks() error = %v", err)
+	}
+	if len(marks.Pinned) != 0 || len(marks.Archived) != 0 {
+		t.Errorf("marks = %+v, want none left", marks)
+	}
+
+	storagePaths = nil
+	rootCmd.SetArgs([]string{"pin", "missing", "--storage", dir})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("pin of an unknown session should fail")
+	}
+}
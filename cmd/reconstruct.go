@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/iksnae/cursor-session/internal"
 	"github.com/spf13/cobra"
@@ -13,63 +11,49 @@ import (
 
 var (
 	reconstructOutput string
+	reconstructFrom   string
 )
 
 // reconstructCmd represents the reconstruct command
 var reconstructCmd = &cobra.Command{
 	Use:   "reconstruct",
 	Short: "Reconstruct and save intermediary format",
-	Long:  `Reconstruct conversations and save to intermediary JSON/YAML format for debugging.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get paths (with optional custom storage location)
-		paths, err := internal.GetStoragePaths(storagePath)
-		if err != nil {
-			return fmt.Errorf("failed to get storage paths: %w", err)
-		}
+	Long: `Reconstruct conversations and save to intermediary JSON/YAML format for debugging.
 
-		// Copy database files to temp location if --copy flag is set
-		var cleanup func() error
-		if copyDB {
-			var copyErr error
-			paths, cleanup, copyErr = internal.CopyStoragePaths(paths)
-			if copyErr != nil {
-				return fmt.Errorf("failed to copy database files: %w", copyErr)
-			}
-			// Schedule cleanup when command completes
-			defer func() {
-				if cleanup != nil {
-					if err := cleanup(); err != nil {
-						internal.LogWarn("Failed to cleanup temporary files: %v", err)
-					} else {
-						internal.LogInfo("Cleaned up temporary database files")
-					}
-				}
-			}()
-		}
+Conversations are written to conversation_<id>.json files in --out. With --from,
+they are reconstructed from a raw dump written by 'extract' instead of the
+databases, and 'normalize' turns them into sessions. Together the three commands
+run the export pipeline one phase at a time, so a failing phase can be rerun
+without reading the databases again:
 
-		// Create storage backend (handles both desktop app and agent storage)
-		backend, err := internal.NewStorageBackend(paths)
-		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %w", err)
+  cursor-session extract                         # Databases -> ./intermediary/raw.json
+  cursor-session reconstruct --from ./intermediary/raw.json
+  cursor-session normalize                       # -> ./sessions/session_<id>.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var backend internal.StorageBackend
+		if reconstructFrom != "" {
+			dump, err := internal.ReadRawDump(reconstructFrom)
+			if err != nil {
+				return err
+			}
+			backend = dump
+		} else {
+			storageBackend, _, cleanup, err := openStorageBackend()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			backend = storageBackend
 		}
 
 		var conversations []*internal.ReconstructedConversation
 
 		// Load data asynchronously with progress
 		ctx := context.Background()
-		err = internal.ShowProgress(ctx, "Loading data from storage", func() error {
+		err := internal.ShowProgress(ctx, "Loading data from storage", func() error {
 			var loadErr error
-			bubbleChan, composerChan, contextChan, loadErr := internal.LoadDataAsyncFromBackend(backend)
-			if loadErr != nil {
-				return fmt.Errorf("failed to load data: %w", loadErr)
-			}
-
-			// Reconstruct conversations
-			conversations, loadErr = internal.ReconstructAsync(bubbleChan, composerChan, contextChan)
-			if loadErr != nil {
-				return fmt.Errorf("failed to reconstruct conversations: %w", loadErr)
-			}
-			return nil
+			conversations, loadErr = internal.ReconstructConversations(backend)
+			return loadErr
 		})
 		if err != nil {
 			return err
@@ -84,18 +68,8 @@ var reconstructCmd = &cobra.Command{
 		saveCtx := context.Background()
 		err = internal.ShowProgress(saveCtx, fmt.Sprintf("Saving %d conversation(s) to intermediary format", len(conversations)), func() error {
 			for _, conv := range conversations {
-				filename := fmt.Sprintf("conversation_%s.json", conv.ComposerID)
-				filepath := filepath.Join(reconstructOutput, filename)
-
-				data, err := json.MarshalIndent(conv, "", "  ")
-				if err != nil {
-					internal.LogError("Failed to marshal conversation %s: %v", conv.ComposerID, err)
-					continue
-				}
-
-				if err := os.WriteFile(filepath, data, 0644); err != nil {
-					internal.LogError("Failed to write file %s: %v", filepath, err)
-					continue
+				if err := internal.WriteConversation(reconstructOutput, conv); err != nil {
+					internal.LogError("%v", err)
 				}
 			}
 			return nil
@@ -112,4 +86,5 @@ var reconstructCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(reconstructCmd)
 	reconstructCmd.Flags().StringVarP(&reconstructOutput, "out", "o", "./intermediary", "Output directory for intermediary format")
+	reconstructCmd.Flags().StringVar(&reconstructFrom, "from", "", "Reconstruct from a raw dump written by 'extract' instead of the databases")
 }
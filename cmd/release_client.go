@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// Release channels of the upgrade command
+const (
+	channelStable     = "stable"
+	channelPrerelease = "prerelease"
+)
+
+// releaseChannels lists the accepted --channel values
+var releaseChannels = []string{channelStable, channelPrerelease}
+
+// checksumsAsset is the release asset listing the SHA-256 of the archives
+const checksumsAsset = "checksums.txt"
+
+const (
+	// releaseAttempts is how many times a request is sent before giving up
+	releaseAttempts = 3
+	// maxRateLimitWait is the longest wait for a rate limit to reset before giving up
+	maxRateLimitWait = time.Minute
+)
+
+// releaseRetryDelay is the wait after the first failed attempt, growing with each attempt
+var releaseRetryDelay = time.Second
+
+// releaseClient fetches releases from the GitHub API and downloads their assets. Requests
+// go through the proxy HTTPS_PROXY or HTTP_PROXY names (except for hosts in NO_PROXY),
+// API requests are authenticated with GITHUB_TOKEN when it is set, and requests failing
+// on a network error, a server error or a rate limit about to reset are retried.
+type releaseClient struct {
+	apiURL string
+	token  string
+	client *http.Client
+	// sleep waits between attempts; replaced in tests
+	sleep func(time.Duration)
+}
+
+// newReleaseClient returns a client for the releases of this repository
+func newReleaseClient() *releaseClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return &releaseClient{
+		apiURL: repoURL,
+		token:  os.Getenv("GITHUB_TOKEN"),
+		client: &http.Client{Transport: transport, Timeout: 5 * time.Minute},
+		sleep:  time.Sleep,
+	}
+}
+
+// latestRelease returns the newest release of a channel: the latest stable release, or
+// the highest version among all releases, prereleases included
+func (c *releaseClient) latestRelease(channel string) (*githubRelease, error) {
+	if channel != channelPrerelease {
+		var release githubRelease
+		if err := c.getJSON(c.apiURL+"/releases/latest", &release); err != nil {
+			return nil, fmt.Errorf("failed to fetch release info: %w", err)
+		}
+		return &release, nil
+	}
+
+	var releases []githubRelease
+	if err := c.getJSON(c.apiURL+"/releases?per_page=30", &releases); err != nil {
+		return nil, fmt.Errorf("failed to fetch release info: %w", err)
+	}
+	var latest *githubRelease
+	var latestVersion *semver.Version
+	for i, release := range releases {
+		if release.Draft {
+			continue
+		}
+		v, err := semver.NewVersion(strings.TrimPrefix(release.TagName, "v"))
+		if err != nil {
+			internal.LogDebug("Skipping release %s: %v", release.TagName, err)
+			continue
+		}
+		if latestVersion == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = &releases[i], v
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no releases found")
+	}
+	return latest, nil
+}
+
+// getJSON decodes the response of an API request into v
+func (c *releaseClient) getJSON(rawURL string, v interface{}) error {
+	resp, err := c.get(rawURL, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// download writes the body of rawURL to destPath
+func (c *releaseClient) download(rawURL, destPath string) error {
+	resp, err := c.get(rawURL, false)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// checksums returns the SHA-256 of the release's archives by file name, from its
+// checksums.txt
+func (c *releaseClient) checksums(release *githubRelease) (map[string]string, error) {
+	checksumsURL := release.assetURL(checksumsAsset)
+	if checksumsURL == "" {
+		return nil, fmt.Errorf("release %s publishes no %s to verify the download against", release.TagName, checksumsAsset)
+	}
+	resp, err := c.get(checksumsURL, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return parseChecksums(resp.Body)
+}
+
+// get sends a GET request, retrying it while it fails on a network error, a server error
+// or a rate limit that resets soon. The body of the response returned is the caller's to
+// close; any other status is turned into an error explaining it.
+func (c *releaseClient) get(rawURL string, api bool) (*http.Response, error) {
+	var lastErr error
+	var wait time.Duration
+	for attempt := 1; attempt <= releaseAttempts; attempt++ {
+		if attempt > 1 {
+			internal.LogDebug("Retrying %s (attempt %d of %d): %v", rawURL, attempt, releaseAttempts, lastErr)
+			c.sleep(wait)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "cursor-session/"+version)
+		if api {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			if c.token != "" {
+				req.Header.Set("Authorization", "Bearer "+c.token)
+			}
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			lastErr = c.explainNetworkError(req, err)
+			wait = backoff(attempt)
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		lastErr = c.explainStatus(resp, body)
+
+		if reset, limited := rateLimitWait(resp); limited {
+			if reset > maxRateLimitWait {
+				return nil, lastErr
+			}
+			wait = max(reset, backoff(attempt))
+			continue
+		}
+		if resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+		wait = backoff(attempt)
+	}
+	return nil, lastErr
+}
+
+// explainStatus turns an unexpected response into an error saying why it was refused
+func (c *releaseClient) explainStatus(resp *http.Response, body []byte) error {
+	detail := strings.TrimSpace(string(body))
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		detail = apiErr.Message
+	}
+	if len(detail) > 200 {
+		detail = detail[:200] + "..."
+	}
+
+	err := fmt.Errorf("status %d", resp.StatusCode)
+	if detail != "" {
+		err = fmt.Errorf("status %d: %s", resp.StatusCode, detail)
+	}
+
+	if _, limited := rateLimitWait(resp); limited {
+		reset := resp.Header.Get("X-RateLimit-Reset")
+		hint := "set GITHUB_TOKEN to raise the limit"
+		if c.token != "" {
+			hint = "the limit of GITHUB_TOKEN is used up"
+		}
+		if secs, perr := strconv.ParseInt(reset, 10, 64); perr == nil {
+			return fmt.Errorf("GitHub rate limit exceeded until %s (%s): %w", time.Unix(secs, 0).Format(time.Kitchen), hint, err)
+		}
+		return fmt.Errorf("GitHub rate limit exceeded (%s): %w", hint, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && c.token != "":
+		return fmt.Errorf("GitHub rejected GITHUB_TOKEN: %w", err)
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusProxyAuthRequired:
+		if proxy := c.proxyFor(resp.Request); proxy != "" {
+			return fmt.Errorf("request refused, possibly by the proxy %s: %w", proxy, err)
+		}
+		return fmt.Errorf("request refused: %w", err)
+	}
+	return err
+}
+
+// explainNetworkError names the proxy a failed request went through
+func (c *releaseClient) explainNetworkError(req *http.Request, err error) error {
+	if proxy := c.proxyFor(req); proxy != "" {
+		return fmt.Errorf("request through the proxy %s failed: %w", proxy, err)
+	}
+	return err
+}
+
+// proxyFor returns the proxy, without credentials, a request is sent through, if any
+func (c *releaseClient) proxyFor(req *http.Request) string {
+	transport, ok := c.client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil || req == nil {
+		return ""
+	}
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return (&url.URL{Scheme: proxy.Scheme, Host: proxy.Host}).String()
+}
+
+// rateLimitWait reports whether a response refused the request for exceeding the GitHub
+// rate limit, and how long until the limit resets
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if after := resp.Header.Get("Retry-After"); after != "" {
+		if secs, err := strconv.Atoi(after); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, resp.StatusCode == http.StatusTooManyRequests
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return maxRateLimitWait + time.Second, true
+	}
+	wait := time.Until(time.Unix(reset, 0))
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// backoff is how long to wait after a failed attempt
+func backoff(attempt int) time.Duration {
+	return time.Duration(attempt) * releaseRetryDelay
+}
+
+// parseChecksums reads a sha256sum listing into a map from file name to checksum
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", checksumsAsset, err)
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("%s lists no checksums", checksumsAsset)
+	}
+	return sums, nil
+}
+
+// errChecksumMismatch is returned when a download does not match its published checksum
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// verifyChecksum checks that the SHA-256 of the file at path is want
+func verifyChecksum(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(want) {
+		return fmt.Errorf("%w: got %s, want %s", errChecksumMismatch, got, want)
+	}
+	return nil
+}
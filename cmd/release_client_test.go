@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// testReleaseClient returns a client of the API served by handler that does not wait
+// between attempts
+func testReleaseClient(t *testing.T, token string, handler http.HandlerFunc) *releaseClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &releaseClient{
+		apiURL: server.URL,
+		token:  token,
+		client: server.Client(),
+		sleep:  func(time.Duration) {},
+	}
+}
+
+func TestReleaseClient_LatestRelease(t *testing.T) {
+	var auth string
+	client := testReleaseClient(t, "secret", func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/releases/latest":
+			_, _ = fmt.Fprint(w, `{"tag_name":"v1.2.0"}`)
+		case "/releases":
+			_, _ = fmt.Fprint(w, `[
+				{"tag_name":"v1.2.0"},
+				{"tag_name":"v1.4.0-rc.1","prerelease":true},
+				{"tag_name":"v2.0.0","draft":true},
+				{"tag_name":"nightly"}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	release, err := client.latestRelease(channelStable)
+	if err != nil || release.TagName != "v1.2.0" {
+		t.Errorf("latestRelease(stable) = %+v, %v, want v1.2.0", release, err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the token", auth)
+	}
+
+	release, err = client.latestRelease(channelPrerelease)
+	if err != nil || release.TagName != "v1.4.0-rc.1" {
+		t.Errorf("latestRelease(prerelease) = %+v, %v, want the highest non-draft release", release, err)
+	}
+}
+
+func TestReleaseClient_Retries(t *testing.T) {
+	attempts := 0
+	client := testReleaseClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts < releaseAttempts {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+	})
+
+	release, err := client.latestRelease(channelStable)
+	if err != nil || release.TagName != "v1.0.0" {
+		t.Errorf("latestRelease() = %+v, %v, want success after retries", release, err)
+	}
+	if attempts != releaseAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, releaseAttempts)
+	}
+}
+
+func TestReleaseClient_RateLimit(t *testing.T) {
+	attempts := 0
+	reset := time.Now().Add(time.Hour).Unix()
+	client := testReleaseClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"message":"API rate limit exceeded for 203.0.113.7."}`)
+	})
+
+	_, err := client.latestRelease(channelStable)
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_TOKEN") || !strings.Contains(err.Error(), "API rate limit exceeded") {
+		t.Errorf("latestRelease() error = %v, want a rate limit error suggesting GITHUB_TOKEN", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want no retry of a limit resetting in an hour", attempts)
+	}
+}
+
+func TestReleaseClient_Forbidden(t *testing.T) {
+	attempts := 0
+	client := testReleaseClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "Access denied by policy", http.StatusForbidden)
+	})
+
+	_, err := client.latestRelease(channelStable)
+	if err == nil || !strings.Contains(err.Error(), "Access denied by policy") {
+		t.Errorf("latestRelease() error = %v, want the reason of the refusal", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want a refusal not to be retried", attempts)
+	}
+}
+
+func TestReleaseClient_Checksums(t *testing.T) {
+	archive := []byte("release archive")
+	sum := sha256.Sum256(archive)
+	var serverURL string
+	client := testReleaseClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/checksums.txt":
+			_, _ = fmt.Fprintf(w, "%s  cursor-session-1.0.0-linux-amd64.tar.gz\n", hex.EncodeToString(sum[:]))
+		case "/download/cursor-session-1.0.0-linux-amd64.tar.gz":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	serverURL = client.apiURL
+
+	release := &githubRelease{TagName: "v1.0.0"}
+	if _, err := client.checksums(release); err == nil {
+		t.Error("checksums() of a release without checksums.txt should fail")
+	}
+	release.Assets = append(release.Assets, struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}{Name: checksumsAsset, BrowserDownloadURL: serverURL + "/download/checksums.txt"})
+
+	sums, err := client.checksums(release)
+	if err != nil {
+		t.Fatalf("checksums() error = %v", err)
+	}
+	want := sums["cursor-session-1.0.0-linux-amd64.tar.gz"]
+	if want != hex.EncodeToString(sum[:]) {
+		t.Fatalf("checksums() = %v", sums)
+	}
+
+	path := filepath.Join(testutil.CreateTempDir(t), "archive.tar.gz")
+	if err := client.download(serverURL+"/download/cursor-session-1.0.0-linux-amd64.tar.gz", path); err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	if err := verifyChecksum(path, want); err != nil {
+		t.Errorf("verifyChecksum() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatalf("Failed to tamper with the archive: %v", err)
+	}
+	if err := verifyChecksum(path, want); !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("verifyChecksum() of a tampered archive error = %v, want a mismatch", err)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	sums, err := parseChecksums(strings.NewReader("ABC123  a.tar.gz\ndef456 *b.tar.gz\n\nnot a checksum line here\n"))
+	if err != nil {
+		t.Fatalf("parseChecksums() error = %v", err)
+	}
+	if sums["a.tar.gz"] != "abc123" || sums["b.tar.gz"] != "def456" || len(sums) != 2 {
+		t.Errorf("parseChecksums() = %v", sums)
+	}
+	if _, err := parseChecksums(strings.NewReader("")); err == nil {
+		t.Error("parseChecksums() of an empty listing should fail")
+	}
+}
+
+func TestUpgradeCommand_InvalidChannel(t *testing.T) {
+	defer func() { upgradeChannel = channelStable }()
+	rootCmd.SetArgs([]string{"upgrade", "--channel", "nightly"})
+	rootCmd.SetOut(&strings.Builder{})
+	rootCmd.SetErr(&strings.Builder{})
+	if code := exitCode(rootCmd.Execute()); code != exitUsage {
+		t.Errorf("upgrade --channel nightly exit code = %d, want %d", code, exitUsage)
+	}
+}
@@ -2,19 +2,53 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/iksnae/cursor-session/internal"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose     bool
-	storagePath string
-	copyDB      bool
-	version     string = "dev"
-	commit      string = "unknown"
-	date        string = "unknown"
+	verbosity    int
+	quiet        bool
+	storagePaths []string
+	allBuilds    bool
+	copyDB       bool
+	readStrategy string
+	version      string = "dev"
+	commit       string = "unknown"
+	date         string = "unknown"
+
+	logLevelName string
+	logFormat    string
+	logFile      string
+	logFileOut   *os.File
+
+	timezone   string
+	timeFormat string
+
+	noGeneratedTitles bool
+
+	rulesFile string
+
+	busyTimeout time.Duration
+
+	maxBlobPayload int
+
+	maxOpenDBs int
+
+	strict          bool
+	strictThreshold float64
+
+	markArgErrorsOnce sync.Once
+
+	plain bool
+
+	// archiveCleanups remove the directories archives given to --storage were extracted to
+	archiveCleanups []func() error
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -41,23 +75,263 @@ Quick Start:
 
 For detailed usage, see: https://github.com/iksnae/cursor-session`,
 	Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		internal.SetVerbose(verbose)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		internal.ResetParseStats()
+		internal.SetPlainOutput(plain)
+		if quiet && verbosity > 0 {
+			return usageErrorf("--quiet and --verbose cannot be combined")
+		}
+		internal.SetVerbosity(verbosity)
+		if quiet {
+			internal.SetVerbosity(internal.VerbosityQuiet)
+		}
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if busyTimeout < 0 {
+			return usageErrorf("--busy-timeout must not be negative, got %s", busyTimeout)
+		}
+		if strictThreshold < 0 || strictThreshold > 1 {
+			return usageErrorf("--strict-threshold must be between 0 and 1, got %g", strictThreshold)
+		}
+		internal.SetBusyTimeout(busyTimeout)
+		if maxBlobPayload < 0 {
+			return usageErrorf("--max-blob-payload must not be negative, got %d", maxBlobPayload)
+		}
+		internal.SetMaxBlobPayload(maxBlobPayload)
+		if maxOpenDBs < 1 {
+			return usageErrorf("--max-open-dbs must be at least 1, got %d", maxOpenDBs)
+		}
+		internal.SetMaxOpenDBs(maxOpenDBs)
+		if err := internal.ValidateReadStrategy(readStrategy); err != nil {
+			return &usageError{err: err}
+		}
+		if copyDB && readStrategy != internal.ReadAuto && readStrategy != internal.ReadCopy {
+			return usageErrorf("--copy conflicts with --read-strategy %s", readStrategy)
+		}
+		internal.SetReadStrategy(effectiveReadStrategy())
+		if err := internal.ConfigureTimeDisplay(timezone, timeFormat); err != nil {
+			return &usageError{err: err}
+		}
+		if err := applyNormalizerRules(); err != nil {
+			return err
+		}
+		if err := extractStorageArchives(); err != nil {
+			return err
+		}
+		startUpdateCheck(cmd)
+		return nil
 	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		removeExtractedArchives()
+		return checkStrict()
+	},
+}
+
+// statusOutput returns where a command writes status lines, such as headers, tips and
+// summaries of what it did: out, or nowhere with --quiet. Results are written to out
+// whatever the verbosity.
+func statusOutput(out io.Writer) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return out
+}
+
+// setupLogging applies the --log-level, --log-format, --log-file, --quiet and --verbose
+// flags. Diagnostics always go to stderr or the log file so stdout only carries command
+// results.
+func setupLogging() error {
+	level, err := internal.ParseLogLevel(logLevelName)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	switch {
+	case quiet:
+		level = internal.LogLevelError
+	case verbosity > 0:
+		level = internal.LogLevelDebug
+	}
+	internal.SetLogLevel(level)
+
+	closeLogFile()
+	var w io.Writer = os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		logFileOut = f
+		w = f
+	}
+
+	if err := internal.ConfigureLogger(w, logFormat); err != nil {
+		return &usageError{err: err}
+	}
+	return nil
+}
+
+// applyNormalizerRules loads the normalizer rules from --rules, or from the rules file in
+// the config directory when there is one
+func applyNormalizerRules() error {
+	path := rulesFile
+	if path == "" {
+		defaultPath, err := internal.DefaultNormalizerRulesPath()
+		if err != nil {
+			internal.SetNormalizerRules(nil)
+			return nil
+		}
+		if _, err := os.Stat(defaultPath); err != nil {
+			internal.SetNormalizerRules(nil)
+			return nil
+		}
+		path = defaultPath
+	}
+
+	rules, err := internal.LoadNormalizerRules(path)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	internal.LogDebug("Normalizing sessions with the rules in %s", path)
+	internal.SetNormalizerRules(rules)
+	return nil
+}
+
+// extractStorageArchives extracts the archives given to --storage, such as a store.db.gz
+// or a tarball of the chats directory downloaded from CI, into temporary directories, which
+// then replace them in storagePaths
+func extractStorageArchives() error {
+	for i, path := range storagePaths {
+		if internal.LocationScheme(path) != "" || !internal.IsStorageArchive(path) {
+			continue
+		}
+		// Paths that don't exist are reported when the storage is detected
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		dir, cleanup, err := internal.ExtractStorageArchive(path)
+		if err != nil {
+			removeExtractedArchives()
+			return err
+		}
+		archiveCleanups = append(archiveCleanups, cleanup)
+		storagePaths[i] = dir
+	}
+	return nil
+}
+
+// removeExtractedArchives removes the directories extractStorageArchives extracted to
+func removeExtractedArchives() {
+	for _, cleanup := range archiveCleanups {
+		if err := cleanup(); err != nil {
+			internal.LogWarn("Failed to cleanup temporary files: %v", err)
+		}
+	}
+	archiveCleanups = nil
+}
+
+// commandOutput returns where a command writes its results: the command's output, with
+// emoji and symbols replaced by ASCII when --plain is set
+func commandOutput(cmd *cobra.Command) io.Writer {
+	if plain {
+		return internal.NewPlainWriter(cmd.OutOrStdout())
+	}
+	return cmd.OutOrStdout()
+}
+
+// closeLogFile closes the log file opened by setupLogging, if any
+func closeLogFile() {
+	if logFileOut != nil {
+		_ = logFileOut.Close()
+		logFileOut = nil
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// It exits with one of the documented exit codes when the command fails.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	os.Exit(run(os.Stderr))
+}
+
+// run executes the root command and returns its exit code, writing errors to stderr.
+// With --strict, a machine-readable summary of the failure follows the error.
+func run(stderr io.Writer) int {
+	markArgErrorsOnce.Do(func() { markArgErrors(rootCmd) })
+	err := rootCmd.Execute()
+	// PersistentPostRunE is skipped when the command fails
+	removeExtractedArchives()
+	internal.CloseAgentDBs()
+	closeLogFile()
+	printUpdateNotice(stderr)
+	if err == nil {
+		return exitOK
+	}
+
+	if plain {
+		stderr = internal.NewPlainWriter(stderr)
+	}
+	_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+	if strict {
+		writeStrictSummary(stderr, err)
+	}
+	return exitCode(err)
+}
+
+// effectiveReadStrategy returns --read-strategy, or copy when --copy is set
+func effectiveReadStrategy() string {
+	if copyDB {
+		return internal.ReadCopy
+	}
+	return readStrategy
+}
+
+// copyStorage reports whether the databases of the storage locations are copied before
+// reading, following --read-strategy (or --copy)
+func copyStorage(paths ...internal.StoragePaths) bool {
+	return internal.ResolveReadStrategy(effectiveReadStrategy(), paths) == internal.ReadCopy
+}
+
+// markArgErrors wraps the argument validators of c and its subcommands so that wrong
+// arguments exit with the usage exit code
+func markArgErrors(c *cobra.Command) {
+	if validate := c.Args; validate != nil {
+		c.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &usageError{err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range c.Commands() {
+		markArgErrors(sub)
 	}
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
-	rootCmd.PersistentFlags().StringVar(&storagePath, "storage", "", "Custom storage location (path to database file or storage directory)")
-	rootCmd.PersistentFlags().BoolVar(&copyDB, "copy", false, "Copy database files to temporary location to avoid locking issues")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Report more: -v for debug logs and details in command output, -vv also for every record skipped while loading")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only errors and command results: no progress, status messages, tips or warnings")
+	rootCmd.PersistentFlags().StringArrayVar(&storagePaths, "storage", nil, "Custom storage location (path to database file, storage directory, directory of exported sessions, a .gz, .tar.gz or .zip archive of one, or a URI such as s3://bucket/path read by a registered backend); repeat to combine several")
+	rootCmd.PersistentFlags().BoolVar(&allBuilds, "all-builds", false, "Without --storage, combine the sessions of every installed Cursor build (Nightly, Insiders, flatpak, snap) instead of reading the first one; combined sessions are not cached")
+	rootCmd.PersistentFlags().BoolVar(&copyDB, "copy", false, "Copy database files to temporary location to avoid locking issues (same as --read-strategy copy)")
+	rootCmd.PersistentFlags().StringVar(&readStrategy, "read-strategy", internal.ReadAuto, "How to read databases Cursor may be writing: auto (direct for WAL, copy otherwise), copy, direct or snapshot")
+	rootCmd.PersistentFlags().DurationVar(&busyTimeout, "busy-timeout", internal.DefaultBusyTimeout, "How long to wait for Cursor to release a lock on its database before retrying")
+	rootCmd.PersistentFlags().IntVar(&maxBlobPayload, "max-blob-payload", internal.DefaultMaxBlobPayload, "Skip payloads, such as tool output, over this many bytes when reading cursor-agent blobs (0 to load everything)")
+	rootCmd.PersistentFlags().IntVar(&maxOpenDBs, "max-open-dbs", internal.DefaultMaxOpenDBs, "Most cursor-agent store.db files to keep open at once; those unused longest are closed first")
+	rootCmd.PersistentFlags().StringVar(&logLevelName, "log-level", "info", "Log level for diagnostics (error, warn, info, debug)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format for diagnostics (text, json)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write diagnostics to this file instead of stderr")
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", os.Getenv("CURSOR_SESSION_TIMEZONE"), "Time zone for displayed timestamps (IANA name, UTC or Local; env CURSOR_SESSION_TIMEZONE)")
+	rootCmd.PersistentFlags().BoolVar(&noGeneratedTitles, "no-generated-titles", false, "Show sessions Cursor left unnamed as Untitled instead of titling them after the first user message")
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", os.Getenv(internal.EnvNormalizerRules), "Normalizer rules file (YAML) mapping message types to actors, keeping messages without text and copying composer fields into session metadata (default: normalizer.yaml in the config directory; env CURSOR_SESSION_RULES)")
+	rootCmd.PersistentFlags().StringVar(&timeFormat, "time-format", os.Getenv("CURSOR_SESSION_TIME_FORMAT"), "Layout for displayed timestamps (rfc3339, datetime, date, time, kitchen, rfc1123 or a Go layout; env CURSOR_SESSION_TIME_FORMAT)")
+
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Exit with code 4 and a JSON error summary when too many records fail to parse or sessions produce no messages")
+	rootCmd.PersistentFlags().Float64Var(&strictThreshold, "strict-threshold", defaultStrictThreshold, "Share of failed records or empty sessions (0-1) that --strict tolerates")
+	rootCmd.PersistentFlags().BoolVar(&plain, "plain", os.Getenv("NO_COLOR") != "", "Plain ASCII output without colors or emoji (env NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&plain, "no-color", os.Getenv("NO_COLOR") != "", "Alias for --plain")
+	rootCmd.SetFlagErrorFunc(flagError)
+	// run reports errors itself, so they are printed once and honor --plain
+	rootCmd.SilenceErrors = true
 
 	// Set version template to ensure --version flag works
 	rootCmd.SetVersionTemplate(`{{printf "%s\n" .Version}}`)
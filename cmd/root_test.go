@@ -2,9 +2,23 @@ package cmd
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
 )
 
+func TestMain(m *testing.M) {
+	// Tests set HOME to a temporary directory; keep the cache and config under it
+	_ = os.Unsetenv("XDG_CACHE_HOME")
+	_ = os.Unsetenv("XDG_CONFIG_HOME")
+	_ = os.Unsetenv("XDG_STATE_HOME")
+	os.Exit(m.Run())
+}
+
 func TestRootCommand(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -77,3 +91,219 @@ func TestExecute(t *testing.T) {
 		t.Error("Execute() should return error for nonexistent command")
 	}
 }
+
+func TestRootCommand_LogFlags(t *testing.T) {
+	defer func() {
+		logLevelName = "info"
+		logFormat = "text"
+		logFile = ""
+		storagePaths = nil
+		closeLogFile()
+		_ = internal.ConfigureLogger(os.Stderr, internal.LogFormatText)
+	}()
+
+	logPath := filepath.Join(t.TempDir(), "cursor-session.log")
+	rootCmd.SetArgs([]string{"--log-level", "debug", "--log-format", "json", "--log-file", logPath, "list", "--storage", t.TempDir()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	_ = rootCmd.Execute()
+
+	internal.LogInfo("log file check")
+	closeLogFile()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Log file was not created: %v", err)
+	}
+	if !strings.Contains(string(data), `"msg":"log file check"`) {
+		t.Errorf("Log file should contain JSON records, got: %s", data)
+	}
+
+	logFile = ""
+	rootCmd.SetArgs([]string{"--log-level", "loud", "list"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("Execute() should return error for invalid --log-level")
+	}
+}
+
+func TestRootCommand_MaxBlobPayloadFlag(t *testing.T) {
+	defer func() {
+		maxBlobPayload = internal.DefaultMaxBlobPayload
+		internal.SetMaxBlobPayload(internal.DefaultMaxBlobPayload)
+		storagePaths = nil
+	}()
+
+	rootCmd.SetArgs([]string{"--max-blob-payload", "-1", "list", "--storage", t.TempDir()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--max-blob-payload") {
+		t.Errorf("Execute() should reject a negative --max-blob-payload, got: %v", err)
+	}
+}
+
+func TestRootCommand_MaxOpenDBsFlag(t *testing.T) {
+	defer func() {
+		maxOpenDBs = internal.DefaultMaxOpenDBs
+		internal.SetMaxOpenDBs(internal.DefaultMaxOpenDBs)
+		storagePaths = nil
+	}()
+
+	rootCmd.SetArgs([]string{"--max-open-dbs", "0", "list", "--storage", t.TempDir()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--max-open-dbs") {
+		t.Errorf("Execute() should reject --max-open-dbs 0, got: %v", err)
+	}
+}
+
+func TestRootCommand_RulesFlag(t *testing.T) {
+	defer func() {
+		rulesFile = ""
+		internal.SetNormalizerRules(nil)
+		storagePaths = nil
+	}()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("actors:\n  30: robot\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"--rules", invalid, "list", "--storage", t.TempDir()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage || !strings.Contains(err.Error(), "robot") {
+		t.Errorf("Execute() should reject invalid rules, got: %v", err)
+	}
+
+	valid := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(valid, []byte("actors:\n  30: system\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"--rules", valid, "list", "--storage", t.TempDir()})
+	_ = rootCmd.Execute()
+	if rules := internal.ActiveNormalizerRules(); rules == nil || rules.Actors[30] != internal.ActorSystem {
+		t.Errorf("ActiveNormalizerRules() = %+v, want the rules of --rules", rules)
+	}
+
+	// Without --rules, only a rules file in the config directory applies
+	rulesFile = ""
+	rootCmd.SetArgs([]string{"list", "--storage", t.TempDir()})
+	_ = rootCmd.Execute()
+	if rules := internal.ActiveNormalizerRules(); rules != nil {
+		t.Errorf("ActiveNormalizerRules() = %+v, want none", rules)
+	}
+}
+
+func TestRootCommand_BusyTimeoutFlag(t *testing.T) {
+	defer func() {
+		busyTimeout = internal.DefaultBusyTimeout
+		storagePaths = nil
+	}()
+
+	rootCmd.SetArgs([]string{"--busy-timeout", "-1s", "list", "--storage", t.TempDir()})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--busy-timeout") {
+		t.Errorf("Execute() should reject a negative --busy-timeout, got: %v", err)
+	}
+}
+
+func TestRootCommand_PlainFlag(t *testing.T) {
+	defer func() {
+		plain = false
+		internal.SetPlainOutput(false)
+		storagePaths = nil
+	}()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, flag := range []string{"--plain", "--no-color"} {
+		t.Run(flag, func(t *testing.T) {
+			plain = false
+			storagePaths = nil
+			var stdout bytes.Buffer
+			rootCmd.SetArgs([]string{"snoop", flag})
+			rootCmd.SetOut(&stdout)
+			rootCmd.SetErr(&bytes.Buffer{})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			output := stdout.String()
+			if !strings.Contains(output, "[WARN]") {
+				t.Errorf("plain output should use ASCII status markers, got:\n%s", output)
+			}
+			for _, r := range strings.ReplaceAll(output, home, "") {
+				if r > 127 || r == '\x1b' {
+					t.Fatalf("plain output contains %q:\n%s", r, output)
+				}
+			}
+		})
+	}
+}
+
+func TestRootCommand_QuietFlag(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	for _, name := range []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL", "CIRCLECI", "TRAVIS", "BUILDKITE", "TEAMCITY_VERSION", "TF_BUILD", "bamboo_buildKey"} {
+		t.Setenv(name, "")
+	}
+	defer func() {
+		quiet = false
+		verbosity = 0
+		internal.SetVerbosity(internal.VerbosityNormal)
+		storagePaths = nil
+		outputDir = "./exports"
+		clearCache = false
+	}()
+
+	run := func(args ...string) (string, error) {
+		quiet, verbosity, storagePaths = false, 0, nil
+		var out bytes.Buffer
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	if _, err := run("--quiet", "-v", "snoop"); exitCode(err) != exitUsage {
+		t.Errorf("--quiet with --verbose should be a usage error, got: %v", err)
+	}
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("quiet-session", []internal.Message{{Actor: "user", Content: "Hello"}})
+	if err := os.WriteFile(filepath.Join(dir, "session_quiet-session.json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	listPreview, listThread, listAllWorkspaces, listFilter = false, false, false, ""
+	got, err := run("list", "--quiet", "--storage", dir)
+	if err != nil {
+		t.Fatalf("list --quiet error = %v", err)
+	}
+	if !strings.Contains(got, "Hello") || strings.Contains(got, "Found") || strings.Contains(got, "Tip") {
+		t.Errorf("list --quiet should print only the table, got:\n%s", got)
+	}
+
+	out := testutil.CreateTempDir(t)
+	sessionID, exportName, workspace, intermediary = "", "", "", false
+	got, err = run("export", "-q", "--storage", dir, "--out", out, "--clear-cache")
+	if err != nil {
+		t.Fatalf("export -q error = %v", err)
+	}
+	if got != out+"\n" {
+		t.Errorf("export -q should print only the destination, got:\n%s", got)
+	}
+
+	globalStorage := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	testutil.CreateSQLiteFixture(t, filepath.Join(globalStorage, "state.vscdb"))
+	_ = healthcheckCmd.Flags().Set("help", "false")
+	got, err = run("healthcheck", "-q", "--storage", globalStorage)
+	if err != nil {
+		t.Fatalf("healthcheck -q error = %v", err)
+	}
+	if got != "ok\n" {
+		t.Errorf("healthcheck -q should print only the status, got:\n%s", got)
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretDetectors  []string
+	entropyThreshold float64
+)
+
+var findingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+
+// secretsFoundError is returned when a scan finds potential secrets
+type secretsFoundError struct {
+	findings int
+	sessions int
+}
+
+func (e *secretsFoundError) Error() string {
+	return fmt.Sprintf("found %d potential secret(s) in %d session(s)", e.findings, e.sessions)
+}
+
+// scanCmd represents the scan command
+var scanCmd = &cobra.Command{
+	Use:   "scan [session-id]",
+	Short: "Scan sessions for leaked secrets",
+	Long: `Scan the messages of every session, or of one session given by ID or unique ID
+prefix, for secrets such as cloud credentials, tokens and private keys.
+
+Each finding is reported with the session, the message number and the line
+within the message; the secret itself is masked. The command exits with code 5
+when anything is found, so it can gate CI jobs that publish transcripts.
+
+Detectors: aws-access-key, aws-secret-key, github-token, slack-token,
+private-key and high-entropy (long random-looking tokens).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		detectors, err := internal.NewSecretDetectors(secretDetectors, entropyThreshold)
+		if err != nil {
+			return &usageError{err: err}
+		}
+
+		sessions, err := loadStorageSessions()
+		if err != nil {
+			return err
+		}
+
+		if len(args) > 0 {
+			refs := make([]internal.SessionRef, 0, len(sessions))
+			for _, session := range sessions {
+				refs = append(refs, internal.SessionRef{ID: session.ID, Name: session.Metadata.Name})
+			}
+			resolvedID, err := internal.ResolveSession(refs, args[0], "")
+			if err != nil {
+				return err
+			}
+			for _, session := range sessions {
+				if session.ID == resolvedID {
+					sessions = []*internal.Session{session}
+					break
+				}
+			}
+		}
+
+		if err := scanSessions(out, sessions, detectors); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "✅ No secrets found in %d session(s)\n", len(sessions))
+		return nil
+	},
+}
+
+// scanSessions reports the potential secrets in sessions to out and returns a
+// secretsFoundError if there are any
+func scanSessions(out io.Writer, sessions []*internal.Session, detectors []internal.SecretDetector) error {
+	findings, affected := 0, 0
+	for _, session := range sessions {
+		found := internal.ScanSession(session, detectors)
+		if len(found) == 0 {
+			continue
+		}
+		findings += len(found)
+		affected++
+
+		name := session.Metadata.Name
+		if name == "" {
+			name = "Untitled"
+		}
+		_, _ = fmt.Fprintf(out, "🔑 %s %s\n", idStyle.Render(session.ID), name)
+		for _, f := range found {
+			_, _ = fmt.Fprintf(out, "   message %d (%s), line %d: %s %s\n",
+				f.MessageIndex, f.Actor, f.Line, findingStyle.Render(f.Detector), f.Redacted)
+		}
+	}
+
+	if findings == 0 {
+		return nil
+	}
+	return &secretsFoundError{findings: findings, sessions: affected}
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().StringSliceVar(&secretDetectors, "detectors", nil, "Detectors to run (default all): "+strings.Join(internal.SecretDetectorNames(), ", "))
+	scanCmd.Flags().Float64Var(&entropyThreshold, "entropy-threshold", internal.DefaultEntropyThreshold, "Bits of entropy per character above which the high-entropy detector reports a token")
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// writeLeakySessions writes an export directory with one clean session and one session
+// containing a GitHub token, and returns the directory and the token
+func writeLeakySessions(t *testing.T) (string, string) {
+	t.Helper()
+	token := "ghp_" + strings.Repeat("a1B2c3D4e5", 4)
+	dir := testutil.CreateTempDir(t)
+	sessions := []*internal.Session{
+		internal.CreateTestSessionWithMessages("clean-session", []internal.Message{
+			{Actor: "user", Content: "How do I list files?"},
+		}),
+		internal.CreateTestSessionWithMessages("leaky-session", []internal.Message{
+			{Actor: "user", Content: "Why does the push fail?"},
+			{Actor: "assistant", Content: "Check the remote.\ngit remote set-url origin https://" + token + "@github.com/acme/app"},
+		}),
+	}
+	for _, session := range sessions {
+		path := filepath.Join(dir, "session_"+session.ID+".json")
+		if err := os.WriteFile(path, testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+	return dir, token
+}
+
+func TestScanCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		secretDetectors = nil
+		entropyThreshold = internal.DefaultEntropyThreshold
+	}()
+	dir, token := writeLeakySessions(t)
+
+	run := func(args ...string) (string, error) {
+		storagePaths, secretDetectors = nil, nil
+		var out bytes.Buffer
+		rootCmd.SetArgs(append([]string{"scan", "--storage", dir}, args...))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	output, err := run()
+	if exitCode(err) != exitSecretsFound {
+		t.Fatalf("scan error = %v, want exit code %d", err, exitSecretsFound)
+	}
+	if !strings.Contains(output, "leaky-session") || !strings.Contains(output, "message 2 (assistant), line 2") || !strings.Contains(output, "github-token") {
+		t.Errorf("scan output does not locate the token:\n%s", output)
+	}
+	if strings.Contains(output, token) || strings.Contains(output, "clean-session") {
+		t.Errorf("scan output shows the token or a clean session:\n%s", output)
+	}
+
+	if output, err := run("clean-session"); err != nil || !strings.Contains(output, "No secrets found in 1 session(s)") {
+		t.Errorf("scan clean-session = %q, %v, want no secrets", output, err)
+	}
+	if _, err := run("--detectors", "aws-access-key"); err != nil {
+		t.Errorf("scan --detectors aws-access-key error = %v, want none", err)
+	}
+	if _, err := run("--detectors", "passwords"); exitCode(err) != exitUsage {
+		t.Errorf("scan --detectors passwords error = %v, want a usage error", err)
+	}
+}
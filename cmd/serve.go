@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen  string
+	serveToken   string
+	serveRefresh time.Duration
+)
+
+// defaultSearchLimit is the number of search results returned when ?limit is not given
+const defaultSearchLimit = 100
+
+// sessionSummary describes a session without its messages
+type sessionSummary struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name,omitempty"`
+	GeneratedName bool     `json:"generated_name,omitempty"`
+	Workspace     string   `json:"workspace,omitempty"`
+	Source        string   `json:"source,omitempty"`
+	CreatedAt     string   `json:"created_at,omitempty"`
+	UpdatedAt     string   `json:"updated_at,omitempty"`
+	MessageCount  int      `json:"message_count"`
+	Tags          []string `json:"tags,omitempty"`
+	Duplicates    []string `json:"duplicates,omitempty"`
+}
+
+// searchResult is one message matching a /search query
+type searchResult struct {
+	SessionID    string `json:"session_id"`
+	SessionName  string `json:"session_name,omitempty"`
+	MessageIndex int    `json:"message_index"`
+	Actor        string `json:"actor"`
+	Timestamp    string `json:"timestamp,omitempty"`
+	Snippet      string `json:"snippet"`
+}
+
+// sessionServer serves sessions over HTTP. Sessions are kept in memory and reloaded, from
+// the cache when it is still valid and from storage otherwise, at most once per refresh
+// interval.
+type sessionServer struct {
+	mu       sync.Mutex
+	load     func() ([]*internal.Session, error)
+	refresh  time.Duration
+	token    string
+	sessions []*internal.Session
+	loadedAt time.Time
+}
+
+// current returns the loaded sessions, reloading them when the refresh interval has passed
+func (s *sessionServer) current() ([]*internal.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions != nil && time.Since(s.loadedAt) < s.refresh {
+		return s.sessions, nil
+	}
+	sessions, err := s.load()
+	if err != nil {
+		if s.sessions != nil {
+			internal.LogWarn("Failed to reload sessions, serving previous data: %v", err)
+			return s.sessions, nil
+		}
+		return nil, err
+	}
+	s.sessions = sessions
+	s.loadedAt = time.Now()
+	return sessions, nil
+}
+
+// handler returns the API routes, behind token authentication when a token is set
+func (s *sessionServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("GET /sessions", s.authenticate(s.handleSessions))
+	mux.Handle("GET /sessions/{id}", s.authenticate(s.handleSession))
+	mux.Handle("GET /sessions/{id}/messages", s.authenticate(s.handleMessages))
+	mux.Handle("GET /search", s.authenticate(s.handleSearch))
+	return mux
+}
+
+// authenticate requires "Authorization: Bearer <token>" when the server has a token
+func (s *sessionServer) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "missing or invalid token")
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+// handleSessions lists session summaries, optionally filtered by ?workspace and ?tag
+func (s *sessionServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	workspaceFilter := r.URL.Query().Get("workspace")
+	tagFilter := strings.ToLower(r.URL.Query().Get("tag"))
+	summaries := make([]sessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		if workspaceFilter != "" && session.Workspace != workspaceFilter {
+			continue
+		}
+		if tagFilter != "" && !hasTag(session.Metadata.Tags, tagFilter) {
+			continue
+		}
+		summaries = append(summaries, summarizeSession(session))
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// handleSession returns the summary of one session
+func (s *sessionServer) handleSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.findSession(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, summarizeSession(session))
+}
+
+// handleMessages returns the messages of one session, filtered by ?since, ?until and ?actor
+// like 'export'
+func (s *sessionServer) handleMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := internal.NewMessageFilter(query.Get("since"), query.Get("until"), query.Get("actor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, ok := s.findSession(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	// Copy the messages so the UTC timestamps don't change the loaded sessions
+	messages := append([]internal.Message{}, filter.Apply(session).Messages...)
+	for i := range messages {
+		messages[i].Timestamp = internal.UTCTimestamp(messages[i].Timestamp)
+	}
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// handleSearch returns messages containing ?q, case-insensitively, up to ?limit results
+func (s *sessionServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing search query: use ?q=")
+		return
+	}
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be a positive integer", value))
+			return
+		}
+		limit = n
+	}
+
+	sessions, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	results := make([]searchResult, 0)
+	for _, session := range sessions {
+		for i, msg := range session.Messages {
+			pos := strings.Index(strings.ToLower(msg.Content), query)
+			if pos < 0 {
+				continue
+			}
+			results = append(results, searchResult{
+				SessionID:    session.ID,
+				SessionName:  session.Metadata.Name,
+				MessageIndex: i,
+				Actor:        msg.Actor,
+				Timestamp:    internal.UTCTimestamp(msg.Timestamp),
+				Snippet:      snippet(msg.Content, pos, len(query)),
+			})
+			if len(results) == limit {
+				writeJSON(w, http.StatusOK, results)
+				return
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// findSession resolves a full session ID or unique ID prefix, writing an error response
+// when the session cannot be found
+func (s *sessionServer) findSession(w http.ResponseWriter, id string) (*internal.Session, bool) {
+	sessions, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	refs := make([]internal.SessionRef, 0, len(sessions))
+	for _, session := range sessions {
+		refs = append(refs, internal.SessionRef{ID: session.ID, Name: session.Metadata.Name})
+	}
+	resolved, err := internal.ResolveSessionID(refs, id)
+	if err != nil {
+		var ambiguous *internal.AmbiguousSessionError
+		if errors.As(err, &ambiguous) {
+			writeError(w, http.StatusConflict, err.Error())
+		} else {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("session not found: %s", id))
+		}
+		return nil, false
+	}
+
+	for _, session := range sessions {
+		if session.ID == resolved {
+			return session, true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("session not found: %s", id))
+	return nil, false
+}
+
+// summarizeSession returns the summary of a session, with timestamps in UTC
+func summarizeSession(session *internal.Session) sessionSummary {
+	return sessionSummary{
+		ID:            session.ID,
+		Name:          session.Metadata.Name,
+		GeneratedName: session.Metadata.GeneratedName,
+		Workspace:     session.Workspace,
+		Source:        session.Source,
+		CreatedAt:     internal.UTCTimestamp(session.Metadata.CreatedAt),
+		UpdatedAt:     internal.UTCTimestamp(session.Metadata.UpdatedAt),
+		MessageCount:  len(session.Messages),
+		Tags:          session.Metadata.Tags,
+		Duplicates:    session.Metadata.Duplicates,
+	}
+}
+
+// hasTag reports whether tags contains tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, strings.TrimSpace(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet returns up to 80 characters of text on either side of the match at pos, on one line
+func snippet(text string, pos, length int) string {
+	const snippetContext = 80
+	start := max(pos-snippetContext, 0)
+	end := min(pos+length+snippetContext, len(text))
+	// pos comes from the lowercased text, which can differ in length for some scripts
+	start = min(start, end)
+	// Move the cut points off the middle of multi-byte characters
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	s := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(text) {
+		s += "..."
+	}
+	return s
+}
+
+// isLoopbackAddress reports whether a listen address only accepts local connections. An
+// empty host listens on every interface.
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve sessions over an HTTP API",
+	Long: `Serve sessions as JSON over HTTP, for dashboards and scripts that would
+otherwise parse the output of 'list' and 'show'.
+
+Endpoints:
+  GET /sessions                  Session summaries (?workspace=, ?tag=)
+  GET /sessions/{id}             One session summary; {id} may be an ID prefix
+  GET /sessions/{id}/messages    Messages of a session (?since=, ?until=, ?actor=)
+  GET /search?q=<text>           Messages containing the text (?limit=, default 100)
+  GET /healthz                   Liveness check, never requires a token
+
+Sessions are read from the cache when it is valid and reconstructed from
+storage otherwise, at most once per --refresh interval. With --token (or
+CURSOR_SESSION_TOKEN), every endpoint except /healthz requires the header
+"Authorization: Bearer <token>".
+
+The server listens on 127.0.0.1:8080 by default. Listening on any other
+address serves private chat history over the network, so it requires a
+token.
+
+Example:
+  cursor-session serve
+  CURSOR_SESSION_TOKEN=s3cret cursor-session serve --listen :8080`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveRefresh <= 0 {
+			return usageErrorf("invalid refresh interval: %s (must be positive)", serveRefresh)
+		}
+
+		token := serveToken
+		if token == "" {
+			token = os.Getenv("CURSOR_SESSION_TOKEN")
+		}
+		if token == "" && !isLoopbackAddress(serveListen) {
+			return usageErrorf("refusing to serve on %s without a token; use --token or CURSOR_SESSION_TOKEN, or listen on a loopback address", serveListen)
+		}
+
+		server := &sessionServer{load: loadStorageSessions, refresh: serveRefresh, token: token}
+		// Load once up front so storage problems are reported before listening
+		if _, err := server.current(); err != nil {
+			return err
+		}
+		if token == "" {
+			internal.LogWarn("Serving without authentication; use --token to require a bearer token")
+		}
+
+		listener, err := net.Listen("tcp", serveListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", serveListen, err)
+		}
+		httpServer := &http.Server{Handler: server.handler(), ReadHeaderTimeout: 5 * time.Second}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			internal.LogInfo("Stopping server")
+			_ = httpServer.Close()
+		}()
+
+		internal.LogInfo("Serving sessions on http://%s", listener.Addr())
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address to listen on; non-loopback addresses require a token")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Require this bearer token on API requests (env CURSOR_SESSION_TOKEN)")
+	serveCmd.Flags().DurationVar(&serveRefresh, "refresh", 30*time.Second, "How often to check storage for new and changed sessions")
+}
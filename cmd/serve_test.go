@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// newTestServer returns an API server over fixed sessions
+func newTestServer(token string) *sessionServer {
+	alpha := internal.CreateTestSessionWithMessages("alpha-1111", []internal.Message{
+		{Actor: "user", Content: "How do I fix the flaky test?", Timestamp: "2024-03-01T10:00:00+01:00"},
+		{Actor: "assistant", Content: "Add a retry around the network call.", Timestamp: "2024-03-01T10:01:00+01:00"},
+	})
+	alpha.Metadata.Name = "Flaky test"
+	alpha.Metadata.Tags = []string{"ci"}
+	beta := internal.CreateTestSessionWithMessages("beta-2222", []internal.Message{
+		{Actor: "user", Content: "Write a README"},
+	})
+	beta.Workspace = "/projects/docs"
+
+	return &sessionServer{
+		load: func() ([]*internal.Session, error) {
+			return []*internal.Session{alpha, beta}, nil
+		},
+		refresh: time.Minute,
+		token:   token,
+	}
+}
+
+// get performs a GET request against the server and decodes the JSON body into v
+func get(t *testing.T, server *sessionServer, path, token string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.handler().ServeHTTP(rec, req)
+
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v\n%s", path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestSessionServer_Sessions(t *testing.T) {
+	server := newTestServer("")
+
+	var summaries []sessionSummary
+	if code := get(t, server, "/sessions", "", &summaries); code != http.StatusOK {
+		t.Fatalf("GET /sessions = %d, want 200", code)
+	}
+	if len(summaries) != 2 || summaries[0].ID != "alpha-1111" || summaries[0].MessageCount != 2 {
+		t.Errorf("GET /sessions = %+v", summaries)
+	}
+	if summaries[0].CreatedAt != "" && !strings.HasSuffix(summaries[0].CreatedAt, "Z") {
+		t.Errorf("CreatedAt = %q, want UTC", summaries[0].CreatedAt)
+	}
+
+	summaries = nil
+	get(t, server, "/sessions?tag=CI", "", &summaries)
+	if len(summaries) != 1 || summaries[0].ID != "alpha-1111" {
+		t.Errorf("GET /sessions?tag=CI = %+v, want only alpha-1111", summaries)
+	}
+
+	summaries = nil
+	get(t, server, "/sessions?workspace=/projects/docs", "", &summaries)
+	if len(summaries) != 1 || summaries[0].ID != "beta-2222" {
+		t.Errorf("GET /sessions?workspace= = %+v, want only beta-2222", summaries)
+	}
+}
+
+func TestSessionServer_Session(t *testing.T) {
+	server := newTestServer("")
+
+	var summary sessionSummary
+	if code := get(t, server, "/sessions/alpha", "", &summary); code != http.StatusOK {
+		t.Fatalf("GET /sessions/alpha = %d, want 200", code)
+	}
+	if summary.ID != "alpha-1111" || summary.Name != "Flaky test" {
+		t.Errorf("GET /sessions/alpha = %+v", summary)
+	}
+
+	var body map[string]string
+	if code := get(t, server, "/sessions/missing", "", &body); code != http.StatusNotFound || body["error"] == "" {
+		t.Errorf("GET /sessions/missing = %d %v, want 404 with an error", code, body)
+	}
+}
+
+func TestSessionServer_Messages(t *testing.T) {
+	server := newTestServer("")
+
+	var messages []internal.Message
+	if code := get(t, server, "/sessions/alpha-1111/messages?actor=assistant", "", &messages); code != http.StatusOK {
+		t.Fatalf("GET messages = %d, want 200", code)
+	}
+	if len(messages) != 1 || messages[0].Actor != "assistant" {
+		t.Fatalf("GET messages?actor=assistant = %+v", messages)
+	}
+	if messages[0].Timestamp != "2024-03-01T09:01:00Z" {
+		t.Errorf("Timestamp = %q, want UTC", messages[0].Timestamp)
+	}
+
+	// The loaded session keeps its original timestamps
+	sessions, _ := server.current()
+	if ts := sessions[0].Messages[1].Timestamp; ts != "2024-03-01T10:01:00+01:00" {
+		t.Errorf("loaded session timestamp changed to %q", ts)
+	}
+
+	if code := get(t, server, "/sessions/alpha-1111/messages?actor=robot", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET messages?actor=robot = %d, want 400", code)
+	}
+}
+
+func TestSessionServer_Search(t *testing.T) {
+	server := newTestServer("")
+
+	var results []searchResult
+	if code := get(t, server, "/search?q=RETRY", "", &results); code != http.StatusOK {
+		t.Fatalf("GET /search = %d, want 200", code)
+	}
+	if len(results) != 1 || results[0].SessionID != "alpha-1111" || results[0].MessageIndex != 1 {
+		t.Fatalf("GET /search?q=RETRY = %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "retry") {
+		t.Errorf("Snippet = %q, want the match", results[0].Snippet)
+	}
+
+	results = nil
+	get(t, server, "/search?q=a&limit=1", "", &results)
+	if len(results) != 1 {
+		t.Errorf("GET /search?limit=1 returned %d results, want 1", len(results))
+	}
+
+	if code := get(t, server, "/search", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /search without q = %d, want 400", code)
+	}
+	if code := get(t, server, "/search?q=a&limit=0", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /search?limit=0 = %d, want 400", code)
+	}
+}
+
+func TestSessionServer_Token(t *testing.T) {
+	server := newTestServer("s3cret")
+
+	if code := get(t, server, "/sessions", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /sessions without token = %d, want 401", code)
+	}
+	if code := get(t, server, "/sessions", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /sessions with wrong token = %d, want 401", code)
+	}
+	if code := get(t, server, "/sessions", "s3cret", nil); code != http.StatusOK {
+		t.Errorf("GET /sessions with token = %d, want 200", code)
+	}
+	if code := get(t, server, "/healthz", "", nil); code != http.StatusOK {
+		t.Errorf("GET /healthz without token = %d, want 200", code)
+	}
+}
+
+func TestSessionServer_Reload(t *testing.T) {
+	loads := 0
+	server := &sessionServer{
+		load: func() ([]*internal.Session, error) {
+			loads++
+			if loads > 1 {
+				return nil, errors.New("storage unavailable")
+			}
+			return []*internal.Session{internal.CreateTestSession("s1")}, nil
+		},
+		refresh: time.Minute,
+	}
+
+	if _, err := server.current(); err != nil {
+		t.Fatalf("current() error = %v", err)
+	}
+	if _, err := server.current(); err != nil || loads != 1 {
+		t.Errorf("current() within the refresh interval reloaded (%d loads, err %v)", loads, err)
+	}
+
+	// A failed reload keeps serving the previous sessions
+	server.loadedAt = time.Now().Add(-2 * time.Minute)
+	sessions, err := server.current()
+	if err != nil || len(sessions) != 1 || loads != 2 {
+		t.Errorf("current() after failed reload = %d sessions, err %v, %d loads", len(sessions), err, loads)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("a ", 100) + "needle" + strings.Repeat(" b", 100)
+	got := snippet(text, strings.Index(text, "needle"), len("needle"))
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") || !strings.Contains(got, "needle") {
+		t.Errorf("snippet() = %q", got)
+	}
+	if got := snippet("short needle", 6, 6); got != "short needle" {
+		t.Errorf("snippet() of short text = %q, want it whole", got)
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+		"not-an-address": false,
+	}
+	for addr, want := range tests {
+		if got := isLoopbackAddress(addr); got != want {
+			t.Errorf("isLoopbackAddress(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestServeCmd_RequiresTokenOffLoopback(t *testing.T) {
+	t.Setenv("CURSOR_SESSION_TOKEN", "")
+	oldListen, oldToken, oldRefresh := serveListen, serveToken, serveRefresh
+	t.Cleanup(func() { serveListen, serveToken, serveRefresh = oldListen, oldToken, oldRefresh })
+	serveListen, serveToken, serveRefresh = ":0", "", time.Second
+
+	err := serveCmd.RunE(serveCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "without a token") {
+		t.Errorf("serve on all interfaces without a token: err = %v, want refusal", err)
+	}
+}
+
+func TestServeCmd_TokenDefaultNotFromEnv(t *testing.T) {
+	if def := serveCmd.Flags().Lookup("token").DefValue; def != "" {
+		t.Errorf("--token default = %q, want empty so help never prints the secret", def)
+	}
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// primaryStoragePath returns the --storage path for commands that inspect a single storage
+// location, or "" to auto-detect. Only list, show, export, exportd, serve, scan, reconstruct
+// and tag combine several paths; other commands use the first one.
+func primaryStoragePath(command string) string {
+	if len(storagePaths) == 0 {
+		return ""
+	}
+	if len(storagePaths) > 1 {
+		internal.LogWarn("%s inspects one storage location; ignoring %d other --storage path(s)", command, len(storagePaths)-1)
+	}
+	return storagePaths[0]
+}
+
+// resolveStoragePaths returns the --storage locations, or the auto-detected default. With
+// --all-builds and no --storage, every installed Cursor build with data is read; their
+// sessions are combined like several --storage paths and are not cached.
+func resolveStoragePaths() ([]internal.StoragePaths, error) {
+	if allBuilds && len(storagePaths) == 0 {
+		return internal.DetectAllStoragePaths()
+	}
+	return internal.GetStoragePathsList(storagePaths)
+}
+
+// cacheDirectory returns the directory sessions and tags are cached in, moving a cache
+// left in the legacy ~/.cursor-session-cache there on first use. If it cannot be moved,
+// the legacy cache keeps being used.
+func cacheDirectory() (string, error) {
+	dirs, err := internal.ResolveAppDirs()
+	if err != nil {
+		return "", err
+	}
+	moved, err := dirs.MigrateLegacyCache()
+	if err != nil {
+		internal.LogWarn("Failed to move the cache from %s to %s: %v", dirs.LegacyCacheDir, dirs.CacheDir, err)
+		if _, statErr := os.Stat(dirs.LegacyCacheDir); statErr == nil {
+			return dirs.LegacyCacheDir, nil
+		}
+	} else if moved {
+		internal.LogInfo("Moved the cache from %s to %s", dirs.LegacyCacheDir, dirs.CacheDir)
+	}
+	return dirs.CacheDir, nil
+}
+
+// storageCacheKey returns the key the session cache is validated against for the given
+// storage, or "" when sessions are combined from several locations, or read from a remote
+// one, and are not cached
+func storageCacheKey(list []internal.StoragePaths) string {
+	if len(list) != 1 || list[0].Location != "" {
+		return ""
+	}
+	return storagePathsCacheKey(list[0])
+}
+
+// storagePathsCacheKey returns the cache key of a single storage location
+func storagePathsCacheKey(paths internal.StoragePaths) string {
+	// A copy is cached under the location it was copied from, so caching survives copying
+	if paths.CopiedFrom != nil {
+		return storagePathsCacheKey(*paths.CopiedFrom)
+	}
+	if paths.Location != "" {
+		return paths.Location
+	} else if paths.GlobalStorageExists() {
+		return paths.GetGlobalStorageDBPath()
+	} else if paths.HasAgentStorage() {
+		return paths.AgentStoragePath
+	} else if paths.ExportDir != "" {
+		return paths.ExportDir
+	}
+	return "unknown"
+}
+
+// hideGeneratedTitles clears names derived from the conversation when --no-generated-titles
+// is set. Generated titles are always cached, so the flag applies whether or not sessions
+// came from the cache.
+func hideGeneratedTitles(sessions []*internal.Session) {
+	if !noGeneratedTitles {
+		return
+	}
+	for _, session := range sessions {
+		if session != nil && session.Metadata.GeneratedName {
+			session.Metadata.Name = ""
+			session.Metadata.GeneratedName = false
+		}
+	}
+}
+
+// applySessionTags copies the tags from the tag store in the cache directory onto sessions,
+// so exports carry tags added after the sessions were cached
+func applySessionTags(sessions []*internal.Session) {
+	if tags := loadTagStore(); tags != nil {
+		tags.Apply(sessions)
+	}
+}
+
+// loadTagStore loads the tag store in the cache directory, or returns nil when it can't
+// be read
+func loadTagStore() *internal.TagStore {
+	cacheDir, err := cacheDirectory()
+	if err != nil {
+		internal.LogWarn("Failed to load tags: %v", err)
+		return nil
+	}
+	tags, err := internal.NewCacheManager(cacheDir).LoadTags()
+	if err != nil {
+		internal.LogWarn("Failed to load tags: %v", err)
+		return nil
+	}
+	return tags
+}
+
+// titleComposers names composers Cursor left unnamed after their first user message, for
+// listings read straight from storage, unless --no-generated-titles is set
+func titleComposers(backend internal.StorageBackend, composers []*internal.RawComposer) {
+	if noGeneratedTitles || backend == nil {
+		return
+	}
+
+	unnamed := false
+	for _, composer := range composers {
+		if composer.Name == "" {
+			unnamed = true
+			break
+		}
+	}
+	if !unnamed {
+		return
+	}
+
+	bubbles, err := backend.LoadBubbles()
+	if err != nil {
+		internal.LogWarn("Failed to load bubbles for session titles: %v", err)
+		return
+	}
+	titles := internal.GenerateComposerTitles(composers, bubbles, internal.FirstMessageSummarizer{})
+	for _, composer := range composers {
+		if title, ok := titles[composer.ComposerID]; ok {
+			composer.Name = title
+		}
+	}
+}
+
+// loadSessions returns every session in the storage, from the cache when it is valid and
+// reconstructed otherwise. Reconstructed sessions are cached unless they were combined
+// from several storage locations.
+func loadSessions(backend internal.StorageBackend, paths []internal.StoragePaths) ([]*internal.Session, error) {
+	cacheDir, err := cacheDirectory()
+	if err != nil {
+		return nil, err
+	}
+	cacheManager := internal.NewCacheManager(cacheDir)
+	cacheKey := storageCacheKey(paths)
+
+	if cacheKey != "" {
+		if valid, err := cacheManager.IsCacheValid(cacheKey); err == nil && valid {
+			sessions, err := cacheManager.LoadAllSessions()
+			if err == nil {
+				return sessions, nil
+			}
+			internal.LogWarn("Failed to load cache: %v, reconstructing...", err)
+		}
+	}
+
+	conversations, err := internal.ReconstructConversations(backend)
+	if err != nil {
+		return nil, err
+	}
+	sessions := internal.NormalizeSessions(conversations, backend, paths, "")
+	if cacheKey != "" {
+		if err := cacheManager.SaveSessions(sessions, cacheKey); err != nil {
+			internal.LogWarn("Failed to save cache: %v", err)
+		}
+	}
+	return sessions, nil
+}
+
+// openStorageBackend opens the --storage locations, copying the databases when
+// --read-strategy asks for it. It returns the locations read and a function removing
+// the copies.
+func openStorageBackend() (internal.StorageBackend, []internal.StoragePaths, func(), error) {
+	paths, err := resolveStoragePaths()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get storage paths: %w", err)
+	}
+
+	cleanup := func() {}
+	if copyStorage(paths...) {
+		var removeCopies func() error
+		paths, removeCopies, err = internal.CopyStoragePathsList(paths)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to copy database files: %w", err)
+		}
+		cleanup = func() {
+			if err := removeCopies(); err != nil {
+				internal.LogWarn("Failed to cleanup temporary files: %v", err)
+			} else {
+				internal.LogInfo("Cleaned up temporary database files")
+			}
+		}
+	}
+
+	backend, err := internal.NewStorageBackendForPaths(paths)
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return backend, paths, cleanup, nil
+}
+
+// loadStorageSessions opens the --storage locations, honoring --copy, and loads every
+// session with tags and title settings applied
+func loadStorageSessions() ([]*internal.Session, error) {
+	paths, err := resolveStoragePaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage paths: %w", err)
+	}
+
+	// Copy database files so the running editor is not disturbed
+	if copyStorage(paths...) {
+		var cleanup func() error
+		paths, cleanup, err = internal.CopyStoragePathsList(paths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy database files: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				internal.LogWarn("Failed to cleanup temporary files: %v", err)
+			}
+		}()
+	}
+
+	backend, err := internal.NewStorageBackendForPaths(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	sessions, err := loadSessions(backend, paths)
+	if err != nil {
+		return nil, err
+	}
+	hideGeneratedTitles(sessions)
+	applySessionTags(sessions)
+	internal.LogInfo("Loaded %d session(s)", len(sessions))
+	return sessions, nil
+}
+
+// resolveSessionArg resolves a session given on the command line as a full ID or a unique
+// ID prefix to its full ID, and returns the cache manager the session's tags and marks
+// are kept by
+func resolveSessionArg(query string) (string, *internal.CacheManager, error) {
+	paths, err := resolveStoragePaths()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get storage paths: %w", err)
+	}
+	backend, err := internal.NewStorageBackendForPaths(paths)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	cacheDir, err := cacheDirectory()
+	if err != nil {
+		return "", nil, err
+	}
+	cacheManager := internal.NewCacheManager(cacheDir)
+
+	valid, _ := cacheManager.IsCacheValid(storageCacheKey(paths))
+	index, _ := cacheManager.LoadIndex()
+	refs, err := loadSessionRefs(index, valid, backend)
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := internal.ResolveSession(refs, query, "")
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, cacheManager, nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestHideGeneratedTitles(t *testing.T) {
+	defer func() { noGeneratedTitles = false }()
+
+	newSessions := func() []*internal.Session {
+		return []*internal.Session{
+			{ID: "named", Metadata: internal.Metadata{Name: "From Cursor"}},
+			{ID: "generated", Metadata: internal.Metadata{Name: "Fix the build", GeneratedName: true}},
+			nil,
+		}
+	}
+
+	sessions := newSessions()
+	hideGeneratedTitles(sessions)
+	if sessions[1].Metadata.Name != "Fix the build" {
+		t.Errorf("generated title was hidden without --no-generated-titles")
+	}
+
+	noGeneratedTitles = true
+	sessions = newSessions()
+	hideGeneratedTitles(sessions)
+	if sessions[0].Metadata.Name != "From Cursor" {
+		t.Errorf("Cursor name = %q, want it kept", sessions[0].Metadata.Name)
+	}
+	if sessions[1].Metadata.Name != "" || sessions[1].Metadata.GeneratedName {
+		t.Errorf("generated title = %q, want it hidden", sessions[1].Metadata.Name)
+	}
+}
+
+func TestStoragePathsCacheKey_Copy(t *testing.T) {
+	original := internal.StoragePaths{ExportDir: "/archive/sessions"}
+	copied := internal.StoragePaths{ExportDir: "/tmp/cursor-session-123", CopiedFrom: &original}
+	if got := storagePathsCacheKey(copied); got != "/archive/sessions" {
+		t.Errorf("storagePathsCacheKey() of a copy = %q, want the original location", got)
+	}
+}
+
+func TestResolveStoragePaths_AllBuilds(t *testing.T) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("storage detection is only supported on macOS and Linux")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	defer func() { allBuilds, storagePaths = false, nil }()
+
+	candidates, err := internal.InstallCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range candidates[:2] {
+		paths := internal.StoragePathsForBase(candidate.BasePath)
+		if err := os.MkdirAll(paths.GlobalStorage, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(paths.GetGlobalStorageDBPath(), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// By default only the first build is read, so its sessions stay cached
+	list, err := resolveStoragePaths()
+	if err != nil {
+		t.Fatalf("resolveStoragePaths() error = %v", err)
+	}
+	if len(list) != 1 || storageCacheKey(list) == "" {
+		t.Errorf("resolveStoragePaths() = %d location(s), cache key %q; want one cached location", len(list), storageCacheKey(list))
+	}
+
+	allBuilds = true
+	list, err = resolveStoragePaths()
+	if err != nil {
+		t.Fatalf("resolveStoragePaths() with --all-builds error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("resolveStoragePaths() with --all-builds = %d location(s), want 2", len(list))
+	}
+}
@@ -1,20 +1,29 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
-	limit int
-	since string
+	limit      int
+	since      string
+	showName   string
+	showThread bool
+	showRaw    bool
+	showCopy   bool
 )
 
 var (
@@ -39,10 +48,29 @@ var (
 				Bold(true).
 				Padding(0, 1)
 
+	toolMessageStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214")).
+				Bold(true).
+				Padding(0, 1)
+
+	terminalMessageStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("78")).
+				Bold(true).
+				Padding(0, 1)
+
+	systemMessageStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("243")).
+				Bold(true).
+				Padding(0, 1)
+
 	messageContentStyle = lipgloss.NewStyle().
 				Padding(0, 2).
 				MarginBottom(1)
 
+	// Command and tool output is dimmed so it stands apart from the model's text
+	outputContentStyle = messageContentStyle.
+				Foreground(lipgloss.Color("250"))
+
 	timestampStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240")).
 			Italic(true)
@@ -50,24 +78,39 @@ var (
 
 // showCmd represents the show command
 var showCmd = &cobra.Command{
-	Use:   "show <session-id>",
+	Use:   "show [session-id]",
 	Short: "Show messages for a specific session",
-	Long:  `Display messages from a specific chat session.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Display messages from a specific chat session.
+
+The session can be given as a full ID, a unique ID prefix (such as the
+8-character short ID shown by 'cursor-session list'), or looked up by
+name with --name.
+
+--copy-to-clipboard also places the messages shown on the clipboard as
+Markdown, ready to paste into a pull request or issue.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: sessionIDArg,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		sessionID := args[0]
+		out := commandOutput(cmd)
+		var sessionQuery string
+		if len(args) > 0 {
+			sessionQuery = args[0]
+		}
+		if sessionQuery == "" && showName == "" {
+			return usageErrorf("a session ID or --name is required")
+		}
 
 		// Get paths (with optional custom storage location)
-		paths, err := internal.GetStoragePaths(storagePath)
+		paths, err := resolveStoragePaths()
 		if err != nil {
 			return fmt.Errorf("failed to get storage paths: %w", err)
 		}
 
-		// Copy database files to temp location if --copy flag is set
+		// Copy database files to temp location if --read-strategy (or --copy) asks for it
 		var cleanup func() error
-		if copyDB {
+		if copyStorage(paths...) {
 			var copyErr error
-			paths, cleanup, copyErr = internal.CopyStoragePaths(paths)
+			paths, cleanup, copyErr = internal.CopyStoragePathsList(paths)
 			if copyErr != nil {
 				return fmt.Errorf("failed to copy database files: %w", copyErr)
 			}
@@ -84,29 +127,20 @@ var showCmd = &cobra.Command{
 		}
 
 		// Create storage backend (handles both desktop app and agent storage)
-		backend, err := internal.NewStorageBackend(paths)
+		backend, err := internal.NewStorageBackendForPaths(paths)
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 
 		// Initialize cache manager (always enabled)
-		// Store cache in user's home directory root
-		homeDir, err := os.UserHomeDir()
+		cacheDir, err := cacheDirectory()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
 		}
-		cacheDir := filepath.Join(homeDir, ".cursor-session-cache")
 		cacheManager := internal.NewCacheManager(cacheDir)
 
 		// Use appropriate cache key based on storage type
-		var cacheKey string
-		if paths.GlobalStorageExists() {
-			cacheKey = paths.GetGlobalStorageDBPath()
-		} else if paths.HasAgentStorage() {
-			cacheKey = paths.AgentStoragePath
-		} else {
-			cacheKey = "unknown"
-		}
+		cacheKey := storageCacheKey(paths)
 
 		var session *internal.Session
 
@@ -121,9 +155,36 @@ var showCmd = &cobra.Command{
 			internal.LogDebug("Cache is valid")
 		}
 
-		// Try to load index and find session (even if cache is invalid)
-		index, err := cacheManager.LoadIndex()
-		if err == nil && index != nil {
+		index, indexErr := cacheManager.LoadIndex()
+
+		// Resolve the query (full ID, ID prefix, or name) to a full session ID
+		refs, err := loadSessionRefs(index, valid, backend)
+		if err != nil {
+			return err
+		}
+		sessionID, err := internal.ResolveSession(refs, sessionQuery, showName)
+		if err != nil {
+			return err
+		}
+
+		// Show the whole thread the session belongs to
+		var thread *internal.Thread
+		if showThread {
+			sessions, err := loadSessions(backend, paths)
+			if err != nil {
+				return err
+			}
+			thread = internal.FindThread(internal.BuildThreads(sessions), sessionID)
+			if thread == nil {
+				return fmt.Errorf("session not found: %s", sessionID)
+			}
+			session = thread.Timeline()
+		}
+
+		// Try to find session in the index (even if cache is invalid)
+		if thread != nil {
+			internal.LogDebug("Showing thread %s of %d session(s)", thread.ID, len(thread.Sessions))
+		} else if indexErr == nil && index != nil {
 			// Verify index is for the same database (path check)
 			if index.Metadata.DatabasePath == cacheKey {
 				internal.LogDebug("Index loaded with %d sessions, searching for composer ID: %s", len(index.Sessions), sessionID)
@@ -144,7 +205,7 @@ var showCmd = &cobra.Command{
 				internal.LogDebug("Index is for different database path, ignoring")
 			}
 		} else {
-			internal.LogDebug("Failed to load index: %v", err)
+			internal.LogDebug("Failed to load index: %v", indexErr)
 		}
 
 		// Load from storage if not in cache
@@ -185,19 +246,29 @@ var showCmd = &cobra.Command{
 				bubbleMap.Set(bubble.BubbleID, bubble)
 			}
 
+			rawDiffs, err := backend.LoadCodeBlockDiffs()
+			if err != nil {
+				internal.LogWarn("Failed to load code block diffs: %v", err)
+			}
+
 			reconstructor := internal.NewReconstructor(bubbleMap, contexts)
+			reconstructor.SetCodeDiffs(internal.ParseCodeDiffs(rawDiffs))
+			reconstructor.SetFileEdits(internal.LoadFileEdits(backend))
 			conv, err := reconstructor.ReconstructConversation(targetComposer)
 			if err != nil {
 				return fmt.Errorf("failed to reconstruct conversation: %w", err)
 			}
 
 			// Associate with workspace
-			workspaces, _ := internal.DetectWorkspaces(paths.BasePath)
+			workspaces := internal.DetectStorageWorkspaces(paths)
 			var composerContexts []*internal.MessageContext
 			if ctxs, ok := contexts[conv.ComposerID]; ok {
 				composerContexts = ctxs
 			}
 			assignedWorkspace := internal.AssociateComposerWithWorkspace(conv.ComposerID, composerContexts, workspaces)
+			if assignedWorkspace == "" {
+				assignedWorkspace = internal.BackendSessionWorkspace(backend, conv.ComposerID)
+			}
 
 			// Normalize
 			normalizer := internal.NewNormalizer()
@@ -206,16 +277,28 @@ var showCmd = &cobra.Command{
 				return fmt.Errorf("failed to normalize conversation: %w", err)
 			}
 
-			// Save to cache for future use
-			if err := cacheManager.SaveSessionAndUpdateIndex(session, cacheKey); err != nil {
+			// Save to cache for future use; sessions combined from several storage locations are not cached
+			if cacheKey == "" {
+				internal.LogDebug("Not caching session read from several storage locations")
+			} else if err := cacheManager.SaveSessionAndUpdateIndex(session, cacheKey); err != nil {
 				internal.LogWarn("Failed to save session to cache: %v", err)
 			} else {
 				internal.LogInfo("Session cached for faster future access")
 			}
 		}
 
+		hideGeneratedTitles([]*internal.Session{session})
+		applySessionTags([]*internal.Session{session})
+
 		// Display session header
-		displaySessionHeader(session)
+		displaySessionHeader(out, session)
+		if thread != nil && len(thread.Sessions) > 1 {
+			ids := make([]string, 0, len(thread.Sessions))
+			for _, s := range thread.Sessions {
+				ids = append(ids, s.ID)
+			}
+			_, _ = fmt.Fprintln(out, sessionMetaStyle.Render("Thread: "+strings.Join(ids, " → ")))
+		}
 
 		// Filter messages if needed
 		messagesToShow := session.Messages
@@ -225,7 +308,7 @@ var showCmd = &cobra.Command{
 		if since != "" {
 			parsedTime, err := time.Parse(time.RFC3339, since)
 			if err != nil {
-				return fmt.Errorf("invalid --since timestamp format (expected RFC3339): %w", err)
+				return usageErrorf("invalid --since timestamp format (expected RFC3339): %w", err)
 			}
 			sinceTime = &parsedTime
 			filtered := make([]internal.Message, 0, len(messagesToShow))
@@ -247,61 +330,146 @@ var showCmd = &cobra.Command{
 			messagesToShow = messagesToShow[:limit]
 		}
 
+		// Render assistant markdown on a terminal, unless --raw or --plain asks for the text as stored
+		var markdown *glamour.TermRenderer
+		if !showRaw && !plain && isTerminal(cmd.OutOrStdout()) {
+			if markdown, err = newMarkdownRenderer(styles.AutoStyle); err != nil {
+				internal.LogDebug("Failed to create markdown renderer: %v", err)
+			}
+		}
+
 		// Display messages
 		for i, msg := range messagesToShow {
-			displayMessage(i+1, msg, totalFiltered)
+			displayMessage(out, i+1, msg, totalFiltered, markdown)
 		}
 
 		// Show remaining count if limit was applied
 		if limit > 0 && limit < totalFiltered {
 			remaining := totalFiltered - limit
-			fmt.Println()
-			fmt.Println(lipgloss.NewStyle().
+			status := statusOutput(out)
+			_, _ = fmt.Fprintln(status)
+			_, _ = fmt.Fprintln(status, lipgloss.NewStyle().
 				Foreground(lipgloss.Color("243")).
 				Italic(true).
 				Render(fmt.Sprintf("... (%d more message(s))", remaining)))
 		}
 
+		if showCopy {
+			shown := *session
+			shown.Messages = messagesToShow
+			used, err := copySessionToClipboard(&shown, &export.MarkdownExporter{})
+			if err != nil {
+				return err
+			}
+			status := statusOutput(out)
+			_, _ = fmt.Fprintln(status)
+			_, _ = fmt.Fprintln(status, sessionMetaStyle.Render(fmt.Sprintf("📋 Copied %d message(s) to the clipboard with %s", len(messagesToShow), used)))
+		}
+
 		return nil
 	},
 }
 
-func displaySessionHeader(session *internal.Session) {
+// copySessionToClipboard places a session on the clipboard as rendered by a Markdown
+// exporter, and returns how it was copied (see internal.CopyToClipboard)
+func copySessionToClipboard(session *internal.Session, exporter *export.MarkdownExporter) (string, error) {
+	var buf bytes.Buffer
+	if err := exporter.Export(session, &buf); err != nil {
+		return "", fmt.Errorf("failed to render session %s: %w", session.ID, err)
+	}
+	used, err := internal.CopyToClipboard(buf.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to copy session %s to the clipboard: %w", session.ID, err)
+	}
+	return used, nil
+}
+
+// loadSessionRefs returns the sessions to resolve IDs and names against. A valid cache
+// index lists every session; otherwise composers are read from storage.
+func loadSessionRefs(index *internal.SessionIndex, cacheValid bool, backend internal.StorageBackend) ([]internal.SessionRef, error) {
+	if cacheValid && index != nil {
+		refs := make([]internal.SessionRef, 0, len(index.Sessions))
+		for _, entry := range index.Sessions {
+			name := entry.Name
+			if entry.GeneratedName && noGeneratedTitles {
+				name = ""
+			}
+			refs = append(refs, internal.SessionRef{ID: entry.ComposerID, Name: name})
+		}
+		return refs, nil
+	}
+
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load composers: %w", err)
+	}
+	refs := make([]internal.SessionRef, 0, len(composers))
+	for _, composer := range composers {
+		refs = append(refs, internal.SessionRef{ID: composer.ComposerID, Name: composer.Name})
+	}
+	return refs, nil
+}
+
+func displaySessionHeader(out io.Writer, session *internal.Session) {
 	if session == nil {
 		return
 	}
 	header := sessionHeaderStyle.Render(fmt.Sprintf("💬 %s", session.Metadata.Name))
-	fmt.Println(header)
+	_, _ = fmt.Fprintln(out, header)
 
 	// Create metadata line
 	var metaParts []string
 	if session.Metadata.CreatedAt != "" {
-		metaParts = append(metaParts, fmt.Sprintf("Created: %s", session.Metadata.CreatedAt))
+		created := session.Metadata.CreatedAt
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			created = internal.FormatDisplayTime(t, time.DateTime)
+		}
+		metaParts = append(metaParts, fmt.Sprintf("Created: %s", created))
 	}
 	metaParts = append(metaParts, fmt.Sprintf("Messages: %d", len(session.Messages)))
 	if session.Workspace != "" {
 		metaParts = append(metaParts, fmt.Sprintf("Workspace: %s", session.Workspace))
 	}
+	if len(session.Metadata.Tags) > 0 {
+		metaParts = append(metaParts, fmt.Sprintf("Tags: %s", strings.Join(session.Metadata.Tags, ", ")))
+	}
+	if verbosity > 0 && session.Metadata.Extraction != nil {
+		metaParts = append(metaParts, fmt.Sprintf("Extraction: %s", session.Metadata.Extraction))
+	}
 
 	if len(metaParts) > 0 {
 		meta := sessionMetaStyle.Render(strings.Join(metaParts, " • "))
-		fmt.Println(meta)
+		_, _ = fmt.Fprintln(out, meta)
 	}
 
-	fmt.Println()
+	_, _ = fmt.Fprintln(out)
 }
 
-func displayMessage(index int, msg internal.Message, total int) {
+// displayMessage prints a message. Assistant messages are rendered as markdown when
+// markdown is not nil.
+func displayMessage(out io.Writer, index int, msg internal.Message, total int, markdown *glamour.TermRenderer) {
 	var actorStyle lipgloss.Style
 	var actorLabel string
 
+	contentStyle := messageContentStyle
 	switch msg.Actor {
-	case "user":
+	case internal.ActorUser:
 		actorStyle = userMessageStyle
 		actorLabel = "👤 User"
-	case "assistant":
+	case internal.ActorAssistant:
 		actorStyle = assistantMessageStyle
 		actorLabel = "🤖 Assistant"
+	case internal.ActorTool:
+		actorStyle = toolMessageStyle
+		actorLabel = "🔧 Tool"
+		contentStyle = outputContentStyle
+	case internal.ActorTerminal:
+		actorStyle = terminalMessageStyle
+		actorLabel = "💻 Terminal"
+		contentStyle = outputContentStyle
+	case internal.ActorSystem:
+		actorStyle = systemMessageStyle
+		actorLabel = "⚙️ System"
 	default:
 		actorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
 		actorLabel = fmt.Sprintf("🔧 %s", msg.Actor)
@@ -312,25 +480,33 @@ func displayMessage(index int, msg internal.Message, total int) {
 	if msg.Timestamp != "" {
 		// Parse and format timestamp
 		if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
-			header += " " + timestampStyle.Render(t.Format("15:04:05"))
+			header += " " + timestampStyle.Render(internal.FormatDisplayTime(t, time.TimeOnly))
 		} else {
 			header += " " + timestampStyle.Render(msg.Timestamp)
 		}
 	}
 
-	fmt.Println(header)
+	_, _ = fmt.Fprintln(out, header)
 
 	// Message content
-	content := strings.TrimSpace(msg.Content)
-	if content != "" {
+	content := strings.TrimSpace(internal.InlineThinking(msg.Thinking, msg.Content))
+	if content != "" && markdown != nil && msg.Actor == internal.ActorAssistant {
+		rendered, err := markdown.Render(content)
+		if err == nil {
+			_, _ = fmt.Fprintln(out, strings.Trim(rendered, "\n"))
+		} else {
+			internal.LogDebug("Failed to render message %d as markdown: %v", index, err)
+			_, _ = fmt.Fprintln(out, contentStyle.Render(wrapText(content, 80)))
+		}
+	} else if content != "" {
 		// Wrap long lines
 		content = wrapText(content, 80)
-		fmt.Println(messageContentStyle.Render(content))
+		_, _ = fmt.Fprintln(out, contentStyle.Render(content))
 	} else {
-		fmt.Println(messageContentStyle.Foreground(lipgloss.Color("240")).Render("(empty message)"))
+		_, _ = fmt.Fprintln(out, messageContentStyle.Foreground(lipgloss.Color("240")).Render("(empty message)"))
 	}
 
-	fmt.Println()
+	_, _ = fmt.Fprintln(out)
 }
 
 func wrapText(text string, width int) string {
@@ -371,8 +547,25 @@ func wrapText(text string, width int) string {
 	return strings.Join(wrapped, "\n")
 }
 
+// newMarkdownRenderer returns a renderer for assistant messages in a glamour style, with
+// code fences highlighted and text wrapped to the width the raw output uses
+func newMarkdownRenderer(style string) (*glamour.TermRenderer, error) {
+	return glamour.NewTermRenderer(glamour.WithStandardStyle(style), glamour.WithWordWrap(80))
+}
+
+// isTerminal reports whether w writes to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 func init() {
 	rootCmd.AddCommand(showCmd)
 	showCmd.Flags().IntVarP(&limit, "limit", "n", 0, "Limit number of messages to show")
 	showCmd.Flags().StringVar(&since, "since", "", "Show messages since timestamp (ISO8601)")
+	showCmd.Flags().StringVar(&showName, "name", "", "Find the session by name (fuzzy match)")
+	showCmd.Flags().BoolVar(&showThread, "thread", false, "Show the session together with the sessions it was resumed from or into")
+	showCmd.Flags().BoolVar(&showRaw, "raw", false, "Print assistant messages as raw markdown instead of rendering them on a terminal")
+	showCmd.Flags().BoolVar(&showCopy, "copy-to-clipboard", false, "Also copy the messages shown to the clipboard as Markdown")
+	_ = showCmd.RegisterFlagCompletionFunc("name", sessionNameFlag)
 }
@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
 )
 
 func TestShowCommand(t *testing.T) {
@@ -35,6 +40,66 @@ func TestShowCommand(t *testing.T) {
 	}
 }
 
+// fakeClipboard puts clipboard programs first on PATH that write what they are given to
+// the returned file
+func fakeClipboard(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the clipboard program")
+	}
+	dir := testutil.CreateTempDir(t)
+	out := filepath.Join(dir, "clipboard.md")
+	for _, name := range []string{"pbcopy", "xclip", "wl-copy"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\ncat > \""+out+"\"\n"), 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return out
+}
+
+func TestShowCommand_CopyToClipboard(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	clipboard := fakeClipboard(t)
+	defer func() {
+		storagePaths = nil
+		showCopy = false
+		limit = 0
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	session := internal.CreateTestSessionWithMessages("clip-session", []internal.Message{
+		{Actor: "user", Content: "Why does the build fail?"},
+		{Actor: "assistant", Content: "The linker flags point at a missing library."},
+		{Actor: "user", Content: "Thanks"},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "session_clip-session.json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	storagePaths, showCopy, showName, showThread, since = nil, false, "", false, ""
+	var out bytes.Buffer
+	rootCmd.SetArgs([]string{"show", "clip-session", "--storage", dir, "--copy-to-clipboard", "--limit", "2"})
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("show --copy-to-clipboard error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Copied 2 message(s) to the clipboard") {
+		t.Errorf("show --copy-to-clipboard should report the copy, got:\n%s", out.String())
+	}
+
+	// The messages shown are copied as Markdown
+	data, err := os.ReadFile(clipboard)
+	if err != nil {
+		t.Fatalf("nothing was copied: %v", err)
+	}
+	copied := string(data)
+	if !strings.HasPrefix(copied, "# ") || !strings.Contains(copied, "The linker flags point at a missing library.") || strings.Contains(copied, "Thanks") {
+		t.Errorf("clipboard should hold the two messages shown as Markdown, got:\n%s", copied)
+	}
+}
+
 func TestShowCommand_FlagParsing(t *testing.T) {
 	// Test that flags are parsed correctly
 	tests := []struct {
@@ -49,6 +114,10 @@ func TestShowCommand_FlagParsing(t *testing.T) {
 			name: "show with since flag",
 			args: []string{"show", "test-session-id", "--since", "2024-01-01T00:00:00Z"},
 		},
+		{
+			name: "show with name flag",
+			args: []string{"show", "--name", "fix flaky tests"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,7 +186,8 @@ func TestDisplaySessionHeader(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Test that function doesn't panic
-			displaySessionHeader(tt.session)
+			var buf bytes.Buffer
+			displaySessionHeader(&buf, tt.session)
 		})
 	}
 }
@@ -182,8 +252,8 @@ func TestDisplayMessage(t *testing.T) {
 			name:  "unknown actor type",
 			index: 1,
 			msg: internal.Message{
-				Actor:   "system",
-				Content: "System message",
+				Actor:   "reviewer",
+				Content: "Reviewer message",
 			},
 			total: 1,
 		},
@@ -192,11 +262,29 @@ func TestDisplayMessage(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Test that function doesn't panic
-			displayMessage(tt.index, tt.msg, tt.total)
+			var buf bytes.Buffer
+			displayMessage(&buf, tt.index, tt.msg, tt.total, nil)
 		})
 	}
 }
 
+func TestDisplayMessage_ActorLabels(t *testing.T) {
+	labels := map[string]string{
+		"user":      "👤 User",
+		"assistant": "🤖 Assistant",
+		"tool":      "🔧 Tool",
+		"terminal":  "💻 Terminal",
+		"system":    "⚙️ System",
+	}
+	for actor, label := range labels {
+		var buf bytes.Buffer
+		displayMessage(&buf, 1, internal.Message{Actor: actor, Content: "output"}, 1, nil)
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("displayMessage() for %s = %q, want label %q", actor, buf.String(), label)
+		}
+	}
+}
+
 func TestWrapText(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -247,3 +335,61 @@ func TestWrapText(t *testing.T) {
 		})
 	}
 }
+
+func TestDisplaySessionHeader_Extraction(t *testing.T) {
+	verbosity = 0
+	defer func() { verbosity = 0 }()
+	session := &internal.Session{
+		ID:       "test-session",
+		Metadata: internal.Metadata{Name: "Test Session", Extraction: &internal.ExtractionStats{Primary: 3, Placeholder: 1}},
+	}
+
+	var buf bytes.Buffer
+	displaySessionHeader(&buf, session)
+	if strings.Contains(buf.String(), "Extraction") {
+		t.Errorf("header without --verbose = %q, want no extraction counts", buf.String())
+	}
+
+	verbosity = 1
+	buf.Reset()
+	displaySessionHeader(&buf, session)
+	if !strings.Contains(buf.String(), "Extraction: 3 primary, 0 rich text, 0 fallback, 1 placeholder") {
+		t.Errorf("header with --verbose = %q, want the extraction counts", buf.String())
+	}
+}
+
+func TestDisplayMessage_Markdown(t *testing.T) {
+	markdown, err := newMarkdownRenderer("dark")
+	if err != nil {
+		t.Fatalf("newMarkdownRenderer() error = %v", err)
+	}
+	content := "## Fix\n\nUse **sync.Mutex**:\n\n```go\nvar mu sync.Mutex\n```"
+
+	var buf bytes.Buffer
+	displayMessage(&buf, 1, internal.Message{Actor: internal.ActorAssistant, Content: content}, 1, markdown)
+	got := buf.String()
+	if strings.Contains(got, "**") || strings.Contains(got, "```") {
+		t.Errorf("rendered assistant message = %q, want markdown syntax rendered", got)
+	}
+	if !strings.Contains(got, "Mutex") {
+		t.Errorf("rendered assistant message = %q, want the code block", got)
+	}
+
+	// User messages and raw output keep the markdown as typed
+	buf.Reset()
+	displayMessage(&buf, 1, internal.Message{Actor: internal.ActorUser, Content: "Use **bold**"}, 1, markdown)
+	if !strings.Contains(buf.String(), "**bold**") {
+		t.Errorf("user message = %q, want raw markdown", buf.String())
+	}
+	buf.Reset()
+	displayMessage(&buf, 1, internal.Message{Actor: internal.ActorAssistant, Content: content}, 1, nil)
+	if !strings.Contains(buf.String(), "```go") {
+		t.Errorf("raw assistant message = %q, want raw markdown", buf.String())
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	if isTerminal(&bytes.Buffer{}) {
+		t.Error("isTerminal(buffer) = true, want false")
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	siteOut       string
+	siteTitle     string
+	siteWorkspace string
+	siteFilter    string
+)
+
+// siteCmd represents the site command
+var siteCmd = &cobra.Command{
+	Use:   "site",
+	Short: "Generate a browsable static website of the sessions",
+	Long: `Generate a static website of the sessions into --out:
+  • index.html: the sessions grouped by workspace, newest first, with a search box
+    over their names, workspaces and messages
+  • sessions/: a page per session with its metadata and messages, thinking
+    collapsed and code blocks preformatted
+  • style.css, search.js and search-index.js, loaded by the pages
+
+The site needs no server, build step or network access: open index.html from disk
+or publish the directory as is. Search runs in the browser over an index of the
+first 20KB of each session's text. Files of an earlier site in --out are replaced.
+
+Examples:
+  cursor-session site                                  # Write ./site
+  cursor-session site --out ~/archive --title "Cursor archive"
+  cursor-session site --workspace ~/code/api --filter 'messages>5'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if siteOut == "" {
+			return usageErrorf("--out must not be empty")
+		}
+		sessionFilter, err := parseFilterFlag(siteFilter)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := loadStorageSessions()
+		if err != nil {
+			return err
+		}
+		if siteWorkspace != "" {
+			filtered := make([]*internal.Session, 0, len(sessions))
+			for _, session := range sessions {
+				if session.Workspace == siteWorkspace {
+					filtered = append(filtered, session)
+				}
+			}
+			sessions = filtered
+		}
+		if sessionFilter != nil {
+			sessions = sessionFilter.FilterSessions(sessions)
+		}
+		internal.SortSessions(sessions)
+
+		dest, err := export.NewDirDestination(siteOut)
+		if err != nil {
+			return err
+		}
+		count, err := export.WriteSite(dest, sessions, siteTitle)
+		if closeErr := dest.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(out, successStyle.Render(fmt.Sprintf("🌐 Wrote a site of %d session(s) to %s", count, siteOut)))
+		_, _ = fmt.Fprintf(out, "   Open %s/index.html in a browser\n", siteOut)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(siteCmd)
+	siteCmd.Flags().StringVar(&siteOut, "out", "site", "Directory to write the site to")
+	siteCmd.Flags().StringVar(&siteTitle, "title", "Cursor sessions", "Title of the site")
+	siteCmd.Flags().StringVar(&siteWorkspace, "workspace", "", "Only include the sessions of this workspace")
+	siteCmd.Flags().StringVar(&siteFilter, "filter", "", "Only include sessions matching a filter expression, e.g. 'messages>10 && workspace~\"api\"'")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestSiteCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		siteOut, siteTitle, siteWorkspace, siteFilter = "site", "Cursor sessions", "", ""
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	for i, workspace := range []string{"/code/api", "/code/web"} {
+		session := internal.CreateTestSessionWithMessages("site-session-"+string(rune('a'+i)), []internal.Message{
+			{Actor: "user", Content: "Question about " + workspace},
+		})
+		session.Workspace = workspace
+		path := filepath.Join(dir, "session_"+session.ID+".json")
+		if err := os.WriteFile(path, testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	out := filepath.Join(testutil.CreateTempDir(t), "site")
+	var output bytes.Buffer
+	rootCmd.SetArgs([]string{"site", "--storage", dir, "--out", out, "--workspace", "/code/api", "--title", "Archive"})
+	rootCmd.SetOut(&output)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("site error = %v", err)
+	}
+	if !strings.Contains(output.String(), "1 session(s)") {
+		t.Errorf("site output = %q, want the number of sessions", output.String())
+	}
+
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatalf("Failed to read index.html: %v", err)
+	}
+	if !strings.Contains(string(index), "<title>Archive</title>") || strings.Contains(string(index), "/code/web") {
+		t.Errorf("index.html should list only the sessions of /code/api:\n%s", index)
+	}
+	if _, err := os.Stat(filepath.Join(out, "sessions", "session_site-session-a.html")); err != nil {
+		t.Errorf("session page not written: %v", err)
+	}
+}
+
+func TestSiteCommand_InvalidFilter(t *testing.T) {
+	defer func() { siteFilter = "" }()
+	rootCmd.SetArgs([]string{"site", "--filter", "messages>>"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	if code := exitCode(rootCmd.Execute()); code != exitUsage {
+		t.Errorf("site --filter exit code = %d, want %d", code, exitUsage)
+	}
+}
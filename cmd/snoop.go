@@ -3,7 +3,9 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,9 +19,15 @@ import (
 )
 
 var (
-	snoopHello bool
+	snoopHello        bool
+	snoopWatch        bool
+	snoopWatchTimeout time.Duration
+	snoopAgent        internal.AgentRunOptions
 )
 
+// snoopWatchInterval is how often --watch checks the agent storage directories
+var snoopWatchInterval = 500 * time.Millisecond
+
 var (
 	snoopSuccessStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("42")).
@@ -58,128 +66,171 @@ This command will:
   • Optionally seed the database with --hello flag
 
 The --hello flag will invoke cursor-agent with a simple prompt to create a session,
-which can help seed the database if it doesn't exist yet.`,
+which can help seed the database if it doesn't exist yet. Each run of cursor-agent is
+stopped after --agent-timeout, and a failed run is repeated up to --agent-retries
+times. Snoop then polls for the new store.db for up to --wait-for-db and detects the
+storage where it appeared.
+
+The --watch flag skips the report and waits instead, checking the cursor-agent storage
+directories (or the --storage directories) until a store.db appears. It prints the path
+of the store.db and exits 0, or fails when --watch-timeout passes first. CI jobs can
+start it before running cursor-agent to know when there is a session to export.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if snoopWatch {
+			return watchForStoreDB(cmd, out)
+		}
+		// A quiet run prints only the storage it found, after the report is left out
+		result := out
+		out = statusOutput(out)
 		// If --hello flag is set, trigger cursor-agent first
+		var seededDB string
 		if snoopHello {
-			fmt.Println(snoopInfoStyle.Render("🔍 Invoking cursor-agent to seed database..."))
-			agentPath, err := triggerCursorAgentHello()
+			if err := snoopAgent.Validate(); err != nil {
+				return &usageError{err}
+			}
+			_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("🔍 Invoking cursor-agent to seed database..."))
+			agentPath, storeDB, err := seedAgentStorage(snoopAgent)
+			if agentPath != "" {
+				_, _ = fmt.Fprintf(out, "%s ℹ️  Found cursor-agent at: %s\n", snoopInfoStyle.Render(""), snoopPathStyle.Render(agentPath))
+			}
 			if err != nil {
-				// Show where cursor-agent was found (if found) even on error
-				if agentPath != "" {
-					fmt.Printf("%s ℹ️  Found cursor-agent at: %s\n", snoopInfoStyle.Render(""), snoopPathStyle.Render(agentPath))
-				}
-				fmt.Printf("%s ⚠️  Could not invoke cursor-agent: %v\n", snoopWarningStyle.Render(""), err)
-				fmt.Println(snoopInfoStyle.Render("   Continuing with path detection anyway..."))
+				_, _ = fmt.Fprintf(out, "%s ⚠️  Could not invoke cursor-agent: %v\n", snoopWarningStyle.Render(""), err)
+				_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("   Continuing with path detection anyway..."))
 			} else {
-				if agentPath != "" {
-					fmt.Printf("%s ✅ Found cursor-agent at: %s\n", snoopSuccessStyle.Render(""), snoopPathStyle.Render(agentPath))
-				}
-				fmt.Println(snoopSuccessStyle.Render("✅ Successfully invoked cursor-agent"))
-				// Give it time to create the database - cursor-agent may need a moment
-				fmt.Println(snoopInfoStyle.Render("   Waiting for database to be created..."))
-				time.Sleep(5 * time.Second)
-
-				// Re-check paths after waiting to see if database was created
-				fmt.Println(snoopInfoStyle.Render("   Re-checking paths after database creation..."))
-
-				// Force a fresh path detection after cursor-agent runs
-				// This ensures we pick up any newly created directories
-				time.Sleep(2 * time.Second)
+				_, _ = fmt.Fprintln(out, snoopSuccessStyle.Render("✅ Successfully invoked cursor-agent"))
+				_, _ = fmt.Fprintf(out, "%s ✅ Database created: %s\n", snoopSuccessStyle.Render(""), snoopPathStyle.Render(storeDB))
+				seededDB = storeDB
 			}
-			fmt.Println()
+			_, _ = fmt.Fprintln(out)
 		}
 
 		// Get storage paths (with optional custom storage location)
-		fmt.Println(snoopSectionStyle.Render("📂 Storage Path Detection"))
-		paths, err := internal.GetStoragePaths(storagePath)
+		_, _ = fmt.Fprintln(out, snoopSectionStyle.Render("📂 Storage Path Detection"))
+		paths, err := internal.GetStoragePaths(primaryStoragePath("snoop"))
+		// The database cursor-agent just created is read where it was found
+		if err == nil && seededDB != "" && primaryStoragePath("snoop") == "" {
+			paths = internal.WithAgentStoreDB(paths, seededDB)
+		}
 		if err != nil {
-			fmt.Printf("%s ❌ Failed to get storage paths: %v\n", snoopErrorStyle.Render(""), err)
+			_, _ = fmt.Fprintf(out, "%s ❌ Failed to get storage paths: %v\n", snoopErrorStyle.Render(""), err)
 		} else {
-			// Copy database files to temp location if --copy flag is set
+			// Copy database files to temp location if --read-strategy (or --copy) asks for it
 			var cleanup func() error
-			if copyDB {
+			if copyStorage(paths) {
 				var copyErr error
 				paths, cleanup, copyErr = internal.CopyStoragePaths(paths)
 				if copyErr != nil {
-					fmt.Printf("%s ❌ Failed to copy database files: %v\n", snoopErrorStyle.Render(""), copyErr)
+					_, _ = fmt.Fprintf(out, "%s ❌ Failed to copy database files: %v\n", snoopErrorStyle.Render(""), copyErr)
 				} else {
-					fmt.Printf("%s ✅ Database files copied to temporary location\n", snoopSuccessStyle.Render(""))
+					_, _ = fmt.Fprintf(out, "%s ✅ Database files copied to temporary location\n", snoopSuccessStyle.Render(""))
 					// Schedule cleanup when command completes
 					defer func() {
 						if cleanup != nil {
 							if err := cleanup(); err != nil {
-								fmt.Printf("⚠️  Failed to cleanup temporary files: %v\n", err)
+								_, _ = fmt.Fprintf(out, "⚠️  Failed to cleanup temporary files: %v\n", err)
 							}
 						}
 					}()
 				}
 			}
-			displayPathInfo(paths)
-
-			// If --hello was used and we still don't see agent storage, check if directory was just created
-			if snoopHello && !paths.HasAgentStorage() && paths.AgentStoragePath != "" {
-				// Give it one more moment and check again
-				time.Sleep(1 * time.Second)
-				if info, err := os.Stat(paths.AgentStoragePath); err == nil && info.IsDir() {
-					fmt.Printf("%s ✅ Agent storage directory now exists (created by cursor-agent)\n", snoopSuccessStyle.Render("  "))
-					// Re-scan for databases
-					if storeDBs, err := paths.FindAgentStoreDBs(); err == nil && len(storeDBs) > 0 {
-						fmt.Printf("%s ✅ Found %d store.db file(s) after cursor-agent run\n", snoopSuccessStyle.Render("  "), len(storeDBs))
-					}
-				}
-			}
+			displayPathInfo(out, paths)
 		}
-		fmt.Println()
+		_, _ = fmt.Fprintln(out)
+
+		// Report every known Cursor build location
+		_, _ = fmt.Fprintln(out, snoopSectionStyle.Render("🧭 Cursor Installations"))
+		displayInstallCandidates(out, paths)
+		_, _ = fmt.Fprintln(out)
 
 		// Try alternative paths
-		fmt.Println(snoopSectionStyle.Render("🔎 Alternative Path Search"))
-		checkAlternativePaths()
-		fmt.Println()
+		_, _ = fmt.Fprintln(out, snoopSectionStyle.Render("🔎 Alternative Path Search"))
+		checkAlternativePaths(out)
+		_, _ = fmt.Fprintln(out)
 
 		// Deep search for database files
-		fmt.Println(snoopSectionStyle.Render("🔍 Deep Search for Database Files"))
-		deepSearchForDatabases()
-		fmt.Println()
+		_, _ = fmt.Fprintln(out, snoopSectionStyle.Render("🔍 Deep Search for Database Files"))
+		deepSearchForDatabases(out)
+		_, _ = fmt.Fprintln(out)
 
 		// Summary
-		fmt.Println(snoopSectionStyle.Render("📊 Summary"))
-		displaySummary(paths)
+		_, _ = fmt.Fprintln(out, snoopSectionStyle.Render("📊 Summary"))
+		displaySummary(out, paths)
+		if quiet {
+			printFoundStorage(result, paths)
+		}
 
 		return nil
 	},
 }
 
-func displayPathInfo(paths internal.StoragePaths) {
-	fmt.Println(snoopInfoStyle.Render("Base Path:"))
-	fmt.Printf("  %s\n", snoopPathStyle.Render(paths.BasePath))
-	checkPath(paths.BasePath, "  ")
+// printFoundStorage prints the storage directories that hold sessions, one per line
+func printFoundStorage(out io.Writer, paths internal.StoragePaths) {
+	if paths.GlobalStorageExists() {
+		_, _ = fmt.Fprintln(out, paths.GlobalStorage)
+	}
+	if paths.HasAgentStorage() {
+		if storeDBs, _ := paths.FindAgentStoreDBs(); len(storeDBs) > 0 {
+			_, _ = fmt.Fprintln(out, paths.AgentStoragePath)
+		}
+	}
+}
+
+// watchForStoreDB waits for a store.db to appear in the agent storage directories and
+// prints its path
+func watchForStoreDB(cmd *cobra.Command, out io.Writer) error {
+	if snoopWatchTimeout <= 0 {
+		return usageErrorf("invalid --watch-timeout %s (expected a positive duration)", snoopWatchTimeout)
+	}
+	dirs := storagePaths
+	if len(dirs) == 0 {
+		dirs = internal.AgentStorageCandidates()
+	}
+	internal.LogInfo("Watching %s for a store.db (timeout %s)", strings.Join(dirs, ", "), snoopWatchTimeout)
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), snoopWatchTimeout)
+	defer cancel()
+	path, err := internal.WaitForAgentStoreDB(ctx, dirs, snoopWatchInterval)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: no store.db appeared in %s within %s", internal.ErrNoStorage, strings.Join(dirs, ", "), snoopWatchTimeout)
+		}
+		return err
+	}
+	_, _ = fmt.Fprintln(out, path)
+	return nil
+}
+
+func displayPathInfo(out io.Writer, paths internal.StoragePaths) {
+	_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("Base Path:"))
+	_, _ = fmt.Fprintf(out, "  %s\n", snoopPathStyle.Render(paths.BasePath))
+	checkPath(out, paths.BasePath, "  ")
 
-	fmt.Println()
-	fmt.Println(snoopInfoStyle.Render("Global Storage:"))
-	fmt.Printf("  %s\n", snoopPathStyle.Render(paths.GlobalStorage))
-	checkPath(paths.GlobalStorage, "  ")
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("Global Storage:"))
+	_, _ = fmt.Fprintf(out, "  %s\n", snoopPathStyle.Render(paths.GlobalStorage))
+	checkPath(out, paths.GlobalStorage, "  ")
 
 	// Check for state.vscdb in globalStorage
 	dbPath := paths.GetGlobalStorageDBPath()
-	fmt.Printf("  Database: %s\n", snoopPathStyle.Render(dbPath))
+	_, _ = fmt.Fprintf(out, "  Database: %s\n", snoopPathStyle.Render(dbPath))
 	if paths.GlobalStorageExists() {
-		fmt.Printf("  %s\n", snoopSuccessStyle.Render("✅ Database file exists"))
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopSuccessStyle.Render("✅ Database file exists"))
 		// Try to open it
 		if db, err := internal.OpenDatabase(dbPath); err == nil {
 			_ = db.Close()
-			fmt.Printf("  %s\n", snoopSuccessStyle.Render("✅ Database is accessible"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopSuccessStyle.Render("✅ Database is accessible"))
 		} else {
-			fmt.Printf("%s ⚠️  Database exists but cannot be opened: %v\n", snoopWarningStyle.Render("  "), err)
+			_, _ = fmt.Fprintf(out, "%s ⚠️  Database exists but cannot be opened: %v\n", snoopWarningStyle.Render("  "), err)
 		}
 	} else {
-		fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  Database file does not exist"))
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  Database file does not exist"))
 	}
 
-	fmt.Println()
-	fmt.Println(snoopInfoStyle.Render("Workspace Storage:"))
-	fmt.Printf("  %s\n", snoopPathStyle.Render(paths.WorkspaceStorage))
-	checkPath(paths.WorkspaceStorage, "  ")
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("Workspace Storage:"))
+	_, _ = fmt.Fprintf(out, "  %s\n", snoopPathStyle.Render(paths.WorkspaceStorage))
+	checkPath(out, paths.WorkspaceStorage, "  ")
 
 	// Check for state.vscdb files in workspaceStorage subdirectories
 	if info, err := os.Stat(paths.WorkspaceStorage); err == nil && info.IsDir() {
@@ -194,77 +245,103 @@ func displayPathInfo(paths internal.StoragePaths) {
 			return nil
 		})
 		if err != nil {
-			fmt.Printf("%s ⚠️  Error scanning workspace storage: %v\n", snoopWarningStyle.Render("  "), err)
+			_, _ = fmt.Fprintf(out, "%s ⚠️  Error scanning workspace storage: %v\n", snoopWarningStyle.Render("  "), err)
 		} else if dbCount > 0 {
-			fmt.Printf("%s ✅ Found %d state.vscdb file(s) in subdirectories\n", snoopSuccessStyle.Render("  "), dbCount)
+			_, _ = fmt.Fprintf(out, "%s ✅ Found %d state.vscdb file(s) in subdirectories\n", snoopSuccessStyle.Render("  "), dbCount)
 		} else {
-			fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  No state.vscdb files found in subdirectories"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  No state.vscdb files found in subdirectories"))
 		}
 	}
 
-	fmt.Println()
-	fmt.Println(snoopInfoStyle.Render("Agent Storage:"))
-	home, _ := os.UserHomeDir()
-	agentStoragePaths := []string{
-		filepath.Join(home, ".config/cursor/chats"), // Newer location (CI/GH workflows)
-		filepath.Join(home, ".cursor/chats"),        // Older location (local installs)
-	}
-
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("Agent Storage:"))
 	foundAgentStorage := false
-	for _, agentPath := range agentStoragePaths {
-		fmt.Printf("  %s\n", snoopPathStyle.Render(agentPath))
+	for _, agentPath := range internal.AgentStorageCandidates() {
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopPathStyle.Render(agentPath))
 		if info, err := os.Stat(agentPath); err == nil && info.IsDir() {
 			foundAgentStorage = true
-			fmt.Printf("  %s\n", snoopSuccessStyle.Render("✅ Directory exists"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopSuccessStyle.Render("✅ Directory exists"))
 			// Create a temporary StoragePaths to use FindAgentStoreDBs
 			tempPaths := internal.StoragePaths{AgentStoragePath: agentPath}
 			storeDBs, err := tempPaths.FindAgentStoreDBs()
 			if err != nil {
-				fmt.Printf("  %s ❌ Error scanning: %v\n", snoopErrorStyle.Render(""), err)
+				_, _ = fmt.Fprintf(out, "  %s ❌ Error scanning: %v\n", snoopErrorStyle.Render(""), err)
 			} else if len(storeDBs) > 0 {
-				fmt.Printf("  %s ✅ Found %d store.db file(s)\n", snoopSuccessStyle.Render(""), len(storeDBs))
+				_, _ = fmt.Fprintf(out, "  %s ✅ Found %d store.db file(s)\n", snoopSuccessStyle.Render(""), len(storeDBs))
 				for i, db := range storeDBs {
 					if i < 3 {
-						fmt.Printf("    • %s\n", snoopPathStyle.Render(db))
+						_, _ = fmt.Fprintf(out, "    • %s\n", snoopPathStyle.Render(db))
 					}
 				}
 				if len(storeDBs) > 3 {
-					fmt.Printf("    ... and %d more\n", len(storeDBs)-3)
+					_, _ = fmt.Fprintf(out, "    ... and %d more\n", len(storeDBs)-3)
 				}
 			} else {
-				fmt.Printf("  %s ⚠️  Directory exists but no store.db files found\n", snoopWarningStyle.Render(""))
+				_, _ = fmt.Fprintf(out, "  %s ⚠️  Directory exists but no store.db files found\n", snoopWarningStyle.Render(""))
 			}
 			break // Found the active location, no need to check others
 		} else {
-			fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  Does not exist"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  Does not exist"))
+		}
+	}
+
+	if !foundAgentStorage {
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  No agent storage directories found"))
+	}
+}
+
+// displayInstallCandidates reports the status of each Cursor build location in priority order
+// and marks the one in use
+func displayInstallCandidates(out io.Writer, active internal.StoragePaths) {
+	candidates, err := internal.InstallCandidates()
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "%s ❌ %v\n", snoopErrorStyle.Render(""), err)
+		return
+	}
+
+	found := 0
+	for i, candidate := range candidates {
+		paths := internal.StoragePathsForBase(candidate.BasePath)
+		label := fmt.Sprintf("%d. %s", i+1, candidate.Name)
+		if candidate.BasePath == active.BasePath {
+			label += " (in use)"
+		}
+		_, _ = fmt.Fprintf(out, "%s: %s\n", snoopInfoStyle.Render(label), snoopPathStyle.Render(candidate.BasePath))
+
+		if paths.GlobalStorageExists() {
+			found++
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopSuccessStyle.Render("✅ Database found: "+paths.GetGlobalStorageDBPath()))
+		} else if _, err := os.Stat(candidate.BasePath); err == nil {
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  Directory exists but has no globalStorage database"))
+		} else {
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopPathStyle.Render("Not installed"))
 		}
 	}
 
-	if !foundAgentStorage && runtime.GOOS == "linux" {
-		fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  No agent storage directories found"))
-	} else if runtime.GOOS != "linux" {
-		fmt.Printf("  %s\n", snoopInfoStyle.Render("ℹ️  Not available on this OS (Linux only)"))
+	if found > 1 {
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render(fmt.Sprintf("ℹ️  %d builds have data; the first one is used by default. Use --storage to pick another, or --all-builds to combine them.", found)))
 	}
 }
 
-func checkPath(path string, indent string) {
+func checkPath(out io.Writer, path string, indent string) {
 	if info, err := os.Stat(path); err == nil {
 		if info.IsDir() {
-			fmt.Printf("%s%s\n", indent, snoopSuccessStyle.Render("✅ Directory exists"))
+			_, _ = fmt.Fprintf(out, "%s%s\n", indent, snoopSuccessStyle.Render("✅ Directory exists"))
 		} else {
-			fmt.Printf("%s%s\n", indent, snoopSuccessStyle.Render("✅ File exists"))
+			_, _ = fmt.Fprintf(out, "%s%s\n", indent, snoopSuccessStyle.Render("✅ File exists"))
 		}
 	} else if os.IsNotExist(err) {
-		fmt.Printf("%s%s\n", indent, snoopWarningStyle.Render("⚠️  Does not exist"))
+		_, _ = fmt.Fprintf(out, "%s%s\n", indent, snoopWarningStyle.Render("⚠️  Does not exist"))
 	} else {
-		fmt.Printf("%s%s ❌ Error checking: %v\n", indent, snoopErrorStyle.Render(""), err)
+		_, _ = fmt.Fprintf(out, "%s%s ❌ Error checking: %v\n", indent, snoopErrorStyle.Render(""), err)
 	}
 }
 
-func checkAlternativePaths() {
+func checkAlternativePaths(out io.Writer) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println(snoopWarningStyle.Render("⚠️  Could not get home directory"))
+		_, _ = fmt.Fprintln(out, snoopWarningStyle.Render("⚠️  Could not get home directory"))
 		return
 	}
 
@@ -284,49 +361,43 @@ func checkAlternativePaths() {
 		if alt.path == "" {
 			continue
 		}
-		fmt.Printf("%s: %s\n", snoopInfoStyle.Render(alt.name), snoopPathStyle.Render(alt.path))
+		_, _ = fmt.Fprintf(out, "%s: %s\n", snoopInfoStyle.Render(alt.name), snoopPathStyle.Render(alt.path))
 		if _, err := os.Stat(alt.path); err == nil {
-			fmt.Printf("  %s\n", snoopSuccessStyle.Render("✅ Found!"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopSuccessStyle.Render("✅ Found!"))
 			foundAny = true
 
 			// Check for database files
 			globalStoragePath := filepath.Join(alt.path, "globalStorage")
 			dbPath := filepath.Join(globalStoragePath, "state.vscdb")
 			if _, err := os.Stat(dbPath); err == nil {
-				fmt.Printf("%s ✅ Database found: %s\n", snoopSuccessStyle.Render("  "), dbPath)
+				_, _ = fmt.Fprintf(out, "%s ✅ Database found: %s\n", snoopSuccessStyle.Render("  "), dbPath)
 			}
 		} else {
-			fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  Not found"))
+			_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  Not found"))
 		}
 	}
 
 	if !foundAny {
-		fmt.Println(snoopInfoStyle.Render("ℹ️  No alternative paths found"))
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("ℹ️  No alternative paths found"))
 	}
 }
 
-func deepSearchForDatabases() {
+func deepSearchForDatabases(out io.Writer) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println(snoopWarningStyle.Render("⚠️  Could not get home directory"))
+		_, _ = fmt.Fprintln(out, snoopWarningStyle.Render("⚠️  Could not get home directory"))
 		return
 	}
 
-	fmt.Println(snoopInfoStyle.Render("Searching for database files in likely locations..."))
+	_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("Searching for database files in likely locations..."))
 
 	var foundDBs []struct {
 		path string
 		typ  string
 	}
 
-	// First, specifically check cursor-agent storage directories (check both locations)
-	// Priority: .config/cursor/chats (newer location used in CI/GH workflows) then .cursor/chats
-	cursorChatsDirs := []string{
-		filepath.Join(home, ".config", "cursor", "chats"), // Newer location (CI/GH workflows)
-		filepath.Join(home, ".cursor", "chats"),           // Older location (local installs)
-	}
-
-	for _, cursorChatsDir := range cursorChatsDirs {
+	// First, specifically check cursor-agent storage directories
+	for _, cursorChatsDir := range internal.AgentStorageCandidates() {
 		if info, err := os.Stat(cursorChatsDir); err == nil && info.IsDir() {
 			// Walk the chats directory looking for store.db files
 			err := filepath.Walk(cursorChatsDir, func(path string, info os.FileInfo, err error) error {
@@ -343,14 +414,14 @@ func deepSearchForDatabases() {
 			})
 			if err == nil && len(foundDBs) > 0 {
 				// Found databases, no need to search further
-				fmt.Printf("%s ✅ Found %d database file(s) in %s:\n", snoopSuccessStyle.Render("  "), len(foundDBs), cursorChatsDir)
+				_, _ = fmt.Fprintf(out, "%s ✅ Found %d database file(s) in %s:\n", snoopSuccessStyle.Render("  "), len(foundDBs), cursorChatsDir)
 				for i, db := range foundDBs {
 					if i < 10 {
-						fmt.Printf("    • %s\n", snoopPathStyle.Render(db.path))
+						_, _ = fmt.Fprintf(out, "    • %s\n", snoopPathStyle.Render(db.path))
 					}
 				}
 				if len(foundDBs) > 10 {
-					fmt.Printf("    ... and %d more\n", len(foundDBs)-10)
+					_, _ = fmt.Fprintf(out, "    ... and %d more\n", len(foundDBs)-10)
 				}
 				return
 			}
@@ -414,22 +485,22 @@ func deepSearchForDatabases() {
 	}
 
 	if len(foundDBs) > 0 {
-		fmt.Printf("%s ✅ Found %d database file(s):\n", snoopSuccessStyle.Render("  "), len(foundDBs))
+		_, _ = fmt.Fprintf(out, "%s ✅ Found %d database file(s):\n", snoopSuccessStyle.Render("  "), len(foundDBs))
 		for i, db := range foundDBs {
 			if i < 10 { // Show first 10
-				fmt.Printf("    • %s (%s)\n", snoopPathStyle.Render(db.path), db.typ)
+				_, _ = fmt.Fprintf(out, "    • %s (%s)\n", snoopPathStyle.Render(db.path), db.typ)
 			}
 		}
 		if len(foundDBs) > 10 {
-			fmt.Printf("    ... and %d more\n", len(foundDBs)-10)
+			_, _ = fmt.Fprintf(out, "    ... and %d more\n", len(foundDBs)-10)
 		}
 	} else {
-		fmt.Printf("  %s\n", snoopWarningStyle.Render("⚠️  No database files found in likely locations"))
-		fmt.Printf("  %s\n", snoopInfoStyle.Render("  Searched: .config, .local, .cursor, Library/Application Support, XDG directories"))
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopWarningStyle.Render("⚠️  No database files found in likely locations"))
+		_, _ = fmt.Fprintf(out, "  %s\n", snoopInfoStyle.Render("  Searched: .config, .local, .cursor, Library/Application Support, XDG directories"))
 	}
 }
 
-func displaySummary(paths internal.StoragePaths) {
+func displaySummary(out io.Writer, paths internal.StoragePaths) {
 	var found []string
 	var missing []string
 
@@ -453,35 +524,49 @@ func displaySummary(paths internal.StoragePaths) {
 	}
 
 	if len(found) > 0 {
-		fmt.Println(snoopSuccessStyle.Render("✅ Found storage:"))
+		_, _ = fmt.Fprintln(out, snoopSuccessStyle.Render("✅ Found storage:"))
 		for _, item := range found {
-			fmt.Printf("  • %s\n", item)
+			_, _ = fmt.Fprintf(out, "  • %s\n", item)
 		}
 	}
 
 	if len(missing) > 0 {
-		fmt.Println()
-		fmt.Println(snoopWarningStyle.Render("⚠️  Missing storage:"))
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, snoopWarningStyle.Render("⚠️  Missing storage:"))
 		for _, item := range missing {
-			fmt.Printf("  • %s\n", item)
+			_, _ = fmt.Fprintf(out, "  • %s\n", item)
 		}
 	}
 
 	if len(found) == 0 && len(missing) > 0 {
-		fmt.Println()
-		fmt.Println(snoopInfoStyle.Render("💡 Tips:"))
-		fmt.Println(snoopInfoStyle.Render("  • Use --hello flag to seed the database with cursor-agent"))
-		fmt.Println(snoopInfoStyle.Render("  • Make sure cursor-agent is authenticated: run 'cursor-agent login'"))
-		fmt.Println(snoopInfoStyle.Render("  • In CI environments, Cursor databases won't be found (this is expected)"))
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("💡 Tips:"))
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("  • Use --hello flag to seed the database with cursor-agent"))
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("  • Make sure cursor-agent is authenticated: run 'cursor-agent login'"))
+		_, _ = fmt.Fprintln(out, snoopInfoStyle.Render("  • In CI environments, Cursor databases won't be found (this is expected)"))
 	}
 }
 
-// triggerCursorAgentHello invokes cursor-agent with a simple "hello" prompt to seed the database
-// Returns the path where cursor-agent was found, or an error
-func triggerCursorAgentHello() (string, error) {
+// seedAgentStorage invokes cursor-agent with a simple "hello" prompt to seed the
+// database, as often as opts allow, and waits for its store.db. It returns where
+// cursor-agent was found and the store.db, or an error.
+func seedAgentStorage(opts internal.AgentRunOptions) (string, string, error) {
+	cursorAgentPath, foundLocation, err := findCursorAgentHello()
+	if err != nil {
+		return foundLocation, "", err
+	}
+	storeDB, err := internal.SeedAgentStorage(context.Background(), opts, internal.AgentStorageCandidates(), func(ctx context.Context) error {
+		return runCursorAgentHello(ctx, cursorAgentPath)
+	})
+	return foundLocation, storeDB, err
+}
+
+// findCursorAgentHello finds cursor-agent and checks that it is logged in. It returns the
+// command to run and where it was found.
+func findCursorAgentHello() (string, string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 
 	// Find cursor-agent in common locations (check installed locations first, then PATH)
@@ -519,7 +604,7 @@ func triggerCursorAgentHello() (string, error) {
 	}
 
 	if cursorAgentPath == "" {
-		return "", fmt.Errorf("cursor-agent not found in PATH or common locations")
+		return "", "", fmt.Errorf("cursor-agent not found in PATH or common locations")
 	}
 
 	// Check if CURSOR_API_KEY is set (for non-interactive authentication)
@@ -536,20 +621,19 @@ func triggerCursorAgentHello() (string, error) {
 		if err := checkCmd.Run(); err != nil {
 			// If status check fails, it might mean not authenticated
 			stderrStr := checkStderr.String()
-			if strings.Contains(stderrStr, "Authentication required") ||
-				strings.Contains(stderrStr, "login") ||
-				strings.Contains(stderrStr, "not authenticated") {
-				return foundLocation, fmt.Errorf("cursor-agent found at %s but requires authentication (run 'cursor-agent login' or set CURSOR_API_KEY environment variable)", foundLocation)
+			if internal.IsAgentAuthenticationError(stderrStr) {
+				return cursorAgentPath, foundLocation, fmt.Errorf("%w (found at %s)", internal.ErrAgentAuthentication, foundLocation)
 			}
 			// Other errors - continue anyway, might still work
 		}
 	}
 
-	// Run cursor-agent with a simple prompt to trigger session creation
-	// Use a context with timeout to avoid hanging
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	return cursorAgentPath, foundLocation, nil
+}
 
+// runCursorAgentHello runs cursor-agent with a simple prompt to trigger session creation,
+// until it answers or ctx ends
+func runCursorAgentHello(ctx context.Context, cursorAgentPath string) error {
 	cmd := exec.CommandContext(ctx, cursorAgentPath, "-p", "hello", "--model", "auto", "--print")
 	cmd.Env = os.Environ()
 
@@ -558,40 +642,26 @@ func triggerCursorAgentHello() (string, error) {
 	cmd.Stderr = &stderr
 	cmd.Stdout = os.Stderr // Redirect stdout to stderr to avoid cluttering
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return foundLocation, fmt.Errorf("failed to start cursor-agent: %w", err)
-	}
-
-	// Wait for completion with timeout - this ensures the process finishes
-	// and gives it time to create the database
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			// Check if it's an authentication error
-			stderrStr := stderr.String()
-			if strings.Contains(stderrStr, "Authentication required") ||
-				strings.Contains(stderrStr, "login") ||
-				strings.Contains(stderrStr, "CURSOR_API_KEY") {
-				return foundLocation, fmt.Errorf("cursor-agent requires authentication: %w (run 'cursor-agent login' or set CURSOR_API_KEY)", err)
-			}
-			// Other errors might still allow database creation, so don't fail completely
-			return foundLocation, nil
+	if err := cmd.Run(); err != nil {
+		if internal.IsAgentAuthenticationError(stderr.String()) {
+			return fmt.Errorf("%w: %v", internal.ErrAgentAuthentication, err)
 		}
-	case <-ctx.Done():
-		// Timeout reached, but that's okay - the process might still be creating the database
-		_ = cmd.Process.Kill()
+		return fmt.Errorf("cursor-agent failed: %w", err)
 	}
+	return nil
+}
 
-	return foundLocation, nil
+// addAgentRunFlags adds the flags controlling how cursor-agent is run to seed its storage
+func addAgentRunFlags(cmd *cobra.Command, opts *internal.AgentRunOptions) {
+	cmd.Flags().DurationVar(&opts.Timeout, "agent-timeout", internal.DefaultAgentTimeout, "How long one cursor-agent run may take before it is stopped")
+	cmd.Flags().IntVar(&opts.Retries, "agent-retries", internal.DefaultAgentRetries, "How many more times to run cursor-agent after a run that fails")
+	cmd.Flags().DurationVar(&opts.WaitForDB, "wait-for-db", internal.DefaultWaitForDB, "How long to poll for a store.db once cursor-agent has run")
 }
 
 func init() {
 	rootCmd.AddCommand(snoopCmd)
 	snoopCmd.Flags().BoolVar(&snoopHello, "hello", false, "Invoke cursor-agent with a simple prompt to seed the database")
+	snoopCmd.Flags().BoolVar(&snoopWatch, "watch", false, "Wait until a cursor-agent store.db appears, then print its path")
+	snoopCmd.Flags().DurationVar(&snoopWatchTimeout, "watch-timeout", 5*time.Minute, "How long --watch waits for a store.db")
+	addAgentRunFlags(snoopCmd, &snoopAgent)
 }
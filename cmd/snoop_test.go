@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestSnoopCommand_Watch(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		snoopWatch = false
+		snoopWatchTimeout = 5 * time.Minute
+	}()
+	snoopWatchInterval = 10 * time.Millisecond
+
+	dir := filepath.Join(testutil.CreateTempDir(t), "chats")
+	storeDB := filepath.Join(dir, "hash", "session", "store.db")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.MkdirAll(filepath.Dir(storeDB), 0755)
+		_ = os.WriteFile(storeDB, nil, 0644)
+	}()
+
+	storagePaths = nil
+	var buf bytes.Buffer
+	rootCmd.SetArgs([]string{"snoop", "--watch", "--watch-timeout", "5s", "--storage", dir})
+	rootCmd.SetOut(&buf)
+	rootCmd.SetErr(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("snoop --watch error = %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != storeDB {
+		t.Errorf("snoop --watch printed %q, want %q", got, storeDB)
+	}
+}
+
+func TestSnoopCommand_WatchTimeout(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		snoopWatch = false
+		snoopWatchTimeout = 5 * time.Minute
+	}()
+	snoopWatchInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		timeout  string
+		wantCode int
+	}{
+		{name: "no store.db", timeout: "50ms", wantCode: exitNoStorage},
+		{name: "invalid timeout", timeout: "0s", wantCode: exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagePaths = nil
+			rootCmd.SetArgs([]string{"snoop", "--watch", "--watch-timeout", tt.timeout, "--storage", testutil.CreateTempDir(t)})
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			err := rootCmd.Execute()
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("exitCode() = %d, want %d (error: %v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestSnoopCommand_Hello(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as cursor-agent")
+	}
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	t.Setenv("CURSOR_API_KEY", "test")
+	defer func() {
+		storagePaths = nil
+		snoopHello = false
+		snoopAgent = internal.DefaultAgentRunOptions()
+	}()
+
+	// A cursor-agent that creates its store.db a moment after it answers
+	bin := testutil.CreateTempDir(t)
+	storeDB := filepath.Join(home, ".cursor", "chats", "hash", "session", "store.db")
+	script := "#!/bin/sh\n(sleep 0.2; mkdir -p \"" + filepath.Dir(storeDB) + "\" && touch \"" + storeDB + "\") &\n"
+	if err := os.WriteFile(filepath.Join(bin, "cursor-agent"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write cursor-agent: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantCode int
+	}{
+		{name: "seeds and detects the new store.db", args: []string{"--wait-for-db", "5s"}, want: "Database created: " + storeDB, wantCode: exitOK},
+		{name: "negative retries", args: []string{"--agent-retries", "-1"}, wantCode: exitUsage},
+		{name: "zero timeout", args: []string{"--agent-timeout", "0s"}, wantCode: exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagePaths, snoopAgent = nil, internal.DefaultAgentRunOptions()
+			var buf bytes.Buffer
+			rootCmd.SetArgs(append([]string{"snoop", "--hello"}, tt.args...))
+			rootCmd.SetOut(&buf)
+			rootCmd.SetErr(&bytes.Buffer{})
+			err := rootCmd.Execute()
+			if code := exitCode(err); code != tt.wantCode {
+				t.Fatalf("exitCode() = %d, want %d (error: %v)", code, tt.wantCode, err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("snoop --hello output does not contain %q:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
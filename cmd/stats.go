@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsQuality   bool
+	statsWorkspace string
+	statsFilter    string
+	statsFormat    string
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the sessions: counts, tokens and quality signals",
+	Long: `Print totals over the sessions: how many there are and in how many workspaces,
+their messages by actor, estimated tokens and the time span they cover. When messages
+have timestamps, it also gives percentiles of the time the assistant took to respond
+to each user message.
+
+--quality also assesses each session for signs that the conversation went wrong,
+and lists the sessions that raise any of these flags:
+  • unanswered: a user message got no reply before the next one
+  • tool-errors: a tool or terminal output looks like an error
+  • short-replies: at least half the assistant's replies are under 20 characters
+  • aborted: the session ends on a user message left without a reply
+  • loop: the assistant repeated near-identical messages at least twice
+
+'list --flag <name>' lists the sessions raising a flag.
+
+Examples:
+  cursor-session stats
+  cursor-session stats --quality --workspace ~/code/api
+  cursor-session stats --quality --format json | jq '.quality.by_flag'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		if statsFormat != "text" && statsFormat != "json" {
+			return usageErrorf("invalid --format %q (expected text or json)", statsFormat)
+		}
+		sessionFilter, err := parseFilterFlag(statsFilter)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := loadStorageSessions()
+		if err != nil {
+			return err
+		}
+		if statsWorkspace != "" {
+			filtered := make([]*internal.Session, 0, len(sessions))
+			for _, session := range sessions {
+				if session.Workspace == statsWorkspace {
+					filtered = append(filtered, session)
+				}
+			}
+			sessions = filtered
+		}
+		if sessionFilter != nil {
+			sessions = sessionFilter.FilterSessions(sessions)
+		}
+		internal.SortSessions(sessions)
+
+		stats := internal.NewCorpusStats(sessions, statsQuality)
+		if statsFormat == "json" {
+			data, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode stats: %w", err)
+			}
+			_, err = fmt.Fprintln(out, string(data))
+			return err
+		}
+		printCorpusStats(out, stats)
+		return nil
+	},
+}
+
+// printCorpusStats prints the totals of the sessions and, when assessed, their quality
+func printCorpusStats(out io.Writer, stats internal.CorpusStats) {
+	_, _ = fmt.Fprintln(out, sessionHeaderStyle.Render(fmt.Sprintf("📊 %d session(s) in %d workspace(s)", stats.Sessions, stats.Workspaces)))
+
+	var actors []string
+	for _, actor := range internal.MessageActors() {
+		if n := stats.MessagesByActor[actor]; n > 0 {
+			actors = append(actors, fmt.Sprintf("%s %d", actor, n))
+		}
+	}
+	messages := fmt.Sprintf("%d", stats.Messages)
+	if len(actors) > 0 {
+		messages += " (" + strings.Join(actors, ", ") + ")"
+	}
+	_, _ = fmt.Fprintf(out, "%-14s%s\n", "Messages:", messages)
+	_, _ = fmt.Fprintf(out, "%-14s~%d (estimated)\n", "Tokens:", stats.EstimatedTokens)
+	if stats.FirstCreated != "" {
+		_, _ = fmt.Fprintf(out, "%-14s%s to %s\n", "Created:", displayTimestamp(stats.FirstCreated), displayTimestamp(stats.LastCreated))
+	}
+	if latency := stats.ResponseLatency; latency != nil {
+		_, _ = fmt.Fprintf(out, "%-14sp50 %s, p90 %s, p99 %s, max %s (%d response(s))\n", "Latency:",
+			displayLatency(latency.P50Ms), displayLatency(latency.P90Ms), displayLatency(latency.P99Ms), displayLatency(latency.MaxMs), latency.Responses)
+	}
+
+	quality := stats.Quality
+	if quality == nil {
+		return
+	}
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, sessionHeaderStyle.Render(fmt.Sprintf("🩺 %d of %d session(s) flagged", quality.FlaggedSessions, stats.Sessions)))
+	for _, flag := range internal.QualityFlags {
+		_, _ = fmt.Fprintf(out, "%-15s%d\n", flag+":", quality.ByFlag[flag])
+	}
+	if len(quality.Sessions) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(out)
+	for _, session := range quality.Sessions {
+		name := session.Name
+		if name == "" {
+			name = "Untitled"
+		}
+		_, _ = fmt.Fprintf(out, "%s %s %s\n", idStyle.Render(session.ID), name, tagStyle.Render(strings.Join(session.Flags, ", ")))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().BoolVar(&statsQuality, "quality", false, "Assess the quality of each session and list the sessions flagged")
+	statsCmd.Flags().StringVar(&statsWorkspace, "workspace", "", "Only count the sessions of this workspace")
+	statsCmd.Flags().StringVar(&statsFilter, "filter", "", "Only count sessions matching a filter expression, e.g. 'messages>10 && workspace~\"api\"'")
+	statsCmd.Flags().StringVar(&statsFormat, "format", "text", "Output format (text, json)")
+}
+
+// displayLatency formats a response time in milliseconds for the terminal
+func displayLatency(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d >= time.Second {
+		d = d.Round(100 * time.Millisecond)
+	}
+	return d.String()
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestStatsCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() {
+		storagePaths = nil
+		statsQuality, statsWorkspace, statsFilter, statsFormat = false, "", "", "text"
+	}()
+
+	dir := testutil.CreateTempDir(t)
+	for _, session := range []*internal.Session{
+		internal.CreateTestSessionWithMessages("stats-healthy", []internal.Message{
+			{Actor: "user", Content: "Why does the build fail?", Timestamp: "2024-06-01T10:00:00Z"},
+			{Actor: "assistant", Content: "The linker flags point at a missing library.", Timestamp: "2024-06-01T10:00:02Z"},
+		}),
+		internal.CreateTestSessionWithMessages("stats-aborted", []internal.Message{
+			{Actor: "user", Content: "Run the tests"},
+			{Actor: "assistant", Content: "[Tool Call]\nTool: run_terminal_cmd\n\n[Tool Response]\nexit status 2"},
+			{Actor: "user", Content: "Hello?"},
+		}),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "session_"+session.ID+".json"), testutil.JSONMarshal(t, session), 0644); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		storagePaths, statsQuality, statsFormat = nil, false, "text"
+		var out bytes.Buffer
+		rootCmd.SetArgs(append([]string{"stats", "--storage", dir}, args...))
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&bytes.Buffer{})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("stats %v error = %v", args, err)
+		}
+		return out.String()
+	}
+
+	output := run()
+	if !strings.Contains(output, "2 session(s)") || !strings.Contains(output, "user 3, assistant 2") {
+		t.Errorf("stats output = %q, want the totals", output)
+	}
+	if !strings.Contains(output, "p50 2s") {
+		t.Errorf("stats output = %q, want the response time", output)
+	}
+	if strings.Contains(output, "flagged") {
+		t.Errorf("stats without --quality assessed the sessions:\n%s", output)
+	}
+
+	output = run("--quality")
+	for _, want := range []string{"1 of 2 session(s) flagged", "stats-aborted", "tool-errors, aborted"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("stats --quality output does not contain %q:\n%s", want, output)
+		}
+	}
+
+	var stats internal.CorpusStats
+	if err := json.Unmarshal([]byte(run("--quality", "--format", "json")), &stats); err != nil {
+		t.Fatalf("stats --format json is not JSON: %v", err)
+	}
+	if stats.Quality == nil || stats.Quality.ByFlag[internal.FlagToolErrors] != 1 {
+		t.Errorf("stats --format json quality = %+v", stats.Quality)
+	}
+
+	rootCmd.SetArgs([]string{"stats", "--format", "csv"})
+	if err := rootCmd.Execute(); exitCode(err) != exitUsage {
+		t.Errorf("stats --format csv error = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var tagRemove bool
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag <session-id> [tags...]",
+	Short: "Tag a session to categorize it",
+	Long: `Attach tags to a session, or remove them with --remove. Without tags, the
+session's current tags are printed.
+
+Tags are lowercase and may contain letters, digits and . _ : / -. They are kept
+across cache rebuilds, shown by 'list', used by 'list --tag', and included in
+exports. The session can be given as a full ID or a unique ID prefix.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+
+		sessionID, cacheManager, err := resolveSessionArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		store, err := cacheManager.LoadTags()
+		if err != nil {
+			return err
+		}
+
+		tags := store.Tags(sessionID)
+		if len(args) > 1 {
+			if tagRemove {
+				tags = store.Remove(sessionID, args[1:]...)
+			} else if tags, err = store.Add(sessionID, args[1:]...); err != nil {
+				return err
+			}
+
+			if err := store.Save(); err != nil {
+				return fmt.Errorf("failed to save tags: %w", err)
+			}
+			if err := cacheManager.SetSessionTags(sessionID, tags); err != nil {
+				internal.LogWarn("Failed to update cached session: %v", err)
+			}
+		}
+
+		if len(tags) == 0 {
+			_, _ = fmt.Fprintf(out, "🏷️  %s has no tags\n", sessionID)
+			return nil
+		}
+		_, _ = fmt.Fprintf(out, "🏷️  %s: %s\n", sessionID, strings.Join(tags, ", "))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "Remove the given tags instead of adding them")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestTagCommand(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	defer func() {
+		storagePaths = nil
+		tagRemove = false
+	}()
+
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "globalStorage", "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "adds tags by ID prefix",
+			args: []string{"tag", "compos", "Bug-Hunt", "prod-incident", "--storage", dbPath},
+			want: "composer1: bug-hunt, prod-incident",
+		},
+		{
+			name: "prints tags",
+			args: []string{"tag", "composer1", "--storage", dbPath},
+			want: "composer1: bug-hunt, prod-incident",
+		},
+		{
+			name: "removes tags",
+			args: []string{"tag", "composer1", "prod-incident", "--remove", "--storage", dbPath},
+			want: "composer1: bug-hunt",
+		},
+		{
+			name:    "rejects invalid tags",
+			args:    []string{"tag", "composer1", "two words", "--storage", dbPath},
+			wantErr: true,
+		},
+		{
+			name:    "unknown session",
+			args:    []string{"tag", "missing", "bug-hunt", "--storage", dbPath},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagRemove = false
+			storagePaths = nil // --storage appends to the slice across executions
+			var out bytes.Buffer
+			rootCmd.SetArgs(tt.args)
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(&bytes.Buffer{})
+
+			err := rootCmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tag error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("Output should contain %q, got:\n%s", tt.want, out.String())
+			}
+		})
+	}
+
+	store, err := internal.NewCacheManager(testCacheDir(t)).LoadTags()
+	if err != nil {
+		t.Fatalf("LoadTags() error = %v", err)
+	}
+	if tags := store.Tags("composer1"); len(tags) != 1 || tags[0] != "bug-hunt" {
+		t.Errorf("stored tags = %v, want [bug-hunt]", tags)
+	}
+}
@@ -4,14 +4,14 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -25,6 +25,8 @@ const (
 	repoURL   = "https://api.github.com/repos/" + repoOwner + "/" + repoName
 )
 
+var upgradeChannel string
+
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade",
 	Short: "Upgrade cursor-session to the latest version",
@@ -33,12 +35,24 @@ var upgradeCmd = &cobra.Command{
 This command will:
 1. Check the current installed version
 2. Fetch the latest release from GitHub
-3. Download and install the latest binary if a newer version is available
+3. Download the latest binary if a newer version is available and verify it against
+   the checksums.txt published with the release
+4. Install it in place of the running binary
+
+--channel prerelease also considers release candidates and other prereleases.
+
+Requests go through the proxy named by HTTPS_PROXY (hosts in NO_PROXY excepted), and
+are authenticated with GITHUB_TOKEN when it is set, to avoid the rate limit GitHub puts
+on anonymous requests from shared addresses. Failed and rate-limited requests are retried.
 
 If you installed via 'go install', you can also upgrade by running:
   go install github.com/iksnae/cursor-session@latest`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
+		if !slices.Contains(releaseChannels, upgradeChannel) {
+			return usageErrorf("invalid --channel %q (expected %s)", upgradeChannel, strings.Join(releaseChannels, ", "))
+		}
+		client := newReleaseClient()
 
 		// Get current version
 		currentVersion, err := parseCurrentVersion()
@@ -61,7 +75,7 @@ If you installed via 'go install', you can also upgrade by running:
 			{
 				Message: "Checking for latest version",
 				Fn: func() error {
-					latestRelease, err := fetchLatestRelease()
+					latestRelease, err := client.latestRelease(upgradeChannel)
 					if err != nil {
 						return fmt.Errorf("failed to fetch latest release: %w", err)
 					}
@@ -101,6 +115,18 @@ If you installed via 'go install', you can also upgrade by running:
 					if err != nil {
 						return fmt.Errorf("failed to get download URL: %w", err)
 					}
+					archiveName := path.Base(downloadURL)
+					if assetURL := data.latestRelease.assetURL(archiveName); assetURL != "" {
+						downloadURL = assetURL
+					}
+					sums, err := client.checksums(data.latestRelease)
+					if err != nil {
+						return err
+					}
+					checksum, ok := sums[archiveName]
+					if !ok {
+						return fmt.Errorf("%s lists no checksum for %s", checksumsAsset, archiveName)
+					}
 
 					// Download the binary
 					tempDir, err := os.MkdirTemp("", "cursor-session-upgrade-*")
@@ -110,9 +136,12 @@ If you installed via 'go install', you can also upgrade by running:
 					data.tempDir = tempDir
 
 					archivePath := filepath.Join(tempDir, "cursor-session.tar.gz")
-					if err := downloadFile(downloadURL, archivePath); err != nil {
+					if err := client.download(downloadURL, archivePath); err != nil {
 						return fmt.Errorf("failed to download binary: %w", err)
 					}
+					if err := verifyChecksum(archivePath, checksum); err != nil {
+						return fmt.Errorf("refusing to install %s: %w", archiveName, err)
+					}
 
 					// Extract binary
 					binaryPath := filepath.Join(tempDir, "cursor-session")
@@ -209,14 +238,26 @@ If you installed via 'go install', you can also upgrade by running:
 }
 
 type githubRelease struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Assets  []struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
 		Name               string `json:"name"`
 		BrowserDownloadURL string `json:"browser_download_url"`
 	} `json:"assets"`
 }
 
+// assetURL returns the download URL of the release's asset with the given name, if any
+func (r *githubRelease) assetURL(name string) string {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.BrowserDownloadURL
+		}
+	}
+	return ""
+}
+
 func parseCurrentVersion() (*semver.Version, error) {
 	// The version string format is: "version (commit: commit, built: date)"
 	// Extract just the version part
@@ -237,24 +278,9 @@ func parseCurrentVersion() (*semver.Version, error) {
 	return v, nil
 }
 
+// fetchLatestRelease returns the latest stable release
 func fetchLatestRelease() (*githubRelease, error) {
-	url := repoURL + "/releases/latest"
-	resp, err := http.Get(url) //nolint:gosec // GitHub API URL is safe
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch release info: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch release info: status %d", resp.StatusCode)
-	}
-
-	var release githubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, fmt.Errorf("failed to parse release info: %w", err)
-	}
-
-	return &release, nil
+	return newReleaseClient().latestRelease(channelStable)
 }
 
 func getDownloadURL(tagName string) (string, error) {
@@ -288,30 +314,6 @@ func getDownloadURL(tagName string) (string, error) {
 	return downloadURL, nil
 }
 
-func downloadFile(url, destPath string) error {
-	resp, err := http.Get(url) //nolint:gosec // URL is constructed from known GitHub releases
-	if err != nil {
-		return fmt.Errorf("failed to download: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download: status %d", resp.StatusCode)
-	}
-
-	out, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer func() { _ = out.Close() }()
-
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
-}
-
 func extractBinary(archivePath, destPath string) error {
 	file, err := os.Open(archivePath)
 	if err != nil {
@@ -381,4 +383,5 @@ func copyFile(src, dst string) error {
 
 func init() {
 	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().StringVar(&upgradeChannel, "channel", channelStable, "Release channel to upgrade from ("+strings.Join(releaseChannels, ", ")+")")
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestUpgradeCommand(t *testing.T) {
@@ -12,6 +13,8 @@ func TestUpgradeCommand(t *testing.T) {
 	// and will try to download the latest release. This test just verifies
 	// the command can be parsed and executed (it will likely fail in test environment
 	// due to network or version parsing, but that's expected)
+	defer func(delay time.Duration) { releaseRetryDelay = delay }(releaseRetryDelay)
+	releaseRetryDelay = 0
 	rootCmd.SetArgs([]string{"upgrade"})
 	rootCmd.SetOut(&bytes.Buffer{})
 	rootCmd.SetErr(&bytes.Buffer{})
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/spf13/cobra"
+)
+
+var versionCheck bool
+
+// updateCheckTimeout bounds the check for a newer release other commands make once a
+// day, and how long they wait for it after finishing
+const updateCheckTimeout = 2 * time.Second
+
+// noUpdateNoticeCommands are the commands that never show the notice of a newer release:
+// those reporting the version themselves, and shell completion
+var noUpdateNoticeCommands = map[string]bool{
+	"version":    true,
+	"upgrade":    true,
+	"completion": true,
+	"__complete": true,
+}
+
+// updateNotice receives the notice of a newer release from the check startUpdateCheck
+// started, or "" when there is none; nil when no check was started
+var updateNotice chan string
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, and check for a newer release",
+	Long: `Print the version of cursor-session, as --version does.
+
+With --check, the latest release is fetched from GitHub, as the upgrade command does,
+and compared with the running version.
+
+Other commands check for a newer release themselves at most once a day, and mention one
+on stderr when they finish. The notice is only shown on a terminal, outside CI, and
+is turned off by --quiet or by setting CURSOR_SESSION_NO_UPDATE_NOTIFIER.
+
+Examples:
+  cursor-session version
+  cursor-session version --check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := commandOutput(cmd)
+		_, _ = fmt.Fprintln(out, rootCmd.Version)
+		if !versionCheck {
+			return nil
+		}
+
+		release, err := newReleaseClient().latestRelease(channelStable)
+		if err != nil {
+			return fmt.Errorf("failed to check for updates: %w", err)
+		}
+		printVersionCheck(out, release.TagName)
+		return nil
+	},
+}
+
+// printVersionCheck writes how the running version compares with the latest release
+func printVersionCheck(w io.Writer, latestTag string) {
+	current, err := parseCurrentVersion()
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Latest release: %s (%v)\n", latestTag, err)
+		return
+	}
+	latest, err := semver.NewVersion(strings.TrimPrefix(latestTag, "v"))
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "Latest release: %s (could not parse it: %v)\n", latestTag, err)
+		return
+	}
+	if latest.GreaterThan(current) {
+		_, _ = fmt.Fprintf(w, "%s %s is available (running %s)\n", warningStyle.Render("⚠️  Update:"), latestTag, version)
+		_, _ = fmt.Fprintf(w, "   %s run 'cursor-session upgrade'\n", infoStyle.Render("→"))
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s %s is the latest release\n", successStyle.Render("✅"), version)
+}
+
+// updateNoticeEnabled reports whether cmd may check for a newer release and mention it
+func updateNoticeEnabled(cmd *cobra.Command) bool {
+	if quiet || os.Getenv(internal.EnvNoUpdateNotifier) != "" || internal.IsCIEnvironment() {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if noUpdateNoticeCommands[c.Name()] {
+			return false
+		}
+	}
+	return isTerminal(os.Stderr)
+}
+
+// startUpdateCheck checks for a newer release in the background when the last check, as
+// recorded in the state directory, is a day old. Development builds are never checked.
+func startUpdateCheck(cmd *cobra.Command) {
+	updateNotice = nil
+	if !updateNoticeEnabled(cmd) {
+		return
+	}
+	current, err := parseCurrentVersion()
+	if err != nil {
+		return
+	}
+	dirs, err := internal.ResolveAppDirs()
+	if err != nil {
+		return
+	}
+	check := internal.LoadUpdateCheck(filepath.Join(dirs.StateDir, internal.UpdateCheckFile))
+	now := time.Now()
+	if !check.Due(now) {
+		return
+	}
+
+	notice := make(chan string, 1)
+	updateNotice = notice
+	go func() {
+		client := newReleaseClient()
+		client.client.Timeout = updateCheckTimeout
+		release, err := client.latestRelease(channelStable)
+		if err != nil {
+			// Recorded all the same, so an offline machine is not checked on every command
+			internal.LogDebug("Failed to check for updates: %v", err)
+			check.Record(check.Latest, now)
+		} else {
+			check.Record(release.TagName, now)
+		}
+		if err := check.Save(); err != nil {
+			internal.LogDebug("Failed to record the update check: %v", err)
+		}
+		notice <- updateNoticeText(current, check.Latest)
+	}()
+}
+
+// updateNoticeText returns the notice of a release newer than current, or "" when
+// latestTag is not newer
+func updateNoticeText(current *semver.Version, latestTag string) string {
+	latest, err := semver.NewVersion(strings.TrimPrefix(latestTag, "v"))
+	if err != nil || !latest.GreaterThan(current) {
+		return ""
+	}
+	return fmt.Sprintf("A new release of cursor-session is available: %s → %s\nRun 'cursor-session upgrade' to install it, or set %s to stop these notices.\n",
+		current, latestTag, internal.EnvNoUpdateNotifier)
+}
+
+// printUpdateNotice writes the notice of a newer release found by startUpdateCheck,
+// waiting for the check at most updateCheckTimeout
+func printUpdateNotice(w io.Writer) {
+	if updateNotice == nil {
+		return
+	}
+	defer func() { updateNotice = nil }()
+	select {
+	case notice := <-updateNotice:
+		if notice != "" {
+			_, _ = fmt.Fprintf(w, "\n%s", notice)
+		}
+	case <-time.After(updateCheckTimeout):
+		internal.LogDebug("Gave up waiting for the check for updates")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Check GitHub for a newer release")
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestVersionCommand(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	defer func() { versionCheck = false }()
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"version"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("version error = %v", err)
+	}
+	if got := buf.String(); got != rootCmd.Version+"\n" {
+		t.Errorf("version output = %q, want %q", got, rootCmd.Version+"\n")
+	}
+	if updateNotice != nil {
+		t.Error("version started a check for updates, want none")
+	}
+}
+
+func TestPrintVersionCheck(t *testing.T) {
+	defer func(v string) { version = v }(version)
+
+	tests := []struct {
+		name    string
+		running string
+		latest  string
+		want    string
+	}{
+		{"update available", "1.2.0", "v1.4.0", "v1.4.0 is available (running 1.2.0)"},
+		{"up to date", "v1.4.0", "v1.4.0", "v1.4.0 is the latest release"},
+		{"newer than the latest release", "1.5.0-rc.1", "v1.4.0", "1.5.0-rc.1 is the latest release"},
+		{"development build", "dev", "v1.4.0", "Latest release: v1.4.0 (running development version)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version = tt.running
+			var buf bytes.Buffer
+			printVersionCheck(&buf, tt.latest)
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("printVersionCheck() = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateNoticeText(t *testing.T) {
+	current := semver.MustParse("1.2.0")
+	if got := updateNoticeText(current, "v1.3.0"); !strings.Contains(got, "1.2.0 → v1.3.0") || !strings.Contains(got, internal.EnvNoUpdateNotifier) {
+		t.Errorf("updateNoticeText() = %q, want a notice of v1.3.0", got)
+	}
+	for _, tag := range []string{"v1.2.0", "v1.1.0", "nightly", ""} {
+		if got := updateNoticeText(current, tag); got != "" {
+			t.Errorf("updateNoticeText(%q) = %q, want no notice", tag, got)
+		}
+	}
+}
+
+func TestUpdateNoticeEnabled(t *testing.T) {
+	defer func() { quiet = false }()
+
+	if updateNoticeEnabled(versionCmd) {
+		t.Error("updateNoticeEnabled(version) = true, want false")
+	}
+	if updateNoticeEnabled(upgradeCmd) {
+		t.Error("updateNoticeEnabled(upgrade) = true, want false")
+	}
+
+	quiet = true
+	if updateNoticeEnabled(listCmd) {
+		t.Error("updateNoticeEnabled() = true with --quiet, want false")
+	}
+	quiet = false
+
+	t.Setenv(internal.EnvNoUpdateNotifier, "1")
+	if updateNoticeEnabled(listCmd) {
+		t.Errorf("updateNoticeEnabled() = true with %s set, want false", internal.EnvNoUpdateNotifier)
+	}
+}
+
+func TestPrintUpdateNotice(t *testing.T) {
+	defer func() { updateNotice = nil }()
+
+	var buf bytes.Buffer
+	printUpdateNotice(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("printUpdateNotice() without a check = %q, want nothing", buf.String())
+	}
+
+	updateNotice = make(chan string, 1)
+	updateNotice <- updateNoticeText(semver.MustParse("1.2.0"), "v1.3.0")
+	printUpdateNotice(&buf)
+	if !strings.Contains(buf.String(), "A new release of cursor-session is available") {
+		t.Errorf("printUpdateNotice() = %q, want the notice", buf.String())
+	}
+	if updateNotice != nil {
+		t.Error("printUpdateNotice() kept the check, want it cleared")
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import "strings"
+
+// Message types of bubbles and conversation headers. Cursor records 1 for user and 2
+// for assistant messages; the others are assigned when a message is recognized as the
+// output of a tool or terminal command, or as a system prompt.
+const (
+	MessageTypeUser      = 1
+	MessageTypeAssistant = 2
+	MessageTypeTool      = 3
+	MessageTypeTerminal  = 4
+	MessageTypeSystem    = 5
+)
+
+// Actors of session messages
+const (
+	ActorUser      = "user"
+	ActorAssistant = "assistant"
+	ActorTool      = "tool"
+	ActorTerminal  = "terminal"
+	ActorSystem    = "system"
+)
+
+// MessageActors lists the actors a session message can have
+func MessageActors() []string {
+	return []string{ActorUser, ActorAssistant, ActorTool, ActorTerminal, ActorSystem}
+}
+
+// ActorForMessageType returns the actor of a message type. Unknown types are attributed
+// to the user, as before tool, terminal and system messages were told apart.
+func ActorForMessageType(msgType int) string {
+	switch msgType {
+	case MessageTypeAssistant:
+		return ActorAssistant
+	case MessageTypeTool:
+		return ActorTool
+	case MessageTypeTerminal:
+		return ActorTerminal
+	case MessageTypeSystem:
+		return ActorSystem
+	default:
+		return ActorUser
+	}
+}
+
+// MessageTypeForActor returns the message type of an actor, the inverse of
+// ActorForMessageType. Unknown actors are taken as the assistant, as exports written
+// before tool, terminal and system messages were told apart only named the user.
+func MessageTypeForActor(actor string) int {
+	switch actor {
+	case ActorUser:
+		return MessageTypeUser
+	case ActorTool:
+		return MessageTypeTool
+	case ActorTerminal:
+		return MessageTypeTerminal
+	case ActorSystem:
+		return MessageTypeSystem
+	default:
+		return MessageTypeAssistant
+	}
+}
+
+// toolMessageType returns the message type of a tool's output: terminal for tools that
+// run shell commands, such as Cursor's run_terminal_cmd, and tool for any other
+func toolMessageType(toolName string) int {
+	name := strings.ToLower(toolName)
+	if strings.Contains(name, "terminal") || strings.Contains(name, "shell") || name == "bash" {
+		return MessageTypeTerminal
+	}
+	return MessageTypeTool
+}
+
+// ToolActor returns the actor of the output of a tool: terminal for tools that run shell
+// commands and tool for any other
+func ToolActor(toolName string) string {
+	return ActorForMessageType(toolMessageType(toolName))
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// agentMessageDirs are the directories, relative to a session directory, that newer
+// cursor-agent builds write one file per message into, next to or instead of the rows of
+// store.db
+var agentMessageDirs = []string{".", "messages"}
+
+// agentMessageExtensions are the extensions of message files: JSON documents, and binary
+// (protobuf) records
+var agentMessageExtensions = map[string]bool{".json": true, ".bin": true, ".pb": true, ".msg": true}
+
+// maxAgentMessageFile bounds the size of a message file read into memory; larger files
+// are skipped with a warning
+const maxAgentMessageFile = 256 << 20
+
+// FindAgentMessageFiles returns the message files in the session directory of a store.db
+// and its messages directory, sorted by path. Files with a message extension count, as
+// do files without one named by a UUID or hash, as message IDs are; store.db and its
+// journal files do not.
+func FindAgentMessageFiles(dbPath string) []string {
+	sessionDir := filepath.Dir(dbPath)
+	var files []string
+	for _, dir := range agentMessageDirs {
+		entries, err := os.ReadDir(filepath.Join(sessionDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && isAgentMessageFile(entry.Name()) {
+				files = append(files, filepath.Join(sessionDir, dir, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// isAgentMessageFile reports whether a file in a session directory holds a message
+func isAgentMessageFile(name string) bool {
+	if strings.HasPrefix(name, "store.db") || strings.HasPrefix(name, ".") {
+		return false
+	}
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return isValidUUID(name) || isHashLike(name)
+	}
+	return agentMessageExtensions[strings.ToLower(ext)]
+}
+
+// agentMessageFileKey returns the key a message file is read under: its name without the
+// extension, which cursor-agent sets to the message ID as it does for blob keys
+func agentMessageFileKey(path string) string {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// LoadAgentMessageFiles reads the message files next to a store.db as blob entries, so
+// they are decoded as the rows of the blobs table are, whether JSON or binary. Files
+// that cannot be read are skipped and recorded in warnings.
+func LoadAgentMessageFiles(dbPath string, warnings *Warnings) []BlobEntry {
+	var entries []BlobEntry
+	for _, path := range FindAgentMessageFiles(dbPath) {
+		key := agentMessageFileKey(path)
+		info, err := os.Stat(path)
+		if err == nil && info.Size() > maxAgentMessageFile {
+			err = fmt.Errorf("file of %d bytes is over the %d byte limit", info.Size(), maxAgentMessageFile)
+		}
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			warnings.Add(WarningMessageFile, path, key, err.Error())
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		entries = append(entries, BlobEntry{Key: key, Value: string(data), Path: path})
+	}
+	if len(entries) > 0 {
+		LogDebugAttrs("Found message files", "path", dbPath, "files", len(entries))
+	}
+	return entries
+}
+
+// addMessageFileHeaders adds the messages read from message files that the conversation
+// headers of their composer leave out, as a composer saved before them does, after its
+// other headers. A message belongs to the composer of its chat, or to the only composer.
+func addMessageFileHeaders(composers []*RawComposer, bubbles map[string]*RawBubble, fileBubbles map[string]string) {
+	if len(fileBubbles) == 0 {
+		return
+	}
+	for _, composer := range composers {
+		known := make(map[string]bool, len(composer.FullConversationHeadersOnly))
+		for _, header := range composer.FullConversationHeadersOnly {
+			known[header.BubbleID] = true
+		}
+		var missing []*RawBubble
+		for id := range fileBubbles {
+			bubble := bubbles[id]
+			if !known[id] && (bubble.ChatID == composer.ComposerID || len(composers) == 1) {
+				missing = append(missing, bubble)
+			}
+		}
+		sortBubbles(missing)
+		for _, bubble := range missing {
+			composer.FullConversationHeadersOnly = append(composer.FullConversationHeadersOnly, ConversationHeader{BubbleID: bubble.BubbleID, Type: bubble.Type})
+		}
+		if len(missing) > 0 {
+			LogDebugAttrs("Added messages from message files", "session", composer.ComposerID, "messages", len(missing))
+		}
+	}
+}
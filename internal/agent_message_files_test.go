@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindAgentMessageFiles(t *testing.T) {
+	sessionDir := t.TempDir()
+	dbPath := filepath.Join(sessionDir, "store.db")
+	uuid := "027f8b2f-d09c-4a69-98b0-b53f0118605d"
+	for _, name := range []string{"store.db", "store.db-wal", "b1.json", "notes.txt", ".b2.json", "b3.bin", filepath.Join("messages", uuid), filepath.Join("messages", "not-an-id")} {
+		path := filepath.Join(sessionDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := FindAgentMessageFiles(dbPath)
+	want := []string{filepath.Join(sessionDir, "b1.json"), filepath.Join(sessionDir, "b3.bin"), filepath.Join(sessionDir, "messages", uuid)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAgentMessageFiles() = %v, want %v", got, want)
+	}
+	if files := FindAgentMessageFiles(filepath.Join(t.TempDir(), "store.db")); len(files) != 0 {
+		t.Errorf("FindAgentMessageFiles() = %v for a session without message files, want none", files)
+	}
+}
+
+func TestLoadSessionFromStoreDB_MessageFiles(t *testing.T) {
+	sessionDir := t.TempDir()
+	dbPath := filepath.Join(sessionDir, "store.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE blobs (key TEXT PRIMARY KEY, value TEXT)",
+		"CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	rows := map[string]string{
+		"composer1": `{"composerId":"composer1","fullConversationHeadersOnly":[{"bubbleId":"bubble1","type":1}]}`,
+		"bubble1":   `{"bubbleId":"bubble1","chatId":"composer1","text":"From the database","timestamp":1000,"type":1}`,
+	}
+	for key, value := range rows {
+		if _, err := db.Exec("INSERT INTO blobs (key, value) VALUES (?, ?)", key, value); err != nil {
+			t.Fatalf("Failed to insert blob: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	// A stale copy of a message in the database, a JSON message file and a binary one
+	// wrapping JSON in a record header
+	files := map[string][]byte{
+		"bubble1.json":                           []byte(`{"bubbleId":"bubble1","chatId":"composer1","text":"Stale copy","timestamp":1000,"type":1}`),
+		"bubble2.json":                           []byte(`{"bubbleId":"bubble2","chatId":"composer1","text":"From a JSON file","timestamp":2000,"type":2}`),
+		filepath.Join("messages", "bubble3.bin"): append([]byte{0x0a, 0x8f, 0x01, 0x00}, []byte(`{"bubbleId":"bubble3","chatId":"composer1","text":"From a binary file","timestamp":3000,"type":1}`)...),
+	}
+	for name, data := range files {
+		path := filepath.Join(sessionDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ResetParseStats()
+	defer ResetParseStats()
+	bubbles, composers, _, _, err := LoadSessionFromStoreDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+
+	texts := map[string]string{"bubble1": "From the database", "bubble2": "From a JSON file", "bubble3": "From a binary file"}
+	for id, text := range texts {
+		bubble, ok := bubbles[id]
+		if !ok {
+			t.Errorf("LoadSessionFromStoreDB() is missing %s", id)
+			continue
+		}
+		if bubble.Text != text {
+			t.Errorf("%s text = %q, want %q", id, bubble.Text, text)
+		}
+	}
+	if got := bubbles["bubble1"].Provenance.SourcePath; got != dbPath {
+		t.Errorf("bubble1 source = %s, want the database", got)
+	}
+	if want := filepath.Join(sessionDir, "bubble2.json"); bubbles["bubble2"].Provenance.SourcePath != want {
+		t.Errorf("bubble2 source = %s, want %s", bubbles["bubble2"].Provenance.SourcePath, want)
+	}
+
+	if len(composers) != 1 {
+		t.Fatalf("LoadSessionFromStoreDB() returned %d composers, want 1", len(composers))
+	}
+	var headers []string
+	for _, header := range composers[0].FullConversationHeadersOnly {
+		headers = append(headers, header.BubbleID)
+	}
+	if want := []string{"bubble1", "bubble2", "bubble3"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("composer headers = %v, want %v", headers, want)
+	}
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// AgentSchema is a layout of the tables in a cursor-agent store.db that cursor-session
+// can read. Each layout has a dedicated reader turning its rows into blob (message) and
+// meta (session metadata) entries for the parser.
+type AgentSchema struct {
+	Name    string   // Name of the layout, such as "blobs/meta"
+	Version int      // SQLite user_version of the database
+	Tables  []string // Tables found in the database
+
+	reader *agentSchemaReader
+}
+
+// agentSchemaReader reads one known store.db layout
+type agentSchemaReader struct {
+	name      string
+	matches   func(tables []string) bool
+	readBlobs func(db *sql.DB) ([]BlobEntry, error)
+	readMeta  func(db *sql.DB) ([]MetaEntry, error)
+}
+
+// agentSchemas are the known store.db layouts, newest first. A database matching several
+// of them is read with the first.
+var agentSchemas = []*agentSchemaReader{
+	{
+		// Newer cursor-agent builds keep the session in a threads table and its messages
+		// in a messages table
+		name: "threads/messages",
+		matches: func(tables []string) bool {
+			return containsString(tables, "threads") && containsString(tables, "messages")
+		},
+		readBlobs: queryThreadMessages,
+		readMeta:  queryThreads,
+	},
+	{
+		name: "blobs/meta",
+		matches: func(tables []string) bool {
+			return containsString(tables, "blobs") || containsString(tables, "meta")
+		},
+		readBlobs: QueryBlobsTable,
+		readMeta:  QueryMetaTable,
+	},
+	{
+		// A store.db created before the first message was written
+		name:      "empty",
+		matches:   func(tables []string) bool { return len(tables) == 0 },
+		readBlobs: func(*sql.DB) ([]BlobEntry, error) { return nil, nil },
+		readMeta:  func(*sql.DB) ([]MetaEntry, error) { return nil, nil },
+	},
+}
+
+// DetectAgentSchema probes the tables of a store.db and returns the layout to read it
+// with, or an UnknownSchemaError listing the tables found when no known layout matches
+func DetectAgentSchema(db *sql.DB, path string) (*AgentSchema, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, reader := range agentSchemas {
+		if reader.matches(tables) {
+			LogDebug("Reading %s with the %s schema (v%d)", path, reader.name, version)
+			return &AgentSchema{Name: reader.name, Version: version, Tables: tables, reader: reader}, nil
+		}
+	}
+	return nil, &UnknownSchemaError{Path: path, Version: version, Tables: tables}
+}
+
+// QueryBlobs reads the message entries of a store.db in this layout
+func (s *AgentSchema) QueryBlobs(db *sql.DB) ([]BlobEntry, error) {
+	return s.reader.readBlobs(db)
+}
+
+// QueryMeta reads the session metadata entries of a store.db in this layout
+func (s *AgentSchema) QueryMeta(db *sql.DB) ([]MetaEntry, error) {
+	return s.reader.readMeta(db)
+}
+
+// tableColumns returns the column names of a table
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s table info: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []string
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, dataType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &pk); err != nil {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// firstColumn returns the first of the candidate names that is a column, or ""
+func firstColumn(columns []string, candidates ...string) string {
+	for _, candidate := range candidates {
+		if containsString(columns, candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// queryKeyValues reads the rows of a table as key and value pairs, in insertion order,
+// picking the key and value columns among the candidates
+func queryKeyValues(db *sql.DB, table string, keyColumns, valueColumns []string) ([]BlobEntry, error) {
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return nil, err
+	}
+	keyColumn := firstColumn(columns, keyColumns...)
+	valueColumn := firstColumn(columns, valueColumns...)
+	if keyColumn == "" || valueColumn == "" {
+		return nil, fmt.Errorf("%s table has no known key and value columns (columns: %s)", table, strings.Join(columns, ", "))
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT CAST(%q AS TEXT), %q FROM %q WHERE %q IS NOT NULL ORDER BY rowid", keyColumn, valueColumn, table, valueColumn))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s table: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []BlobEntry
+	for rows.Next() {
+		var entry BlobEntry
+		if err := rows.Scan(&entry.Key, &entry.Value); err != nil {
+			LogWarn("Failed to scan %s row: %v", table, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Columns the threads/messages layout keeps IDs and JSON documents in
+var (
+	threadKeyColumns   = []string{"id", "thread_id", "key", "uuid"}
+	threadValueColumns = []string{"data", "value", "json", "metadata", "content"}
+)
+
+// queryThreadMessages reads the messages table of the threads/messages layout as blobs
+func queryThreadMessages(db *sql.DB) ([]BlobEntry, error) {
+	entries, err := queryKeyValues(db, "messages", threadKeyColumns, threadValueColumns)
+	if err != nil {
+		return nil, err
+	}
+	LogDebugAttrs("Queried thread messages", "messages", len(entries))
+	return entries, nil
+}
+
+// queryThreads reads the threads table of the threads/messages layout as the session
+// metadata entry, meta key "0", of the blobs/meta layout. A store.db holds one session,
+// so only the first thread is used.
+func queryThreads(db *sql.DB) ([]MetaEntry, error) {
+	entries, err := queryKeyValues(db, "threads", threadKeyColumns, threadValueColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if len(entries) > 1 {
+		LogWarn("Found %d threads in one store.db, reading the metadata of the first", len(entries))
+	}
+	return []MetaEntry{{Key: "0", Value: entries[0].Value}}, nil
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createStoreDB creates a store.db in a cursor-agent session directory and runs stmts on it
+func createStoreDB(t *testing.T, sessionID string, stmts ...string) string {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "workspace-hash", sessionID, "store.db")
+	writeFile(t, dbPath, "")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to run %q: %v", stmt, err)
+		}
+	}
+	return dbPath
+}
+
+func TestDetectAgentSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		stmts []string
+		want  string
+	}{
+		{"blobs and meta", []string{`CREATE TABLE blobs (id TEXT, data BLOB)`, `CREATE TABLE meta (key TEXT, value TEXT)`}, "blobs/meta"},
+		{"blobs only", []string{`CREATE TABLE blobs (id TEXT, data BLOB)`}, "blobs/meta"},
+		{"threads", []string{`CREATE TABLE threads (id TEXT, data TEXT)`, `CREATE TABLE messages (id TEXT, thread_id TEXT, data TEXT)`, `PRAGMA user_version = 3`}, "threads/messages"},
+		{"empty", nil, "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := OpenDatabase(createStoreDB(t, "session", tt.stmts...))
+			if err != nil {
+				t.Fatalf("OpenDatabase() error = %v", err)
+			}
+			defer func() { _ = db.Close() }()
+
+			schema, err := DetectAgentSchema(db, "store.db")
+			if err != nil {
+				t.Fatalf("DetectAgentSchema() error = %v", err)
+			}
+			if schema.Name != tt.want {
+				t.Errorf("DetectAgentSchema() = %s, want %s", schema.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSessionFromStoreDB_Threads(t *testing.T) {
+	dbPath := createStoreDB(t, "thread-session",
+		`CREATE TABLE threads (id TEXT PRIMARY KEY, data TEXT)`,
+		`CREATE TABLE messages (id TEXT PRIMARY KEY, thread_id TEXT, data TEXT)`,
+		`INSERT INTO threads VALUES ('thread-session', '{"name":"Fix the build","createdAt":1700000000000}')`,
+		`INSERT INTO messages VALUES ('m1', 'thread-session', '{"id":"m1","role":"user","content":"Why does CI fail?"}')`,
+		`INSERT INTO messages VALUES ('m2', 'thread-session', '{"id":"m2","role":"assistant","content":"A missing dependency."}')`,
+	)
+
+	bubbles, _, _, _, err := LoadSessionFromStoreDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+	if len(bubbles) != 2 {
+		t.Errorf("LoadSessionFromStoreDB() returned %d bubbles, want 2", len(bubbles))
+	}
+	for _, bubble := range bubbles {
+		if bubble.Timestamp != 1700000000000 {
+			t.Errorf("bubble %s timestamp = %d, want the thread's createdAt", bubble.BubbleID, bubble.Timestamp)
+		}
+	}
+
+	composer, err := LoadSessionMetadataFromStoreDB(dbPath)
+	if err != nil || composer.Name != "Fix the build" || composer.CreatedAt != 1700000000000 {
+		t.Errorf("LoadSessionMetadataFromStoreDB() = %+v, %v, want the thread's name and creation time", composer, err)
+	}
+}
+
+func TestLoadSessionFromStoreDB_UnknownSchema(t *testing.T) {
+	dbPath := createStoreDB(t, "future-session",
+		`CREATE TABLE conversations (id TEXT, payload BLOB)`,
+		`CREATE TABLE turns (id TEXT, payload BLOB)`,
+		`PRAGMA user_version = 7`,
+	)
+
+	_, _, _, _, err := LoadSessionFromStoreDB(dbPath)
+	var schemaErr *UnknownSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v, want an UnknownSchemaError", err)
+	}
+	if schemaErr.Version != 7 || strings.Join(schemaErr.Tables, ",") != "conversations,turns" {
+		t.Errorf("UnknownSchemaError = %+v, want v7 with conversations and turns", schemaErr)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "unknown cursor-agent schema v7") || !strings.Contains(msg, "tables: conversations, turns") {
+		t.Errorf("error message %q should name the version and tables", msg)
+	}
+
+	if _, err := LoadSessionMetadataFromStoreDB(dbPath); !errors.As(err, &schemaErr) {
+		t.Errorf("LoadSessionMetadataFromStoreDB() error = %v, want an UnknownSchemaError", err)
+	}
+}
@@ -2,20 +2,44 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
-	"time"
 	"unicode"
 	"unicode/utf8"
 )
 
-// AgentStorageReader reads session data from cursor-agent CLI store.db files
+// parentSessionKeys are the session metadata fields, in meta key "0", that name the session
+// a resumed session continues
+var parentSessionKeys = []string{"parentAgentId", "parentSessionId", "parentThreadId", "resumedFrom"}
+
+// parseConcurrency is how many store.db files are parsed at once; 0 uses one per CPU
+var parseConcurrency int
+
+// SetParseConcurrency sets how many cursor-agent store.db files are parsed at once; 0 or
+// less parses one per CPU
+func SetParseConcurrency(n int) {
+	parseConcurrency = n
+}
+
+// ParseConcurrency returns how many cursor-agent store.db files are parsed at once
+func ParseConcurrency() int {
+	if parseConcurrency > 0 {
+		return parseConcurrency
+	}
+	return runtime.NumCPU()
+}
+
+// AgentStorageReader reads session data from cursor-agent CLI store.db files, keeping no
+// more of them open at once than SetMaxOpenDBs allows
 type AgentStorageReader struct {
 	storeDBPaths []string
 }
@@ -109,18 +133,15 @@ func QueryBlobsTable(db *sql.DB) ([]BlobEntry, error) {
 			entries = append(entries, entry)
 			// Log first few entries for diagnostics
 			if rowCount <= 3 {
-				valuePreview := entry.Value
-				if len(valuePreview) > 200 {
-					valuePreview = valuePreview[:200] + "..."
-				}
-				LogInfo("Blob entry %d: key='%s', value_preview='%s'", rowCount, entry.Key, valuePreview)
+				valuePreview := logPreview(entry.Value, 200)
+				LogDebugAttrs("Read blob", "row", rowCount, "key", entry.Key, "preview", valuePreview)
 			}
 		} else {
 			LogWarn("Blob row %d has NULL value: key='%s'", rowCount, entry.Key)
 		}
 	}
 
-	LogInfo("QueryBlobsTable: queried %d rows, returned %d valid entries", rowCount, len(entries))
+	LogDebugAttrs("Queried blobs table", "rows", rowCount, "entries", len(entries))
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration error: %w", err)
@@ -204,18 +225,15 @@ func QueryMetaTable(db *sql.DB) ([]MetaEntry, error) {
 			entries = append(entries, entry)
 			// Log first few entries for diagnostics
 			if rowCount <= 3 {
-				valuePreview := entry.Value
-				if len(valuePreview) > 200 {
-					valuePreview = valuePreview[:200] + "..."
-				}
-				LogInfo("Meta entry %d: key='%s', value_preview='%s'", rowCount, entry.Key, valuePreview)
+				valuePreview := logPreview(entry.Value, 200)
+				LogDebugAttrs("Read meta entry", "row", rowCount, "key", entry.Key, "preview", valuePreview)
 			}
 		} else {
 			LogWarn("Meta row %d has NULL value: key='%s'", rowCount, entry.Key)
 		}
 	}
 
-	LogInfo("QueryMetaTable: queried %d rows, returned %d valid entries", rowCount, len(entries))
+	LogDebugAttrs("Queried meta table", "rows", rowCount, "entries", len(entries))
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration error: %w", err)
@@ -228,6 +246,7 @@ func QueryMetaTable(db *sql.DB) ([]MetaEntry, error) {
 type BlobEntry struct {
 	Key   string
 	Value string
+	Path  string // Message file the entry was read from, empty for rows of store.db
 }
 
 // MetaEntry represents an entry from the meta table
@@ -236,25 +255,37 @@ type MetaEntry struct {
 	Value string
 }
 
-// LoadSessionFromStoreDB loads session data from a single store.db file
-func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext, error) {
-	db, err := OpenDatabase(dbPath)
+// LoadSessionFromStoreDB loads session data from a single store.db file, with warnings
+// about the records it skipped. The database is opened through the pool bounded by
+// SetMaxOpenDBs, so it may wait for another to be released.
+func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext, *Warnings, error) {
+	db, release, err := agentDBs.Acquire(dbPath)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to open store.db: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to open store.db: %w", err)
 	}
-	defer func() { _ = db.Close() }()
+	defer release()
 
-	// Query both tables
-	blobs, err := QueryBlobsTable(db)
+	// Read the messages and session metadata with the reader for the database's layout
+	schema, err := DetectAgentSchema(db, dbPath)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to query blobs table: %w", err)
+		return nil, nil, nil, nil, err
+	}
+	blobs, err := schema.QueryBlobs(db)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to query %s messages: %w", schema.Name, err)
 	}
 
-	meta, err := QueryMetaTable(db)
+	meta, err := schema.QueryMeta(db)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to query meta table: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to query %s metadata: %w", schema.Name, err)
 	}
 
+	// Newer cursor-agent builds also write each message to a file in the session directory.
+	// The files are read first, so that a message also in the database is taken from it.
+	loadWarnings := NewWarnings()
+	fileBlobs := LoadAgentMessageFiles(dbPath, loadWarnings)
+	blobs = append(fileBlobs, blobs...)
+
 	// Extract session ID from path: ~/.cursor/chats/{hash}/{session-id}/store.db
 	// Use this to help identify the session
 	sessionID := extractSessionIDFromPath(dbPath)
@@ -264,49 +295,66 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 	var composers []*RawComposer
 	contexts := make(map[string][]*MessageContext)
 
-	// Process blobs - they may contain bubble data
+	// Process blobs - they may contain bubble data. Records that cannot be used are
+	// skipped and collected as warnings, summed up once at the end instead of logged each.
+	var warnings []string
 	jsonParseFailures := 0
+	fileBubbles := make(map[string]string) // Bubble ID -> message file it was read from
+	addBubble := func(blob BlobEntry, bubble *RawBubble) {
+		bubbles[bubble.BubbleID] = bubble
+		if blob.Path != "" {
+			fileBubbles[bubble.BubbleID] = blob.Path
+		} else {
+			delete(fileBubbles, bubble.BubbleID)
+		}
+	}
 	for i, blob := range blobs {
+		// Values written by Windows builds may be UTF-16 or start with a byte order mark,
+		// which every decoding below would fail on
+		if decoded, encoding := decodeTextValue(blob.Value); encoding != "" {
+			LogDebug("Blob %d (key='%s') was %s text, converted to UTF-8", i+1, blob.Key, encoding)
+			blob.Value = decoded
+		}
+
 		// Try to parse as JSON and identify the type
-		var data map[string]interface{}
 		valueBytes := []byte(blob.Value)
 
-		// Try JSON first
-		if err := json.Unmarshal(valueBytes, &data); err != nil {
+		// Try JSON first; payloads over the limit, such as huge tool output, are skipped
+		data, skippedPayloads, err := unmarshalBlob(valueBytes)
+		if skippedPayloads > 0 {
+			loadWarnings.Add(WarningBlobPayload, dbPath, blob.Key, fmt.Sprintf("%d payload(s) over %d bytes not loaded (blob of %d bytes)", skippedPayloads, maxBlobPayload, len(valueBytes)))
+		}
+		if err != nil {
 			// Not JSON - try base64 decode in case it's encoded
 			decoded, decodeErr := tryBase64Decode(blob.Value)
 			if decodeErr == nil {
 				if jsonErr := json.Unmarshal(decoded, &data); jsonErr == nil {
 					// Successfully decoded and parsed
-					LogInfo("Blob %d (key='%s') was base64 encoded, decoded successfully", i+1, blob.Key)
+					LogDebugAttrs("Decoded base64 blob", "path", dbPath, "key", blob.Key)
 				} else {
 					// Base64 decoded but not JSON - try extracting JSON from binary
 					jsonBytes, found := extractJSONFromBinary(decoded)
 					if found {
 						// extractJSONFromBinary already validated it's valid JSON
 						if jsonErr := json.Unmarshal(jsonBytes, &data); jsonErr == nil {
-							LogInfo("Blob %d (key='%s') had JSON embedded in binary data, extracted successfully", i+1, blob.Key)
+							LogDebugAttrs("Extracted JSON embedded in binary blob", "path", dbPath, "key", blob.Key)
 						} else {
 							// This shouldn't happen since extractJSONFromBinary validates, but handle it anyway
 							jsonParseFailures++
+							loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, fmt.Sprintf("failed JSON parse after extraction from base64 data: %v", jsonErr))
 							if i < 5 {
-								valuePreview := blob.Value
-								if len(valuePreview) > 100 {
-									valuePreview = valuePreview[:100] + "..."
-								}
-								LogWarn("Blob %d (key='%s') failed JSON parse after extraction: %v. Value preview: %s", i+1, blob.Key, jsonErr, valuePreview)
+								valuePreview := logPreview(blob.Value, 100)
+								LogDebug("Blob %d (key='%s') failed JSON parse after extraction: %v. Value preview: %s", i+1, blob.Key, jsonErr, valuePreview)
 							}
 							continue
 						}
 					} else {
 						// Decoded but still not JSON - log and skip
 						jsonParseFailures++
+						loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, fmt.Sprintf("failed JSON parse (tried base64 too): %v", jsonErr))
 						if i < 5 {
-							valuePreview := blob.Value
-							if len(valuePreview) > 100 {
-								valuePreview = valuePreview[:100] + "..."
-							}
-							LogWarn("Blob %d (key='%s') failed JSON parse (tried base64 too): %v. Value preview: %s", i+1, blob.Key, jsonErr, valuePreview)
+							valuePreview := logPreview(blob.Value, 100)
+							LogDebug("Blob %d (key='%s') failed JSON parse (tried base64 too): %v. Value preview: %s", i+1, blob.Key, jsonErr, valuePreview)
 						}
 						continue
 					}
@@ -317,27 +365,29 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 				if hexErr == nil {
 					// Try JSON parse on hex-decoded data
 					if jsonErr := json.Unmarshal(hexDecoded, &data); jsonErr == nil {
-						LogInfo("Blob %d (key='%s') was hex encoded, decoded successfully", i+1, blob.Key)
+						LogDebugAttrs("Decoded hex blob", "path", dbPath, "key", blob.Key)
 					} else {
 						// Hex decoded but not JSON - try extracting JSON from binary
 						jsonBytes, found := extractJSONFromBinary(hexDecoded)
 						if found {
 							// extractJSONFromBinary already validated it's valid JSON
 							if jsonErr := json.Unmarshal(jsonBytes, &data); jsonErr == nil {
-								LogInfo("Blob %d (key='%s') had JSON embedded in hex-decoded binary data, extracted successfully", i+1, blob.Key)
+								LogDebugAttrs("Extracted JSON from hex-decoded binary blob", "path", dbPath, "key", blob.Key)
 							} else {
 								// This shouldn't happen since extractJSONFromBinary validates, but handle it anyway
 								jsonParseFailures++
+								loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, fmt.Sprintf("hex decoded but JSON parse failed after extraction: %v", jsonErr))
 								if i < 5 {
-									LogWarn("Blob %d (key='%s') hex decoded but JSON parse failed after extraction: %v", i+1, blob.Key, jsonErr)
+									LogDebug("Blob %d (key='%s') hex decoded but JSON parse failed after extraction: %v", i+1, blob.Key, jsonErr)
 								}
 								continue
 							}
 						} else {
 							// Hex decoded but no JSON found - skip
 							jsonParseFailures++
+							loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, "hex encoded but contains no JSON")
 							if i < 5 {
-								LogWarn("Blob %d (key='%s') was hex encoded but contains no JSON", i+1, blob.Key)
+								LogDebug("Blob %d (key='%s') was hex encoded but contains no JSON", i+1, blob.Key)
 							}
 							continue
 						}
@@ -348,23 +398,21 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 					if found {
 						// extractJSONFromBinary already validated it's valid JSON, so we can parse it directly
 						if jsonErr := json.Unmarshal(jsonBytes, &data); jsonErr == nil {
-							jsonPreview := string(jsonBytes)
-							if len(jsonPreview) > 200 {
-								jsonPreview = jsonPreview[:200] + "..."
-							}
-							LogInfo("Blob %d (key='%s'): Found valid JSON in binary (len=%d): %s", i+1, blob.Key, len(jsonBytes), jsonPreview)
-							LogInfo("Blob %d (key='%s') had JSON embedded in binary data, extracted successfully", i+1, blob.Key)
+							jsonPreview := logPreview(string(jsonBytes), 200)
+							LogDebugAttrs("Found JSON in binary blob", "path", dbPath, "key", blob.Key, "length", len(jsonBytes), "preview", jsonPreview)
+							LogDebugAttrs("Extracted JSON embedded in binary blob", "path", dbPath, "key", blob.Key)
 							// Log fields to understand structure
 							keys := make([]string, 0, len(data))
 							for k := range data {
 								keys = append(keys, k)
 							}
-							LogInfo("Blob %d extracted JSON fields: %v", i+1, keys)
+							LogDebugAttrs("Extracted JSON fields from blob", "path", dbPath, "key", blob.Key, "fields", keys)
 						} else {
 							// This shouldn't happen since extractJSONFromBinary validates, but handle it anyway
 							jsonParseFailures++
+							loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, fmt.Sprintf("failed JSON parse after extraction: %v", jsonErr))
 							if i < 10 {
-								LogWarn("Blob %d (key='%s', key_len=%d) failed JSON parse after extraction: %v", i+1, blob.Key, len(blob.Key), jsonErr)
+								LogDebug("Blob %d (key='%s', key_len=%d) failed JSON parse after extraction: %v", i+1, blob.Key, len(blob.Key), jsonErr)
 							}
 							continue
 						}
@@ -388,7 +436,7 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 											for k, v := range jsonData {
 												data[k] = v
 											}
-											LogInfo("Blob %d (key='%s'): Extracted JSON from protobuf field %s", i+1, blob.Key, key)
+											LogDebugAttrs("Extracted JSON from protobuf field", "path", dbPath, "key", blob.Key, "field", key)
 										}
 									}
 								} else if nestedMap, ok := value.(map[string]interface{}); ok {
@@ -415,25 +463,27 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 								if len(extractedStrings) < previewCount {
 									previewCount = len(extractedStrings)
 								}
-								LogInfo("Blob %d (key='%s'): Decoded protobuf, extracted %d string(s): %v", i+1, blob.Key, len(extractedStrings), extractedStrings[:previewCount])
+								LogDebugAttrs("Decoded protobuf blob", "path", dbPath, "key", blob.Key, "strings", len(extractedStrings), "preview", logPreview(fmt.Sprint(extractedStrings[:previewCount]), 200))
 								// If we extracted JSON data, continue processing
 								if len(data) > 0 {
 									// Continue to bubble parsing below
 								} else {
 									// No JSON found in protobuf - try text message format
 									if bubble := parseTextMessageFormat(blob.Key, blob.Value, sessionID); bubble != nil {
-										bubbles[bubble.BubbleID] = bubble
-										LogInfo("Blob %d parsed as text message format (user message): bubbleId='%s', text='%s', chatId='%s'", i+1, bubble.BubbleID, bubble.Text, bubble.ChatID)
+										addBubble(blob, bubble)
+										LogDebugAttrs("Parsed text$uuid user message", "path", dbPath, "key", blob.Key, "session", bubble.ChatID, "bubble", bubble.BubbleID, "preview", logPreview(bubble.Text, 200))
 										continue
 									}
 									jsonParseFailures++
+									loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, "decoded as protobuf but found no JSON or text message")
 									continue
 								}
 							} else {
 								// Protobuf decoded but no readable strings found
 								jsonParseFailures++
+								loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, "decoded as protobuf but no readable strings extracted")
 								if i < 5 {
-									LogWarn("Blob %d (key='%s'): Decoded as protobuf but no readable strings extracted", i+1, blob.Key)
+									LogDebug("Blob %d (key='%s'): Decoded as protobuf but no readable strings extracted", i+1, blob.Key)
 								}
 								continue
 							}
@@ -441,39 +491,28 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 							// Not protobuf - try parsing as text message format (text$uuid)
 							// This handles cursor-agent's user message format: "hello$027f8b2f-d09c-4a69-98b0-b53f0118605d"
 							if bubble := parseTextMessageFormat(blob.Key, blob.Value, sessionID); bubble != nil {
-								bubbles[bubble.BubbleID] = bubble
-								LogInfo("Blob %d parsed as text message format (user message): bubbleId='%s', text='%s', chatId='%s'", i+1, bubble.BubbleID, bubble.Text, bubble.ChatID)
+								addBubble(blob, bubble)
+								LogDebugAttrs("Parsed text$uuid user message", "path", dbPath, "key", blob.Key, "session", bubble.ChatID, "bubble", bubble.BubbleID, "preview", logPreview(bubble.Text, 200))
 								continue
 							} else {
 								// Log that we tried but failed to parse as text format
 								// Only log if value appears to be readable (not binary garbage)
 								if i < 5 && isReadableText(blob.Value) {
-									valuePreview := blob.Value
-									if len(valuePreview) > 100 {
-										valuePreview = valuePreview[:100] + "..."
-									}
-									LogInfo("Blob %d: tried text message format but didn't match pattern. Value preview: %s", i+1, valuePreview)
+									valuePreview := logPreview(blob.Value, 100)
+									LogDebugAttrs("Blob is not a text$uuid message", "path", dbPath, "key", blob.Key, "preview", valuePreview)
 								}
 								// Not a text message format - the value might be a reference or in a different format
 								// Log detailed info for first few failures to understand the format
 								jsonParseFailures++
+								loadWarnings.Add(WarningBlobParse, dbPath, blob.Key, fmt.Sprintf("failed JSON parse: %v", err))
 								if i < 10 {
-									valuePreview := blob.Value
 									fullValue := blob.Value
-									if len(valuePreview) > 200 {
-										valuePreview = valuePreview[:200] + "..."
-									}
-									LogWarn("Blob %d (key='%s', key_len=%d) failed JSON parse: %v", i+1, blob.Key, len(blob.Key), err)
-									LogInfo("  Value (len=%d): %s", len(fullValue), valuePreview)
-									LogInfo("  Key looks like hash: %v", isHashLike(blob.Key))
-									// Check if value looks like a path or reference
-									if strings.HasPrefix(fullValue, "/") || strings.Contains(fullValue, "$") {
-										LogInfo("  Value appears to be a path/reference, not JSON data")
-									}
-									// Check if there's a { in the value that might indicate JSON
-									if bytes.Contains(valueBytes, []byte("{")) {
-										LogInfo("  Value contains '{' but extraction failed - JSON might be incomplete or malformed")
-									}
+									LogDebugAttrs("Blob failed JSON parse", "path", dbPath, "key", blob.Key, "error", err,
+										"length", len(fullValue), "preview", logPreview(fullValue, 200),
+										"hash_key", isHashLike(blob.Key),
+										// A path or reference value, or a '{' that failed extraction, hints at the format
+										"reference", strings.HasPrefix(fullValue, "/") || strings.Contains(fullValue, "$"),
+										"contains_brace", bytes.Contains(valueBytes, []byte("{")))
 								}
 								continue
 							}
@@ -489,24 +528,25 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 			for k := range data {
 				keys = append(keys, k)
 			}
-			LogInfo("Blob %d (key='%s') parsed successfully. Available fields: %v", i+1, blob.Key, keys)
+			LogDebugAttrs("Parsed blob", "path", dbPath, "key", blob.Key, "fields", keys)
 		}
 
 		// Check if it's a bubble (has bubbleId)
 		if _, ok := data["bubbleId"].(string); ok {
 			bubble, err := parseBubbleFromData(blob.Key, data, sessionID)
 			if err == nil {
-				bubbles[bubble.BubbleID] = bubble
+				addBubble(blob, bubble)
 			}
 		} else if id, ok := data["id"].(string); ok {
 			// Check if it's a message format (has id, role, content) - cursor-agent format
 			if role, hasRole := data["role"].(string); hasRole {
 				bubble, err := parseMessageToBubble(blob.Key, id, role, data, sessionID)
 				if err == nil {
-					bubbles[bubble.BubbleID] = bubble
-					LogInfo("Blob %d converted message (id='%s', role='%s') to bubble (bubbleId='%s')", i+1, id, role, bubble.BubbleID)
+					addBubble(blob, bubble)
+					LogDebugAttrs("Converted message to bubble", "path", dbPath, "key", blob.Key, "session", sessionID, "message", id, "role", role, "bubble", bubble.BubbleID)
 				} else {
-					LogWarn("Blob %d failed to convert message to bubble: %v", i+1, err)
+					LogDebugAttrs("Failed to convert message to bubble", "path", dbPath, "key", blob.Key, "session", sessionID, "error", err)
+					loadWarnings.Add(WarningMessage, dbPath, blob.Key, fmt.Sprintf("failed to convert message to bubble: %v", err))
 				}
 			}
 		} else if role, hasRole := data["role"].(string); hasRole {
@@ -518,10 +558,11 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 			}
 			bubble, err := parseMessageToBubble(blob.Key, generatedID, role, data, sessionID)
 			if err == nil {
-				bubbles[bubble.BubbleID] = bubble
-				LogInfo("Blob %d converted message (no id, role='%s') to bubble (bubbleId='%s')", i+1, role, bubble.BubbleID)
+				addBubble(blob, bubble)
+				LogDebugAttrs("Converted message without an ID to bubble", "path", dbPath, "key", blob.Key, "session", sessionID, "role", role, "bubble", bubble.BubbleID)
 			} else {
-				LogWarn("Blob %d failed to convert message to bubble: %v", i+1, err)
+				LogDebugAttrs("Failed to convert message to bubble", "path", dbPath, "key", blob.Key, "session", sessionID, "error", err)
+				loadWarnings.Add(WarningMessage, dbPath, blob.Key, fmt.Sprintf("failed to convert message to bubble: %v", err))
 			}
 		}
 
@@ -529,32 +570,44 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 		if composerID, ok := data["composerId"].(string); ok {
 			composer, err := parseComposerFromData(blob.Key, data)
 			if err != nil {
-				LogWarn("Failed to parse composer from blob key %s: %v", blob.Key, err)
+				LogDebugAttrs("Failed to parse composer", "path", dbPath, "key", blob.Key, "error", err)
+				loadWarnings.Add(WarningComposer, dbPath, blob.Key, fmt.Sprintf("failed to parse composer: %v", err))
+				warnings = append(warnings, fmt.Sprintf("failed to parse composer from blob %s: %v", blob.Key, err))
 				continue
 			}
 			if composer.ComposerID == "" {
-				LogWarn("Composer parsed but missing composerId. Blob key: %s", blob.Key)
+				LogDebugAttrs("Composer parsed but missing composerId", "path", dbPath, "key", blob.Key)
+				loadWarnings.Add(WarningComposer, dbPath, blob.Key, "composer is missing its composerId")
 				continue
 			}
 			composer.ComposerID = composerID
 			headerCount := len(composer.FullConversationHeadersOnly)
-			LogInfo("Parsed composer %s: %d headers, name='%s'", composer.ComposerID, headerCount, composer.Name)
+			LogDebugAttrs("Parsed composer", "path", dbPath, "key", blob.Key, "session", composer.ComposerID, "headers", headerCount, "name", composer.Name)
 			composers = append(composers, composer)
 		}
 	}
 
 	if jsonParseFailures > 0 {
-		LogWarn("Failed to parse %d/%d blobs as JSON", jsonParseFailures, len(blobs))
+		LogDebugAttrs("Failed to parse blobs as JSON", "path", dbPath, "failed", jsonParseFailures, "blobs", len(blobs))
+		warnings = append(warnings, fmt.Sprintf("failed to parse %d/%d blobs as JSON", jsonParseFailures, len(blobs)))
 	}
+	RecordParsed(len(blobs), jsonParseFailures)
+	recordBlobParseFailures(loadWarnings)
 
 	// Extract session-level metadata from meta table (key="0" contains session metadata)
 	var sessionCreatedAt int64 = 0
 	var sessionAgentID string
 	var sessionName string
+	var sessionParentID string
+	var sessionFields map[string]interface{}
 
 	// Process meta - may contain context or additional metadata
 	metaJsonParseFailures := 0
 	for i, entry := range meta {
+		if decoded, encoding := decodeTextValue(entry.Value); encoding != "" {
+			LogDebug("Meta %d (key='%s') was %s text, converted to UTF-8", i+1, entry.Key, encoding)
+			entry.Value = decoded
+		}
 		var data map[string]interface{}
 		valueBytes := []byte(entry.Value)
 
@@ -564,32 +617,28 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 			decoded, decodeErr := tryBase64Decode(entry.Value)
 			if decodeErr == nil {
 				if jsonErr := json.Unmarshal(decoded, &data); jsonErr == nil {
-					LogInfo("Meta %d (key='%s') was base64 encoded, decoded successfully", i+1, entry.Key)
+					LogDebugAttrs("Decoded base64 meta entry", "path", dbPath, "key", entry.Key)
 				} else {
 					// Base64 decoded but not JSON - try hex decode
 					hexDecoded, hexErr := tryHexDecode(entry.Value)
 					if hexErr == nil {
 						if jsonErr := json.Unmarshal(hexDecoded, &data); jsonErr == nil {
-							LogInfo("Meta %d (key='%s') was hex encoded, decoded successfully", i+1, entry.Key)
+							LogDebugAttrs("Decoded hex meta entry", "path", dbPath, "key", entry.Key)
 						} else {
 							metaJsonParseFailures++
+							loadWarnings.Add(WarningMetaParse, dbPath, entry.Key, fmt.Sprintf("failed JSON parse (tried base64 and hex): %v", jsonErr))
 							if i < 5 {
-								valuePreview := entry.Value
-								if len(valuePreview) > 100 {
-									valuePreview = valuePreview[:100] + "..."
-								}
-								LogWarn("Meta %d (key='%s') failed JSON parse (tried base64 and hex): %v. Value preview: %s", i+1, entry.Key, jsonErr, valuePreview)
+								valuePreview := logPreview(entry.Value, 100)
+								LogDebug("Meta %d (key='%s') failed JSON parse (tried base64 and hex): %v. Value preview: %s", i+1, entry.Key, jsonErr, valuePreview)
 							}
 							continue
 						}
 					} else {
 						metaJsonParseFailures++
+						loadWarnings.Add(WarningMetaParse, dbPath, entry.Key, fmt.Sprintf("failed JSON parse (tried base64 too): %v", jsonErr))
 						if i < 5 {
-							valuePreview := entry.Value
-							if len(valuePreview) > 100 {
-								valuePreview = valuePreview[:100] + "..."
-							}
-							LogWarn("Meta %d (key='%s') failed JSON parse (tried base64 too): %v. Value preview: %s", i+1, entry.Key, jsonErr, valuePreview)
+							valuePreview := logPreview(entry.Value, 100)
+							LogDebug("Meta %d (key='%s') failed JSON parse (tried base64 too): %v. Value preview: %s", i+1, entry.Key, jsonErr, valuePreview)
 						}
 						continue
 					}
@@ -599,33 +648,24 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 				hexDecoded, hexErr := tryHexDecode(entry.Value)
 				if hexErr == nil {
 					if jsonErr := json.Unmarshal(hexDecoded, &data); jsonErr == nil {
-						LogInfo("Meta %d (key='%s') was hex encoded, decoded successfully", i+1, entry.Key)
+						LogDebugAttrs("Decoded hex meta entry", "path", dbPath, "key", entry.Key)
 					} else {
 						metaJsonParseFailures++
+						loadWarnings.Add(WarningMetaParse, dbPath, entry.Key, fmt.Sprintf("failed JSON parse (tried hex): %v", jsonErr))
 						if i < 10 {
-							valuePreview := entry.Value
-							fullValue := entry.Value
-							if len(valuePreview) > 200 {
-								valuePreview = valuePreview[:200] + "..."
-							}
-							LogWarn("Meta %d (key='%s', key_len=%d) failed JSON parse (tried hex): %v", i+1, entry.Key, len(entry.Key), jsonErr)
-							LogInfo("  Value (len=%d): %s", len(fullValue), valuePreview)
+							LogDebugAttrs("Meta entry failed JSON parse (tried hex)", "path", dbPath, "key", entry.Key, "error", jsonErr,
+								"length", len(entry.Value), "preview", logPreview(entry.Value, 200))
 						}
 						continue
 					}
 				} else {
 					metaJsonParseFailures++
+					loadWarnings.Add(WarningMetaParse, dbPath, entry.Key, fmt.Sprintf("failed JSON parse: %v", err))
 					if i < 10 {
-						valuePreview := entry.Value
 						fullValue := entry.Value
-						if len(valuePreview) > 200 {
-							valuePreview = valuePreview[:200] + "..."
-						}
-						LogWarn("Meta %d (key='%s', key_len=%d) failed JSON parse: %v", i+1, entry.Key, len(entry.Key), err)
-						LogInfo("  Value (len=%d): %s", len(fullValue), valuePreview)
-						if strings.HasPrefix(fullValue, "/") || strings.Contains(fullValue, "$") {
-							LogInfo("  Value appears to be a path/reference, not JSON data")
-						}
+						LogDebugAttrs("Meta entry failed JSON parse", "path", dbPath, "key", entry.Key, "error", err,
+							"length", len(fullValue), "preview", logPreview(fullValue, 200),
+							"reference", strings.HasPrefix(fullValue, "/") || strings.Contains(fullValue, "$"))
 					}
 					continue
 				}
@@ -638,7 +678,7 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 			for k := range data {
 				keys = append(keys, k)
 			}
-			LogInfo("Meta %d (key='%s') parsed successfully. Available fields: %v", i+1, entry.Key, keys)
+			LogDebugAttrs("Parsed meta entry", "path", dbPath, "key", entry.Key, "fields", keys)
 		}
 
 		// Extract session-level metadata from meta entry with key "0"
@@ -646,23 +686,35 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 			// Extract createdAt (session creation timestamp)
 			if ts, ok := data["createdAt"].(float64); ok {
 				sessionCreatedAt = int64(ts)
-				LogInfo("Meta: Extracted session createdAt: %d (from meta key='0')", sessionCreatedAt)
+				LogDebugAttrs("Read session creation time", "path", dbPath, "session", sessionID, "created_at", sessionCreatedAt)
 			} else if ts, ok := data["createdAt"].(int64); ok {
 				sessionCreatedAt = ts
-				LogInfo("Meta: Extracted session createdAt: %d (from meta key='0')", sessionCreatedAt)
+				LogDebugAttrs("Read session creation time", "path", dbPath, "session", sessionID, "created_at", sessionCreatedAt)
 			}
 
 			// Extract agentId (session ID)
 			if agentID, ok := data["agentId"].(string); ok {
 				sessionAgentID = agentID
-				LogInfo("Meta: Extracted session agentId: %s (from meta key='0')", sessionAgentID)
+				LogDebugAttrs("Read session agent ID", "path", dbPath, "session", sessionID, "agent_id", sessionAgentID)
 			}
 
 			// Extract name (session name)
 			if name, ok := data["name"].(string); ok {
 				sessionName = name
-				LogInfo("Meta: Extracted session name: %s (from meta key='0')", sessionName)
+				LogDebugAttrs("Read session name", "path", dbPath, "session", sessionID, "name", sessionName)
+			}
+
+			// Extract the session a resumed session continues
+			for _, key := range parentSessionKeys {
+				if parentID, ok := data[key].(string); ok && parentID != "" {
+					sessionParentID = parentID
+					LogDebugAttrs("Read parent session", "path", dbPath, "session", sessionID, "parent", sessionParentID, "field", key)
+					break
+				}
 			}
+
+			// Extract the fields the normalizer rules map into session metadata
+			sessionFields = normalizerRules.composerFields(data)
 		}
 
 		// Check if it's a message context
@@ -684,39 +736,86 @@ func LoadSessionFromStoreDB(dbPath string) (map[string]*RawBubble, []*RawCompose
 		}
 	}
 
+	// Record where each bubble came from so exported messages can be traced back
+	for id, bubble := range bubbles {
+		if bubble.Provenance == nil {
+			bubble.Provenance = &Provenance{}
+		}
+		bubble.Provenance.SourcePath = dbPath
+		if path, ok := fileBubbles[id]; ok {
+			bubble.Provenance.SourcePath = path
+		}
+		bubble.Provenance.Backend = BackendAgentStorage
+	}
+	addMessageFileHeaders(composers, bubbles, fileBubbles)
+
 	// Apply session createdAt to bubbles that don't have timestamps
 	if sessionCreatedAt > 0 {
 		for bubbleID, bubble := range bubbles {
 			if bubble.Timestamp == 0 {
 				bubble.Timestamp = sessionCreatedAt
 				bubbles[bubbleID] = bubble
-				LogInfo("Applied session createdAt (%d) to bubble %s (was missing timestamp)", sessionCreatedAt, bubbleID)
+				LogDebugAttrs("Applied session creation time to bubble without a timestamp", "path", dbPath, "session", sessionID, "bubble", bubbleID, "created_at", sessionCreatedAt)
 			}
 		}
 	}
 
+	// The directory the store.db is in names the session's workspace
+	if hash := extractWorkspaceHashFromPath(dbPath); hash != "" {
+		for i := range composers {
+			composers[i].WorkspaceHash = hash
+		}
+	}
+
 	// Apply session metadata to composers
-	if sessionCreatedAt > 0 || sessionName != "" {
+	if sessionCreatedAt > 0 || sessionName != "" || sessionParentID != "" || len(sessionFields) > 0 {
 		for i := range composers {
+			composers[i].Fields = mergeFields(composers[i].Fields, sessionFields)
+			if sessionParentID != "" && composers[i].ParentID == "" {
+				composers[i].ParentID = sessionParentID
+			}
 			if sessionCreatedAt > 0 && composers[i].CreatedAt == 0 {
 				composers[i].CreatedAt = sessionCreatedAt
-				LogInfo("Applied session createdAt (%d) to composer %s", sessionCreatedAt, composers[i].ComposerID)
+				LogDebugAttrs("Applied session creation time to composer", "path", dbPath, "session", composers[i].ComposerID, "created_at", sessionCreatedAt)
 			}
 			if sessionName != "" && composers[i].Name == "" {
 				composers[i].Name = sessionName
-				LogInfo("Applied session name (%s) to composer %s", sessionName, composers[i].ComposerID)
+				LogDebugAttrs("Applied session name to composer", "path", dbPath, "session", composers[i].ComposerID, "name", sessionName)
 			}
 		}
 	}
 
 	if metaJsonParseFailures > 0 {
-		LogWarn("Failed to parse %d/%d meta entries as JSON", metaJsonParseFailures, len(meta))
-	}
-
-	LogInfo("LoadSessionFromStoreDB summary: %d blobs queried, %d meta queried, %d bubbles extracted, %d composers extracted, %d contexts extracted",
-		len(blobs), len(meta), len(bubbles), len(composers), len(contexts))
-
-	return bubbles, composers, contexts, nil
+		LogDebugAttrs("Failed to parse meta entries as JSON", "path", dbPath, "failed", metaJsonParseFailures, "meta", len(meta))
+		warnings = append(warnings, fmt.Sprintf("failed to parse %d/%d meta entries as JSON", metaJsonParseFailures, len(meta)))
+	}
+	RecordParsed(len(meta), metaJsonParseFailures)
+	if len(composers) == 0 {
+		warnings = append(warnings, "no composer found")
+	}
+
+	sessionIDs := make([]string, 0, len(composers))
+	for _, composer := range composers {
+		sessionIDs = append(sessionIDs, composer.ComposerID)
+	}
+	RecordStoreDB(StoreDBStats{
+		Path:              dbPath,
+		Schema:            schema.Name,
+		Blobs:             len(blobs),
+		BlobParseFailures: jsonParseFailures,
+		MetaEntries:       len(meta),
+		MetaParseFailures: metaJsonParseFailures,
+		Bubbles:           len(bubbles),
+		Composers:         len(composers),
+		Contexts:          len(contexts),
+		SessionIDs:        sessionIDs,
+		Warnings:          warnings,
+	})
+
+	LogDebugAttrs("Loaded session from store.db", "path", dbPath, "session", sessionID,
+		"blobs", len(blobs), "meta", len(meta), "bubbles", len(bubbles), "composers", len(composers), "contexts", len(contexts))
+
+	return bubbles, composers, contexts, loadWarnings, nil
 }
 
 // LoadAllSessionsFromAgentStorage loads all sessions from all store.db files
@@ -725,11 +824,33 @@ func (r *AgentStorageReader) LoadAllSessionsFromAgentStorage() (map[string]*RawB
 	var allComposers []*RawComposer
 	allContexts := make(map[string][]*MessageContext)
 
-	for _, dbPath := range r.storeDBPaths {
-		bubbles, composers, contexts, err := LoadSessionFromStoreDB(dbPath)
-		if err != nil {
-			// Log error but continue with other files
-			LogWarn("Failed to load session from %s: %v", dbPath, err)
+	// Parse the store.db files in parallel, then merge them in order, so that a bubble
+	// found in several files is taken from the same one every time
+	type storeDBResult struct {
+		bubbles      map[string]*RawBubble
+		composers    []*RawComposer
+		contexts     map[string][]*MessageContext
+		loadWarnings *Warnings
+		err          error
+	}
+	results := make([]storeDBResult, len(r.storeDBPaths))
+	steps := make([]ProgressStep, len(r.storeDBPaths))
+	for i, dbPath := range r.storeDBPaths {
+		steps[i] = ProgressStep{
+			Message: extractSessionIDFromPath(dbPath),
+			Fn: func() error {
+				result := &results[i]
+				result.bubbles, result.composers, result.contexts, result.loadWarnings, result.err = LoadSessionFromStoreDB(dbPath)
+				return result.err
+			},
+		}
+	}
+	// Failures are logged below, with the path of their store.db
+	_ = ShowParallelProgress(context.Background(), fmt.Sprintf("Parsing %d cursor-agent session(s)", len(steps)), steps, ParseConcurrency())
+
+	for i, dbPath := range r.storeDBPaths {
+		bubbles, composers, contexts := results[i].bubbles, results[i].composers, results[i].contexts
+		if !recordStoreDBLoad(dbPath, results[i].loadWarnings, results[i].err) {
 			continue
 		}
 
@@ -740,7 +861,7 @@ func (r *AgentStorageReader) LoadAllSessionsFromAgentStorage() (map[string]*RawB
 
 		// Append composers
 		allComposers = append(allComposers, composers...)
-		LogInfo("Loaded from %s: %d bubbles, %d composers, %d context entries", dbPath, len(bubbles), len(composers), len(contexts))
+		LogDebugAttrs("Loaded store.db", "path", dbPath, "bubbles", len(bubbles), "composers", len(composers), "contexts", len(contexts))
 
 		// Merge contexts
 		for composerID, ctxList := range contexts {
@@ -752,8 +873,124 @@ func (r *AgentStorageReader) LoadAllSessionsFromAgentStorage() (map[string]*RawB
 	return allBubbles, allComposers, allContexts, nil
 }
 
+// recordStoreDBLoad reports the outcome of loading a store.db with LoadSessionFromStoreDB:
+// the error it failed with, or the records it skipped. It returns false when the store.db
+// could not be loaded; other files are still read.
+func recordStoreDBLoad(dbPath string, loadWarnings *Warnings, err error) bool {
+	if err != nil {
+		// A schema this version can't read is an error, since its sessions would
+		// otherwise go missing silently
+		var schemaErr *UnknownSchemaError
+		if errors.As(err, &schemaErr) {
+			LogError("%v", err)
+		} else {
+			LogWarn("Failed to load session from %s: %v", dbPath, err)
+		}
+		RecordStoreDB(StoreDBStats{Path: dbPath, Error: err.Error()})
+		failed := NewWarnings()
+		failed.Add(WarningDatabase, dbPath, "", err.Error())
+		RecordWarnings(failed)
+		return false
+	}
+	if loadWarnings.Len() > 0 {
+		LogWarn("Skipped records in %s: %s", dbPath, loadWarnings.Summary())
+		if Verbosity() >= VerbosityTrace {
+			for _, warning := range loadWarnings.List() {
+				LogDebug("Skipped %s", warning)
+			}
+		}
+	}
+	RecordWarnings(loadWarnings)
+	return true
+}
+
+// LoadSessionMetadataFromStoreDB reads the name, creation time and parent of the session
+// in a store.db from its meta table, without reading the blobs. The composer has no
+// conversation headers.
+func LoadSessionMetadataFromStoreDB(dbPath string) (*RawComposer, error) {
+	db, release, err := agentDBs.Acquire(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store.db: %w", err)
+	}
+	defer release()
+
+	schema, err := DetectAgentSchema(db, dbPath)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := schema.QueryMeta(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s metadata: %w", schema.Name, err)
+	}
+
+	composer := &RawComposer{ComposerID: extractSessionIDFromPath(dbPath), WorkspaceHash: extractWorkspaceHashFromPath(dbPath)}
+	for _, entry := range meta {
+		if entry.Key != "0" {
+			continue
+		}
+		data, ok := decodeMetaValue(entry.Value)
+		if !ok {
+			LogWarn("Failed to parse session metadata in %s", dbPath)
+			break
+		}
+		if ts, ok := data["createdAt"].(float64); ok {
+			composer.CreatedAt = int64(ts)
+		}
+		if name, ok := data["name"].(string); ok {
+			composer.Name = name
+		}
+		for _, key := range parentSessionKeys {
+			if parentID, ok := data[key].(string); ok && parentID != "" {
+				composer.ParentID = parentID
+				break
+			}
+		}
+	}
+	return composer, nil
+}
+
+// LoadAllSessionMetadata reads the metadata of the session in every store.db, skipping
+// files that fail to open
+func (r *AgentStorageReader) LoadAllSessionMetadata() []*RawComposer {
+	composers := make([]*RawComposer, 0, len(r.storeDBPaths))
+	for _, dbPath := range r.storeDBPaths {
+		composer, err := LoadSessionMetadataFromStoreDB(dbPath)
+		var schemaErr *UnknownSchemaError
+		if errors.As(err, &schemaErr) {
+			LogError("%v", err)
+			continue
+		}
+		if err != nil {
+			LogWarn("Failed to load session metadata from %s: %v", dbPath, err)
+			continue
+		}
+		composers = append(composers, composer)
+	}
+	return composers
+}
+
 // Helper functions
 
+// decodeMetaValue parses a meta value stored as JSON, or as base64 or hex encoded JSON
+func decodeMetaValue(value string) (map[string]interface{}, bool) {
+	var data map[string]interface{}
+	value, _ = decodeTextValue(value)
+	if json.Unmarshal([]byte(value), &data) == nil {
+		return data, true
+	}
+	for _, decode := range []func(string) ([]byte, error){tryBase64Decode, tryHexDecode} {
+		decoded, err := decode(value)
+		if err != nil {
+			continue
+		}
+		decoded, _ = decodeTextEncoding(decoded)
+		if json.Unmarshal(decoded, &data) == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
 func containsString(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -770,6 +1007,17 @@ func extractSessionIDFromPath(path string) string {
 	return sessionID
 }
 
+// extractWorkspaceHashFromPath returns the {hash} directory of a store.db at
+// ~/.cursor/chats/{hash}/{session-id}/store.db, or "" when the directory is not named like
+// a hash, such as a store.db copied out of the chats directory
+func extractWorkspaceHashFromPath(path string) string {
+	hash := filepath.Base(filepath.Dir(filepath.Dir(path)))
+	if !isHashLike(hash) {
+		return ""
+	}
+	return hash
+}
+
 // normalizeTimestamp converts a timestamp to milliseconds
 // If the timestamp is less than 1e12 (1 trillion), it's assumed to be in seconds and converted to milliseconds
 // Otherwise, it's assumed to already be in milliseconds
@@ -785,7 +1033,9 @@ func normalizeTimestamp(ts int64) int64 {
 }
 
 func parseBubbleFromData(key string, data map[string]interface{}, sessionID string) (*RawBubble, error) {
-	bubble := &RawBubble{}
+	bubble := &RawBubble{
+		Provenance: &Provenance{BlobKey: key},
+	}
 
 	// Extract bubbleId
 	if id, ok := data["bubbleId"].(string); ok {
@@ -818,6 +1068,8 @@ func parseBubbleFromData(key string, data map[string]interface{}, sessionID stri
 				codeBlock := CodeBlock{}
 				if lang, ok := cbMap["language"].(string); ok {
 					codeBlock.Language = lang
+				} else if lang, ok := cbMap["languageId"].(string); ok {
+					codeBlock.Language = lang
 				}
 				if content, ok := cbMap["content"].(string); ok {
 					codeBlock.Content = content
@@ -980,18 +1232,45 @@ func parseTextMessageFormat(key, value, sessionID string) *RawBubble {
 		}
 	}
 
-	// Create user bubble (type=1 for user messages)
+	// Create user bubble (type=1 for user messages). The format carries no timestamp; the
+	// session's createdAt is applied once the meta table is read.
 	bubble := &RawBubble{
-		BubbleID:  bubbleID,
-		ChatID:    sessionID,
-		Type:      1, // User message
-		Text:      text,
-		Timestamp: time.Now().UnixMilli(), // Use current time if not available
+		BubbleID: bubbleID,
+		ChatID:   sessionID,
+		Type:     1, // User message
+		Text:     text,
+		Provenance: &Provenance{
+			BlobKey:  key,
+			Strategy: "text$uuid",
+		},
 	}
 
 	return bubble
 }
 
+// messageToolName returns the name of the tool whose output a cursor-agent tool message
+// holds, from the message or its first content item that names one
+func messageToolName(data map[string]interface{}) string {
+	for _, field := range []string{"name", "toolName"} {
+		if name, ok := data[field].(string); ok && name != "" {
+			return name
+		}
+	}
+	content, _ := data["content"].([]interface{})
+	for _, item := range content {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range []string{"name", "toolName"} {
+			if name, ok := itemMap[field].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
 // parseMessageToBubble converts a message format (id, role, content) to a RawBubble
 // This handles cursor-agent's message format where messages have id, role, and content fields
 func parseMessageToBubble(key, id, role string, data map[string]interface{}, sessionID string) (*RawBubble, error) {
@@ -1006,28 +1285,47 @@ func parseMessageToBubble(key, id, role string, data map[string]interface{}, ses
 	}
 
 	bubble := &RawBubble{
-		BubbleID: bubbleID,
-		ChatID:   sessionID,
+		BubbleID:   bubbleID,
+		ChatID:     sessionID,
+		Provenance: &Provenance{BlobKey: key},
 	}
 
-	// Map role to type: "user" = 1, "assistant" = 2
+	// Map role to type: "user" = 1, "assistant" = 2, "tool" = 3 or 4 (terminal), "system" = 5
 	switch role {
 	case "user":
-		bubble.Type = 1
+		bubble.Type = MessageTypeUser
 	case "assistant":
-		bubble.Type = 2
+		bubble.Type = MessageTypeAssistant
+	case "tool":
+		bubble.Type = toolMessageType(messageToolName(data))
+	case "system":
+		bubble.Type = MessageTypeSystem
 	default:
 		// Default to assistant if unknown
-		bubble.Type = 2
+		bubble.Type = MessageTypeAssistant
 	}
 
 	// Extract text from content array
 	if content, ok := data["content"].([]interface{}); ok {
-		var textParts []string
+		var textParts, thinkingParts []string
 		for _, item := range content {
 			if itemMap, ok := item.(map[string]interface{}); ok {
 				itemType, _ := itemMap["type"].(string)
 
+				// Keep reasoning apart from the text
+				if itemType == "reasoning" || itemType == "thinking" {
+					if text, ok := itemMap["text"].(string); ok && text != "" {
+						thinkingParts = append(thinkingParts, text)
+						continue
+					}
+				}
+
+				// Keep images as attachments rather than their base64 data as text
+				if img, ok := agentImage(itemType, itemMap); ok {
+					bubble.Images = append(bubble.Images, img)
+					continue
+				}
+
 				// Handle tool calls
 				if itemType == "tool_call" || itemType == "function_call" {
 					toolCallParts := []string{"[Tool Call]"}
@@ -1128,6 +1426,9 @@ func parseMessageToBubble(key, id, role string, data map[string]interface{}, ses
 		if len(textParts) > 0 {
 			bubble.Text = strings.Join(textParts, "\n\n")
 		}
+		if len(thinkingParts) > 0 {
+			bubble.Thinking = &BubbleThinking{Text: strings.Join(thinkingParts, "\n\n")}
+		}
 	}
 
 	// Extract timestamp if available
@@ -1147,6 +1448,52 @@ func parseMessageToBubble(key, id, role string, data map[string]interface{}, ses
 	return bubble, nil
 }
 
+// agentImage returns the image of a cursor-agent content item of type image or image_url.
+// The image may be held as base64 data or a data: URL, directly or under source (as
+// Anthropic's API has it) or image_url (as OpenAI's does), or as the path of a file.
+// Images at remote URLs are not downloaded, so they are only marked in the text.
+func agentImage(itemType string, item map[string]interface{}) (BubbleImage, bool) {
+	if itemType != "image" && itemType != "image_url" && itemType != "input_image" {
+		return BubbleImage{}, false
+	}
+
+	var img BubbleImage
+	fields := item
+	if source, ok := item["source"].(map[string]interface{}); ok {
+		fields = source
+	} else if imageURL, ok := item["image_url"].(map[string]interface{}); ok {
+		fields = imageURL
+	}
+	for _, field := range []string{"mimeType", "mime_type", "mediaType", "media_type"} {
+		if mimeType, ok := fields[field].(string); ok && mimeType != "" {
+			img.MimeType = mimeType
+			break
+		}
+		if mimeType, ok := item[field].(string); ok && mimeType != "" {
+			img.MimeType = mimeType
+			break
+		}
+	}
+
+	for _, field := range []string{"data", "image", "image_url", "url", "path"} {
+		value, ok := fields[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://"):
+			LogDebug("Leaving out image at remote URL %s", value)
+			return BubbleImage{}, false
+		case field == "path" || strings.HasPrefix(value, "file://") || filepath.IsAbs(value):
+			img.Path = value
+		default:
+			img.Data = value
+		}
+		return img, true
+	}
+	return BubbleImage{}, false
+}
+
 func parseComposerFromData(key string, data map[string]interface{}) (*RawComposer, error) {
 	composer := &RawComposer{}
 
@@ -1160,6 +1507,9 @@ func parseComposerFromData(key string, data map[string]interface{}) (*RawCompose
 		composer.Name = name
 	}
 
+	// Extract the fields the normalizer rules map into session metadata
+	composer.Fields = normalizerRules.composerFields(data)
+
 	// Extract fullConversationHeadersOnly
 	if headers, ok := data["fullConversationHeadersOnly"].([]interface{}); ok {
 		for _, h := range headers {
@@ -1182,7 +1532,7 @@ func parseComposerFromData(key string, data map[string]interface{}) (*RawCompose
 	if len(composer.FullConversationHeadersOnly) == 0 {
 		// Try legacy format: conversation[] array
 		if convArray, ok := data["conversation"].([]interface{}); ok && len(convArray) > 0 {
-			LogInfo("Composer %s: Using legacy conversation[] format (found %d entries)", composer.ComposerID, len(convArray))
+			LogDebugAttrs("Reading legacy conversation[] format", "key", key, "session", composer.ComposerID, "entries", len(convArray))
 			// Convert legacy format to headers
 			for _, entry := range convArray {
 				if entryMap, ok := entry.(map[string]interface{}); ok {
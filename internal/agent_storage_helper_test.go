@@ -312,6 +312,40 @@ func TestParseMessageToBubble(t *testing.T) {
 			wantType:  2,
 			wantText:  "Hello",
 		},
+		{
+			name: "tool message",
+			key:  "key12345678",
+			id:   "msg7",
+			role: "tool",
+			data: map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "tool", "name": "read_file", "content": "package main"}},
+			},
+			sessionID: "session1",
+			wantType:  MessageTypeTool,
+			wantText:  "[Tool Response]\nTool: read_file\nContent: package main",
+		},
+		{
+			name: "terminal output",
+			key:  "key12345678",
+			id:   "msg8",
+			role: "tool",
+			data: map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "tool", "name": "run_terminal_cmd", "content": "ok"}},
+			},
+			sessionID: "session1",
+			wantType:  MessageTypeTerminal,
+			wantText:  "[Tool Response]\nTool: run_terminal_cmd\nContent: ok",
+		},
+		{
+			name:      "system prompt",
+			key:       "key12345678",
+			id:        "msg9",
+			role:      "system",
+			data:      map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": "Be brief"}}},
+			sessionID: "session1",
+			wantType:  MessageTypeSystem,
+			wantText:  "Be brief",
+		},
 		{
 			name:      "short key",
 			key:       "key",
@@ -346,3 +380,55 @@ func TestParseMessageToBubble(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMessageToBubble_Reasoning(t *testing.T) {
+	data := map[string]interface{}{
+		"content": []interface{}{
+			map[string]interface{}{"type": "reasoning", "text": "The test is flaky"},
+			map[string]interface{}{"type": "text", "text": "Retry it"},
+		},
+	}
+	got, err := parseMessageToBubble("key12345", "msg1", "assistant", data, "session1")
+	if err != nil {
+		t.Fatalf("parseMessageToBubble() error = %v", err)
+	}
+	if got.Text != "Retry it" {
+		t.Errorf("parseMessageToBubble() Text = %q, want the text without the reasoning", got.Text)
+	}
+	if got.Thinking == nil || got.Thinking.Text != "The test is flaky" {
+		t.Errorf("parseMessageToBubble() Thinking = %+v, want the reasoning", got.Thinking)
+	}
+}
+
+func TestParseMessageToBubble_Images(t *testing.T) {
+	data := map[string]interface{}{
+		"content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "What is this?"},
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/jpeg;base64,/9j/"}},
+			map[string]interface{}{"type": "input_image", "path": "/tmp/screenshot.png"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/remote.png"}},
+		},
+	}
+	got, err := parseMessageToBubble("key12345", "msg1", "user", data, "session1")
+	if err != nil {
+		t.Fatalf("parseMessageToBubble() error = %v", err)
+	}
+	// The remote image is only marked, as an item of unknown type is
+	if got.Text != "What is this?\n\n[image_url]" {
+		t.Errorf("parseMessageToBubble() Text = %q, want the text without the images' data", got.Text)
+	}
+	want := []BubbleImage{
+		{Data: "iVBORw0KGgo=", MimeType: "image/png"},
+		{Data: "data:image/jpeg;base64,/9j/"},
+		{Path: "/tmp/screenshot.png"},
+	}
+	if len(got.Images) != len(want) {
+		t.Fatalf("parseMessageToBubble() Images = %+v, want %+v", got.Images, want)
+	}
+	for i := range want {
+		if got.Images[i].Path != want[i].Path || got.Images[i].Data != want[i].Data || got.Images[i].MimeType != want[i].MimeType {
+			t.Errorf("image %d = %+v, want %+v", i+1, got.Images[i], want[i])
+		}
+	}
+}
@@ -2,7 +2,11 @@ package internal
 
 import (
 	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/iksnae/cursor-session/testutil"
@@ -172,11 +176,19 @@ func TestLoadSessionFromStoreDB(t *testing.T) {
 	_ = db.Close()
 
 	// Load session
-	bubbles, composers, contexts, err := LoadSessionFromStoreDB(dbPath)
+	ResetParseStats()
+	defer ResetParseStats()
+	bubbles, composers, contexts, _, err := LoadSessionFromStoreDB(dbPath)
 	if err != nil {
 		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
 	}
 
+	dbStats := GetStoreDBStats()
+	if len(dbStats) != 1 || dbStats[0].Path != dbPath || dbStats[0].Blobs != 2 || dbStats[0].Composers != 1 ||
+		len(dbStats[0].SessionIDs) != 1 || dbStats[0].SessionIDs[0] != "composer1" {
+		t.Errorf("GetStoreDBStats() = %+v, want one record for %s with 2 blobs and composer1", dbStats, dbPath)
+	}
+
 	if len(bubbles) == 0 {
 		t.Error("LoadSessionFromStoreDB() returned no bubbles")
 	}
@@ -191,7 +203,7 @@ func TestLoadSessionFromStoreDB(t *testing.T) {
 }
 
 func TestLoadSessionFromStoreDB_Nonexistent(t *testing.T) {
-	bubbles, composers, contexts, err := LoadSessionFromStoreDB("/nonexistent/path/store.db")
+	bubbles, composers, contexts, _, err := LoadSessionFromStoreDB("/nonexistent/path/store.db")
 	if err == nil {
 		t.Error("LoadSessionFromStoreDB() should return error for nonexistent file")
 	}
@@ -306,6 +318,18 @@ func TestExtractSessionIDFromPath(t *testing.T) {
 	}
 }
 
+func TestExtractWorkspaceHashFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/home/user/.cursor/chats/0cc175b9c0f1b6a831c399e269772661/session-abc/store.db": "0cc175b9c0f1b6a831c399e269772661",
+		"/home/user/backup/session-abc/store.db":                                         "",
+		"/store.db":                                                                      "",
+	} {
+		if got := extractWorkspaceHashFromPath(path); got != want {
+			t.Errorf("extractWorkspaceHashFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
 func TestParseBubbleFromData(t *testing.T) {
 	data := map[string]interface{}{
 		"bubbleId":  "bubble1",
@@ -379,3 +403,121 @@ func TestParseContextFromData(t *testing.T) {
 		t.Errorf("parseContextFromData() ComposerID = %q, want %q", context.ComposerID, "composer1")
 	}
 }
+
+func TestLoadSessionMetadataFromStoreDB(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "session-1", "store.db")
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE blobs (key TEXT PRIMARY KEY, value TEXT)`,
+		`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`,
+		// Blobs are not read: an unparseable one must not matter
+		`INSERT INTO blobs (key, value) VALUES ('b1', 'not json')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up database: %v", err)
+		}
+	}
+	meta := hex.EncodeToString([]byte(`{"agentId":"session-1","name":"Fix login","createdAt":1700000000000,"parentAgentId":"session-0"}`))
+	if _, err := db.Exec(`INSERT INTO meta (key, value) VALUES ('0', ?)`, meta); err != nil {
+		t.Fatalf("Failed to insert meta: %v", err)
+	}
+	_ = db.Close()
+
+	ResetParseStats()
+	defer ResetParseStats()
+	composers, err := NewAgentStorage([]string{dbPath}).LoadComposerMetadata()
+	if err != nil {
+		t.Fatalf("LoadComposerMetadata() error = %v", err)
+	}
+	want := RawComposer{ComposerID: "session-1", Name: "Fix login", CreatedAt: 1700000000000, ParentID: "session-0"}
+	if len(composers) != 1 || composers[0].ComposerID != want.ComposerID || composers[0].Name != want.Name ||
+		composers[0].CreatedAt != want.CreatedAt || composers[0].ParentID != want.ParentID {
+		t.Errorf("LoadComposerMetadata() = %+v, want %+v", composers, want)
+	}
+	if stats := GetParseStats(); stats.Records != 0 {
+		t.Errorf("LoadComposerMetadata() parsed %d records, want the blobs left unread", stats.Records)
+	}
+
+	// Backends without a metadata path load their composers as usual
+	merged, err := LoadComposerMetadata(NewMultiBackend(NewAgentStorage([]string{dbPath}), NewFileBackend(createExportDir(t))))
+	if err != nil || len(merged) != 4 {
+		t.Errorf("LoadComposerMetadata(multi) = %d composers, %v, want 4", len(merged), err)
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	defer SetParseConcurrency(0)
+	if got := ParseConcurrency(); got != runtime.NumCPU() {
+		t.Errorf("ParseConcurrency() = %d, want one per CPU by default", got)
+	}
+	SetParseConcurrency(3)
+	if got := ParseConcurrency(); got != 3 {
+		t.Errorf("ParseConcurrency() = %d, want 3", got)
+	}
+	SetParseConcurrency(-1)
+	if got := ParseConcurrency(); got != runtime.NumCPU() {
+		t.Errorf("ParseConcurrency() = %d, want one per CPU when not positive", got)
+	}
+}
+
+func TestLoadSessionFromStoreDB_UTF16(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE blobs (key TEXT PRIMARY KEY, value BLOB)",
+		"CREATE TABLE meta (key TEXT PRIMARY KEY, value BLOB)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	// Rows written by a Windows build: UTF-16LE with and without a byte order mark, and
+	// UTF-8 with one
+	rows := map[string][]byte{
+		"composer1": encodeUTF16(`{"composerId":"composer1","fullConversationHeadersOnly":[{"bubbleId":"bubble1","type":1},{"bubbleId":"bubble2","type":2}]}`, binary.LittleEndian, utf16LEBOM),
+		"bubble1":   encodeUTF16(`{"bubbleId":"bubble1","chatId":"composer1","text":"Pourquoi ça échoue ?","timestamp":1000,"type":1}`, binary.LittleEndian, nil),
+		"bubble2":   append([]byte{0xEF, 0xBB, 0xBF}, `{"bubbleId":"bubble2","chatId":"composer1","text":"Il manque une dépendance.","timestamp":2000,"type":2}`...),
+	}
+	for key, value := range rows {
+		if _, err := db.Exec("INSERT INTO blobs (key, value) VALUES (?, ?)", key, value); err != nil {
+			t.Fatalf("Failed to insert blob: %v", err)
+		}
+	}
+	meta := encodeUTF16(`{"agentId":"composer1","name":"Windows session","createdAt":1000}`, binary.LittleEndian, utf16LEBOM)
+	if _, err := db.Exec("INSERT INTO meta (key, value) VALUES ('0', ?)", meta); err != nil {
+		t.Fatalf("Failed to insert meta: %v", err)
+	}
+	_ = db.Close()
+
+	ResetParseStats()
+	defer ResetParseStats()
+	bubbles, composers, _, warnings, err := LoadSessionFromStoreDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+	if warnings.Len() > 0 {
+		t.Errorf("LoadSessionFromStoreDB() skipped records: %s", warnings.Summary())
+	}
+	for id, text := range map[string]string{"bubble1": "Pourquoi ça échoue ?", "bubble2": "Il manque une dépendance."} {
+		if bubble, ok := bubbles[id]; !ok || bubble.Text != text {
+			t.Errorf("%s = %+v, want text %q", id, bubble, text)
+		}
+	}
+	if len(composers) != 1 || composers[0].Name != "Windows session" {
+		t.Errorf("composers = %+v, want the session named from its UTF-16 metadata", composers)
+	}
+
+	composer, err := LoadSessionMetadataFromStoreDB(dbPath)
+	if err != nil || composer.Name != "Windows session" {
+		t.Errorf("LoadSessionMetadataFromStoreDB() = %+v, %v", composer, err)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults of AgentRunOptions
+const (
+	DefaultAgentTimeout = 15 * time.Second
+	DefaultAgentRetries = 0
+	DefaultWaitForDB    = 10 * time.Second
+)
+
+// ErrAgentAuthentication is returned when cursor-agent cannot run because it is not
+// logged in; running it again does not help
+var ErrAgentAuthentication = errors.New("cursor-agent requires authentication (run 'cursor-agent login' or set CURSOR_API_KEY)")
+
+// IsAgentAuthenticationError reports whether the output of a failed cursor-agent run says
+// it is not logged in
+func IsAgentAuthenticationError(output string) bool {
+	return strings.Contains(output, "Authentication required") ||
+		strings.Contains(output, "login") ||
+		strings.Contains(output, "not authenticated") ||
+		strings.Contains(output, "CURSOR_API_KEY")
+}
+
+// agentPollInterval is how often SeedAgentStorage checks for the store.db of a run
+var agentPollInterval = 250 * time.Millisecond
+
+// AgentRunOptions controls how cursor-agent is run to seed its storage with a session
+type AgentRunOptions struct {
+	// Timeout is how long one run may take before it is stopped
+	Timeout time.Duration
+	// Retries is how many more times cursor-agent is run after a run that fails, other
+	// than for lack of authentication
+	Retries int
+	// WaitForDB is how long to poll the agent storage directories for a store.db once
+	// cursor-agent has run; 0 does not wait
+	WaitForDB time.Duration
+}
+
+// DefaultAgentRunOptions returns the options cursor-agent is run with unless set otherwise
+func DefaultAgentRunOptions() AgentRunOptions {
+	return AgentRunOptions{Timeout: DefaultAgentTimeout, Retries: DefaultAgentRetries, WaitForDB: DefaultWaitForDB}
+}
+
+// Validate reports options that cannot be run with
+func (o AgentRunOptions) Validate() error {
+	switch {
+	case o.Timeout <= 0:
+		return fmt.Errorf("agent timeout must be positive, got %s", o.Timeout)
+	case o.Retries < 0:
+		return fmt.Errorf("agent retries cannot be negative, got %d", o.Retries)
+	case o.WaitForDB < 0:
+		return fmt.Errorf("wait for the database cannot be negative, got %s", o.WaitForDB)
+	}
+	return nil
+}
+
+// RunAgent calls run, which runs cursor-agent, until it succeeds or fails Retries more
+// times, giving each call Timeout. A call that runs out of time counts as a success, as
+// cursor-agent may have created its session before it was stopped. It returns the error
+// of the last call.
+func RunAgent(ctx context.Context, opts AgentRunOptions, run func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= opts.Retries+1; attempt++ {
+		runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		err = run(runCtx)
+		timedOut := runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+		if err == nil || timedOut {
+			return nil
+		}
+		if errors.Is(err, ErrAgentAuthentication) || ctx.Err() != nil {
+			return err
+		}
+		LogWarn("cursor-agent run %d of %d failed: %v", attempt, opts.Retries+1, err)
+	}
+	return err
+}
+
+// SeedAgentStorage runs cursor-agent with RunAgent, then polls the agent storage
+// directories for up to WaitForDB until one holds a store.db, and returns its path. A
+// failed run is still followed by the wait, since cursor-agent may have created its
+// database before failing; its error is returned only when no store.db appears.
+func SeedAgentStorage(ctx context.Context, opts AgentRunOptions, dirs []string, run func(ctx context.Context) error) (string, error) {
+	runErr := RunAgent(ctx, opts, run)
+	if errors.Is(runErr, ErrAgentAuthentication) {
+		return "", runErr
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.WaitForDB)
+	defer cancel()
+	storeDB, err := WaitForAgentStoreDB(waitCtx, dirs, agentPollInterval)
+	if err == nil {
+		return storeDB, nil
+	}
+	if runErr != nil {
+		return "", runErr
+	}
+	return "", fmt.Errorf("%w: no store.db appeared within %s of running cursor-agent", ErrNoStorage, opts.WaitForDB)
+}
+
+// WithAgentStoreDB returns paths reading cursor-agent sessions from the storage directory
+// holding storeDB, which is laid out as <dir>/{hash}/{session id}/store.db
+func WithAgentStoreDB(paths StoragePaths, storeDB string) StoragePaths {
+	paths.AgentStoragePath = filepath.Dir(filepath.Dir(filepath.Dir(storeDB)))
+	return paths
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestRunAgent_Retries(t *testing.T) {
+	failing := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		retries   int
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds at once", 2, 0, failing, 1, false},
+		{"succeeds on a retry", 2, 2, failing, 3, false},
+		{"runs out of retries", 1, 5, failing, 2, true},
+		{"authentication is not retried", 3, 5, ErrAgentAuthentication, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := RunAgent(context.Background(), AgentRunOptions{Timeout: time.Second, Retries: tt.retries}, func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("RunAgent() = %v after %d call(s), want error %v after %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunAgent_TimeoutCountsAsRun(t *testing.T) {
+	calls := 0
+	err := RunAgent(context.Background(), AgentRunOptions{Timeout: 10 * time.Millisecond, Retries: 2}, func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("RunAgent() = %v after %d call(s), want a single run stopped at its timeout", err, calls)
+	}
+}
+
+func TestSeedAgentStorage(t *testing.T) {
+	agentPollInterval = 5 * time.Millisecond
+	defer func() { agentPollInterval = 250 * time.Millisecond }()
+
+	dir := filepath.Join(testutil.CreateTempDir(t), "chats")
+	storeDB := filepath.Join(dir, "hash", "session", "store.db")
+	opts := AgentRunOptions{Timeout: time.Second, WaitForDB: 5 * time.Second}
+
+	// The database appears after a failed run
+	got, err := SeedAgentStorage(context.Background(), opts, []string{dir}, func(ctx context.Context) error {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = os.MkdirAll(filepath.Dir(storeDB), 0755)
+			_ = os.WriteFile(storeDB, nil, 0644)
+		}()
+		return errors.New("exit status 1")
+	})
+	if err != nil || got != storeDB {
+		t.Fatalf("SeedAgentStorage() = %q, %v, want %q", got, err, storeDB)
+	}
+
+	paths := WithAgentStoreDB(StoragePaths{BasePath: "/base"}, got)
+	if paths.AgentStoragePath != dir || paths.BasePath != "/base" {
+		t.Errorf("WithAgentStoreDB() = %+v, want agent storage %s", paths, dir)
+	}
+}
+
+func TestSeedAgentStorage_NoDatabase(t *testing.T) {
+	dir := filepath.Join(testutil.CreateTempDir(t), "chats")
+	opts := AgentRunOptions{Timeout: time.Second, WaitForDB: 20 * time.Millisecond}
+	run := func(ctx context.Context) error { return nil }
+	if _, err := SeedAgentStorage(context.Background(), opts, []string{dir}, run); !errors.Is(err, ErrNoStorage) {
+		t.Errorf("SeedAgentStorage() error = %v, want ErrNoStorage", err)
+	}
+
+	auth := func(ctx context.Context) error { return ErrAgentAuthentication }
+	if _, err := SeedAgentStorage(context.Background(), opts, []string{dir}, auth); !errors.Is(err, ErrAgentAuthentication) {
+		t.Errorf("SeedAgentStorage() error = %v, want ErrAgentAuthentication", err)
+	}
+}
+
+func TestAgentRunOptions_Validate(t *testing.T) {
+	if err := DefaultAgentRunOptions().Validate(); err != nil {
+		t.Errorf("Validate() of the defaults = %v", err)
+	}
+	for _, opts := range []AgentRunOptions{
+		{Timeout: 0, WaitForDB: time.Second},
+		{Timeout: time.Second, Retries: -1},
+		{Timeout: time.Second, WaitForDB: -time.Second},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", opts)
+		}
+	}
+}
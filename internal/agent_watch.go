@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// WaitForAgentStoreDB polls the agent storage directories every interval until one of them
+// holds a store.db, and returns its path. Directories that don't exist yet are polled too,
+// so the wait can start before cursor-agent first runs. A store.db that already exists is
+// returned at once. When ctx ends first, its error is returned.
+func WaitForAgentStoreDB(ctx context.Context, dirs []string, interval time.Duration) (string, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, dir := range dirs {
+			storeDBs, err := StoragePaths{AgentStoragePath: dir}.FindAgentStoreDBs()
+			if err != nil {
+				LogDebug("Failed to scan %s while waiting for a store.db: %v", dir, err)
+				continue
+			}
+			if len(storeDBs) > 0 {
+				return storeDBs[0], nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestWaitForAgentStoreDB_Existing(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+	storeDB := filepath.Join(dir, "hash", "session", "store.db")
+	writeFile(t, storeDB, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := WaitForAgentStoreDB(ctx, []string{filepath.Join(dir, "missing"), dir}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForAgentStoreDB() error = %v", err)
+	}
+	if got != storeDB {
+		t.Errorf("WaitForAgentStoreDB() = %q, want %q", got, storeDB)
+	}
+}
+
+func TestWaitForAgentStoreDB_Appears(t *testing.T) {
+	// The storage directory doesn't exist until the store.db is written
+	dir := filepath.Join(testutil.CreateTempDir(t), "chats")
+	storeDB := filepath.Join(dir, "hash", "session", "store.db")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.MkdirAll(filepath.Dir(storeDB), 0755)
+		_ = os.WriteFile(storeDB, nil, 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := WaitForAgentStoreDB(ctx, []string{dir}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForAgentStoreDB() error = %v", err)
+	}
+	if got != storeDB {
+		t.Errorf("WaitForAgentStoreDB() = %q, want %q", got, storeDB)
+	}
+}
+
+func TestWaitForAgentStoreDB_Timeout(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := WaitForAgentStoreDB(ctx, []string{dir}, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForAgentStoreDB() error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appDirName names the directories cursor-session keeps its own files in
+const appDirName = "cursor-session"
+
+// AppDirs are the directories cursor-session keeps its own files in
+type AppDirs struct {
+	// CacheDir holds the session cache and tags: $XDG_CACHE_HOME/cursor-session on Linux
+	// (~/.cache/cursor-session when unset), ~/Library/Caches/cursor-session on macOS
+	CacheDir string
+	// ConfigDir is for configuration files: $XDG_CONFIG_HOME/cursor-session on Linux
+	// (~/.config/cursor-session when unset), ~/Library/Application Support/cursor-session
+	// on macOS
+	ConfigDir string
+	// StateDir holds state kept between runs, such as when each storage was last exported:
+	// $XDG_STATE_HOME/cursor-session on Linux (~/.local/state/cursor-session when unset),
+	// ~/Library/Application Support/cursor-session on macOS
+	StateDir string
+	// LegacyCacheDir is where versions before XDG support kept the cache
+	LegacyCacheDir string
+}
+
+// ResolveAppDirs returns the directories for this OS and environment. As the XDG base
+// directory specification requires, relative XDG_CACHE_HOME, XDG_CONFIG_HOME and
+// XDG_STATE_HOME values are ignored.
+func ResolveAppDirs() (AppDirs, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return AppDirs{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	dirs := AppDirs{LegacyCacheDir: filepath.Join(home, ".cursor-session-cache")}
+	switch runtime.GOOS {
+	case "darwin":
+		dirs.CacheDir = filepath.Join(home, "Library/Caches", appDirName)
+		dirs.ConfigDir = filepath.Join(home, "Library/Application Support", appDirName)
+		dirs.StateDir = dirs.ConfigDir
+	default:
+		dirs.CacheDir = filepath.Join(xdgBaseDir("XDG_CACHE_HOME", filepath.Join(home, ".cache")), appDirName)
+		dirs.ConfigDir = filepath.Join(xdgBaseDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), appDirName)
+		dirs.StateDir = filepath.Join(xdgBaseDir("XDG_STATE_HOME", filepath.Join(home, ".local", "state")), appDirName)
+	}
+	return dirs, nil
+}
+
+// xdgBaseDir returns the base directory an XDG environment variable sets, or fallback when
+// it is unset or not an absolute path
+func xdgBaseDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return fallback
+}
+
+// MigrateLegacyCache moves a cache left in LegacyCacheDir to CacheDir, and reports whether
+// it did. Nothing is moved when there is no legacy cache, or when CacheDir already holds
+// files, which are kept rather than overwritten.
+func (d AppDirs) MigrateLegacyCache() (bool, error) {
+	if info, err := os.Stat(d.LegacyCacheDir); err != nil || !info.IsDir() {
+		return false, nil
+	}
+	if entries, err := os.ReadDir(d.CacheDir); err == nil && len(entries) > 0 {
+		LogDebug("Keeping the legacy cache in %s: %s is already in use", d.LegacyCacheDir, d.CacheDir)
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.CacheDir), 0755); err != nil {
+		return false, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// An empty cache directory is replaced by the legacy one
+	_ = os.Remove(d.CacheDir)
+	if err := os.Rename(d.LegacyCacheDir, d.CacheDir); err == nil {
+		return true, nil
+	}
+
+	// Renaming fails across file systems, so copy the files and remove the originals
+	if err := copyTree(d.LegacyCacheDir, d.CacheDir); err != nil {
+		return false, err
+	}
+	if err := os.RemoveAll(d.LegacyCacheDir); err != nil {
+		return true, fmt.Errorf("failed to remove legacy cache directory: %w", err)
+	}
+	return true, nil
+}
+
+// copyTree copies the directories and regular files under src to dst
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0755)
+		case entry.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestResolveAppDirs(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS uses fixed Library locations")
+	}
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		name       string
+		cacheHome  string
+		configHome string
+		stateHome  string
+		wantCache  string
+		wantConfig string
+		wantState  string
+	}{
+		{
+			name:       "defaults",
+			wantCache:  filepath.Join(home, ".cache", "cursor-session"),
+			wantConfig: filepath.Join(home, ".config", "cursor-session"),
+			wantState:  filepath.Join(home, ".local", "state", "cursor-session"),
+		},
+		{
+			name:       "xdg",
+			cacheHome:  "/var/cache/me",
+			configHome: "/etc/me",
+			stateHome:  "/var/lib/me",
+			wantCache:  "/var/cache/me/cursor-session",
+			wantConfig: "/etc/me/cursor-session",
+			wantState:  "/var/lib/me/cursor-session",
+		},
+		{
+			name:       "relative xdg is ignored",
+			cacheHome:  "cache",
+			configHome: "config",
+			stateHome:  "state",
+			wantCache:  filepath.Join(home, ".cache", "cursor-session"),
+			wantConfig: filepath.Join(home, ".config", "cursor-session"),
+			wantState:  filepath.Join(home, ".local", "state", "cursor-session"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", tt.cacheHome)
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			t.Setenv("XDG_STATE_HOME", tt.stateHome)
+			dirs, err := ResolveAppDirs()
+			if err != nil {
+				t.Fatalf("ResolveAppDirs() error = %v", err)
+			}
+			if dirs.CacheDir != tt.wantCache || dirs.ConfigDir != tt.wantConfig || dirs.StateDir != tt.wantState {
+				t.Errorf("ResolveAppDirs() = %+v, want cache %s, config %s and state %s", dirs, tt.wantCache, tt.wantConfig, tt.wantState)
+			}
+			if want := filepath.Join(home, ".cursor-session-cache"); dirs.LegacyCacheDir != want {
+				t.Errorf("LegacyCacheDir = %s, want %s", dirs.LegacyCacheDir, want)
+			}
+		})
+	}
+}
+
+func TestAppDirs_MigrateLegacyCache(t *testing.T) {
+	root := testutil.CreateTempDir(t)
+	dirs := AppDirs{
+		CacheDir:       filepath.Join(root, "cache", "cursor-session"),
+		LegacyCacheDir: filepath.Join(root, ".cursor-session-cache"),
+	}
+
+	if moved, err := dirs.MigrateLegacyCache(); moved || err != nil {
+		t.Fatalf("MigrateLegacyCache() without a legacy cache = %v, %v, want false, nil", moved, err)
+	}
+
+	writeFile(t, filepath.Join(dirs.LegacyCacheDir, "sessions.yaml"), "sessions: []\n")
+	writeFile(t, filepath.Join(dirs.LegacyCacheDir, "session_a.yaml"), "id: a\n")
+	if moved, err := dirs.MigrateLegacyCache(); !moved || err != nil {
+		t.Fatalf("MigrateLegacyCache() = %v, %v, want true, nil", moved, err)
+	}
+	for _, name := range []string{"sessions.yaml", "session_a.yaml"} {
+		if _, err := os.Stat(filepath.Join(dirs.CacheDir, name)); err != nil {
+			t.Errorf("%s was not moved: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(dirs.LegacyCacheDir); !os.IsNotExist(err) {
+		t.Errorf("legacy cache should be gone, stat error = %v", err)
+	}
+
+	// A cache directory already in use is kept
+	writeFile(t, filepath.Join(dirs.LegacyCacheDir, "sessions.yaml"), "old\n")
+	if moved, err := dirs.MigrateLegacyCache(); moved || err != nil {
+		t.Fatalf("MigrateLegacyCache() into a used directory = %v, %v, want false, nil", moved, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dirs.CacheDir, "sessions.yaml")); string(data) != "sessions: []\n" {
+		t.Errorf("MigrateLegacyCache() overwrote the cache: %q", data)
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := testutil.CreateTempDir(t)
+	writeFile(t, filepath.Join(src, "a", "b.yaml"), "b")
+	dst := filepath.Join(testutil.CreateTempDir(t), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("copyTree() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "a", "b.yaml")); err != nil || string(data) != "b" {
+		t.Errorf("copyTree() copied %q, %v", data, err)
+	}
+}
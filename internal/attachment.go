@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AttachmentsDir is the directory, next to a session's exported file, that its attachments
+// are written to, in a subdirectory per session
+const AttachmentsDir = "assets"
+
+// BubbleImage is an image attached to a bubble as Cursor records it: the path of the copy
+// it keeps, or the image itself as base64 data or a data: URL
+type BubbleImage struct {
+	Path      string          `json:"path,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	MimeType  string          `json:"mimeType,omitempty"`
+	Dimension *ImageDimension `json:"dimension,omitempty"`
+}
+
+// ImageDimension is the size of an image in pixels
+type ImageDimension struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// UnmarshalJSON reads an image recorded as an object or, as some versions do, as a plain
+// path or data: URL
+func (img *BubbleImage) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*img = BubbleImage{}
+		if strings.HasPrefix(value, "data:") {
+			img.Data = value
+		} else {
+			img.Path = value
+		}
+		return nil
+	}
+	type bubbleImageAlias BubbleImage
+	var raw bubbleImageAlias
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*img = BubbleImage(raw)
+	return nil
+}
+
+// BubbleContext is the context Cursor records on a bubble, of which only the images
+// selected for the message are read
+type BubbleContext struct {
+	SelectedImages []BubbleImage `json:"selectedImages,omitempty"`
+}
+
+// UnmarshalJSON reads the selected images of a context, ignoring a context of another
+// shape rather than failing the whole bubble
+func (c *BubbleContext) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		SelectedImages []BubbleImage `json:"selectedImages"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		LogDebug("Ignoring bubble context: %v", err)
+		return nil
+	}
+	c.SelectedImages = raw.SelectedImages
+	return nil
+}
+
+// AttachedImages returns the images attached to a bubble, on the bubble or in its
+// context, each once
+func (b *RawBubble) AttachedImages() []BubbleImage {
+	images := b.Images
+	if b.Context != nil {
+		images = append(images[:len(images):len(images)], b.Context.SelectedImages...)
+	}
+	seen := make(map[string]bool, len(images))
+	unique := make([]BubbleImage, 0, len(images))
+	for _, img := range images {
+		key := img.Path + "\x00" + img.Data
+		if key == "\x00" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, img)
+	}
+	return unique
+}
+
+// Attachment is an image attached to a message. Images Cursor keeps as files are read
+// from Source when exported; those it stores inline are held in Data.
+type Attachment struct {
+	Name     string `json:"name"` // File name, unique within the session
+	MimeType string `json:"mime_type,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Source   string `json:"source,omitempty"` // Where Cursor keeps the image
+	Data     []byte `json:"data,omitempty"`   // The image, when Cursor stored it inline
+	File     string `json:"file,omitempty"`   // Where an export wrote the image, relative to the session's file
+}
+
+// newAttachment returns the attachment of an image, named after the message it is attached
+// to and its place among the message's images, both numbered from 1. Images whose data
+// cannot be decoded are left out.
+func newAttachment(img BubbleImage, message, n int) (Attachment, bool) {
+	att := Attachment{MimeType: img.MimeType, Source: strings.TrimPrefix(img.Path, "file://")}
+	if img.Dimension != nil {
+		att.Width, att.Height = img.Dimension.Width, img.Dimension.Height
+	}
+
+	data := img.Data
+	if strings.HasPrefix(att.Source, "data:") {
+		data, att.Source = att.Source, ""
+	}
+	if data != "" {
+		mimeType, decoded, err := decodeImageData(data)
+		if err != nil {
+			LogDebug("Skipping image %d of message %d: %v", n, message, err)
+			return Attachment{}, false
+		}
+		att.Data = decoded
+		if att.MimeType == "" {
+			att.MimeType = mimeType
+		}
+		if att.MimeType == "" {
+			att.MimeType = http.DetectContentType(decoded)
+		}
+	}
+	if att.Source == "" && att.Data == nil {
+		return Attachment{}, false
+	}
+	if att.MimeType == "" {
+		att.MimeType = mime.TypeByExtension(filepath.Ext(att.Source))
+	}
+
+	att.Name = fmt.Sprintf("image_%d_%d%s", message, n, imageExtension(att.MimeType, att.Source))
+	return att, true
+}
+
+// decodeImageData decodes an image stored as a data: URL or as plain base64, and returns
+// the media type a data: URL gives
+func decodeImageData(data string) (string, []byte, error) {
+	mimeType := ""
+	if rest, ok := strings.CutPrefix(data, "data:"); ok {
+		header, payload, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return "", nil, fmt.Errorf("unsupported data URL")
+		}
+		mimeType = strings.TrimSuffix(header, ";base64")
+		data = payload
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(data); err == nil {
+			return mimeType, decoded, nil
+		}
+	}
+	return "", nil, fmt.Errorf("image data is not base64")
+}
+
+// imageExtension returns the file extension of an image, from its media type or else the
+// path it was stored at
+func imageExtension(mimeType, source string) string {
+	switch strings.ToLower(mimeType) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	case "image/bmp":
+		return ".bmp"
+	}
+	if ext := strings.ToLower(filepath.Ext(source)); ext != "" {
+		return ext
+	}
+	return ".bin"
+}
+
+// Content returns the image: its inline data, or the file it was stored at
+func (a Attachment) Content() ([]byte, error) {
+	if a.Data != nil {
+		return a.Data, nil
+	}
+	if a.Source == "" {
+		return nil, fmt.Errorf("attachment %s has no data", a.Name)
+	}
+	data, err := os.ReadFile(a.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment %s: %w", a.Name, err)
+	}
+	return data, nil
+}
+
+// available reports whether the image can be read
+func (a Attachment) available() bool {
+	if a.Data != nil {
+		return true
+	}
+	info, err := os.Stat(a.Source)
+	return a.Source != "" && err == nil && info.Mode().IsRegular()
+}
+
+// AttachmentFile returns the path, relative to a session's exported file, its attachment
+// name is written to
+func AttachmentFile(sessionID, name string) string {
+	return path.Join(AttachmentsDir, sessionID, name)
+}
+
+// DetachAttachments returns a copy of session whose attachments point at the files an
+// export writes them to, without their inline data, along with the attachments to write
+// there. Attachments whose image can no longer be read get no file. The original session
+// is not modified.
+func DetachAttachments(session *Session) (*Session, []Attachment) {
+	var attachments []Attachment
+	var detached *Session
+	for i, msg := range session.Messages {
+		if len(msg.Attachments) == 0 {
+			continue
+		}
+		if detached == nil {
+			copied := *session
+			copied.Messages = append([]Message(nil), session.Messages...)
+			detached = &copied
+		}
+		kept := make([]Attachment, len(msg.Attachments))
+		for j, att := range msg.Attachments {
+			if att.available() {
+				att.File = AttachmentFile(session.ID, att.Name)
+				attachments = append(attachments, att)
+			} else {
+				LogWarn("Image %s of session %s is no longer at %s", att.Name, session.ID, att.Source)
+				att.File = ""
+			}
+			att.Data = nil
+			kept[j] = att
+		}
+		detached.Messages[i].Attachments = kept
+	}
+	if detached == nil {
+		return session, nil
+	}
+	return detached, attachments
+}
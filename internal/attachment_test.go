@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// pngHeader is the start of a PNG file, enough for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestRawBubble_AttachedImages(t *testing.T) {
+	var bubble RawBubble
+	data := `{
+		"text": "What is wrong with this layout?",
+		"images": [{"path": "/tmp/shot.png", "dimension": {"width": 800, "height": 600}}, "data:image/gif;base64,R0lG"],
+		"context": {"selectedImages": [{"path": "/tmp/shot.png"}, {"path": "/tmp/other.jpg"}], "fileSelections": []}
+	}`
+	if err := json.Unmarshal([]byte(data), &bubble); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	images := bubble.AttachedImages()
+	if len(images) != 3 {
+		t.Fatalf("AttachedImages() = %+v, want 3 images, the repeated one once", images)
+	}
+	if images[0].Path != "/tmp/shot.png" || images[0].Dimension == nil || images[0].Dimension.Width != 800 {
+		t.Errorf("image 1 = %+v, want the path and its dimension", images[0])
+	}
+	if images[1].Data != "data:image/gif;base64,R0lG" || images[2].Path != "/tmp/other.jpg" {
+		t.Errorf("images = %+v, want the data URL and then the context's image", images)
+	}
+
+	// A context of another shape is ignored rather than failing the bubble
+	if err := json.Unmarshal([]byte(`{"text": "hi", "context": "none"}`), &bubble); err != nil {
+		t.Errorf("Unmarshal() with a string context error = %v", err)
+	}
+}
+
+func TestNewAttachment(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(pngHeader)
+	tests := []struct {
+		name     string
+		img      BubbleImage
+		wantName string
+		wantMIME string
+		wantData bool
+	}{
+		{name: "path", img: BubbleImage{Path: "file:///tmp/shot.jpeg"}, wantName: "image_2_1.jpg", wantMIME: "image/jpeg"},
+		{name: "data URL", img: BubbleImage{Data: "data:image/webp;base64," + encoded}, wantName: "image_2_1.webp", wantMIME: "image/webp", wantData: true},
+		{name: "data URL as path", img: BubbleImage{Path: "data:image/gif;base64," + encoded}, wantName: "image_2_1.gif", wantMIME: "image/gif", wantData: true},
+		{name: "base64 detected", img: BubbleImage{Data: encoded}, wantName: "image_2_1.png", wantMIME: "image/png", wantData: true},
+		{name: "mime type given", img: BubbleImage{Data: encoded, MimeType: "image/jpeg"}, wantName: "image_2_1.jpg", wantMIME: "image/jpeg", wantData: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att, ok := newAttachment(tt.img, 2, 1)
+			if !ok {
+				t.Fatal("newAttachment() = false, want an attachment")
+			}
+			if att.Name != tt.wantName || att.MimeType != tt.wantMIME || (att.Data != nil) != tt.wantData {
+				t.Errorf("newAttachment() = %s %s data=%t, want %s %s data=%t", att.Name, att.MimeType, att.Data != nil, tt.wantName, tt.wantMIME, tt.wantData)
+			}
+		})
+	}
+
+	for _, img := range []BubbleImage{{}, {Data: "not base64!"}, {Data: "data:image/png,plain"}} {
+		if _, ok := newAttachment(img, 1, 1); ok {
+			t.Errorf("newAttachment(%+v) = true, want the image left out", img)
+		}
+	}
+}
+
+func TestNormalizeConversation_Attachments(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(pngHeader)
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: MessageTypeUser, Text: "Why is this red?", Images: []BubbleImage{{Data: encoded}, {Path: "/tmp/shot.jpg"}}},
+			{Type: MessageTypeAssistant, Text: "The border color is set twice."},
+		},
+	}
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	attachments := session.Messages[0].Attachments
+	if len(attachments) != 2 || attachments[0].Name != "image_1_1.png" || attachments[1].Name != "image_1_2.jpg" {
+		t.Errorf("attachments = %+v, want image_1_1.png and image_1_2.jpg", attachments)
+	}
+	if string(attachments[0].Data) != string(pngHeader) || attachments[1].Source != "/tmp/shot.jpg" {
+		t.Errorf("attachments = %+v, want the decoded data and the source path", attachments)
+	}
+	if len(session.Messages[1].Attachments) != 0 {
+		t.Errorf("message without images has attachments %+v", session.Messages[1].Attachments)
+	}
+}
+
+func TestDetachAttachments(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+	stored := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(stored, pngHeader, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: ActorUser, Content: "Look", Attachments: []Attachment{
+			{Name: "image_1_1.png", Data: pngHeader},
+			{Name: "image_1_2.png", Source: stored},
+			{Name: "image_1_3.png", Source: filepath.Join(dir, "deleted.png")},
+		}},
+		{Actor: ActorAssistant, Content: "Seen"},
+	})
+
+	detached, attachments := DetachAttachments(session)
+	if len(attachments) != 2 {
+		t.Fatalf("DetachAttachments() returned %d attachments to write, want the 2 readable ones", len(attachments))
+	}
+	for _, att := range attachments {
+		data, err := att.Content()
+		if err != nil || string(data) != string(pngHeader) {
+			t.Errorf("Content() of %s = %q, %v, want the image", att.Name, data, err)
+		}
+	}
+	kept := detached.Messages[0].Attachments
+	if kept[0].File != "assets/s1/image_1_1.png" || kept[0].Data != nil || kept[1].File != "assets/s1/image_1_2.png" {
+		t.Errorf("detached attachments = %+v, want files and no inline data", kept)
+	}
+	if kept[2].File != "" {
+		t.Errorf("missing image got the file %s", kept[2].File)
+	}
+	if session.Messages[0].Attachments[0].Data == nil || session.Messages[0].Attachments[0].File != "" {
+		t.Error("DetachAttachments() modified the original session")
+	}
+
+	if same, attachments := DetachAttachments(CreateTestSession("plain")); attachments != nil || same.ID != "plain" {
+		t.Errorf("DetachAttachments() of a session without images = %v", attachments)
+	}
+}
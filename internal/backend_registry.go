@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BackendProvider opens storage backends for locations given with a URI scheme, such as
+// s3://bucket/path, letting applications read sessions from stores this package does not
+// know about
+type BackendProvider struct {
+	// Name identifies the provider in logs and errors
+	Name string
+	// Scheme is the scheme of the locations the provider opens, such as "s3" for
+	// s3://bucket/path. Schemes are matched regardless of case.
+	Scheme string
+	// Priority orders the providers of the same scheme: they are tried from the highest
+	// priority, and in the order they were registered for equal priorities
+	Priority int
+	// Open returns the backend for a location, the whole --storage value
+	Open func(location string) (StorageBackend, error)
+}
+
+// backendRegistry holds the registered providers, in registration order
+var backendRegistry struct {
+	mu        sync.RWMutex
+	providers []BackendProvider
+}
+
+// RegisterBackend registers a provider of storage backends for its scheme. The file
+// scheme is reserved for local paths.
+func RegisterBackend(provider BackendProvider) error {
+	scheme := strings.ToLower(provider.Scheme)
+	switch {
+	case provider.Open == nil:
+		return fmt.Errorf("backend provider %q has no Open function", provider.Name)
+	case !validScheme(scheme):
+		return fmt.Errorf("backend provider %q has an invalid scheme %q", provider.Name, provider.Scheme)
+	case scheme == "file":
+		return fmt.Errorf("backend provider %q cannot register the file scheme", provider.Name)
+	}
+	provider.Scheme = scheme
+
+	backendRegistry.mu.Lock()
+	defer backendRegistry.mu.Unlock()
+	backendRegistry.providers = append(backendRegistry.providers, provider)
+	return nil
+}
+
+// UnregisterBackends removes every provider registered for scheme
+func UnregisterBackends(scheme string) {
+	scheme = strings.ToLower(scheme)
+	backendRegistry.mu.Lock()
+	defer backendRegistry.mu.Unlock()
+	kept := backendRegistry.providers[:0]
+	for _, p := range backendRegistry.providers {
+		if p.Scheme != scheme {
+			kept = append(kept, p)
+		}
+	}
+	backendRegistry.providers = kept
+}
+
+// BackendProviders returns the providers registered for scheme, in the order they are tried
+func BackendProviders(scheme string) []BackendProvider {
+	scheme = strings.ToLower(scheme)
+	backendRegistry.mu.RLock()
+	var providers []BackendProvider
+	for _, p := range backendRegistry.providers {
+		if p.Scheme == scheme {
+			providers = append(providers, p)
+		}
+	}
+	backendRegistry.mu.RUnlock()
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].Priority > providers[j].Priority
+	})
+	return providers
+}
+
+// LocationScheme returns the lowercased scheme of a storage location written as a URI,
+// such as "s3" for s3://bucket/path, or "" for a filesystem path
+func LocationScheme(location string) string {
+	scheme, _, found := strings.Cut(location, "://")
+	if !found || !validScheme(strings.ToLower(scheme)) {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// validScheme reports whether s is a URI scheme: a letter followed by letters, digits,
+// +, - or . (RFC 3986). Single letters are left out, since they are Windows drive letters.
+func validScheme(s string) bool {
+	if len(s) < 2 || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// openRegisteredBackend opens a location with the providers of its scheme, returning the
+// first backend one of them opens
+func openRegisteredBackend(location string) (StorageBackend, error) {
+	scheme := LocationScheme(location)
+	providers := BackendProviders(scheme)
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%w: no storage backend registered for %s://", ErrNoStorage, scheme)
+	}
+
+	var errs []error
+	for _, p := range providers {
+		backend, err := p.Open(location)
+		if err == nil && backend != nil {
+			LogInfo("Reading sessions from %s with the %s backend", location, p.Name)
+			return backend, nil
+		}
+		if err == nil {
+			err = errors.New("no backend returned")
+		}
+		LogDebug("Backend %s could not open %s: %v", p.Name, location, err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+	}
+	return nil, fmt.Errorf("failed to open %s: %w", location, errors.Join(errs...))
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLocationScheme(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{"s3://bucket/path", "s3"},
+		{"S3://bucket", "s3"},
+		{"company+store://sessions", "company+store"},
+		{"file:///home/dev/state.vscdb", "file"},
+		{"/home/dev/state.vscdb", ""},
+		{`C://Users/dev`, ""},
+		{"relative/path", ""},
+		{"1abc://x", ""},
+	}
+	for _, tt := range tests {
+		if got := LocationScheme(tt.location); got != tt.want {
+			t.Errorf("LocationScheme(%q) = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterBackend_Invalid(t *testing.T) {
+	open := func(string) (StorageBackend, error) { return nil, nil }
+	for _, provider := range []BackendProvider{
+		{Name: "no open", Scheme: "test"},
+		{Name: "no scheme", Open: open},
+		{Name: "bad scheme", Scheme: "te st", Open: open},
+		{Name: "file", Scheme: "FILE", Open: open},
+	} {
+		if err := RegisterBackend(provider); err == nil {
+			t.Errorf("RegisterBackend(%s) should fail", provider.Name)
+		}
+	}
+}
+
+func TestRegisteredBackends(t *testing.T) {
+	t.Cleanup(func() { UnregisterBackends("regtest") })
+
+	dir := t.TempDir()
+	var tried []string
+	provider := func(name string, priority int, err error) BackendProvider {
+		return BackendProvider{
+			Name:     name,
+			Scheme:   "RegTest",
+			Priority: priority,
+			Open: func(location string) (StorageBackend, error) {
+				tried = append(tried, name)
+				if err != nil {
+					return nil, err
+				}
+				return NewFileBackend(dir), nil
+			},
+		}
+	}
+	for _, p := range []BackendProvider{
+		provider("fallback", 0, nil),
+		provider("primary", 10, errors.New("unreachable")),
+		provider("late", 0, nil),
+	} {
+		if err := RegisterBackend(p); err != nil {
+			t.Fatalf("RegisterBackend(%s) error = %v", p.Name, err)
+		}
+	}
+
+	var names []string
+	for _, p := range BackendProviders("regtest") {
+		names = append(names, p.Name)
+	}
+	if want := []string{"primary", "fallback", "late"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("BackendProviders() = %v, want %v", names, want)
+	}
+
+	paths, err := GetStoragePaths("regtest://bucket/sessions")
+	if err != nil {
+		t.Fatalf("GetStoragePaths() error = %v", err)
+	}
+	if paths.Location != "regtest://bucket/sessions" {
+		t.Errorf("GetStoragePaths() = %+v, want the location kept as is", paths)
+	}
+
+	backend, err := NewStorageBackend(paths)
+	if err != nil {
+		t.Fatalf("NewStorageBackend() error = %v", err)
+	}
+	if _, ok := backend.(*FileBackend); !ok {
+		t.Errorf("NewStorageBackend() = %T, want the fallback's backend", backend)
+	}
+	if want := []string{"primary", "fallback"}; !reflect.DeepEqual(tried, want) {
+		t.Errorf("providers tried = %v, want %v", tried, want)
+	}
+}
+
+func TestRegisteredBackends_Failures(t *testing.T) {
+	t.Cleanup(func() { UnregisterBackends("failtest") })
+
+	if _, err := GetStoragePaths("failtest://bucket"); !errors.Is(err, ErrNoStorage) {
+		t.Errorf("GetStoragePaths() with no provider error = %v, want ErrNoStorage", err)
+	}
+	if _, err := NewStorageBackend(StoragePaths{Location: "failtest://bucket"}); !errors.Is(err, ErrNoStorage) {
+		t.Errorf("NewStorageBackend() with no provider error = %v, want ErrNoStorage", err)
+	}
+
+	err := RegisterBackend(BackendProvider{
+		Name:   "broken",
+		Scheme: "failtest",
+		Open:   func(string) (StorageBackend, error) { return nil, errors.New("access denied") },
+	})
+	if err != nil {
+		t.Fatalf("RegisterBackend() error = %v", err)
+	}
+	if _, err := NewStorageBackend(StoragePaths{Location: "failtest://bucket"}); err == nil {
+		t.Error("NewStorageBackend() should fail when every provider fails")
+	}
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Benchmark phases, in the order the export pipeline runs them
+const (
+	PhaseLoad        = "load"
+	PhaseParse       = "parse"
+	PhaseReconstruct = "reconstruct"
+	PhaseNormalize   = "normalize"
+)
+
+// BenchmarkPhase is the cost of one phase of the export pipeline, averaged over the runs
+type BenchmarkPhase struct {
+	Name string `json:"name"`
+	// Rows is how many records the phase worked through: bubbles and composers for load,
+	// bubbles for parse and reconstruct, conversations for normalize
+	Rows          int           `json:"rows"`
+	Duration      time.Duration `json:"duration_ns"`
+	RowsPerSecond float64       `json:"rows_per_second"`
+	// Allocs and AllocBytes are the heap allocations made while the phase ran
+	Allocs     uint64 `json:"allocs"`
+	AllocBytes uint64 `json:"alloc_bytes"`
+}
+
+// BenchmarkReport is the cost of each phase of the export pipeline over one storage
+type BenchmarkReport struct {
+	Version   string           `json:"version,omitempty"`
+	GoVersion string           `json:"go_version"`
+	Platform  string           `json:"platform"`
+	Runs      int              `json:"runs"`
+	Sessions  int              `json:"sessions"`
+	Phases    []BenchmarkPhase `json:"phases"`
+	Total     BenchmarkPhase   `json:"total"`
+}
+
+// RunBenchmark runs the export pipeline over a backend runs times and measures each phase:
+// loading the records from storage, extracting the text of every bubble, reconstructing
+// conversations and normalizing them into sessions. Each run reads the storage again, so
+// caches in the backend are the only state carried between runs.
+func RunBenchmark(backend StorageBackend, paths []StoragePaths, runs int) (*BenchmarkReport, error) {
+	if runs < 1 {
+		return nil, fmt.Errorf("benchmark needs at least one run, got %d", runs)
+	}
+
+	report := &BenchmarkReport{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Runs:      runs,
+	}
+	totals := make(map[string]*BenchmarkPhase)
+	names := []string{PhaseLoad, PhaseParse, PhaseReconstruct, PhaseNormalize}
+	for _, name := range names {
+		totals[name] = &BenchmarkPhase{Name: name}
+	}
+
+	for run := 0; run < runs; run++ {
+		var dump *RawDump
+		var conversations []*ReconstructedConversation
+		var sessions []*Session
+		phases := map[string]func() (int, error){
+			PhaseLoad: func() (int, error) {
+				var err error
+				dump, err = ExtractRawDump(backend, paths)
+				if err != nil {
+					return 0, err
+				}
+				return len(dump.Bubbles) + len(dump.Composers), nil
+			},
+			PhaseParse: func() (int, error) {
+				for _, bubble := range dump.Bubbles {
+					if bubble == nil {
+						continue
+					}
+					_, _, _ = ExtractTextWithTier(bubble)
+					_ = ExtractThinking(bubble)
+				}
+				return len(dump.Bubbles), nil
+			},
+			PhaseReconstruct: func() (int, error) {
+				var err error
+				conversations, err = ReconstructConversations(dump)
+				return len(dump.Bubbles), err
+			},
+			PhaseNormalize: func() (int, error) {
+				sessions = dump.NormalizeSessions(conversations, "")
+				return len(conversations), nil
+			},
+		}
+		for _, name := range names {
+			phase, err := measurePhase(name, phases[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s phase failed: %w", name, err)
+			}
+			total := totals[name]
+			total.Rows = phase.Rows
+			total.Duration += phase.Duration
+			total.Allocs += phase.Allocs
+			total.AllocBytes += phase.AllocBytes
+		}
+		report.Sessions = len(sessions)
+	}
+
+	report.Total = BenchmarkPhase{Name: "total"}
+	for _, name := range names {
+		phase := totals[name]
+		phase.Duration /= time.Duration(runs)
+		phase.Allocs /= uint64(runs)
+		phase.AllocBytes /= uint64(runs)
+		phase.RowsPerSecond = rowsPerSecond(phase.Rows, phase.Duration)
+		report.Phases = append(report.Phases, *phase)
+
+		report.Total.Duration += phase.Duration
+		report.Total.Allocs += phase.Allocs
+		report.Total.AllocBytes += phase.AllocBytes
+	}
+	// Every bubble passes through the whole pipeline, so the total is per bubble
+	report.Total.Rows = totals[PhaseParse].Rows
+	report.Total.RowsPerSecond = rowsPerSecond(report.Total.Rows, report.Total.Duration)
+	return report, nil
+}
+
+// measurePhase runs one phase and returns its rows, time and heap allocations. Garbage
+// left by the phase before is collected first, so it is not charged to this one.
+func measurePhase(name string, run func() (int, error)) (BenchmarkPhase, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	rows, err := run()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		return BenchmarkPhase{}, err
+	}
+	return BenchmarkPhase{
+		Name:       name,
+		Rows:       rows,
+		Duration:   elapsed,
+		Allocs:     after.Mallocs - before.Mallocs,
+		AllocBytes: after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// rowsPerSecond returns the rate rows were worked through in elapsed, or 0 for no time
+func rowsPerSecond(rows int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(rows) / elapsed.Seconds()
+}
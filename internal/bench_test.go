@@ -0,0 +1,34 @@
+package internal
+
+import "testing"
+
+func TestRunBenchmark(t *testing.T) {
+	backend := NewFileBackend(createExportDir(t))
+	report, err := RunBenchmark(backend, nil, 2)
+	if err != nil {
+		t.Fatalf("RunBenchmark() error = %v", err)
+	}
+	if report.Runs != 2 || report.Sessions == 0 || report.GoVersion == "" {
+		t.Errorf("report = %+v, want 2 runs over some sessions", report)
+	}
+
+	want := []string{PhaseLoad, PhaseParse, PhaseReconstruct, PhaseNormalize}
+	if len(report.Phases) != len(want) {
+		t.Fatalf("Phases = %+v, want %v", report.Phases, want)
+	}
+	for i, phase := range report.Phases {
+		if phase.Name != want[i] {
+			t.Errorf("Phases[%d] = %s, want %s", i, phase.Name, want[i])
+		}
+		if phase.Rows == 0 || phase.Duration <= 0 || phase.RowsPerSecond <= 0 {
+			t.Errorf("phase %s = %+v, want rows counted and timed", phase.Name, phase)
+		}
+	}
+	if report.Total.Rows != report.Phases[1].Rows || report.Total.Duration < report.Phases[0].Duration {
+		t.Errorf("Total = %+v, want the bubbles over the time of every phase", report.Total)
+	}
+
+	if _, err := RunBenchmark(backend, nil, 0); err == nil {
+		t.Error("RunBenchmark() with no runs should fail")
+	}
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxBlobPayload is the default size, in bytes, above which the payloads embedded
+// in a blob, such as tool output, are not loaded
+const DefaultMaxBlobPayload = 8 << 20
+
+// maxBlobPayload is the payload size limit; 0 loads every payload
+var maxBlobPayload = DefaultMaxBlobPayload
+
+// SetMaxBlobPayload sets the size, in bytes, above which blob payloads are skipped, or
+// 0 to load every payload
+func SetMaxBlobPayload(n int) {
+	maxBlobPayload = n
+}
+
+// skippedPayloadText replaces the text of a payload that was not loaded
+func skippedPayloadText(size int) string {
+	return fmt.Sprintf("[%d bytes not loaded]", size)
+}
+
+// unmarshalBlob decodes a blob holding a JSON object. Blobs over the payload limit are
+// decoded token by token, so that multi-megabyte tool output embedded in a message does
+// not have to be held as a whole tree of values: strings over the limit are replaced by
+// a note of their size. It returns how many strings were replaced.
+func unmarshalBlob(value []byte) (map[string]interface{}, int, error) {
+	if maxBlobPayload <= 0 || len(value) <= maxBlobPayload {
+		var data map[string]interface{}
+		if err := json.Unmarshal(value, &data); err != nil {
+			return nil, 0, err
+		}
+		return data, 0, nil
+	}
+
+	d := &blobDecoder{dec: json.NewDecoder(bytes.NewReader(value)), limit: maxBlobPayload}
+	d.dec.UseNumber()
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, 0, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, 0, errors.New("blob is not a JSON object")
+	}
+	data, err := d.object()
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, 0, errors.New("unexpected data after the JSON object")
+	}
+	return data, d.skipped, nil
+}
+
+// blobDecoder decodes JSON values token by token, skipping strings over limit
+type blobDecoder struct {
+	dec     *json.Decoder
+	limit   int
+	skipped int
+}
+
+// value decodes the value starting with tok
+func (d *blobDecoder) value(tok json.Token) (interface{}, error) {
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			return d.object()
+		case '[':
+			return d.array()
+		}
+		return nil, fmt.Errorf("unexpected %v", v)
+	case string:
+		if len(v) > d.limit {
+			d.skipped++
+			return skippedPayloadText(len(v)), nil
+		}
+		return v, nil
+	case json.Number:
+		// Numbers decode as float64, as with json.Unmarshal into interface{}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	default:
+		return v, nil // bool or nil
+	}
+}
+
+// object decodes the members of an object whose opening brace was read
+func (d *blobDecoder) object() (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object key %v", tok)
+		}
+		if tok, err = d.dec.Token(); err != nil {
+			return nil, err
+		}
+		if obj[key], err = d.value(tok); err != nil {
+			return nil, err
+		}
+	}
+	_, err := d.dec.Token() // Closing brace
+	return obj, err
+}
+
+// array decodes the elements of an array whose opening bracket was read
+func (d *blobDecoder) array() ([]interface{}, error) {
+	arr := []interface{}{}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(tok)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	_, err := d.dec.Token() // Closing bracket
+	return arr, err
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalBlob(t *testing.T) {
+	defer SetMaxBlobPayload(DefaultMaxBlobPayload)
+	SetMaxBlobPayload(64)
+
+	output := strings.Repeat("x", 100)
+	value := `{"id":"m1","role":"tool","n":3,"ok":true,"none":null,` +
+		`"content":[{"type":"text","text":"Ran the tests"},{"type":"tool-result","result":"` + output + `"}]}`
+
+	data, skipped, err := unmarshalBlob([]byte(value))
+	if err != nil {
+		t.Fatalf("unmarshalBlob() error = %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("unmarshalBlob() skipped %d payloads, want 1", skipped)
+	}
+	want := map[string]interface{}{
+		"id": "m1", "role": "tool", "n": float64(3), "ok": true, "none": nil,
+		"content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "Ran the tests"},
+			map[string]interface{}{"type": "tool-result", "result": "[100 bytes not loaded]"},
+		},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("unmarshalBlob() = %v, want %v", data, want)
+	}
+
+	// Blobs within the limit, or with no limit, decode as with json.Unmarshal
+	SetMaxBlobPayload(0)
+	data, skipped, err = unmarshalBlob([]byte(value))
+	var full map[string]interface{}
+	_ = json.Unmarshal([]byte(value), &full)
+	if err != nil || skipped != 0 || !reflect.DeepEqual(data, full) {
+		t.Errorf("unmarshalBlob() without a limit = %v, %d, %v; want the whole blob", data, skipped, err)
+	}
+
+	SetMaxBlobPayload(8)
+	for _, invalid := range []string{`["not", "an object"]`, `{"truncated": "value`, `{"a":1} trailing`, `not json at all`} {
+		if data, _, err := unmarshalBlob([]byte(invalid)); err == nil || data != nil {
+			t.Errorf("unmarshalBlob(%q) = %v, %v; want an error", invalid, data, err)
+		}
+	}
+}
+
+func TestLoadSessionFromStoreDB_LargeBlob(t *testing.T) {
+	defer SetMaxBlobPayload(DefaultMaxBlobPayload)
+	SetMaxBlobPayload(1024)
+
+	output := strings.Repeat("log line\n", 1000)
+	dbPath := createStoreDB(t, "large-session",
+		`CREATE TABLE blobs (key TEXT PRIMARY KEY, value TEXT)`,
+		`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`,
+		`INSERT INTO blobs VALUES ('m1', '{"id":"m1","role":"user","content":"Run the tests"}')`,
+		`INSERT INTO blobs VALUES ('m2', '{"id":"m2","role":"assistant","content":[{"type":"text","text":"They pass."},{"type":"tool-result","result":"`+strings.ReplaceAll(output, "\n", `\n`)+`"}]}')`,
+	)
+
+	bubbles, _, _, warnings, err := LoadSessionFromStoreDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+	if len(bubbles) != 2 {
+		t.Fatalf("LoadSessionFromStoreDB() returned %d bubbles, want both messages", len(bubbles))
+	}
+	for _, bubble := range bubbles {
+		if strings.Contains(bubble.Text, "log line") {
+			t.Errorf("bubble %s holds the skipped payload", bubble.BubbleID)
+		}
+	}
+	if counts := warnings.Counts(); counts[WarningBlobPayload] != 1 {
+		t.Errorf("warnings = %v, want one blob_payload warning", warnings.List())
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import "fmt"
+
+// Branch links an alternate branch of a forked conversation back to the session it
+// forked from. Cursor forks a conversation when a prior message is edited: the edited
+// message and the replies to it follow the same parent as the original, which stays in
+// the conversation headers.
+type Branch struct {
+	SessionID   string `json:"session_id"`   // ID of the session the branch forked from
+	Index       int    `json:"index"`        // Position of this branch, from 1
+	Count       int    `json:"count"`        // Number of alternate branches of the session
+	ForkMessage int    `json:"fork_message"` // Number of the branch's first message that differs from the session, from 1
+}
+
+// BranchID returns the ID of a session's alternate branch, numbered from 1
+func BranchID(sessionID string, index int) string {
+	return fmt.Sprintf("%s.branch_%d", sessionID, index)
+}
+
+// conversationBranches splits a composer's headers into the paths of its branches. Each
+// header follows its ParentBubbleID, or the previous header when it has none (or names
+// an unknown bubble), so headers without parents form a single path. Where a message has
+// several replies the conversation forks; the active branch follows the reply added
+// last, as Cursor appends a forked conversation after the one it replaces. The
+// alternates are every other path from the first header to a last one, oldest first.
+func conversationBranches(headers []ConversationHeader) (active []ConversationHeader, alternates [][]ConversationHeader) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int, len(headers))
+	for i, header := range headers {
+		if _, ok := index[header.BubbleID]; !ok {
+			index[header.BubbleID] = i
+		}
+	}
+
+	// children[i+1] lists the replies to header i; children[0] the headers without a parent
+	children := make([][]int, len(headers)+1)
+	forked := false
+	for i, header := range headers {
+		parent := i - 1
+		if p, ok := index[header.ParentBubbleID]; ok && header.ParentBubbleID != "" && p < i {
+			parent = p
+		}
+		children[parent+1] = append(children[parent+1], i)
+		if len(children[parent+1]) > 1 {
+			forked = true
+		}
+	}
+	if !forked {
+		return headers, nil
+	}
+
+	// Walk every path, visiting the replies added last first so the active branch comes first
+	var paths [][]ConversationHeader
+	var walk func(node int, path []ConversationHeader)
+	walk = func(node int, path []ConversationHeader) {
+		if node >= 0 {
+			path = append(path[:len(path):len(path)], headers[node])
+		}
+		replies := children[node+1]
+		if len(replies) == 0 {
+			paths = append(paths, path)
+			return
+		}
+		for i := len(replies) - 1; i >= 0; i-- {
+			walk(replies[i], path)
+		}
+	}
+	walk(-1, nil)
+
+	// Alternates are listed oldest first, which reverses the walk
+	alternates = make([][]ConversationHeader, 0, len(paths)-1)
+	for i := len(paths) - 1; i > 0; i-- {
+		alternates = append(alternates, paths[i])
+	}
+	return paths[0], alternates
+}
+
+// WithBranches returns the conversations with each one's alternate branches following
+// it, for exporting them as sessions of their own. Branches without messages are left out.
+func WithBranches(conversations []*ReconstructedConversation) []*ReconstructedConversation {
+	all := make([]*ReconstructedConversation, 0, len(conversations))
+	for _, conv := range conversations {
+		all = append(all, conv)
+		for _, branch := range conv.Branches {
+			if len(branch.Messages) > 0 {
+				all = append(all, branch)
+			}
+		}
+	}
+	return all
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+// headerIDs returns the bubble IDs of headers
+func headerIDs(headers []ConversationHeader) []string {
+	ids := make([]string, len(headers))
+	for i, header := range headers {
+		ids[i] = header.BubbleID
+	}
+	return ids
+}
+
+func TestConversationBranches(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    []ConversationHeader
+		active     []string
+		alternates [][]string
+	}{
+		{
+			name:    "linear",
+			headers: []ConversationHeader{{BubbleID: "u1"}, {BubbleID: "a1"}, {BubbleID: "u2"}},
+			active:  []string{"u1", "a1", "u2"},
+		},
+		{
+			name: "edited message",
+			headers: []ConversationHeader{
+				{BubbleID: "u1"}, {BubbleID: "a1"},
+				{BubbleID: "u2"}, {BubbleID: "a2"},
+				{BubbleID: "u2-edit", ParentBubbleID: "a1"}, {BubbleID: "a2-edit"},
+			},
+			active:     []string{"u1", "a1", "u2-edit", "a2-edit"},
+			alternates: [][]string{{"u1", "a1", "u2", "a2"}},
+		},
+		{
+			name: "missing parents follow the previous header",
+			headers: []ConversationHeader{
+				{BubbleID: "u1"}, {BubbleID: "a1"},
+				{BubbleID: "u1-edit", ParentBubbleID: ""},
+				{BubbleID: "u1-second", ParentBubbleID: "missing"},
+				{BubbleID: "u2"},
+				{BubbleID: "u1-third", ParentBubbleID: "u1"},
+			},
+			active:     []string{"u1", "u1-third"},
+			alternates: [][]string{{"u1", "a1", "u1-edit", "u1-second", "u2"}},
+		},
+		{
+			name: "parents after the message are ignored",
+			headers: []ConversationHeader{
+				{BubbleID: "u1"}, {BubbleID: "a1"},
+				{BubbleID: "u1-edit", ParentBubbleID: "a1"},
+				{BubbleID: "u0", ParentBubbleID: "later"},
+				{BubbleID: "later"},
+			},
+			active: []string{"u1", "a1", "u1-edit", "u0", "later"},
+		},
+		{
+			name: "nested forks",
+			headers: []ConversationHeader{
+				{BubbleID: "u1"}, {BubbleID: "a1"},
+				{BubbleID: "u2"}, {BubbleID: "a2"},
+				{BubbleID: "u3", ParentBubbleID: "a1"}, {BubbleID: "a3"},
+				{BubbleID: "u4", ParentBubbleID: "a3"}, {BubbleID: "a4"},
+				{BubbleID: "u5", ParentBubbleID: "a3"},
+			},
+			active: []string{"u1", "a1", "u3", "a3", "u5"},
+			alternates: [][]string{
+				{"u1", "a1", "u2", "a2"},
+				{"u1", "a1", "u3", "a3", "u4", "a4"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, alternates := conversationBranches(tt.headers)
+			if got := headerIDs(active); !reflect.DeepEqual(got, tt.active) {
+				t.Errorf("active = %v, want %v", got, tt.active)
+			}
+			var got [][]string
+			for _, alternate := range alternates {
+				got = append(got, headerIDs(alternate))
+			}
+			if !reflect.DeepEqual(got, tt.alternates) {
+				t.Errorf("alternates = %v, want %v", got, tt.alternates)
+			}
+		})
+	}
+}
+
+func TestWithBranches(t *testing.T) {
+	branch := &ReconstructedConversation{ComposerID: "a.branch_1", Messages: []ReconstructedMessage{{BubbleID: "b"}}}
+	empty := &ReconstructedConversation{ComposerID: "a.branch_2"}
+	conversations := []*ReconstructedConversation{
+		{ComposerID: "a", Branches: []*ReconstructedConversation{branch, empty}},
+		{ComposerID: "b"},
+	}
+
+	var ids []string
+	for _, conv := range WithBranches(conversations) {
+		ids = append(ids, conv.ComposerID)
+	}
+	if want := []string{"a", "a.branch_1", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("WithBranches() = %v, want %v", ids, want)
+	}
+}
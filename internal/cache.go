@@ -15,24 +15,100 @@ type CacheManager struct {
 	cacheDir string
 }
 
+// CacheFormatVersion is the version of the cache layout written by this build. Caches of
+// another version are rebuilt, so that their index entries have every field.
+const CacheFormatVersion = "1.6"
+
 // CacheMetadata stores metadata about the cache
 type CacheMetadata struct {
 	DatabasePath    string    `json:"database_path" yaml:"database_path"`
 	DatabaseModTime time.Time `json:"database_mod_time" yaml:"database_mod_time"`
-	CacheVersion    string    `json:"cache_version" yaml:"cache_version"`
-	CreatedAt       time.Time `json:"created_at" yaml:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" yaml:"updated_at"`
+	// DatabaseFingerprint is the StorageFingerprint of the database, which matches copies
+	// of it that have another path or modification time
+	DatabaseFingerprint string `json:"database_fingerprint,omitempty" yaml:"database_fingerprint,omitempty"`
+	CacheVersion        string `json:"cache_version" yaml:"cache_version"`
+	// RulesFingerprint is the Fingerprint of the normalizer rules the sessions were
+	// normalized with, empty for the built-in behavior
+	RulesFingerprint string    `json:"rules_fingerprint,omitempty" yaml:"rules_fingerprint,omitempty"`
+	CreatedAt        time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" yaml:"updated_at"`
 }
 
 // SessionIndexEntry represents a session entry in the index
 type SessionIndexEntry struct {
-	ID           string `yaml:"id"`
-	ComposerID   string `yaml:"composer_id"`
-	Name         string `yaml:"name,omitempty"`
-	CreatedAt    string `yaml:"created_at,omitempty"`
-	UpdatedAt    string `yaml:"updated_at,omitempty"`
-	MessageCount int    `yaml:"message_count"`
-	Workspace    string `yaml:"workspace,omitempty"`
+	ID           string `yaml:"id" json:"id"`
+	ComposerID   string `yaml:"composer_id" json:"composer_id"`
+	Name         string `yaml:"name,omitempty" json:"name,omitempty"`
+	CreatedAt    string `yaml:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt    string `yaml:"updated_at,omitempty" json:"updated_at,omitempty"`
+	MessageCount int    `yaml:"message_count" json:"message_count"`
+	Workspace    string `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	// GeneratedName is set when Name was derived from the conversation rather than set in Cursor
+	GeneratedName bool     `yaml:"generated_name,omitempty" json:"generated_name,omitempty"`
+	Tags          []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Branch        string   `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// Preview is the start of the first user prompt, which tells sessions apart when
+	// Cursor left them untitled
+	Preview string `yaml:"preview,omitempty" json:"preview,omitempty"`
+	// LastResponseAt is the timestamp of the last assistant message
+	LastResponseAt string `yaml:"last_response_at,omitempty" json:"last_response_at,omitempty"`
+	// Bytes is the size of the content and thinking of the session's messages
+	Bytes int `yaml:"bytes,omitempty" json:"bytes,omitempty"`
+	// EstimatedTokens approximates the model tokens of the session's messages
+	EstimatedTokens int `yaml:"estimated_tokens,omitempty" json:"estimated_tokens,omitempty"`
+	// DurationSeconds is the time between the first and last message
+	DurationSeconds int64 `yaml:"duration_seconds,omitempty" json:"duration_seconds,omitempty"`
+	// ToolCalls is the number of tool calls the assistant made
+	ToolCalls int `yaml:"tool_calls,omitempty" json:"tool_calls,omitempty"`
+	// WorkspaceHash is the chats/{hash} directory of a cursor-agent session
+	WorkspaceHash string `yaml:"workspace_hash,omitempty" json:"workspace_hash,omitempty"`
+	// Type is the session's Type
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Backend is the storage the session's messages were read from, such as
+	// globalStorage or agentStorage
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// SourcePath is the database or file the session's messages were read from
+	SourcePath string `yaml:"source_path,omitempty" json:"source_path,omitempty"`
+	// Pinned and Archived are the marks set by the pin and archive commands; like Tags,
+	// they are refreshed from their store when sessions are listed
+	Pinned   bool `yaml:"pinned,omitempty" json:"pinned,omitempty"`
+	Archived bool `yaml:"archived,omitempty" json:"archived,omitempty"`
+}
+
+// NewSessionIndexEntry returns the index entry for a session. Its size, token estimate,
+// duration and tool calls are computed from the messages when the normalized session is
+// indexed, so listings and filters don't need to load them.
+func NewSessionIndexEntry(session *Session) SessionIndexEntry {
+	bytes := 0
+	var source Provenance
+	for _, msg := range session.Messages {
+		bytes += len(msg.Content) + len(msg.Thinking)
+		if source.SourcePath == "" && msg.Provenance != nil {
+			source = *msg.Provenance
+		}
+	}
+	return SessionIndexEntry{
+		ID:              session.ID,
+		ComposerID:      session.Metadata.ComposerID,
+		Name:            session.Metadata.Name,
+		CreatedAt:       session.Metadata.CreatedAt,
+		UpdatedAt:       session.Metadata.UpdatedAt,
+		MessageCount:    len(session.Messages),
+		Workspace:       session.Workspace,
+		GeneratedName:   session.Metadata.GeneratedName,
+		Tags:            session.Metadata.Tags,
+		Branch:          session.Metadata.Git.GetBranch(),
+		Preview:         FirstUserPrompt(session.Messages, MaxPreviewLength),
+		LastResponseAt:  LastResponseAt(session.Messages),
+		Bytes:           bytes,
+		EstimatedTokens: SessionTokens(session),
+		DurationSeconds: int64(SessionDuration(session).Seconds()),
+		ToolCalls:       CountToolCalls(session),
+		WorkspaceHash:   session.WorkspaceHash,
+		Type:            session.Type,
+		Backend:         source.Backend,
+		SourcePath:      source.SourcePath,
+	}
 }
 
 // SessionIndex represents the YAML index of all sessions
@@ -63,7 +139,69 @@ func (cm *CacheManager) GetSessionPath(sessionID string) string {
 	return filepath.Join(cm.cacheDir, fmt.Sprintf("session_%s.json", sessionID))
 }
 
-// IsCacheValid checks if the cache is valid for the given database
+// GetTagsPath returns the path to the tag store, which is kept across cache rebuilds
+func (cm *CacheManager) GetTagsPath() string {
+	return filepath.Join(cm.cacheDir, "tags.yaml")
+}
+
+// LoadTags loads the tags users attached to sessions
+func (cm *CacheManager) LoadTags() (*TagStore, error) {
+	return LoadTagStore(cm.GetTagsPath())
+}
+
+// GetMarksPath returns the path to the session marks, which are kept across cache rebuilds
+func (cm *CacheManager) GetMarksPath() string {
+	return filepath.Join(cm.cacheDir, "marks.yaml")
+}
+
+// LoadMarks loads the sessions users pinned or archived
+func (cm *CacheManager) LoadMarks() (*SessionMarks, error) {
+	return LoadSessionMarks(cm.GetMarksPath())
+}
+
+// applyTags copies stored tags onto sessions before they are cached
+func (cm *CacheManager) applyTags(sessions []*Session) {
+	store, err := cm.LoadTags()
+	if err != nil {
+		LogWarn("Failed to load tags: %v", err)
+		return
+	}
+	store.Apply(sessions)
+}
+
+// SetSessionTags updates the tags of a session in its cached file and in the index, if
+// the session is cached. The tag store itself is saved separately.
+func (cm *CacheManager) SetSessionTags(sessionID string, tags []string) error {
+	index, err := cm.LoadIndex()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for i, entry := range index.Sessions {
+		if entry.ID != sessionID {
+			continue
+		}
+		index.Sessions[i].Tags = tags
+
+		session, err := cm.LoadSession(sessionID)
+		if err == nil {
+			session.Metadata.Tags = tags
+			if err := cm.SaveSession(session); err != nil {
+				return fmt.Errorf("failed to save session: %w", err)
+			}
+		}
+		return cm.SaveIndex(index)
+	}
+	return nil
+}
+
+// IsCacheValid checks if the cache is valid for the given database. The cache is valid
+// when it was built from the same path and the database has not been modified since, or
+// when the database has the same content, as a copy of it has, and its sessions were
+// normalized with the normalizer rules in effect by a build writing the same cache format.
 func (cm *CacheManager) IsCacheValid(dbPath string) (bool, error) {
 	indexPath := cm.GetIndexPath()
 
@@ -78,22 +216,56 @@ func (cm *CacheManager) IsCacheValid(dbPath string) (bool, error) {
 		return false, nil
 	}
 
-	// Check if database path matches
-	if index.Metadata.DatabasePath != dbPath {
+	if index.Metadata.CacheVersion != CacheFormatVersion || index.Metadata.RulesFingerprint != normalizerRules.Fingerprint() {
 		return false, nil
 	}
+	return index.Metadata.matchesDatabase(dbPath), nil
+}
 
-	// Check if database modification time matches
+// matchesDatabase reports whether the cache was built from the database at dbPath. A
+// database at the path the cache was built from must still have the same modification
+// time, which is cheaper to check than its fingerprint; a copy elsewhere must have the
+// same fingerprint. A directory, such as cursor-agent storage, must have both, since its
+// modification time does not change when the files in it do.
+func (m CacheMetadata) matchesDatabase(dbPath string) bool {
 	dbInfo, err := os.Stat(dbPath)
 	if err != nil {
-		return false, nil
+		return false
+	}
+	if m.DatabasePath == dbPath {
+		if !m.DatabaseModTime.Equal(dbInfo.ModTime()) {
+			return false
+		}
+		if !dbInfo.IsDir() {
+			return true
+		}
+	}
+	if m.DatabaseFingerprint == "" {
+		return false
 	}
 
-	if !index.Metadata.DatabaseModTime.Equal(dbInfo.ModTime()) {
-		return false, nil
+	fingerprint, err := StorageFingerprint(dbPath)
+	if err != nil {
+		LogDebug("Failed to fingerprint %s: %v", dbPath, err)
+		return false
 	}
+	if fingerprint != m.DatabaseFingerprint {
+		return false
+	}
+	LogDebug("Cache built from %s matches the content of %s", m.DatabasePath, dbPath)
+	return true
+}
 
-	return true, nil
+// storageFingerprint returns the fingerprint of the database to record in the cache index,
+// or an empty one if it cannot be read, leaving the cache to be validated by path and
+// modification time
+func storageFingerprint(dbPath string) string {
+	fingerprint, err := StorageFingerprint(dbPath)
+	if err != nil {
+		LogDebug("Failed to fingerprint %s: %v", dbPath, err)
+		return ""
+	}
+	return fingerprint
 }
 
 // GetCacheDir returns the cache directory path
@@ -203,6 +375,8 @@ func (cm *CacheManager) SaveSessionAndUpdateIndex(session *Session, dbPath strin
 			index = existingIndex
 			// Update metadata to reflect current database state
 			index.Metadata.DatabaseModTime = dbInfo.ModTime()
+			index.Metadata.DatabaseFingerprint = storageFingerprint(dbPath)
+			index.Metadata.RulesFingerprint = normalizerRules.Fingerprint()
 			index.Metadata.UpdatedAt = time.Now()
 		}
 	}
@@ -212,15 +386,19 @@ func (cm *CacheManager) SaveSessionAndUpdateIndex(session *Session, dbPath strin
 		index = &SessionIndex{
 			Sessions: make([]SessionIndexEntry, 0),
 			Metadata: CacheMetadata{
-				DatabasePath:    dbPath,
-				DatabaseModTime: dbInfo.ModTime(),
-				CacheVersion:    "1.0",
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
+				DatabasePath:        dbPath,
+				DatabaseModTime:     dbInfo.ModTime(),
+				DatabaseFingerprint: storageFingerprint(dbPath),
+				CacheVersion:        CacheFormatVersion,
+				RulesFingerprint:    normalizerRules.Fingerprint(),
+				CreatedAt:           time.Now(),
+				UpdatedAt:           time.Now(),
 			},
 		}
 	}
 
+	cm.applyTags([]*Session{session})
+
 	// Save session file
 	if err := cm.SaveSession(session); err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
@@ -231,15 +409,7 @@ func (cm *CacheManager) SaveSessionAndUpdateIndex(session *Session, dbPath strin
 	for i, entry := range index.Sessions {
 		if entry.ComposerID == session.Metadata.ComposerID {
 			// Update existing entry
-			index.Sessions[i] = SessionIndexEntry{
-				ID:           session.ID,
-				ComposerID:   session.Metadata.ComposerID,
-				Name:         session.Metadata.Name,
-				CreatedAt:    session.Metadata.CreatedAt,
-				UpdatedAt:    session.Metadata.UpdatedAt,
-				MessageCount: len(session.Messages),
-				Workspace:    session.Workspace,
-			}
+			index.Sessions[i] = NewSessionIndexEntry(session)
 			found = true
 			break
 		}
@@ -247,15 +417,7 @@ func (cm *CacheManager) SaveSessionAndUpdateIndex(session *Session, dbPath strin
 
 	if !found {
 		// Add new entry
-		index.Sessions = append(index.Sessions, SessionIndexEntry{
-			ID:           session.ID,
-			ComposerID:   session.Metadata.ComposerID,
-			Name:         session.Metadata.Name,
-			CreatedAt:    session.Metadata.CreatedAt,
-			UpdatedAt:    session.Metadata.UpdatedAt,
-			MessageCount: len(session.Messages),
-			Workspace:    session.Workspace,
-		})
+		index.Sessions = append(index.Sessions, NewSessionIndexEntry(session))
 	}
 
 	// Save updated index
@@ -277,14 +439,18 @@ func (cm *CacheManager) SaveSessions(sessions []*Session, dbPath string) error {
 	index := SessionIndex{
 		Sessions: make([]SessionIndexEntry, 0, len(sessions)),
 		Metadata: CacheMetadata{
-			DatabasePath:    dbPath,
-			DatabaseModTime: dbInfo.ModTime(),
-			CacheVersion:    "1.0",
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
+			DatabasePath:        dbPath,
+			DatabaseModTime:     dbInfo.ModTime(),
+			DatabaseFingerprint: storageFingerprint(dbPath),
+			CacheVersion:        CacheFormatVersion,
+			RulesFingerprint:    normalizerRules.Fingerprint(),
+			CreatedAt:           time.Now(),
+			UpdatedAt:           time.Now(),
 		},
 	}
 
+	cm.applyTags(sessions)
+
 	// Save each session and add to index
 	for _, session := range sessions {
 		if err := cm.SaveSession(session); err != nil {
@@ -292,15 +458,7 @@ func (cm *CacheManager) SaveSessions(sessions []*Session, dbPath string) error {
 			continue
 		}
 
-		index.Sessions = append(index.Sessions, SessionIndexEntry{
-			ID:           session.ID,
-			ComposerID:   session.Metadata.ComposerID,
-			Name:         session.Metadata.Name,
-			CreatedAt:    session.Metadata.CreatedAt,
-			UpdatedAt:    session.Metadata.UpdatedAt,
-			MessageCount: len(session.Messages),
-			Workspace:    session.Workspace,
-		})
+		index.Sessions = append(index.Sessions, NewSessionIndexEntry(session))
 	}
 
 	// Save index
@@ -329,15 +487,13 @@ func (cm *CacheManager) LoadConversations() ([]*ReconstructedConversation, error
 
 		// Convert messages
 		for _, msg := range session.Messages {
-			msgType := 2 // default to assistant
-			if msg.Actor == "user" {
-				msgType = 1
-			}
 			reconstructedMsg := ReconstructedMessage{
-				BubbleID:  fmt.Sprintf("bubble_%d", len(conv.Messages)),
-				Text:      msg.Content,
-				Type:      msgType,
-				Timestamp: parseTimestamp(msg.Timestamp),
+				BubbleID:   fmt.Sprintf("bubble_%d", len(conv.Messages)),
+				Text:       msg.Content,
+				Thinking:   msg.Thinking,
+				Type:       MessageTypeForActor(msg.Actor),
+				Timestamp:  parseTimestamp(msg.Timestamp),
+				Provenance: msg.Provenance,
 			}
 			conv.Messages = append(conv.Messages, reconstructedMsg)
 		}
@@ -348,6 +504,48 @@ func (cm *CacheManager) LoadConversations() ([]*ReconstructedConversation, error
 	return conversations, nil
 }
 
+// StaleReasons explains why the cache does not match the given database or storage path.
+// It returns nil when there is no cache yet or the cache is consistent.
+func (cm *CacheManager) StaleReasons(dbPath string) []string {
+	if _, err := os.Stat(cm.GetIndexPath()); os.IsNotExist(err) {
+		return nil
+	}
+
+	index, err := cm.LoadIndex()
+	if err != nil {
+		return []string{fmt.Sprintf("index is unreadable: %v", err)}
+	}
+
+	var reasons []string
+	if index.Metadata.DatabasePath != dbPath {
+		// A cache built from a copy of the database is still consistent
+		if !index.Metadata.matchesDatabase(dbPath) {
+			reasons = append(reasons, fmt.Sprintf("cache was built from %s", index.Metadata.DatabasePath))
+		}
+	} else if _, err := os.Stat(dbPath); err == nil && !index.Metadata.matchesDatabase(dbPath) {
+		reasons = append(reasons, fmt.Sprintf("database changed since the cache was built (%s)", index.Metadata.DatabaseModTime.Format(time.RFC3339)))
+	}
+
+	if index.Metadata.CacheVersion != CacheFormatVersion {
+		reasons = append(reasons, fmt.Sprintf("cache was built by another version of cursor-session (format %s)", index.Metadata.CacheVersion))
+	}
+	if index.Metadata.RulesFingerprint != normalizerRules.Fingerprint() {
+		reasons = append(reasons, "normalizer rules changed since the cache was built")
+	}
+
+	missing := 0
+	for _, entry := range index.Sessions {
+		if _, err := os.Stat(cm.GetSessionPath(entry.ID)); err != nil {
+			missing++
+		}
+	}
+	if missing > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d indexed session file(s) are missing", missing))
+	}
+
+	return reasons
+}
+
 // ClearCache clears the cache
 func (cm *CacheManager) ClearCache() error {
 	indexPath := cm.GetIndexPath()
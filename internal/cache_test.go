@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,7 +90,7 @@ func TestCacheManager_IsCacheValid(t *testing.T) {
 					Metadata: CacheMetadata{
 						DatabasePath:    dbPath,
 						DatabaseModTime: getFileModTime(t, dbPath),
-						CacheVersion:    "1.0",
+						CacheVersion:    CacheFormatVersion,
 					},
 				}
 				if err := cm.SaveIndex(index); err != nil {
@@ -98,6 +100,23 @@ func TestCacheManager_IsCacheValid(t *testing.T) {
 			want:    true,
 			wantErr: false,
 		},
+		{
+			name: "cache of an older format",
+			setup: func() {
+				index := &SessionIndex{
+					Metadata: CacheMetadata{
+						DatabasePath:    dbPath,
+						DatabaseModTime: getFileModTime(t, dbPath),
+						CacheVersion:    "1.0",
+					},
+				}
+				if err := cm.SaveIndex(index); err != nil {
+					t.Fatalf("SaveIndex() error = %v", err)
+				}
+			},
+			want:    false,
+			wantErr: false,
+		},
 		{
 			name: "cache exists but database path mismatch",
 			setup: func() {
@@ -105,7 +124,47 @@ func TestCacheManager_IsCacheValid(t *testing.T) {
 					Metadata: CacheMetadata{
 						DatabasePath:    "/different/path.db",
 						DatabaseModTime: time.Now(),
-						CacheVersion:    "1.0",
+						CacheVersion:    CacheFormatVersion,
+					},
+				}
+				if err := cm.SaveIndex(index); err != nil {
+					t.Fatalf("SaveIndex() error = %v", err)
+				}
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "cache built from a copy of the database",
+			setup: func() {
+				fingerprint, err := StorageFingerprint(dbPath)
+				if err != nil {
+					t.Fatalf("StorageFingerprint() error = %v", err)
+				}
+				index := &SessionIndex{
+					Metadata: CacheMetadata{
+						DatabasePath:        "/tmp/copy/state.vscdb",
+						DatabaseModTime:     time.Now().Add(-time.Hour),
+						DatabaseFingerprint: fingerprint,
+						CacheVersion:        CacheFormatVersion,
+					},
+				}
+				if err := cm.SaveIndex(index); err != nil {
+					t.Fatalf("SaveIndex() error = %v", err)
+				}
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "cache built from a database with other content",
+			setup: func() {
+				index := &SessionIndex{
+					Metadata: CacheMetadata{
+						DatabasePath:        dbPath,
+						DatabaseModTime:     time.Now().Add(-time.Hour),
+						DatabaseFingerprint: "sha256:0000",
+						CacheVersion:        CacheFormatVersion,
 					},
 				}
 				if err := cm.SaveIndex(index); err != nil {
@@ -122,7 +181,7 @@ func TestCacheManager_IsCacheValid(t *testing.T) {
 					Metadata: CacheMetadata{
 						DatabasePath:    dbPath,
 						DatabaseModTime: time.Now().Add(-time.Hour),
-						CacheVersion:    "1.0",
+						CacheVersion:    CacheFormatVersion,
 					},
 				}
 				if err := cm.SaveIndex(index); err != nil {
@@ -171,7 +230,7 @@ func TestCacheManager_SaveAndLoadIndex(t *testing.T) {
 		Metadata: CacheMetadata{
 			DatabasePath:    "/test/path.db",
 			DatabaseModTime: time.Now(),
-			CacheVersion:    "1.0",
+			CacheVersion:    CacheFormatVersion,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 		},
@@ -248,7 +307,7 @@ func TestCacheManager_LoadAllSessions(t *testing.T) {
 			{ID: session2.ID},
 		},
 		Metadata: CacheMetadata{
-			CacheVersion: "1.0",
+			CacheVersion: CacheFormatVersion,
 		},
 	}
 	if err := cm.SaveIndex(index); err != nil {
@@ -360,6 +419,7 @@ func TestCacheManager_SaveSessions(t *testing.T) {
 	session1.Metadata.ComposerID = "composer1"
 	session2 := CreateTestSession("session2")
 	session2.Metadata.ComposerID = "composer2"
+	session2.Metadata.GeneratedName = true
 
 	sessions := []*Session{session1, session2}
 
@@ -375,6 +435,8 @@ func TestCacheManager_SaveSessions(t *testing.T) {
 	}
 	if len(index.Sessions) != 2 {
 		t.Errorf("LoadIndex() returned %d sessions, want 2", len(index.Sessions))
+	} else if index.Sessions[0].GeneratedName || !index.Sessions[1].GeneratedName {
+		t.Errorf("LoadIndex() GeneratedName = %v, %v, want false, true", index.Sessions[0].GeneratedName, index.Sessions[1].GeneratedName)
 	}
 
 	// Verify sessions were saved
@@ -410,7 +472,7 @@ func TestCacheManager_LoadConversations(t *testing.T) {
 			{ID: session.ID},
 		},
 		Metadata: CacheMetadata{
-			CacheVersion: "1.0",
+			CacheVersion: CacheFormatVersion,
 		},
 	}
 	if err := cm.SaveIndex(index); err != nil {
@@ -431,6 +493,40 @@ func TestCacheManager_LoadConversations(t *testing.T) {
 	}
 }
 
+func TestCacheManager_LoadConversations_Actors(t *testing.T) {
+	cm := NewCacheManager(testutil.CreateTempDir(t))
+	if err := cm.EnsureCacheDir(); err != nil {
+		t.Fatalf("EnsureCacheDir() error = %v", err)
+	}
+
+	actors := MessageActors()
+	messages := make([]Message, len(actors))
+	for i, actor := range actors {
+		messages[i] = Message{Actor: actor, Content: "from " + actor}
+	}
+	session := CreateTestSessionWithMessages("actors", messages)
+	if err := cm.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	index := &SessionIndex{
+		Sessions: []SessionIndexEntry{{ID: session.ID}},
+		Metadata: CacheMetadata{CacheVersion: CacheFormatVersion},
+	}
+	if err := cm.SaveIndex(index); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	conversations, err := cm.LoadConversations()
+	if err != nil || len(conversations) != 1 {
+		t.Fatalf("LoadConversations() = %d conversations, %v, want 1", len(conversations), err)
+	}
+	for i, msg := range conversations[0].Messages {
+		if got := ActorForMessageType(msg.Type); got != actors[i] {
+			t.Errorf("message %d has type %d (%s), want %s", i, msg.Type, got, actors[i])
+		}
+	}
+}
+
 func TestCacheManager_ClearCache(t *testing.T) {
 	cacheDir := testutil.CreateTempDir(t)
 	cm := NewCacheManager(cacheDir)
@@ -449,7 +545,7 @@ func TestCacheManager_ClearCache(t *testing.T) {
 			{ID: session.ID},
 		},
 		Metadata: CacheMetadata{
-			CacheVersion: "1.0",
+			CacheVersion: CacheFormatVersion,
 		},
 	}
 	if err := cm.SaveIndex(index); err != nil {
@@ -475,6 +571,81 @@ func TestCacheManager_ClearCache(t *testing.T) {
 	}
 }
 
+func TestCacheManager_StaleReasons(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	if err := os.WriteFile(dbPath, []byte("db"), 0644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+	dbInfo, _ := os.Stat(dbPath)
+	fingerprint, err := StorageFingerprint(dbPath)
+	if err != nil {
+		t.Fatalf("StorageFingerprint() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		index *SessionIndex
+		want  []string
+	}{
+		{
+			name: "no cache",
+			want: nil,
+		},
+		{
+			name:  "consistent",
+			index: &SessionIndex{Metadata: CacheMetadata{DatabasePath: dbPath, DatabaseModTime: dbInfo.ModTime(), CacheVersion: CacheFormatVersion}},
+			want:  nil,
+		},
+		{
+			name:  "older cache format",
+			index: &SessionIndex{Metadata: CacheMetadata{DatabasePath: dbPath, DatabaseModTime: dbInfo.ModTime(), CacheVersion: "1.0"}},
+			want:  []string{"cache was built by another version of cursor-session (format 1.0)"},
+		},
+		{
+			name:  "copy of the database",
+			index: &SessionIndex{Metadata: CacheMetadata{DatabasePath: "/other/state.vscdb", DatabaseFingerprint: fingerprint, CacheVersion: CacheFormatVersion}},
+			want:  nil,
+		},
+		{
+			name:  "other database",
+			index: &SessionIndex{Metadata: CacheMetadata{DatabasePath: "/other/state.vscdb", CacheVersion: CacheFormatVersion}},
+			want:  []string{"cache was built from /other/state.vscdb"},
+		},
+		{
+			name: "modified database with missing session file",
+			index: &SessionIndex{
+				Sessions: []SessionIndexEntry{{ID: "gone"}},
+				Metadata: CacheMetadata{DatabasePath: dbPath, DatabaseModTime: dbInfo.ModTime().Add(-time.Hour), CacheVersion: CacheFormatVersion},
+			},
+			want: []string{
+				"database changed since the cache was built (" + dbInfo.ModTime().Add(-time.Hour).Format(time.RFC3339) + ")",
+				"1 indexed session file(s) are missing",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewCacheManager(testutil.CreateTempDir(t))
+			if tt.index != nil {
+				if err := cm.SaveIndex(tt.index); err != nil {
+					t.Fatalf("SaveIndex() error = %v", err)
+				}
+			}
+
+			got := cm.StaleReasons(dbPath)
+			if len(got) != len(tt.want) {
+				t.Fatalf("StaleReasons() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("StaleReasons()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
 func TestParseTimestamp(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -515,3 +686,139 @@ func TestParseTimestamp(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheManager_Tags(t *testing.T) {
+	cacheDir := testutil.CreateTempDir(t)
+	cm := NewCacheManager(cacheDir)
+
+	dbPath := filepath.Join(cacheDir, "test.db")
+	createTestDBFile(t, dbPath)
+
+	store, err := cm.LoadTags()
+	if err != nil {
+		t.Fatalf("LoadTags() error = %v", err)
+	}
+	if _, err := store.Add("session1", "bug-hunt"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Stored tags are copied onto sessions as they are cached
+	if err := cm.SaveSessions([]*Session{CreateTestSession("session1"), CreateTestSession("session2")}, dbPath); err != nil {
+		t.Fatalf("SaveSessions() error = %v", err)
+	}
+	index, err := cm.LoadIndex()
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+	if len(index.Sessions) != 2 || len(index.Sessions[0].Tags) != 1 || index.Sessions[1].Tags != nil {
+		t.Fatalf("index tags = %+v, want [bug-hunt] on session1 only", index.Sessions)
+	}
+
+	// SetSessionTags updates the cached session and its index entry
+	if err := cm.SetSessionTags("session2", []string{"prod-incident"}); err != nil {
+		t.Fatalf("SetSessionTags() error = %v", err)
+	}
+	index, _ = cm.LoadIndex()
+	if len(index.Sessions[1].Tags) != 1 || index.Sessions[1].Tags[0] != "prod-incident" {
+		t.Errorf("index entry Tags = %v, want [prod-incident]", index.Sessions[1].Tags)
+	}
+	session, err := cm.LoadSession("session2")
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	if len(session.Metadata.Tags) != 1 || session.Metadata.Tags[0] != "prod-incident" {
+		t.Errorf("cached session Tags = %v, want [prod-incident]", session.Metadata.Tags)
+	}
+
+	// Tags survive clearing the cache
+	if err := cm.ClearCache(); err != nil {
+		t.Fatalf("ClearCache() error = %v", err)
+	}
+	if _, err := os.Stat(cm.GetTagsPath()); err != nil {
+		t.Errorf("ClearCache() should keep the tag store: %v", err)
+	}
+}
+
+func TestCacheManager_IsCacheValid_Copy(t *testing.T) {
+	cm := NewCacheManager(testutil.CreateTempDir(t))
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	if err := cm.SaveSessions([]*Session{CreateTestSession("session1")}, dbPath); err != nil {
+		t.Fatalf("SaveSessions() error = %v", err)
+	}
+
+	// A copy, as a CI job downloads or --copy makes, has another path and modification time
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	writeFile(t, copyPath, string(data))
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(copyPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if valid, _ := cm.IsCacheValid(copyPath); !valid {
+		t.Error("IsCacheValid() = false for a copy of the cached database, want true")
+	}
+
+	db, err := sql.Open("sqlite", copyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM cursorDiskKV WHERE key = 'composerData:composer1'"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	if valid, _ := cm.IsCacheValid(copyPath); valid {
+		t.Error("IsCacheValid() = true for a modified copy, want false")
+	}
+}
+
+func TestCacheManager_IsCacheValid_ModifiedInPlace(t *testing.T) {
+	cm := NewCacheManager(testutil.CreateTempDir(t))
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	if err := cm.SaveSessions([]*Session{CreateTestSession("session1")}, dbPath); err != nil {
+		t.Fatalf("SaveSessions() error = %v", err)
+	}
+
+	// A database at the cached path that was modified is stale even if its fingerprint
+	// still matches, as it can after a checkpoint
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(dbPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if valid, _ := cm.IsCacheValid(dbPath); valid {
+		t.Error("IsCacheValid() = true for a database modified since it was cached, want false")
+	}
+}
+
+func TestCacheManager_IsCacheValid_Rules(t *testing.T) {
+	defer SetNormalizerRules(nil)
+	cm := NewCacheManager(testutil.CreateTempDir(t))
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	writeFile(t, dbPath, "database")
+	if err := cm.SaveSessions([]*Session{CreateTestSession("session1")}, dbPath); err != nil {
+		t.Fatalf("SaveSessions() error = %v", err)
+	}
+
+	// Sessions normalized with other rules are stale
+	SetNormalizerRules(&NormalizerRules{Placeholders: PlaceholderRule{Keep: true}})
+	if valid, _ := cm.IsCacheValid(dbPath); valid {
+		t.Error("IsCacheValid() = true after the rules changed, want false")
+	}
+	if reasons := cm.StaleReasons(dbPath); len(reasons) != 1 || !strings.Contains(reasons[0], "rules") {
+		t.Errorf("StaleReasons() = %v, want the rules named", reasons)
+	}
+
+	if err := cm.SaveSessions([]*Session{CreateTestSession("session1")}, dbPath); err != nil {
+		t.Fatalf("SaveSessions() error = %v", err)
+	}
+	if valid, _ := cm.IsCacheValid(dbPath); !valid {
+		t.Error("IsCacheValid() = false for sessions saved with the rules in effect, want true")
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/muesli/termenv"
+)
+
+// ClipboardOSC52 is reported by CopyToClipboard when the text was sent to the terminal
+const ClipboardOSC52 = "osc52"
+
+// CopyToClipboard places text on the system clipboard with the first clipboard program
+// found: pbcopy on macOS, clip.exe on Windows, and wl-copy (under Wayland), xclip or xsel
+// elsewhere. Without one it falls back to sending the text to the terminal on stderr in
+// an OSC 52 escape sequence, which most terminal emulators, and tmux, put on the clipboard
+// of the machine they run on, even over SSH. It returns the program used, or
+// ClipboardOSC52.
+func CopyToClipboard(text string) (string, error) {
+	return copyToClipboard(text, clipboardCommands(runtime.GOOS, os.Getenv("WAYLAND_DISPLAY") != ""), os.Stderr)
+}
+
+// clipboardCommands returns the clipboard programs to try on an operating system, each
+// with the arguments that make it read the clipboard's new content from stdin
+func clipboardCommands(goos string, wayland bool) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+	commands := [][]string{{"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	if wayland {
+		commands = append([][]string{{"wl-copy"}}, commands...)
+	}
+	return commands
+}
+
+// copyToClipboard implements CopyToClipboard with the programs to try and the terminal
+// to fall back to
+func copyToClipboard(text string, commands [][]string, terminal io.Writer) (string, error) {
+	var tried []string
+	for _, command := range commands {
+		tried = append(tried, command[0])
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		// xclip and wl-copy stay in the background to serve the clipboard, so their
+		// output is not captured: waiting for it to close would wait for them to exit
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			LogDebug("Failed to copy with %s: %v", command[0], err)
+			continue
+		}
+		return command[0], nil
+	}
+
+	if !isTerminal(terminal) {
+		return "", fmt.Errorf("no clipboard program found (tried %s) and no terminal to copy through", strings.Join(tried, ", "))
+	}
+	termenv.NewOutput(terminal).Copy(text)
+	return ClipboardOSC52, nil
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestClipboardCommands(t *testing.T) {
+	if got := clipboardCommands("darwin", false); !reflect.DeepEqual(got, [][]string{{"pbcopy"}}) {
+		t.Errorf("darwin = %v, want pbcopy", got)
+	}
+	if got := clipboardCommands("windows", false); !reflect.DeepEqual(got, [][]string{{"clip.exe"}}) {
+		t.Errorf("windows = %v, want clip.exe", got)
+	}
+	if got := clipboardCommands("linux", false); got[0][0] != "xclip" || len(got) != 2 {
+		t.Errorf("linux = %v, want xclip then xsel", got)
+	}
+	if got := clipboardCommands("linux", true); got[0][0] != "wl-copy" || len(got) != 3 {
+		t.Errorf("linux under Wayland = %v, want wl-copy first", got)
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the clipboard program")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "clipboard.txt")
+	program := filepath.Join(dir, "fake-copy")
+	if err := os.WriteFile(program, []byte("#!/bin/sh\ncat > \""+out+"\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write program: %v", err)
+	}
+
+	// Programs that are missing or fail are skipped
+	failing := filepath.Join(dir, "failing-copy")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write program: %v", err)
+	}
+	used, err := copyToClipboard("# Session\n", [][]string{{"no-such-clipboard-program"}, {failing}, {program}}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("copyToClipboard() error = %v", err)
+	}
+	if used != program {
+		t.Errorf("copyToClipboard() used %s, want %s", used, program)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "# Session\n" {
+		t.Errorf("clipboard = %q", data)
+	}
+
+	// Without a program or a terminal to fall back to, nothing can be copied
+	if _, err := copyToClipboard("text", [][]string{{"no-such-clipboard-program"}}, &bytes.Buffer{}); err == nil {
+		t.Error("copyToClipboard() without a program or terminal should fail")
+	}
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CodeDiff is a structured edit applied to a file by the agent, built from a codeBlockDiff entry
+type CodeDiff struct {
+	DiffID   string     `json:"diff_id,omitempty"`
+	BubbleID string     `json:"bubble_id,omitempty"`
+	FilePath string     `json:"file_path,omitempty"`
+	Status   string     `json:"status,omitempty"` // "accepted", "rejected", ... as recorded by Cursor
+	Hunks    []DiffHunk `json:"hunks"`
+}
+
+// DiffHunk is one changed region of a file. OriginalStart and OriginalEnd are 1-based line
+// numbers in the original file (end exclusive); Before is empty when Cursor did not record
+// the replaced lines.
+type DiffHunk struct {
+	OriginalStart int      `json:"original_start"`
+	OriginalEnd   int      `json:"original_end"`
+	Before        []string `json:"before,omitempty"`
+	After         []string `json:"after,omitempty"`
+}
+
+// codeBlockRef links a diff to the bubble and file it was applied from
+type codeBlockRef struct {
+	BubbleID string
+	FilePath string
+	Status   string
+}
+
+// ParseCodeDiffs converts the raw codeBlockDiff values returned by LoadCodeBlockDiffs into
+// structured diffs, keyed by composer ID. Entries without any hunks are skipped.
+func ParseCodeDiffs(raw map[string][]interface{}) map[string][]CodeDiff {
+	diffs := make(map[string][]CodeDiff)
+	for composerID, values := range raw {
+		for _, value := range values {
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			diff, ok := parseCodeDiff(fields)
+			if !ok {
+				LogDebug("Skipping code block diff without hunks in composer %s", composerID)
+				continue
+			}
+			diffs[composerID] = append(diffs[composerID], diff)
+		}
+	}
+
+	// Sort by diff ID so exports do not depend on storage order
+	for composerID := range diffs {
+		sort.SliceStable(diffs[composerID], func(i, j int) bool {
+			return diffs[composerID][i].DiffID < diffs[composerID][j].DiffID
+		})
+	}
+	return diffs
+}
+
+// parseCodeDiff reads a single codeBlockDiff value
+func parseCodeDiff(fields map[string]interface{}) (CodeDiff, bool) {
+	diff := CodeDiff{
+		DiffID:   stringField(fields, "diffId"),
+		BubbleID: stringField(fields, "bubbleId"),
+		FilePath: uriPath(fields["uri"]),
+		Status:   stringField(fields, "status"),
+	}
+	if diff.FilePath == "" {
+		diff.FilePath = stringField(fields, "filePath")
+	}
+
+	for _, key := range []string{"newModelDiffWrtV0", "hunks", "changes"} {
+		items, ok := fields[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			if hunk, ok := parseDiffHunk(item); ok {
+				diff.Hunks = append(diff.Hunks, hunk)
+			}
+		}
+		break
+	}
+
+	// Whole-file edits store the full text before and after instead of hunks
+	if len(diff.Hunks) == 0 {
+		before, after := textLines(fields["before"]), textLines(fields["after"])
+		if before != nil || after != nil {
+			diff.Hunks = append(diff.Hunks, DiffHunk{
+				OriginalStart: 1,
+				OriginalEnd:   len(before) + 1,
+				Before:        before,
+				After:         after,
+			})
+		}
+	}
+
+	return diff, len(diff.Hunks) > 0
+}
+
+// parseDiffHunk reads a hunk in Cursor's {original: {startLineNumber, endLineNumberExclusive}, modified: [...]} shape
+func parseDiffHunk(item interface{}) (DiffHunk, bool) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return DiffHunk{}, false
+	}
+
+	hunk := DiffHunk{
+		Before: textLines(fields["before"]),
+		After:  textLines(fields["modified"]),
+	}
+	if hunk.After == nil {
+		hunk.After = textLines(fields["after"])
+	}
+	if hunk.Before == nil {
+		hunk.Before = textLines(fields["originalLines"])
+	}
+
+	if original, ok := fields["original"].(map[string]interface{}); ok {
+		hunk.OriginalStart = intField(original, "startLineNumber")
+		hunk.OriginalEnd = intField(original, "endLineNumberExclusive")
+	}
+	if hunk.OriginalStart == 0 {
+		hunk.OriginalStart = 1
+	}
+	if hunk.OriginalEnd < hunk.OriginalStart {
+		hunk.OriginalEnd = hunk.OriginalStart + len(hunk.Before)
+	}
+
+	return hunk, hunk.Before != nil || hunk.After != nil || hunk.OriginalEnd > hunk.OriginalStart
+}
+
+// codeBlockRefs indexes the composer's codeBlockData by diff ID. Cursor stores the entries
+// for each file URI either as a list or as an object keyed by code block index.
+func (c *RawComposer) codeBlockRefs() map[string]codeBlockRef {
+	refs := make(map[string]codeBlockRef)
+	for uri, data := range c.CodeBlockData {
+		var entries []map[string]interface{}
+		if err := json.Unmarshal(data, &entries); err != nil {
+			var byIndex map[string]map[string]interface{}
+			if err := json.Unmarshal(data, &byIndex); err != nil {
+				continue
+			}
+			for _, entry := range byIndex {
+				entries = append(entries, entry)
+			}
+		}
+
+		for _, entry := range entries {
+			diffID := stringField(entry, "diffId")
+			if diffID == "" {
+				continue
+			}
+			ref := codeBlockRef{
+				BubbleID: stringField(entry, "bubbleId"),
+				FilePath: uriPath(entry["uri"]),
+				Status:   stringField(entry, "status"),
+			}
+			if ref.FilePath == "" {
+				ref.FilePath = uriPath(uri)
+			}
+			refs[diffID] = ref
+		}
+	}
+	return refs
+}
+
+// resolve fills in the bubble, file and status of a diff from the composer's code block data
+func (ref codeBlockRef) resolve(diff *CodeDiff) {
+	if diff.BubbleID == "" {
+		diff.BubbleID = ref.BubbleID
+	}
+	if diff.FilePath == "" {
+		diff.FilePath = ref.FilePath
+	}
+	if diff.Status == "" {
+		diff.Status = ref.Status
+	}
+}
+
+// Unified renders the diff in unified diff format, without file headers
+func (d CodeDiff) Unified() string {
+	var b strings.Builder
+	offset := 0
+	for _, hunk := range d.Hunks {
+		removed := hunk.OriginalEnd - hunk.OriginalStart
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OriginalStart, removed, hunk.OriginalStart+offset, len(hunk.After))
+		for _, line := range hunk.Before {
+			fmt.Fprintf(&b, "-%s\n", line)
+		}
+		for _, line := range hunk.After {
+			fmt.Fprintf(&b, "+%s\n", line)
+		}
+		offset += len(hunk.After) - removed
+	}
+	return b.String()
+}
+
+// uriPath returns a filesystem path from a URI string or a VS Code URI object
+func uriPath(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "file://") {
+			if u, err := url.Parse(v); err == nil {
+				return u.Path
+			}
+		}
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"fsPath", "path", "external"} {
+			if s := stringField(v, key); s != "" {
+				return uriPath(s)
+			}
+		}
+	}
+	return ""
+}
+
+// textLines returns a list of lines from a string or a JSON array of strings
+func textLines(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return []string{}
+		}
+		return strings.Split(strings.TrimSuffix(v, "\n"), "\n")
+	case []interface{}:
+		lines := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				lines = append(lines, s)
+			}
+		}
+		return lines
+	}
+	return nil
+}
+
+// stringField returns fields[key] if it is a string
+func stringField(fields map[string]interface{}, key string) string {
+	s, _ := fields[key].(string)
+	return s
+}
+
+// intField returns fields[key] if it is a JSON number
+func intField(fields map[string]interface{}, key string) int {
+	f, _ := fields[key].(float64)
+	return int(f)
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseCodeDiffs(t *testing.T) {
+	raw := map[string][]interface{}{
+		"composer1": {
+			map[string]interface{}{
+				"diffId": "diff2",
+				"newModelDiffWrtV0": []interface{}{
+					map[string]interface{}{
+						"original": map[string]interface{}{"startLineNumber": float64(3), "endLineNumberExclusive": float64(5)},
+						"modified": []interface{}{"x := 1"},
+					},
+				},
+			},
+			map[string]interface{}{
+				"diffId": "diff1",
+				"uri":    map[string]interface{}{"fsPath": "/repo/new.go"},
+				"before": "",
+				"after":  "package main\n",
+			},
+			map[string]interface{}{"diffId": "diff3"},
+			"not an object",
+		},
+	}
+
+	diffs := ParseCodeDiffs(raw)["composer1"]
+	if len(diffs) != 2 {
+		t.Fatalf("ParseCodeDiffs() returned %d diffs, want 2", len(diffs))
+	}
+
+	if diffs[0].DiffID != "diff1" || diffs[0].FilePath != "/repo/new.go" {
+		t.Errorf("diffs[0] = %+v, want diff1 for /repo/new.go", diffs[0])
+	}
+	wantWhole := DiffHunk{OriginalStart: 1, OriginalEnd: 1, Before: []string{}, After: []string{"package main"}}
+	if !reflect.DeepEqual(diffs[0].Hunks, []DiffHunk{wantWhole}) {
+		t.Errorf("diffs[0].Hunks = %+v, want %+v", diffs[0].Hunks, wantWhole)
+	}
+
+	wantHunk := DiffHunk{OriginalStart: 3, OriginalEnd: 5, After: []string{"x := 1"}}
+	if !reflect.DeepEqual(diffs[1].Hunks, []DiffHunk{wantHunk}) {
+		t.Errorf("diffs[1].Hunks = %+v, want %+v", diffs[1].Hunks, wantHunk)
+	}
+}
+
+func TestCodeDiff_Unified(t *testing.T) {
+	diff := CodeDiff{Hunks: []DiffHunk{
+		{OriginalStart: 2, OriginalEnd: 3, Before: []string{"old"}, After: []string{"new", "added"}},
+		{OriginalStart: 10, OriginalEnd: 10, After: []string{"tail"}},
+	}}
+
+	want := "@@ -2,1 +2,2 @@\n-old\n+new\n+added\n@@ -10,0 +11,1 @@\n+tail\n"
+	if got := diff.Unified(); got != want {
+		t.Errorf("Unified() = %q, want %q", got, want)
+	}
+}
+
+func TestRawComposer_CodeBlockRefs(t *testing.T) {
+	composer := &RawComposer{CodeBlockData: map[string]json.RawMessage{
+		"file:///repo/a%20b.go": json.RawMessage(`[{"diffId":"d1","bubbleId":"b1","status":"accepted"}]`),
+		"file:///repo/c.go":     json.RawMessage(`{"0":{"diffId":"d2","bubbleId":"b2","uri":{"path":"/repo/c.go"}}}`),
+		"file:///repo/d.go":     json.RawMessage(`"unexpected"`),
+	}}
+
+	refs := composer.codeBlockRefs()
+	want := map[string]codeBlockRef{
+		"d1": {BubbleID: "b1", FilePath: "/repo/a b.go", Status: "accepted"},
+		"d2": {BubbleID: "b2", FilePath: "/repo/c.go"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("codeBlockRefs() = %+v, want %+v", refs, want)
+	}
+}
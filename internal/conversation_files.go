@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ConversationFilePattern matches the files WriteConversation writes
+const ConversationFilePattern = "conversation_*.json"
+
+// WriteConversation writes a reconstructed conversation to conversation_<id>.json in dir
+func WriteConversation(dir string, conv *ReconstructedConversation) error {
+	data, err := json.MarshalIndent(conv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation %s: %w", conv.ComposerID, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("conversation_%s.json", conv.ComposerID))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadConversations reads the conversations WriteConversation wrote to dir, ordered by
+// file name
+func ReadConversations(dir string) ([]*ReconstructedConversation, error) {
+	files, err := filepath.Glob(filepath.Join(dir, ConversationFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	conversations := make([]*ReconstructedConversation, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var conv ReconstructedConversation
+		if err := json.Unmarshal(data, &conv); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		conversations = append(conversations, &conv)
+	}
+	return conversations, nil
+}
@@ -0,0 +1,88 @@
+package internal
+
+// CorpusStats sums up a set of sessions, as printed by the stats command
+type CorpusStats struct {
+	Sessions        int            `json:"sessions"`
+	Messages        int            `json:"messages"`
+	MessagesByActor map[string]int `json:"messages_by_actor"`
+	EstimatedTokens int            `json:"estimated_tokens"`
+	Workspaces      int            `json:"workspaces"`
+	FirstCreated    string         `json:"first_created,omitempty"`
+	LastCreated     string         `json:"last_created,omitempty"`
+	// ResponseLatency is set when any assistant message has a response time
+	ResponseLatency *LatencyStats `json:"response_latency,omitempty"`
+	// Quality is set when the quality signals of the sessions were assessed
+	Quality *QualityStats `json:"quality,omitempty"`
+}
+
+// QualityStats counts the sessions raising each quality flag and lists them
+type QualityStats struct {
+	FlaggedSessions int                  `json:"flagged_sessions"`
+	ByFlag          map[string]int       `json:"by_flag"`
+	Sessions        []SessionQualityInfo `json:"sessions,omitempty"` // Flagged sessions only
+}
+
+// SessionQualityInfo is the quality of a session, named
+type SessionQualityInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	SessionQuality
+}
+
+// NewCorpusStats sums up sessions, assessing their quality when quality is set
+func NewCorpusStats(sessions []*Session, quality bool) CorpusStats {
+	stats := CorpusStats{MessagesByActor: make(map[string]int)}
+	if quality {
+		stats.Quality = &QualityStats{ByFlag: make(map[string]int, len(QualityFlags))}
+		for _, flag := range QualityFlags {
+			stats.Quality.ByFlag[flag] = 0
+		}
+	}
+
+	workspaces := make(map[string]bool)
+	var first, last int64
+	var responseTimes []int64
+	for _, session := range sessions {
+		if session == nil {
+			continue
+		}
+		stats.Sessions++
+		stats.Messages += len(session.Messages)
+		stats.EstimatedTokens += ThinkingTokens(session)
+		responseTimes = append(responseTimes, ResponseTimes(session)...)
+		for _, msg := range session.Messages {
+			stats.MessagesByActor[msg.Actor]++
+			stats.EstimatedTokens += EstimateTokens(msg.Content)
+		}
+		if session.Workspace != "" {
+			workspaces[session.Workspace] = true
+		}
+		if created := parseTimestamp(session.Metadata.CreatedAt); created > 0 {
+			if first == 0 || created < first {
+				first, stats.FirstCreated = created, UTCTimestamp(session.Metadata.CreatedAt)
+			}
+			if created > last {
+				last, stats.LastCreated = created, UTCTimestamp(session.Metadata.CreatedAt)
+			}
+		}
+
+		if stats.Quality != nil {
+			q := AssessQuality(session)
+			if len(q.Flags) == 0 {
+				continue
+			}
+			stats.Quality.FlaggedSessions++
+			for _, flag := range q.Flags {
+				stats.Quality.ByFlag[flag]++
+			}
+			stats.Quality.Sessions = append(stats.Quality.Sessions, SessionQualityInfo{
+				ID:             session.ID,
+				Name:           session.Metadata.Name,
+				SessionQuality: q,
+			})
+		}
+	}
+	stats.Workspaces = len(workspaces)
+	stats.ResponseLatency = NewLatencyStats(responseTimes)
+	return stats
+}
@@ -0,0 +1,46 @@
+package internal
+
+import "testing"
+
+func TestNewCorpusStats(t *testing.T) {
+	first := CreateTestSessionWithMessages("first", []Message{
+		{Actor: ActorUser, Content: "Question"},
+		{Actor: ActorAssistant, Content: "A complete answer to the question", ResponseMs: 1500},
+	})
+	first.Workspace = "/code/api"
+	first.Metadata.CreatedAt = "2024-06-01T10:00:00Z"
+	second := CreateTestSessionWithMessages("second", []Message{{Actor: ActorUser, Content: "Anyone?"}})
+	second.Workspace = "/code/api"
+	second.Metadata.CreatedAt = "2024-05-01T10:00:00+02:00"
+
+	stats := NewCorpusStats([]*Session{first, second, nil}, false)
+	if stats.Sessions != 2 || stats.Messages != 3 || stats.Workspaces != 1 {
+		t.Errorf("stats = %+v, want 2 sessions, 3 messages and 1 workspace", stats)
+	}
+	if stats.MessagesByActor[ActorUser] != 2 || stats.MessagesByActor[ActorAssistant] != 1 {
+		t.Errorf("MessagesByActor = %v", stats.MessagesByActor)
+	}
+	if stats.FirstCreated != "2024-05-01T08:00:00Z" || stats.LastCreated != "2024-06-01T10:00:00Z" {
+		t.Errorf("created span = %s to %s", stats.FirstCreated, stats.LastCreated)
+	}
+	if stats.EstimatedTokens == 0 {
+		t.Error("EstimatedTokens = 0")
+	}
+	if stats.Quality != nil {
+		t.Error("Quality is set without being asked for")
+	}
+	if latency := stats.ResponseLatency; latency == nil || latency.Responses != 1 || latency.P50Ms != 1500 {
+		t.Errorf("ResponseLatency = %+v, want the one response of 1500ms", latency)
+	}
+	if latency := NewCorpusStats([]*Session{second}, false).ResponseLatency; latency != nil {
+		t.Errorf("ResponseLatency = %+v for sessions without response times, want nil", latency)
+	}
+
+	stats = NewCorpusStats([]*Session{first, second}, true)
+	if stats.Quality == nil || stats.Quality.FlaggedSessions != 1 || stats.Quality.ByFlag[FlagAborted] != 1 || stats.Quality.ByFlag[FlagLoop] != 0 {
+		t.Fatalf("Quality = %+v, want the second session flagged aborted", stats.Quality)
+	}
+	if len(stats.Quality.Sessions) != 1 || stats.Quality.Sessions[0].ID != "second" {
+		t.Errorf("flagged sessions = %+v", stats.Quality.Sessions)
+	}
+}
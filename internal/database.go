@@ -2,12 +2,45 @@ package internal
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// DefaultBusyTimeout is how long SQLite waits for another connection to release a lock
+// before giving up with SQLITE_BUSY
+const DefaultBusyTimeout = 5 * time.Second
+
+// busyRetries is how many times an operation is retried after SQLITE_BUSY
+const busyRetries = 3
+
+var (
+	busyTimeout    = DefaultBusyTimeout
+	busyRetryDelay = 200 * time.Millisecond
+)
+
+// SetBusyTimeout sets how long SQLite waits on a locked database before reporting it busy
+func SetBusyTimeout(d time.Duration) {
+	busyTimeout = d
+}
+
+// databaseDSN returns a data source name that opens path read-only with the configured
+// busy timeout. The file: prefix is required for the driver to honor the parameters.
+// With the snapshot read strategy the file is opened as immutable: SQLite then takes no
+// locks and ignores the -wal.
+func databaseDSN(path string) string {
+	escaped := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	immutable := 0
+	if readStrategy == ReadSnapshot {
+		immutable = 1
+	}
+	return fmt.Sprintf("file:%s?mode=ro&immutable=%d&_pragma=busy_timeout(%d)", escaped, immutable, busyTimeout.Milliseconds())
+}
+
 // OpenDatabase opens a SQLite database in read-only mode
 func OpenDatabase(path string) (*sql.DB, error) {
 	// Check if file exists when opening in read-only mode
@@ -15,13 +48,13 @@ func OpenDatabase(path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("database file does not exist: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", path+"?mode=ro")
+	db, err := sql.Open("sqlite", databaseDSN(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Test connection
-	if err := db.Ping(); err != nil {
+	if err := retryBusy(path, db.Ping); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("database ping failed: %w", err)
 	}
@@ -29,8 +62,71 @@ func OpenDatabase(path string) (*sql.DB, error) {
 	return db, nil
 }
 
-// QueryCursorDiskKV queries the cursorDiskKV table with a LIKE pattern
-func QueryCursorDiskKV(db *sql.DB, pattern string) ([]KeyValuePair, error) {
+// retryBusy runs fn, retrying a few times while SQLite reports the database as locked.
+// If the lock outlasts the retries, the error is returned as a DatabaseLockedError.
+func retryBusy(path string, fn func() error) error {
+	err := fn()
+	var locked *DatabaseLockedError
+	if errors.As(err, &locked) {
+		return err // already retried by a nested call
+	}
+	for attempt := 1; attempt <= busyRetries && IsDatabaseLocked(err); attempt++ {
+		LogDebug("Database %s is locked, retrying (%d/%d)", path, attempt, busyRetries)
+		time.Sleep(busyRetryDelay * time.Duration(attempt))
+		err = fn()
+	}
+	if IsDatabaseLocked(err) {
+		return &DatabaseLockedError{Path: path, Err: err}
+	}
+	return err
+}
+
+// ProbeDatabase opens a database read-only and reads its schema, returning any error
+// SQLite reports, such as a lock held by a running Cursor instance
+func ProbeDatabase(path string) error {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	var tables int
+	err = retryBusy(path, func() error {
+		return db.QueryRow("SELECT count(*) FROM sqlite_master").Scan(&tables)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	return nil
+}
+
+// IsDatabaseLocked reports whether err is SQLite refusing access because another
+// connection holds a lock on the database
+func IsDatabaseLocked(err error) bool {
+	if err == nil {
+		return false
+	}
+	var locked *DatabaseLockedError
+	if errors.As(err, &locked) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "sqlite_busy")
+}
+
+// QueryCursorDiskKV queries the cursorDiskKV table of the database at path with a LIKE
+// pattern, retrying while the database is locked
+func QueryCursorDiskKV(db *sql.DB, path, pattern string) ([]KeyValuePair, error) {
+	var pairs []KeyValuePair
+	err := retryBusy(path, func() error {
+		var err error
+		pairs, err = queryCursorDiskKV(db, pattern)
+		return err
+	})
+	return pairs, err
+}
+
+func queryCursorDiskKV(db *sql.DB, pattern string) ([]KeyValuePair, error) {
 	query := "SELECT key, value FROM cursorDiskKV WHERE key LIKE ? AND value IS NOT NULL"
 	rows, err := db.Query(query, pattern)
 	if err != nil {
@@ -58,6 +154,67 @@ func QueryCursorDiskKV(db *sql.DB, pattern string) ([]KeyValuePair, error) {
 	return pairs, nil
 }
 
+// QueryCursorDiskKVKey reads the value of a single cursorDiskKV key, retrying while the
+// database is locked. found is false if the key doesn't exist.
+func QueryCursorDiskKVKey(db *sql.DB, path, key string) (value string, found bool, err error) {
+	err = retryBusy(path, func() error {
+		var raw sql.NullString
+		err := db.QueryRow("SELECT value FROM cursorDiskKV WHERE key = ?", key).Scan(&raw)
+		if err == sql.ErrNoRows {
+			value, found = "", false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		value, found = raw.String, raw.Valid
+		return nil
+	})
+	return value, found, err
+}
+
+// QueryItemTable reads a single value from the ItemTable key-value store used by
+// workspace databases, of the database at path. found is false if the table or key
+// doesn't exist.
+func QueryItemTable(db *sql.DB, path, key string) (value string, found bool, err error) {
+	err = retryBusy(path, func() error {
+		var err error
+		value, found, err = queryItemTable(db, key)
+		return err
+	})
+	return value, found, err
+}
+
+func queryItemTable(db *sql.DB, key string) (value string, found bool, err error) {
+	var tableExists bool
+	err = db.QueryRow(`
+		SELECT EXISTS (
+			SELECT name FROM sqlite_master
+			WHERE type='table' AND name='ItemTable'
+		)
+	`).Scan(&tableExists)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check for ItemTable: %w", err)
+	}
+	if !tableExists {
+		return "", false, nil
+	}
+
+	var raw sql.NullString
+	err = db.QueryRow("SELECT value FROM ItemTable WHERE key = ?", key).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("query failed: %w", err)
+	}
+	if !raw.Valid {
+		return "", false, nil
+	}
+
+	return raw.String, true, nil
+}
+
 // KeyValuePair represents a key-value pair from cursorDiskKV
 type KeyValuePair struct {
 	Key   string
@@ -1,8 +1,12 @@
 package internal
 
 import (
+	"database/sql"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/iksnae/cursor-session/testutil"
 )
@@ -105,7 +109,7 @@ func TestQueryCursorDiskKV(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pairs, err := QueryCursorDiskKV(db, tt.pattern)
+			pairs, err := QueryCursorDiskKV(db, "", tt.pattern)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("QueryCursorDiskKV() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -150,7 +154,7 @@ func TestQueryCursorDiskKV_NullValues(t *testing.T) {
 		t.Fatalf("Failed to insert valid value: %v", err)
 	}
 
-	pairs, err := QueryCursorDiskKV(db, "test:%")
+	pairs, err := QueryCursorDiskKV(db, "", "test:%")
 	if err != nil {
 		t.Fatalf("QueryCursorDiskKV() error = %v", err)
 	}
@@ -174,3 +178,190 @@ func matchesPattern(key, pattern string) bool {
 	}
 	return key == pattern
 }
+
+func TestProbeDatabase(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	if err := ProbeDatabase(dbPath); err != nil {
+		t.Errorf("ProbeDatabase() error = %v", err)
+	}
+	if err := ProbeDatabase(dbPath + ".missing"); err == nil {
+		t.Error("ProbeDatabase() should fail for a missing database")
+	}
+}
+
+func TestIsDatabaseLocked(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"locked", errors.New("failed to read schema: database is locked (5) (SQLITE_BUSY)"), true},
+		{"other", errors.New("file is not a database"), false},
+		{"typed", &DatabaseLockedError{Path: "state.vscdb", Err: errors.New("busy")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDatabaseLocked(tt.err); got != tt.want {
+				t.Errorf("IsDatabaseLocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabaseDSN(t *testing.T) {
+	defer SetBusyTimeout(DefaultBusyTimeout)
+	SetBusyTimeout(1500 * time.Millisecond)
+
+	got := databaseDSN("/tmp/50%?#/state.vscdb")
+	want := "file:/tmp/50%25%3f%23/state.vscdb?mode=ro&immutable=0&_pragma=busy_timeout(1500)"
+	if got != want {
+		t.Errorf("databaseDSN() = %q, want %q", got, want)
+	}
+
+	defer SetReadStrategy(ReadAuto)
+	SetReadStrategy(ReadSnapshot)
+	if got := databaseDSN("/tmp/state.vscdb"); !strings.Contains(got, "immutable=1") {
+		t.Errorf("databaseDSN() with the snapshot strategy = %q, want immutable=1", got)
+	}
+}
+
+func TestOpenDatabase_ReadOnly(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.Exec("INSERT INTO cursorDiskKV (key, value) VALUES ('k', 'v')"); err == nil {
+		t.Error("OpenDatabase() should open the database read-only")
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	defer func(d time.Duration) { busyRetryDelay = d }(busyRetryDelay)
+	busyRetryDelay = 0
+
+	t.Run("succeeds after transient lock", func(t *testing.T) {
+		calls := 0
+		err := retryBusy("state.vscdb", func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("database is locked (5) (SQLITE_BUSY)")
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("retryBusy() = %v after %d calls, want nil after 3", err, calls)
+		}
+	})
+
+	t.Run("gives up with DatabaseLockedError", func(t *testing.T) {
+		calls := 0
+		err := retryBusy("state.vscdb", func() error {
+			calls++
+			return errors.New("database is locked (5) (SQLITE_BUSY)")
+		})
+		var locked *DatabaseLockedError
+		if !errors.As(err, &locked) || locked.Path != "state.vscdb" {
+			t.Fatalf("retryBusy() error = %v, want DatabaseLockedError", err)
+		}
+		if calls != busyRetries+1 {
+			t.Errorf("retryBusy() made %d calls, want %d", calls, busyRetries+1)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		calls := 0
+		err := retryBusy("state.vscdb", func() error {
+			calls++
+			return errors.New("file is not a database")
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("retryBusy() = %v after %d calls, want an error after 1", err, calls)
+		}
+	})
+}
+
+func TestProbeDatabase_Locked(t *testing.T) {
+	defer SetBusyTimeout(DefaultBusyTimeout)
+	defer func(d time.Duration) { busyRetryDelay = d }(busyRetryDelay)
+	SetBusyTimeout(10 * time.Millisecond)
+	busyRetryDelay = 0
+
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	writer, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open writer: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+	writer.SetMaxOpenConns(1)
+	if _, err := writer.Exec("BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("failed to lock database: %v", err)
+	}
+	defer func() { _, _ = writer.Exec("ROLLBACK") }()
+
+	err = ProbeDatabase(dbPath)
+	var locked *DatabaseLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("ProbeDatabase() error = %v, want DatabaseLockedError", err)
+	}
+	if !strings.Contains(err.Error(), "--copy") {
+		t.Errorf("error should suggest --copy, got: %q", err.Error())
+	}
+}
+
+func TestQueryCursorDiskKV_LockedReportsPath(t *testing.T) {
+	defer SetBusyTimeout(DefaultBusyTimeout)
+	defer func(d time.Duration) { busyRetryDelay = d }(busyRetryDelay)
+	SetBusyTimeout(10 * time.Millisecond)
+	busyRetryDelay = 0
+
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	writer, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open writer: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+	writer.SetMaxOpenConns(1)
+	if _, err := writer.Exec("BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("failed to lock database: %v", err)
+	}
+	defer func() { _, _ = writer.Exec("ROLLBACK") }()
+
+	queries := map[string]func() error{
+		"QueryCursorDiskKV": func() error {
+			_, err := QueryCursorDiskKV(db, dbPath, "composerData:%")
+			return err
+		},
+		"QueryCursorDiskKVKey": func() error {
+			_, _, err := QueryCursorDiskKVKey(db, dbPath, "composerData:composer1")
+			return err
+		},
+		"QueryItemTable": func() error {
+			_, _, err := QueryItemTable(db, dbPath, "workbench.panel.aichat.view.aichat.chatdata")
+			return err
+		},
+	}
+	for name, query := range queries {
+		var locked *DatabaseLockedError
+		if err := query(); !errors.As(err, &locked) || locked.Path != dbPath {
+			t.Errorf("%s() error = %v, want a DatabaseLockedError for %s", name, err, dbPath)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"container/list"
+	"database/sql"
+	"os"
+	"sync"
+)
+
+// DefaultMaxOpenDBs is how many databases are kept open at once by default
+const DefaultMaxOpenDBs = 64
+
+// DBPool hands out connections to SQLite databases, keeping at most a given number of
+// them open at once, so that reading hundreds of cursor-agent store.db files in parallel
+// does not run out of file descriptors. A database released by every reader stays open
+// until another needs its place; the one released longest ago is closed first. Readers
+// wanting a database while the pool is full of databases in use wait for one to be
+// released.
+type DBPool struct {
+	mu    sync.Mutex
+	freed *sync.Cond
+	max   int
+	dbs   map[string]*pooledDB
+	idle  *list.List // Databases no reader holds, released longest ago first
+}
+
+// pooledDB is a database of a DBPool, opened once ready is closed
+type pooledDB struct {
+	path  string
+	db    *sql.DB
+	err   error
+	info  os.FileInfo // The file when it was opened, to tell when it was replaced
+	ready chan struct{}
+	refs  int
+	idle  *list.Element // Set while no reader holds the database
+}
+
+// NewDBPool returns a DBPool keeping at most max databases open; max is at least 1
+func NewDBPool(max int) *DBPool {
+	if max < 1 {
+		max = 1
+	}
+	p := &DBPool{max: max, dbs: make(map[string]*pooledDB), idle: list.New()}
+	p.freed = sync.NewCond(&p.mu)
+	return p
+}
+
+// Acquire returns the open database at path, opening it once a place is free, and the
+// function releasing it; the database must not be used or closed once released
+func (p *DBPool) Acquire(path string) (*sql.DB, func(), error) {
+	p.mu.Lock()
+	for {
+		if e, ok := p.dbs[path]; ok && !p.replaced(e) {
+			e.refs++
+			if e.idle != nil {
+				p.idle.Remove(e.idle)
+				e.idle = nil
+			}
+			p.mu.Unlock()
+			<-e.ready
+			if e.err != nil {
+				return nil, nil, e.err
+			}
+			return e.db, p.releaser(e), nil
+		}
+		if len(p.dbs) < p.max {
+			break
+		}
+		if oldest := p.idle.Front(); oldest != nil {
+			p.closeIdle(oldest.Value.(*pooledDB))
+			continue
+		}
+		p.freed.Wait()
+	}
+
+	// Reserve the place before opening the database, which may wait on a lock
+	e := &pooledDB{path: path, ready: make(chan struct{}), refs: 1}
+	p.dbs[path] = e
+	p.mu.Unlock()
+
+	e.info, _ = os.Stat(path)
+	e.db, e.err = OpenDatabase(path)
+	close(e.ready)
+	if e.err != nil {
+		p.mu.Lock()
+		delete(p.dbs, path)
+		p.freed.Broadcast()
+		p.mu.Unlock()
+		return nil, nil, e.err
+	}
+	return e.db, p.releaser(e), nil
+}
+
+// replaced reports whether the file of an idle database was replaced since it was
+// opened, so that the old connection is closed rather than handed out again. It is
+// called with the lock held.
+func (p *DBPool) replaced(e *pooledDB) bool {
+	if e.idle == nil || e.info == nil {
+		return false
+	}
+	info, err := os.Stat(e.path)
+	if err == nil && os.SameFile(info, e.info) {
+		return false
+	}
+	p.closeIdle(e)
+	return true
+}
+
+// releaser returns the function releasing a database held by a reader, once
+func (p *DBPool) releaser(e *pooledDB) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			e.refs--
+			if e.refs == 0 {
+				e.idle = p.idle.PushBack(e)
+				p.freed.Broadcast()
+			}
+		})
+	}
+}
+
+// closeIdle closes a database no reader holds and frees its place. It is called with the
+// lock held.
+func (p *DBPool) closeIdle(e *pooledDB) {
+	p.idle.Remove(e.idle)
+	e.idle = nil
+	delete(p.dbs, e.path)
+	_ = e.db.Close()
+	p.freed.Broadcast()
+}
+
+// Open returns how many databases the pool has open, or is opening
+func (p *DBPool) Open() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.dbs)
+}
+
+// CloseIdle closes the databases no reader holds
+func (p *DBPool) CloseIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.idle.Len() > 0 {
+		p.closeIdle(p.idle.Front().Value.(*pooledDB))
+	}
+}
+
+// agentDBs is the pool cursor-agent store.db files are read through
+var agentDBs = NewDBPool(DefaultMaxOpenDBs)
+
+// SetMaxOpenDBs sets how many cursor-agent store.db files are kept open at once, closing
+// the databases left open by earlier reads; 0 or less keeps the default
+func SetMaxOpenDBs(n int) {
+	if n < 1 {
+		n = DefaultMaxOpenDBs
+	}
+	agentDBs.CloseIdle()
+	agentDBs = NewDBPool(n)
+}
+
+// CloseAgentDBs closes the cursor-agent store.db files left open by earlier reads
+func CloseAgentDBs() {
+	agentDBs.CloseIdle()
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createPoolTestDB writes a database at path holding value in table t
+func createPoolTestDB(t *testing.T, path, value string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	for _, stmt := range []string{"CREATE TABLE t (v TEXT)", "INSERT INTO t VALUES ('" + value + "')"} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to write database: %v", err)
+		}
+	}
+}
+
+func poolTestValue(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	var value string
+	if err := db.QueryRow("SELECT v FROM t").Scan(&value); err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	return value
+}
+
+func TestDBPool_ClosesLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	paths := make([]string, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		paths[i] = filepath.Join(dir, name+".db")
+		createPoolTestDB(t, paths[i], name)
+	}
+
+	pool := NewDBPool(2)
+	defer pool.CloseIdle()
+	dbA, releaseA, err := pool.Acquire(paths[0])
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	releaseA()
+	dbB, releaseB, err := pool.Acquire(paths[1])
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	releaseB()
+
+	// Reading a again makes b the database released longest ago
+	again, releaseA, err := pool.Acquire(paths[0])
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if again != dbA {
+		t.Error("Acquire() reopened a database that was still open")
+	}
+	releaseA()
+
+	dbC, releaseC, err := pool.Acquire(paths[2])
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer releaseC()
+	if got := poolTestValue(t, dbC); got != "c" {
+		t.Errorf("c holds %q", got)
+	}
+	if n := pool.Open(); n != 2 {
+		t.Errorf("Open() = %d, want 2", n)
+	}
+	if err := dbB.Ping(); err == nil {
+		t.Error("b is still open; it was released longest ago and should have been closed")
+	}
+	if got := poolTestValue(t, dbA); got != "a" {
+		t.Errorf("a holds %q", got)
+	}
+}
+
+func TestDBPool_WaitsForRelease(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.db"), filepath.Join(dir, "second.db")
+	createPoolTestDB(t, first, "first")
+	createPoolTestDB(t, second, "second")
+
+	pool := NewDBPool(1)
+	defer pool.CloseIdle()
+	_, release, err := pool.Acquire(first)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	acquired := make(chan string)
+	go func() {
+		db, release, err := pool.Acquire(second)
+		if err != nil {
+			acquired <- err.Error()
+			return
+		}
+		defer release()
+		var value string
+		if err := db.QueryRow("SELECT v FROM t").Scan(&value); err != nil {
+			value = err.Error()
+		}
+		acquired <- value
+	}()
+
+	select {
+	case got := <-acquired:
+		t.Fatalf("Acquire() returned %q while the pool was full of databases in use", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	release() // Releasing twice must not free another place
+	select {
+	case got := <-acquired:
+		if got != "second" {
+			t.Errorf("second holds %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire() still waiting after the database in use was released")
+	}
+	if n := pool.Open(); n != 1 {
+		t.Errorf("Open() = %d, want 1", n)
+	}
+}
+
+func TestDBPool_ReopensReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.db")
+	createPoolTestDB(t, path, "old")
+
+	pool := NewDBPool(4)
+	defer pool.CloseIdle()
+	db, release, err := pool.Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if got := poolTestValue(t, db); got != "old" {
+		t.Fatalf("database holds %q", got)
+	}
+	release()
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	createPoolTestDB(t, path, "new")
+	db, release, err = pool.Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer release()
+	if got := poolTestValue(t, db); got != "new" {
+		t.Errorf("database holds %q after it was replaced, want the new file", got)
+	}
+
+	if _, _, err := pool.Acquire(filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("Acquire() opened a missing database")
+	}
+	if n := pool.Open(); n != 1 {
+		t.Errorf("Open() = %d after a failed open, want 1", n)
+	}
+}
@@ -3,6 +3,9 @@ package internal
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // Deduplicator removes duplicate sessions
@@ -13,32 +16,134 @@ func NewDeduplicator() *Deduplicator {
 	return &Deduplicator{}
 }
 
-// Deduplicate removes duplicate sessions based on content hash
+// HashMessage returns a content address for a message: the SHA-256 of its actor and its
+// text with line endings and runs of whitespace normalized. Timestamps and IDs are left
+// out so the same message read from two copies of a conversation hashes the same.
+func HashMessage(msg Message) string {
+	h := sha256.New()
+	h.Write([]byte(msg.Actor))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeMessageText(msg.Content)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeMessageText trims text and collapses whitespace within each line, keeping the
+// line breaks
+func normalizeMessageText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// minResumedOverlap is the fewest messages a session must share with the start of a longer
+// one to be folded into it as a resumed copy. Single messages such as "continue" or
+// "thanks" are common to unrelated sessions.
+const minResumedOverlap = 2
+
+// Deduplicate removes sessions that duplicate another one: exact copies, whose messages
+// and timestamps are all the same, and earlier copies of a conversation that was later
+// resumed, whose messages are a strict prefix of at least minResumedOverlap messages of a
+// longer session in the same workspace. The longest variant is kept and the IDs of the
+// sessions folded into it are recorded once in its Metadata.Duplicates, so deduplicating
+// again records nothing new. Sessions keep their original order.
 func (d *Deduplicator) Deduplicate(sessions []*Session) []*Session {
-	seen := make(map[string]bool)
-	var unique []*Session
+	hashes := make([][]string, len(sessions))
+	for i, session := range sessions {
+		hashes[i] = make([]string, len(session.Messages))
+		for j, msg := range session.Messages {
+			hashes[i][j] = HashMessage(msg)
+		}
+	}
+
+	// Visit longer sessions first so every session is compared against the variants that
+	// could contain it
+	order := make([]int, len(sessions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(hashes[order[a]]) > len(hashes[order[b]])
+	})
+
+	keptBy := make(map[int]int)       // index of each dropped session -> index of its kept variant
+	exact := make(map[string]int)     // exact copy hash -> kept session
+	byFirst := make(map[string][]int) // first message hash -> kept sessions starting with it
+	for _, i := range order {
+		copyHash := hashSessionCopy(sessions[i], hashes[i])
+		if k, ok := exact[copyHash]; ok {
+			keptBy[i] = k
+			continue
+		}
+		if len(hashes[i]) >= minResumedOverlap {
+			if k, ok := findResumed(sessions, hashes, byFirst[hashes[i][0]], i); ok {
+				keptBy[i] = k
+				continue
+			}
+		}
+		exact[copyHash] = i
+		if len(hashes[i]) > 0 {
+			byFirst[hashes[i][0]] = append(byFirst[hashes[i][0]], i)
+		}
+	}
 
-	for _, session := range sessions {
-		hash := d.hashSessionContent(session)
-		if !seen[hash] {
-			seen[hash] = true
+	var unique []*Session
+	for i, session := range sessions {
+		k, dropped := keptBy[i]
+		if !dropped {
 			unique = append(unique, session)
+			continue
+		}
+		LogDebug("Session %s duplicates session %s", session.ID, sessions[k].ID)
+		// IDs already recorded, as when sessions are deduplicated again, are not repeated,
+		// and the duplicates of a dropped session move to the one kept
+		kept := &sessions[k].Metadata
+		for _, id := range append([]string{session.ID}, session.Metadata.Duplicates...) {
+			if !slices.Contains(kept.Duplicates, id) {
+				kept.Duplicates = append(kept.Duplicates, id)
+			}
 		}
 	}
 
 	return unique
 }
 
-// hashSessionContent creates a content-based hash for a session
-func (d *Deduplicator) hashSessionContent(session *Session) string {
+// hashSessionCopy returns the hash of a session's message hashes and timestamps, which
+// exact copies of the session share
+func hashSessionCopy(session *Session, hashes []string) string {
 	h := sha256.New()
-
-	// Hash all message content
-	for _, msg := range session.Messages {
-		h.Write([]byte(msg.Actor))
-		h.Write([]byte(msg.Content))
+	for j, msg := range session.Messages {
+		h.Write([]byte(hashes[j]))
 		h.Write([]byte(msg.Timestamp))
+		h.Write([]byte{0})
 	}
-
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// findResumed returns the first of the candidate sessions that continues session i: it
+// is in the same workspace and longer, and its messages start with all of session i's
+func findResumed(sessions []*Session, hashes [][]string, candidates []int, i int) (int, bool) {
+	for _, k := range candidates {
+		if sessions[k].Workspace != sessions[i].Workspace || len(hashes[k]) <= len(hashes[i]) {
+			continue
+		}
+		if hasPrefix(hashes[k], hashes[i]) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// hasPrefix reports whether seq starts with prefix
+func hasPrefix(seq, prefix []string) bool {
+	if len(prefix) > len(seq) {
+		return false
+	}
+	for j := range prefix {
+		if seq[j] != prefix[j] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -121,26 +122,143 @@ func TestDeduplicator_Deduplicate_DifferentContent(t *testing.T) {
 	}
 }
 
-func TestDeduplicator_hashSessionContent(t *testing.T) {
+func TestHashMessage(t *testing.T) {
+	hello := HashMessage(Message{Actor: "user", Content: "Hello world"})
+
+	if got := HashMessage(Message{Actor: "user", Content: "Hello world"}); got != hello {
+		t.Error("HashMessage() should be stable for the same message")
+	}
+	if got := HashMessage(Message{Actor: "user", Content: "  Hello   world \r\n", Timestamp: "2024-03-01T12:00:00Z", Provenance: &Provenance{BlobKey: "bubbleId:c:b2"}}); got != hello {
+		t.Error("HashMessage() should ignore whitespace differences, timestamps and provenance")
+	}
+	if got := HashMessage(Message{Actor: "assistant", Content: "Hello world"}); got == hello {
+		t.Error("HashMessage() should depend on the actor")
+	}
+	if got := HashMessage(Message{Actor: "user", Content: "Hello\nworld"}); got == hello {
+		t.Error("HashMessage() should keep line breaks")
+	}
+}
+
+func TestDeduplicator_Deduplicate_Superset(t *testing.T) {
+	original := CreateTestSessionWithMessages("original", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done"},
+	})
+	resumed := CreateTestSessionWithMessages("resumed", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done"},
+		{Actor: "user", Content: "Now add tests"},
+		{Actor: "assistant", Content: "Added"},
+	})
+	tail := CreateTestSessionWithMessages("tail", []Message{
+		{Actor: "user", Content: "Now add tests"},
+		{Actor: "assistant", Content: "Added"},
+	})
+	unrelated := CreateTestSessionWithMessages("unrelated", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Which one?"},
+	})
+
 	d := NewDeduplicator()
+	got := d.Deduplicate([]*Session{original, resumed, tail, unrelated})
 
-	session1 := CreateTestSessionWithMessages("session1", []Message{
-		{Actor: "user", Content: "Hello"},
+	// Only the prefix is folded; a run in the middle of another session is kept
+	if len(got) != 3 || got[0] != resumed || got[1] != tail || got[2] != unrelated {
+		ids := make([]string, len(got))
+		for i, session := range got {
+			ids[i] = session.ID
+		}
+		t.Fatalf("Deduplicate() kept %v, want [resumed tail unrelated]", ids)
+	}
+	want := []string{"original"}
+	if !reflect.DeepEqual(resumed.Metadata.Duplicates, want) {
+		t.Errorf("Duplicates = %v, want %v", resumed.Metadata.Duplicates, want)
+	}
+	if len(unrelated.Metadata.Duplicates) != 0 {
+		t.Errorf("unrelated Duplicates = %v, want none", unrelated.Metadata.Duplicates)
+	}
+}
+
+func TestDeduplicator_Deduplicate_Twice(t *testing.T) {
+	messages := []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done"},
+	}
+	first := CreateTestSessionWithMessages("first", messages)
+	second := CreateTestSessionWithMessages("second", messages)
+	resumed := CreateTestSessionWithMessages("resumed", append(append([]Message{}, messages...), Message{Actor: "user", Content: "Now add tests"}))
+
+	d := NewDeduplicator()
+	sessions := []*Session{first, second, resumed}
+	d.Deduplicate(sessions)
+	got := d.Deduplicate(sessions)
+
+	if len(got) != 1 || got[0] != resumed {
+		t.Fatalf("Deduplicate() kept %d session(s), want only resumed", len(got))
+	}
+	want := []string{"first", "second"}
+	if !reflect.DeepEqual(resumed.Metadata.Duplicates, want) {
+		t.Errorf("Duplicates after deduplicating twice = %v, want %v", resumed.Metadata.Duplicates, want)
+	}
+
+	// A session that absorbed duplicates passes them on when it is folded in turn
+	longer := CreateTestSessionWithMessages("longer", append(append([]Message{}, resumed.Messages...), Message{Actor: "assistant", Content: "Added"}))
+	d.Deduplicate([]*Session{resumed, longer})
+	want = []string{"resumed", "first", "second"}
+	if !reflect.DeepEqual(longer.Metadata.Duplicates, want) {
+		t.Errorf("Duplicates = %v, want %v", longer.Metadata.Duplicates, want)
+	}
+}
+
+func TestDeduplicator_Deduplicate_SharedMessage(t *testing.T) {
+	long := CreateTestSessionWithMessages("long", []Message{
+		{Actor: "user", Content: "continue", Timestamp: "2024-03-01T10:00:00Z"},
+		{Actor: "assistant", Content: "Refactored the parser"},
+	})
+	short := CreateTestSessionWithMessages("short", []Message{
+		{Actor: "user", Content: "continue", Timestamp: "2024-03-02T09:00:00Z"},
 	})
-	hash1 := d.hashSessionContent(session1)
 
-	// Same session should produce same hash
-	hash2 := d.hashSessionContent(session1)
-	if hash1 != hash2 {
-		t.Error("hashSessionContent() should be stable for same session")
+	d := NewDeduplicator()
+	if got := d.Deduplicate([]*Session{long, short}); len(got) != 2 {
+		t.Errorf("Deduplicate() returned %d sessions, want 2 (a single shared message does not fold sessions)", len(got))
 	}
+	if len(long.Metadata.Duplicates) != 0 {
+		t.Errorf("Duplicates = %v, want none", long.Metadata.Duplicates)
+	}
+}
 
-	// Different session with different content should produce different hash
-	session2 := CreateTestSessionWithMessages("session2", []Message{
-		{Actor: "user", Content: "Goodbye"},
+func TestDeduplicator_Deduplicate_OtherWorkspace(t *testing.T) {
+	original := CreateTestSessionWithMessages("original", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done"},
+	})
+	original.Workspace = "other-workspace"
+	resumed := CreateTestSessionWithMessages("resumed", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done"},
+		{Actor: "user", Content: "Now add tests"},
 	})
-	hash3 := d.hashSessionContent(session2)
-	if hash1 == hash3 {
-		t.Error("hashSessionContent() should produce different hashes for different sessions")
+
+	d := NewDeduplicator()
+	if got := d.Deduplicate([]*Session{original, resumed}); len(got) != 2 {
+		t.Errorf("Deduplicate() returned %d sessions, want 2 (sessions are in different workspaces)", len(got))
+	}
+}
+
+func TestDeduplicator_Deduplicate_NonContiguous(t *testing.T) {
+	long := CreateTestSessionWithMessages("long", []Message{
+		{Actor: "user", Content: "One"},
+		{Actor: "assistant", Content: "Two"},
+		{Actor: "user", Content: "Three"},
+	})
+	gapped := CreateTestSessionWithMessages("gapped", []Message{
+		{Actor: "user", Content: "One"},
+		{Actor: "user", Content: "Three"},
+	})
+
+	d := NewDeduplicator()
+	if got := d.Deduplicate([]*Session{long, gapped}); len(got) != 2 {
+		t.Errorf("Deduplicate() returned %d sessions, want 2 (messages are not a contiguous run)", len(got))
 	}
 }
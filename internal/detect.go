@@ -17,6 +17,12 @@ type StoragePaths struct {
 	GlobalStorage    string // globalStorage directory (modern format)
 	BasePath         string // Base Cursor User directory
 	AgentStoragePath string // cursor-agent CLI storage directory (~/.cursor/chats/)
+	ExportDir        string // Directory of previously exported session files, used instead of the databases
+	// Location is a storage location given as a URI, such as s3://bucket/path, read by the
+	// backend registered for its scheme instead of the databases
+	Location string
+	// CopiedFrom is the storage location these paths are a temporary copy of, if any
+	CopiedFrom *StoragePaths
 }
 
 // DetectStoragePaths detects the Cursor storage paths based on the operating system
@@ -30,16 +36,28 @@ func DetectStoragePaths() (StoragePaths, error) {
 //   - Path to a database file (state.vscdb or store.db): use that file
 //   - Path to globalStorage directory: use that directory
 //   - Path to agent storage directory: use that directory
+//   - Path to a directory of exported sessions or intermediary dumps: read those files
+//   - A URI such as s3://bucket/path: read by the backend registered for its scheme
 func GetStoragePaths(customPath string) (StoragePaths, error) {
 	// If no custom path provided, use auto-detection
 	if customPath == "" {
 		return detectStoragePathsAuto()
 	}
 
+	// Locations with a scheme are left to the backend registered for it
+	if scheme := LocationScheme(customPath); scheme == "file" {
+		customPath = customPath[len("file://"):]
+	} else if scheme != "" {
+		if len(BackendProviders(scheme)) == 0 {
+			return StoragePaths{}, fmt.Errorf("%w: no storage backend registered for %s://", ErrNoStorage, scheme)
+		}
+		return StoragePaths{Location: customPath}, nil
+	}
+
 	// Check if custom path exists
 	info, err := os.Stat(customPath)
 	if err != nil {
-		return StoragePaths{}, fmt.Errorf("custom storage path does not exist: %w", err)
+		return StoragePaths{}, fmt.Errorf("%w: custom storage path does not exist: %w", ErrNoStorage, err)
 	}
 
 	// If it's a file, determine what type of database it is
@@ -89,6 +107,11 @@ func GetStoragePaths(customPath string) (StoragePaths, error) {
 			}, nil
 		}
 
+		// Archives are extracted before their storage is detected
+		if IsStorageArchive(customPath) {
+			return StoragePaths{}, fmt.Errorf("storage archive %s must be extracted first (see ExtractStorageArchive)", filename)
+		}
+
 		// Unknown file type
 		return StoragePaths{}, fmt.Errorf("unsupported database file: %s (expected state.vscdb or store.db)", filename)
 	}
@@ -136,49 +159,185 @@ func GetStoragePaths(customPath string) (StoragePaths, error) {
 		}, nil
 	}
 
+	// Check if it's a directory of exported session files (an archive)
+	if IsExportDir(customPath) {
+		return StoragePaths{ExportDir: customPath}, nil
+	}
+
 	// Unknown directory type
-	return StoragePaths{}, fmt.Errorf("directory does not appear to be a valid Cursor storage location (expected globalStorage directory with state.vscdb, or agent storage directory with store.db files)")
+	return StoragePaths{}, fmt.Errorf("directory does not appear to be a valid Cursor storage location (expected globalStorage directory with state.vscdb, agent storage directory with store.db files, or a directory of exported sessions)")
 }
 
-// detectStoragePathsAuto detects the Cursor storage paths based on the operating system
-func detectStoragePathsAuto() (StoragePaths, error) {
+// InstallCandidate is a location where a Cursor build may keep its User directory
+type InstallCandidate struct {
+	Name     string // Human-readable build name, e.g. "Cursor Nightly" or "Cursor (snap)"
+	BasePath string // The build's User directory
+}
+
+// InstallCandidates returns the User directories of known Cursor builds and packagings
+// for this OS, in priority order: stable first, then Nightly/Insiders, then sandboxed packages
+func InstallCandidates() ([]InstallCandidate, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return StoragePaths{}, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	var basePath string
-	var agentStoragePath string
 	switch runtime.GOOS {
 	case "darwin":
-		basePath = filepath.Join(home, "Library/Application Support/Cursor/User")
-		// Agent storage is Linux-only
-		agentStoragePath = ""
+		appSupport := filepath.Join(home, "Library/Application Support")
+		return []InstallCandidate{
+			{Name: "Cursor", BasePath: filepath.Join(appSupport, "Cursor/User")},
+			{Name: "Cursor Nightly", BasePath: filepath.Join(appSupport, "Cursor Nightly/User")},
+			{Name: "Cursor Insiders", BasePath: filepath.Join(appSupport, "Cursor Insiders/User")},
+		}, nil
 	case "linux":
-		basePath = filepath.Join(home, ".config/Cursor/User")
-		// Check both possible locations for cursor-agent storage
-		// Priority: .config/cursor/chats (newer location) then .cursor/chats (older location)
-		configCursorChats := filepath.Join(home, ".config/cursor/chats")
-		dotCursorChats := filepath.Join(home, ".cursor/chats")
-
-		if info, err := os.Stat(configCursorChats); err == nil && info.IsDir() {
-			agentStoragePath = configCursorChats
-		} else if info, err := os.Stat(dotCursorChats); err == nil && info.IsDir() {
-			agentStoragePath = dotCursorChats
-		} else {
-			// Default to .cursor/chats if neither exists (for backward compatibility)
-			agentStoragePath = dotCursorChats
+		// Electron apps honour XDG_CONFIG_HOME for their config directory
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
 		}
+		return []InstallCandidate{
+			{Name: "Cursor", BasePath: filepath.Join(configHome, "Cursor/User")},
+			{Name: "Cursor Nightly", BasePath: filepath.Join(configHome, "Cursor Nightly/User")},
+			{Name: "Cursor Insiders", BasePath: filepath.Join(configHome, "Cursor Insiders/User")},
+			{Name: "Cursor (flatpak)", BasePath: filepath.Join(home, ".var/app/com.cursor.Cursor/config/Cursor/User")},
+			{Name: "Cursor (snap)", BasePath: filepath.Join(home, "snap/cursor/current/.config/Cursor/User")},
+		}, nil
 	default:
-		return StoragePaths{}, fmt.Errorf("unsupported OS: %s (only macOS and Linux are supported)", runtime.GOOS)
+		return nil, fmt.Errorf("unsupported OS: %s (only macOS and Linux are supported)", runtime.GOOS)
 	}
+}
 
+// StoragePathsForBase returns the storage paths of a Cursor User directory
+func StoragePathsForBase(basePath string) StoragePaths {
 	return StoragePaths{
 		WorkspaceStorage: filepath.Join(basePath, "workspaceStorage"),
 		GlobalStorage:    filepath.Join(basePath, "globalStorage"),
 		BasePath:         basePath,
-		AgentStoragePath: agentStoragePath,
-	}, nil
+	}
+}
+
+// DetectAllStoragePaths returns the storage paths of every installed Cursor build that
+// has a globalStorage database, in priority order. Agent storage is shared between
+// builds, so it is only attached to the first entry (or returned alone if no build has data).
+func DetectAllStoragePaths() ([]StoragePaths, error) {
+	candidates, err := InstallCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	agentStoragePath := detectAgentStoragePath()
+	var all []StoragePaths
+	for _, candidate := range candidates {
+		paths := StoragePathsForBase(candidate.BasePath)
+		if !paths.GlobalStorageExists() {
+			continue
+		}
+		if len(all) == 0 {
+			paths.AgentStoragePath = agentStoragePath
+		}
+		all = append(all, paths)
+	}
+
+	if len(all) == 0 {
+		paths := StoragePathsForBase(candidates[0].BasePath)
+		paths.AgentStoragePath = agentStoragePath
+		all = append(all, paths)
+	}
+	return all, nil
+}
+
+// detectStoragePathsAuto detects the Cursor storage paths based on the operating system,
+// for commands that inspect a single location. The first installed build with a
+// globalStorage database wins; without one, the stable build's location is returned.
+func detectStoragePathsAuto() (StoragePaths, error) {
+	all, err := DetectAllStoragePaths()
+	if err != nil {
+		return StoragePaths{}, err
+	}
+	return all[0], nil
+}
+
+// Environment variables that move cursor-agent storage away from its default directories
+const (
+	// EnvAgentDir names the cursor-agent storage directory to use, skipping detection
+	EnvAgentDir = "CURSOR_SESSION_AGENT_DIR"
+	// EnvCursorConfigDir is cursor-agent's own override of its config directory, which
+	// then holds the chats directory
+	EnvCursorConfigDir = "CURSOR_CONFIG_DIR"
+)
+
+// AgentStorageCandidates returns the directories where cursor-agent may keep its sessions
+// on this OS, in priority order
+func AgentStorageCandidates() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return agentStorageCandidates(runtime.GOOS, home)
+}
+
+// agentStorageCandidates returns the cursor-agent storage directories for an OS and home
+// directory. cursor-agent writes to ~/.cursor/chats on every OS; Linux CI installs use
+// ~/.config/cursor/chats and macOS installs may use Application Support.
+// The directory set in the environment, if any, comes first, or alone when explicit.
+func agentStorageCandidates(goos, home string) []string {
+	override, explicit := agentStorageOverride()
+	if explicit {
+		return []string{override}
+	}
+
+	var candidates []string
+	if override != "" {
+		candidates = append(candidates, override)
+	}
+	dotCursorChats := filepath.Join(home, ".cursor/chats")
+	switch goos {
+	case "linux":
+		return append(candidates, filepath.Join(home, ".config/cursor/chats"), dotCursorChats)
+	case "darwin":
+		return append(candidates, dotCursorChats, filepath.Join(home, "Library/Application Support/Cursor/chats"))
+	default:
+		return append(candidates, dotCursorChats)
+	}
+}
+
+// agentStorageOverride returns the cursor-agent storage directory set in the environment:
+// CURSOR_SESSION_AGENT_DIR, which is explicit, or else the chats directory under
+// CURSOR_CONFIG_DIR. It is empty when neither is set.
+func agentStorageOverride() (dir string, explicit bool) {
+	if dir := os.Getenv(EnvAgentDir); dir != "" {
+		return dir, true
+	}
+	if configDir := os.Getenv(EnvCursorConfigDir); configDir != "" {
+		return filepath.Join(configDir, "chats"), false
+	}
+	return "", false
+}
+
+// detectAgentStoragePath returns the cursor-agent CLI storage directory
+func detectAgentStoragePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return detectAgentStoragePathIn(runtime.GOOS, home)
+}
+
+// detectAgentStoragePathIn returns the first existing cursor-agent storage directory for an
+// OS and home directory. If none exists, it is the directory set in the environment, where
+// cursor-agent will create it, or else ~/.cursor/chats.
+func detectAgentStoragePathIn(goos, home string) string {
+	for _, candidate := range agentStorageCandidates(goos, home) {
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+	}
+	if override, _ := agentStorageOverride(); override != "" {
+		return override
+	}
+	// Default to .cursor/chats if none exists (for backward compatibility)
+	return filepath.Join(home, ".cursor/chats")
 }
 
 // GetGlobalStorageDBPath returns the path to the globalStorage state.vscdb file
@@ -272,6 +431,7 @@ func CopyStoragePaths(paths StoragePaths) (StoragePaths, func() error, error) {
 	}
 
 	newPaths := paths
+	newPaths.CopiedFrom = &paths
 
 	// Copy globalStorage database if it exists
 	if paths.GlobalStorageExists() {
@@ -340,6 +500,56 @@ func CopyStoragePaths(paths StoragePaths) (StoragePaths, func() error, error) {
 	return newPaths, cleanup, nil
 }
 
+// GetStoragePathsList resolves each custom storage path with GetStoragePaths. With no
+// custom paths, the default storage is auto-detected; DetectAllStoragePaths returns
+// every installed build instead.
+func GetStoragePathsList(customPaths []string) ([]StoragePaths, error) {
+	if len(customPaths) == 0 {
+		paths, err := GetStoragePaths("")
+		if err != nil {
+			return nil, err
+		}
+		return []StoragePaths{paths}, nil
+	}
+
+	list := make([]StoragePaths, 0, len(customPaths))
+	for _, customPath := range customPaths {
+		paths, err := GetStoragePaths(customPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", customPath, err)
+		}
+		list = append(list, paths)
+	}
+	return list, nil
+}
+
+// CopyStoragePathsList copies the databases of every storage location with
+// CopyStoragePaths. The returned cleanup function removes all of the copies.
+func CopyStoragePathsList(list []StoragePaths) ([]StoragePaths, func() error, error) {
+	copied := make([]StoragePaths, 0, len(list))
+	var cleanups []func() error
+	cleanup := func() error {
+		var firstErr error
+		for _, fn := range cleanups {
+			if err := fn(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	for _, paths := range list {
+		newPaths, fn, err := CopyStoragePaths(paths)
+		if err != nil {
+			_ = cleanup()
+			return nil, nil, err
+		}
+		copied = append(copied, newPaths)
+		cleanups = append(cleanups, fn)
+	}
+	return copied, cleanup, nil
+}
+
 // copyFile copies a file from source to destination
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
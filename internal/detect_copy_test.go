@@ -147,6 +147,37 @@ func TestCopyStoragePaths(t *testing.T) {
 	defer func() { _ = db.Close() }()
 }
 
+func TestCopyStoragePathsList(t *testing.T) {
+	var list []StoragePaths
+	for i := 0; i < 2; i++ {
+		dir := testutil.CreateTempDir(t)
+		testutil.CreateSQLiteFixture(t, filepath.Join(dir, "state.vscdb"))
+		list = append(list, StoragePaths{GlobalStorage: dir})
+	}
+
+	copied, cleanup, err := CopyStoragePathsList(list)
+	if err != nil {
+		t.Fatalf("CopyStoragePathsList() error = %v", err)
+	}
+	if len(copied) != 2 || copied[0].GlobalStorage == copied[1].GlobalStorage {
+		t.Fatalf("CopyStoragePathsList() = %+v, want two separate copies", copied)
+	}
+	for _, paths := range copied {
+		if _, err := os.Stat(paths.GetGlobalStorageDBPath()); err != nil {
+			t.Errorf("copied database missing: %v", err)
+		}
+	}
+
+	if err := cleanup(); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+	for _, paths := range copied {
+		if _, err := os.Stat(paths.GlobalStorage); !os.IsNotExist(err) {
+			t.Errorf("cleanup() should remove %s", paths.GlobalStorage)
+		}
+	}
+}
+
 func TestCopyStoragePaths_NoStorage(t *testing.T) {
 	// Create storage paths with nonexistent paths
 	paths := StoragePaths{
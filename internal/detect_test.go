@@ -4,10 +4,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
 )
 
 func TestDetectStoragePaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(EnvAgentDir, "")
+	t.Setenv(EnvCursorConfigDir, "")
 	paths, err := DetectStoragePaths()
 	if err != nil {
 		t.Fatalf("DetectStoragePaths() error = %v", err)
@@ -89,13 +95,135 @@ func TestDetectStoragePaths_AgentStoragePath(t *testing.T) {
 			t.Errorf("AgentStoragePath = %v, want %v or %v", paths.AgentStoragePath, expected1, expected2)
 		}
 	case "darwin":
-		// On macOS, AgentStoragePath should be empty
-		if paths.AgentStoragePath != "" {
-			t.Errorf("AgentStoragePath = %v, want empty string on macOS", paths.AgentStoragePath)
+		// On macOS, cursor-agent uses .cursor/chats or Application Support
+		home, _ := os.UserHomeDir()
+		expected1 := filepath.Join(home, ".cursor/chats")
+		expected2 := filepath.Join(home, "Library/Application Support/Cursor/chats")
+		if paths.AgentStoragePath != expected1 && paths.AgentStoragePath != expected2 {
+			t.Errorf("AgentStoragePath = %v, want %v or %v", paths.AgentStoragePath, expected1, expected2)
 		}
 	}
 }
 
+func TestAgentStorageCandidates(t *testing.T) {
+	home := "/home/user"
+	t.Setenv(EnvAgentDir, "")
+	t.Setenv(EnvCursorConfigDir, "")
+	tests := []struct {
+		goos string
+		want []string
+	}{
+		{goos: "linux", want: []string{"/home/user/.config/cursor/chats", "/home/user/.cursor/chats"}},
+		{goos: "darwin", want: []string{"/home/user/.cursor/chats", "/home/user/Library/Application Support/Cursor/chats"}},
+		{goos: "windows", want: []string{"/home/user/.cursor/chats"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			got := agentStorageCandidates(tt.goos, home)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("agentStorageCandidates(%s) = %v, want %v", tt.goos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectAgentStoragePathIn(t *testing.T) {
+	tests := []struct {
+		name   string
+		goos   string
+		create string // directory created under home, if any
+		want   string
+	}{
+		{name: "darwin dot cursor", goos: "darwin", create: ".cursor/chats", want: ".cursor/chats"},
+		{name: "darwin application support", goos: "darwin", create: "Library/Application Support/Cursor/chats", want: "Library/Application Support/Cursor/chats"},
+		{name: "darwin default", goos: "darwin", want: ".cursor/chats"},
+		{name: "linux config", goos: "linux", create: ".config/cursor/chats", want: ".config/cursor/chats"},
+		{name: "linux default", goos: "linux", want: ".cursor/chats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvAgentDir, "")
+			t.Setenv(EnvCursorConfigDir, "")
+			home := testutil.CreateTempDir(t)
+			if tt.create != "" {
+				if err := os.MkdirAll(filepath.Join(home, tt.create), 0755); err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+			}
+			if got := detectAgentStoragePathIn(tt.goos, home); got != filepath.Join(home, tt.want) {
+				t.Errorf("detectAgentStoragePathIn() = %v, want %v", got, filepath.Join(home, tt.want))
+			}
+		})
+	}
+}
+
+func TestAgentStorageEnvOverrides(t *testing.T) {
+	home := testutil.CreateTempDir(t)
+	configDir := testutil.CreateTempDir(t)
+	if err := os.MkdirAll(filepath.Join(home, ".config/cursor/chats"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	t.Run("config dir searched first", func(t *testing.T) {
+		t.Setenv(EnvAgentDir, "")
+		t.Setenv(EnvCursorConfigDir, configDir)
+		got := agentStorageCandidates("linux", home)
+		want := []string{filepath.Join(configDir, "chats"), filepath.Join(home, ".config/cursor/chats"), filepath.Join(home, ".cursor/chats")}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("agentStorageCandidates() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("config dir chats preferred once created", func(t *testing.T) {
+		t.Setenv(EnvAgentDir, "")
+		t.Setenv(EnvCursorConfigDir, configDir)
+		// Before cursor-agent creates it, an existing default directory wins
+		if got := detectAgentStoragePathIn("linux", home); got != filepath.Join(home, ".config/cursor/chats") {
+			t.Errorf("detectAgentStoragePathIn() = %v, want the existing default", got)
+		}
+		if err := os.MkdirAll(filepath.Join(configDir, "chats"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if got := detectAgentStoragePathIn("linux", home); got != filepath.Join(configDir, "chats") {
+			t.Errorf("detectAgentStoragePathIn() = %v, want %v", got, filepath.Join(configDir, "chats"))
+		}
+	})
+
+	t.Run("config dir is the fallback", func(t *testing.T) {
+		empty := testutil.CreateTempDir(t)
+		t.Setenv(EnvAgentDir, "")
+		t.Setenv(EnvCursorConfigDir, filepath.Join(empty, "config"))
+		if got := detectAgentStoragePathIn("darwin", empty); got != filepath.Join(empty, "config/chats") {
+			t.Errorf("detectAgentStoragePathIn() = %v, want %v", got, filepath.Join(empty, "config/chats"))
+		}
+	})
+
+	t.Run("explicit agent dir", func(t *testing.T) {
+		agentDir := filepath.Join(testutil.CreateTempDir(t), "agent")
+		t.Setenv(EnvAgentDir, agentDir)
+		t.Setenv(EnvCursorConfigDir, configDir)
+		if got := agentStorageCandidates("linux", home); len(got) != 1 || got[0] != agentDir {
+			t.Errorf("agentStorageCandidates() = %v, want [%s]", got, agentDir)
+		}
+		// It is used even before it exists
+		if got := detectAgentStoragePathIn("linux", home); got != agentDir {
+			t.Errorf("detectAgentStoragePathIn() = %v, want %v", got, agentDir)
+		}
+	})
+}
+
+func TestDetectStoragePaths_AgentDirEnv(t *testing.T) {
+	agentDir := testutil.CreateTempDir(t)
+	t.Setenv(EnvAgentDir, agentDir)
+	paths, err := GetStoragePaths("")
+	if err != nil {
+		t.Fatalf("GetStoragePaths() error = %v", err)
+	}
+	if paths.AgentStoragePath != agentDir {
+		t.Errorf("AgentStoragePath = %v, want %v", paths.AgentStoragePath, agentDir)
+	}
+}
+
 func TestHasAgentStorage(t *testing.T) {
 	paths, _ := DetectStoragePaths()
 
@@ -166,3 +294,94 @@ func TestFindAgentStoreDBs(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectAllStoragePaths(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("storage detection is only supported on macOS and Linux")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	candidates, err := InstallCandidates()
+	if err != nil {
+		t.Fatalf("InstallCandidates() error = %v", err)
+	}
+	if candidates[0].Name != "Cursor" {
+		t.Errorf("first candidate = %s, want stable Cursor", candidates[0].Name)
+	}
+
+	// Nothing installed: fall back to the stable location
+	all, err := DetectAllStoragePaths()
+	if err != nil {
+		t.Fatalf("DetectAllStoragePaths() error = %v", err)
+	}
+	if len(all) != 1 || all[0].BasePath != candidates[0].BasePath {
+		t.Errorf("DetectAllStoragePaths() = %+v, want stable location only", all)
+	}
+
+	// Only Nightly has data: it is picked automatically
+	nightly := StoragePathsForBase(candidates[1].BasePath)
+	if err := os.MkdirAll(nightly.GlobalStorage, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nightly.GetGlobalStorageDBPath(), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := DetectStoragePaths()
+	if err != nil {
+		t.Fatalf("DetectStoragePaths() error = %v", err)
+	}
+	if paths.BasePath != nightly.BasePath {
+		t.Errorf("BasePath = %s, want Nightly %s", paths.BasePath, nightly.BasePath)
+	}
+
+	// Stable and Nightly both have data: stable comes first, agent storage only on the first entry
+	stable := StoragePathsForBase(candidates[0].BasePath)
+	if err := os.MkdirAll(stable.GlobalStorage, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stable.GetGlobalStorageDBPath(), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	all, err = DetectAllStoragePaths()
+	if err != nil {
+		t.Fatalf("DetectAllStoragePaths() error = %v", err)
+	}
+	if len(all) != 2 || all[0].BasePath != stable.BasePath || all[1].BasePath != nightly.BasePath {
+		t.Fatalf("DetectAllStoragePaths() = %+v, want stable then Nightly", all)
+	}
+	if all[1].AgentStoragePath != "" {
+		t.Errorf("second entry AgentStoragePath = %q, want empty", all[1].AgentStoragePath)
+	}
+
+	// Without --storage, only the first build with data is read
+	list, err := GetStoragePathsList(nil)
+	if err != nil {
+		t.Fatalf("GetStoragePathsList() error = %v", err)
+	}
+	if len(list) != 1 || list[0].BasePath != stable.BasePath {
+		t.Errorf("GetStoragePathsList(nil) = %+v, want only stable", list)
+	}
+}
+
+func TestGetStoragePathsList(t *testing.T) {
+	dir1 := testutil.CreateTempDir(t)
+	testutil.CreateSQLiteFixture(t, filepath.Join(dir1, "state.vscdb"))
+	dir2 := testutil.CreateTempDir(t)
+	testutil.CreateSQLiteFixture(t, filepath.Join(dir2, "state.vscdb"))
+
+	list, err := GetStoragePathsList([]string{dir1, filepath.Join(dir2, "state.vscdb")})
+	if err != nil {
+		t.Fatalf("GetStoragePathsList() error = %v", err)
+	}
+	if len(list) != 2 || list[0].GlobalStorage != dir1 || list[1].GlobalStorage != dir2 {
+		t.Errorf("GetStoragePathsList() = %+v, want globalStorage %s and %s", list, dir1, dir2)
+	}
+
+	missing := filepath.Join(dir1, "missing")
+	if _, err := GetStoragePathsList([]string{dir1, missing}); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("GetStoragePathsList() error = %v, want one naming %s", err, missing)
+	}
+}
@@ -1,11 +1,39 @@
 package internal
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoStorage is wrapped by the errors returned when no Cursor storage exists at the
+// checked locations
+var ErrNoStorage = errors.New("no Cursor storage found")
+
+// ErrBubbleNotFound is returned by a BubbleLoader when the storage has no such bubble
+var ErrBubbleNotFound = errors.New("bubble not found")
+
+// UnknownSchemaError is returned for a cursor-agent store.db whose tables match none of
+// the schemas cursor-session can read, typically written by a newer cursor-agent
+type UnknownSchemaError struct {
+	Path    string
+	Version int      // SQLite user_version of the database
+	Tables  []string // Tables found in the database
+}
+
+func (e *UnknownSchemaError) Error() string {
+	tables := "none"
+	if len(e.Tables) > 0 {
+		tables = strings.Join(e.Tables, ", ")
+	}
+	return fmt.Sprintf("unknown cursor-agent schema v%d in %s (tables: %s); this cursor-agent version is not supported yet, please report it at https://github.com/iksnae/cursor-session/issues with this message",
+		e.Version, e.Path, tables)
+}
 
 // StorageError represents errors accessing storage files
 type StorageError struct {
 	Path string
-	Op   string // "open", "read", "parse"
+	Op   string // "open", "read", "parse", "write"
 	Err  error
 }
 
@@ -60,3 +88,47 @@ func (e *ExportError) Error() string {
 func (e *ExportError) Unwrap() error {
 	return e.Err
 }
+
+// DatabaseLockedError is returned when a database stays locked by another process, usually
+// a running Cursor, after the busy timeout and retries are exhausted
+type DatabaseLockedError struct {
+	Path string // empty when the lock was hit on an already open database
+	Err  error
+}
+
+func (e *DatabaseLockedError) Error() string {
+	db := "database"
+	if e.Path != "" {
+		db = "database " + e.Path
+	}
+	return fmt.Sprintf("%s is locked by another process (is Cursor running?): %v; retry with --copy to read a snapshot, or raise --busy-timeout", db, e.Err)
+}
+
+func (e *DatabaseLockedError) Unwrap() error {
+	return e.Err
+}
+
+// AmbiguousSessionError is returned when a session lookup matches more than one session
+type AmbiguousSessionError struct {
+	Query   string
+	ByName  bool // the query was a session name rather than an ID prefix
+	Matches []SessionRef
+}
+
+func (e *AmbiguousSessionError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ambiguous session %q: %d sessions match\n", e.Query, len(e.Matches))
+	for _, m := range e.Matches {
+		name := m.Name
+		if name == "" {
+			name = "Untitled"
+		}
+		fmt.Fprintf(&b, "  %s  %s\n", m.ID, name)
+	}
+	if e.ByName {
+		b.WriteString("use a more specific name, or one of the session IDs above")
+	} else {
+		b.WriteString("use a longer ID prefix or the full session ID")
+	}
+	return b.String()
+}
@@ -83,6 +83,47 @@ func TestReconstructionError(t *testing.T) {
 	}
 }
 
+func TestDatabaseLockedError(t *testing.T) {
+	originalErr := errors.New("database is locked (5) (SQLITE_BUSY)")
+	err := &DatabaseLockedError{
+		Path: "/data/state.vscdb",
+		Err:  originalErr,
+	}
+
+	errorMsg := err.Error()
+	if !strings.Contains(errorMsg, "/data/state.vscdb") {
+		t.Errorf("DatabaseLockedError.Error() should contain path, got: %q", errorMsg)
+	}
+	if !strings.Contains(errorMsg, "--copy") {
+		t.Errorf("DatabaseLockedError.Error() should suggest --copy, got: %q", errorMsg)
+	}
+
+	if !errors.Is(err, originalErr) {
+		t.Error("DatabaseLockedError.Unwrap() should return original error")
+	}
+}
+
+func TestAmbiguousSessionError_Hint(t *testing.T) {
+	matches := []SessionRef{{ID: "3f2a1111", Name: "Fix login"}, {ID: "3f2a2222", Name: "Fix logout"}}
+	tests := []struct {
+		name     string
+		err      *AmbiguousSessionError
+		want     string
+		unwanted string
+	}{
+		{"ID prefix", &AmbiguousSessionError{Query: "3f2a", Matches: matches}, "longer ID prefix", "more specific name"},
+		{"name", &AmbiguousSessionError{Query: "fix", ByName: true, Matches: matches}, "more specific name, or one of the session IDs above", "ID prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.err.Error()
+			if !strings.Contains(msg, tt.want) || strings.Contains(msg, tt.unwanted) {
+				t.Errorf("AmbiguousSessionError.Error() = %q, want %q and not %q", msg, tt.want, tt.unwanted)
+			}
+		})
+	}
+}
+
 func TestExportError(t *testing.T) {
 	originalErr := errors.New("write failed")
 	err := &ExportError{
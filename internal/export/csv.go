@@ -0,0 +1,59 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// CSVExporter exports sessions as CSV with a header row and one row per message, for
+// spreadsheets and warehouse loaders that don't handle JSON. Fields are quoted as RFC 4180
+// describes, so text may contain commas, quotes and newlines.
+type CSVExporter struct{}
+
+// csvHeader names the columns of a CSV export
+var csvHeader = []string{"session_id", "session_name", "workspace", "index", "actor", "timestamp", "text", "parent_session_id"}
+
+// Export exports a session to CSV format
+func (e *CSVExporter) Export(session *internal.Session, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	// Rows of a chunk of a split session name the session it came from
+	var parentID string
+	if chunk := session.Metadata.Chunk; chunk != nil {
+		parentID = chunk.SessionID
+	}
+
+	for i, msg := range session.Messages {
+		row := []string{
+			session.ID,
+			session.Metadata.Name,
+			session.Workspace,
+			strconv.Itoa(i),
+			msg.Actor,
+			internal.UTCTimestamp(msg.Timestamp),
+			msg.Content,
+			parentID,
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write message: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// Extension returns the file extension for this format
+func (e *CSVExporter) Extension() string {
+	return "csv"
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestCSVExporter_Export(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "user", Content: `Fix "parse", please`, Timestamp: "2024-03-01T14:30:00+02:00"},
+		{Actor: "assistant", Content: "Done:\n- line one, with a comma\n- line two"},
+	})
+	session.Metadata.Name = "Parser, v2"
+	session.Workspace = "/src/api"
+
+	var buf bytes.Buffer
+	if err := (&CSVExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Export() wrote invalid CSV: %v\n%s", err, buf.String())
+	}
+	want := [][]string{
+		csvHeader,
+		{"test", "Parser, v2", "/src/api", "0", "user", "2024-03-01T12:30:00Z", `Fix "parse", please`, ""},
+		{"test", "Parser, v2", "/src/api", "1", "assistant", "", "Done:\n- line one, with a comma\n- line two", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Export() rows = %q, want %q", records, want)
+	}
+}
+
+func TestCSVExporter_Chunk(t *testing.T) {
+	session := internal.CreateTestSession("test.chunk_2")
+	session.Metadata.Chunk = &internal.Chunk{SessionID: "test", Index: 2, Count: 2}
+
+	var buf bytes.Buffer
+	if err := (&CSVExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Export() wrote invalid CSV: %v", err)
+	}
+	for _, row := range records[1:] {
+		if row[len(row)-1] != "test" {
+			t.Errorf("Row of a chunk should name its parent session, got %q", row)
+		}
+	}
+}
+
+func TestCSVExporter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	session := internal.CreateTestSessionWithMessages("empty", []internal.Message{})
+	if err := (&CSVExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got := buf.String(); got != "session_id,session_name,workspace,index,actor,timestamp,text,parent_session_id\n" {
+		t.Errorf("Export() of an empty session = %q, want only the header", got)
+	}
+}
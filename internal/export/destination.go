@@ -0,0 +1,162 @@
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// Destination receives the files of an export: a directory or a single archive
+type Destination interface {
+	// WriteSession exports a session to the file name, such as the one SessionFileName
+	// returns. A session skipped by a hook returns ErrSkipSession and writes nothing.
+	// Names may place files in subdirectories, separated by /.
+	WriteSession(exporter Exporter, session *internal.Session, name string) error
+	// WriteFile writes another file of the export, such as a report or an intermediary dump
+	WriteFile(name string, data []byte) error
+	// Close finishes the export
+	Close() error
+}
+
+// dirDestination writes each file of an export into a directory
+type dirDestination struct {
+	dir string
+}
+
+// NewDirDestination returns a Destination writing into dir, creating it if needed
+func NewDirDestination(dir string) (Destination, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return &dirDestination{dir: dir}, nil
+}
+
+func (d *dirDestination) WriteSession(exporter Exporter, session *internal.Session, name string) error {
+	return writeSessionFile(exporter, d.dir, name, session)
+}
+
+func (d *dirDestination) WriteFile(name string, data []byte) error {
+	path := filepath.Join(d.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (d *dirDestination) Close() error {
+	return nil
+}
+
+// archiveDestination streams the files of an export into a tar.gz or zip archive. Each
+// file is rendered in memory before it is added, so only one session is held at a time.
+type archiveDestination struct {
+	file *os.File
+	gz   *gzip.Writer
+	tar  *tar.Writer
+	zip  *zip.Writer
+
+	// listFiles records the files added in files, for the manifest of a staged archive
+	listFiles bool
+	files     []ManifestEntry
+}
+
+// IsArchivePath reports whether path names an archive CreateArchive can write
+func IsArchivePath(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".zip")
+}
+
+// CreateArchive returns a Destination writing a single archive at path. The archive
+// format follows the extension: .tar.gz or .tgz for a gzipped tarball, .zip for a zip file.
+func CreateArchive(path string) (Destination, error) {
+	if !IsArchivePath(path) {
+		return nil, fmt.Errorf("unsupported archive %s (expected .tar.gz, .tgz or .zip)", path)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create archive directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive %s: %w", path, err)
+	}
+	return newArchiveDestination(file, path), nil
+}
+
+// newArchiveDestination returns a Destination writing an archive into file, in the format
+// the extension of path names
+func newArchiveDestination(file *os.File, path string) *archiveDestination {
+	a := &archiveDestination{file: file}
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		a.zip = zip.NewWriter(file)
+	} else {
+		a.gz = gzip.NewWriter(file)
+		a.tar = tar.NewWriter(a.gz)
+	}
+	return a
+}
+
+func (a *archiveDestination) WriteSession(exporter Exporter, session *internal.Session, name string) error {
+	var buf bytes.Buffer
+	if err := exporter.Export(session, &buf); err != nil {
+		return err
+	}
+	return a.WriteFile(name, buf.Bytes())
+}
+
+func (a *archiveDestination) WriteFile(name string, data []byte) error {
+	if a.listFiles {
+		a.files = append(a.files, newManifestEntry(name, data))
+	}
+	modified := time.Now()
+	if a.zip != nil {
+		w, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", name, err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modified,
+		Typeflag: tar.TypeReg,
+	}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	_, err := a.tar.Write(data)
+	return err
+}
+
+func (a *archiveDestination) Close() error {
+	var closers []io.Closer
+	if a.zip != nil {
+		closers = append(closers, a.zip)
+	} else {
+		closers = append(closers, a.tar, a.gz)
+	}
+	closers = append(closers, a.file)
+
+	var firstErr error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to finish archive: %w", err)
+		}
+	}
+	return firstErr
+}
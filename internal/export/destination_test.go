@@ -0,0 +1,142 @@
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// readArchive returns the contents of every file in a tar.gz or zip archive
+func readArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+
+	if strings.HasSuffix(path, ".zip") {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatalf("failed to open zip: %v", err)
+		}
+		defer func() { _ = r.Close() }()
+		for _, f := range r.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", f.Name, err)
+			}
+			data, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to open gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	return files
+}
+
+func TestCreateArchive(t *testing.T) {
+	skipping := WithHooks(&JSONExporter{}, Hooks{
+		BeforeExport: func(s *internal.Session) (*internal.Session, error) {
+			if s.ID == "skipped" {
+				return nil, ErrSkipSession
+			}
+			return s, nil
+		},
+	})
+
+	for _, name := range []string{"sessions.tar.gz", "sessions.tgz", "sessions.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", name)
+			dest, err := CreateArchive(path)
+			if err != nil {
+				t.Fatalf("CreateArchive() error = %v", err)
+			}
+			if err := dest.WriteSession(skipping, internal.CreateTestSession("archived"), "session_archived.json"); err != nil {
+				t.Fatalf("WriteSession() error = %v", err)
+			}
+			if err := dest.WriteSession(skipping, internal.CreateTestSession("skipped"), "session_skipped.json"); !errors.Is(err, ErrSkipSession) {
+				t.Errorf("WriteSession() of a skipped session error = %v, want ErrSkipSession", err)
+			}
+			if err := dest.WriteFile("export-report.json", []byte(`{"sessions_exported":1}`)); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if err := dest.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			files := readArchive(t, path)
+			var names []string
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != "export-report.json,session_archived.json" {
+				t.Errorf("archive contains %v, want the session and the report", names)
+			}
+			if !strings.Contains(files["session_archived.json"], "Hello, how are you?") {
+				t.Errorf("session_archived.json = %q, want the exported session", files["session_archived.json"])
+			}
+		})
+	}
+
+	if _, err := CreateArchive(filepath.Join(t.TempDir(), "sessions.rar")); err == nil {
+		t.Error("CreateArchive(.rar) succeeded, want an error")
+	}
+}
+
+func TestNewDirDestination(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	dest, err := NewDirDestination(dir)
+	if err != nil {
+		t.Fatalf("NewDirDestination() error = %v", err)
+	}
+	if err := dest.WriteSession(&JSONExporter{}, internal.CreateTestSession("dir"), "session_dir.json"); err != nil {
+		t.Fatalf("WriteSession() error = %v", err)
+	}
+	if err := dest.WriteFile("export-report.json", []byte("{}")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	// Subdirectories are created as needed
+	if err := dest.WriteSession(&JSONExporter{}, internal.CreateTestSession("sub"), "api/session_sub.json"); err != nil {
+		t.Fatalf("WriteSession() into a subdirectory error = %v", err)
+	}
+	if err := dest.WriteFile("api/index.json", []byte("{}")); err != nil {
+		t.Fatalf("WriteFile() into a subdirectory error = %v", err)
+	}
+	if err := dest.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for _, name := range []string{"session_dir.json", "export-report.json", "api/session_sub.json", "api/index.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not written: %v", name, err)
+		}
+	}
+}
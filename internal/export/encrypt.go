@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// EncryptedExtension is appended to the name of every file an encrypted export writes
+const EncryptedExtension = ".age"
+
+// ParseRecipients parses age X25519 recipients, the age1... public keys printed by
+// age-keygen
+func ParseRecipients(values []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(values))
+	for _, value := range values {
+		recipient, err := age.ParseX25519Recipient(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", value, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// ParseIdentityFile reads the age identities (AGE-SECRET-KEY-1... private keys) in a key
+// file written by age-keygen
+func ParseIdentityFile(path string) ([]age.Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open identity file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// encryptedExporter encrypts the output of another exporter to age recipients
+type encryptedExporter struct {
+	exporter   Exporter
+	recipients []age.Recipient
+}
+
+// WithEncryption returns an exporter that encrypts the output of exporter to every
+// recipient, so any of their identities can decrypt it. Its extension ends in .age.
+func WithEncryption(exporter Exporter, recipients []age.Recipient) Exporter {
+	return &encryptedExporter{exporter: exporter, recipients: recipients}
+}
+
+func (e *encryptedExporter) Export(session *internal.Session, w io.Writer) error {
+	encrypted, err := age.Encrypt(w, e.recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt session %s: %w", session.ID, err)
+	}
+	if err := e.exporter.Export(session, encrypted); err != nil {
+		return err
+	}
+	if err := encrypted.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+func (e *encryptedExporter) Extension() string {
+	return e.exporter.Extension() + EncryptedExtension
+}
+
+// encryptedDestination encrypts the files written to another destination
+type encryptedDestination struct {
+	Destination
+	recipients []age.Recipient
+}
+
+// WithEncryptedFiles returns a destination that encrypts the files written with WriteFile,
+// such as reports and sidecar files, to every recipient and adds .age to their names.
+// Sessions are encrypted by exporting them with an exporter from WithEncryption.
+func WithEncryptedFiles(dest Destination, recipients []age.Recipient) Destination {
+	return &encryptedDestination{Destination: dest, recipients: recipients}
+}
+
+func (d *encryptedDestination) WriteFile(name string, data []byte) error {
+	var buf bytes.Buffer
+	encrypted, err := age.Encrypt(&buf, d.recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	if _, err := encrypted.Write(data); err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	if err := encrypted.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	return d.Destination.WriteFile(name+EncryptedExtension, buf.Bytes())
+}
+
+// Decrypt copies the age-encrypted src to dst, decrypting it with the first of the
+// identities it was encrypted to
+func Decrypt(dst io.Writer, src io.Reader, identities []age.Identity) error {
+	decrypted, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	if _, err := io.Copy(dst, decrypted); err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return nil
+}
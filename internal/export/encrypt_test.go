@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestParseRecipients(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients, err := ParseRecipients([]string{" " + identity.Recipient().String() + " "})
+	if err != nil || len(recipients) != 1 {
+		t.Fatalf("ParseRecipients() = %v, %v", recipients, err)
+	}
+	if _, err := ParseRecipients([]string{"age1notakey"}); err == nil {
+		t.Error("ParseRecipients() of an invalid key should fail")
+	}
+}
+
+func TestWithEncryption(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := WithEncryption(&JSONLExporter{}, []age.Recipient{identity.Recipient()})
+	if exporter.Extension() != "jsonl.age" {
+		t.Errorf("Extension() = %q, want jsonl.age", exporter.Extension())
+	}
+
+	session := internal.CreateTestSessionWithMessages("secret", []internal.Message{{Actor: "user", Content: "proprietary code"}})
+	var encrypted bytes.Buffer
+	if err := exporter.Export(session, &encrypted); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if strings.Contains(encrypted.String(), "proprietary code") {
+		t.Fatal("Encrypted export contains the plaintext")
+	}
+
+	var decrypted bytes.Buffer
+	if err := Decrypt(&decrypted, &encrypted, []age.Identity{identity}); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !strings.Contains(decrypted.String(), "proprietary code") {
+		t.Errorf("Decrypted export = %q", decrypted.String())
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	var again bytes.Buffer
+	_ = exporter.Export(session, &again)
+	if err := Decrypt(&bytes.Buffer{}, &again, []age.Identity{other}); err == nil {
+		t.Error("Decrypt() with another identity should fail")
+	}
+}
+
+func TestWithEncryptedFiles(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	plain, err := NewDirDestination(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := WithEncryptedFiles(plain, []age.Recipient{identity.Recipient()})
+	if err := dest.WriteFile("export-report.json", []byte(`{"sessions_exported":1}`)); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "export-report.json.age"))
+	if err != nil {
+		t.Fatalf("WriteFile() should write export-report.json.age: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	var decrypted bytes.Buffer
+	if err := Decrypt(&decrypted, file, []age.Identity{identity}); err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if decrypted.String() != `{"sessions_exported":1}` {
+		t.Errorf("Decrypted file = %q", decrypted.String())
+	}
+}
+
+func TestParseIdentityFile(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.txt")
+	content := "# created: 2024-06-01T00:00:00Z\n# public key: " + identity.Recipient().String() + "\n" + identity.String() + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	identities, err := ParseIdentityFile(path)
+	if err != nil || len(identities) != 1 {
+		t.Errorf("ParseIdentityFile() = %v, %v", identities, err)
+	}
+	if _, err := ParseIdentityFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("ParseIdentityFile() of a missing file should fail")
+	}
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// Event types of an events-jsonl export
+const (
+	EventSession       = "session"
+	EventGitStatus     = "git_status"
+	EventTerminalFiles = "terminal_files"
+	EventMessage       = "message"
+	EventCodeDiff      = "code_diff"
+	EventFileEdit      = "file_edit"
+)
+
+// EventsExporter exports a session as a chronological log of typed events, one per line,
+// so the state of the workspace between turns can be followed alongside the transcript.
+// The log opens with a session event. Before each message comes a git_status event when
+// the git status recorded with it differs from the last one, and a terminal_files event
+// when the files open in terminals do; after it come a code_diff event per edit it applied
+// and a file_edit event per file it edited. Edits that matched no message close the log.
+// Every event carries the session's ID, its position in the log (seq) and its type, and
+// events about a message carry the message's index and timestamp.
+type EventsExporter struct{}
+
+// Export exports a session to the events-jsonl format
+func (e *EventsExporter) Export(session *internal.Session, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	seq := 0
+	emit := func(eventType string, obj map[string]interface{}) error {
+		seq++
+		obj["type"] = eventType
+		obj["seq"] = seq
+		obj["session_id"] = session.ID
+		if err := enc.Encode(obj); err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+		}
+		return nil
+	}
+
+	header := map[string]interface{}{}
+	if session.Metadata.Name != "" {
+		header["name"] = session.Metadata.Name
+	}
+	if session.Workspace != "" {
+		header["workspace"] = session.Workspace
+	}
+	if session.Metadata.CreatedAt != "" {
+		header["timestamp"] = internal.UTCTimestamp(session.Metadata.CreatedAt)
+	}
+	if err := emit(EventSession, header); err != nil {
+		return err
+	}
+
+	var parentID string
+	if session.Metadata.Chunk != nil {
+		parentID = session.Metadata.Chunk.SessionID
+	}
+	var git *internal.GitInfo
+	var terminalFiles []string
+	for i, msg := range session.Messages {
+		// about returns a new event about the message
+		about := func() map[string]interface{} {
+			obj := map[string]interface{}{"message_index": i}
+			if msg.Timestamp != "" {
+				obj["timestamp"] = internal.UTCTimestamp(msg.Timestamp)
+			}
+			return obj
+		}
+
+		if env := msg.Environment; env != nil {
+			if env.Git != nil && !reflect.DeepEqual(env.Git, git) {
+				git = env.Git
+				obj := about()
+				obj["git"] = git
+				if err := emit(EventGitStatus, obj); err != nil {
+					return err
+				}
+			}
+			if len(env.TerminalFiles) > 0 && !slices.Equal(env.TerminalFiles, terminalFiles) {
+				terminalFiles = env.TerminalFiles
+				obj := about()
+				obj["files"] = terminalFiles
+				if err := emit(EventTerminalFiles, obj); err != nil {
+					return err
+				}
+			}
+		}
+
+		obj := jsonlMessage(msg, parentID)
+		obj["message_index"] = i
+		if err := emit(EventMessage, obj); err != nil {
+			return err
+		}
+
+		for _, diff := range msg.Diffs {
+			obj := about()
+			obj["diff"] = diff
+			if err := emit(EventCodeDiff, obj); err != nil {
+				return err
+			}
+		}
+		for _, edit := range msg.FileEdits {
+			obj := about()
+			obj["file_path"] = edit.FilePath
+			obj["action"] = edit.Action
+			if err := emit(EventFileEdit, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, diff := range session.Diffs {
+		if err := emit(EventCodeDiff, map[string]interface{}{"diff": diff}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extension returns the file extension for this format
+func (e *EventsExporter) Extension() string {
+	return "events.jsonl"
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestEventsExporter_Export(t *testing.T) {
+	onMain := &internal.Environment{Git: &internal.GitInfo{Branch: "main"}, TerminalFiles: []string{"/repo/t1.log"}}
+	session := internal.CreateTestSessionWithMessages("run1", []internal.Message{
+		{Actor: "user", Content: "Fix the test", Timestamp: "2024-06-01T11:30:00+02:00", Environment: onMain},
+		{
+			Actor: "assistant", Content: "Fixed", Environment: onMain,
+			Diffs:     []internal.CodeDiff{{FilePath: "/repo/a.go", Hunks: []internal.DiffHunk{}}},
+			FileEdits: []internal.FileEdit{{FilePath: "/repo/a.go", Action: internal.FileEditApply}},
+		},
+		{Actor: "user", Content: "Commit it", Environment: &internal.Environment{Git: &internal.GitInfo{Branch: "main", DirtyFiles: []string{"a.go"}}}},
+	})
+	session.Metadata.Name = "Fix the test"
+	session.Metadata.CreatedAt = "2024-06-01T09:30:00Z"
+	session.Diffs = []internal.CodeDiff{{FilePath: "/repo/b.go", Hunks: []internal.DiffHunk{}}}
+
+	var buf bytes.Buffer
+	if err := (&EventsExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	var types []string
+	for i, event := range events {
+		types = append(types, event["type"].(string))
+		if event["seq"] != float64(i+1) || event["session_id"] != "run1" {
+			t.Errorf("event %d seq = %v, session_id = %v", i, event["seq"], event["session_id"])
+		}
+	}
+	want := []string{
+		EventSession,
+		EventGitStatus, EventTerminalFiles, EventMessage,
+		EventMessage, EventCodeDiff, EventFileEdit,
+		EventGitStatus, EventMessage,
+		EventCodeDiff,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+
+	if events[0]["name"] != "Fix the test" || events[0]["timestamp"] != "2024-06-01T09:30:00Z" {
+		t.Errorf("session event = %v", events[0])
+	}
+	if events[1]["timestamp"] != "2024-06-01T09:30:00Z" || events[1]["message_index"] != float64(0) {
+		t.Errorf("git_status event = %v, want the time and index of the first message", events[1])
+	}
+	if files := events[2]["files"].([]interface{}); len(files) != 1 || files[0] != "/repo/t1.log" {
+		t.Errorf("terminal_files event = %v", events[2])
+	}
+	if events[3]["content"] != "Fix the test" || events[3]["actor"] != "user" {
+		t.Errorf("message event = %v", events[3])
+	}
+	if events[6]["file_path"] != "/repo/a.go" || events[6]["action"] != internal.FileEditApply || events[6]["message_index"] != float64(1) {
+		t.Errorf("file_edit event = %v", events[6])
+	}
+	git := events[7]["git"].(map[string]interface{})
+	if dirty := git["dirty_files"].([]interface{}); len(dirty) != 1 || dirty[0] != "a.go" {
+		t.Errorf("git_status event = %v, want a.go dirty", events[7])
+	}
+	last := events[len(events)-1]
+	if _, ok := last["message_index"]; ok || last["diff"].(map[string]interface{})["file_path"] != "/repo/b.go" {
+		t.Errorf("unmatched code_diff event = %v", last)
+	}
+}
+
+func TestEventsExporter_Extension(t *testing.T) {
+	if got := (&EventsExporter{}).Extension(); got != "events.jsonl" {
+		t.Errorf("Extension() = %q, want events.jsonl", got)
+	}
+}
@@ -0,0 +1,203 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// FieldFormats are the formats a FieldSelection can be applied to
+var FieldFormats = []string{"json", "jsonl"}
+
+// fieldAliases are the shorter names a field can be selected by, and the paths they stand for
+var fieldAliases = map[string]string{
+	"name":          "metadata.name",
+	"messages.text": "messages.content",
+}
+
+// jsonlOnlyFields are the fields of the messages of a JSONL export that sessions do not have
+var jsonlOnlyFields = map[string]bool{"parent_session_id": true}
+
+// FieldSelection is a projection of exported sessions down to some of their fields, as
+// given by --fields. Each field is a dotted path into the session as the JSON export
+// writes it, such as "id", "metadata.name" or "messages.content"; a path through a list
+// selects the field in each of its items, and a path to an object keeps the whole object.
+type FieldSelection struct {
+	tree fieldTree
+}
+
+// fieldTree holds the selected paths by their keys; a nil subtree keeps the whole value
+type fieldTree map[string]fieldTree
+
+// ParseFields parses a comma-separated list of field paths. Paths that do not name a
+// field of a session are rejected, so a misspelled field is not silently left out.
+func ParseFields(spec string) (*FieldSelection, error) {
+	selection := &FieldSelection{tree: fieldTree{}}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if alias, ok := fieldAliases[field]; ok {
+			field = alias
+		}
+		if err := checkFieldPath(field); err != nil {
+			return nil, err
+		}
+		selection.tree.add(strings.Split(field, "."))
+	}
+	if len(selection.tree) == 0 {
+		return nil, fmt.Errorf("no fields given (expected a comma-separated list such as id,metadata.name,messages.content)")
+	}
+	return selection, nil
+}
+
+// add selects the value at keys; a path selecting a whole value overrides paths below it
+func (t fieldTree) add(keys []string) {
+	sub, ok := t[keys[0]]
+	if len(keys) == 1 {
+		t[keys[0]] = nil
+		return
+	}
+	if ok && sub == nil {
+		return
+	}
+	if sub == nil {
+		sub = fieldTree{}
+		t[keys[0]] = sub
+	}
+	sub.add(keys[1:])
+}
+
+// checkFieldPath reports a path that does not lead to a field of a session
+func checkFieldPath(field string) error {
+	keys := strings.Split(field, ".")
+	if len(keys) == 2 && keys[0] == "messages" && jsonlOnlyFields[keys[1]] {
+		return nil
+	}
+	t := reflect.TypeOf(internal.Session{})
+	for i, key := range keys {
+		if key == "" {
+			return fmt.Errorf("invalid field %q", field)
+		}
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			// Maps, such as metadata.fields, have keys of any name
+			return nil
+		case reflect.Struct:
+			next, ok := jsonField(t, key)
+			if !ok {
+				return fmt.Errorf("unknown field %q in %q", strings.Join(keys[:i+1], "."), field)
+			}
+			t = next
+		default:
+			return fmt.Errorf("unknown field %q: %s has no fields", field, strings.Join(keys[:i], "."))
+		}
+	}
+	return nil
+}
+
+// jsonField returns the type of the field of struct type t encoded as key
+func jsonField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if name == key && name != "-" {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// Session returns the selected fields of a session as the JSON export writes it
+func (f *FieldSelection) Session(session *internal.Session) (interface{}, error) {
+	value, err := toJSONValue(session)
+	if err != nil {
+		return nil, err
+	}
+	return f.tree.project(value), nil
+}
+
+// Message returns the selected fields of the JSONL line of a message: the fields under
+// messages, and the other fields of its session in a "session" object, when any are
+// selected
+func (f *FieldSelection) Message(session *internal.Session, line map[string]interface{}) (map[string]interface{}, error) {
+	projected := map[string]interface{}{}
+	if sub, ok := f.tree["messages"]; ok {
+		value, err := toJSONValue(line)
+		if err != nil {
+			return nil, err
+		}
+		if obj, ok := sub.project(value).(map[string]interface{}); ok {
+			projected = obj
+		}
+	}
+
+	sessionTree := fieldTree{}
+	for key, sub := range f.tree {
+		if key != "messages" {
+			sessionTree[key] = sub
+		}
+	}
+	if len(sessionTree) > 0 {
+		header := *session
+		header.Messages = nil
+		value, err := toJSONValue(utcSession(&header))
+		if err != nil {
+			return nil, err
+		}
+		projected["session"] = sessionTree.project(value)
+	}
+	return projected, nil
+}
+
+// project keeps the selected fields of a decoded JSON value; a nil tree keeps all of it
+func (t fieldTree) project(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(t))
+		for key, sub := range t {
+			if field, ok := v[key]; ok {
+				projected[key] = sub.project(field)
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, item := range v {
+			projected[i] = t.project(item)
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+// toJSONValue returns v as encoding/json decodes it into an interface{}, keeping numbers
+// as written
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
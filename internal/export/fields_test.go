@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"id,name,messages.actor,messages.text", false},
+		{"metadata.fields.model, messages.provenance.backend", false},
+		{"messages.parent_session_id", false},
+		{"messages", false},
+		{"", true},
+		{" , ", true},
+		{"title", true},
+		{"messages.body", true},
+		{"id.value", true},
+		{"metadata..name", true},
+	}
+	for _, tt := range tests {
+		_, err := ParseFields(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFields(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func fieldsSession() *internal.Session {
+	session := internal.CreateTestSessionWithMessages("fields", []internal.Message{
+		{Actor: "user", Content: "Hello", Timestamp: "2024-06-03T09:00:00Z", Provenance: &internal.Provenance{Backend: "agentStorage", BlobKey: "b1"}},
+		{Actor: "assistant", Content: "Hi there"},
+	})
+	session.Metadata.Name = "Greeting"
+	session.Workspace = "/src/api"
+	return session
+}
+
+func TestJSONExporter_Fields(t *testing.T) {
+	fields, err := ParseFields("id,name,messages.actor,messages.text,messages.provenance.backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := (&JSONExporter{Fields: fields}).Export(fieldsSession(), &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("export is not JSON: %v\n%s", err, buf.String())
+	}
+	compact, _ := json.Marshal(got)
+	want := `{"id":"fields","messages":[{"actor":"user","content":"Hello","provenance":{"backend":"agentStorage"}},{"actor":"assistant","content":"Hi there"}],"metadata":{"name":"Greeting"}}`
+	if string(compact) != want {
+		t.Errorf("Export() = %s, want %s", compact, want)
+	}
+}
+
+func TestJSONLExporter_Fields(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"messages.actor,messages.text", []string{`{"actor":"user","content":"Hello"}`, `{"actor":"assistant","content":"Hi there"}`}},
+		{"id,workspace,messages.actor", []string{`{"actor":"user","session":{"id":"fields","workspace":"/src/api"}}`, `{"actor":"assistant","session":{"id":"fields","workspace":"/src/api"}}`}},
+		{"messages.timestamp", []string{`{"timestamp":"2024-06-03T09:00:00Z"}`, `{}`}},
+	}
+	for _, tt := range tests {
+		fields, err := ParseFields(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := (&JSONLExporter{Fields: fields}).Export(fieldsSession(), &buf); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if strings.Join(lines, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("--fields %s lines = %q, want %q", tt.spec, lines, tt.want)
+		}
+	}
+}
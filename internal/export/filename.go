@@ -0,0 +1,251 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// DefaultFilenameTemplate names exported files session_<id>.<ext>
+const DefaultFilenameTemplate = "session_{id}"
+
+// maxSlugBytes bounds each substitution, keeping file names well under the 255 byte
+// limit of common file systems
+const maxSlugBytes = 80
+
+// filenamePlaceholders are the fields a filename template can use, with the value used
+// when a session has none
+var filenamePlaceholders = map[string]string{
+	"id":        "",
+	"short_id":  "",
+	"name":      "untitled",
+	"workspace": "no-workspace",
+	"date":      "undated",
+	"time":      "00-00",
+}
+
+// FilenameTemplate names the file a session is exported to from its fields, such as
+// "{date}_{name}". The extension of the format is appended.
+type FilenameTemplate struct {
+	source string
+	hasID  bool
+}
+
+// ParseFilenameTemplate parses a filename template. Placeholders are {id}, {short_id} (the
+// first 8 characters of the ID), {name}, {workspace} (the last element of its path),
+// {date} (the creation date, YYYY-MM-DD) and {time} (the creation time in UTC, HH-MM).
+// The text around them may not contain path separators, so every file is written into
+// the output directory.
+func ParseFilenameTemplate(source string) (*FilenameTemplate, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("invalid filename template %q: empty", source)
+	}
+	t := &FilenameTemplate{source: source}
+	rest := source
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("invalid filename template %q: unmatched }", source)
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid filename template %q: unterminated {", source)
+		}
+		name := rest[open+1 : open+end]
+		if _, ok := filenamePlaceholders[name]; !ok {
+			return nil, fmt.Errorf("invalid filename template %q: unknown placeholder {%s} (expected {id}, {short_id}, {name}, {workspace}, {date} or {time})", source, name)
+		}
+		if name == "id" {
+			t.hasID = true
+		}
+		rest = rest[open+end+1:]
+	}
+	if strings.ContainsAny(source, `/\`) || strings.Contains(source, "..") {
+		return nil, fmt.Errorf("invalid filename template %q: file names may not contain path separators or ..", source)
+	}
+	return t, nil
+}
+
+// String returns the template as it was written
+func (t *FilenameTemplate) String() string {
+	return t.source
+}
+
+// Render returns the file name of a session, without the extension. The ID is kept as is
+// apart from characters that are unsafe in file names; the name and workspace, which users
+// choose, are turned into slugs.
+func (t *FilenameTemplate) Render(session *internal.Session) string {
+	values := map[string]string{
+		"id":        safeFilenamePart(session.ID),
+		"short_id":  safeFilenamePart(shortSessionID(session.ID)),
+		"name":      Slugify(session.Metadata.Name),
+		"workspace": Slugify(filepath.Base(filepath.ToSlash(session.Workspace))),
+	}
+	if session.Workspace == "" {
+		values["workspace"] = ""
+	}
+	if created, err := time.Parse(time.RFC3339, session.Metadata.CreatedAt); err == nil {
+		values["date"] = created.UTC().Format("2006-01-02")
+		values["time"] = created.UTC().Format("15-04")
+	}
+
+	var b strings.Builder
+	rest := t.source
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:open])
+		end := strings.IndexByte(rest[open:], '}')
+		name := rest[open+1 : open+end]
+		value := values[name]
+		if value == "" {
+			value = filenamePlaceholders[name]
+		}
+		b.WriteString(value)
+		rest = rest[open+end+1:]
+	}
+	return b.String()
+}
+
+// shortSessionID returns the first 8 characters of a session ID
+func shortSessionID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// Slugify turns user-provided text into a lowercase file name part: letters and digits
+// of any script are kept, everything else becomes a single -. Case is folded rune by
+// rune, so the result is the same whatever the locale. It returns "" for text without
+// letters or digits.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		dash = true
+	}
+	return truncateUTF8(b.String(), maxSlugBytes)
+}
+
+// safeFilenamePart replaces the characters of s that are unsafe in file names on common
+// file systems (path separators, reserved and control characters) with _
+func safeFilenamePart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "." || s == ".." {
+		return strings.Repeat("_", len(s))
+	}
+	return s
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character, dropping a
+// trailing -
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return strings.TrimRight(s[:n], "-")
+}
+
+// FileNamer picks the file each session of an export is written to. When a name is
+// already taken, by another session of the same export or by an existing file that is
+// not an earlier export of the same session, a numeric suffix is added: name-2.ext,
+// name-3.ext. An existing file counts as an earlier export of a session when the
+// export's progress manifest says so or, for templates using {id}, when the manifest
+// doesn't record it for another session. With Overwrite, names are used as they are and
+// existing files are replaced.
+type FileNamer struct {
+	// Group, when set, returns the subdirectory of the output a session's file is placed
+	// in, such as WorkspaceDir of its workspace
+	Group func(session *internal.Session) string
+
+	template  *FilenameTemplate
+	dir       string
+	overwrite bool
+	previous  map[string]string // file name -> ID of the session an earlier export wrote to it
+	used      map[string]string // file name -> ID of the session this export writes to it
+}
+
+// NewFileNamer returns a FileNamer for an export into dir, or into an archive when dir is
+// "". previous maps session IDs to the files an earlier export into dir wrote them to.
+func NewFileNamer(template *FilenameTemplate, dir string, overwrite bool, previous map[string]string) *FileNamer {
+	n := &FileNamer{
+		template:  template,
+		dir:       dir,
+		overwrite: overwrite,
+		previous:  make(map[string]string, len(previous)),
+		used:      make(map[string]string),
+	}
+	for id, name := range previous {
+		n.previous[name] = id
+	}
+	return n
+}
+
+// Name returns the file name a session is written to and reserves it for the session.
+// Asking again for the same session returns the same name.
+func (n *FileNamer) Name(exporter Exporter, session *internal.Session) string {
+	base := n.template.Render(session)
+	if n.Group != nil {
+		base = n.Group(session) + "/" + base
+	}
+	ext := "." + exporter.Extension()
+	name := base + ext
+	if n.overwrite {
+		n.used[name] = session.ID
+		return name
+	}
+	for i := 2; !n.available(name, session.ID); i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	if name != base+ext {
+		internal.LogInfo("%s is taken, exporting session %s to %s", base+ext, session.ID, name)
+	}
+	n.used[name] = session.ID
+	return name
+}
+
+// available reports whether a session can be written to a file name
+func (n *FileNamer) available(name, sessionID string) bool {
+	if owner, ok := n.used[name]; ok {
+		return owner == sessionID
+	}
+	if n.dir == "" {
+		return true
+	}
+	if _, err := os.Lstat(filepath.Join(n.dir, name)); err != nil {
+		return true
+	}
+	if owner, ok := n.previous[name]; ok {
+		return owner == sessionID
+	}
+	return n.template.hasID
+}
@@ -0,0 +1,153 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Fix the Login Bug", "fix-the-login-bug"},
+		{"  ../../etc/passwd  ", "etc-passwd"},
+		{"API: v2 (draft)?", "api-v2-draft"},
+		{"Résumé über Straße", "résumé-über-straße"},
+		{"İstanbul", "istanbul"},
+		{"日本語のテスト", "日本語のテスト"},
+		{"C:\\Users\\me", "c-users-me"},
+		{"---", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := Slugify(strings.Repeat("é", 100))
+	if len(long) > maxSlugBytes || !strings.HasPrefix(long, "éé") || strings.ContainsRune(long, '\uFFFD') {
+		t.Errorf("Slugify() of a long name = %q (%d bytes), want at most %d bytes of whole characters", long, len(long), maxSlugBytes)
+	}
+}
+
+func TestParseFilenameTemplate(t *testing.T) {
+	for _, source := range []string{"session_{id}", "{date}_{name}", "{workspace}-{short_id}", "chat"} {
+		if _, err := ParseFilenameTemplate(source); err != nil {
+			t.Errorf("ParseFilenameTemplate(%q) error = %v", source, err)
+		}
+	}
+	for _, source := range []string{"", "{title}", "{name", "name}", "{workspace}/{name}", "..{id}", `a\{id}`} {
+		if _, err := ParseFilenameTemplate(source); err == nil {
+			t.Errorf("ParseFilenameTemplate(%q) should fail", source)
+		}
+	}
+}
+
+func TestFilenameTemplate_Render(t *testing.T) {
+	session := internal.CreateTestSession("0123456789abcdef")
+	session.Metadata.Name = "Fix: the/login bug"
+	session.Metadata.CreatedAt = "2024-06-01T23:30:00-02:00"
+	session.Workspace = "/home/me/projects/My API"
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{DefaultFilenameTemplate, "session_0123456789abcdef"},
+		{"{date}_{name}", "2024-06-02_fix-the-login-bug"},
+		{"{workspace}-{short_id}", "my-api-01234567"},
+		{SpecStoryFilenameTemplate, "2024-06-02_01-30Z-fix-the-login-bug"},
+	}
+	for _, tt := range tests {
+		template, err := ParseFilenameTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("ParseFilenameTemplate(%q) error = %v", tt.template, err)
+		}
+		if got := template.Render(session); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	// Missing fields fall back to placeholders, and IDs lose unsafe characters
+	bare := internal.CreateTestSession("../evil")
+	bare.Metadata.Name = ""
+	bare.Metadata.CreatedAt = ""
+	bare.Workspace = ""
+	template, _ := ParseFilenameTemplate("{id}_{name}_{workspace}_{date}_{time}")
+	if got, want := template.Render(bare), ".._evil_untitled_no-workspace_undated_00-00"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestFileNamer(t *testing.T) {
+	dir := t.TempDir()
+	exporter := &JSONLExporter{}
+	named := func(id, name string) *internal.Session {
+		session := internal.CreateTestSession(id)
+		session.Metadata.Name = name
+		return session
+	}
+	byName, _ := ParseFilenameTemplate("{name}")
+	byID, _ := ParseFilenameTemplate(DefaultFilenameTemplate)
+
+	// Sessions sharing a name get numbered; asking again returns the same name
+	namer := NewFileNamer(byName, "", false, nil)
+	for _, tt := range []struct{ id, want string }{
+		{"a", "refactor.jsonl"},
+		{"b", "refactor-2.jsonl"},
+		{"c", "refactor-3.jsonl"},
+		{"a", "refactor.jsonl"},
+	} {
+		if got := namer.Name(exporter, named(tt.id, "Refactor")); got != tt.want {
+			t.Errorf("Name(%s) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	// An existing file is only replaced by the session an earlier export wrote to it
+	if err := os.WriteFile(filepath.Join(dir, "refactor.jsonl"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.jsonl"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	namer = NewFileNamer(byName, dir, false, map[string]string{"b": "refactor.jsonl"})
+	if got := namer.Name(exporter, named("a", "Refactor")); got != "refactor-2.jsonl" {
+		t.Errorf("Name() of another session = %q, want refactor-2.jsonl", got)
+	}
+	if got := namer.Name(exporter, named("b", "Refactor")); got != "refactor.jsonl" {
+		t.Errorf("Name() of the session that wrote the file = %q, want refactor.jsonl", got)
+	}
+	if got := namer.Name(exporter, named("c", "Notes")); got != "notes-2.jsonl" {
+		t.Errorf("Name() over an unknown file = %q, want notes-2.jsonl", got)
+	}
+
+	// A file named after the session's ID is its earlier export
+	if err := os.WriteFile(filepath.Join(dir, "session_a.jsonl"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	namer = NewFileNamer(byID, dir, false, nil)
+	if got := namer.Name(exporter, named("a", "")); got != "session_a.jsonl" {
+		t.Errorf("Name() with {id} = %q, want session_a.jsonl", got)
+	}
+
+	// Grouped sessions are named within their group's directory
+	namer = NewFileNamer(byName, dir, false, nil)
+	namer.Group = func(session *internal.Session) string { return WorkspaceDir(session.Workspace) }
+	if got := namer.Name(exporter, named("a", "Refactor")); got != "test-workspace/refactor.jsonl" {
+		t.Errorf("Name() with a group = %q, want test-workspace/refactor.jsonl", got)
+	}
+
+	// --overwrite keeps the names as they are
+	namer = NewFileNamer(byName, dir, true, nil)
+	for _, id := range []string{"a", "b"} {
+		if got := namer.Name(exporter, named(id, "Notes")); got != "notes.jsonl" {
+			t.Errorf("Name(%s) with overwrite = %q, want notes.jsonl", id, got)
+		}
+	}
+}
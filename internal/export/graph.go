@@ -0,0 +1,278 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// graphToolCall matches the tool calls rendered into message text: a [Tool Call] section,
+// capturing the tool name, or a marker the rich text parser writes before a tool call
+var graphToolCall = regexp.MustCompile(`(?m)^\[Tool Call\](?:\nTool: ([^\n]+))?|^\[(?:tool|tool_call|function_call)\]$`)
+
+// graphSnippetLength is the maximum length of a message preview in a diagram
+const graphSnippetLength = 60
+
+// graphStep is one step of a session's timeline, as drawn by the graph exporters
+type graphStep struct {
+	Actor     string              // Actor of the message
+	Text      string              // Preview of the message text
+	ToolCalls []string            // Tools the message invoked, in order
+	Diffs     []internal.CodeDiff // Edits the message applied
+}
+
+// graphSteps returns the timeline of a session, one step per message
+func graphSteps(session *internal.Session) []graphStep {
+	steps := make([]graphStep, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		step := graphStep{Actor: msg.Actor, Text: graphSnippet(msg.Content), Diffs: msg.Diffs}
+		for _, match := range graphToolCall.FindAllStringSubmatch(msg.Content, -1) {
+			name := strings.TrimSpace(match[1])
+			if name == "" {
+				name = "tool"
+			}
+			step.ToolCalls = append(step.ToolCalls, name)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// graphSnippet returns the first line of a message that reads as text, cut to
+// graphSnippetLength characters. Tool call sections, which the diagrams draw on their own,
+// code fences and the labels of tool responses are left out.
+func graphSnippet(content string) string {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if graphToolCall.MatchString(line) {
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "```") || (strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")) ||
+			strings.HasPrefix(line, "Tool: ") || strings.HasPrefix(line, "Call ID: ") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "Content: ")
+		if runes := []rune(line); len(runes) > graphSnippetLength {
+			line = string(runes[:graphSnippetLength]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// diffSummary describes an edit by its file and the number of lines added and removed
+func diffSummary(diff internal.CodeDiff) string {
+	path := diff.FilePath
+	if path == "" {
+		path = "unknown file"
+	}
+	added, removed := 0, 0
+	for _, hunk := range diff.Hunks {
+		added += len(hunk.After)
+		removed += len(hunk.Before)
+	}
+	return fmt.Sprintf("Edited %s (+%d -%d)", path, added, removed)
+}
+
+// MermaidExporter exports a session as a Mermaid sequence diagram, for embedding a
+// summary of an agent run in a pull request description or wiki page. User and assistant
+// turns are messages between the two, tool and terminal invocations activate a Tools or
+// Terminal participant until their output arrives, and edits are notes on the assistant.
+type MermaidExporter struct{}
+
+// mermaidParticipants declares the participants of the diagram, keyed by actor
+var mermaidParticipants = []struct{ actor, declaration string }{
+	{internal.ActorUser, "actor User"},
+	{internal.ActorAssistant, "participant Assistant"},
+	{internal.ActorTool, "participant Tools"},
+	{internal.ActorTerminal, "participant Terminal"},
+}
+
+// mermaidNames names the participant of each actor
+var mermaidNames = map[string]string{
+	internal.ActorUser:      "User",
+	internal.ActorAssistant: "Assistant",
+	internal.ActorTool:      "Tools",
+	internal.ActorTerminal:  "Terminal",
+}
+
+// Export exports a session to a Mermaid sequence diagram
+func (e *MermaidExporter) Export(session *internal.Session, w io.Writer) error {
+	steps := graphSteps(session)
+
+	// Declare only the participants the session involves, in a fixed order
+	used := map[string]bool{internal.ActorUser: true, internal.ActorAssistant: true}
+	for _, step := range steps {
+		if _, ok := mermaidNames[step.Actor]; ok {
+			used[step.Actor] = true
+		}
+		for _, tool := range step.ToolCalls {
+			used[internal.ToolActor(tool)] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	title := session.Metadata.Name
+	if title == "" {
+		title = "Session " + session.ID
+	}
+	fmt.Fprintf(&b, "    title %s\n", mermaidText(title))
+	for _, p := range mermaidParticipants {
+		if used[p.actor] {
+			fmt.Fprintf(&b, "    %s\n", p.declaration)
+		}
+	}
+
+	// active lists the participants activated by tool calls that are waiting for output
+	var active []string
+	deactivate := func() {
+		for _, name := range active {
+			fmt.Fprintf(&b, "    deactivate %s\n", name)
+		}
+		active = nil
+	}
+
+	for _, step := range steps {
+		text := mermaidText(step.Text)
+		if text == "" {
+			text = "..."
+		}
+		switch step.Actor {
+		case internal.ActorUser:
+			deactivate()
+			fmt.Fprintf(&b, "    User->>Assistant: %s\n", text)
+		case internal.ActorAssistant:
+			deactivate()
+			if step.Text != "" || len(step.ToolCalls) == 0 {
+				fmt.Fprintf(&b, "    Assistant->>User: %s\n", text)
+			}
+			for _, tool := range step.ToolCalls {
+				name := mermaidNames[internal.ToolActor(tool)]
+				fmt.Fprintf(&b, "    Assistant->>+%s: %s\n", name, mermaidText(tool))
+				active = append(active, name)
+			}
+		case internal.ActorTool, internal.ActorTerminal:
+			name := mermaidNames[step.Actor]
+			arrow := "-->>"
+			// Output closes the oldest call to the same participant
+			for i, a := range active {
+				if a == name {
+					arrow = "-->>-"
+					active = append(active[:i], active[i+1:]...)
+					break
+				}
+			}
+			fmt.Fprintf(&b, "    %s%sAssistant: %s\n", name, arrow, text)
+		default:
+			label := step.Actor
+			if label == internal.ActorSystem {
+				label = "System"
+			}
+			fmt.Fprintf(&b, "    Note over User,Assistant: %s: %s\n", mermaidText(label), text)
+		}
+		for _, diff := range step.Diffs {
+			fmt.Fprintf(&b, "    Note over Assistant: %s\n", mermaidText(diffSummary(diff)))
+		}
+	}
+	deactivate()
+
+	for _, diff := range session.Diffs {
+		fmt.Fprintf(&b, "    Note over Assistant: %s\n", mermaidText(diffSummary(diff)))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidText makes text safe for a Mermaid message or note: it stays on one line, and
+// the characters Mermaid reads as syntax are written as entity codes
+func mermaidText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer("#", "#35;", ";", "#59;", "<", "#lt;", ">", "#gt;").Replace(text)
+}
+
+// Extension returns the file extension for this format
+func (e *MermaidExporter) Extension() string {
+	return "mmd"
+}
+
+// DOTExporter exports a session as a Graphviz DOT graph: the messages form a chain of
+// boxes colored by actor, with each tool invocation and edit hanging off the message
+// that made it. Render it with `dot -Tsvg`.
+type DOTExporter struct{}
+
+// dotColors are the fill colors of message nodes, by actor
+var dotColors = map[string]string{
+	internal.ActorUser:      "#dbeafe",
+	internal.ActorAssistant: "#ede9fe",
+	internal.ActorTool:      "#fef3c7",
+	internal.ActorTerminal:  "#dcfce7",
+	internal.ActorSystem:    "#e5e7eb",
+}
+
+// Export exports a session to a DOT graph
+func (e *DOTExporter) Export(session *internal.Session, w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotString(session.ID))
+	if session.Metadata.Name != "" {
+		fmt.Fprintf(&b, "    label=%s;\n    labelloc=t;\n", dotString(session.Metadata.Name))
+	}
+	b.WriteString("    rankdir=TB;\n")
+	b.WriteString("    node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	for i, step := range graphSteps(session) {
+		id := fmt.Sprintf("m%d", i+1)
+		color, ok := dotColors[step.Actor]
+		if !ok {
+			color = "#ffffff"
+		}
+		label := step.Actor
+		if step.Text != "" {
+			label += "\n" + step.Text
+		}
+		fmt.Fprintf(&b, "    %s [label=%s, fillcolor=%s];\n", id, dotString(label), dotString(color))
+		if i > 0 {
+			fmt.Fprintf(&b, "    m%d -> %s;\n", i, id)
+		}
+		for j, tool := range step.ToolCalls {
+			toolID := fmt.Sprintf("%s_tool%d", id, j+1)
+			fmt.Fprintf(&b, "    %s [label=%s, shape=ellipse, style=filled, fillcolor=%s];\n", toolID, dotString(tool), dotString(dotColors[internal.ToolActor(tool)]))
+			fmt.Fprintf(&b, "    %s -> %s [style=dashed];\n", id, toolID)
+		}
+		for j, diff := range step.Diffs {
+			diffID := fmt.Sprintf("%s_edit%d", id, j+1)
+			fmt.Fprintf(&b, "    %s [label=%s, shape=note, style=\"\"];\n", diffID, dotString(diffSummary(diff)))
+			fmt.Fprintf(&b, "    %s -> %s [style=dotted];\n", id, diffID)
+		}
+	}
+	// Edits that could not be matched to a message follow the last one
+	for j, diff := range session.Diffs {
+		fmt.Fprintf(&b, "    edit%d [label=%s, shape=note, style=\"\"];\n", j+1, dotString(diffSummary(diff)))
+		if len(session.Messages) > 0 {
+			fmt.Fprintf(&b, "    m%d -> edit%d [style=dotted];\n", len(session.Messages), j+1)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotString quotes text as a DOT string, keeping line breaks as DOT escapes
+func dotString(text string) string {
+	text = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`).Replace(text)
+	return `"` + text + `"`
+}
+
+// Extension returns the file extension for this format
+func (e *DOTExporter) Extension() string {
+	return "dot"
+}
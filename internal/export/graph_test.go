@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// graphTestSession is an agent run with a tool call, a terminal command and an edit
+func graphTestSession() *internal.Session {
+	session := internal.CreateTestSessionWithMessages("run1", []internal.Message{
+		{Actor: "system", Content: "You are a coding agent"},
+		{Actor: "user", Content: "Fix the failing test; see #42"},
+		{Actor: "assistant", Content: "Let me look.\n\n[Tool Call]\nTool: read_file\nArguments: {\"path\":\"a.go\"}\n\n[Tool Call]\nTool: run_terminal_cmd"},
+		{Actor: "tool", Content: "[Tool Response]\nTool: read_file\nContent: package a"},
+		{Actor: "terminal", Content: "[Tool Response]\nFAIL a_test.go"},
+		{Actor: "assistant", Content: "Fixed the <nil> check.", Diffs: []internal.CodeDiff{{
+			FilePath: "a.go",
+			Hunks:    []internal.DiffHunk{{OriginalStart: 3, OriginalEnd: 4, Before: []string{"old"}, After: []string{"new", "line"}}},
+		}}},
+	})
+	session.Metadata.Name = "Fix \"a\" test"
+	return session
+}
+
+func TestMermaidExporter_Export(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&MermaidExporter{}).Export(graphTestSession(), &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := `sequenceDiagram
+    title Fix "a" test
+    actor User
+    participant Assistant
+    participant Tools
+    participant Terminal
+    Note over User,Assistant: System: You are a coding agent
+    User->>Assistant: Fix the failing test#59; see #35;42
+    Assistant->>User: Let me look.
+    Assistant->>+Tools: read_file
+    Assistant->>+Terminal: run_terminal_cmd
+    Tools-->>-Assistant: package a
+    Terminal-->>-Assistant: FAIL a_test.go
+    Assistant->>User: Fixed the #lt;nil#gt; check.
+    Note over Assistant: Edited a.go (+2 -1)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Export() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMermaidExporter_UnansweredToolCall(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("run2", []internal.Message{
+		{Actor: "assistant", Content: "[Tool Call]\nTool: grep"},
+		{Actor: "user", Content: "Stop"},
+	})
+
+	var buf bytes.Buffer
+	if err := (&MermaidExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "    title Session run2\n") {
+		t.Errorf("Export() should title an unnamed session by ID, got:\n%s", got)
+	}
+	if strings.Contains(got, "Assistant->>User") {
+		t.Errorf("Export() drew an empty reply for a message with only a tool call:\n%s", got)
+	}
+	if !strings.Contains(got, "    Assistant->>+Tools: grep\n    deactivate Tools\n    User->>Assistant: Stop\n") {
+		t.Errorf("Export() should close an unanswered tool call before the next turn, got:\n%s", got)
+	}
+}
+
+func TestDOTExporter_Export(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&DOTExporter{}).Export(graphTestSession(), &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"digraph \"run1\" {\n",
+		`    label="Fix \"a\" test";`,
+		`    m2 [label="user\nFix the failing test; see #42", fillcolor="#dbeafe"];`,
+		"    m2 -> m3;",
+		`    m3_tool1 [label="read_file", shape=ellipse, style=filled, fillcolor="#fef3c7"];`,
+		`    m3_tool2 [label="run_terminal_cmd", shape=ellipse, style=filled, fillcolor="#dcfce7"];`,
+		"    m3 -> m3_tool2 [style=dashed];",
+		`    m6_edit1 [label="Edited a.go (+2 -1)", shape=note, style=""];`,
+		"    m6 -> m6_edit1 [style=dotted];",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Export() missing %q in:\n%s", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "}\n") {
+		t.Errorf("Export() should close the graph, got:\n%s", got)
+	}
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// ErrSkipSession can be returned by a BeforeExport hook to leave a session out of the export
+var ErrSkipSession = errors.New("session skipped")
+
+// Hooks customize an export for applications embedding the exporters. Every hook is optional.
+type Hooks struct {
+	// BeforeExport is called with each session before it is written and returns the
+	// session to write. Return a modified copy rather than changing the session in place.
+	BeforeExport func(session *internal.Session) (*internal.Session, error)
+
+	// TransformMessage is called with each message of the session being written and
+	// returns the message to write, or false to leave the message out
+	TransformMessage func(session *internal.Session, msg internal.Message) (internal.Message, bool)
+
+	// AfterExport is called once the session has been written, or has failed to export,
+	// and returns the error to report in place of err. It is not called for skipped sessions.
+	AfterExport func(session *internal.Session, err error) error
+}
+
+// hookedExporter runs hooks around another exporter
+type hookedExporter struct {
+	exporter Exporter
+	hooks    []Hooks
+}
+
+// WithHooks returns an exporter that runs hooks around exporter. With several Hooks, each
+// stage runs them in the order given, so one hook sees the output of the previous one.
+func WithHooks(exporter Exporter, hooks ...Hooks) Exporter {
+	return &hookedExporter{exporter: exporter, hooks: hooks}
+}
+
+func (e *hookedExporter) Export(session *internal.Session, w io.Writer) error {
+	var err error
+	for _, h := range e.hooks {
+		if h.BeforeExport == nil {
+			continue
+		}
+		next, hookErr := h.BeforeExport(session)
+		if hookErr != nil {
+			err = hookErr
+			break
+		}
+		session = next
+		if session == nil {
+			err = ErrSkipSession
+			break
+		}
+	}
+
+	if errors.Is(err, ErrSkipSession) {
+		return err
+	}
+	if err == nil {
+		session = e.transformMessages(session)
+		err = e.exporter.Export(session, w)
+	}
+
+	for _, h := range e.hooks {
+		if h.AfterExport != nil {
+			err = h.AfterExport(session, err)
+		}
+	}
+	return err
+}
+
+func (e *hookedExporter) Extension() string {
+	return e.exporter.Extension()
+}
+
+// transformMessages returns a copy of session with every TransformMessage hook applied to
+// its messages
+func (e *hookedExporter) transformMessages(session *internal.Session) *internal.Session {
+	var transforms []func(*internal.Session, internal.Message) (internal.Message, bool)
+	for _, h := range e.hooks {
+		if h.TransformMessage != nil {
+			transforms = append(transforms, h.TransformMessage)
+		}
+	}
+	if len(transforms) == 0 {
+		return session
+	}
+
+	transformed := *session
+	transformed.Messages = make([]internal.Message, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		keep := true
+		for _, transform := range transforms {
+			if msg, keep = transform(session, msg); !keep {
+				break
+			}
+		}
+		if keep {
+			transformed.Messages = append(transformed.Messages, msg)
+		}
+	}
+	transformed.Metadata.MessageCount = len(transformed.Messages)
+	return &transformed
+}
+
+// SessionFileName returns the name of the file a session is exported to by default:
+// session_<id>.<ext>, with characters that are unsafe in file names replaced
+func SessionFileName(exporter Exporter, session *internal.Session) string {
+	return fmt.Sprintf("session_%s.%s", safeFilenamePart(session.ID), exporter.Extension())
+}
+
+// WriteSessionFile writes a session to session_<id>.<ext> in dir. The file is written under
+// a temporary name and renamed when complete, so a failed or skipped export leaves an
+// earlier file in place. A session skipped by a hook returns ErrSkipSession.
+func WriteSessionFile(exporter Exporter, dir string, session *internal.Session) error {
+	return writeSessionFile(exporter, dir, SessionFileName(exporter, session), session)
+}
+
+// writeSessionFile writes a session to the file name in dir, like WriteSessionFile
+func writeSessionFile(exporter Exporter, dir, name string, session *internal.Session) error {
+	path := filepath.Join(dir, name)
+	// Names may place the file in a subdirectory, such as that of its workspace
+	dir = filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.CreateTemp(dir, ".session_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+
+	if err := exporter.Export(session, file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		internal.LogWarn("Failed to set permissions on %s: %v", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return nil
+}
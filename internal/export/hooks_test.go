@@ -0,0 +1,134 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestWithHooks(t *testing.T) {
+	session := internal.CreateTestSession("hooked")
+	var after []string
+
+	exporter := WithHooks(&JSONExporter{},
+		Hooks{
+			BeforeExport: func(s *internal.Session) (*internal.Session, error) {
+				tagged := *s
+				tagged.Metadata.Tags = []string{"org:acme"}
+				return &tagged, nil
+			},
+			TransformMessage: func(s *internal.Session, msg internal.Message) (internal.Message, bool) {
+				if msg.Actor == "assistant" {
+					return msg, false
+				}
+				msg.Content = strings.ToUpper(msg.Content)
+				return msg, true
+			},
+		},
+		Hooks{
+			TransformMessage: func(s *internal.Session, msg internal.Message) (internal.Message, bool) {
+				msg.Content += " [" + s.Metadata.Tags[0] + "]"
+				return msg, true
+			},
+			AfterExport: func(s *internal.Session, err error) error {
+				after = append(after, s.ID)
+				return err
+			},
+		},
+	)
+
+	if ext := exporter.Extension(); ext != "json" {
+		t.Errorf("Extension() = %s, want json", ext)
+	}
+
+	var buf bytes.Buffer
+	if err := exporter.Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"org:acme", "HELLO, HOW ARE YOU? [org:acme]"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Export() output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "doing well") {
+		t.Errorf("Export() kept a dropped message:\n%s", output)
+	}
+	if len(after) != 1 || after[0] != "hooked" {
+		t.Errorf("AfterExport calls = %v, want [hooked]", after)
+	}
+	if len(session.Messages) != 2 || session.Messages[0].Content != "Hello, how are you?" || session.Metadata.Tags != nil {
+		t.Error("Export() modified the original session")
+	}
+}
+
+func TestWithHooks_Errors(t *testing.T) {
+	session := internal.CreateTestSession("hooked")
+	hookErr := errors.New("rejected")
+
+	afterCalled := false
+	exporter := WithHooks(&JSONExporter{}, Hooks{
+		BeforeExport: func(s *internal.Session) (*internal.Session, error) {
+			return nil, hookErr
+		},
+		AfterExport: func(s *internal.Session, err error) error {
+			afterCalled = true
+			if s != session || !errors.Is(err, hookErr) {
+				t.Errorf("AfterExport(%v, %v), want the original session and the hook error", s, err)
+			}
+			return nil
+		},
+	})
+	var buf bytes.Buffer
+	if err := exporter.Export(session, &buf); err != nil || !afterCalled {
+		t.Errorf("Export() = %v with AfterExport called %v, want the error cleared by AfterExport", err, afterCalled)
+	}
+
+	skipping := WithHooks(&JSONExporter{}, Hooks{
+		BeforeExport: func(s *internal.Session) (*internal.Session, error) {
+			return nil, nil
+		},
+		AfterExport: func(s *internal.Session, err error) error {
+			t.Error("AfterExport called for a skipped session")
+			return err
+		},
+	})
+	if err := skipping.Export(session, &buf); !errors.Is(err, ErrSkipSession) {
+		t.Errorf("Export() error = %v, want ErrSkipSession", err)
+	}
+}
+
+func TestWriteSessionFile(t *testing.T) {
+	dir := t.TempDir()
+	session := internal.CreateTestSession("written")
+	path := filepath.Join(dir, "session_written.json")
+
+	if err := WriteSessionFile(&JSONExporter{}, dir, session); err != nil {
+		t.Fatalf("WriteSessionFile() error = %v", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(original, []byte("written")) {
+		t.Fatalf("WriteSessionFile() wrote %q, %v", original, err)
+	}
+
+	skipping := WithHooks(&JSONExporter{}, Hooks{
+		BeforeExport: func(s *internal.Session) (*internal.Session, error) {
+			return nil, ErrSkipSession
+		},
+	})
+	if err := WriteSessionFile(skipping, dir, session); !errors.Is(err, ErrSkipSession) {
+		t.Errorf("WriteSessionFile() error = %v, want ErrSkipSession", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, original) {
+		t.Error("skipped export replaced the earlier file")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("output directory has %d entries, want only the session file", len(entries))
+	}
+}
@@ -24,7 +24,33 @@ func NewExporter(format string) (Exporter, error) {
 		return &YAMLExporter{}, nil
 	case "json":
 		return &JSONExporter{}, nil
+	case "parquet":
+		return &ParquetExporter{}, nil
+	case "csv":
+		return &CSVExporter{}, nil
+	case "mermaid", "mmd":
+		return &MermaidExporter{}, nil
+	case "dot":
+		return &DOTExporter{}, nil
+	case "specstory-md":
+		return &SpecStoryExporter{}, nil
+	case "events-jsonl":
+		return &EventsExporter{}, nil
 	default:
-		return nil, fmt.Errorf("unsupported format: %s (supported: jsonl, md, yaml, json)", format)
+		return nil, fmt.Errorf("unsupported format: %s (supported: jsonl, md, yaml, json, parquet, csv, mermaid, dot, specstory-md, events-jsonl)", format)
 	}
 }
+
+// utcSession returns a copy of session with every timestamp rewritten in UTC.
+// Sessions cached by older versions may still carry local time offsets.
+func utcSession(session *internal.Session) *internal.Session {
+	converted := *session
+	converted.Metadata.CreatedAt = internal.UTCTimestamp(session.Metadata.CreatedAt)
+	converted.Metadata.UpdatedAt = internal.UTCTimestamp(session.Metadata.UpdatedAt)
+	converted.Messages = make([]internal.Message, len(session.Messages))
+	for i, msg := range session.Messages {
+		msg.Timestamp = internal.UTCTimestamp(msg.Timestamp)
+		converted.Messages[i] = msg
+	}
+	return &converted
+}
@@ -1,7 +1,11 @@
 package export
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
 )
 
 func TestNewExporter(t *testing.T) {
@@ -47,6 +51,48 @@ func TestNewExporter(t *testing.T) {
 			wantExt:  "json",
 			wantErr:  false,
 		},
+		{
+			name:     "parquet format",
+			format:   "parquet",
+			wantType: "ParquetExporter",
+			wantExt:  "parquet",
+			wantErr:  false,
+		},
+		{
+			name:     "csv format",
+			format:   "csv",
+			wantType: "CSVExporter",
+			wantExt:  "csv",
+			wantErr:  false,
+		},
+		{
+			name:     "mermaid format",
+			format:   "mermaid",
+			wantType: "MermaidExporter",
+			wantExt:  "mmd",
+			wantErr:  false,
+		},
+		{
+			name:     "dot format",
+			format:   "dot",
+			wantType: "DOTExporter",
+			wantExt:  "dot",
+			wantErr:  false,
+		},
+		{
+			name:     "specstory-md format",
+			format:   "specstory-md",
+			wantType: "SpecStoryExporter",
+			wantExt:  "md",
+			wantErr:  false,
+		},
+		{
+			name:     "events-jsonl format",
+			format:   "events-jsonl",
+			wantType: "EventsExporter",
+			wantExt:  "events.jsonl",
+			wantErr:  false,
+		},
 		{
 			name:     "unsupported format",
 			format:   "xml",
@@ -100,6 +146,30 @@ func TestNewExporter(t *testing.T) {
 					if _, ok := exporter.(*JSONExporter); !ok {
 						t.Errorf("Expected JSONExporter, got %T", exporter)
 					}
+				case "ParquetExporter":
+					if _, ok := exporter.(*ParquetExporter); !ok {
+						t.Errorf("Expected ParquetExporter, got %T", exporter)
+					}
+				case "CSVExporter":
+					if _, ok := exporter.(*CSVExporter); !ok {
+						t.Errorf("Expected CSVExporter, got %T", exporter)
+					}
+				case "MermaidExporter":
+					if _, ok := exporter.(*MermaidExporter); !ok {
+						t.Errorf("Expected MermaidExporter, got %T", exporter)
+					}
+				case "DOTExporter":
+					if _, ok := exporter.(*DOTExporter); !ok {
+						t.Errorf("Expected DOTExporter, got %T", exporter)
+					}
+				case "SpecStoryExporter":
+					if _, ok := exporter.(*SpecStoryExporter); !ok {
+						t.Errorf("Expected SpecStoryExporter, got %T", exporter)
+					}
+				case "EventsExporter":
+					if _, ok := exporter.(*EventsExporter); !ok {
+						t.Errorf("Expected EventsExporter, got %T", exporter)
+					}
 				}
 			} else {
 				if exporter != nil {
@@ -109,3 +179,35 @@ func TestNewExporter(t *testing.T) {
 		})
 	}
 }
+
+func TestExporters_UTCTimestamps(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "user", Content: "Hello", Timestamp: "2024-03-01T14:30:00+02:00"},
+	})
+	session.Metadata.CreatedAt = "2024-03-01T14:00:00+02:00"
+
+	for _, format := range []string{"jsonl", "json", "yaml", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			exporter, err := NewExporter(format)
+			if err != nil {
+				t.Fatalf("NewExporter() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := exporter.Export(session, &buf); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			output := buf.String()
+			if !strings.Contains(output, "2024-03-01T12:30:00Z") {
+				t.Errorf("Export() should write message timestamps in UTC, got:\n%s", output)
+			}
+			if strings.Contains(output, "+02:00") {
+				t.Errorf("Export() should not keep local offsets, got:\n%s", output)
+			}
+		})
+	}
+
+	// The session passed in is left untouched
+	if session.Messages[0].Timestamp != "2024-03-01T14:30:00+02:00" {
+		t.Errorf("Export() modified the session timestamp: %s", session.Messages[0].Timestamp)
+	}
+}
@@ -2,20 +2,31 @@ package export
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/iksnae/cursor-session/internal"
 )
 
 // JSONExporter exports sessions in JSON format (pretty-printed)
-type JSONExporter struct{}
+type JSONExporter struct {
+	// Fields, when set, limits each session to the selected fields
+	Fields *FieldSelection
+}
 
 // Export exports a session to JSON format
 func (e *JSONExporter) Export(session *internal.Session, w io.Writer) error {
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 
-	return enc.Encode(session)
+	if e.Fields != nil {
+		projected, err := e.Fields.Session(utcSession(session))
+		if err != nil {
+			return fmt.Errorf("failed to select fields: %w", err)
+		}
+		return enc.Encode(projected)
+	}
+	return enc.Encode(utcSession(session))
 }
 
 // Extension returns the file extension for this format
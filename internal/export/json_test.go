@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
 )
 
 func TestJSONExporter_Export(t *testing.T) {
@@ -88,3 +89,37 @@ func TestJSONExporter_Extension(t *testing.T) {
 		t.Errorf("JSONExporter.Extension() = %v, want json", got)
 	}
 }
+
+func TestExport_RoundTripActors(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("actors", []internal.Message{
+		{Actor: internal.ActorSystem, Content: "You are a coding agent"},
+		{Actor: internal.ActorUser, Content: "Run the tests"},
+		{Actor: internal.ActorAssistant, Content: "Running them"},
+		{Actor: internal.ActorTool, Content: "read_file main.go"},
+		{Actor: internal.ActorTerminal, Content: "ok  ./..."},
+	})
+
+	for _, exporter := range []Exporter{&JSONExporter{}, &JSONLExporter{}, &YAMLExporter{}} {
+		t.Run(exporter.Extension(), func(t *testing.T) {
+			dir := testutil.CreateTempDir(t)
+			if err := WriteSessionFile(exporter, dir, session); err != nil {
+				t.Fatalf("WriteSessionFile() error = %v", err)
+			}
+
+			backend := internal.NewFileBackend(dir)
+			conversations, err := internal.ReconstructConversations(backend)
+			if err != nil {
+				t.Fatalf("ReconstructConversations() error = %v", err)
+			}
+			sessions := internal.NormalizeSessions(conversations, backend, nil, "")
+			if len(sessions) != 1 || len(sessions[0].Messages) != len(session.Messages) {
+				t.Fatalf("reloaded %d session(s), want 1 with %d messages", len(sessions), len(session.Messages))
+			}
+			for i, msg := range sessions[0].Messages {
+				if msg.Actor != session.Messages[i].Actor {
+					t.Errorf("message %d actor = %q, want %q", i, msg.Actor, session.Messages[i].Actor)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package export
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,26 +10,33 @@ import (
 )
 
 // JSONLExporter exports sessions in JSONL format (one message per line)
-type JSONLExporter struct{}
+type JSONLExporter struct {
+	// Fields, when set, limits each line to the selected fields
+	Fields *FieldSelection
+}
 
 // Export exports a session to JSONL format
 func (e *JSONLExporter) Export(session *internal.Session, w io.Writer) error {
 	enc := json.NewEncoder(w)
 
-	for _, msg := range session.Messages {
-		// Create message object
-		obj := map[string]interface{}{
-			"actor":   msg.Actor,
-			"content": msg.Content,
-		}
+	// Lines of a chunk of a split session name the session it came from
+	var parentID string
+	if session.Metadata.Chunk != nil {
+		parentID = session.Metadata.Chunk.SessionID
+	}
 
-		// Add timestamp if present
-		if msg.Timestamp != "" {
-			obj["timestamp"] = msg.Timestamp
+	for _, msg := range session.Messages {
+		line := jsonlMessage(msg, parentID)
+		if e.Fields != nil {
+			projected, err := e.Fields.Message(session, line)
+			if err != nil {
+				return fmt.Errorf("failed to select fields: %w", err)
+			}
+			line = projected
 		}
 
 		// Encode to single line
-		if err := enc.Encode(obj); err != nil {
+		if err := enc.Encode(line); err != nil {
 			return fmt.Errorf("failed to encode message: %w", err)
 		}
 	}
@@ -40,3 +48,106 @@ func (e *JSONLExporter) Export(session *internal.Session, w io.Writer) error {
 func (e *JSONLExporter) Extension() string {
 	return "jsonl"
 }
+
+// jsonlMessage is the JSONL line of a message; parentID names the session a chunk came from
+func jsonlMessage(msg internal.Message, parentID string) map[string]interface{} {
+	// Create message object
+	obj := map[string]interface{}{
+		"actor":   msg.Actor,
+		"content": msg.Content,
+	}
+
+	// Add timestamp if present
+	if msg.Timestamp != "" {
+		obj["timestamp"] = internal.UTCTimestamp(msg.Timestamp)
+	}
+
+	// Add the thinking kept apart by --thinking separate
+	if msg.Thinking != "" {
+		obj["thinking"] = msg.Thinking
+	}
+
+	// Add provenance if present
+	if msg.Provenance != nil {
+		obj["provenance"] = msg.Provenance
+	}
+
+	// Add the time the assistant took to respond
+	if msg.ResponseMs > 0 {
+		obj["response_ms"] = msg.ResponseMs
+	}
+
+	// Record the original length of content cut down by a size limit
+	if msg.Oversize != nil {
+		obj["oversize"] = msg.Oversize
+	}
+
+	// List the attached images, without the data of those stored inline
+	if len(msg.Attachments) > 0 {
+		attachments := make([]internal.Attachment, len(msg.Attachments))
+		for i, att := range msg.Attachments {
+			att.Data = nil
+			attachments[i] = att
+		}
+		obj["attachments"] = attachments
+	}
+
+	if parentID != "" {
+		obj["parent_session_id"] = parentID
+	}
+	return obj
+}
+
+// Record types of a JSONL stream
+const (
+	StreamRecordSession = "session"
+	StreamRecordMessage = "message"
+)
+
+// JSONLStreamWriter writes sessions into a single NDJSON stream: for each session, a
+// session record with its metadata followed by a record per message, as the JSONL export
+// writes them, each carrying the session's ID. Every session is flushed once written, so
+// a consumer reading the stream sees it without waiting for the next.
+type JSONLStreamWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLStreamWriter returns a JSONLStreamWriter writing to w
+func NewJSONLStreamWriter(w io.Writer) *JSONLStreamWriter {
+	buffered := bufio.NewWriter(w)
+	return &JSONLStreamWriter{w: buffered, enc: json.NewEncoder(buffered)}
+}
+
+// WriteSession writes the records of a session
+func (s *JSONLStreamWriter) WriteSession(session *internal.Session) error {
+	metadata := session.Metadata
+	metadata.CreatedAt = internal.UTCTimestamp(metadata.CreatedAt)
+	metadata.UpdatedAt = internal.UTCTimestamp(metadata.UpdatedAt)
+	header := map[string]interface{}{
+		"type":       StreamRecordSession,
+		"session_id": session.ID,
+		"source":     session.Source,
+		"metadata":   metadata,
+	}
+	if session.Workspace != "" {
+		header["workspace"] = session.Workspace
+	}
+	if err := s.enc.Encode(header); err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", session.ID, err)
+	}
+
+	var parentID string
+	if session.Metadata.Chunk != nil {
+		parentID = session.Metadata.Chunk.SessionID
+	}
+	for _, msg := range session.Messages {
+		obj := jsonlMessage(msg, parentID)
+		obj["type"] = StreamRecordMessage
+		obj["session_id"] = session.ID
+		if err := s.enc.Encode(obj); err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+	}
+	return s.w.Flush()
+}
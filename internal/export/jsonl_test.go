@@ -131,3 +131,150 @@ func TestJSONLExporter_Export_NilSession(t *testing.T) {
 	}()
 	_ = exporter.Export(nil, &buf) // Error ignored intentionally for panic test
 }
+
+func TestJSONLExporter_Export_Provenance(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := &JSONLExporter{}
+
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{
+			Actor:   "user",
+			Content: "Hello",
+			Provenance: &internal.Provenance{
+				SourcePath: "/tmp/store.db",
+				BlobKey:    "abc123",
+				Backend:    "agentStorage",
+				Strategy:   "text$uuid",
+			},
+		},
+		{Actor: "assistant", Content: "Hi"},
+	})
+
+	if err := exporter.Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Export() produced %d lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], `"provenance":{"source_path":"/tmp/store.db","blob_key":"abc123","backend":"agentStorage","strategy":"text$uuid"}`) {
+		t.Errorf("First line should contain provenance, got: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "provenance") {
+		t.Errorf("Second line should not contain provenance, got: %s", lines[1])
+	}
+}
+
+func TestJSONLExporter_Export_Oversize(t *testing.T) {
+	var buf bytes.Buffer
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "assistant", Content: "[message content dropped: 600000 bytes]", Oversize: &internal.Oversize{OriginalBytes: 600000, Strategy: internal.OversizeDrop}},
+	})
+
+	if err := (&JSONLExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"oversize":{"original_bytes":600000,"strategy":"drop"}`) {
+		t.Errorf("Line should record the original length, got: %s", buf.String())
+	}
+}
+
+func TestJSONLExporter_Export_Thinking(t *testing.T) {
+	var buf bytes.Buffer
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "assistant", Content: "Done", Thinking: "Check the tests first"},
+	})
+
+	if err := (&JSONLExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"thinking":"Check the tests first"`) {
+		t.Errorf("Line should hold the thinking, got: %s", buf.String())
+	}
+}
+
+func TestJSONLExporter_Export_ResponseTime(t *testing.T) {
+	var buf bytes.Buffer
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Content: "Done", ResponseMs: 4200},
+	})
+
+	if err := (&JSONLExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], "response_ms") || !strings.Contains(lines[1], `"response_ms":4200`) {
+		t.Errorf("Only the reply should hold its response time, got: %s", buf.String())
+	}
+}
+
+func TestJSONLExporter_Export_Chunk(t *testing.T) {
+	var buf bytes.Buffer
+	session := internal.CreateTestSession("parent.chunk_1")
+	session.Metadata.Chunk = &internal.Chunk{SessionID: "parent", Index: 1, Count: 2, FirstMessage: 1}
+
+	if err := (&JSONLExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		if !strings.Contains(line, `"parent_session_id":"parent"`) {
+			t.Errorf("Each line of a chunk should name its session, got: %s", line)
+		}
+	}
+}
+
+func TestJSONLStreamWriter(t *testing.T) {
+	first := internal.CreateTestSessionWithMessages("first", []internal.Message{
+		{Actor: "user", Content: "Hi", Timestamp: "2024-06-01T10:00:00+02:00"},
+		{Actor: "assistant", Content: "Hello", Thinking: "Greet back"},
+	})
+	first.Workspace = "/code/app"
+	first.Metadata.CreatedAt = "2024-06-01T10:00:00+02:00"
+	second := internal.CreateTestSessionWithMessages("second", []internal.Message{{Actor: "user", Content: "Bye"}})
+	second.Workspace = ""
+
+	var buf bytes.Buffer
+	writer := NewJSONLStreamWriter(&buf)
+	for _, session := range []*internal.Session{first, second} {
+		if err := writer.WriteSession(session); err != nil {
+			t.Fatalf("WriteSession() error = %v", err)
+		}
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+
+	wantTypes := []string{StreamRecordSession, StreamRecordMessage, StreamRecordMessage, StreamRecordSession, StreamRecordMessage}
+	if len(records) != len(wantTypes) {
+		t.Fatalf("wrote %d records, want %d:\n%s", len(records), len(wantTypes), buf.String())
+	}
+	for i, want := range wantTypes {
+		if records[i]["type"] != want {
+			t.Errorf("record %d type = %v, want %s", i, records[i]["type"], want)
+		}
+	}
+
+	header := records[0]
+	metadata, _ := header["metadata"].(map[string]interface{})
+	if header["session_id"] != "first" || header["workspace"] != "/code/app" || metadata["created_at"] != "2024-06-01T08:00:00Z" {
+		t.Errorf("session record = %v, want the session's ID, workspace and UTC metadata", header)
+	}
+	if records[1]["session_id"] != "first" || records[1]["timestamp"] != "2024-06-01T08:00:00Z" {
+		t.Errorf("message record = %v", records[1])
+	}
+	if records[2]["thinking"] != "Greet back" {
+		t.Errorf("message record = %v, want its thinking", records[2])
+	}
+	if _, ok := records[3]["workspace"]; ok || records[4]["session_id"] != "second" {
+		t.Errorf("records of the second session = %v, %v", records[3], records[4])
+	}
+}
@@ -3,16 +3,66 @@ package export
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 
 	"github.com/iksnae/cursor-session/internal"
+	"gopkg.in/yaml.v3"
 )
 
 // MarkdownExporter exports sessions in Markdown format
-type MarkdownExporter struct{}
+type MarkdownExporter struct {
+	Frontmatter bool // Emit YAML frontmatter with session metadata
+	TOC         bool // Emit a table of contents linking to each message
+}
+
+// markdownFrontmatter is the YAML frontmatter written when Frontmatter is set
+type markdownFrontmatter struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name,omitempty"`
+	Workspace    string   `yaml:"workspace,omitempty"`
+	Created      string   `yaml:"created,omitempty"`
+	MessageCount int      `yaml:"message_count"`
+	Tags         []string `yaml:"tags,omitempty"`
+	Branch       string   `yaml:"branch,omitempty"`
+	Commit       string   `yaml:"commit,omitempty"`
+	ChunkOf      string   `yaml:"chunk_of,omitempty"`
+	Chunk        int      `yaml:"chunk,omitempty"`
+	FilesChanged []string `yaml:"files_changed,omitempty"`
+}
+
+// collapsibleMarker matches the markers the rich text parser writes before thinking and tool call content
+var collapsibleMarker = regexp.MustCompile(`^\[(thinking|tool|tool_call|function_call)\]$`)
+
+// tocSnippetLength is the maximum length of a message preview in the table of contents
+const tocSnippetLength = 60
 
 // Export exports a session to Markdown format
 func (e *MarkdownExporter) Export(session *internal.Session, w io.Writer) error {
+	git := session.Metadata.Git
+	if e.Frontmatter {
+		frontmatter := markdownFrontmatter{
+			ID:           session.ID,
+			Name:         session.Metadata.Name,
+			Workspace:    session.Workspace,
+			Created:      internal.UTCTimestamp(session.Metadata.CreatedAt),
+			MessageCount: len(session.Messages),
+			Tags:         session.Metadata.Tags,
+			FilesChanged: session.Metadata.FilesChanged,
+		}
+		if git != nil {
+			frontmatter.Branch, frontmatter.Commit = git.Branch, git.Commit
+		}
+		if chunk := session.Metadata.Chunk; chunk != nil {
+			frontmatter.ChunkOf, frontmatter.Chunk = chunk.SessionID, chunk.Index
+		}
+		data, err := yaml.Marshal(frontmatter)
+		if err != nil {
+			return fmt.Errorf("failed to marshal frontmatter: %w", err)
+		}
+		_, _ = fmt.Fprintf(w, "---\n%s---\n\n", data)
+	}
+
 	// Header
 	_, _ = fmt.Fprintf(w, "# Session %s\n\n", session.ID)
 
@@ -25,21 +75,56 @@ func (e *MarkdownExporter) Export(session *internal.Session, w io.Writer) error
 	if session.Metadata.Name != "" {
 		_, _ = fmt.Fprintf(w, "**Name:** %s\n\n", session.Metadata.Name)
 	}
+	if chunk := session.Metadata.Chunk; chunk != nil {
+		_, _ = fmt.Fprintf(w, "**Part:** %d of %d of session %s, from message %d\n\n", chunk.Index, chunk.Count, chunk.SessionID, chunk.FirstMessage)
+	}
+	if len(session.Metadata.Tags) > 0 {
+		_, _ = fmt.Fprintf(w, "**Tags:** %s\n\n", strings.Join(session.Metadata.Tags, ", "))
+	}
+	if git != nil && (git.Branch != "" || git.Commit != "") {
+		_, _ = fmt.Fprintf(w, "**Git:** %s\n\n", strings.TrimSpace(git.Branch+" "+git.Commit))
+	}
+	if len(session.Metadata.FilesChanged) > 0 {
+		_, _ = fmt.Fprintf(w, "**Files changed:** %s\n\n", strings.Join(session.Metadata.FilesChanged, ", "))
+	}
 
 	_, _ = fmt.Fprintf(w, "---\n\n")
+
+	if e.TOC && len(session.Messages) > 0 {
+		_, _ = fmt.Fprintf(w, "## Contents\n\n")
+		for i, msg := range session.Messages {
+			_, _ = fmt.Fprintf(w, "- [%d. %s: %s](#message-%d)\n", i+1, msg.Actor, tocSnippet(msg.Content), i+1)
+		}
+		_, _ = fmt.Fprintf(w, "\n---\n\n")
+	}
+
 	_, _ = fmt.Fprintf(w, "## Messages\n\n")
 
 	// Messages
 	for i, msg := range session.Messages {
 		timestamp := ""
 		if msg.Timestamp != "" {
-			timestamp = fmt.Sprintf(" (%s)", msg.Timestamp)
+			timestamp = fmt.Sprintf(" (%s)", internal.FormatDisplayTimestamp(msg.Timestamp))
 		}
 
 		// Escape markdown in content if needed
-		content := escapeMarkdown(msg.Content)
+		content := collapseBlocks(escapeMarkdown(msg.Content))
 
-		_, _ = fmt.Fprintf(w, "**%s:**%s\n\n%s\n\n", msg.Actor, timestamp, content)
+		if e.TOC {
+			_, _ = fmt.Fprintf(w, "<a id=\"message-%d\"></a>\n\n", i+1)
+		}
+		_, _ = fmt.Fprintf(w, "**%s:**%s\n\n", msg.Actor, timestamp)
+		if msg.Thinking != "" {
+			_, _ = fmt.Fprintf(w, "<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n\n", escapeMarkdown(msg.Thinking))
+		}
+		if content != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", content)
+		}
+		writeAttachments(w, msg.Attachments)
+		for _, diff := range msg.Diffs {
+			writeDiff(w, diff)
+		}
+		writeFileEdits(w, msg.FileEdits)
 
 		// Add horizontal rule after each message (except the last one)
 		if i < len(session.Messages)-1 {
@@ -47,9 +132,55 @@ func (e *MarkdownExporter) Export(session *internal.Session, w io.Writer) error
 		}
 	}
 
+	if len(session.Diffs) > 0 {
+		_, _ = fmt.Fprintf(w, "---\n\n## Code Changes\n\n")
+		for _, diff := range session.Diffs {
+			writeDiff(w, diff)
+		}
+	}
+
 	return nil
 }
 
+// writeAttachments embeds the images attached to a message, from the files the export
+// wrote them to, and names those it could not write
+func writeAttachments(w io.Writer, attachments []internal.Attachment) {
+	for _, att := range attachments {
+		if att.File != "" {
+			_, _ = fmt.Fprintf(w, "![%s](<%s>)\n\n", att.Name, att.File)
+		} else {
+			_, _ = fmt.Fprintf(w, "*Image %s not exported*\n\n", att.Name)
+		}
+	}
+}
+
+// writeFileEdits lists the files a message edited, with what was done to each
+func writeFileEdits(w io.Writer, edits []internal.FileEdit) {
+	if len(edits) == 0 {
+		return
+	}
+	items := make([]string, 0, len(edits))
+	for _, edit := range edits {
+		items = append(items, fmt.Sprintf("`%s` (%s)", edit.FilePath, edit.Action))
+	}
+	_, _ = fmt.Fprintf(w, "*Files: %s*\n\n", strings.Join(items, ", "))
+}
+
+// writeDiff renders a code block diff as a fenced diff block headed by the edited file
+func writeDiff(w io.Writer, diff internal.CodeDiff) {
+	path := diff.FilePath
+	if path == "" {
+		path = "unknown file"
+	}
+	status := ""
+	if diff.Status != "" {
+		status = fmt.Sprintf(" (%s)", diff.Status)
+	}
+
+	_, _ = fmt.Fprintf(w, "**Edited `%s`**%s\n\n", path, status)
+	_, _ = fmt.Fprintf(w, "```diff\n--- a/%s\n+++ b/%s\n%s```\n\n", strings.TrimPrefix(path, "/"), strings.TrimPrefix(path, "/"), diff.Unified())
+}
+
 // escapeMarkdown escapes markdown special characters
 func escapeMarkdown(text string) string {
 	// Basic escaping - preserve code blocks
@@ -74,6 +205,86 @@ func escapeMarkdown(text string) string {
 	return strings.Join(result, "\n")
 }
 
+// collapseBlocks wraps thinking, tool call and reasoning sections in <details> blocks.
+// A [thinking]/[tool] marker section runs until the next blank line; a reasoning
+// section is a code fence whose first line starts with a reasoning or encryption label.
+func collapseBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	var result []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(line, "```") && i+1 < len(lines) && isReasoningLabel(lines[i+1]) {
+			end := closingFence(lines, i)
+			result = append(result, "<details>", "<summary>Reasoning</summary>", "")
+			result = append(result, lines[i:end+1]...)
+			result = append(result, "", "</details>")
+			i = end
+			continue
+		}
+
+		if strings.HasPrefix(line, "```") {
+			end := closingFence(lines, i)
+			result = append(result, lines[i:end+1]...)
+			i = end
+			continue
+		}
+
+		if m := collapsibleMarker.FindStringSubmatch(line); m != nil {
+			summary := "Tool call"
+			if m[1] == "thinking" {
+				summary = "Thinking"
+			}
+			end := i
+			for end+1 < len(lines) && strings.TrimSpace(lines[end+1]) != "" && !collapsibleMarker.MatchString(lines[end+1]) {
+				end++
+				if strings.HasPrefix(lines[end], "```") {
+					end = closingFence(lines, end)
+				}
+			}
+			result = append(result, "<details>", fmt.Sprintf("<summary>%s</summary>", summary), "")
+			result = append(result, lines[i+1:end+1]...)
+			result = append(result, "", "</details>")
+			i = end
+			continue
+		}
+
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// isReasoningLabel reports whether a line is the label the text extractor puts inside reasoning fences
+func isReasoningLabel(line string) bool {
+	return strings.HasPrefix(line, "[Redacted Reasoning") ||
+		strings.HasPrefix(line, "[Encrypted:") ||
+		strings.HasPrefix(line, "[Encoded:")
+}
+
+// closingFence returns the index of the fence closing the one opened at start,
+// or the last line if the fence is never closed
+func closingFence(lines []string, start int) int {
+	for j := start + 1; j < len(lines); j++ {
+		if strings.HasPrefix(lines[j], "```") {
+			return j
+		}
+	}
+	return len(lines) - 1
+}
+
+// tocSnippet returns the first line of a message, shortened for the table of contents
+func tocSnippet(content string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(content), "\n", 2)[0])
+	line = strings.NewReplacer("[", "", "]", "", "`", "").Replace(line)
+	runes := []rune(line)
+	if len(runes) > tocSnippetLength {
+		line = string(runes[:tocSnippetLength]) + "..."
+	}
+	return line
+}
+
 // Extension returns the file extension for this format
 func (e *MarkdownExporter) Extension() string {
 	return "md"
@@ -166,3 +166,219 @@ func TestEscapeMarkdown(t *testing.T) {
 		})
 	}
 }
+
+func TestMarkdownExporter_Options(t *testing.T) {
+	session := internal.CreateTestSession("test1")
+	session.Metadata.Name = "Fix: flaky tests"
+	session.Metadata.CreatedAt = "2023-01-01T00:00:00Z"
+	session.Metadata.Tags = []string{"bug-hunt"}
+	session.Metadata.Git = &internal.GitInfo{Branch: "fix/flaky", Commit: "9fceb02"}
+
+	tests := []struct {
+		name     string
+		exporter *MarkdownExporter
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "defaults",
+			exporter: &MarkdownExporter{},
+			want:     []string{"**Tags:** bug-hunt", "**Git:** fix/flaky 9fceb02"},
+			notWant:  []string{"message_count:", "## Contents", `<a id="message-1">`},
+		},
+		{
+			name:     "frontmatter",
+			exporter: &MarkdownExporter{Frontmatter: true},
+			want: []string{
+				"---\nid: test1\n",
+				"name: 'Fix: flaky tests'",
+				"workspace: test-workspace",
+				"created: \"2023-01-01T00:00:00Z\"",
+				"message_count: 2\ntags:\n    - bug-hunt\nbranch: fix/flaky\ncommit: 9fceb02\n---\n\n# Session test1",
+			},
+		},
+		{
+			name:     "table of contents",
+			exporter: &MarkdownExporter{TOC: true},
+			want: []string{
+				"## Contents",
+				"- [1. user: Hello, how are you?](#message-1)",
+				"- [2. assistant: ",
+				`<a id="message-2"></a>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.exporter.Export(session, &buf); err != nil {
+				t.Fatalf("MarkdownExporter.Export() error = %v", err)
+			}
+
+			output := buf.String()
+			for _, wantStr := range tt.want {
+				if !strings.Contains(output, wantStr) {
+					t.Errorf("Output should contain %q, got:\n%s", wantStr, output)
+				}
+			}
+			for _, notWantStr := range tt.notWant {
+				if strings.Contains(output, notWantStr) {
+					t.Errorf("Output should not contain %q, got:\n%s", notWantStr, output)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownExporter_Chunk(t *testing.T) {
+	session := internal.CreateTestSession("parent.chunk_2")
+	session.Metadata.Chunk = &internal.Chunk{SessionID: "parent", Index: 2, Count: 3, FirstMessage: 9}
+
+	var buf bytes.Buffer
+	if err := (&MarkdownExporter{Frontmatter: true}).Export(session, &buf); err != nil {
+		t.Fatalf("MarkdownExporter.Export() error = %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"chunk_of: parent\nchunk: 2\n", "**Part:** 2 of 3 of session parent, from message 9"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestMarkdownExporter_FileEdits(t *testing.T) {
+	session := internal.CreateTestSession("test1")
+	session.Metadata.FilesChanged = []string{"/repo/main.go", "/repo/new.go"}
+	session.Messages[1].FileEdits = []internal.FileEdit{
+		{FilePath: "/repo/main.go", Action: internal.FileEditApply},
+		{FilePath: "/repo/new.go", Action: internal.FileEditCreate},
+	}
+
+	var buf bytes.Buffer
+	if err := (&MarkdownExporter{Frontmatter: true}).Export(session, &buf); err != nil {
+		t.Fatalf("MarkdownExporter.Export() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"files_changed:\n    - /repo/main.go\n    - /repo/new.go\n",
+		"**Files changed:** /repo/main.go, /repo/new.go",
+		"*Files: `/repo/main.go` (apply), `/repo/new.go` (create)*",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestMarkdownExporter_Thinking(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("test", []internal.Message{
+		{Actor: "assistant", Content: "Done", Thinking: "Check the tests\n\nthen **fix** them"},
+		{Actor: "assistant", Thinking: "Only thinking"},
+	})
+
+	var buf bytes.Buffer
+	if err := (&MarkdownExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("MarkdownExporter.Export() error = %v", err)
+	}
+
+	output := buf.String()
+	want := "<details>\n<summary>Thinking</summary>\n\nCheck the tests\n\nthen \\*\\*fix\\*\\* them\n\n</details>\n\nDone\n\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Output should contain %q, got:\n%s", want, output)
+	}
+	if !strings.Contains(output, "Only thinking\n\n</details>\n\n") {
+		t.Errorf("Message with only thinking should be collapsed, got:\n%s", output)
+	}
+}
+
+func TestMarkdownExporter_Diffs(t *testing.T) {
+	session := internal.CreateTestSession("test1")
+	session.Messages[1].Diffs = []internal.CodeDiff{{
+		FilePath: "/repo/main.go",
+		Status:   "accepted",
+		Hunks:    []internal.DiffHunk{{OriginalStart: 1, OriginalEnd: 2, Before: []string{"old"}, After: []string{"new"}}},
+	}}
+	session.Diffs = []internal.CodeDiff{{
+		Hunks: []internal.DiffHunk{{OriginalStart: 1, OriginalEnd: 1, After: []string{"orphan"}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := (&MarkdownExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("MarkdownExporter.Export() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"**Edited `/repo/main.go`** (accepted)\n\n```diff\n--- a/repo/main.go\n+++ b/repo/main.go\n@@ -1,1 +1,1 @@\n-old\n+new\n```",
+		"## Code Changes\n\n**Edited `unknown file`**",
+		"+orphan\n```",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestMarkdownExporter_Attachments(t *testing.T) {
+	session := internal.CreateTestSession("test1")
+	session.Messages[0].Attachments = []internal.Attachment{
+		{Name: "image_1_1.png", File: "assets/test1/image_1_1.png"},
+		{Name: "image_1_2.jpg"},
+	}
+
+	var buf bytes.Buffer
+	if err := (&MarkdownExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("MarkdownExporter.Export() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"![image_1_1.png](<assets/test1/image_1_1.png>)", "*Image image_1_2.jpg not exported*"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestCollapseBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text unchanged",
+			input: "Hello\n\nworld",
+			want:  "Hello\n\nworld",
+		},
+		{
+			name:  "thinking section",
+			input: "Answer\n[thinking]\nLet me check\nthe code\n\nDone",
+			want:  "Answer\n<details>\n<summary>Thinking</summary>\n\nLet me check\nthe code\n\n</details>\n\nDone",
+		},
+		{
+			name:  "tool call with code fence",
+			input: "[tool_call]\nrun\n```sh\n\nls\n```",
+			want:  "<details>\n<summary>Tool call</summary>\n\nrun\n```sh\n\nls\n```\n\n</details>",
+		},
+		{
+			name:  "redacted reasoning fence",
+			input: "```\n[Redacted Reasoning]\nabc\n```\nAfter",
+			want:  "<details>\n<summary>Reasoning</summary>\n\n```\n[Redacted Reasoning]\nabc\n```\n\n</details>\nAfter",
+		},
+		{
+			name:  "marker inside code fence ignored",
+			input: "```\n[thinking]\n```",
+			want:  "```\n[thinking]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collapseBlocks(tt.input); got != tt.want {
+				t.Errorf("collapseBlocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
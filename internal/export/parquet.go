@@ -0,0 +1,90 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/parquet-go/parquet-go"
+)
+
+// ParquetExporter exports sessions as Parquet files with one row per message, for loading
+// transcripts into analytics tools such as DuckDB or Spark. Files hold a single row group
+// and are written uncompressed.
+type ParquetExporter struct{}
+
+// toolCallMarker and thinkingMarker match the markers the rich text parser writes before tool calls and thinking
+var (
+	toolCallMarker = regexp.MustCompile(`(?m)^\[(tool|tool_call|function_call)\]$`)
+	thinkingMarker = regexp.MustCompile(`(?m)^\[thinking\]$`)
+)
+
+// parquetMessage is one row of the message table. Optional columns are null when empty.
+type parquetMessage struct {
+	SessionID       string `parquet:"session_id"`
+	Name            string `parquet:"name,optional"`
+	Workspace       string `parquet:"workspace,optional"`
+	MessageIndex    int32  `parquet:"message_index"`
+	Actor           string `parquet:"actor"`
+	Timestamp       int64  `parquet:"timestamp,optional,timestamp(millisecond)"` // Unix milliseconds
+	Text            string `parquet:"text"`
+	TokenCount      int32  `parquet:"token_count"`
+	HasToolCall     bool   `parquet:"has_tool_call"`
+	HasThinking     bool   `parquet:"has_thinking"`
+	Tags            string `parquet:"tags,optional"` // comma-separated
+	GitBranch       string `parquet:"git_branch,optional"`
+	GitCommit       string `parquet:"git_commit,optional"`
+	ParentSessionID string `parquet:"parent_session_id,optional"` // set on chunks of a split session
+}
+
+// Export exports a session to Parquet format
+func (e *ParquetExporter) Export(session *internal.Session, w io.Writer) error {
+	tags := strings.Join(session.Metadata.Tags, ",")
+	var branch, commit string
+	if git := session.Metadata.Git; git != nil {
+		branch, commit = git.Branch, git.Commit
+	}
+	var parentID string
+	if chunk := session.Metadata.Chunk; chunk != nil {
+		parentID = chunk.SessionID
+	}
+
+	rows := make([]parquetMessage, len(session.Messages))
+	for i, msg := range session.Messages {
+		rows[i] = parquetMessage{
+			SessionID:       session.ID,
+			Name:            session.Metadata.Name,
+			Workspace:       session.Workspace,
+			MessageIndex:    int32(i),
+			Actor:           msg.Actor,
+			Text:            msg.Content,
+			TokenCount:      int32(internal.EstimateTokens(msg.Content)),
+			HasToolCall:     toolCallMarker.MatchString(msg.Content),
+			HasThinking:     msg.Thinking != "" || thinkingMarker.MatchString(msg.Content),
+			Tags:            tags,
+			GitBranch:       branch,
+			GitCommit:       commit,
+			ParentSessionID: parentID,
+		}
+		if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+			rows[i].Timestamp = t.UnixMilli()
+		}
+	}
+
+	writer := parquet.NewGenericWriter[parquetMessage](w, parquet.CreatedBy("cursor-session", "", ""))
+	if _, err := writer.Write(rows); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return nil
+}
+
+// Extension returns the file extension for this format
+func (e *ParquetExporter) Extension() string {
+	return "parquet"
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetExporter_Export(t *testing.T) {
+	session := &internal.Session{
+		ID:       "session1",
+		Metadata: internal.Metadata{Name: "Refactor", Tags: []string{"bug-hunt", "prod"}, Git: &internal.GitInfo{Branch: "main"}},
+		Messages: []internal.Message{
+			{Actor: "user", Content: "Rename the package", Timestamp: "2024-01-02T03:04:05Z"},
+			{Actor: "assistant", Content: "[tool_call]\nrename()"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&ParquetExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("ParquetExporter.Export() error = %v", err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("exported file does not open as Parquet: %v", err)
+	}
+	if file.NumRows() != 2 {
+		t.Errorf("num_rows = %d, want 2", file.NumRows())
+	}
+
+	wantColumns := []struct {
+		name     string
+		kind     parquet.Kind
+		optional bool
+		logical  string
+	}{
+		{"session_id", parquet.ByteArray, false, "STRING"},
+		{"name", parquet.ByteArray, true, "STRING"},
+		{"workspace", parquet.ByteArray, true, "STRING"},
+		{"message_index", parquet.Int32, false, "INT(32,true)"},
+		{"actor", parquet.ByteArray, false, "STRING"},
+		{"timestamp", parquet.Int64, true, "TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)"},
+		{"text", parquet.ByteArray, false, "STRING"},
+		{"token_count", parquet.Int32, false, "INT(32,true)"},
+		{"has_tool_call", parquet.Boolean, false, ""},
+		{"has_thinking", parquet.Boolean, false, ""},
+		{"tags", parquet.ByteArray, true, "STRING"},
+		{"git_branch", parquet.ByteArray, true, "STRING"},
+		{"git_commit", parquet.ByteArray, true, "STRING"},
+		{"parent_session_id", parquet.ByteArray, true, "STRING"},
+	}
+	fields := file.Schema().Fields()
+	if len(fields) != len(wantColumns) {
+		t.Fatalf("schema has %d columns, want %d", len(fields), len(wantColumns))
+	}
+	for i, want := range wantColumns {
+		field := fields[i]
+		var logical string
+		if lt := field.Type().LogicalType(); lt != nil {
+			logical = lt.String()
+		}
+		if field.Name() != want.name || field.Type().Kind() != want.kind || field.Optional() != want.optional || logical != want.logical {
+			t.Errorf("column %d = %s %v optional=%v %s, want %s %v optional=%v %s", i, field.Name(), field.Type().Kind(), field.Optional(), logical,
+				want.name, want.kind, want.optional, want.logical)
+		}
+	}
+
+	rows, err := parquet.Read[parquetMessage](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read the rows back: %v", err)
+	}
+	want := []parquetMessage{
+		{
+			SessionID: "session1", Name: "Refactor", MessageIndex: 0, Actor: "user",
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli(), Text: "Rename the package", TokenCount: 5,
+			Tags: "bug-hunt,prod", GitBranch: "main",
+		},
+		{
+			SessionID: "session1", Name: "Refactor", MessageIndex: 1, Actor: "assistant",
+			Text: "[tool_call]\nrename()", TokenCount: 5, HasToolCall: true,
+			Tags: "bug-hunt,prod", GitBranch: "main",
+		},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	// Empty optional values and unparseable timestamps are written as nulls
+	nulls := map[string]int64{"workspace": 2, "git_commit": 2, "parent_session_id": 2, "timestamp": 1, "name": 0}
+	for _, chunk := range file.RowGroups()[0].ColumnChunks() {
+		name := fields[chunk.Column()].Name()
+		want, ok := nulls[name]
+		if !ok {
+			continue
+		}
+		index, err := chunk.ColumnIndex()
+		if err != nil {
+			t.Fatalf("column %s has no index: %v", name, err)
+		}
+		var got int64
+		for i := 0; i < index.NumPages(); i++ {
+			got += index.NullCount(i)
+		}
+		if got != want {
+			t.Errorf("column %s has %d null(s), want %d", name, got, want)
+		}
+	}
+}
+
+func TestParquetExporter_Extension(t *testing.T) {
+	exporter := &ParquetExporter{}
+	if got := exporter.Extension(); got != "parquet" {
+		t.Errorf("Extension() = %v, want parquet", got)
+	}
+}
@@ -0,0 +1,540 @@
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// siteSearchTextBytes bounds the text of each session put in the search index, so the
+// index of a large archive stays small enough for a browser to load
+const siteSearchTextBytes = 20000
+
+// siteSessionsDir is the directory of the site holding the session pages
+const siteSessionsDir = "sessions"
+
+// siteLunrScript is lunr.js 2.3.9 (MIT), which the index page searches with
+//
+//go:embed lunr.js
+var siteLunrScript []byte
+
+// SiteExporter renders a session as a standalone HTML page of a static site, linking
+// back to the site's index
+type SiteExporter struct{}
+
+// Export writes a session's page
+func (e *SiteExporter) Export(session *internal.Session, w io.Writer) error {
+	page := sitePage{Title: sessionTitle(session), Root: "../", Session: siteSessionFor(session, "")}
+	for i, msg := range session.Messages {
+		page.Messages = append(page.Messages, siteMessage{
+			Number:    i + 1,
+			Actor:     msg.Actor,
+			Timestamp: internal.FormatDisplayTimestamp(internal.UTCTimestamp(msg.Timestamp)),
+			Thinking:  msg.Thinking,
+			Blocks:    siteBlocks(msg.Content),
+			Images:    siteImages(msg),
+			Files:     siteFileEdits(msg),
+		})
+	}
+	return sessionPageTemplate.Execute(w, page)
+}
+
+// Extension returns the file extension for this format
+func (e *SiteExporter) Extension() string {
+	return "html"
+}
+
+// WriteSite writes a browsable static website of sessions to dest: an index.html
+// listing the sessions by workspace with a search box, a page per session under
+// sessions/, and the stylesheet, script and search index they load. The site needs no
+// server or network access, so it can be opened from disk or published as is. It returns
+// the number of session pages written.
+func WriteSite(dest Destination, sessions []*internal.Session, title string) (int, error) {
+	names, err := ParseFilenameTemplate(DefaultFilenameTemplate)
+	if err != nil {
+		return 0, err
+	}
+	namer := NewFileNamer(names, "", false, nil)
+	namer.Group = func(*internal.Session) string { return siteSessionsDir }
+
+	exporter := &SiteExporter{}
+	var entries []siteSession
+	var search []siteSearchEntry
+	for _, session := range sessions {
+		if session == nil {
+			continue
+		}
+		name := namer.Name(exporter, session)
+		session, attachments := internal.DetachAttachments(session)
+		if err := dest.WriteSession(exporter, session, name); err != nil {
+			return len(entries), fmt.Errorf("failed to write the page of session %s: %w", session.ID, err)
+		}
+		// Images go next to the pages that show them
+		for _, att := range attachments {
+			data, err := att.Content()
+			if err == nil {
+				err = dest.WriteFile(path.Join(siteSessionsDir, att.File), data)
+			}
+			if err != nil {
+				internal.LogWarn("Failed to write image %s of session %s: %v", att.Name, session.ID, err)
+			}
+		}
+		entry := siteSessionFor(session, name)
+		entries = append(entries, entry)
+		search = append(search, siteSearchEntry{
+			Title:     entry.Title,
+			Workspace: session.Workspace,
+			URL:       name,
+			Text:      siteSearchText(session),
+		})
+	}
+
+	index := siteIndex{Title: title, Workspaces: siteWorkspaces(entries), Count: len(entries)}
+	var buf bytes.Buffer
+	if err := siteIndexTemplate.Execute(&buf, index); err != nil {
+		return len(entries), fmt.Errorf("failed to render the site index: %w", err)
+	}
+
+	searchIndex, err := json.Marshal(search)
+	if err != nil {
+		return len(entries), fmt.Errorf("failed to encode the search index: %w", err)
+	}
+	// A script rather than JSON, since browsers refuse to fetch files from a page opened
+	// from disk
+	searchScript := append([]byte("window.SEARCH_INDEX = "), searchIndex...)
+	searchScript = append(searchScript, ";\n"...)
+
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"index.html", buf.Bytes()},
+		{"style.css", []byte(siteStylesheet)},
+		{"lunr.js", siteLunrScript},
+		{"search.js", []byte(siteSearchScript)},
+		{"search-index.js", searchScript},
+	} {
+		if err := dest.WriteFile(file.name, file.data); err != nil {
+			return len(entries), err
+		}
+	}
+	return len(entries), nil
+}
+
+// siteSession is a session as the index lists it
+type siteSession struct {
+	ID        string
+	Title     string
+	Workspace string
+	Created   string
+	Updated   string
+	Messages  int
+	Tags      []string
+	Branch    string
+	URL       string
+}
+
+// siteSessionFor describes a session written to the page at url
+func siteSessionFor(session *internal.Session, url string) siteSession {
+	return siteSession{
+		ID:        session.ID,
+		Title:     sessionTitle(session),
+		Workspace: session.Workspace,
+		Created:   internal.FormatDisplayTimestamp(internal.UTCTimestamp(session.Metadata.CreatedAt)),
+		Updated:   internal.FormatDisplayTimestamp(internal.UTCTimestamp(session.Metadata.UpdatedAt)),
+		Messages:  len(session.Messages),
+		Tags:      session.Metadata.Tags,
+		Branch:    session.Metadata.Git.GetBranch(),
+		URL:       url,
+	}
+}
+
+// sessionTitle is the name of a session, or its ID when it has none
+func sessionTitle(session *internal.Session) string {
+	if session.Metadata.Name != "" {
+		return session.Metadata.Name
+	}
+	return "Session " + session.ID
+}
+
+// siteWorkspace is a workspace of the index with its sessions, newest first
+type siteWorkspace struct {
+	Name     string
+	Anchor   string
+	Sessions []siteSession
+}
+
+// siteWorkspaces groups sessions by workspace, sorted by name with sessions without a
+// workspace last
+func siteWorkspaces(sessions []siteSession) []siteWorkspace {
+	byName := make(map[string]*siteWorkspace)
+	var workspaces []*siteWorkspace
+	for _, session := range sessions {
+		ws, ok := byName[session.Workspace]
+		if !ok {
+			ws = &siteWorkspace{Name: session.Workspace, Anchor: "workspace-" + WorkspaceDir(session.Workspace)}
+			byName[session.Workspace] = ws
+			workspaces = append(workspaces, ws)
+		}
+		ws.Sessions = append(ws.Sessions, session)
+	}
+	sort.SliceStable(workspaces, func(i, j int) bool {
+		a, b := workspaces[i].Name, workspaces[j].Name
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return a < b
+	})
+
+	result := make([]siteWorkspace, 0, len(workspaces))
+	for _, ws := range workspaces {
+		// Sessions come oldest first; the index shows the latest first
+		for i, j := 0, len(ws.Sessions)-1; i < j; i, j = i+1, j-1 {
+			ws.Sessions[i], ws.Sessions[j] = ws.Sessions[j], ws.Sessions[i]
+		}
+		result = append(result, *ws)
+	}
+	return result
+}
+
+// siteSearchEntry is a session of the search index
+type siteSearchEntry struct {
+	Title     string `json:"t"`
+	Workspace string `json:"w,omitempty"`
+	URL       string `json:"u"`
+	Text      string `json:"x"`
+}
+
+// siteSearchText is the text of a session searched by the site, bounded to
+// siteSearchTextBytes
+func siteSearchText(session *internal.Session) string {
+	var b strings.Builder
+	for _, msg := range session.Messages {
+		if b.Len() >= siteSearchTextBytes {
+			break
+		}
+		b.WriteString(strings.Join(strings.Fields(msg.Content), " "))
+		b.WriteByte('\n')
+	}
+	return truncateUTF8(b.String(), siteSearchTextBytes)
+}
+
+// sitePage is the data of a session page
+type sitePage struct {
+	Title    string
+	Root     string
+	Session  siteSession
+	Messages []siteMessage
+}
+
+// siteMessage is a message of a session page
+type siteMessage struct {
+	Number    int
+	Actor     string
+	Timestamp string
+	Thinking  string
+	Blocks    []siteBlock
+	Images    []siteImage
+	Files     []string
+}
+
+// siteImage is an image attached to a message, by the URL of its file relative to the page
+type siteImage struct {
+	Name string
+	URL  string
+}
+
+// siteImages returns the images of a message that were written to files
+func siteImages(msg internal.Message) []siteImage {
+	var images []siteImage
+	for _, att := range msg.Attachments {
+		if att.File != "" {
+			images = append(images, siteImage{Name: att.Name, URL: att.File})
+		}
+	}
+	return images
+}
+
+// siteBlock is a run of prose or a fenced code block of a message
+type siteBlock struct {
+	Code     bool
+	Language string
+	Text     string
+}
+
+// siteBlocks splits message content into prose and the code fences it holds
+func siteBlocks(content string) []siteBlock {
+	var blocks []siteBlock
+	var current []string
+	inCode := false
+	language := ""
+	flush := func(code bool) {
+		text := strings.Join(current, "\n")
+		if code || strings.TrimSpace(text) != "" {
+			blocks = append(blocks, siteBlock{Code: code, Language: language, Text: strings.Trim(text, "\n")})
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "```") {
+			flush(inCode)
+			inCode = !inCode
+			language = ""
+			if inCode {
+				language = strings.TrimSpace(strings.TrimPrefix(line, "```"))
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	flush(inCode)
+	return blocks
+}
+
+// siteFileEdits lists the files a message edited, with what was done to each
+func siteFileEdits(msg internal.Message) []string {
+	files := make([]string, 0, len(msg.FileEdits))
+	for _, edit := range msg.FileEdits {
+		files = append(files, fmt.Sprintf("%s (%s)", edit.FilePath, edit.Action))
+	}
+	return files
+}
+
+// siteIndex is the data of the index page
+type siteIndex struct {
+	Title      string
+	Count      int
+	Workspaces []siteWorkspace
+}
+
+// workspaceLabel names a workspace in the index
+func workspaceLabel(name string) string {
+	if name == "" {
+		return "No workspace"
+	}
+	return name
+}
+
+var siteFuncs = template.FuncMap{"workspaceLabel": workspaceLabel}
+
+var siteIndexTemplate = template.Must(template.New("index").Funcs(siteFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Count}} session(s) in {{len .Workspaces}} workspace(s)</p>
+<input id="search" type="search" placeholder="Search sessions" autocomplete="off">
+</header>
+<main>
+<ol id="results" hidden></ol>
+<div id="browse">
+<nav>
+<ul>
+{{- range .Workspaces}}
+<li><a href="#{{.Anchor}}">{{workspaceLabel .Name}}</a> ({{len .Sessions}})</li>
+{{- end}}
+</ul>
+</nav>
+{{- range .Workspaces}}
+<section id="{{.Anchor}}">
+<h2>{{workspaceLabel .Name}}</h2>
+<ul class="sessions">
+{{- range .Sessions}}
+<li><a href="{{.URL}}">{{.Title}}</a> <span class="meta">{{.Created}} · {{.Messages}} message(s){{range .Tags}} · <span class="tag">{{.}}</span>{{end}}</span></li>
+{{- end}}
+</ul>
+</section>
+{{- end}}
+</div>
+</main>
+<script src="lunr.js"></script>
+<script src="search-index.js"></script>
+<script src="search.js"></script>
+</body>
+</html>
+`))
+
+var sessionPageTemplate = template.Must(template.New("session").Funcs(siteFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<header>
+<p><a href="{{.Root}}index.html">← All sessions</a></p>
+<h1>{{.Title}}</h1>
+<dl class="meta">
+<dt>ID</dt><dd>{{.Session.ID}}</dd>
+<dt>Workspace</dt><dd>{{workspaceLabel .Session.Workspace}}</dd>
+{{- with .Session.Created}}
+<dt>Created</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Session.Updated}}
+<dt>Updated</dt><dd>{{.}}</dd>
+{{- end}}
+<dt>Messages</dt><dd>{{.Session.Messages}}</dd>
+{{- with .Session.Branch}}
+<dt>Git branch</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Session.Tags}}
+<dt>Tags</dt><dd>{{range .}}<span class="tag">{{.}}</span> {{end}}</dd>
+{{- end}}
+</dl>
+</header>
+<main>
+{{- range .Messages}}
+<article id="message-{{.Number}}" class="message {{.Actor}}">
+<h3><a href="#message-{{.Number}}">{{.Actor}}</a>{{with .Timestamp}} <span class="meta">{{.}}</span>{{end}}</h3>
+{{- with .Thinking}}
+<details><summary>Thinking</summary><div class="text">{{.}}</div></details>
+{{- end}}
+{{- range .Blocks}}
+{{- if .Code}}
+<pre{{with .Language}} data-language="{{.}}"{{end}}><code>{{.Text}}</code></pre>
+{{- else}}
+<div class="text">{{.Text}}</div>
+{{- end}}
+{{- end}}
+{{- range .Images}}
+<p><a href="{{.URL}}"><img src="{{.URL}}" alt="{{.Name}}"></a></p>
+{{- end}}
+{{- with .Files}}
+<p class="meta">Files: {{range $i, $f := .}}{{if $i}}, {{end}}<code>{{$f}}</code>{{end}}</p>
+{{- end}}
+</article>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+const siteStylesheet = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0 auto; max-width: 960px; padding: 1rem 2rem; color: #1f2328; line-height: 1.5; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.meta { color: #656d76; font-size: 0.875rem; }
+.tag { background: #ddf4ff; border-radius: 1em; padding: 0 0.5em; }
+#search { width: 100%; font-size: 1rem; padding: 0.5rem; box-sizing: border-box; }
+nav ul { columns: 2; padding-left: 1.2rem; }
+.sessions li, #results li { margin: 0.3rem 0; }
+#results .snippet { display: block; color: #656d76; font-size: 0.875rem; }
+dl.meta { display: grid; grid-template-columns: max-content auto; gap: 0 1rem; }
+dl.meta dd { margin: 0; }
+.message { border-top: 1px solid #d0d7de; padding: 0.5rem 0; }
+.message h3 { font-size: 1rem; margin: 0.5rem 0; text-transform: capitalize; }
+.message.user h3 a { color: #1a7f37; }
+.text { white-space: pre-wrap; overflow-wrap: anywhere; }
+.message img { max-width: 100%; border: 1px solid #d0d7de; border-radius: 6px; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; border-radius: 6px; }
+details { background: #f6f8fa; border-radius: 6px; padding: 0.5rem; margin: 0.5rem 0; }
+summary { cursor: pointer; color: #656d76; }
+`
+
+const siteSearchScript = `(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var browse = document.getElementById("browse");
+  var docs = window.SEARCH_INDEX || [];
+
+  // Words are matched by prefix as they are typed, so they are indexed unstemmed
+  var index = lunr(function () {
+    this.pipeline.remove(lunr.stemmer);
+    this.searchPipeline.remove(lunr.stemmer);
+    this.ref("i");
+    this.field("t", { boost: 10 });
+    this.field("w", { boost: 3 });
+    this.field("x");
+    docs.forEach(function (doc, i) {
+      this.add({ i: String(i), t: doc.t, w: doc.w || "", x: doc.x });
+    }, this);
+  });
+
+  function escapeRegExp(s) {
+    return s.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+  }
+
+  // snippet shows the text around the first word of the query. The word is found in the
+  // original text, ignoring case, so the position is right whatever lowercasing does to
+  // the length of the text.
+  function snippet(doc, word) {
+    var match = new RegExp(escapeRegExp(word), "i").exec(doc.x);
+    var start = match ? Math.max(0, match.index - 60) : 0;
+    var end = Math.min(doc.x.length, start + 160);
+    return (start > 0 ? "…" : "") + doc.x.slice(start, end) + (end < doc.x.length ? "…" : "");
+  }
+
+  function search(query) {
+    var words = lunr.tokenizer(query).map(String);
+    // Stop words are left out of the index, so they are only searched for on their own
+    var significant = words.filter(function (word) { return lunr.stopWordFilter(word) !== undefined; });
+    if (significant.length > 0) { words = significant; }
+    if (words.length === 0) { return []; }
+    var hits = index.query(function (q) {
+      words.forEach(function (word) {
+        q.term(word, { presence: lunr.Query.presence.REQUIRED, wildcard: lunr.Query.wildcard.TRAILING, usePipeline: false });
+      });
+    });
+    return hits.slice(0, 50).map(function (hit) {
+      var doc = docs[Number(hit.ref)];
+      return { doc: doc, snippet: snippet(doc, words[0]) };
+    });
+  }
+
+  function render(query) {
+    results.textContent = "";
+    if (!query.trim()) {
+      results.hidden = true;
+      browse.hidden = false;
+      return;
+    }
+    var hits = search(query);
+    hits.forEach(function (hit) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = hit.doc.u;
+      a.textContent = hit.doc.t;
+      li.appendChild(a);
+      if (hit.doc.w) {
+        var ws = document.createElement("span");
+        ws.className = "meta";
+        ws.textContent = " " + hit.doc.w;
+        li.appendChild(ws);
+      }
+      var snip = document.createElement("span");
+      snip.className = "snippet";
+      snip.textContent = hit.snippet;
+      li.appendChild(snip);
+      results.appendChild(li);
+    });
+    if (hits.length === 0) {
+      var none = document.createElement("li");
+      none.textContent = "No sessions match.";
+      results.appendChild(none);
+    }
+    results.hidden = false;
+    browse.hidden = true;
+  }
+
+  input.addEventListener("input", function () { render(input.value); });
+  if (location.hash.indexOf("#q=") === 0) {
+    input.value = decodeURIComponent(location.hash.slice(3));
+    render(input.value);
+  }
+})();
+`
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestWriteSite(t *testing.T) {
+	api := internal.CreateTestSessionWithMessages("api-session", []internal.Message{
+		{Actor: "user", Content: "Why is <script> escaped?"},
+		{Actor: "assistant", Thinking: "Check the template", Content: "Because html/template does it:\n```go\nfmt.Println(\"hi\")\n```\nDone."},
+	})
+	api.Workspace = "/code/api"
+	api.Metadata.Name = "Escaping"
+	loose := internal.CreateTestSessionWithMessages("loose-session", []internal.Message{
+		{Actor: "user", Content: "Hello"},
+	})
+	loose.Workspace = ""
+
+	dir := testutil.CreateTempDir(t)
+	dest, err := NewDirDestination(dir)
+	if err != nil {
+		t.Fatalf("NewDirDestination() error = %v", err)
+	}
+	count, err := WriteSite(dest, []*internal.Session{loose, api}, "My <archive>")
+	if err != nil || count != 2 {
+		t.Fatalf("WriteSite() = %d, %v, want 2 pages", count, err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	index := read("index.html")
+	for _, want := range []string{"My &lt;archive&gt;", `href="sessions/session_api-session.html"`, "Escaping", "/code/api", "No workspace", `src="lunr.js"`, `src="search.js"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html does not contain %q", want)
+		}
+	}
+	if strings.Index(index, "<h2>/code/api</h2>") > strings.Index(index, "<h2>No workspace</h2>") {
+		t.Error("index.html should list sessions without a workspace last")
+	}
+
+	page := read("sessions/session_api-session.html")
+	for _, want := range []string{
+		"Why is &lt;script&gt; escaped?",
+		`<pre data-language="go"><code>fmt.Println(&#34;hi&#34;)</code></pre>`,
+		"<summary>Thinking</summary>",
+		"Check the template",
+		`href="../index.html"`,
+		`href="../style.css"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("session page does not contain %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script>") {
+		t.Error("session page should escape message content")
+	}
+
+	search := read("search-index.js")
+	if !strings.HasPrefix(search, "window.SEARCH_INDEX = [") || !strings.Contains(search, "sessions/session_loose-session.html") {
+		t.Errorf("search-index.js = %s", search)
+	}
+	for _, name := range []string{"style.css", "lunr.js", "search.js"} {
+		if read(name) == "" {
+			t.Errorf("%s is empty", name)
+		}
+	}
+}
+
+func TestWriteSite_Attachments(t *testing.T) {
+	session := internal.CreateTestSession("shots")
+	session.Messages[0].Attachments = []internal.Attachment{{Name: "image_1_1.png", Data: []byte("png")}}
+
+	dir := testutil.CreateTempDir(t)
+	dest, err := NewDirDestination(dir)
+	if err != nil {
+		t.Fatalf("NewDirDestination() error = %v", err)
+	}
+	if _, err := WriteSite(dest, []*internal.Session{session}, ""); err != nil {
+		t.Fatalf("WriteSite() error = %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "sessions", "assets", "shots", "image_1_1.png")); err != nil || string(data) != "png" {
+		t.Errorf("image file = %q, %v, want it next to the pages", data, err)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "sessions", "session_shots.html"))
+	if err != nil {
+		t.Fatalf("Failed to read the session page: %v", err)
+	}
+	if !strings.Contains(string(page), `src="assets/shots/image_1_1.png"`) {
+		t.Errorf("session page does not show the image:\n%s", page)
+	}
+}
+
+func TestSiteBlocks(t *testing.T) {
+	blocks := siteBlocks("Intro\n```sh\nls -la\n```\n\nOutro")
+	if len(blocks) != 3 {
+		t.Fatalf("siteBlocks() = %+v, want prose, code and prose", blocks)
+	}
+	if blocks[0].Code || blocks[0].Text != "Intro" {
+		t.Errorf("blocks[0] = %+v", blocks[0])
+	}
+	if !blocks[1].Code || blocks[1].Language != "sh" || blocks[1].Text != "ls -la" {
+		t.Errorf("blocks[1] = %+v", blocks[1])
+	}
+	if blocks[2].Code || blocks[2].Text != "Outro" {
+		t.Errorf("blocks[2] = %+v", blocks[2])
+	}
+
+	// An unclosed fence keeps the rest of the message as code
+	blocks = siteBlocks("```\nunterminated")
+	if len(blocks) != 1 || !blocks[0].Code || blocks[0].Text != "unterminated" {
+		t.Errorf("siteBlocks() of an unclosed fence = %+v", blocks)
+	}
+}
+
+func TestSiteSearchText(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("long", []internal.Message{
+		{Actor: "user", Content: strings.Repeat("word ", siteSearchTextBytes)},
+		{Actor: "assistant", Content: "never indexed"},
+	})
+	text := siteSearchText(session)
+	if len(text) > siteSearchTextBytes || strings.Contains(text, "never indexed") {
+		t.Errorf("siteSearchText() is %d bytes, want at most %d", len(text), siteSearchTextBytes)
+	}
+}
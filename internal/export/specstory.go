@@ -0,0 +1,150 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// SpecStoryFilenameTemplate names files the way SpecStory names its history files, such as
+// 2024-06-01_09-30Z-fix-the-parser
+const SpecStoryFilenameTemplate = "{date}_{time}Z-{name}"
+
+// specStoryTool matches the first line of a tool call or tool response section rendered
+// into message text, with the name of the tool on the line after it
+var specStoryTool = regexp.MustCompile(`^\[Tool (Call|Response)\]$`)
+
+// SpecStoryExporter exports sessions in the Markdown layout of SpecStory's history files,
+// so tooling written for SpecStory reads them unchanged: a title heading with the
+// session's creation time, then one _**User**_ or _**Assistant**_ turn per exchange,
+// separated by rules. Tool, terminal and assistant messages between two user prompts make
+// up one assistant turn, with thinking in <think> blocks and each tool call or response
+// collapsed into a <details> block. System messages, which SpecStory does not record, are
+// left out.
+type SpecStoryExporter struct{}
+
+// Export exports a session to the SpecStory Markdown layout
+func (e *SpecStoryExporter) Export(session *internal.Session, w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "<!-- Generated by SpecStory -->\n\n")
+	created := specStoryTime(session.Metadata.CreatedAt)
+	_, _ = fmt.Fprintf(w, "<!-- cursor Session %s%s -->\n\n", session.ID, specStorySuffix(created))
+
+	title := session.Metadata.Name
+	if title == "" {
+		title = "Session " + session.ID
+	}
+	_, _ = fmt.Fprintf(w, "# %s%s\n\n", title, specStorySuffix(created))
+
+	// The speaker of the open turn, so consecutive messages of one side share a heading
+	speaker := ""
+	for _, msg := range session.Messages {
+		if msg.Actor == internal.ActorSystem {
+			continue
+		}
+		next := "Assistant"
+		if msg.Actor == internal.ActorUser {
+			next = "User"
+		}
+		if next != speaker {
+			if speaker != "" {
+				_, _ = fmt.Fprintf(w, "---\n\n")
+			}
+			_, _ = fmt.Fprintf(w, "_**%s%s**_\n\n", next, specStorySuffix(specStoryTime(msg.Timestamp)))
+			speaker = next
+		}
+
+		if msg.Thinking != "" {
+			_, _ = fmt.Fprintf(w, "<think><details><summary>Thought Process</summary>\n%s</details></think>\n\n", msg.Thinking)
+		}
+		content := msg.Content
+		if (msg.Actor == internal.ActorTool || msg.Actor == internal.ActorTerminal) && !strings.HasPrefix(content, "[Tool ") {
+			content = "[Tool Response]\nTool: " + msg.Actor + "\n" + content
+		}
+		if body := specStoryBody(content); body != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", body)
+		}
+		writeAttachments(w, msg.Attachments)
+		for _, diff := range msg.Diffs {
+			writeDiff(w, diff)
+		}
+	}
+	if speaker != "" {
+		_, _ = fmt.Fprintf(w, "---\n\n")
+	}
+	return nil
+}
+
+// specStoryBody returns message text with each tool call or response section, which runs
+// until the next blank line outside a code fence, collapsed into a <details> block named
+// after its tool
+func specStoryBody(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		m := specStoryTool.FindStringSubmatch(lines[i])
+		if m == nil {
+			if strings.HasPrefix(lines[i], "```") {
+				end := closingFence(lines, i)
+				result = append(result, lines[i:end+1]...)
+				i = end
+				continue
+			}
+			result = append(result, lines[i])
+			continue
+		}
+
+		end := i
+		for end+1 < len(lines) && strings.TrimSpace(lines[end+1]) != "" {
+			end++
+			if strings.HasPrefix(lines[end], "```") {
+				end = closingFence(lines, end)
+			}
+		}
+		section := lines[i+1 : end+1]
+		tool := "tool"
+		if len(section) > 0 && strings.HasPrefix(section[0], "Tool: ") {
+			tool = strings.TrimPrefix(section[0], "Tool: ")
+			section = section[1:]
+		}
+		summary := "Tool use: **" + tool + "**"
+		if m[1] == "Response" {
+			summary = "Tool output: **" + tool + "**"
+		}
+		result = append(result, "<details>", "<summary>"+summary+"</summary>", "")
+		if len(section) > 0 {
+			result = append(result, "```")
+			result = append(result, section...)
+			result = append(result, "```", "")
+		}
+		result = append(result, "</details>")
+		i = end
+	}
+	return strings.Join(result, "\n")
+}
+
+// specStoryTime formats an RFC3339 timestamp the way SpecStory writes times, in UTC to the
+// minute, or returns "" when it is missing or invalid
+func specStoryTime(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04Z")
+}
+
+// specStorySuffix returns the parenthesized time appended to headings, or "" for no time
+func specStorySuffix(formatted string) string {
+	if formatted == "" {
+		return ""
+	}
+	return " (" + formatted + ")"
+}
+
+// Extension returns the file extension for this format
+func (e *SpecStoryExporter) Extension() string {
+	return "md"
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestSpecStoryExporter_Export(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("run1", []internal.Message{
+		{Actor: "system", Content: "You are a coding agent"},
+		{Actor: "user", Content: "Fix the failing test", Timestamp: "2024-06-01T11:30:00+02:00"},
+		{Actor: "assistant", Content: "Let me look.\n\n[Tool Call]\nTool: read_file\nArguments: {\"path\":\"a.go\"}", Thinking: "Read it first", Timestamp: "2024-06-01T09:31:00Z"},
+		{Actor: "terminal", Content: "FAIL a_test.go"},
+		{Actor: "assistant", Content: "Fixed."},
+		{Actor: "user", Content: "Thanks"},
+	})
+	session.Metadata.Name = "Fix the test"
+	session.Metadata.CreatedAt = "2024-06-01T09:30:00Z"
+
+	var buf bytes.Buffer
+	if err := (&SpecStoryExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := "<!-- Generated by SpecStory -->\n\n" +
+		"<!-- cursor Session run1 (2024-06-01 09:30Z) -->\n\n" +
+		"# Fix the test (2024-06-01 09:30Z)\n\n" +
+		"_**User (2024-06-01 09:30Z)**_\n\n" +
+		"Fix the failing test\n\n" +
+		"---\n\n" +
+		"_**Assistant (2024-06-01 09:31Z)**_\n\n" +
+		"<think><details><summary>Thought Process</summary>\nRead it first</details></think>\n\n" +
+		"Let me look.\n\n<details>\n<summary>Tool use: **read_file**</summary>\n\n```\nArguments: {\"path\":\"a.go\"}\n```\n\n</details>\n\n" +
+		"<details>\n<summary>Tool output: **terminal**</summary>\n\n```\nFAIL a_test.go\n```\n\n</details>\n\n" +
+		"Fixed.\n\n" +
+		"---\n\n" +
+		"_**User**_\n\n" +
+		"Thanks\n\n" +
+		"---\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Export() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSpecStoryExporter_Untitled(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("bare", []internal.Message{})
+	session.Metadata.Name = ""
+	session.Metadata.CreatedAt = ""
+
+	var buf bytes.Buffer
+	if err := (&SpecStoryExporter{}).Export(session, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "# Session bare\n") || strings.Contains(buf.String(), "---") {
+		t.Errorf("Export() of an untitled empty session =\n%s", buf.String())
+	}
+}
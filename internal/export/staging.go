@@ -0,0 +1,336 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// ManifestFile is the manifest a staged export writes last, listing its files
+const ManifestFile = "manifest.json"
+
+// Manifest describes a complete export, so downstream jobs can tell it from a partial one
+// and check its files
+type Manifest struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Format    string          `json:"format"`
+	Counts    ManifestCounts  `json:"counts"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// ManifestCounts counts what an export holds
+type ManifestCounts struct {
+	SessionsExported int `json:"sessions_exported"` // Including the sessions --resume left in place
+	SessionsSkipped  int `json:"sessions_skipped,omitempty"`
+	SessionsFailed   int `json:"sessions_failed,omitempty"`
+	MessagesExported int `json:"messages_exported"`
+	Files            int `json:"files"`
+}
+
+// ManifestEntry is a file of an export, by its path relative to the export's root
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// newManifestEntry returns the manifest entry of a file holding data
+func newManifestEntry(name string, data []byte) ManifestEntry {
+	sum := sha256.Sum256(data)
+	return ManifestEntry{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// StagedExport is a Destination that keeps an export apart until it is complete. A
+// directory export is written into a staging directory next to the output directory,
+// starting from hard links to the files already there, and an archive into a temporary file
+// next to it. Commit adds the manifest and renames the export into place; Close without
+// Commit removes it, leaving what was there before untouched.
+type StagedExport struct {
+	Destination
+	target    string
+	staging   string
+	archive   *archiveDestination // The archive being written, nil for a directory
+	committed bool
+	// inTarget is set when the working directory is the output directory, which the
+	// commit replaces
+	inTarget bool
+}
+
+// StageDir returns a StagedExport of the directory dir
+func StageDir(dir string) (*StagedExport, error) {
+	// The parent of a relative dir such as "." is only found from its absolute path
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	parent := filepath.Dir(dir)
+	if rel, err := filepath.Rel(dir, parent); err != nil || !strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("cannot stage an export of %s: there is no directory outside it to stage it in", dir)
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// The staging directory is a sibling of dir, so that it can be renamed into its place
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".staging-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if err := os.Chmod(staging, 0755); err != nil {
+		internal.LogWarn("Failed to set permissions on %s: %v", staging, err)
+	}
+
+	// The export adds to the files already in dir, as it does without staging
+	if info, err := os.Stat(dir); err == nil {
+		if !info.IsDir() {
+			_ = os.RemoveAll(staging)
+			return nil, fmt.Errorf("output %s is not a directory", dir)
+		}
+		if err := linkTree(dir, staging); err != nil {
+			_ = os.RemoveAll(staging)
+			return nil, fmt.Errorf("failed to stage %s: %w", dir, err)
+		}
+	} else if !os.IsNotExist(err) {
+		_ = os.RemoveAll(staging)
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+
+	staged := &StagedExport{Destination: &dirDestination{dir: staging}, target: dir, staging: staging}
+	if wd, err := os.Getwd(); err == nil && wd == dir {
+		staged.inTarget = true
+	}
+	return staged, nil
+}
+
+// StageArchive returns a StagedExport of the archive at path, in the format CreateArchive
+// writes
+func StageArchive(path string) (*StagedExport, error) {
+	if !IsArchivePath(path) {
+		return nil, fmt.Errorf("unsupported archive %s (expected .tar.gz, .tgz or .zip)", path)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".staging-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive %s: %w", path, err)
+	}
+	a := newArchiveDestination(file, path)
+	a.listFiles = true
+	return &StagedExport{Destination: a, target: path, staging: file.Name(), archive: a}, nil
+}
+
+// Dir returns the directory a directory export is staged in, where the files already in
+// the output directory can be read while it is written. It is empty for an archive.
+func (s *StagedExport) Dir() string {
+	if s.archive != nil {
+		return ""
+	}
+	return s.staging
+}
+
+func (s *StagedExport) WriteFile(name string, data []byte) error {
+	if s.archive == nil {
+		// A file carried over from the output directory is a hard link to it, so it is
+		// replaced rather than written through
+		if err := os.Remove(filepath.Join(s.staging, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to replace %s: %w", name, err)
+		}
+	}
+	return s.Destination.WriteFile(name, data)
+}
+
+// Commit lists every file of the export in manifest, writes it to manifest.json and moves
+// the export into place. The output directory is replaced in two renames, so it is missing
+// for a moment but never partial.
+func (s *StagedExport) Commit(manifest Manifest) error {
+	if s.committed {
+		return fmt.Errorf("export to %s already committed", s.target)
+	}
+
+	if s.archive != nil {
+		manifest.Files = s.archive.files
+	} else {
+		files, err := listFiles(s.staging)
+		if err != nil {
+			return fmt.Errorf("failed to list the exported files: %w", err)
+		}
+		manifest.Files = files
+	}
+	if manifest.Files == nil {
+		manifest.Files = []ManifestEntry{}
+	}
+	manifest.Counts.Files = len(manifest.Files)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := s.WriteFile(ManifestFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if s.archive != nil {
+		if err := s.archive.Close(); err != nil {
+			return err
+		}
+		s.archive = nil
+		if err := os.Chmod(s.staging, 0644); err != nil {
+			internal.LogWarn("Failed to set permissions on %s: %v", s.target, err)
+		}
+		if err := os.Rename(s.staging, s.target); err != nil {
+			_ = os.Remove(s.staging)
+			return fmt.Errorf("failed to move the archive into place: %w", err)
+		}
+		s.committed = true
+		return nil
+	}
+
+	// Past this point the complete export is kept, even if it can't be moved into place
+	s.committed = true
+	if err := replaceDir(s.staging, s.target); err != nil {
+		return fmt.Errorf("failed to move the export into %s: %w (it is complete in %s)", s.target, err, s.staging)
+	}
+	// The working directory was moved aside and removed with the previous export; follow
+	// the output directory so relative paths still resolve
+	if s.inTarget {
+		if err := os.Chdir(s.target); err != nil {
+			internal.LogWarn("Failed to change into %s: %v", s.target, err)
+		}
+	}
+	return nil
+}
+
+// Close removes the export unless it was committed
+func (s *StagedExport) Close() error {
+	if s.committed {
+		return nil
+	}
+	if s.archive != nil {
+		_ = s.archive.Close()
+		s.archive = nil
+		return os.Remove(s.staging)
+	}
+	return os.RemoveAll(s.staging)
+}
+
+// replaceDir renames the directory staging to dir, replacing the directory there
+func replaceDir(staging, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.Rename(staging, dir)
+	}
+
+	// The previous directory is moved aside into a directory of its own, whose name is free
+	old, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".old-")
+	if err != nil {
+		return err
+	}
+	previous := filepath.Join(old, filepath.Base(dir))
+	if err := os.Rename(dir, previous); err != nil {
+		_ = os.Remove(old)
+		return err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		// Put the previous directory back
+		_ = os.Rename(previous, dir)
+		_ = os.Remove(old)
+		return err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		internal.LogWarn("Failed to remove the previous export in %s: %v", old, err)
+	}
+	return nil
+}
+
+// linkTree recreates the files of src in dst as hard links, or copies where they can't be
+// linked, such as across file systems
+func linkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			if err := os.Link(path, target); err == nil {
+				return nil
+			}
+			return copyFile(path, target)
+		default:
+			return nil
+		}
+	})
+}
+
+// copyFile copies the regular file src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// listFiles returns the manifest entries of the regular files under dir, except the
+// manifest itself, sorted by path
+func listFiles(dir string) ([]ManifestEntry, error) {
+	var files []ManifestEntry
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFile {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		h := sha256.New()
+		size, err := io.Copy(h, file)
+		if err != nil {
+			return err
+		}
+		files = append(files, ManifestEntry{Path: rel, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
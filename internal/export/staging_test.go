@@ -0,0 +1,229 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// readManifest reads the manifest.json of an export directory
+func readManifest(t *testing.T, dir string) Manifest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	return manifest
+}
+
+// leftovers returns the staging files and directories left next to path
+func leftovers(t *testing.T, path string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestStageDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kept.txt"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "export-report.json"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	staged, err := StageDir(dir)
+	if err != nil {
+		t.Fatalf("StageDir() error = %v", err)
+	}
+	if err := staged.WriteSession(&JSONExporter{}, internal.CreateTestSession("staged"), "api/session_staged.json"); err != nil {
+		t.Fatalf("WriteSession() error = %v", err)
+	}
+	if err := staged.WriteFile("export-report.json", []byte("new")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// Nothing reaches the output directory before the commit, not even through the links
+	if _, err := os.Stat(filepath.Join(dir, "api/session_staged.json")); !os.IsNotExist(err) {
+		t.Errorf("session written to the output directory before the commit: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "export-report.json")); string(data) != "old" {
+		t.Errorf("export-report.json = %q before the commit, want the previous content", data)
+	}
+
+	if err := staged.Commit(Manifest{Format: "json", Counts: ManifestCounts{SessionsExported: 1}}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := staged.Close(); err != nil {
+		t.Fatalf("Close() after Commit() error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "export-report.json")); string(data) != "new" {
+		t.Errorf("export-report.json = %q, want the new content", data)
+	}
+	manifest := readManifest(t, dir)
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	if got := strings.Join(paths, ","); got != "api/session_staged.json,export-report.json,kept.txt" {
+		t.Errorf("manifest files = %s, want the new and the kept files", got)
+	}
+	if manifest.Counts.Files != 3 || manifest.Counts.SessionsExported != 1 || manifest.Format != "json" {
+		t.Errorf("manifest = %+v, want 3 files and the counts given", manifest)
+	}
+	report := manifest.Files[1]
+	if report.Size != 3 || report.SHA256 != newManifestEntry("", []byte("new")).SHA256 {
+		t.Errorf("export-report.json entry = %+v, want the size and hash of the new content", report)
+	}
+	if names := leftovers(t, dir); len(names) != 0 {
+		t.Errorf("staging left behind: %v", names)
+	}
+}
+
+func TestStageDir_WorkingDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kept.txt"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// "." is staged next to the working directory, not inside it
+	staged, err := StageDir(".")
+	if err != nil {
+		t.Fatalf("StageDir(\".\") error = %v", err)
+	}
+	if filepath.Dir(staged.Dir()) != filepath.Dir(dir) {
+		t.Errorf("staging directory = %s, want a sibling of %s", staged.Dir(), dir)
+	}
+	if err := staged.WriteFile("session_new.json", []byte("{}")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := staged.Commit(Manifest{Format: "json"}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	for _, name := range []string{"kept.txt", "session_new.json", ManifestFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s missing after the commit: %v", name, err)
+		}
+		// The working directory follows the output directory
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("%s missing from the working directory after the commit: %v", name, err)
+		}
+	}
+	if names := leftovers(t, dir); len(names) != 0 {
+		t.Errorf("staging left behind: %v", names)
+	}
+
+	if _, err := StageDir(string(filepath.Separator)); err == nil {
+		t.Error("StageDir() of the root directory should fail, having nowhere outside it to stage")
+	}
+}
+
+func TestStageDir_Discard(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	staged, err := StageDir(dir)
+	if err != nil {
+		t.Fatalf("StageDir() error = %v", err)
+	}
+	if err := staged.WriteFile("partial.json", []byte("{}")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	// An export that fails is closed without being committed
+	if err := staged.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("output directory exists after a discarded export: %v", err)
+	}
+	if names := leftovers(t, dir); len(names) != 0 {
+		t.Errorf("staging left behind: %v", names)
+	}
+}
+
+func TestStageArchive(t *testing.T) {
+	for _, name := range []string{"sessions.tar.gz", "sessions.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte("previous"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// A discarded archive leaves the previous one in place
+			staged, err := StageArchive(path)
+			if err != nil {
+				t.Fatalf("StageArchive() error = %v", err)
+			}
+			if err := staged.WriteFile("partial.json", []byte("{}")); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if err := staged.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != "previous" {
+				t.Errorf("archive = %q after a discarded export, want the previous one", data)
+			}
+
+			staged, err = StageArchive(path)
+			if err != nil {
+				t.Fatalf("StageArchive() error = %v", err)
+			}
+			if err := staged.WriteSession(&JSONExporter{}, internal.CreateTestSession("archived"), "session_archived.json"); err != nil {
+				t.Fatalf("WriteSession() error = %v", err)
+			}
+			if err := staged.Commit(Manifest{Format: "json"}); err != nil {
+				t.Fatalf("Commit() error = %v", err)
+			}
+			if err := staged.Close(); err != nil {
+				t.Fatalf("Close() after Commit() error = %v", err)
+			}
+
+			files := readArchive(t, path)
+			var manifest Manifest
+			if err := json.Unmarshal([]byte(files[ManifestFile]), &manifest); err != nil {
+				t.Fatalf("failed to parse manifest: %v", err)
+			}
+			if len(manifest.Files) != 1 || manifest.Files[0].Path != "session_archived.json" {
+				t.Fatalf("manifest files = %+v, want the session", manifest.Files)
+			}
+			if want := newManifestEntry("session_archived.json", []byte(files["session_archived.json"])); manifest.Files[0] != want {
+				t.Errorf("manifest entry = %+v, want %+v", manifest.Files[0], want)
+			}
+			if names := leftovers(t, path); len(names) != 0 {
+				t.Errorf("staging left behind: %v", names)
+			}
+		})
+	}
+}
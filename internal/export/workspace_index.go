@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+// GroupByWorkspace groups exported files into a directory per workspace
+const GroupByWorkspace = "workspace"
+
+// GroupByOptions are the values of export --group-by
+var GroupByOptions = []string{GroupByWorkspace}
+
+// NoWorkspaceDir is the directory of sessions not associated with a workspace
+const NoWorkspaceDir = "no-workspace"
+
+// WorkspaceIndexName is the name, without the extension, of the index written into each
+// workspace directory
+const WorkspaceIndexName = "index"
+
+// WorkspaceDir returns the directory a session's files are grouped into: a slug of the
+// last element of its workspace path, or NoWorkspaceDir
+func WorkspaceDir(workspace string) string {
+	if slug := Slugify(filepath.Base(filepath.ToSlash(workspace))); workspace != "" && slug != "" {
+		return slug
+	}
+	return NoWorkspaceDir
+}
+
+// WorkspaceIndex summarizes the sessions exported into a workspace directory
+type WorkspaceIndex struct {
+	Workspace string              `json:"workspace,omitempty"`
+	Sessions  []WorkspaceIndexRow `json:"sessions"`
+}
+
+// WorkspaceIndexRow is a session of a WorkspaceIndex
+type WorkspaceIndexRow struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+	MessageCount int    `json:"message_count"`
+	File         string `json:"file"` // Relative to the workspace directory
+}
+
+// WorkspaceIndexes collects the sessions of an export grouped by workspace, for writing
+// an index into each workspace directory
+type WorkspaceIndexes struct {
+	indexes map[string]*WorkspaceIndex // Keyed by directory
+}
+
+// NewWorkspaceIndexes creates an empty WorkspaceIndexes
+func NewWorkspaceIndexes() *WorkspaceIndexes {
+	return &WorkspaceIndexes{indexes: make(map[string]*WorkspaceIndex)}
+}
+
+// Add records that a session was exported to file, a name within its workspace directory
+func (w *WorkspaceIndexes) Add(session *internal.Session, file string) {
+	dir := WorkspaceDir(session.Workspace)
+	index, ok := w.indexes[dir]
+	if !ok {
+		index = &WorkspaceIndex{Workspace: session.Workspace}
+		w.indexes[dir] = index
+	}
+	index.Sessions = append(index.Sessions, WorkspaceIndexRow{
+		ID:           session.ID,
+		Name:         session.Metadata.Name,
+		CreatedAt:    internal.UTCTimestamp(session.Metadata.CreatedAt),
+		UpdatedAt:    internal.UTCTimestamp(session.Metadata.UpdatedAt),
+		MessageCount: len(session.Messages),
+		File:         file,
+	})
+}
+
+// Dirs returns the workspace directories with sessions, sorted
+func (w *WorkspaceIndexes) Dirs() []string {
+	dirs := make([]string, 0, len(w.indexes))
+	for dir := range w.indexes {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Index returns the index of a workspace directory, its sessions sorted by creation time
+// and then ID, or nil
+func (w *WorkspaceIndexes) Index(dir string) *WorkspaceIndex {
+	index, ok := w.indexes[dir]
+	if !ok {
+		return nil
+	}
+	sort.SliceStable(index.Sessions, func(i, j int) bool {
+		a, b := index.Sessions[i], index.Sessions[j]
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID < b.ID
+	})
+	return index
+}
+
+// JSON renders the index as indented JSON
+func (i *WorkspaceIndex) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workspace index: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Markdown renders the index as a Markdown table linking to each session's file
+func (i *WorkspaceIndex) Markdown() []byte {
+	var b bytes.Buffer
+	title := i.Workspace
+	if title == "" {
+		title = "Sessions without a workspace"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "%d session(s)\n\n", len(i.Sessions))
+	b.WriteString("| Session | Name | Created | Updated | Messages |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, s := range i.Sessions {
+		fmt.Fprintf(&b, "| [%s](%s) | %s | %s | %s | %d |\n",
+			s.ID, s.File, markdownCell(s.Name), s.CreatedAt, s.UpdatedAt, s.MessageCount)
+	}
+	return b.Bytes()
+}
+
+// markdownCell keeps text within a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
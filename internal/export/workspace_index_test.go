@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+)
+
+func TestWorkspaceDir(t *testing.T) {
+	tests := []struct {
+		workspace string
+		want      string
+	}{
+		{"/Users/dev/src/My API", "my-api"},
+		{"", NoWorkspaceDir},
+		{"/", NoWorkspaceDir},
+	}
+	for _, tt := range tests {
+		if got := WorkspaceDir(tt.workspace); got != tt.want {
+			t.Errorf("WorkspaceDir(%q) = %q, want %q", tt.workspace, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspaceIndexes(t *testing.T) {
+	session := func(id, workspace, created string) *internal.Session {
+		s := internal.CreateTestSession(id)
+		s.Workspace = workspace
+		s.Metadata.CreatedAt = created
+		s.Metadata.Name = "Fix | tests"
+		return s
+	}
+
+	indexes := NewWorkspaceIndexes()
+	indexes.Add(session("b", "/src/api", "2024-01-02T00:00:00Z"), "session_b.jsonl")
+	indexes.Add(session("a", "/src/api", "2024-01-01T00:00:00Z"), "session_a.jsonl")
+	indexes.Add(session("c", "", ""), "session_c.jsonl")
+
+	if dirs := indexes.Dirs(); !reflect.DeepEqual(dirs, []string{"api", NoWorkspaceDir}) {
+		t.Errorf("Dirs() = %v, want [api no-workspace]", dirs)
+	}
+	if index := indexes.Index("missing"); index != nil {
+		t.Errorf("Index() of an unknown directory = %+v, want nil", index)
+	}
+
+	index := indexes.Index("api")
+	if index.Workspace != "/src/api" || len(index.Sessions) != 2 || index.Sessions[0].ID != "a" {
+		t.Fatalf("Index(api) = %+v, want sessions a and b of /src/api", index)
+	}
+
+	data, err := index.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var decoded WorkspaceIndex
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("JSON() is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, index) {
+		t.Errorf("JSON() round trip = %+v, want %+v", decoded, index)
+	}
+
+	markdown := string(index.Markdown())
+	for _, want := range []string{
+		"# /src/api\n\n2 session(s)",
+		`| [a](session_a.jsonl) | Fix \| tests | 2024-01-01T00:00:00Z |`,
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown() should contain %q, got:\n%s", want, markdown)
+		}
+	}
+	if markdown := string(indexes.Index(NoWorkspaceDir).Markdown()); !strings.Contains(markdown, "# Sessions without a workspace") {
+		t.Errorf("Markdown() without a workspace = %s", markdown)
+	}
+}
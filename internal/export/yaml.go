@@ -15,7 +15,7 @@ func (e *YAMLExporter) Export(session *internal.Session, w io.Writer) error {
 	enc := yaml.NewEncoder(w)
 	defer func() { _ = enc.Close() }()
 
-	return enc.Encode(session)
+	return enc.Encode(utcSession(session))
 }
 
 // Extension returns the file extension for this format
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ExportReportFile is the name of the report written next to exported sessions
+const ExportReportFile = "export-report.json"
+
+// ExportReport summarizes an export run so CI can check that sessions were actually read
+// and written, per store.db for cursor-agent storage
+type ExportReport struct {
+	GeneratedAt      string          `json:"generated_at"`
+	Format           string          `json:"format"`
+	OutputDir        string          `json:"output_dir"`
+	SessionsExported int             `json:"sessions_exported"`
+	SessionsFailed   int             `json:"sessions_failed"`
+	SessionsSkipped  int             `json:"sessions_skipped,omitempty"` // Counted in SessionsExported: left in place by --resume
+	Totals           ParseStats      `json:"totals"`
+	Databases        []StoreDBReport `json:"databases"`
+	// Extraction is how the text of the exported sessions was extracted, when known
+	Extraction *ExtractionReport `json:"extraction,omitempty"`
+	// LoadWarnings are the records skipped while loading sessions, counted by category in
+	// LoadWarningCounts
+	LoadWarnings      []Warning      `json:"load_warnings,omitempty"`
+	LoadWarningCounts map[string]int `json:"load_warning_counts,omitempty"`
+	Warnings          []string       `json:"warnings,omitempty"`
+}
+
+// StoreDBReport is the part of an ExportReport about one store.db
+type StoreDBReport struct {
+	StoreDBStats
+	SessionsExported int `json:"sessions_exported"`
+}
+
+// ExtractionReport is the part of an ExportReport about text extraction quality: the
+// tier counts over all sessions, and the sessions whose text was degraded
+type ExtractionReport struct {
+	Totals   ExtractionStats     `json:"totals"`
+	Degraded []SessionExtraction `json:"degraded,omitempty"`
+}
+
+// SessionExtraction is the extraction tier counts of one session
+type SessionExtraction struct {
+	SessionID string `json:"session_id"`
+	ExtractionStats
+}
+
+// NewExportReport builds a report from the statistics recorded during the run and the IDs
+// of the sessions that were exported or failed to export
+func NewExportReport(format, outputDir string, exported, failed []string) *ExportReport {
+	report := &ExportReport{
+		GeneratedAt:      time.Now().UTC().Format(time.RFC3339),
+		Format:           format,
+		OutputDir:        outputDir,
+		SessionsExported: len(exported),
+		SessionsFailed:   len(failed),
+		Totals:           GetParseStats(),
+		Databases:        []StoreDBReport{},
+	}
+
+	exportedIDs := make(map[string]bool, len(exported))
+	for _, id := range exported {
+		exportedIDs[id] = true
+	}
+
+	if len(exported) == 0 {
+		report.Warnings = append(report.Warnings, "no sessions exported")
+	}
+	for _, stats := range GetStoreDBStats() {
+		db := StoreDBReport{StoreDBStats: stats}
+		for _, id := range stats.SessionIDs {
+			if exportedIDs[id] {
+				db.SessionsExported++
+			}
+		}
+		if db.SessionsExported == 0 && db.Error == "" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: no sessions exported", stats.Path))
+		}
+		report.Databases = append(report.Databases, db)
+	}
+
+	if loaded := GetWarnings(); len(loaded) > 0 {
+		report.LoadWarnings = loaded
+		report.LoadWarningCounts = CountWarnings(loaded)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d load warning(s): %s", len(loaded), SummarizeWarnings(loaded)))
+	}
+	return report
+}
+
+// AddExtraction adds the extraction tier counts of sessions to the report, with a warning
+// when any bubble's text could not be extracted or any bubble was missing. Sessions
+// without counts, such as those read from exports, are left out.
+func (r *ExportReport) AddExtraction(sessions []*Session) {
+	var extraction ExtractionReport
+	for _, session := range sessions {
+		if session == nil || session.Metadata.Extraction == nil {
+			continue
+		}
+		stats := *session.Metadata.Extraction
+		extraction.Totals.Add(stats)
+		if stats.Degraded() {
+			extraction.Degraded = append(extraction.Degraded, SessionExtraction{SessionID: session.ID, ExtractionStats: stats})
+		}
+	}
+	if extraction.Totals.Total() == 0 {
+		return
+	}
+	r.Extraction = &extraction
+	if extraction.Totals.Placeholder > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d message(s) had no extractable text", extraction.Totals.Placeholder))
+	}
+	if extraction.Totals.Missing > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d message(s) referenced by conversation headers were not found in any database", extraction.Totals.Missing))
+	}
+}
+
+// JSON returns the report as indented JSON
+func (r *ExportReport) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export report: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Write saves the report as indented JSON at path
+func (r *ExportReport) Write(path string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export report: %w", err)
+	}
+	return nil
+}
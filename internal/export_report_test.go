@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewExportReport(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	RecordParsed(12, 1)
+	RecordStoreDB(StoreDBStats{Path: "/chats/a/store.db", Blobs: 10, BlobParseFailures: 1, Composers: 1, SessionIDs: []string{"session-a"}})
+	RecordStoreDB(StoreDBStats{Path: "/chats/b/store.db", Blobs: 2, SessionIDs: []string{"session-b"}})
+	RecordStoreDB(StoreDBStats{Path: "/chats/c/store.db", Error: "failed to open store.db"})
+	// Reading a database again replaces its record
+	RecordStoreDB(StoreDBStats{Path: "/chats/a/store.db", Blobs: 10, BlobParseFailures: 1, Composers: 1, SessionIDs: []string{"session-a"}})
+
+	report := NewExportReport("json", "/out", []string{"session-a"}, []string{"session-b"})
+	if report.SessionsExported != 1 || report.SessionsFailed != 1 || report.Totals.Records != 12 {
+		t.Errorf("NewExportReport() = %+v, want 1 exported, 1 failed and 12 records", report)
+	}
+	if len(report.Databases) != 3 {
+		t.Fatalf("NewExportReport() has %d databases, want 3", len(report.Databases))
+	}
+	if got := []int{report.Databases[0].SessionsExported, report.Databases[1].SessionsExported, report.Databases[2].SessionsExported}; !reflect.DeepEqual(got, []int{1, 0, 0}) {
+		t.Errorf("sessions exported per database = %v, want [1 0 0]", got)
+	}
+	if want := []string{"/chats/b/store.db: no sessions exported"}; !reflect.DeepEqual(report.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", report.Warnings, want)
+	}
+
+	path := filepath.Join(t.TempDir(), ExportReportFile)
+	if err := report.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	databases := decoded["databases"].([]interface{})
+	first := databases[0].(map[string]interface{})
+	if first["path"] != "/chats/a/store.db" || first["blob_parse_failures"] != float64(1) || first["sessions_exported"] != float64(1) {
+		t.Errorf("first database = %v, want its stats and export count at the top level", first)
+	}
+}
+
+func TestNewExportReport_NothingExported(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	report := NewExportReport("jsonl", "/out", nil, nil)
+	if report.Databases == nil || len(report.Databases) != 0 {
+		t.Errorf("Databases = %v, want an empty list", report.Databases)
+	}
+	if want := []string{"no sessions exported"}; !reflect.DeepEqual(report.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", report.Warnings, want)
+	}
+}
+
+func TestNewExportReport_LoadWarnings(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	loaded := NewWarnings()
+	loaded.Add(WarningBlobParse, "/chats/a/store.db", "k1", "failed JSON parse")
+	loaded.Add(WarningBlobParse, "/chats/a/store.db", "k2", "failed JSON parse")
+	loaded.Add(WarningMetaParse, "/chats/a/store.db", "1", "failed JSON parse")
+	RecordWarnings(loaded)
+
+	report := NewExportReport("json", "/out", []string{"session-a"}, nil)
+	if len(report.LoadWarnings) != 3 {
+		t.Errorf("LoadWarnings = %v, want the 3 recorded warnings", report.LoadWarnings)
+	}
+	if want := map[string]int{WarningBlobParse: 2, WarningMetaParse: 1}; !reflect.DeepEqual(report.LoadWarningCounts, want) {
+		t.Errorf("LoadWarningCounts = %v, want %v", report.LoadWarningCounts, want)
+	}
+	if want := []string{"3 load warning(s): 2 blob_parse, 1 meta_parse"}; !reflect.DeepEqual(report.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", report.Warnings, want)
+	}
+}
+
+func TestExportReport_AddExtraction(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	sessions := []*Session{
+		{ID: "clean", Metadata: Metadata{Extraction: &ExtractionStats{Primary: 4, RichText: 1}}},
+		{ID: "lossy", Metadata: Metadata{Extraction: &ExtractionStats{Primary: 2, Fallback: 1, Placeholder: 2}}},
+		{ID: "imported"},
+		nil,
+	}
+	report := NewExportReport("json", "/out", []string{"clean", "lossy", "imported"}, nil)
+	report.AddExtraction(sessions)
+
+	if report.Extraction == nil {
+		t.Fatal("Extraction = nil, want the tier counts")
+	}
+	if want := (ExtractionStats{Primary: 6, RichText: 1, Fallback: 1, Placeholder: 2}); report.Extraction.Totals != want {
+		t.Errorf("Extraction.Totals = %+v, want %+v", report.Extraction.Totals, want)
+	}
+	if len(report.Extraction.Degraded) != 1 || report.Extraction.Degraded[0].SessionID != "lossy" {
+		t.Errorf("Extraction.Degraded = %+v, want only the lossy session", report.Extraction.Degraded)
+	}
+	if want := []string{"2 message(s) had no extractable text"}; !reflect.DeepEqual(report.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", report.Warnings, want)
+	}
+
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var decoded struct {
+		Extraction struct {
+			Degraded []map[string]interface{} `json:"degraded"`
+		} `json:"extraction"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if got := decoded.Extraction.Degraded[0]; got["session_id"] != "lossy" || got["placeholder"] != float64(2) {
+		t.Errorf("degraded session = %v, want its ID and counts at the top level", got)
+	}
+
+	// Sessions without counts leave the section out
+	report = NewExportReport("json", "/out", []string{"imported"}, nil)
+	report.AddExtraction([]*Session{{ID: "imported"}})
+	if report.Extraction != nil {
+		t.Errorf("Extraction = %+v, want nil", report.Extraction)
+	}
+}
+
+func TestExportReport_AddExtraction_Missing(t *testing.T) {
+	report := NewExportReport("json", "/out", []string{"resumed"}, nil)
+	report.AddExtraction([]*Session{{ID: "resumed", Metadata: Metadata{Extraction: &ExtractionStats{Primary: 3, Missing: 2}}}})
+
+	if report.Extraction == nil || len(report.Extraction.Degraded) != 1 || report.Extraction.Degraded[0].Missing != 2 {
+		t.Fatalf("Extraction = %+v, want the session with missing messages listed", report.Extraction)
+	}
+	if want := []string{"2 message(s) referenced by conversation headers were not found in any database"}; !reflect.DeepEqual(report.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", report.Warnings, want)
+	}
+}
@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ExportProgressFile is the progress manifest export keeps in its output directory, so
+// an interrupted export can be resumed
+const ExportProgressFile = ".export-progress.json"
+
+// ExportState records a fingerprint of every exported session so repeated
+// export runs only write sessions that changed since the last run. The sessions are
+// recorded apart for each format, so exporting into the same directory in one format
+// does not forget what was exported in another.
+type ExportState struct {
+	Format  string                    `json:"format"`
+	Outputs map[string]*ExportOutputs `json:"outputs"` // Format -> sessions exported in it
+
+	// Sessions, Files and Hashes are the records of Format, as in Outputs
+	Sessions map[string]string `json:"-"`
+	Files    map[string]string `json:"-"`
+	Hashes   map[string]string `json:"-"`
+
+	path string
+}
+
+// ExportOutputs records the sessions exported in one format
+type ExportOutputs struct {
+	Sessions map[string]string `json:"sessions"`         // Session ID -> fingerprint of the session exported
+	Files    map[string]string `json:"files,omitempty"`  // Session ID -> name of the file it was exported to
+	Hashes   map[string]string `json:"hashes,omitempty"` // Session ID -> SHA-256 of the file written
+}
+
+// legacyExportState is the layout of export states written before they recorded each
+// format apart, holding the sessions of a single format
+type legacyExportState struct {
+	Sessions map[string]string `json:"sessions"`
+	Files    map[string]string `json:"files"`
+}
+
+// NewExportState returns an empty export state saved to path
+func NewExportState(path string) *ExportState {
+	state := &ExportState{Outputs: make(map[string]*ExportOutputs), path: path}
+	state.SetFormat("")
+	return state
+}
+
+// LoadExportState loads the export state from path. A missing file yields an empty state.
+func LoadExportState(path string) (*ExportState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewExportState(path), nil
+		}
+		return nil, fmt.Errorf("failed to read export state: %w", err)
+	}
+
+	state := &ExportState{path: path}
+	var legacy legacyExportState
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export state: %w", err)
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export state: %w", err)
+	}
+	if state.Outputs == nil {
+		state.Outputs = make(map[string]*ExportOutputs)
+	}
+	if _, ok := state.Outputs[state.Format]; !ok && legacy.Sessions != nil {
+		state.Outputs[state.Format] = &ExportOutputs{Sessions: legacy.Sessions, Files: legacy.Files}
+	}
+	state.SetFormat(state.Format)
+
+	return state, nil
+}
+
+// SetFormat selects the format whose sessions Changed, Mark, SetFile and SetHash work
+// on, keeping those recorded for other formats
+func (s *ExportState) SetFormat(format string) {
+	outputs := s.Outputs[format]
+	if outputs == nil {
+		outputs = &ExportOutputs{}
+		s.Outputs[format] = outputs
+	}
+	if outputs.Sessions == nil {
+		outputs.Sessions = make(map[string]string)
+	}
+	if outputs.Files == nil {
+		outputs.Files = make(map[string]string)
+	}
+	if outputs.Hashes == nil {
+		outputs.Hashes = make(map[string]string)
+	}
+	s.Format = format
+	s.Sessions, s.Files, s.Hashes = outputs.Sessions, outputs.Files, outputs.Hashes
+}
+
+// Changed reports whether a session differs from the last recorded export and
+// returns its current fingerprint
+func (s *ExportState) Changed(session *Session) (string, bool) {
+	fingerprint := SessionFingerprint(session)
+	return fingerprint, fingerprint == "" || s.Sessions[session.ID] != fingerprint
+}
+
+// Mark records the fingerprint of an exported session
+func (s *ExportState) Mark(sessionID, fingerprint string) {
+	s.Sessions[sessionID] = fingerprint
+}
+
+// SetFile records the name of the file a session was exported to
+func (s *ExportState) SetFile(sessionID, name string) {
+	s.Files[sessionID] = name
+}
+
+// SetHash records the SHA-256 of the file a session was exported to, as returned by
+// HashFile
+func (s *ExportState) SetHash(sessionID, hash string) {
+	s.Hashes[sessionID] = hash
+}
+
+// Written reports whether the file at path still holds what a session was last exported
+// as. States written before files were hashed only record that the file was written, so
+// for them any file at path counts.
+func (s *ExportState) Written(sessionID, path string) bool {
+	want, ok := s.Hashes[sessionID]
+	if !ok {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	hash, err := HashFile(path)
+	return err == nil && hash == want
+}
+
+// HashFile returns the hex-encoded SHA-256 of the content of a file
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Save writes the export state back to its file. The file is replaced in one rename, so
+// a crash while saving leaves the previous state in place.
+func (s *ExportState) Save() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Formats nothing was exported in are not worth a record
+	for format, outputs := range s.Outputs {
+		if len(outputs.Sessions) == 0 && format != s.Format {
+			delete(s.Outputs, format)
+		}
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export state: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".export-state_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		LogWarn("Failed to set permissions on %s: %v", s.path, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	return nil
+}
+
+// ExportFormatVersion is the version of how exporters write a session. It is part of every
+// session fingerprint, so files written before a change to an exporter are written again.
+const ExportFormatVersion = "2"
+
+// SessionFingerprint hashes everything recorded of a session, as it is marshalled, with
+// the ExportFormatVersion it is exported in
+func SessionFingerprint(session *Session) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00", ExportFormatVersion)
+	if err := json.NewEncoder(h).Encode(session); err != nil {
+		// A session that can't be marshalled can't be exported either; Changed reports an
+		// empty fingerprint as changed, so it is tried again
+		LogDebug("Failed to fingerprint session %s: %v", session.ID, err)
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestExportState_Changed(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	state, err := LoadExportState(statePath)
+	if err != nil {
+		t.Fatalf("LoadExportState() error = %v", err)
+	}
+	state.SetFormat("jsonl")
+
+	session := CreateTestSession("session1")
+	fingerprint, changed := state.Changed(session)
+	if !changed {
+		t.Error("Changed() = false for a new session, want true")
+	}
+	state.Mark(session.ID, fingerprint)
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadExportState(statePath)
+	if err != nil {
+		t.Fatalf("LoadExportState() error = %v", err)
+	}
+	reloaded.SetFormat("jsonl")
+	if _, changed := reloaded.Changed(session); changed {
+		t.Error("Changed() = true for an unchanged session after reload, want false")
+	}
+
+	// Anything written to the file counts as a change, timestamps and metadata included
+	timestamp := session.Messages[0].Timestamp
+	session.Messages[0].Timestamp = "2020-01-01T00:00:00Z"
+	if _, changed := reloaded.Changed(session); !changed {
+		t.Error("Changed() = false after a timestamp-only change, want true")
+	}
+	session.Messages[0].Timestamp = timestamp
+	session.Metadata.ParentID = "parent-session"
+	if _, changed := reloaded.Changed(session); !changed {
+		t.Error("Changed() = false after a metadata-only change, want true")
+	}
+	session.Metadata.ParentID = ""
+
+	session.Messages = append(session.Messages, Message{Actor: "user", Content: "One more thing"})
+	if _, changed := reloaded.Changed(session); !changed {
+		t.Error("Changed() = false after adding a message, want true")
+	}
+
+	// Each format records its own sessions
+	reloaded.SetFormat("md")
+	if len(reloaded.Sessions) != 0 {
+		t.Errorf("SetFormat() found %d sessions of a format nothing was exported in, want 0", len(reloaded.Sessions))
+	}
+	reloaded.SetFormat("jsonl")
+	if reloaded.Sessions[session.ID] != fingerprint {
+		t.Error("SetFormat() should keep the sessions recorded for another format")
+	}
+}
+
+func TestLoadExportState_Legacy(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	statePath := filepath.Join(tmpDir, ExportProgressFile)
+	legacy := `{"format": "jsonl", "sessions": {"session1": "abc"}, "files": {"session1": "session_session1.jsonl"}}`
+	if err := os.WriteFile(statePath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	state, err := LoadExportState(statePath)
+	if err != nil {
+		t.Fatalf("LoadExportState() error = %v", err)
+	}
+	if state.Format != "jsonl" || state.Sessions["session1"] != "abc" || state.Files["session1"] != "session_session1.jsonl" {
+		t.Errorf("LoadExportState() = %+v, want the sessions of the legacy state under jsonl", state)
+	}
+	state.SetFormat("md")
+	state.SetFormat("jsonl")
+	if state.Sessions["session1"] != "abc" {
+		t.Error("SetFormat() should keep the legacy sessions")
+	}
+}
+
+func TestExportState_Written(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	file := filepath.Join(tmpDir, "session_session1.jsonl")
+	if err := os.WriteFile(file, []byte("exported\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := NewExportState(filepath.Join(tmpDir, ExportProgressFile))
+	state.SetFormat("jsonl")
+	if !state.Written("session1", file) {
+		t.Error("Written() = false for a file recorded without a hash, want true")
+	}
+	hash, err := HashFile(file)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	state.SetHash("session1", hash)
+	if !state.Written("session1", file) {
+		t.Error("Written() = false for the file as written, want true")
+	}
+
+	if err := os.WriteFile(file, []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if state.Written("session1", file) {
+		t.Error("Written() = true for a file edited since, want false")
+	}
+	if state.Written("session1", filepath.Join(tmpDir, "missing.jsonl")) {
+		t.Error("Written() = true for a missing file, want false")
+	}
+}
+
+func TestLoadExportState_Invalid(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	statePath := filepath.Join(tmpDir, "state.json")
+	if err := os.WriteFile(statePath, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	if _, err := LoadExportState(statePath); err == nil {
+		t.Error("LoadExportState() should return error for invalid JSON")
+	}
+}
+
+func TestExportState_SaveReplacesFile(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	statePath := filepath.Join(tmpDir, "nested", ExportProgressFile)
+
+	state := NewExportState(statePath)
+	state.SetFormat("json")
+	state.Mark("session1", "abc")
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	state.Mark("session2", "def")
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadExportState(statePath)
+	if err != nil {
+		t.Fatalf("LoadExportState() error = %v", err)
+	}
+	if reloaded.Format != "json" || len(reloaded.Sessions) != 2 || len(reloaded.Outputs) != 1 {
+		t.Errorf("reloaded state = %+v, want json with 2 sessions", reloaded)
+	}
+	entries, err := os.ReadDir(filepath.Dir(statePath))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Save() should leave only the state file behind, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,72 @@
+package internal
+
+import "fmt"
+
+// ExtractionTier is the source a bubble's text was extracted from
+type ExtractionTier string
+
+const (
+	ExtractionPrimary  ExtractionTier = "primary"   // The bubble's text field
+	ExtractionRichText ExtractionTier = "rich_text" // The richText JSON, when the text field is empty
+	// ExtractionFallback is text recovered from malformed richText, code blocks or tool
+	// call data alone
+	ExtractionFallback ExtractionTier = "fallback"
+	// ExtractionPlaceholder is a bubble with no extractable text, whose message is dropped
+	ExtractionPlaceholder ExtractionTier = "placeholder"
+)
+
+// ExtractionStats counts the bubbles of a session by the tier their text was extracted
+// from, to quantify how much content reconstruction loses across Cursor versions
+type ExtractionStats struct {
+	Primary     int `json:"primary"`
+	RichText    int `json:"rich_text"`
+	Fallback    int `json:"fallback"`
+	Placeholder int `json:"placeholder"`
+	// Missing counts the bubbles the conversation headers reference that no database
+	// holds, whose messages are lost
+	Missing int `json:"missing,omitempty"`
+}
+
+// Record counts a bubble extracted from tier
+func (s *ExtractionStats) Record(tier ExtractionTier) {
+	switch tier {
+	case ExtractionPrimary:
+		s.Primary++
+	case ExtractionRichText:
+		s.RichText++
+	case ExtractionFallback:
+		s.Fallback++
+	case ExtractionPlaceholder:
+		s.Placeholder++
+	}
+}
+
+// Add adds the counts of other to s
+func (s *ExtractionStats) Add(other ExtractionStats) {
+	s.Primary += other.Primary
+	s.RichText += other.RichText
+	s.Fallback += other.Fallback
+	s.Placeholder += other.Placeholder
+	s.Missing += other.Missing
+}
+
+// Total returns the number of bubbles counted
+func (s ExtractionStats) Total() int {
+	return s.Primary + s.RichText + s.Fallback + s.Placeholder + s.Missing
+}
+
+// Degraded reports whether any bubble lost its text, was only recovered by fallbacks or
+// was not found at all
+func (s ExtractionStats) Degraded() bool {
+	return s.Fallback > 0 || s.Placeholder > 0 || s.Missing > 0
+}
+
+// String summarizes the counts, such as "40 primary, 2 rich text, 1 fallback, 3 placeholder",
+// followed by the bubbles missing when there are any
+func (s ExtractionStats) String() string {
+	summary := fmt.Sprintf("%d primary, %d rich text, %d fallback, %d placeholder", s.Primary, s.RichText, s.Fallback, s.Placeholder)
+	if s.Missing > 0 {
+		summary += fmt.Sprintf(", %d missing", s.Missing)
+	}
+	return summary
+}
@@ -0,0 +1,33 @@
+package internal
+
+import "testing"
+
+func TestExtractionStats(t *testing.T) {
+	var stats ExtractionStats
+	for _, tier := range []ExtractionTier{ExtractionPrimary, ExtractionPrimary, ExtractionRichText, ExtractionPlaceholder} {
+		stats.Record(tier)
+	}
+	if stats.Total() != 4 || !stats.Degraded() {
+		t.Errorf("stats = %+v, want 4 bubbles and degraded", stats)
+	}
+	if got, want := stats.String(), "2 primary, 1 rich text, 0 fallback, 1 placeholder"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	stats.Add(ExtractionStats{Primary: 1, Fallback: 2})
+	if want := (ExtractionStats{Primary: 3, RichText: 1, Fallback: 2, Placeholder: 1}); stats != want {
+		t.Errorf("Add() = %+v, want %+v", stats, want)
+	}
+
+	if (ExtractionStats{Primary: 5, RichText: 2}).Degraded() {
+		t.Error("Degraded() = true for text and rich text only, want false")
+	}
+
+	missing := ExtractionStats{Primary: 5, Missing: 2}
+	if !missing.Degraded() || missing.Total() != 7 {
+		t.Errorf("stats with missing bubbles = %+v, want degraded over 7 bubbles", missing)
+	}
+	if got, want := missing.String(), "5 primary, 0 rich text, 0 fallback, 0 placeholder, 2 missing"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
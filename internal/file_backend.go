@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BackendExportDir is the provenance backend name for sessions read back from exported files
+const BackendExportDir = "exportDir"
+
+// Exported file kinds, in increasing order of fidelity. When a session appears in
+// several files, the one with the most complete data wins.
+const (
+	exportFileJSONL = iota + 1
+	exportFileSession
+	exportFileIntermediary
+)
+
+// FileBackend provides methods to read sessions from a directory of previously
+// exported files (JSON, YAML or JSONL exports, and intermediary dumps), so archives
+// can be inspected and re-exported without the original databases. The directory is
+// walked once, on the first load, and every later load reuses what it read.
+type FileBackend struct {
+	dir string
+
+	loadOnce   sync.Once
+	bubbles    map[string]*RawBubble
+	composers  []*RawComposer
+	contexts   map[string][]*MessageContext
+	workspaces map[string]string
+	loadErr    error
+}
+
+// Ensure FileBackend implements StorageBackend
+var _ StorageBackend = (*FileBackend)(nil)
+
+// NewFileBackend creates a new FileBackend reading from dir (searched recursively)
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+// LoadBubbles loads all messages from the exported files as bubbles
+func (f *FileBackend) LoadBubbles() (map[string]*RawBubble, error) {
+	f.load()
+	return f.bubbles, f.loadErr
+}
+
+// LoadComposers loads one composer per exported session
+func (f *FileBackend) LoadComposers() ([]*RawComposer, error) {
+	f.load()
+	return f.composers, f.loadErr
+}
+
+// LoadMessageContexts loads contexts from intermediary dumps; other exports only carry
+// their git state
+func (f *FileBackend) LoadMessageContexts() (map[string][]*MessageContext, error) {
+	f.load()
+	return f.contexts, f.loadErr
+}
+
+// LoadCodeBlockDiffs returns an empty map; exported files have no code block diffs
+func (f *FileBackend) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
+	return make(map[string][]interface{}), nil
+}
+
+// SessionWorkspace returns the workspace recorded in the export of a session
+func (f *FileBackend) SessionWorkspace(composerID string) string {
+	f.load()
+	return f.workspaces[composerID]
+}
+
+// exportedSession is a session read from one exported file
+type exportedSession struct {
+	kind      int
+	workspace string
+	composer  *RawComposer
+	bubbles   []*RawBubble
+	contexts  []*MessageContext
+}
+
+// load reads the directory the first time it is called
+func (f *FileBackend) load() {
+	f.loadOnce.Do(func() {
+		f.bubbles, f.composers, f.contexts, f.workspaces, f.loadErr = f.loadAll()
+	})
+}
+
+// loadAll reads every exported file under the directory, skipping ones that fail to parse
+func (f *FileBackend) loadAll() (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext, map[string]string, error) {
+	sessions := make(map[string]*exportedSession)
+	var order []string
+
+	err := filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		loaded, err := loadExportedFile(path)
+		if err != nil {
+			LogWarn("Failed to read exported session %s: %v", path, err)
+			RecordParsed(1, 1)
+			return nil
+		}
+		if loaded == nil {
+			return nil
+		}
+		RecordParsed(1, 0)
+
+		id := loaded.composer.ComposerID
+		existing, seen := sessions[id]
+		if !seen {
+			order = append(order, id)
+		}
+		if !seen || loaded.kind > existing.kind {
+			if seen && loaded.workspace == "" {
+				loaded.workspace = existing.workspace
+			}
+			sessions[id] = loaded
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to scan export directory: %w", err)
+	}
+
+	bubbles := make(map[string]*RawBubble)
+	composers := make([]*RawComposer, 0, len(order))
+	contexts := make(map[string][]*MessageContext)
+	workspaces := make(map[string]string)
+
+	for _, id := range order {
+		s := sessions[id]
+		composers = append(composers, s.composer)
+		for _, bubble := range s.bubbles {
+			bubbles[bubble.BubbleID] = bubble
+		}
+		if len(s.contexts) > 0 {
+			contexts[id] = s.contexts
+		}
+		if s.workspace != "" {
+			workspaces[id] = s.workspace
+		}
+	}
+
+	return bubbles, composers, contexts, workspaces, nil
+}
+
+// loadExportedFile parses a single exported file. It returns nil for files that
+// are not session exports (Markdown, state files, cache indexes).
+func loadExportedFile(path string) (*exportedSession, error) {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(name))
+	isIntermediary := strings.Contains(name, ".intermediary.")
+
+	switch {
+	case isIntermediary && (ext == ".json" || ext == ".yaml" || ext == ".yml"):
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dump, err := ParseIntermediaryDump(data, ext != ".json")
+		if err != nil {
+			return nil, err
+		}
+		return dumpToExportedSession(dump, path), nil
+
+	case ext == ".json" || ext == ".yaml" || ext == ".yml":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var session Session
+		if ext == ".json" {
+			err = json.Unmarshal(data, &session)
+		} else {
+			err = yaml.Unmarshal(data, &session)
+		}
+		if err != nil || session.ID == "" {
+			// Not a session export
+			return nil, nil
+		}
+		return sessionToExportedSession(&session, path, exportFileSession), nil
+
+	case ext == ".jsonl" && strings.HasPrefix(name, "session_"):
+		session, err := readJSONLSession(path, strings.TrimSuffix(strings.TrimPrefix(name, "session_"), filepath.Ext(name)))
+		if err != nil {
+			return nil, err
+		}
+		return sessionToExportedSession(session, path, exportFileJSONL), nil
+	}
+
+	return nil, nil
+}
+
+// readJSONLSession reads a JSONL export (one message per line) into a session
+func readJSONLSession(path, sessionID string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	session := &Session{ID: sessionID, Metadata: Metadata{ComposerID: sessionID}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return nil, &ParseError{Source: BackendExportDir, Key: path, Err: err}
+		}
+		session.Messages = append(session.Messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// sessionToExportedSession converts a normalized session back into a raw composer and bubbles
+func sessionToExportedSession(session *Session, path string, kind int) *exportedSession {
+	composer := &RawComposer{
+		ComposerID:    session.ID,
+		Name:          session.Metadata.Name,
+		CreatedAt:     parseTimestamp(session.Metadata.CreatedAt),
+		LastUpdatedAt: parseTimestamp(session.Metadata.UpdatedAt),
+		ParentID:      session.Metadata.ParentID,
+		WorkspaceHash: session.WorkspaceHash,
+		Fields:        session.Metadata.Fields,
+	}
+
+	bubbles := make([]*RawBubble, 0, len(session.Messages))
+	for i, msg := range session.Messages {
+		msgType := MessageTypeForActor(msg.Actor)
+
+		// Keep the original provenance so messages still trace back to the source database
+		provenance := msg.Provenance
+		if provenance == nil {
+			provenance = &Provenance{SourcePath: path, Backend: BackendExportDir}
+		}
+
+		bubble := &RawBubble{
+			BubbleID:   fmt.Sprintf("%s-%d", session.ID, i),
+			ChatID:     session.ID,
+			Text:       msg.Content,
+			Timestamp:  parseTimestamp(msg.Timestamp),
+			Type:       msgType,
+			Provenance: provenance,
+		}
+		if msg.Thinking != "" {
+			bubble.Thinking = &BubbleThinking{Text: msg.Thinking}
+		}
+		bubble.Images = exportedImages(msg.Attachments, path)
+		bubbles = append(bubbles, bubble)
+		composer.FullConversationHeadersOnly = append(composer.FullConversationHeadersOnly, ConversationHeader{
+			BubbleID: bubble.BubbleID,
+			Type:     msgType,
+		})
+	}
+
+	// Keep the git state by recording it as the context of the last message
+	var contexts []*MessageContext
+	if session.Metadata.Git != nil && len(bubbles) > 0 {
+		contexts = append(contexts, &MessageContext{
+			BubbleID:     bubbles[len(bubbles)-1].BubbleID,
+			ComposerID:   session.ID,
+			GitStatusRaw: gitStatusText(session.Metadata.Git),
+		})
+	}
+
+	return &exportedSession{
+		kind:      kind,
+		workspace: session.Workspace,
+		composer:  composer,
+		bubbles:   bubbles,
+		contexts:  contexts,
+	}
+}
+
+// exportedImages returns the images of a message read from the export at path: the files
+// the export wrote them to, or else where Cursor kept them
+func exportedImages(attachments []Attachment, path string) []BubbleImage {
+	var images []BubbleImage
+	for _, att := range attachments {
+		img := BubbleImage{MimeType: att.MimeType, Path: att.Source}
+		if att.Width > 0 || att.Height > 0 {
+			img.Dimension = &ImageDimension{Width: att.Width, Height: att.Height}
+		}
+		switch {
+		case att.File != "":
+			img.Path = filepath.Join(filepath.Dir(path), filepath.FromSlash(att.File))
+		case att.Data != nil:
+			img.Path, img.Data = "", base64.StdEncoding.EncodeToString(att.Data)
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// dumpToExportedSession uses the raw data of an intermediary dump as is
+func dumpToExportedSession(dump *IntermediaryDump, path string) *exportedSession {
+	// Provenance is not part of the dump, so point messages at the dump file
+	for _, bubble := range dump.Bubbles {
+		if bubble.Provenance == nil {
+			bubble.Provenance = &Provenance{SourcePath: path, BlobKey: bubble.BubbleID, Backend: BackendExportDir}
+		}
+	}
+
+	composer := dump.Composer
+	if composer == nil {
+		// Dumps of agent sessions have no stored composer; rebuild one from the bubble order
+		composer = &RawComposer{ComposerID: dump.SessionID}
+		for _, bubble := range dump.Bubbles {
+			composer.FullConversationHeadersOnly = append(composer.FullConversationHeadersOnly, ConversationHeader{
+				BubbleID: bubble.BubbleID,
+				Type:     bubble.Type,
+			})
+		}
+		if len(dump.Bubbles) > 0 {
+			composer.CreatedAt = dump.Bubbles[0].Timestamp
+			composer.LastUpdatedAt = dump.Bubbles[len(dump.Bubbles)-1].Timestamp
+		}
+	}
+
+	return &exportedSession{
+		kind:      exportFileIntermediary,
+		workspace: dump.Workspace,
+		composer:  composer,
+		bubbles:   dump.Bubbles,
+		contexts:  dump.Contexts,
+	}
+}
+
+// IsExportDir reports whether dir contains exported session files or intermediary dumps
+func IsExportDir(dir string) bool {
+	found := false
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if strings.HasPrefix(name, "session_") || strings.Contains(name, ".intermediary.") {
+			switch strings.ToLower(filepath.Ext(name)) {
+			case ".json", ".jsonl", ".yaml", ".yml":
+				found = true
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func writeExportFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func createExportDir(t *testing.T) string {
+	t.Helper()
+	dir := testutil.CreateTempDir(t)
+
+	// JSON export with workspace and provenance
+	session := CreateTestSession("json-session")
+	session.Metadata.Name = "From JSON"
+	session.Metadata.CreatedAt = "2023-01-01T00:00:00Z"
+	session.Messages[0].Provenance = &Provenance{SourcePath: "/db/state.vscdb", Backend: BackendGlobalStorage}
+	session.Metadata.Git = &GitInfo{Branch: "main", Commit: "9fceb02"}
+	writeExportFile(t, filepath.Join(dir, "session_json-session.json"), testutil.JSONMarshal(t, session))
+
+	// JSONL export in a subdirectory
+	writeExportFile(t, filepath.Join(dir, "nested", "session_jsonl-session.jsonl"), []byte(
+		`{"actor":"user","content":"Hi","timestamp":"2023-01-02T00:00:00Z"}`+"\n"+
+			`{"actor":"assistant","content":"Hello"}`+"\n"))
+
+	// Session exported both as JSONL and as an intermediary dump; the dump wins
+	writeExportFile(t, filepath.Join(dir, "session_dumped.jsonl"), []byte(`{"actor":"user","content":"lossy"}`+"\n"))
+	dump := &IntermediaryDump{
+		SessionID: "dumped",
+		Workspace: "ws-dump",
+		Composer: &RawComposer{
+			ComposerID:                  "dumped",
+			Name:                        "From dump",
+			FullConversationHeadersOnly: []ConversationHeader{{BubbleID: "b1", Type: 1}},
+		},
+		Bubbles:  []*RawBubble{{BubbleID: "b1", ChatID: "dumped", RichText: `{"root":{"children":[]}}`, Text: "raw text", Type: 1}},
+		Contexts: []*MessageContext{{ComposerID: "dumped", ContextID: "ctx"}},
+	}
+	data, err := dump.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML() error = %v", err)
+	}
+	writeExportFile(t, filepath.Join(dir, "session_dumped.intermediary.yaml"), data)
+
+	// Files that are not session exports are ignored
+	writeExportFile(t, filepath.Join(dir, "session_json-session.md"), []byte("# Session"))
+	writeExportFile(t, filepath.Join(dir, ".exportd-state.json"), []byte(`{"format":"jsonl","sessions":{}}`))
+
+	return dir
+}
+
+func TestFileBackend_Load(t *testing.T) {
+	backend := NewFileBackend(createExportDir(t))
+
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		t.Fatalf("LoadComposers() error = %v", err)
+	}
+	names := make(map[string]string)
+	for _, composer := range composers {
+		names[composer.ComposerID] = composer.Name
+	}
+	want := map[string]string{"json-session": "From JSON", "jsonl-session": "", "dumped": "From dump"}
+	if len(names) != len(want) {
+		t.Fatalf("LoadComposers() returned %v, want %v", names, want)
+	}
+	for id, name := range want {
+		if got, ok := names[id]; !ok || got != name {
+			t.Errorf("composer %s name = %q (found %v), want %q", id, got, ok, name)
+		}
+	}
+
+	bubbles, err := backend.LoadBubbles()
+	if err != nil {
+		t.Fatalf("LoadBubbles() error = %v", err)
+	}
+	if b := bubbles["json-session-0"]; b == nil || b.Type != 1 || b.Provenance.SourcePath != "/db/state.vscdb" {
+		t.Errorf("json-session-0 = %+v, want user bubble keeping its original provenance", b)
+	}
+	if b := bubbles["jsonl-session-1"]; b == nil || b.Type != 2 || b.Provenance.Backend != BackendExportDir {
+		t.Errorf("jsonl-session-1 = %+v, want assistant bubble with exportDir provenance", b)
+	}
+	if b := bubbles["jsonl-session-0"]; b == nil || b.Timestamp == 0 {
+		t.Errorf("jsonl-session-0 = %+v, want parsed timestamp", b)
+	}
+	if b := bubbles["b1"]; b == nil || b.RichText == "" {
+		t.Errorf("b1 = %+v, want raw bubble from the intermediary dump", b)
+	}
+	if _, ok := bubbles["dumped-0"]; ok {
+		t.Error("LoadBubbles() kept the JSONL copy of a session that has an intermediary dump")
+	}
+
+	contexts, _ := backend.LoadMessageContexts()
+	if len(contexts["dumped"]) != 1 {
+		t.Errorf("contexts[dumped] = %v, want 1 context", contexts["dumped"])
+	}
+
+	if got := backend.SessionWorkspace("json-session"); got != "test-workspace" {
+		t.Errorf("SessionWorkspace(json-session) = %q, want test-workspace", got)
+	}
+	if got := BackendSessionWorkspace(backend, "dumped"); got != "ws-dump" {
+		t.Errorf("BackendSessionWorkspace(dumped) = %q, want ws-dump", got)
+	}
+}
+
+func TestFileBackend_WalksOnce(t *testing.T) {
+	dir := createExportDir(t)
+	backend := NewFileBackend(dir)
+
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		t.Fatalf("LoadComposers() error = %v", err)
+	}
+
+	// A file written after the first load is not read: later loads reuse the first walk
+	late := CreateTestSession("late-session")
+	writeExportFile(t, filepath.Join(dir, "session_late-session.json"), testutil.JSONMarshal(t, late))
+
+	bubbles, err := backend.LoadBubbles()
+	if err != nil {
+		t.Fatalf("LoadBubbles() error = %v", err)
+	}
+	if _, ok := bubbles["late-session-0"]; ok {
+		t.Error("LoadBubbles() walked the directory again")
+	}
+	again, _ := backend.LoadComposers()
+	if len(again) != len(composers) {
+		t.Errorf("LoadComposers() returned %d composers the second time, want %d", len(again), len(composers))
+	}
+	if got := backend.SessionWorkspace("late-session"); got != "" {
+		t.Errorf("SessionWorkspace(late-session) = %q, want it unread", got)
+	}
+}
+
+func TestFileBackend_RoundTrip(t *testing.T) {
+	backend := NewFileBackend(createExportDir(t))
+
+	bubbleChan, composerChan, contextChan, err := LoadDataAsyncFromBackend(backend)
+	if err != nil {
+		t.Fatalf("LoadDataAsyncFromBackend() error = %v", err)
+	}
+	conversations, err := ReconstructAsync(bubbleChan, composerChan, contextChan)
+	if err != nil {
+		t.Fatalf("ReconstructAsync() error = %v", err)
+	}
+
+	normalizer := NewNormalizer()
+	for _, conv := range conversations {
+		if conv.ComposerID != "json-session" {
+			continue
+		}
+		session, err := normalizer.NormalizeConversation(conv, backend.SessionWorkspace(conv.ComposerID))
+		if err != nil {
+			t.Fatalf("NormalizeConversation() error = %v", err)
+		}
+		original := CreateTestSession("json-session")
+		if len(session.Messages) != len(original.Messages) {
+			t.Fatalf("round trip has %d messages, want %d", len(session.Messages), len(original.Messages))
+		}
+		for i := range original.Messages {
+			if session.Messages[i].Content != original.Messages[i].Content || session.Messages[i].Actor != original.Messages[i].Actor {
+				t.Errorf("message %d = %+v, want %+v", i, session.Messages[i], original.Messages[i])
+			}
+		}
+		if git := session.Metadata.Git; git == nil || git.Branch != "main" || git.Commit != "9fceb02" {
+			t.Errorf("round trip git = %+v, want main at 9fceb02", git)
+		}
+		return
+	}
+	t.Error("json-session was not reconstructed")
+}
+
+func TestGetStoragePaths_ExportDir(t *testing.T) {
+	dir := createExportDir(t)
+
+	paths, err := GetStoragePaths(dir)
+	if err != nil {
+		t.Fatalf("GetStoragePaths() error = %v", err)
+	}
+	if paths.ExportDir != dir {
+		t.Errorf("ExportDir = %q, want %q", paths.ExportDir, dir)
+	}
+
+	backend, err := NewStorageBackend(paths)
+	if err != nil {
+		t.Fatalf("NewStorageBackend() error = %v", err)
+	}
+	if _, ok := backend.(*FileBackend); !ok {
+		t.Errorf("NewStorageBackend() = %T, want *FileBackend", backend)
+	}
+
+	empty := testutil.CreateTempDir(t)
+	if _, err := GetStoragePaths(empty); err == nil {
+		t.Error("GetStoragePaths() should return error for a directory without sessions")
+	}
+}
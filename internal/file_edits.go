@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// File edit actions, as recorded in FileEdit.Action
+const (
+	FileEditApply  = "apply"  // The agent applied an edit to an existing file
+	FileEditCreate = "create" // The agent created the file
+	FileEditAccept = "accept" // The user accepted the edit
+	FileEditReject = "reject" // The user rejected the edit
+)
+
+// cursorDiskKV key families recording file edits, besides the codeBlockDiff entries
+const (
+	// checkpointKeyPrefix keys the files a composer edited at a checkpoint:
+	// checkpointId:<composerId>:<checkpointId>
+	checkpointKeyPrefix = "checkpointId:"
+	// diffFateKeyPrefix keys what the user decided on an applied diff:
+	// codeBlockPartialInlineDiffFates:<composerId>:<diffId>
+	diffFateKeyPrefix = "codeBlockPartialInlineDiffFates:"
+)
+
+// FileEdit is a change to a file made or decided in a message
+type FileEdit struct {
+	FilePath string `json:"file_path"`
+	Action   string `json:"action"` // "apply", "create", "accept" or "reject"
+}
+
+// RawFileEdit is a file edit as loaded from storage, before it is matched to a message.
+// Edits from a checkpoint carry the checkpoint ID, matched to the bubble that recorded
+// it; decisions on a diff carry the diff ID, matched through the composer's code block
+// data, which also supplies the file when the decision does not.
+type RawFileEdit struct {
+	FileEdit
+	BubbleID     string
+	CheckpointID string
+	DiffID       string
+}
+
+// FileEditLoader is implemented by backends that record the file edits applied and
+// accepted in their sessions
+type FileEditLoader interface {
+	LoadFileEdits() (map[string][]RawFileEdit, error)
+}
+
+// LoadFileEdits loads the file edits of a backend, keyed by composer ID. Edits are
+// optional, so backends without a FileEditLoader, or failing to load them, have none.
+func LoadFileEdits(backend StorageBackend) map[string][]RawFileEdit {
+	loader, ok := backend.(FileEditLoader)
+	if !ok {
+		return nil
+	}
+	edits, err := loader.LoadFileEdits()
+	if err != nil {
+		LogWarn("Failed to load file edits: %v", err)
+		return nil
+	}
+	return edits
+}
+
+// LoadFileEdits loads the files edited at each checkpoint and the user's decisions on
+// applied diffs
+func (s *Storage) LoadFileEdits() (map[string][]RawFileEdit, error) {
+	edits := make(map[string][]RawFileEdit)
+
+	checkpoints, err := QueryCursorDiskKV(s.db, s.dbPath, checkpointKeyPrefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	for _, pair := range checkpoints {
+		parts := splitKey(pair.Key, checkpointKeyPrefix)
+		if len(parts) < 3 {
+			continue
+		}
+		checkpointEdits, err := ParseCheckpointEdits(parts[1], parts[2], pair.Value)
+		if err != nil {
+			LogDebug("Skipping checkpoint %s: %v", pair.Key, err)
+			continue
+		}
+		edits[parts[1]] = append(edits[parts[1]], checkpointEdits...)
+	}
+
+	fates, err := QueryCursorDiskKV(s.db, s.dbPath, diffFateKeyPrefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query diff fates: %w", err)
+	}
+	for _, pair := range fates {
+		parts := splitKey(pair.Key, diffFateKeyPrefix)
+		if len(parts) < 3 {
+			continue
+		}
+		edit, ok, err := ParseDiffFate(parts[2], pair.Value)
+		if err != nil {
+			LogDebug("Skipping diff fate %s: %v", pair.Key, err)
+			continue
+		}
+		if ok {
+			edits[parts[1]] = append(edits[parts[1]], edit)
+		}
+	}
+
+	return edits, nil
+}
+
+// LoadFileEdits loads the file edits of every backend that records them
+func (m *MultiBackend) LoadFileEdits() (map[string][]RawFileEdit, error) {
+	all := make(map[string][]RawFileEdit)
+	for _, b := range m.backends {
+		for composerID, edits := range LoadFileEdits(b) {
+			all[composerID] = append(all[composerID], edits...)
+		}
+	}
+	return all, nil
+}
+
+// checkpointValue is the part of a checkpoint value listing the files it edited
+type checkpointValue struct {
+	Files []struct {
+		URI            interface{} `json:"uri"`
+		IsNewlyCreated bool        `json:"isNewlyCreated"`
+	} `json:"files"`
+	NewlyCreatedFiles []struct {
+		URI interface{} `json:"uri"`
+	} `json:"newlyCreatedFiles"`
+}
+
+// ParseCheckpointEdits reads the files edited at a composer's checkpoint. A file listed
+// both as edited and as newly created is recorded once, as created.
+func ParseCheckpointEdits(composerID, checkpointID, value string) ([]RawFileEdit, error) {
+	var checkpoint checkpointValue
+	if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+		return nil, &ParseError{Source: BackendGlobalStorage, Key: checkpointKeyPrefix + composerID + ":" + checkpointID, Err: err}
+	}
+
+	actions := make(map[string]string)
+	var order []string
+	add := func(uri interface{}, action string) {
+		path := uriPath(uri)
+		if path == "" {
+			return
+		}
+		if _, seen := actions[path]; !seen {
+			order = append(order, path)
+		}
+		if actions[path] != FileEditCreate {
+			actions[path] = action
+		}
+	}
+	for _, file := range checkpoint.Files {
+		action := FileEditApply
+		if file.IsNewlyCreated {
+			action = FileEditCreate
+		}
+		add(file.URI, action)
+	}
+	for _, file := range checkpoint.NewlyCreatedFiles {
+		add(file.URI, FileEditCreate)
+	}
+
+	edits := make([]RawFileEdit, 0, len(order))
+	for _, path := range order {
+		edits = append(edits, RawFileEdit{
+			FileEdit:     FileEdit{FilePath: path, Action: actions[path]},
+			CheckpointID: checkpointID,
+		})
+	}
+	return edits, nil
+}
+
+// ParseDiffFate reads the user's decision on the hunks of an applied diff. Cursor records
+// a fate per hunk, as a list or as an object keyed by hunk index; the diff counts as
+// accepted when any hunk was, and as rejected when all decided hunks were. It reports
+// false when no hunk was decided yet.
+func ParseDiffFate(diffID, value string) (RawFileEdit, bool, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return RawFileEdit{}, false, &ParseError{Source: BackendGlobalStorage, Key: diffFateKeyPrefix + diffID, Err: err}
+	}
+
+	var entries []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		entries = v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			entries = append(entries, v[key])
+		}
+	}
+
+	edit := RawFileEdit{DiffID: diffID}
+	accepted, rejected := false, false
+	for _, entry := range entries {
+		fate := ""
+		switch e := entry.(type) {
+		case string:
+			fate = e
+		case map[string]interface{}:
+			fate = stringField(e, "fate")
+			if edit.FilePath == "" {
+				edit.FilePath = uriPath(e["uri"])
+			}
+		}
+		switch fate {
+		case "accepted", "accept":
+			accepted = true
+		case "rejected", "reject":
+			rejected = true
+		}
+	}
+
+	switch {
+	case accepted:
+		edit.Action = FileEditAccept
+	case rejected:
+		edit.Action = FileEditReject
+	default:
+		return RawFileEdit{}, false, nil
+	}
+	return edit, true, nil
+}
+
+// FilesChanged returns the files a session changed, sorted: those its diffs and file
+// edits touched, leaving out files whose every edit was rejected
+func FilesChanged(diffs []CodeDiff, edits []FileEdit) []string {
+	changed := make(map[string]bool)
+	mark := func(path string, rejected bool) {
+		if path == "" {
+			return
+		}
+		if !rejected {
+			changed[path] = true
+		} else if _, ok := changed[path]; !ok {
+			changed[path] = false
+		}
+	}
+	for _, diff := range diffs {
+		mark(diff.FilePath, diff.Status == "rejected")
+	}
+	for _, edit := range edits {
+		mark(edit.FilePath, edit.Action == FileEditReject)
+	}
+
+	var files []string
+	for path, ok := range changed {
+		if ok {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
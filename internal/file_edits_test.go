@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestParseCheckpointEdits(t *testing.T) {
+	value := `{
+		"files": [
+			{"uri": {"fsPath": "/repo/main.go"}},
+			{"uri": "file:///repo/new%20file.go", "isNewlyCreated": true},
+			{"uri": {}}
+		],
+		"newlyCreatedFiles": [{"uri": {"path": "/repo/main.go"}}]
+	}`
+
+	edits, err := ParseCheckpointEdits("composer1", "cp1", value)
+	if err != nil {
+		t.Fatalf("ParseCheckpointEdits() error = %v", err)
+	}
+	want := []RawFileEdit{
+		{FileEdit: FileEdit{FilePath: "/repo/main.go", Action: FileEditCreate}, CheckpointID: "cp1"},
+		{FileEdit: FileEdit{FilePath: "/repo/new file.go", Action: FileEditCreate}, CheckpointID: "cp1"},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("ParseCheckpointEdits() = %+v, want %+v", edits, want)
+	}
+
+	if _, err := ParseCheckpointEdits("composer1", "cp1", "not json"); err == nil {
+		t.Error("ParseCheckpointEdits() should fail on invalid JSON")
+	}
+}
+
+func TestParseDiffFate(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantAction string
+		wantPath   string
+		wantOK     bool
+	}{
+		{name: "accepted hunk", value: `[{"fate":"rejected"},{"fate":"accepted"}]`, wantAction: FileEditAccept, wantOK: true},
+		{name: "all rejected", value: `{"0":"rejected","1":{"fate":"rejected"}}`, wantAction: FileEditReject, wantOK: true},
+		{name: "file recorded", value: `[{"fate":"accepted","uri":"file:///repo/a.go"}]`, wantAction: FileEditAccept, wantPath: "/repo/a.go", wantOK: true},
+		{name: "undecided", value: `[{"fate":"pending"}]`},
+		{name: "empty", value: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit, ok, err := ParseDiffFate("diff1", tt.value)
+			if err != nil {
+				t.Fatalf("ParseDiffFate() error = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ParseDiffFate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if edit.Action != tt.wantAction || edit.FilePath != tt.wantPath || edit.DiffID != "diff1" {
+				t.Errorf("ParseDiffFate() = %+v, want %s of %q from diff1", edit, tt.wantAction, tt.wantPath)
+			}
+		})
+	}
+
+	if _, _, err := ParseDiffFate("diff1", "not json"); err == nil {
+		t.Error("ParseDiffFate() should fail on invalid JSON")
+	}
+}
+
+func TestFilesChanged(t *testing.T) {
+	diffs := []CodeDiff{
+		{FilePath: "/repo/b.go", Status: "accepted"},
+		{FilePath: "/repo/rejected.go", Status: "rejected"},
+		{FilePath: ""},
+	}
+	edits := []FileEdit{
+		{FilePath: "/repo/a.go", Action: FileEditApply},
+		{FilePath: "/repo/b.go", Action: FileEditReject},
+		{FilePath: "/repo/c.go", Action: FileEditReject},
+		{FilePath: "/repo/c.go", Action: FileEditAccept},
+	}
+
+	want := []string{"/repo/a.go", "/repo/b.go", "/repo/c.go"}
+	if got := FilesChanged(diffs, edits); !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesChanged() = %v, want %v", got, want)
+	}
+	if got := FilesChanged(nil, nil); got != nil {
+		t.Errorf("FilesChanged() = %v, want nil", got)
+	}
+}
+
+func TestStorage_LoadFileEdits(t *testing.T) {
+	db := testutil.CreateInMemoryDB(t)
+	defer func() { _ = db.Close() }()
+
+	testutil.InsertBubble(t, db, "checkpointId:composer1:cp1", `{"files":[{"uri":{"fsPath":"/repo/main.go"}}]}`)
+	testutil.InsertBubble(t, db, "checkpointId:composer1:broken", "not json")
+	testutil.InsertBubble(t, db, "checkpointId:invalid", `{"files":[]}`)
+	testutil.InsertBubble(t, db, "codeBlockPartialInlineDiffFates:composer1:diff1", `[{"fate":"accepted"}]`)
+	testutil.InsertBubble(t, db, "codeBlockPartialInlineDiffFates:composer2:diff2", `[]`)
+
+	edits, err := NewStorage(db).LoadFileEdits()
+	if err != nil {
+		t.Fatalf("LoadFileEdits() error = %v", err)
+	}
+	want := map[string][]RawFileEdit{
+		"composer1": {
+			{FileEdit: FileEdit{FilePath: "/repo/main.go", Action: FileEditApply}, CheckpointID: "cp1"},
+			{FileEdit: FileEdit{Action: FileEditAccept}, DiffID: "diff1"},
+		},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("LoadFileEdits() = %+v, want %+v", edits, want)
+	}
+}
+
+func TestLoadFileEdits_Unsupported(t *testing.T) {
+	if edits := LoadFileEdits(NewAgentStorage(nil)); edits != nil {
+		t.Errorf("LoadFileEdits() = %v, want nil for a backend without file edits", edits)
+	}
+}
@@ -0,0 +1,457 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FilterExpr is a parsed session filter expression, such as
+//
+//	workspace=="api" && messages>10 && created>"2024-06-01"
+//
+// Expressions compare session fields with literals and combine the comparisons with
+// &&, || and !, grouped with parentheses. They are evaluated against the cache index
+// entry of a session, so they work on listings that never load messages.
+type FilterExpr struct {
+	source string
+	root   filterNode
+}
+
+// filterFieldKind is the type of a field a filter expression can compare
+type filterFieldKind int
+
+const (
+	filterString filterFieldKind = iota
+	filterNumber
+	filterTime
+	filterList
+	filterBool
+)
+
+// filterField describes a session field available to filter expressions
+type filterField struct {
+	kind filterFieldKind
+	get  func(entry SessionIndexEntry) interface{}
+}
+
+// filterFields are the fields filter expressions can use, by name
+var filterFields = map[string]filterField{
+	"id":             {filterString, func(e SessionIndexEntry) interface{} { return e.ID }},
+	"name":           {filterString, func(e SessionIndexEntry) interface{} { return e.Name }},
+	"workspace":      {filterString, func(e SessionIndexEntry) interface{} { return e.Workspace }},
+	"branch":         {filterString, func(e SessionIndexEntry) interface{} { return e.Branch }},
+	"workspace_hash": {filterString, func(e SessionIndexEntry) interface{} { return e.WorkspaceHash }},
+	"messages":       {filterNumber, func(e SessionIndexEntry) interface{} { return float64(e.MessageCount) }},
+	"bytes":          {filterNumber, func(e SessionIndexEntry) interface{} { return float64(e.Bytes) }},
+	"tokens":         {filterNumber, func(e SessionIndexEntry) interface{} { return float64(e.EstimatedTokens) }},
+	"duration":       {filterNumber, func(e SessionIndexEntry) interface{} { return float64(e.DurationSeconds) }},
+	"tool_calls":     {filterNumber, func(e SessionIndexEntry) interface{} { return float64(e.ToolCalls) }},
+	"created":        {filterTime, func(e SessionIndexEntry) interface{} { return e.CreatedAt }},
+	"updated":        {filterTime, func(e SessionIndexEntry) interface{} { return e.UpdatedAt }},
+	"tags":           {filterList, func(e SessionIndexEntry) interface{} { return e.Tags }},
+	"generated":      {filterBool, func(e SessionIndexEntry) interface{} { return e.GeneratedName }},
+	"type":           {filterString, func(e SessionIndexEntry) interface{} { return e.sessionType() }},
+}
+
+// sessionType returns the type of the session, SessionTypeChat when it has none
+func (e SessionIndexEntry) sessionType() string {
+	if e.Type == "" {
+		return SessionTypeChat
+	}
+	return e.Type
+}
+
+// FilterFieldNames returns the names of the fields filter expressions can use, sorted
+func FilterFieldNames() []string {
+	names := make([]string, 0, len(filterFields))
+	for name := range filterFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFilterExpr parses a filter expression. Fields are id, name, workspace, branch,
+// workspace_hash and type (chat or inline) (strings), messages, bytes, tokens, duration (in seconds) and tool_calls
+// (numbers), created and updated (times), tags (matches if any tag does) and generated
+// (true for generated titles). Operators are ==, !=, <, <=, >, >= and ~, a
+// case-insensitive substring match. Times are compared with RFC3339 timestamps
+// or dates, which cover the whole day: created>"2024-06-01" means after June 1st.
+func ParseFilterExpr(source string) (*FilterExpr, error) {
+	tokens, err := tokenizeFilter(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", source, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", source, err)
+	}
+	return &FilterExpr{source: source, root: root}, nil
+}
+
+// String returns the expression as it was written
+func (f *FilterExpr) String() string {
+	return f.source
+}
+
+// Match reports whether a session's index entry satisfies the expression
+func (f *FilterExpr) Match(entry SessionIndexEntry) bool {
+	return f.root.eval(entry)
+}
+
+// MatchSession reports whether a session satisfies the expression
+func (f *FilterExpr) MatchSession(session *Session) bool {
+	return f.Match(NewSessionIndexEntry(session))
+}
+
+// FilterSessions returns the sessions matching the expression, keeping their order
+func (f *FilterExpr) FilterSessions(sessions []*Session) []*Session {
+	filtered := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if f.MatchSession(session) {
+			filtered = append(filtered, session)
+		}
+	}
+	return filtered
+}
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	eval(entry SessionIndexEntry) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+
+func (n filterAnd) eval(e SessionIndexEntry) bool { return n.left.eval(e) && n.right.eval(e) }
+
+type filterOr struct{ left, right filterNode }
+
+func (n filterOr) eval(e SessionIndexEntry) bool { return n.left.eval(e) || n.right.eval(e) }
+
+type filterNot struct{ operand filterNode }
+
+func (n filterNot) eval(e SessionIndexEntry) bool { return !n.operand.eval(e) }
+
+// filterCompare compares a field with a literal, already converted to the field's type
+type filterCompare struct {
+	field filterField
+	op    string
+	str   string
+	num   float64
+	from  time.Time // first instant of a time literal
+	to    time.Time // last instant of a time literal; equal to from for timestamps
+	flag  bool
+}
+
+func (n filterCompare) eval(e SessionIndexEntry) bool {
+	value := n.field.get(e)
+	switch n.field.kind {
+	case filterString:
+		return compareStrings(value.(string), n.op, n.str)
+	case filterNumber:
+		return compareOrdered(value.(float64), n.op, n.num)
+	case filterTime:
+		t, err := time.Parse(time.RFC3339, value.(string))
+		if err != nil {
+			return false // sessions without a time never match a time comparison
+		}
+		switch n.op {
+		case "<":
+			return t.Before(n.from)
+		case "<=":
+			return !t.After(n.to)
+		case ">":
+			return t.After(n.to)
+		case ">=":
+			return !t.Before(n.from)
+		}
+	case filterList:
+		// A list matches == and ~ if any element does, and != if no element is equal
+		op := n.op
+		if op == "!=" {
+			op = "=="
+		}
+		matched := false
+		for _, item := range value.([]string) {
+			if compareStrings(item, op, n.str) {
+				matched = true
+				break
+			}
+		}
+		return matched != (n.op == "!=")
+	case filterBool:
+		return (value.(bool) == n.flag) == (n.op == "==")
+	}
+	return false
+}
+
+// compareStrings applies a comparison operator to two strings; ~ is a case-insensitive
+// substring match
+func compareStrings(a, op, b string) bool {
+	if op == "~" {
+		return strings.Contains(strings.ToLower(a), strings.ToLower(b))
+	}
+	return compareOrdered(a, op, b)
+}
+
+func compareOrdered[T string | float64](a T, op string, b T) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// filterOperators lists the comparison operators each field kind accepts
+var filterOperators = map[filterFieldKind][]string{
+	filterString: {"==", "!=", "<", "<=", ">", ">=", "~"},
+	filterNumber: {"==", "!=", "<", "<=", ">", ">="},
+	filterTime:   {"<", "<=", ">", ">="},
+	filterList:   {"==", "!=", "~"},
+	filterBool:   {"==", "!="},
+}
+
+// Filter expression tokens
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func (t filterToken) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.pos+1)
+}
+
+// tokenizeFilter splits a filter expression into tokens
+func tokenizeFilter(source string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		two := ""
+		if i+1 < len(runes) {
+			two = string(runes[i : i+2])
+		}
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case two == "&&":
+			tokens = append(tokens, filterToken{tokenAnd, two, i})
+			i += 2
+		case two == "||":
+			tokens = append(tokens, filterToken{tokenOr, two, i})
+			i += 2
+		case two == "==" || two == "!=" || two == "<=" || two == ">=":
+			tokens = append(tokens, filterToken{tokenOp, two, i})
+			i += 2
+		case r == '<' || r == '>' || r == '~':
+			tokens = append(tokens, filterToken{tokenOp, string(r), i})
+			i++
+		case r == '!':
+			tokens = append(tokens, filterToken{tokenNot, "!", i})
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{tokenRParen, ")", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, filterToken{tokenString, b.String(), start})
+		case unicode.IsDigit(r) || r == '-' || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || (i == start && runes[i] == '-')) {
+				i++
+			}
+			tokens = append(tokens, filterToken{tokenNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, filterToken{tokenIdent, string(runes[start:i]), start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, i+1)
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// filterParser is a recursive descent parser over filter tokens:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field [ operator literal ]
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch t := p.next(); t.kind {
+	case tokenNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{operand}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected \")\", got %s", closing)
+		}
+		return inner, nil
+	case tokenIdent:
+		return p.parseComparison(t)
+	default:
+		return nil, fmt.Errorf("expected a field, got %s", t)
+	}
+}
+
+func (p *filterParser) parseComparison(name filterToken) (filterNode, error) {
+	field, ok := filterFields[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q (expected one of %s)", name.text, strings.Join(FilterFieldNames(), ", "))
+	}
+
+	// A bool field on its own is true when set
+	if p.peek().kind != tokenOp {
+		if field.kind == filterBool {
+			return filterCompare{field: field, op: "==", flag: true}, nil
+		}
+		return nil, fmt.Errorf("expected an operator after %q, got %s", name.text, p.peek())
+	}
+
+	op := p.next()
+	if !containsString(filterOperators[field.kind], op.text) {
+		return nil, fmt.Errorf("%q does not support %s (use %s)", name.text, op.text, strings.Join(filterOperators[field.kind], " "))
+	}
+	node := filterCompare{field: field, op: op.text}
+
+	literal := p.next()
+	switch field.kind {
+	case filterString, filterList:
+		if literal.kind != tokenString {
+			return nil, fmt.Errorf("%q compares with a quoted string, got %s", name.text, literal)
+		}
+		node.str = literal.text
+	case filterNumber:
+		n, err := strconv.ParseFloat(literal.text, 64)
+		if literal.kind != tokenNumber || err != nil {
+			return nil, fmt.Errorf("%q compares with a number, got %s", name.text, literal)
+		}
+		node.num = n
+	case filterTime:
+		if literal.kind != tokenString {
+			return nil, fmt.Errorf("%q compares with a quoted date or RFC3339 time, got %s", name.text, literal)
+		}
+		from, err := parseFilterTime(literal.text, false)
+		if err != nil {
+			return nil, fmt.Errorf("%q compares with a date (YYYY-MM-DD) or RFC3339 time, got %s", name.text, literal)
+		}
+		to, _ := parseFilterTime(literal.text, true)
+		node.from, node.to = from, to
+	case filterBool:
+		if literal.kind != tokenIdent || (literal.text != "true" && literal.text != "false") {
+			return nil, fmt.Errorf("%q compares with true or false, got %s", name.text, literal)
+		}
+		node.flag = literal.text == "true"
+	}
+	return node, nil
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterExpr_Match(t *testing.T) {
+	entry := SessionIndexEntry{
+		ID:              "abc123",
+		Name:            "Refactor API handlers",
+		Workspace:       "api",
+		Branch:          "feature/auth",
+		MessageCount:    12,
+		CreatedAt:       "2024-06-01T15:00:00Z",
+		UpdatedAt:       "2024-06-03T09:00:00Z",
+		Tags:            []string{"backend", "review"},
+		GeneratedName:   true,
+		Bytes:           2048,
+		EstimatedTokens: 512,
+		DurationSeconds: 3600,
+		ToolCalls:       4,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`workspace=="api" && messages>10 && created>"2024-05-31"`, true},
+		{`workspace == "web"`, false},
+		{`workspace != "web"`, true},
+		{`messages >= 12 && messages <= 12 && messages == 12`, true},
+		{`messages < 12 || messages != 12`, false},
+		{`name ~ "api"`, true},
+		{`name ~ 'HANDLERS'`, true},
+		{`branch == "feature/auth"`, true},
+		{`id < "b"`, true},
+		{`tags == "review"`, true},
+		{`tags == "frontend"`, false},
+		{`tags != "frontend"`, true},
+		{`tags != "review"`, false},
+		{`tags ~ "back"`, true},
+		{`generated`, true},
+		{`!generated`, false},
+		{`generated == false`, false},
+		{`generated != false`, true},
+		// Dates cover the whole day
+		{`created > "2024-06-01"`, false},
+		{`created >= "2024-06-01"`, true},
+		{`created <= "2024-06-01"`, true},
+		{`created < "2024-06-01"`, false},
+		{`created < "2024-06-01T16:00:00Z"`, true},
+		{`updated > "2024-06-02"`, true},
+		{`!(workspace == "api" && messages > 100) && (tags == "x" || tags == "backend")`, true},
+		{`workspace == "web" || workspace == "api" && messages > 100`, false},
+		{`bytes > 1024 && tokens < 1000`, true},
+		{`duration >= 3600 && tool_calls == 4`, true},
+		{`tool_calls > 10 || duration < 60`, false},
+		// Sessions without a type are chats
+		{`type == "chat"`, true},
+		{`type == "inline"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseFilterExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilterExpr() error = %v", err)
+			}
+			if got := expr.Match(entry); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExpr_MissingTime(t *testing.T) {
+	expr, err := ParseFilterExpr(`created < "2030-01-01" || created > "2030-01-01"`)
+	if err != nil {
+		t.Fatalf("ParseFilterExpr() error = %v", err)
+	}
+	if expr.Match(SessionIndexEntry{ID: "no-time"}) {
+		t.Error("a session without a creation time should not match a time comparison")
+	}
+}
+
+func TestParseFilterExpr_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`color == "red"`, "unknown field"},
+		{`messages > "ten"`, "compares with a number"},
+		{`workspace == api`, "quoted string"},
+		{`created == "2024-06-01"`, "does not support =="},
+		{`created > "June"`, "date"},
+		{`tags > "a"`, "does not support >"},
+		{`generated == yes`, "true or false"},
+		{`workspace`, "expected an operator"},
+		{`(messages > 1`, `expected ")"`},
+		{`messages > 1 messages`, "unexpected"},
+		{`name == "open`, "unterminated string"},
+		{`messages > 1 & name == "x"`, "unexpected"},
+		{``, "expected a field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseFilterExpr(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseFilterExpr() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExpr_FilterSessions(t *testing.T) {
+	small := CreateTestSessionWithMessages("small", []Message{{Actor: "user", Content: "hi"}})
+	large := CreateTestSession("large")
+	large.Metadata.Tags = []string{"keep"}
+
+	expr, err := ParseFilterExpr(`messages > 1 && tags == "keep"`)
+	if err != nil {
+		t.Fatalf("ParseFilterExpr() error = %v", err)
+	}
+	got := expr.FilterSessions([]*Session{small, large})
+	if len(got) != 1 || got[0].ID != "large" {
+		t.Errorf("FilterSessions() = %v, want only large", got)
+	}
+}
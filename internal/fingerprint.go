@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SQLite file layout read by StorageFingerprint
+const (
+	sqliteHeaderSize = 100
+	walHeaderSize    = 32
+	walFrameHeader   = 24
+)
+
+// StorageFingerprint returns a SHA-256 hash summarizing the state of a database or storage
+// directory, so the cache recognizes a copy of the same storage whatever its path, without
+// hashing its content. A SQLite database is summarized by its size, the header fields
+// SQLite updates when it changes (file change counter, page count, freelist, schema
+// cookie), and the highest rowid and row count of each of its tables, since in WAL mode
+// the change counter is not bumped on commit and a checkpoint can leave the header as it
+// was. Its write-ahead log, which holds the changes not yet checkpointed into it, is
+// summarized by its size, its checkpoint sequence and salts, and the cumulative checksum
+// of its last frame. A
+// directory, such as the cursor-agent storage or a directory of exports, is summarized
+// from the names of its files and the summary of each; a file that is not a SQLite
+// database is summarized by its size and modification time.
+func StorageFingerprint(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if !info.IsDir() {
+		if err := summarizeFile(h, path, info); err != nil {
+			return "", err
+		}
+	} else {
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			// Shared memory files only coordinate readers and writers, and write-ahead
+			// logs are summarized with their database
+			if strings.HasSuffix(rel, "-shm") || strings.HasSuffix(rel, "-wal") {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+			return summarizeFile(h, file, info)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// summarizeFile writes the summary of a file into h: the header of a SQLite database and
+// its write-ahead log, or else the size and modification time of the file
+func summarizeFile(h io.Writer, path string, info os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, sqliteHeaderSize)
+	if n, err := io.ReadFull(f, header); err != nil || !bytes.HasPrefix(header[:n], sqliteHeaderMagic) {
+		_, _ = fmt.Fprintf(h, "file %d %d\x00", info.Size(), info.ModTime().UnixNano())
+		return nil
+	}
+
+	// Change counter and page count (24-31), freelist trunk page and count (32-39),
+	// schema cookie (40-43) and the version the change counter is valid for (92-95)
+	_, _ = fmt.Fprintf(h, "sqlite %d\x00", info.Size())
+	_, _ = h.Write(header[24:44])
+	_, _ = h.Write(header[92:96])
+
+	if err := summarizeTables(h, path); err != nil {
+		return err
+	}
+	if err := summarizeWAL(h, path+"-wal"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// summarizeTables writes the highest rowid and the row count of each table of a database
+// into h. Both are read from the b-trees without reading the values, and change with
+// every row inserted or deleted, including rows replaced under the same key.
+func summarizeTables(h io.Writer, path string) error {
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	var tables []string
+	err = retryBusy(path, func() error {
+		tables = nil
+		rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			tables = append(tables, name)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list tables of %s: %w", path, err)
+	}
+
+	for _, table := range tables {
+		var maxRowID sql.NullInt64
+		var count int64
+		quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+		err := retryBusy(path, func() error {
+			return db.QueryRow("SELECT max(rowid), count(*) FROM "+quoted).Scan(&maxRowID, &count)
+		})
+		if err != nil {
+			// Tables without rowids are summarized by their row count alone
+			if err := retryBusy(path, func() error {
+				return db.QueryRow("SELECT count(*) FROM " + quoted).Scan(&count)
+			}); err != nil {
+				return fmt.Errorf("failed to summarize table %s of %s: %w", table, path, err)
+			}
+		}
+		_, _ = fmt.Fprintf(h, "table %s %d %d\x00", table, maxRowID.Int64, count)
+	}
+	return nil
+}
+
+// summarizeWAL writes the size, checkpoint sequence and salts of a write-ahead log into h,
+// with the checksum of its last frame, which covers every frame before it
+func summarizeWAL(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(h, "wal %d\x00", info.Size())
+
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		// An empty or partly written log has no frames yet
+		return nil
+	}
+	_, _ = h.Write(header[12:24])
+
+	pageSize := int64(binary.BigEndian.Uint32(header[8:12]))
+	frameSize := walFrameHeader + pageSize
+	frames := (info.Size() - walHeaderSize) / frameSize
+	if pageSize == 0 || frames <= 0 {
+		return nil
+	}
+	frame := make([]byte, walFrameHeader)
+	if _, err := f.ReadAt(frame, walHeaderSize+(frames-1)*frameSize); err != nil {
+		return nil
+	}
+	_, _ = h.Write(frame[16:24])
+	return nil
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestStorageFingerprint_File(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+	dbPath := filepath.Join(dir, "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	fingerprint, err := StorageFingerprint(dbPath)
+	if err != nil {
+		t.Fatalf("StorageFingerprint() error = %v", err)
+	}
+	if !strings.HasPrefix(fingerprint, "sha256:") {
+		t.Errorf("StorageFingerprint() = %q, want a sha256: prefix", fingerprint)
+	}
+
+	// A copy elsewhere, modified at another time, has the same fingerprint
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(testutil.CreateTempDir(t), "copy.vscdb")
+	writeFile(t, copyPath, string(data))
+	if err := os.Chtimes(copyPath, time.Now().Add(time.Hour), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := StorageFingerprint(copyPath); got != fingerprint {
+		t.Errorf("StorageFingerprint() of a copy = %q, want %q", got, fingerprint)
+	}
+
+	// A write bumps the file change counter
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE cursorDiskKV SET value = 'changed' WHERE key = 'composerData:composer1'"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	updated, _ := StorageFingerprint(dbPath)
+	if updated == fingerprint {
+		t.Error("StorageFingerprint() did not change with a write to the database")
+	}
+
+	// Frames added to the write-ahead log, or a restarted log, change the fingerprint
+	wal := walHeader(4096, 1, 0x1111)
+	writeFile(t, dbPath+"-wal", string(wal))
+	withWAL, _ := StorageFingerprint(dbPath)
+	if withWAL == updated {
+		t.Error("StorageFingerprint() did not change with the write-ahead log")
+	}
+	frame := make([]byte, walFrameHeader+4096)
+	copy(frame[16:24], "checksum")
+	writeFile(t, dbPath+"-wal", string(append(append([]byte{}, wal...), frame...)))
+	withFrame, _ := StorageFingerprint(dbPath)
+	if withFrame == withWAL {
+		t.Error("StorageFingerprint() did not change with a frame added to the write-ahead log")
+	}
+	writeFile(t, dbPath+"-wal", string(append(walHeader(4096, 2, 0x2222), frame...)))
+	if got, _ := StorageFingerprint(dbPath); got == withFrame {
+		t.Error("StorageFingerprint() did not change with a restarted write-ahead log")
+	}
+
+	if _, err := StorageFingerprint(filepath.Join(dir, "missing.vscdb")); err == nil {
+		t.Error("StorageFingerprint() of a missing database should fail")
+	}
+}
+
+func TestStorageFingerprint_CheckpointedWAL(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "store.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"PRAGMA journal_mode=WAL",
+		"CREATE TABLE blobs (id TEXT PRIMARY KEY, data BLOB)",
+		"INSERT INTO blobs (id, data) VALUES ('a', 'one')",
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := StorageFingerprint(dbPath)
+	if err != nil {
+		t.Fatalf("StorageFingerprint() error = %v", err)
+	}
+
+	// A commit in WAL mode leaves the change counter alone; once the log is checkpointed
+	// and truncated, only the rows tell the database changed
+	for _, stmt := range []string{
+		"INSERT INTO blobs (id, data) VALUES ('b', 'two')",
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := StorageFingerprint(dbPath); got == before {
+		t.Error("StorageFingerprint() did not change with rows checkpointed from the write-ahead log")
+	}
+}
+
+// walHeader returns a write-ahead log header with the given page size, checkpoint
+// sequence and salt
+func walHeader(pageSize, checkpoint, salt uint32) []byte {
+	header := make([]byte, walHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], 0x377f0682)
+	binary.BigEndian.PutUint32(header[4:8], 3007000)
+	binary.BigEndian.PutUint32(header[8:12], pageSize)
+	binary.BigEndian.PutUint32(header[12:16], checkpoint)
+	binary.BigEndian.PutUint32(header[16:20], salt)
+	binary.BigEndian.PutUint32(header[20:24], salt+1)
+	return header
+}
+
+func TestStorageFingerprint_Directory(t *testing.T) {
+	modTime := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fingerprint := func(files map[string]string) string {
+		t.Helper()
+		dir := testutil.CreateTempDir(t)
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := StorageFingerprint(dir)
+		if err != nil {
+			t.Fatalf("StorageFingerprint() error = %v", err)
+		}
+		return got
+	}
+
+	base := fingerprint(map[string]string{"a/session_a.json": "one", "b/session_b.json": "two"})
+	if got := fingerprint(map[string]string{"a/session_a.json": "one", "b/session_b.json": "two", "b/store.db-shm": "shared"}); got != base {
+		t.Errorf("StorageFingerprint() changed with a shared memory file")
+	}
+	if got := fingerprint(map[string]string{"a/session_a.json": "one", "c/session_b.json": "two"}); got == base {
+		t.Errorf("StorageFingerprint() did not change with a renamed file")
+	}
+	if got := fingerprint(map[string]string{"a/session_a.json": "one", "b/session_b.json": "three"}); got == base {
+		t.Errorf("StorageFingerprint() did not change with modified content")
+	}
+
+	// Files that are not databases are summarized by size and modification time
+	dir := testutil.CreateTempDir(t)
+	writeFile(t, filepath.Join(dir, "session_a.json"), "{}")
+	before, _ := StorageFingerprint(dir)
+	if err := os.Chtimes(filepath.Join(dir, "session_a.json"), time.Now().Add(time.Hour), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := StorageFingerprint(dir); got == before {
+		t.Errorf("StorageFingerprint() did not change with a modified export")
+	}
+
+	// Databases are summarized by their header, so a copy of agent storage matches
+	agent := testutil.CreateTempDir(t)
+	testutil.CreateSQLiteFixture(t, filepath.Join(agent, "ws", "chat", "store.db"))
+	data, err := os.ReadFile(filepath.Join(agent, "ws", "chat", "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentCopy := testutil.CreateTempDir(t)
+	writeFile(t, filepath.Join(agentCopy, "ws", "chat", "store.db"), string(data))
+	if err := os.Chtimes(filepath.Join(agentCopy, "ws", "chat", "store.db"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	original, _ := StorageFingerprint(agent)
+	if got, _ := StorageFingerprint(agentCopy); got != original {
+		t.Errorf("StorageFingerprint() of a copy of agent storage = %q, want %q", got, original)
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// TestGeneratedFixture_Loads reads the storage gen-fixture writes for both backends end to
+// end, so the generator stays in step with the readers it is meant to exercise
+func TestGeneratedFixture_Loads(t *testing.T) {
+	const sessions, messages = 4, 12
+	result, err := testutil.GenerateFixture(t.TempDir(), testutil.FixtureOptions{Sessions: sessions, Messages: messages, Seed: 3})
+	if err != nil {
+		t.Fatalf("GenerateFixture() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		location string
+		want     int
+	}{
+		{"desktop", result.GlobalStorage, result.DesktopSessions},
+		{"agent", result.AgentStorage, result.AgentSessions},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := GetStoragePathsList([]string{tt.location})
+			if err != nil {
+				t.Fatalf("GetStoragePathsList() error = %v", err)
+			}
+			backend, err := NewStorageBackendForPaths(paths)
+			if err != nil {
+				t.Fatalf("NewStorageBackendForPaths() error = %v", err)
+			}
+			conversations, err := ReconstructConversations(backend)
+			if err != nil {
+				t.Fatalf("ReconstructConversations() error = %v", err)
+			}
+			if len(conversations) != tt.want {
+				t.Fatalf("ReconstructConversations() = %d conversation(s), want %d", len(conversations), tt.want)
+			}
+			for _, conv := range conversations {
+				if len(conv.Messages) != messages {
+					t.Errorf("conversation %s has %d message(s), want %d", conv.ComposerID, len(conv.Messages), messages)
+				}
+				if conv.Name == "" {
+					t.Errorf("conversation %s has no name", conv.ComposerID)
+				}
+			}
+		})
+	}
+
+	// Both backends combined, as when several --storage paths are given
+	paths, err := GetStoragePathsList([]string{result.GlobalStorage, result.AgentStorage})
+	if err != nil {
+		t.Fatalf("GetStoragePathsList() error = %v", err)
+	}
+	backend, err := NewStorageBackendForPaths(paths)
+	if err != nil {
+		t.Fatalf("NewStorageBackendForPaths() error = %v", err)
+	}
+	conversations, err := ReconstructConversations(backend)
+	if err != nil {
+		t.Fatalf("ReconstructConversations() error = %v", err)
+	}
+	if len(conversations) != sessions {
+		t.Errorf("ReconstructConversations() of both backends = %d conversation(s), want %d", len(conversations), sessions)
+	}
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitInfo is the state of the repository a session worked in, parsed from the git status
+// Cursor attaches to message contexts
+type GitInfo struct {
+	Branch     string   `json:"branch,omitempty"`
+	Commit     string   `json:"commit,omitempty"`
+	DirtyFiles []string `json:"dirty_files,omitempty"` // Modified, staged and untracked files
+}
+
+// GetBranch returns the branch, or "" if g is nil
+func (g *GitInfo) GetBranch() string {
+	if g == nil {
+		return ""
+	}
+	return g.Branch
+}
+
+var (
+	// commitPattern matches an abbreviated or full commit hash
+	commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	// longStatusFile matches a file line of `git status` in its default long format
+	longStatusFile = regexp.MustCompile(`^(?:modified|new file|deleted|renamed|copied|typechange|both modified|both added|both deleted|added by us|added by them|deleted by us|deleted by them):\s+(.+)$`)
+)
+
+// ParseGitStatus extracts the branch, commit and changed files from git status output. It
+// understands the long format, `--short --branch` and `--porcelain=v2 --branch`. It
+// returns nil if raw holds none of them.
+func ParseGitStatus(raw string) *GitInfo {
+	info := &GitInfo{}
+	seen := make(map[string]bool)
+	addFile := func(path string) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		if path != "" && !seen[path] {
+			seen[path] = true
+			info.DirtyFiles = append(info.DirtyFiles, path)
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "On branch "):
+			info.Branch = strings.TrimPrefix(trimmed, "On branch ")
+		case strings.HasPrefix(trimmed, "HEAD detached at "), strings.HasPrefix(trimmed, "HEAD detached from "):
+			if ref := trimmed[strings.LastIndex(trimmed, " ")+1:]; commitPattern.MatchString(ref) {
+				info.Commit = ref
+			}
+		case strings.HasPrefix(trimmed, "commit "):
+			if fields := strings.Fields(trimmed); info.Commit == "" && commitPattern.MatchString(fields[1]) {
+				info.Commit = fields[1]
+			}
+		case strings.HasPrefix(line, "## "):
+			info.Branch = parseShortBranch(line[3:])
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				info.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); commitPattern.MatchString(oid) {
+				info.Commit = oid
+			}
+		case strings.HasPrefix(line, "\t"):
+			// Long format: "modified:   path" in the change sections, bare paths for untracked files
+			file := trimmed
+			if m := longStatusFile.FindStringSubmatch(trimmed); m != nil {
+				file = m[1]
+			}
+			addFile(renamedPath(file))
+		case strings.HasPrefix(line, "1 "):
+			if fields := strings.SplitN(line, " ", 9); len(fields) == 9 {
+				addFile(fields[8])
+			}
+		case strings.HasPrefix(line, "2 "):
+			if fields := strings.SplitN(line, " ", 10); len(fields) == 10 {
+				path, _, _ := strings.Cut(fields[9], "\t")
+				addFile(path)
+			}
+		case strings.HasPrefix(line, "u "):
+			if fields := strings.SplitN(line, " ", 11); len(fields) == 11 {
+				addFile(fields[10])
+			}
+		case strings.HasPrefix(line, "? "):
+			addFile(line[2:])
+		case len(line) > 3 && line[2] == ' ' && line[:2] != "  " && isShortStatusCode(line[0]) && isShortStatusCode(line[1]):
+			if line[:2] != "!!" { // ignored files are not changes
+				addFile(renamedPath(line[3:]))
+			}
+		}
+	}
+
+	if info.Branch == "" && info.Commit == "" && len(info.DirtyFiles) == 0 {
+		return nil
+	}
+	return info
+}
+
+// parseShortBranch returns the branch from the header of `git status --short --branch`,
+// such as "main...origin/main [ahead 1]" or "No commits yet on main"
+func parseShortBranch(header string) string {
+	for _, prefix := range []string{"No commits yet on ", "Initial commit on "} {
+		if strings.HasPrefix(header, prefix) {
+			return strings.TrimPrefix(header, prefix)
+		}
+	}
+	if strings.HasPrefix(header, "HEAD (no branch)") {
+		return ""
+	}
+	branch, _, _ := strings.Cut(header, "...")
+	branch, _, _ = strings.Cut(branch, " ")
+	return branch
+}
+
+// renamedPath returns the new path of a "old -> new" rename, or path unchanged
+func renamedPath(path string) string {
+	if _, to, ok := strings.Cut(path, " -> "); ok {
+		return to
+	}
+	return path
+}
+
+// isShortStatusCode reports whether c is a status letter of `git status --short`
+func isShortStatusCode(c byte) bool {
+	return strings.IndexByte(" MTADRCU?!", c) >= 0
+}
+
+// sessionGitInfo returns the git state recorded with the latest message of a conversation
+// that has one
+func sessionGitInfo(conv *ReconstructedConversation) *GitInfo {
+	for i := len(conv.Messages) - 1; i >= 0; i-- {
+		if ctx := conv.Messages[i].Context; ctx != nil && ctx.GitStatusRaw != "" {
+			if info := ParseGitStatus(ctx.GitStatusRaw); info != nil {
+				return info
+			}
+		}
+	}
+	return nil
+}
+
+// gitStatusText renders info as `git status --porcelain=v2 --branch` output, so it can be
+// stored in a message context and parsed back by ParseGitStatus
+func gitStatusText(info *GitInfo) string {
+	var b strings.Builder
+	if info.Commit != "" {
+		b.WriteString("# branch.oid " + info.Commit + "\n")
+	}
+	if info.Branch != "" {
+		b.WriteString("# branch.head " + info.Branch + "\n")
+	}
+	for _, file := range info.DirtyFiles {
+		b.WriteString("? " + file + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *GitInfo
+	}{
+		{
+			name: "long format",
+			raw: "On branch feature/login\nYour branch is ahead of 'origin/feature/login' by 1 commit.\n\n" +
+				"Changes to be committed:\n  (use \"git restore --staged <file>...\" to unstage)\n\tnew file:   auth.go\n\trenamed:    old.go -> new.go\n\n" +
+				"Changes not staged for commit:\n\tmodified:   main.go\n\n" +
+				"Untracked files:\n  (use \"git add <file>...\" to include in what will be committed)\n\tnotes.txt\n",
+			want: &GitInfo{Branch: "feature/login", DirtyFiles: []string{"auth.go", "new.go", "main.go", "notes.txt"}},
+		},
+		{
+			name: "detached head",
+			raw:  "HEAD detached at 9fceb02\nnothing to commit, working tree clean\n",
+			want: &GitInfo{Commit: "9fceb02"},
+		},
+		{
+			name: "short format",
+			raw:  "## main...origin/main [ahead 2]\n M cmd/list.go\nA  internal/git.go\nR  a.go -> b.go\n?? \"with space.txt\"\n!! build/\n",
+			want: &GitInfo{Branch: "main", DirtyFiles: []string{"cmd/list.go", "internal/git.go", "b.go", "with space.txt"}},
+		},
+		{
+			name: "short format before the first commit",
+			raw:  "## No commits yet on trunk\n",
+			want: &GitInfo{Branch: "trunk"},
+		},
+		{
+			name: "porcelain v2",
+			raw: "# branch.oid e69de29bb2d1d6434b8b29ae775ad8c2e48c5391\n# branch.head release/1.2\n# branch.upstream origin/release/1.2\n" +
+				"1 .M N... 100644 100644 100644 e69de29 e69de29 go.mod\n" +
+				"2 R. N... 100644 100644 100644 e69de29 e69de29 R100 new name.go\told.go\n" +
+				"? scratch.md\n",
+			want: &GitInfo{Branch: "release/1.2", Commit: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391", DirtyFiles: []string{"go.mod", "new name.go", "scratch.md"}},
+		},
+		{
+			name: "not git status",
+			raw:  "fatal: not a git repository (or any of the parent directories): .git\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseGitStatus(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitStatusText(t *testing.T) {
+	info := &GitInfo{Branch: "main", Commit: "9fceb02", DirtyFiles: []string{"a.go", "b c.go"}}
+	if got := ParseGitStatus(gitStatusText(info)); !reflect.DeepEqual(got, info) {
+		t.Errorf("ParseGitStatus(gitStatusText()) = %+v, want %+v", got, info)
+	}
+}
+
+func TestNormalizeConversation_Git(t *testing.T) {
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: 1, Text: "Start", Context: &MessageContext{GitStatusRaw: "On branch main\n"}},
+			{Type: 2, Text: "Switched", Context: &MessageContext{GitStatusRaw: "## feature/x\n M app.go\n"}},
+			{Type: 1, Text: "Thanks"},
+		},
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	want := &GitInfo{Branch: "feature/x", DirtyFiles: []string{"app.go"}}
+	if !reflect.DeepEqual(session.Metadata.Git, want) {
+		t.Errorf("Metadata.Git = %+v, want the latest recorded state %+v", session.Metadata.Git, want)
+	}
+	if got := NewSessionIndexEntry(session).Branch; got != "feature/x" {
+		t.Errorf("index entry branch = %q, want feature/x", got)
+	}
+}
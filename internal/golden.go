@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fields a GoldenComparison can ignore
+const (
+	GoldenIgnoreTimestamps = "timestamps" // Message timestamps and session creation and update times
+	GoldenIgnoreIDs        = "ids"        // Session and composer IDs
+	GoldenIgnoreNames      = "names"      // Session names
+	GoldenIgnoreWorkspace  = "workspace"  // Session workspace
+	GoldenIgnoreActors     = "actors"     // Message actors
+)
+
+// goldenIgnoreFields lists the fields a GoldenComparison can ignore
+var goldenIgnoreFields = []string{GoldenIgnoreTimestamps, GoldenIgnoreIDs, GoldenIgnoreNames, GoldenIgnoreWorkspace, GoldenIgnoreActors}
+
+// GoldenIgnoreFields returns the fields a GoldenComparison can ignore, sorted
+func GoldenIgnoreFields() []string {
+	fields := append([]string(nil), goldenIgnoreFields...)
+	sort.Strings(fields)
+	return fields
+}
+
+// GoldenComparison compares sessions against a golden transcript for regression tests of
+// agent runs. Message texts are compared with whitespace normalized and must be at least
+// Threshold similar; every other field that is not ignored must be equal.
+type GoldenComparison struct {
+	Ignore    map[string]bool // Fields left out of the comparison
+	Threshold float64         // Least similarity (0-1) of a message's text to the golden one; 1 requires equal text
+}
+
+// GoldenMismatch is a difference between a session and its golden transcript
+type GoldenMismatch struct {
+	Message    int     // Number of the message, from 1; 0 for a session field
+	Field      string  // id, name, workspace, created_at, updated_at, messages, actor, timestamp or text
+	Want       string  // Value in the golden transcript
+	Got        string  // Value in the session
+	Similarity float64 // Similarity of the texts, for a text mismatch
+}
+
+// NewGoldenComparison builds a comparison from the --ignore and --threshold flag values
+func NewGoldenComparison(ignore []string, threshold float64) (GoldenComparison, error) {
+	if threshold < 0 || threshold > 1 {
+		return GoldenComparison{}, fmt.Errorf("invalid --threshold %g (expected 0 to 1)", threshold)
+	}
+	c := GoldenComparison{Ignore: make(map[string]bool), Threshold: threshold}
+	for _, field := range ignore {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(goldenIgnoreFields, field) {
+			return GoldenComparison{}, fmt.Errorf("unknown --ignore field %q (expected %s)", field, strings.Join(GoldenIgnoreFields(), ", "))
+		}
+		c.Ignore[field] = true
+	}
+	return c, nil
+}
+
+// Compare returns the differences between a session and its golden transcript, in the
+// order of the session's fields and messages. Session fields the golden transcript
+// leaves empty, such as the name of a JSONL export, are not compared. Messages are
+// compared by position; when the number of messages differs, the messages both have are
+// still compared.
+func (c GoldenComparison) Compare(golden, session *Session) []GoldenMismatch {
+	var mismatches []GoldenMismatch
+	field := func(message int, name, want, got string) {
+		if message == 0 && want == "" {
+			return
+		}
+		if want != got {
+			mismatches = append(mismatches, GoldenMismatch{Message: message, Field: name, Want: want, Got: got})
+		}
+	}
+
+	if !c.Ignore[GoldenIgnoreIDs] {
+		field(0, "id", golden.ID, session.ID)
+	}
+	if !c.Ignore[GoldenIgnoreNames] {
+		field(0, "name", golden.Metadata.Name, session.Metadata.Name)
+	}
+	if !c.Ignore[GoldenIgnoreWorkspace] {
+		field(0, "workspace", golden.Workspace, session.Workspace)
+	}
+	if !c.Ignore[GoldenIgnoreTimestamps] {
+		field(0, "created_at", UTCTimestamp(golden.Metadata.CreatedAt), UTCTimestamp(session.Metadata.CreatedAt))
+		field(0, "updated_at", UTCTimestamp(golden.Metadata.UpdatedAt), UTCTimestamp(session.Metadata.UpdatedAt))
+	}
+	field(0, "messages", strconv.Itoa(len(golden.Messages)), strconv.Itoa(len(session.Messages)))
+
+	for i := 0; i < len(golden.Messages) && i < len(session.Messages); i++ {
+		want, got := golden.Messages[i], session.Messages[i]
+		if !c.Ignore[GoldenIgnoreActors] {
+			field(i+1, "actor", want.Actor, got.Actor)
+		}
+		if !c.Ignore[GoldenIgnoreTimestamps] {
+			field(i+1, "timestamp", UTCTimestamp(want.Timestamp), UTCTimestamp(got.Timestamp))
+		}
+		if similarity := TextSimilarity(want.Content, got.Content); similarity < c.Threshold {
+			mismatches = append(mismatches, GoldenMismatch{
+				Message:    i + 1,
+				Field:      "text",
+				Want:       want.Content,
+				Got:        got.Content,
+				Similarity: similarity,
+			})
+		}
+	}
+	return mismatches
+}
+
+// TextSimilarity returns how similar two texts are, from 0 to 1: one minus the number of
+// words to insert, delete or replace to turn one into the other, relative to the longer
+// text. Whitespace is normalized first, so texts that only differ in spacing or line
+// endings are equal.
+func TextSimilarity(a, b string) float64 {
+	a, b = normalizeMessageText(a), normalizeMessageText(b)
+	if a == b {
+		return 1
+	}
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	longest := len(wordsA)
+	if len(wordsB) > longest {
+		longest = len(wordsB)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(wordsA, wordsB))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between two sequences of words
+func editDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			curr[j] = prev[j-1]
+			if a[i-1] != b[j-1] {
+				curr[j]++
+			}
+			if d := prev[j] + 1; d < curr[j] {
+				curr[j] = d
+			}
+			if d := curr[j-1] + 1; d < curr[j] {
+				curr[j] = d
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// ReadSessionFile reads a session exported as JSON, YAML or JSONL, such as a golden
+// transcript. A JSONL export holds only messages, so the session takes its ID from a
+// session_<id>.jsonl file name.
+func ReadSessionFile(path string) (*Session, error) {
+	name := filepath.Base(path)
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".json", ".yaml", ".yml":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var session Session
+		if ext == ".json" {
+			err = json.Unmarshal(data, &session)
+		} else {
+			err = yaml.Unmarshal(data, &session)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return &session, nil
+	case ".jsonl":
+		return readJSONLSession(path, strings.TrimPrefix(strings.TrimSuffix(name, filepath.Ext(name)), "session_"))
+	default:
+		return nil, fmt.Errorf("%s is not a JSON, YAML or JSONL session export", path)
+	}
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTextSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"the parser is done", "the parser is done", 1},
+		{"the parser\r\n  is done ", "the parser\nis done", 1},
+		{"the parser is done", "the parser is finished", 0.75},
+		{"the parser is done", "", 0},
+		{"", "", 1},
+	}
+	for _, tt := range tests {
+		if got := TextSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("TextSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNewGoldenComparison(t *testing.T) {
+	c, err := NewGoldenComparison([]string{" Timestamps", "ids", ""}, 0.9)
+	if err != nil {
+		t.Fatalf("NewGoldenComparison() error = %v", err)
+	}
+	if !c.Ignore[GoldenIgnoreTimestamps] || !c.Ignore[GoldenIgnoreIDs] || len(c.Ignore) != 2 {
+		t.Errorf("NewGoldenComparison() ignores %v, want timestamps and ids", c.Ignore)
+	}
+	if _, err := NewGoldenComparison([]string{"provenance"}, 1); err == nil {
+		t.Error("NewGoldenComparison() with an unknown field should fail")
+	}
+	if _, err := NewGoldenComparison(nil, 1.5); err == nil {
+		t.Error("NewGoldenComparison() with a threshold over 1 should fail")
+	}
+}
+
+func TestGoldenComparison_Compare(t *testing.T) {
+	golden := CreateTestSessionWithMessages("golden", []Message{
+		{Actor: "user", Content: "Add a retry to the HTTP client", Timestamp: "2024-06-01T10:00:00Z"},
+		{Actor: "assistant", Content: "I added a retry with exponential backoff to the client.", Timestamp: "2024-06-01T10:00:05Z"},
+	})
+	golden.Workspace = "/src/api"
+
+	live := CreateTestSessionWithMessages("live", []Message{
+		{Actor: "user", Content: "Add a retry to the HTTP client", Timestamp: "2024-06-02T08:00:00+02:00"},
+		{Actor: "assistant", Content: "I added a retry with jittered exponential backoff to the client.", Timestamp: "2024-06-02T08:00:09+02:00"},
+	})
+	live.Workspace = "/src/api"
+	live.Metadata.Name = golden.Metadata.Name
+
+	strict, _ := NewGoldenComparison(nil, 1)
+	fields := make(map[string]bool)
+	for _, m := range strict.Compare(golden, live) {
+		fields[m.Field] = true
+		if m.Field == "text" && (m.Message != 2 || m.Similarity < 0.9) {
+			t.Errorf("text mismatch = %+v, want message 2 about 0.9 similar", m)
+		}
+	}
+	for _, want := range []string{"id", "timestamp", "text"} {
+		if !fields[want] {
+			t.Errorf("Compare() should report a different %s, got %v", want, fields)
+		}
+	}
+
+	lenient, _ := NewGoldenComparison([]string{GoldenIgnoreIDs, GoldenIgnoreTimestamps}, 0.8)
+	if mismatches := lenient.Compare(golden, live); len(mismatches) != 0 {
+		t.Errorf("Compare() ignoring ids and timestamps = %+v, want a match", mismatches)
+	}
+
+	live.Messages = live.Messages[:1]
+	live.Messages[0].Actor = "assistant"
+	mismatches := lenient.Compare(golden, live)
+	if len(mismatches) != 2 || mismatches[0].Field != "messages" || mismatches[1].Field != "actor" {
+		t.Errorf("Compare() of a shorter session = %+v, want the message count and actor", mismatches)
+	}
+}
+
+func TestReadSessionFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "golden.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"id":"abc","messages":[{"actor":"user","content":"hi"}],"metadata":{"name":"Greeting"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ReadSessionFile(jsonPath)
+	if err != nil || session.ID != "abc" || session.Metadata.Name != "Greeting" || len(session.Messages) != 1 {
+		t.Errorf("ReadSessionFile(json) = %+v, %v", session, err)
+	}
+
+	jsonlPath := filepath.Join(dir, "session_abc.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(`{"actor":"user","content":"hi"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session, err = ReadSessionFile(jsonlPath)
+	if err != nil || session.ID != "abc" || len(session.Messages) != 1 {
+		t.Errorf("ReadSessionFile(jsonl) = %+v, %v", session, err)
+	}
+
+	if _, err := ReadSessionFile(filepath.Join(dir, "golden.md")); err == nil {
+		t.Error("ReadSessionFile() of a Markdown file should fail")
+	}
+}
@@ -0,0 +1,238 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// HealthStatus is the overall outcome of a health check
+type HealthStatus string
+
+const (
+	HealthOK         HealthStatus = "ok"          // Storage is readable and holds sessions
+	HealthNoSessions HealthStatus = "no_sessions" // Storage is readable but holds no sessions
+	HealthFailed     HealthStatus = "failed"      // No storage could be read
+)
+
+// HealthOptions selects the storage RunHealthCheck inspects
+type HealthOptions struct {
+	StoragePath string // Storage location, as accepted by --storage; empty auto-detects
+	// ReadStrategy is how the databases are read, as --read-strategy: auto, direct or
+	// copy. Empty reads them directly.
+	ReadStrategy string
+	// TriggerAgent runs cursor-agent, when running in CI and no session is found, so that
+	// it creates its storage, and checks again
+	TriggerAgent bool
+	// Agent is how cursor-agent is run and waited for when TriggerAgent is set; the zero
+	// value uses DefaultAgentRunOptions
+	Agent AgentRunOptions
+}
+
+// HealthReport is the result of RunHealthCheck. Each step records what it found, or the
+// error that stopped it.
+type HealthReport struct {
+	Paths  StoragePaths // Storage locations that were checked
+	Copied bool         // The databases were read from a temporary copy
+
+	DesktopStorage  bool     // The desktop app database exists
+	DesktopDBPath   string   // Where the desktop app database is, or is expected
+	AgentStorage    bool     // The cursor-agent storage directory exists
+	AgentStoreDBs   []string // cursor-agent session databases found
+	AgentStorageErr error    // Error scanning the cursor-agent storage directory
+
+	Backend    string       // Backend opened: globalStorage, agentStorage or exportDir
+	BackendErr error        // Why no backend could be opened
+	Sessions   []SessionRef // Sessions the backend lists
+	LoadErr    error        // Why the sessions could not be listed
+	Warnings   []Warning    // Records skipped while listing the sessions
+
+	CI             bool   // Running in a CI environment
+	AgentTriggered bool   // cursor-agent was run to create a session
+	TriggerErr     error  // Why cursor-agent could not be run, or created no store.db
+	AgentStoreDB   string // The store.db found after cursor-agent ran, read by the second check
+
+	Status HealthStatus
+}
+
+// RunHealthCheck checks that session data can be located and read: the storage paths,
+// the desktop app and cursor-agent storage, opening a backend and listing its sessions.
+// Problems with the storage are recorded in the report, whose Status sums them up; an
+// error is returned only when the check itself could not run, such as when the storage
+// paths cannot be determined.
+func RunHealthCheck(opts HealthOptions) (HealthReport, error) {
+	report := HealthReport{CI: IsCIEnvironment()}
+
+	paths, err := GetStoragePaths(opts.StoragePath)
+	if err != nil {
+		return report, fmt.Errorf("failed to get storage paths: %w", err)
+	}
+	report.Paths = paths
+	original := paths
+	report.DesktopStorage = paths.GlobalStorageExists()
+	report.DesktopDBPath = paths.GetGlobalStorageDBPath()
+	report.AgentStorage = paths.HasAgentStorage()
+	if report.AgentStorage {
+		report.AgentStoreDBs, err = paths.FindAgentStoreDBs()
+		if err != nil {
+			report.AgentStorageErr = err
+		}
+	}
+
+	if ResolveReadStrategy(opts.ReadStrategy, []StoragePaths{paths}) == ReadCopy {
+		var cleanup func() error
+		paths, cleanup, err = CopyStoragePaths(paths)
+		if err != nil {
+			return report, fmt.Errorf("failed to copy database files: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				LogWarn("Failed to cleanup temporary files: %v", err)
+			}
+		}()
+		report.Copied = true
+	}
+
+	report.loadSessions(paths)
+	loaded := report.BackendErr == nil && report.LoadErr == nil
+	if loaded && len(report.Sessions) == 0 && opts.TriggerAgent && report.CI {
+		report.triggerAgent(original, opts.Agent)
+	}
+
+	available := report.Paths.ExportDir != "" || report.DesktopStorage || (report.AgentStorage && len(report.AgentStoreDBs) > 0)
+	switch {
+	case available && len(report.Sessions) > 0:
+		report.Status = HealthOK
+	case available:
+		report.Status = HealthNoSessions
+	default:
+		report.Status = HealthFailed
+	}
+	return report, nil
+}
+
+// loadSessions opens a backend for paths and lists its sessions into the report
+func (r *HealthReport) loadSessions(paths StoragePaths) {
+	r.Backend, r.BackendErr, r.LoadErr, r.Sessions, r.Warnings = "", nil, nil, nil, nil
+
+	backend, err := NewStorageBackend(paths)
+	if err != nil {
+		r.BackendErr = err
+		return
+	}
+
+	switch backend.(type) {
+	case *Storage:
+		r.Backend = BackendGlobalStorage
+	case *AgentStorage:
+		r.Backend = BackendAgentStorage
+	case *FileBackend:
+		r.Backend = BackendExportDir
+	default:
+		r.Backend = fmt.Sprintf("%T", backend)
+	}
+
+	recorded := len(GetWarnings())
+	composers, err := backend.LoadComposers()
+	r.Warnings = GetWarnings()[recorded:]
+	if err != nil {
+		r.LoadErr = err
+		return
+	}
+	r.Sessions = make([]SessionRef, 0, len(composers))
+	for _, composer := range composers {
+		r.Sessions = append(r.Sessions, SessionRef{ID: composer.ComposerID, Name: composer.Name})
+	}
+}
+
+// triggerAgent runs cursor-agent to create a session, waits for its store.db and, once it
+// appears, checks the storage holding it again. The new session is read from the storage
+// itself, not from a copy.
+func (r *HealthReport) triggerAgent(paths StoragePaths, opts AgentRunOptions) {
+	if opts == (AgentRunOptions{}) {
+		opts = DefaultAgentRunOptions()
+	}
+	cursorAgentPath, err := findCursorAgentSession()
+	if err != nil {
+		r.TriggerErr = err
+		return
+	}
+
+	dirs := AgentStorageCandidates()
+	if paths.AgentStoragePath != "" {
+		dirs = append([]string{paths.AgentStoragePath}, dirs...)
+	}
+	storeDB, err := SeedAgentStorage(context.Background(), opts, dirs, func(ctx context.Context) error {
+		return runCursorAgentSession(ctx, cursorAgentPath)
+	})
+	if errors.Is(err, ErrAgentAuthentication) {
+		r.TriggerErr = err
+		return
+	}
+	r.AgentTriggered = true
+	if err != nil {
+		r.TriggerErr = err
+		return
+	}
+
+	r.AgentStoreDB = storeDB
+	detected := WithAgentStoreDB(paths, storeDB)
+	if dbs, _ := detected.FindAgentStoreDBs(); len(dbs) > 0 {
+		r.Paths.AgentStoragePath = detected.AgentStoragePath
+		r.AgentStorage = true
+		r.AgentStoreDBs = dbs
+		r.loadSessions(detected)
+	}
+}
+
+// findCursorAgentSession returns the path of the cursor-agent to run, from PATH or its
+// usual install locations
+func findCursorAgentSession() (string, error) {
+	// Find cursor-agent in common locations
+	possiblePaths := []string{
+		"cursor-agent", // In PATH
+		filepath.Join(os.Getenv("HOME"), ".local/bin/cursor-agent"),
+		filepath.Join(os.Getenv("HOME"), ".cursor/bin/cursor-agent"),
+	}
+
+	var cursorAgentPath string
+	for _, path := range possiblePaths {
+		if path == "cursor-agent" {
+			// Check if it's in PATH
+			if _, err := exec.LookPath("cursor-agent"); err == nil {
+				cursorAgentPath = "cursor-agent"
+				break
+			}
+		} else {
+			if _, err := os.Stat(path); err == nil {
+				cursorAgentPath = path
+				break
+			}
+		}
+	}
+
+	if cursorAgentPath == "" {
+		return "", fmt.Errorf("cursor-agent not found in PATH or common locations")
+	}
+	return cursorAgentPath, nil
+}
+
+// runCursorAgentSession sends cursor-agent a simple "hello" message, which creates a
+// session, and waits for it to answer or ctx to end
+func runCursorAgentSession(ctx context.Context, cursorAgentPath string) error {
+	cmd := exec.CommandContext(ctx, cursorAgentPath, "--print", "hello", "--model", "auto")
+	cmd.Env = os.Environ()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if IsAgentAuthenticationError(stderr.String()) {
+			return fmt.Errorf("%w: %v", ErrAgentAuthentication, err)
+		}
+		return fmt.Errorf("cursor-agent failed: %w", err)
+	}
+	return nil
+}
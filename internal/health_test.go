@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestRunHealthCheck(t *testing.T) {
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	testutil.CreateSQLiteFixture(t, filepath.Join(dir, "state.vscdb"))
+
+	for _, strategy := range []string{"", ReadCopy} {
+		report, err := RunHealthCheck(HealthOptions{StoragePath: dir, ReadStrategy: strategy})
+		if err != nil {
+			t.Fatalf("RunHealthCheck() error = %v", err)
+		}
+		if report.Status != HealthOK || !report.DesktopStorage || report.Backend != BackendGlobalStorage {
+			t.Errorf("RunHealthCheck() = %+v, want ok from globalStorage", report)
+		}
+		if report.Copied != (strategy == ReadCopy) {
+			t.Errorf("RunHealthCheck() with read strategy %q copied = %v", strategy, report.Copied)
+		}
+		if len(report.Sessions) != 1 || report.Sessions[0] != (SessionRef{ID: "composer1", Name: "Test Conversation"}) {
+			t.Errorf("RunHealthCheck() sessions = %+v, want composer1", report.Sessions)
+		}
+	}
+}
+
+func TestRunHealthCheck_NoStorage(t *testing.T) {
+	t.Setenv("HOME", testutil.CreateTempDir(t))
+	report, err := RunHealthCheck(HealthOptions{})
+	if err != nil {
+		t.Fatalf("RunHealthCheck() error = %v", err)
+	}
+	if report.Status != HealthFailed || !errors.Is(report.BackendErr, ErrNoStorage) {
+		t.Errorf("RunHealthCheck() without storage = %s, %v, want failed with ErrNoStorage", report.Status, report.BackendErr)
+	}
+
+	if _, err := RunHealthCheck(HealthOptions{StoragePath: testutil.CreateTempDir(t)}); err == nil {
+		t.Error("RunHealthCheck() of a directory that is not a storage location should fail")
+	}
+}
+
+func TestRunHealthCheck_TriggerAgent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as cursor-agent")
+	}
+	home := testutil.CreateTempDir(t)
+	t.Setenv("HOME", home)
+	t.Setenv("CI", "true")
+	agentPollInterval = 5 * time.Millisecond
+	defer func() { agentPollInterval = 250 * time.Millisecond }()
+
+	// A desktop database without sessions
+	dir := filepath.Join(testutil.CreateTempDir(t), "globalStorage")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create globalStorage: %v", err)
+	}
+	db, err := sql.Open("sqlite", filepath.Join(dir, "state.vscdb"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE cursorDiskKV (key TEXT PRIMARY KEY, value TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	_ = db.Close()
+
+	// A cursor-agent that fails once, then creates its store.db
+	bin := testutil.CreateTempDir(t)
+	storeDB := filepath.Join(home, ".cursor", "chats", "hash", "session", "store.db")
+	script := "#!/bin/sh\nif [ ! -f \"" + bin + "/ran\" ]; then touch \"" + bin + "/ran\"; exit 1; fi\n" +
+		"mkdir -p \"" + filepath.Dir(storeDB) + "\" && touch \"" + storeDB + "\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "cursor-agent"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write cursor-agent: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	report, err := RunHealthCheck(HealthOptions{
+		StoragePath:  dir,
+		TriggerAgent: true,
+		Agent:        AgentRunOptions{Timeout: 5 * time.Second, Retries: 1, WaitForDB: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("RunHealthCheck() error = %v", err)
+	}
+	if !report.AgentTriggered || report.TriggerErr != nil || report.AgentStoreDB != storeDB {
+		t.Fatalf("RunHealthCheck() triggered = %v, %v, store.db %q, want %q", report.AgentTriggered, report.TriggerErr, report.AgentStoreDB, storeDB)
+	}
+	if report.Paths.AgentStoragePath != filepath.Join(home, ".cursor", "chats") || len(report.AgentStoreDBs) != 1 {
+		t.Errorf("RunHealthCheck() agent storage = %s with %v, want the directory of the new store.db", report.Paths.AgentStoragePath, report.AgentStoreDBs)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteStateDB writes the sessions of backend into a new desktop app database at path, in
+// the cursorDiskKV layout Cursor uses: composerData:<composerId> for each composer,
+// bubbleId:<composerId>:<bubbleId> for each message in its headers, and
+// messageRequestContext:<composerId>:<contextId> for each context. The database must not
+// exist yet. It returns the number of sessions written.
+func WriteStateDB(path string, backend StorageBackend) (int, error) {
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load composers: %w", err)
+	}
+	bubbles, err := backend.LoadBubbles()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load bubbles: %w", err)
+	}
+	contexts, err := backend.LoadMessageContexts()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load contexts: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return 0, fmt.Errorf("database already exists: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := writeStateDB(path, composers, bubbles, contexts); err != nil {
+		// Leave no half-written database behind
+		_ = os.Remove(path)
+		return 0, err
+	}
+	return len(composers), nil
+}
+
+// writeStateDB creates the database at path and writes the raw sessions in one transaction
+func writeStateDB(path string, composers []*RawComposer, bubbles map[string]*RawBubble, contexts map[string][]*MessageContext) error {
+	db, err := sql.Open("sqlite", path+"?mode=rwc")
+	if err != nil {
+		return &StorageError{Path: path, Op: "open", Err: err}
+	}
+	defer func() { _ = db.Close() }()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return &StorageError{Path: path, Op: "open", Err: err}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		"CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+		"CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return &StorageError{Path: path, Op: "write", Err: err}
+		}
+	}
+
+	insert, err := tx.Prepare("INSERT INTO cursorDiskKV (key, value) VALUES (?, ?)")
+	if err != nil {
+		return &StorageError{Path: path, Op: "write", Err: err}
+	}
+	defer func() { _ = insert.Close() }()
+
+	put := func(key string, value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		if _, err := insert.Exec(key, string(data)); err != nil {
+			return &StorageError{Path: path, Op: "write", Err: err}
+		}
+		return nil
+	}
+
+	for _, composer := range composers {
+		if err := put("composerData:"+composer.ComposerID, composer); err != nil {
+			return err
+		}
+		for _, header := range composer.FullConversationHeadersOnly {
+			bubble, ok := bubbles[header.BubbleID]
+			if !ok {
+				LogWarn("Session %s: message %s not found, skipping", composer.ComposerID, header.BubbleID)
+				continue
+			}
+			if err := put(fmt.Sprintf("bubbleId:%s:%s", composer.ComposerID, header.BubbleID), bubble); err != nil {
+				return err
+			}
+		}
+		for _, ctx := range contexts[composer.ComposerID] {
+			contextID := ctx.ContextID
+			if contextID == "" {
+				contextID = ctx.BubbleID
+			}
+			if err := put(fmt.Sprintf("messageRequestContext:%s:%s", composer.ComposerID, contextID), ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &StorageError{Path: path, Op: "write", Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestWriteStateDB_RoundTrip(t *testing.T) {
+	source := NewFileBackend(createExportDir(t))
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "globalStorage", "state.vscdb")
+
+	count, err := WriteStateDB(dbPath, source)
+	if err != nil {
+		t.Fatalf("WriteStateDB() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("WriteStateDB() = %d sessions, want 3", count)
+	}
+
+	paths, err := GetStoragePaths(filepath.Dir(dbPath))
+	if err != nil {
+		t.Fatalf("GetStoragePaths() error = %v", err)
+	}
+	backend, err := NewStorageBackend(paths)
+	if err != nil {
+		t.Fatalf("NewStorageBackend() error = %v", err)
+	}
+
+	want := map[string][]ReconstructedMessage{}
+	conversations, err := ReconstructConversations(source)
+	if err != nil {
+		t.Fatalf("ReconstructConversations() error = %v", err)
+	}
+	for _, conv := range conversations {
+		want[conv.ComposerID] = nil
+		for _, msg := range conv.Messages {
+			want[conv.ComposerID] = append(want[conv.ComposerID], ReconstructedMessage{Type: msg.Type, Text: msg.Text, Timestamp: msg.Timestamp})
+		}
+	}
+
+	conversations, err = ReconstructConversations(backend)
+	if err != nil {
+		t.Fatalf("ReconstructConversations() of the imported database error = %v", err)
+	}
+	if len(conversations) != len(want) {
+		t.Fatalf("imported database has %d conversations, want %d", len(conversations), len(want))
+	}
+	for _, conv := range conversations {
+		expected, ok := want[conv.ComposerID]
+		if !ok {
+			t.Errorf("unexpected conversation %s", conv.ComposerID)
+			continue
+		}
+		if len(conv.Messages) != len(expected) {
+			t.Errorf("conversation %s has %d messages, want %d", conv.ComposerID, len(conv.Messages), len(expected))
+			continue
+		}
+		for i, msg := range conv.Messages {
+			if msg.Type != expected[i].Type || msg.Text != expected[i].Text || msg.Timestamp != expected[i].Timestamp {
+				t.Errorf("conversation %s message %d = %d %q at %d, want %d %q at %d", conv.ComposerID, i, msg.Type, msg.Text, msg.Timestamp, expected[i].Type, expected[i].Text, expected[i].Timestamp)
+			}
+		}
+	}
+
+	contexts, err := backend.LoadMessageContexts()
+	if err != nil || len(contexts["dumped"]) != 1 {
+		t.Errorf("LoadMessageContexts() = %v, %v, want the context of the dumped session", contexts, err)
+	}
+}
+
+func TestWriteStateDB_Existing(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	if err := os.WriteFile(dbPath, []byte("existing"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if _, err := WriteStateDB(dbPath, NewFileBackend(createExportDir(t))); err == nil {
+		t.Error("WriteStateDB() over an existing database succeeded, want an error")
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "existing" {
+		t.Error("WriteStateDB() modified the existing file")
+	}
+}
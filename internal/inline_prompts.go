@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// Session types; chat sessions, which hold nearly every session, leave Session.Type empty
+const (
+	SessionTypeChat   = "chat"
+	SessionTypeInline = "inline"
+)
+
+// workspacePromptsKey is the ItemTable key listing the prompts typed into a workspace's
+// AI features, without when they were sent or which feature they went to
+const workspacePromptsKey = "aiService.prompts"
+
+// chatGenerationTypes are the generation types of prompts sent from the chat panel or the
+// composer, which are already exported as chat sessions
+var chatGenerationTypes = map[string]bool{"composer": true, "chat": true}
+
+// workspacePrompt is an entry of the value stored under workspacePromptsKey
+type workspacePrompt struct {
+	Text string `json:"text"`
+}
+
+// inlineGeneration is an entry of the value stored under workspaceGenerationsKey, with the
+// fields that identify an inline prompt
+type inlineGeneration struct {
+	UnixMs          int64  `json:"unixMs"`
+	GenerationUUID  string `json:"generationUUID"`
+	Type            string `json:"type"`
+	TextDescription string `json:"textDescription"`
+}
+
+// LoadInlineSessions reads the inline prompts of every workspace database of the storage
+// locations, such as Cmd-K edits, which Cursor keeps apart from composer chats, as
+// sessions of type inline holding the prompt alone. Prompts that begin a user message of
+// one of sessions are chat prompts and left out. Databases that cannot be read are skipped.
+func LoadInlineSessions(list []StoragePaths, sessions []*Session) []*Session {
+	chatPrompts := make(map[string]bool)
+	for _, session := range sessions {
+		for _, msg := range session.Messages {
+			if msg.Actor == ActorUser {
+				chatPrompts[normalizePromptText(msg.Content)] = true
+			}
+		}
+	}
+
+	var inline []*Session
+	for _, paths := range list {
+		dbPaths, err := paths.FindWorkspaceStateDBs()
+		if err != nil {
+			LogDebug("Failed to find workspace databases: %v", err)
+			continue
+		}
+		for _, dbPath := range dbPaths {
+			found, err := loadInlineSessionsFromDB(dbPath)
+			if err != nil {
+				LogWarn("Failed to load inline prompts from %s: %v", dbPath, err)
+				continue
+			}
+			for _, session := range found {
+				if !isChatPrompt(session.Messages[0].Content, chatPrompts) {
+					inline = append(inline, session)
+				}
+			}
+		}
+	}
+	if len(inline) > 0 {
+		LogInfo("Loaded %d inline prompt(s) from workspace storage", len(inline))
+	}
+	return inline
+}
+
+// isChatPrompt reports whether a prompt begins one of the user messages of chatPrompts,
+// which are normalized with normalizePromptText
+func isChatPrompt(prompt string, chatPrompts map[string]bool) bool {
+	prompt = normalizePromptText(prompt)
+	if chatPrompts[prompt] {
+		return true
+	}
+	for text := range chatPrompts {
+		if strings.HasPrefix(text, prompt) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadInlineSessionsFromDB reads the inline prompts of a single workspace state.vscdb
+func loadInlineSessionsFromDB(dbPath string) ([]*Session, error) {
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	prompts, _, err := QueryItemTable(db, dbPath, workspacePromptsKey)
+	if err != nil {
+		return nil, err
+	}
+	generations, _, err := QueryItemTable(db, dbPath, workspaceGenerationsKey)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInlinePrompts(prompts, generations, dbPath)
+}
+
+// ParseInlinePrompts converts the aiService.prompts and aiService.generations values of a
+// workspace database, either of which may be empty, into inline sessions of one user
+// message each. Every generation not sent from the chat panel or the composer is a
+// session named by its generation UUID and timed by it. The prompts list also keeps
+// prompts whose generations Cursor has since dropped; those that no generation accounts
+// for are sessions without a time, named by a hash of the workspace and their text.
+// Sessions belong to the workspace whose directory holds dbPath, as desktop chats do.
+func ParseInlinePrompts(prompts, generations, dbPath string) ([]*Session, error) {
+	var gens []inlineGeneration
+	if generations != "" {
+		if err := json.Unmarshal([]byte(generations), &gens); err != nil {
+			return nil, &ParseError{Source: BackendWorkspaceStorage, Key: workspaceGenerationsKey, Err: err}
+		}
+	}
+	var entries []workspacePrompt
+	if prompts != "" {
+		if err := json.Unmarshal([]byte(prompts), &entries); err != nil {
+			return nil, &ParseError{Source: BackendWorkspaceStorage, Key: workspacePromptsKey, Err: err}
+		}
+	}
+
+	var sessions []*Session
+	generated := make(map[string]bool)
+	for _, g := range gens {
+		text := normalizePromptText(g.TextDescription)
+		if text == "" {
+			continue
+		}
+		generated[text] = true
+		if chatGenerationTypes[g.Type] || g.GenerationUUID == "" {
+			continue
+		}
+		sessions = append(sessions, newInlineSession(g.GenerationUUID, g.TextDescription, g.UnixMs, dbPath, workspaceGenerationsKey))
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range entries {
+		text := normalizePromptText(p.Text)
+		if text == "" || generated[text] || seen[text] {
+			continue
+		}
+		seen[text] = true
+		sessions = append(sessions, newInlineSession(inlinePromptID(dbPath, text), p.Text, 0, dbPath, workspacePromptsKey))
+	}
+	return sessions, nil
+}
+
+// inlinePromptID returns the ID of an untimed inline prompt: a hash of its workspace and
+// text, so the same prompt keeps its ID from one export to the next
+func inlinePromptID(dbPath, text string) string {
+	sum := sha256.Sum256([]byte(inlineWorkspace(dbPath) + "\x00" + text))
+	return "inline-" + hex.EncodeToString(sum[:8])
+}
+
+// newInlineSession returns the session of an inline prompt sent at timestamp (Unix
+// milliseconds, 0 when unknown), titled after the prompt
+func newInlineSession(id, text string, timestamp int64, dbPath, key string) *Session {
+	msg := Message{
+		Actor:      ActorUser,
+		Content:    strings.TrimSpace(text),
+		Provenance: &Provenance{SourcePath: dbPath, BlobKey: key, Backend: BackendWorkspaceStorage},
+	}
+	metadata := Metadata{
+		MessageCount:  1,
+		Name:          titleFromText(msg.Content, defaultTitleLength),
+		GeneratedName: true,
+	}
+	if timestamp > 0 {
+		msg.Timestamp = formatTimestamp(timestamp)
+		metadata.CreatedAt = msg.Timestamp
+		metadata.UpdatedAt = msg.Timestamp
+	}
+	return &Session{
+		ID:        id,
+		Type:      SessionTypeInline,
+		Workspace: inlineWorkspace(dbPath),
+		Source:    BackendWorkspaceStorage,
+		Messages:  []Message{msg},
+		Metadata:  metadata,
+	}
+}
+
+// inlineWorkspace returns the workspace of the prompts of a workspace database: the name of
+// its workspaceStorage directory
+func inlineWorkspace(dbPath string) string {
+	return filepath.Base(filepath.Dir(dbPath))
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+const testInlineGenerations = `[
+	{"unixMs":1700000100000,"generationUUID":"g1","type":"composer","textDescription":"Why does this fail?"},
+	{"unixMs":1700000200000,"generationUUID":"g2","type":"cmdk","textDescription":"add error handling"},
+	{"unixMs":1700000300000,"type":"cmdk","textDescription":"no uuid"}
+]`
+
+const testInlinePrompts = `[
+	{"text":"Why does this fail?","commandType":4},
+	{"text":"add error handling","commandType":1},
+	{"text":"rename to  parseConfig","commandType":1},
+	{"text":"rename to parseConfig","commandType":1},
+	{"text":"  ","commandType":1}
+]`
+
+func TestParseInlinePrompts(t *testing.T) {
+	dbPath := filepath.Join("/storage", "workspaceStorage", "ws1", "state.vscdb")
+	sessions, err := ParseInlinePrompts(testInlinePrompts, testInlineGenerations, dbPath)
+	if err != nil {
+		t.Fatalf("ParseInlinePrompts() error = %v", err)
+	}
+	// The composer generation is a chat, the one without a UUID is dropped, and prompts
+	// with a generation or already seen are not repeated
+	if len(sessions) != 2 {
+		t.Fatalf("ParseInlinePrompts() returned %d sessions, want 2: %+v", len(sessions), sessions)
+	}
+
+	timed := sessions[0]
+	if timed.ID != "g2" || timed.Type != SessionTypeInline || timed.Workspace != "ws1" || timed.Source != BackendWorkspaceStorage {
+		t.Errorf("generation session = %+v", timed)
+	}
+	if timed.Metadata.CreatedAt != "2023-11-14T22:16:40Z" || timed.Messages[0].Timestamp != timed.Metadata.CreatedAt {
+		t.Errorf("generation session times = %q, %q", timed.Metadata.CreatedAt, timed.Messages[0].Timestamp)
+	}
+	if timed.Metadata.Name != "add error handling" || !timed.Metadata.GeneratedName {
+		t.Errorf("generation session name = %q (generated %v)", timed.Metadata.Name, timed.Metadata.GeneratedName)
+	}
+	if prov := timed.Messages[0].Provenance; prov == nil || prov.BlobKey != workspaceGenerationsKey || prov.SourcePath != dbPath {
+		t.Errorf("generation session provenance = %+v", prov)
+	}
+
+	untimed := sessions[1]
+	if untimed.Messages[0].Content != "rename to  parseConfig" || untimed.Metadata.CreatedAt != "" {
+		t.Errorf("prompt session = %+v", untimed)
+	}
+	if untimed.ID != inlinePromptID(dbPath, "rename to parseConfig") || untimed.ID == inlinePromptID("/other/ws2/state.vscdb", "rename to parseConfig") {
+		t.Errorf("prompt session ID = %q, want a hash of its workspace and text", untimed.ID)
+	}
+}
+
+func TestParseInlinePrompts_InvalidJSON(t *testing.T) {
+	if _, err := ParseInlinePrompts("{", "", "/ws/state.vscdb"); err == nil {
+		t.Error("ParseInlinePrompts() with invalid prompts error = nil, want a parse error")
+	}
+	if _, err := ParseInlinePrompts("", "{", "/ws/state.vscdb"); err == nil {
+		t.Error("ParseInlinePrompts() with invalid generations error = nil, want a parse error")
+	}
+}
+
+func TestLoadInlineSessions(t *testing.T) {
+	workspaceStorage := filepath.Join(testutil.CreateTempDir(t), "workspaceStorage")
+	for hash, items := range map[string]map[string]string{
+		"ws1": {workspacePromptsKey: testInlinePrompts, workspaceGenerationsKey: testInlineGenerations},
+		"ws2": {workspacePromptsKey: "not json"},
+		"ws3": {},
+	} {
+		dir := filepath.Join(workspaceStorage, hash)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create workspace directory: %v", err)
+		}
+		createWorkspaceStateDB(t, filepath.Join(dir, "state.vscdb"), "")
+		db, err := sql.Open("sqlite", filepath.Join(dir, "state.vscdb"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		for key, value := range items {
+			if _, err := db.Exec(`INSERT INTO ItemTable (key, value) VALUES (?, ?)`, key, value); err != nil {
+				t.Fatalf("Failed to insert %s: %v", key, err)
+			}
+		}
+		_ = db.Close()
+	}
+
+	// A chat that began with one of the prompts claims it
+	chats := []*Session{CreateTestSessionWithMessages("chat", []Message{
+		{Actor: ActorUser, Content: "rename to parseConfig\n\nand update the callers"},
+	})}
+	sessions := LoadInlineSessions([]StoragePaths{{WorkspaceStorage: workspaceStorage}}, chats)
+	if len(sessions) != 1 || sessions[0].ID != "g2" {
+		t.Errorf("LoadInlineSessions() = %+v, want the g2 generation alone", sessions)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IntermediaryDump holds the raw storage data a session was normalized from, so it
+// can be re-normalized later without access to the original databases
+type IntermediaryDump struct {
+	SessionID string            `json:"sessionId"`
+	Workspace string            `json:"workspace,omitempty"`
+	Composer  *RawComposer      `json:"composer,omitempty"`
+	Bubbles   []*RawBubble      `json:"bubbles"`
+	Contexts  []*MessageContext `json:"contexts,omitempty"`
+}
+
+// BuildIntermediaryDump collects the raw composer, bubbles and contexts for a session.
+// Bubbles follow the composer's header order. Sessions without an explicit composer
+// (cursor-agent stores where composers are synthesized from bubbles) get every bubble
+// with a matching chat ID, ordered by timestamp and then bubble ID, so repeated dumps
+// of a session are identical.
+func BuildIntermediaryDump(sessionID string, composers []*RawComposer, bubbles map[string]*RawBubble, contexts map[string][]*MessageContext) *IntermediaryDump {
+	dump := &IntermediaryDump{
+		SessionID: sessionID,
+		Bubbles:   make([]*RawBubble, 0),
+		Contexts:  contexts[sessionID],
+	}
+
+	for _, composer := range composers {
+		if composer.ComposerID == sessionID {
+			dump.Composer = composer
+			break
+		}
+	}
+
+	if dump.Composer != nil {
+		for _, header := range dump.Composer.FullConversationHeadersOnly {
+			if bubble, ok := bubbles[header.BubbleID]; ok {
+				dump.Bubbles = append(dump.Bubbles, bubble)
+			}
+		}
+		return dump
+	}
+
+	ids := make([]string, 0, len(bubbles))
+	for id, bubble := range bubbles {
+		if bubble.ChatID == sessionID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		dump.Bubbles = append(dump.Bubbles, bubbles[id])
+	}
+	sort.SliceStable(dump.Bubbles, func(i, j int) bool {
+		return dump.Bubbles[i].Timestamp < dump.Bubbles[j].Timestamp
+	})
+	return dump
+}
+
+// ToJSON converts the dump to indented JSON
+func (d *IntermediaryDump) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// ToYAML converts the dump to YAML, keeping the raw JSON field names
+func (d *IntermediaryDump) ToYAML() ([]byte, error) {
+	return jsonToYAML(d)
+}
+
+// ParseIntermediaryDump parses a dump written by ToJSON or, if isYAML is set, ToYAML
+func ParseIntermediaryDump(data []byte, isYAML bool) (*IntermediaryDump, error) {
+	if isYAML {
+		// YAML dumps use the JSON field names, so convert back to JSON before decoding
+		var generic interface{}
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
+		}
+		converted, err := json.Marshal(generic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML to JSON: %w", err)
+		}
+		data = converted
+	}
+
+	var dump IntermediaryDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal intermediary dump: %w", err)
+	}
+	if dump.SessionID == "" {
+		return nil, fmt.Errorf("intermediary dump has no session ID")
+	}
+	return &dump, nil
+}
+
+// jsonToYAML marshals v as YAML using its JSON field names. The raw models only
+// carry json tags, so going through JSON keeps both formats in sync.
+func jsonToYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to convert JSON to YAML: %w", err)
+	}
+
+	return yaml.Marshal(generic)
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildIntermediaryDump(t *testing.T) {
+	composers := []*RawComposer{
+		{
+			ComposerID: "c1",
+			Name:       "Test",
+			FullConversationHeadersOnly: []ConversationHeader{
+				{BubbleID: "b2", Type: 2},
+				{BubbleID: "b1", Type: 1},
+				{BubbleID: "missing", Type: 1},
+			},
+		},
+	}
+	bubbles := map[string]*RawBubble{
+		"b1": {BubbleID: "b1", ChatID: "c1", Text: "first"},
+		"b2": {BubbleID: "b2", ChatID: "c1", Text: "second"},
+		"b3": {BubbleID: "b3", ChatID: "agent", Text: "agent only"},
+		"b4": {BubbleID: "b4", ChatID: "agent", Text: "agent second", Timestamp: 2000},
+		"b5": {BubbleID: "b5", ChatID: "agent", Text: "agent first", Timestamp: 1000},
+		"b6": {BubbleID: "b6", ChatID: "agent", Text: "agent first too", Timestamp: 1000},
+	}
+	contexts := map[string][]*MessageContext{
+		"c1": {{ComposerID: "c1", ContextID: "ctx1"}},
+	}
+
+	tests := []struct {
+		name         string
+		sessionID    string
+		wantComposer bool
+		wantBubbles  []string
+		wantContexts int
+	}{
+		{"composer header order", "c1", true, []string{"b2", "b1"}, 1},
+		{"chat ID fallback by timestamp and ID", "agent", false, []string{"b3", "b5", "b6", "b4"}, 0},
+		{"unknown session", "nope", false, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump := BuildIntermediaryDump(tt.sessionID, composers, bubbles, contexts)
+			if (dump.Composer != nil) != tt.wantComposer {
+				t.Errorf("Composer = %v, want present=%v", dump.Composer, tt.wantComposer)
+			}
+			if len(dump.Bubbles) != len(tt.wantBubbles) {
+				t.Fatalf("len(Bubbles) = %d, want %d", len(dump.Bubbles), len(tt.wantBubbles))
+			}
+			for i, id := range tt.wantBubbles {
+				if dump.Bubbles[i].BubbleID != id {
+					t.Errorf("Bubbles[%d] = %s, want %s", i, dump.Bubbles[i].BubbleID, id)
+				}
+			}
+			if len(dump.Contexts) != tt.wantContexts {
+				t.Errorf("len(Contexts) = %d, want %d", len(dump.Contexts), tt.wantContexts)
+			}
+		})
+	}
+}
+
+func TestIntermediaryDump_Formats(t *testing.T) {
+	dump := &IntermediaryDump{
+		SessionID: "c1",
+		Composer:  &RawComposer{ComposerID: "c1", Name: "Test"},
+		Bubbles:   []*RawBubble{{BubbleID: "b1", ChatID: "c1", Text: "Hello"}},
+	}
+
+	jsonData, err := dump.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var decoded IntermediaryDump
+	if err := json.Unmarshal(jsonData, &decoded); err != nil {
+		t.Fatalf("ToJSON() produced invalid JSON: %v", err)
+	}
+	if decoded.Composer == nil || decoded.Composer.Name != "Test" {
+		t.Errorf("decoded composer = %v, want name Test", decoded.Composer)
+	}
+
+	yamlData, err := dump.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML() error = %v", err)
+	}
+	if !strings.Contains(string(yamlData), "composerId: c1") {
+		t.Errorf("ToYAML() should keep JSON field names, got:\n%s", yamlData)
+	}
+	var generic map[string]interface{}
+	if err := yaml.Unmarshal(yamlData, &generic); err != nil {
+		t.Fatalf("ToYAML() produced invalid YAML: %v", err)
+	}
+	if generic["sessionId"] != "c1" {
+		t.Errorf("sessionId = %v, want c1", generic["sessionId"])
+	}
+}
+
+func TestParseIntermediaryDump(t *testing.T) {
+	dump := &IntermediaryDump{SessionID: "s1", Bubbles: []*RawBubble{{BubbleID: "b1", Text: "hi"}}}
+	jsonData, _ := dump.ToJSON()
+	yamlData, _ := dump.ToYAML()
+
+	for name, tc := range map[string]struct {
+		data   []byte
+		isYAML bool
+	}{"json": {jsonData, false}, "yaml": {yamlData, true}} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := ParseIntermediaryDump(tc.data, tc.isYAML)
+			if err != nil {
+				t.Fatalf("ParseIntermediaryDump() error = %v", err)
+			}
+			if parsed.SessionID != "s1" || len(parsed.Bubbles) != 1 || parsed.Bubbles[0].Text != "hi" {
+				t.Errorf("ParseIntermediaryDump() = %+v", parsed)
+			}
+		})
+	}
+
+	noID, _ := json.Marshal(map[string]string{"workspace": "x"})
+	if _, err := ParseIntermediaryDump(noID, false); err == nil {
+		t.Error("ParseIntermediaryDump() should return error without a session ID")
+	}
+}
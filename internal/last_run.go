@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LastRunFile is the file in the state directory recording when each storage location
+// was last exported in full
+const LastRunFile = "last-runs.json"
+
+// LastRuns records the start of the last successful export of each storage location, for
+// exporting only the sessions that changed since
+type LastRuns struct {
+	Storage map[string]time.Time `json:"storage"` // Keyed by the storage's cache key
+
+	path string
+}
+
+// LoadLastRuns loads the last runs from path. A missing file yields no runs.
+func LoadLastRuns(path string) (*LastRuns, error) {
+	runs := &LastRuns{Storage: make(map[string]time.Time), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return runs, nil
+		}
+		return nil, fmt.Errorf("failed to read last runs: %w", err)
+	}
+	if err := json.Unmarshal(data, runs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last runs: %w", err)
+	}
+	if runs.Storage == nil {
+		runs.Storage = make(map[string]time.Time)
+	}
+	return runs, nil
+}
+
+// Get returns when the storage was last exported, if it was
+func (r *LastRuns) Get(storage string) (time.Time, bool) {
+	t, ok := r.Storage[storage]
+	return t, ok
+}
+
+// Set records that the storage was exported in a run started at t
+func (r *LastRuns) Set(storage string, t time.Time) {
+	r.Storage[storage] = t.UTC()
+}
+
+// Save writes the last runs back to their file. The file is replaced in one rename, so a
+// crash while saving leaves the previous runs in place.
+func (r *LastRuns) Save() error {
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal last runs: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".last-runs_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write last runs: %w", err)
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write last runs: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write last runs: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write last runs: %w", err)
+	}
+	return nil
+}
+
+// SessionsChangedSince returns the sessions created or updated at or after since. Session
+// times are recorded to the second, so since is too. Sessions with no recorded time are
+// kept, since it cannot be told whether they changed.
+func SessionsChangedSince(sessions []*Session, since time.Time) []*Session {
+	since = since.Truncate(time.Second)
+	changed := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session == nil {
+			continue
+		}
+		latest := session.Metadata.UpdatedAt
+		if latest == "" {
+			latest = session.Metadata.CreatedAt
+		}
+		t, err := time.Parse(time.RFC3339, latest)
+		if err != nil || !t.Before(since) {
+			changed = append(changed, session)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestLastRuns_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), "state", LastRunFile)
+
+	runs, err := LoadLastRuns(path)
+	if err != nil {
+		t.Fatalf("LoadLastRuns() on a missing file error = %v", err)
+	}
+	if _, ok := runs.Get("/storage/state.vscdb"); ok {
+		t.Error("Get() on a missing file found a run")
+	}
+
+	started := time.Date(2025, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	runs.Set("/storage/state.vscdb", started)
+	if err := runs.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadLastRuns(path)
+	if err != nil {
+		t.Fatalf("LoadLastRuns() error = %v", err)
+	}
+	if got, ok := loaded.Get("/storage/state.vscdb"); !ok || !got.Equal(started) || got.Location() != time.UTC {
+		t.Errorf("Get() = %v, %v, want %v in UTC", got, ok, started)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLastRuns(path); err == nil {
+		t.Error("LoadLastRuns() on a corrupt file error = nil, want an error")
+	}
+}
+
+func TestSessionsChangedSince(t *testing.T) {
+	since := time.Date(2025, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	sessions := []*Session{
+		{ID: "old", Metadata: Metadata{CreatedAt: "2025-02-01T00:00:00Z", UpdatedAt: "2025-02-28T00:00:00Z"}},
+		{ID: "updated", Metadata: Metadata{CreatedAt: "2025-02-01T00:00:00Z", UpdatedAt: "2025-03-02T00:00:00Z"}},
+		{ID: "new", Metadata: Metadata{CreatedAt: "2025-03-01T13:00:00Z"}},
+		// Recorded to the second, in the second the last run started
+		{ID: "boundary", Metadata: Metadata{UpdatedAt: "2025-03-01T12:00:00Z"}},
+		{ID: "undated"},
+		nil,
+	}
+
+	var got []string
+	for _, session := range SessionsChangedSince(sessions, since) {
+		got = append(got, session.ID)
+	}
+	want := []string{"updated", "new", "boundary", "undated"}
+	if len(got) != len(want) {
+		t.Fatalf("SessionsChangedSince() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SessionsChangedSince() = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import "sort"
+
+// LatencyStats sums up how long the assistant took to respond to user messages, over the
+// responses whose messages both have timestamps
+type LatencyStats struct {
+	Responses int   `json:"responses"`
+	P50Ms     int64 `json:"p50_ms"`
+	P90Ms     int64 `json:"p90_ms"`
+	P99Ms     int64 `json:"p99_ms"`
+	MaxMs     int64 `json:"max_ms"`
+}
+
+// setResponseTimes sets the ResponseMs of the first assistant message after each user
+// message to the time between them, from timestamps, the Unix milliseconds each message
+// was recorded at. Messages without a timestamp are left out: a user message without one
+// leaves the reply to it unmeasured.
+func setResponseTimes(messages []Message, timestamps []int64) {
+	var asked int64
+	for i := range messages {
+		switch messages[i].Actor {
+		case "user":
+			asked = timestamps[i]
+		case "assistant":
+			if asked > 0 && timestamps[i] > asked {
+				messages[i].ResponseMs = timestamps[i] - asked
+			}
+			asked = 0
+		}
+	}
+}
+
+// ResponseTimes returns the response times of the assistant messages of a session that
+// have one, in milliseconds
+func ResponseTimes(session *Session) []int64 {
+	var times []int64
+	for _, msg := range session.Messages {
+		if msg.ResponseMs > 0 {
+			times = append(times, msg.ResponseMs)
+		}
+	}
+	return times
+}
+
+// NewLatencyStats sums up response times in milliseconds, or returns nil when there are none
+func NewLatencyStats(times []int64) *LatencyStats {
+	if len(times) == 0 {
+		return nil
+	}
+	sorted := append([]int64(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &LatencyStats{
+		Responses: len(sorted),
+		P50Ms:     percentile(sorted, 50),
+		P90Ms:     percentile(sorted, 90),
+		P99Ms:     percentile(sorted, 99),
+		MaxMs:     sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetResponseTimes(t *testing.T) {
+	messages := []Message{
+		{Actor: ActorUser},
+		{Actor: ActorTool},
+		{Actor: ActorAssistant},
+		{Actor: ActorAssistant},
+		{Actor: ActorUser},
+		{Actor: ActorAssistant},
+		{Actor: ActorUser},
+		{Actor: ActorAssistant},
+	}
+	// The second user message has no timestamp, so the reply to it is not measured
+	setResponseTimes(messages, []int64{1000, 1500, 4000, 9000, 0, 12000, 20000, 20250})
+
+	var got []int64
+	for _, msg := range messages {
+		got = append(got, msg.ResponseMs)
+	}
+	if want := []int64{0, 0, 3000, 0, 0, 0, 0, 250}; !reflect.DeepEqual(got, want) {
+		t.Errorf("response times = %v, want %v", got, want)
+	}
+	if times := ResponseTimes(&Session{Messages: messages}); !reflect.DeepEqual(times, []int64{3000, 250}) {
+		t.Errorf("ResponseTimes() = %v, want [3000 250]", times)
+	}
+}
+
+func TestNewLatencyStats(t *testing.T) {
+	if stats := NewLatencyStats(nil); stats != nil {
+		t.Errorf("NewLatencyStats(nil) = %+v, want nil", stats)
+	}
+
+	var times []int64
+	for i := int64(100); i >= 1; i-- {
+		times = append(times, i*10)
+	}
+	want := &LatencyStats{Responses: 100, P50Ms: 500, P90Ms: 900, P99Ms: 990, MaxMs: 1000}
+	if stats := NewLatencyStats(times); !reflect.DeepEqual(stats, want) {
+		t.Errorf("NewLatencyStats() = %+v, want %+v", stats, want)
+	}
+	if times[0] != 1000 {
+		t.Error("NewLatencyStats() sorted the times it was given")
+	}
+
+	want = &LatencyStats{Responses: 1, P50Ms: 42, P90Ms: 42, P99Ms: 42, MaxMs: 42}
+	if stats := NewLatencyStats([]int64{42}); !reflect.DeepEqual(stats, want) {
+		t.Errorf("NewLatencyStats([42]) = %+v, want %+v", stats, want)
+	}
+}
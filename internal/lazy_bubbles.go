@@ -0,0 +1,48 @@
+package internal
+
+import "errors"
+
+// BubbleLoader is implemented by backends that can load a single bubble by its key,
+// without reading the others
+type BubbleLoader interface {
+	LoadBubble(composerID, bubbleID string) (*RawBubble, error)
+}
+
+// BubbleFinder is implemented by backends that can look up a bubble their conversation
+// headers reference but that they did not load, under whichever composer it is stored
+type BubbleFinder interface {
+	FindBubble(bubbleID string) (*RawBubble, error)
+}
+
+// BubbleResolver finds the bubbles referenced by a composer's conversation headers
+type BubbleResolver interface {
+	Resolve(composerID, bubbleID string) (*RawBubble, bool)
+}
+
+// Resolve looks a bubble up by ID; the composer ID is not needed
+func (bm *BubbleMap) Resolve(composerID, bubbleID string) (*RawBubble, bool) {
+	return bm.Get(bubbleID)
+}
+
+// LazyBubbleResolver loads each bubble from the storage when a conversation references it,
+// so reconstructing one session holds only that session's bubbles in memory
+type LazyBubbleResolver struct {
+	loader BubbleLoader
+}
+
+// NewLazyBubbleResolver creates a resolver loading bubbles through loader
+func NewLazyBubbleResolver(loader BubbleLoader) *LazyBubbleResolver {
+	return &LazyBubbleResolver{loader: loader}
+}
+
+// Resolve loads a bubble of a composer, reporting false if it is missing or unreadable
+func (l *LazyBubbleResolver) Resolve(composerID, bubbleID string) (*RawBubble, bool) {
+	bubble, err := l.loader.LoadBubble(composerID, bubbleID)
+	if err != nil {
+		if !errors.Is(err, ErrBubbleNotFound) {
+			LogWarn("Failed to load bubble %s of composer %s: %v", bubbleID, composerID, err)
+		}
+		return nil, false
+	}
+	return bubble, true
+}
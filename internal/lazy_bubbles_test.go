@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// countingLoader records which bubbles were loaded
+type countingLoader struct {
+	bubbles map[string]*RawBubble
+	loaded  []string
+}
+
+func (c *countingLoader) LoadBubble(composerID, bubbleID string) (*RawBubble, error) {
+	c.loaded = append(c.loaded, composerID+":"+bubbleID)
+	if bubbleID == "broken" {
+		return nil, errors.New("disk I/O error")
+	}
+	bubble, ok := c.bubbles[bubbleID]
+	if !ok {
+		return nil, ErrBubbleNotFound
+	}
+	return bubble, nil
+}
+
+func TestLazyBubbleResolver(t *testing.T) {
+	loader := &countingLoader{bubbles: map[string]*RawBubble{"b1": {BubbleID: "b1", Text: "hi"}}}
+	resolver := NewLazyBubbleResolver(loader)
+
+	if bubble, ok := resolver.Resolve("c1", "b1"); !ok || bubble.Text != "hi" {
+		t.Errorf("Resolve(b1) = %+v, %v, want the loaded bubble", bubble, ok)
+	}
+	for _, id := range []string{"missing", "broken"} {
+		if _, ok := resolver.Resolve("c1", id); ok {
+			t.Errorf("Resolve(%s) reported a bubble", id)
+		}
+	}
+	if len(loader.loaded) != 3 || loader.loaded[0] != "c1:b1" {
+		t.Errorf("loaded = %v, want one load per lookup keyed by composer", loader.loaded)
+	}
+}
+
+func TestBubbleMap_Resolve(t *testing.T) {
+	bm := NewBubbleMap()
+	bm.Set("b1", &RawBubble{BubbleID: "b1"})
+	if _, ok := bm.Resolve("any", "b1"); !ok {
+		t.Error("Resolve() did not find a stored bubble")
+	}
+	if _, ok := bm.Resolve("any", "b2"); ok {
+		t.Error("Resolve() found a bubble that was never stored")
+	}
+}
+
+// twoSessionDB creates a database with two composers of two bubbles each
+func twoSessionDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := testutil.CreateInMemoryDB(t)
+	for _, id := range []string{"alpha", "beta"} {
+		testutil.InsertComposer(t, db, "composerData:"+id, fmt.Sprintf(
+			`{"composerId":%q,"name":"%s chat","fullConversationHeadersOnly":[{"bubbleId":"%s-1","type":1},{"bubbleId":"%s-2","type":2}]}`,
+			id, id, id, id))
+		testutil.InsertBubble(t, db, "bubbleId:"+id+":"+id+"-1", fmt.Sprintf(`{"bubbleId":"%s-1","text":"question for %s","type":1}`, id, id))
+		testutil.InsertBubble(t, db, "bubbleId:"+id+":"+id+"-2", fmt.Sprintf(`{"bubbleId":"%s-2","text":"answer from %s","type":2}`, id, id))
+	}
+	return db
+}
+
+func TestStorage_LoadBubble(t *testing.T) {
+	db := twoSessionDB(t)
+	defer func() { _ = db.Close() }()
+	storage := NewStorage(db)
+
+	bubble, err := storage.LoadBubble("alpha", "alpha-2")
+	if err != nil {
+		t.Fatalf("LoadBubble() error = %v", err)
+	}
+	if bubble.Text != "answer from alpha" {
+		t.Errorf("Text = %q, want %q", bubble.Text, "answer from alpha")
+	}
+	if bubble.Provenance == nil || bubble.Provenance.BlobKey != "bubbleId:alpha:alpha-2" {
+		t.Errorf("Provenance = %+v, want the bubble's key", bubble.Provenance)
+	}
+
+	// The bubble ID alone is not enough; it must belong to the composer
+	if _, err := storage.LoadBubble("beta", "alpha-2"); !errors.Is(err, ErrBubbleNotFound) {
+		t.Errorf("LoadBubble() of another composer's bubble error = %v, want ErrBubbleNotFound", err)
+	}
+}
+
+func TestAgentStorage_FindBubble(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "globalStorage", "state.vscdb")
+	testutil.CreateSQLiteFixture(t, dbPath)
+
+	agent := NewAgentStorage(nil)
+	agent.globalStoragePath = func() string { return dbPath }
+	bubble, err := agent.FindBubble("bubble1")
+	if err != nil {
+		t.Fatalf("FindBubble() error = %v", err)
+	}
+	if bubble.Text != "Hello world" || bubble.Provenance == nil || bubble.Provenance.BlobKey != "bubbleId:chat1:bubble1" {
+		t.Errorf("FindBubble() = %+v, want the desktop app's bubble under any composer", bubble)
+	}
+	if _, err := agent.FindBubble("bubble"); !errors.Is(err, ErrBubbleNotFound) {
+		t.Errorf("FindBubble() of a bubble ID prefix error = %v, want ErrBubbleNotFound", err)
+	}
+
+	// Without a desktop app database there is nowhere else to look
+	alone := NewAgentStorage(nil)
+	alone.globalStoragePath = func() string { return "" }
+	if _, err := alone.FindBubble("bubble1"); !errors.Is(err, ErrBubbleNotFound) {
+		t.Errorf("FindBubble() without globalStorage error = %v, want ErrBubbleNotFound", err)
+	}
+
+	multi := NewMultiBackend(NewStorage(twoSessionDB(t)), alone, agent)
+	if bubble, err := multi.FindBubble("bubble1"); err != nil || bubble.Text != "Hello world" {
+		t.Errorf("MultiBackend.FindBubble() = %+v, %v, want the agent storage's fallback", bubble, err)
+	}
+}
+
+func TestReconstructSession(t *testing.T) {
+	db := twoSessionDB(t)
+	defer func() { _ = db.Close() }()
+	storage := NewStorage(db)
+
+	ResetParseStats()
+	defer ResetParseStats()
+
+	session, err := ReconstructSession(storage, nil, "alp", "")
+	if err != nil {
+		t.Fatalf("ReconstructSession() error = %v", err)
+	}
+	if session.ID != "alpha" {
+		t.Errorf("ID = %q, want alpha", session.ID)
+	}
+	if len(session.Messages) != 2 || session.Messages[0].Content != "question for alpha" {
+		t.Errorf("Messages = %+v, want alpha's two messages", session.Messages)
+	}
+
+	// Two composers and only the two bubbles of alpha were parsed
+	if stats := GetParseStats(); stats.Records != 4 {
+		t.Errorf("parsed %d records, want 2 composers and 2 bubbles", stats.Records)
+	}
+
+	if _, err := ReconstructSession(storage, nil, "gamma", ""); err == nil {
+		t.Error("ReconstructSession() of an unknown session should fail")
+	}
+	if _, err := ReconstructSession(NewMultiBackend(storage), nil, "alpha", ""); err == nil {
+		t.Error("ReconstructSession() should fail for a backend without BubbleLoader")
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// threadHeadLength is the number of opening messages two sessions must share to be linked
+// as one conversation. A single opening message such as "hi" is too common to rely on.
+const threadHeadLength = 2
+
+// Thread is a logical conversation made of a session and the sessions resumed from it,
+// ordered by creation time
+type Thread struct {
+	ID       string     // ID of the first session
+	Sessions []*Session // Sessions in the order they were created
+}
+
+// BuildThreads groups sessions into threads. Sessions are linked when one records the
+// other as its parent, or when they open with the same messages (compared by HashMessage).
+// Every session belongs to exactly one thread; threads are ordered by their first session.
+func BuildThreads(sessions []*Session) []*Thread {
+	parent := make([]int, len(sessions))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	byID := make(map[string]int, len(sessions))
+	for i, session := range sessions {
+		byID[session.ID] = i
+	}
+	byHead := make(map[string]int)
+	for i, session := range sessions {
+		if p, ok := byID[session.Metadata.ParentID]; ok && session.Metadata.ParentID != "" {
+			union(p, i)
+		}
+		if head := headHash(session); head != "" {
+			if j, ok := byHead[head]; ok {
+				union(j, i)
+			} else {
+				byHead[head] = i
+			}
+		}
+	}
+
+	groups := make(map[int]*Thread)
+	var threads []*Thread
+	for i, session := range sessions {
+		root := find(i)
+		thread, ok := groups[root]
+		if !ok {
+			thread = &Thread{}
+			groups[root] = thread
+			threads = append(threads, thread)
+		}
+		thread.Sessions = append(thread.Sessions, session)
+	}
+
+	for _, thread := range threads {
+		sort.SliceStable(thread.Sessions, func(a, b int) bool {
+			return sessionCreated(thread.Sessions[a]).Before(sessionCreated(thread.Sessions[b]))
+		})
+		thread.ID = thread.Sessions[0].ID
+	}
+	sort.SliceStable(threads, func(a, b int) bool {
+		return sessionCreated(threads[a].Sessions[0]).Before(sessionCreated(threads[b].Sessions[0]))
+	})
+	return threads
+}
+
+// FindThread returns the thread containing the session with the given ID, or nil
+func FindThread(threads []*Thread, sessionID string) *Thread {
+	for _, thread := range threads {
+		for _, session := range thread.Sessions {
+			if session.ID == sessionID {
+				return thread
+			}
+		}
+	}
+	return nil
+}
+
+// MessageCount returns the number of messages in the thread's timeline
+func (t *Thread) MessageCount() int {
+	return len(t.timeline())
+}
+
+// Timeline returns the thread as a single session: the first session's metadata and the
+// messages of every session in order. Each resumed session's opening messages that repeat
+// earlier sessions are left out.
+func (t *Thread) Timeline() *Session {
+	merged := *t.Sessions[0]
+	merged.Messages = t.timeline()
+	merged.Metadata.MessageCount = len(merged.Messages)
+	if last := t.Sessions[len(t.Sessions)-1]; last.Metadata.UpdatedAt != "" {
+		merged.Metadata.UpdatedAt = last.Metadata.UpdatedAt
+	}
+	return &merged
+}
+
+// timeline returns the messages of every session in the thread, skipping the leading run
+// of messages each session repeats from the sessions before it
+func (t *Thread) timeline() []Message {
+	seen := make(map[string]bool)
+	var messages []Message
+	for _, session := range t.Sessions {
+		repeating := true
+		for _, msg := range session.Messages {
+			hash := HashMessage(msg)
+			if repeating && seen[hash] {
+				continue
+			}
+			repeating = false
+			seen[hash] = true
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// headHash returns the hash of a session's opening messages, or "" if it is too short to
+// be linked by content
+func headHash(session *Session) string {
+	if len(session.Messages) < threadHeadLength {
+		return ""
+	}
+	var head string
+	for _, msg := range session.Messages[:threadHeadLength] {
+		head += HashMessage(msg)
+	}
+	return head
+}
+
+// sessionCreated returns when a session was created, or the zero time if unknown
+func sessionCreated(session *Session) time.Time {
+	t, _ := time.Parse(time.RFC3339, session.Metadata.CreatedAt)
+	return t
+}
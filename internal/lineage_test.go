@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func threadSession(id, createdAt, parentID string, contents ...string) *Session {
+	messages := make([]Message, len(contents))
+	for i, content := range contents {
+		actor := "user"
+		if i%2 == 1 {
+			actor = "assistant"
+		}
+		messages[i] = Message{Actor: actor, Content: content}
+	}
+	session := CreateTestSessionWithMessages(id, messages)
+	session.Metadata.CreatedAt = createdAt
+	session.Metadata.ParentID = parentID
+	return session
+}
+
+func threadIDs(threads []*Thread) [][]string {
+	var ids [][]string
+	for _, thread := range threads {
+		var group []string
+		for _, session := range thread.Sessions {
+			group = append(group, session.ID)
+		}
+		ids = append(ids, group)
+	}
+	return ids
+}
+
+func TestBuildThreads(t *testing.T) {
+	tests := []struct {
+		name     string
+		sessions []*Session
+		want     [][]string
+	}{
+		{
+			name: "parent ID links sessions",
+			sessions: []*Session{
+				threadSession("resumed", "2024-01-02T00:00:00Z", "original", "continue"),
+				threadSession("original", "2024-01-01T00:00:00Z", "", "fix the bug", "done"),
+			},
+			want: [][]string{{"original", "resumed"}},
+		},
+		{
+			name: "shared opening messages link sessions",
+			sessions: []*Session{
+				threadSession("first", "2024-01-01T00:00:00Z", "", "fix the bug", "done", "thanks"),
+				threadSession("second", "2024-01-02T00:00:00Z", "", "fix the bug", "done", "now add tests"),
+				threadSession("other", "2024-01-03T00:00:00Z", "", "write docs", "ok"),
+			},
+			want: [][]string{{"first", "second"}, {"other"}},
+		},
+		{
+			name: "single shared message does not link sessions",
+			sessions: []*Session{
+				threadSession("first", "2024-01-01T00:00:00Z", "", "hi"),
+				threadSession("second", "2024-01-02T00:00:00Z", "", "hi"),
+			},
+			want: [][]string{{"first"}, {"second"}},
+		},
+		{
+			name: "unknown parent is ignored",
+			sessions: []*Session{
+				threadSession("orphan", "2024-01-01T00:00:00Z", "missing", "hello"),
+			},
+			want: [][]string{{"orphan"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threads := BuildThreads(tt.sessions)
+			if got := threadIDs(threads); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildThreads() = %v, want %v", got, tt.want)
+			}
+			for _, thread := range threads {
+				if thread.ID != thread.Sessions[0].ID {
+					t.Errorf("thread ID = %s, want %s", thread.ID, thread.Sessions[0].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestFindThread(t *testing.T) {
+	threads := BuildThreads([]*Session{
+		threadSession("original", "2024-01-01T00:00:00Z", "", "fix the bug"),
+		threadSession("resumed", "2024-01-02T00:00:00Z", "original", "continue"),
+	})
+
+	if thread := FindThread(threads, "resumed"); thread == nil || thread.ID != "original" {
+		t.Errorf("FindThread(resumed) = %v, want thread original", thread)
+	}
+	if thread := FindThread(threads, "missing"); thread != nil {
+		t.Errorf("FindThread(missing) = %v, want nil", thread)
+	}
+}
+
+func TestThread_Timeline(t *testing.T) {
+	original := threadSession("original", "2024-01-01T00:00:00Z", "", "fix the bug", "done")
+	original.Metadata.Name = "Bug fix"
+	resumed := threadSession("resumed", "2024-01-02T00:00:00Z", "", "fix the bug", "done", "add tests", "added")
+	resumed.Metadata.UpdatedAt = "2024-01-02T01:00:00Z"
+
+	thread := BuildThreads([]*Session{resumed, original})[0]
+	timeline := thread.Timeline()
+
+	var got []string
+	for _, msg := range timeline.Messages {
+		got = append(got, msg.Content)
+	}
+	want := []string{"fix the bug", "done", "add tests", "added"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Timeline() messages = %v, want %v", got, want)
+	}
+	if thread.MessageCount() != len(want) || timeline.Metadata.MessageCount != len(want) {
+		t.Errorf("message count = %d/%d, want %d", thread.MessageCount(), timeline.Metadata.MessageCount, len(want))
+	}
+	if timeline.ID != "original" || timeline.Metadata.Name != "Bug fix" {
+		t.Errorf("Timeline() = %s %q, want the first session's ID and name", timeline.ID, timeline.Metadata.Name)
+	}
+	if timeline.Metadata.UpdatedAt != "2024-01-02T01:00:00Z" {
+		t.Errorf("Timeline() UpdatedAt = %s, want the last session's", timeline.Metadata.UpdatedAt)
+	}
+	if len(original.Messages) != 2 {
+		t.Error("Timeline() modified the first session")
+	}
+}
@@ -1,8 +1,13 @@
 package internal
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
 )
 
 // LogLevel represents the logging level
@@ -15,11 +20,35 @@ const (
 	LogLevelDebug
 )
 
+// Log output formats accepted by ConfigureLogger
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 var (
 	logLevel = LogLevelInfo
-	logger   = log.New(os.Stderr, "", log.LstdFlags)
+	// The handler accepts every record; filtering happens against logLevel so
+	// the level can change without rebuilding the handler. Records are logged from the
+	// goroutines parsing storage in parallel, so the loggers are swapped atomically.
+	logger atomic.Pointer[slog.Logger]
+	// captureLogger, when set, also receives every record, debug included
+	captureLogger atomic.Pointer[slog.Logger]
 )
 
+func init() {
+	logger.Store(newLogger(os.Stderr, LogFormatText))
+}
+
+// newLogger builds an slog logger writing to w in the given format
+func newLogger(w io.Writer, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
 // SetLogLevel sets the global log level
 func SetLogLevel(level LogLevel) {
 	logLevel = level
@@ -34,46 +63,81 @@ func SetVerbose(verbose bool) {
 	}
 }
 
-func logError(format string, args ...interface{}) {
-	if logLevel >= LogLevelError {
-		logger.Printf("[ERROR] "+format, args...)
+// ParseLogLevel parses a level name (error, warn, info, debug)
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return LogLevelError, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "debug":
+		return LogLevelDebug, nil
+	default:
+		return LogLevelInfo, fmt.Errorf("invalid log level: %s (supported: error, warn, info, debug)", name)
 	}
 }
 
-func logWarn(format string, args ...interface{}) {
-	if logLevel >= LogLevelWarn {
-		logger.Printf("[WARN] "+format, args...)
+// ConfigureLogger sets where diagnostics are written and in which format (text or json)
+func ConfigureLogger(w io.Writer, format string) error {
+	switch format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid log format: %s (supported: text, json)", format)
 	}
+	logger.Store(newLogger(w, format))
+	return nil
 }
 
-func logInfo(format string, args ...interface{}) {
-	if logLevel >= LogLevelInfo {
-		logger.Printf("[INFO] "+format, args...)
-	}
+// CaptureLogs also writes every diagnostic, whatever the log level, to w as text until
+// the returned function is called
+func CaptureLogs(w io.Writer) (stop func()) {
+	captureLogger.Store(newLogger(w, LogFormatText))
+	return func() { captureLogger.Store(nil) }
+}
+
+// slogLevels maps log levels to the slog levels records are written at
+var slogLevels = map[LogLevel]slog.Level{
+	LogLevelError: slog.LevelError,
+	LogLevelWarn:  slog.LevelWarn,
+	LogLevelInfo:  slog.LevelInfo,
+	LogLevelDebug: slog.LevelDebug,
 }
 
-func logDebug(format string, args ...interface{}) {
-	if logLevel >= LogLevelDebug {
-		logger.Printf("[DEBUG] "+format, args...)
+// logRecord writes a message with key/value attributes, as slog takes them, to the
+// logger when the log level allows it and to the capture logger, if any
+func logRecord(level LogLevel, msg string, attrs ...interface{}) {
+	if logLevel >= level {
+		logger.Load().Log(context.Background(), slogLevels[level], msg, attrs...)
+	}
+	if capture := captureLogger.Load(); capture != nil {
+		capture.Log(context.Background(), slogLevels[level], msg, attrs...)
 	}
 }
 
 // LogError logs an error message
 func LogError(format string, args ...interface{}) {
-	logError(format, args...)
+	logRecord(LogLevelError, fmt.Sprintf(format, args...))
 }
 
 // LogWarn logs a warning message
 func LogWarn(format string, args ...interface{}) {
-	logWarn(format, args...)
+	logRecord(LogLevelWarn, fmt.Sprintf(format, args...))
 }
 
 // LogInfo logs an info message
 func LogInfo(format string, args ...interface{}) {
-	logInfo(format, args...)
+	logRecord(LogLevelInfo, fmt.Sprintf(format, args...))
 }
 
 // LogDebug logs a debug message
 func LogDebug(format string, args ...interface{}) {
-	logDebug(format, args...)
+	logRecord(LogLevelDebug, fmt.Sprintf(format, args...))
+}
+
+// LogDebugAttrs logs a debug message with key/value attributes, such as "path", dbPath,
+// which --log-format json writes as fields to filter on
+func LogDebugAttrs(msg string, attrs ...interface{}) {
+	logRecord(LogLevelDebug, msg, attrs...)
 }
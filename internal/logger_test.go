@@ -1,6 +1,11 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -58,3 +63,142 @@ func TestLogLevels(t *testing.T) {
 		t.Error("LogLevelInfo should be less than LogLevelDebug")
 	}
 }
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"error", LogLevelError, false},
+		{"warn", LogLevelWarn, false},
+		{"WARNING", LogLevelWarn, false},
+		{"info", LogLevelInfo, false},
+		{"debug", LogLevelDebug, false},
+		{"trace", LogLevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogLevel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLogLevel(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConfigureLogger(t *testing.T) {
+	originalLevel := logLevel
+	defer func() {
+		logLevel = originalLevel
+		_ = ConfigureLogger(os.Stderr, LogFormatText)
+	}()
+
+	var buf bytes.Buffer
+	if err := ConfigureLogger(&buf, LogFormatJSON); err != nil {
+		t.Fatalf("ConfigureLogger() error = %v", err)
+	}
+	SetLogLevel(LogLevelWarn)
+
+	LogInfo("filtered %d", 1)
+	LogWarn("kept %d", 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 log line, got %d: %q", len(lines), buf.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Log line is not valid JSON: %v", err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "kept 2" {
+		t.Errorf("Unexpected log record: %v", record)
+	}
+
+	if err := ConfigureLogger(&buf, "xml"); err == nil {
+		t.Error("ConfigureLogger() should return error for unsupported format")
+	}
+}
+
+func TestCaptureLogs(t *testing.T) {
+	originalLevel := logLevel
+	defer func() {
+		logLevel = originalLevel
+		_ = ConfigureLogger(os.Stderr, LogFormatText)
+	}()
+
+	var out, captured bytes.Buffer
+	if err := ConfigureLogger(&out, LogFormatText); err != nil {
+		t.Fatalf("ConfigureLogger() error = %v", err)
+	}
+	SetLogLevel(LogLevelWarn)
+
+	stop := CaptureLogs(&captured)
+	LogDebug("debug %d", 1)
+	LogWarn("warn %d", 2)
+	stop()
+	LogWarn("after %d", 3)
+
+	if strings.Contains(out.String(), "debug 1") || !strings.Contains(out.String(), "warn 2") {
+		t.Errorf("log output should still honor the log level, got %q", out.String())
+	}
+	got := captured.String()
+	if !strings.Contains(got, "debug 1") || !strings.Contains(got, "warn 2") {
+		t.Errorf("captured logs = %q, want every record", got)
+	}
+	if strings.Contains(got, "after 3") {
+		t.Errorf("captured logs = %q, want nothing after stop", got)
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	originalLevel := logLevel
+	defer func() {
+		logLevel = originalLevel
+		_ = ConfigureLogger(os.Stderr, LogFormatText)
+	}()
+
+	var buf bytes.Buffer
+	if err := ConfigureLogger(&buf, LogFormatJSON); err != nil {
+		t.Fatalf("ConfigureLogger() error = %v", err)
+	}
+
+	SetLogLevel(LogLevelInfo)
+	LogDebugAttrs("Parsed blob", "path", "/tmp/store.db", "key", "abc")
+	if buf.Len() != 0 {
+		t.Fatalf("debug record should be filtered at info level, got %q", buf.String())
+	}
+
+	SetLogLevel(LogLevelDebug)
+	LogDebugAttrs("Parsed blob", "path", "/tmp/store.db", "key", "abc", "session", "s1")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record); err != nil {
+		t.Fatalf("Log line is not valid JSON: %v", err)
+	}
+	if record["msg"] != "Parsed blob" || record["path"] != "/tmp/store.db" || record["key"] != "abc" || record["session"] != "s1" {
+		t.Errorf("Unexpected log record: %v", record)
+	}
+}
+
+func TestCaptureLogs_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			var captured bytes.Buffer
+			stop := CaptureLogs(&captured)
+			stop()
+		}()
+		go func() {
+			defer wg.Done()
+			LogDebug("concurrent %d", 1)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SessionRef is the minimal identity of a session used for lookups
+type SessionRef struct {
+	ID   string
+	Name string
+}
+
+// ResolveSession resolves a session from either an ID (full or prefix) or a name query.
+// Exactly one of id and name should be set. An *AmbiguousSessionError is returned when
+// more than one session matches equally well.
+func ResolveSession(refs []SessionRef, id, name string) (string, error) {
+	switch {
+	case id != "" && name != "":
+		return "", fmt.Errorf("specify either a session ID or a name, not both")
+	case id != "":
+		return ResolveSessionID(refs, id)
+	case name != "":
+		return ResolveSessionName(refs, name)
+	default:
+		return "", fmt.Errorf("a session ID or name is required")
+	}
+}
+
+// ResolveSessionID resolves a full session ID or a unique ID prefix (such as the
+// 8-character short ID shown by list)
+func ResolveSessionID(refs []SessionRef, query string) (string, error) {
+	var matches []SessionRef
+	for _, ref := range refs {
+		if ref.ID == query {
+			return ref.ID, nil
+		}
+		if strings.HasPrefix(ref.ID, query) {
+			matches = append(matches, ref)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session not found: %s (use 'cursor-session list' to see available sessions)", query)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", &AmbiguousSessionError{Query: query, Matches: matches}
+	}
+}
+
+// ResolveSessionName resolves a session by fuzzy name match. Candidates are ranked as
+// exact (case-insensitive) > substring > all words present > characters in order,
+// and only the best-ranked tier is considered.
+func ResolveSessionName(refs []SessionRef, query string) (string, error) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return "", fmt.Errorf("session name query is empty")
+	}
+
+	best := 0
+	var matches []SessionRef
+	for _, ref := range refs {
+		score := nameMatchScore(strings.ToLower(ref.Name), q)
+		if score == 0 || score < best {
+			continue
+		}
+		if score > best {
+			best = score
+			matches = nil
+		}
+		matches = append(matches, ref)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no session name matches %q (use 'cursor-session list' to see available sessions)", query)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", &AmbiguousSessionError{Query: query, ByName: true, Matches: matches}
+	}
+}
+
+// nameMatchScore scores how well a lowercased name matches a lowercased query (0 = no match)
+func nameMatchScore(name, query string) int {
+	if name == "" {
+		return 0
+	}
+	if name == query {
+		return 4
+	}
+	if strings.Contains(name, query) {
+		return 3
+	}
+
+	allWords := true
+	for _, word := range strings.Fields(query) {
+		if !strings.Contains(name, word) {
+			allWords = false
+			break
+		}
+	}
+	if allWords {
+		return 2
+	}
+
+	if isSubsequence(query, name) {
+		return 1
+	}
+	return 0
+}
+
+// isSubsequence reports whether all non-space runes of sub appear in s in order
+func isSubsequence(sub, s string) bool {
+	rest := s
+	for _, r := range sub {
+		if r == ' ' {
+			continue
+		}
+		idx := strings.IndexRune(rest, r)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(string(r)):]
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSessionRefs = []SessionRef{
+	{ID: "3f2a9c1e-1111-4c55-8a2b-000000000001", Name: "Fix flaky tests"},
+	{ID: "3f2a9c1e-2222-4c55-8a2b-000000000002", Name: "Refactor storage layer"},
+	{ID: "7b81d004-3333-4c55-8a2b-000000000003", Name: "Fix flaky tests in CI"},
+	{ID: "9c00aa11-4444-4c55-8a2b-000000000004", Name: ""},
+}
+
+func TestResolveSessionID(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		want          string
+		wantErr       bool
+		wantAmbiguous bool
+	}{
+		{"full ID", "7b81d004-3333-4c55-8a2b-000000000003", "7b81d004-3333-4c55-8a2b-000000000003", false, false},
+		{"short ID", "7b81d004", "7b81d004-3333-4c55-8a2b-000000000003", false, false},
+		{"longer prefix disambiguates", "3f2a9c1e-2", "3f2a9c1e-2222-4c55-8a2b-000000000002", false, false},
+		{"ambiguous prefix", "3f2a9c1e", "", true, true},
+		{"not found", "deadbeef", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSessionID(testSessionRefs, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSessionID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ambiguous *AmbiguousSessionError
+			if errors.As(err, &ambiguous) != tt.wantAmbiguous {
+				t.Errorf("ResolveSessionID() error = %v, wantAmbiguous %v", err, tt.wantAmbiguous)
+			}
+			if ambiguous != nil && ambiguous.ByName {
+				t.Errorf("ResolveSessionID() ambiguous error marked ByName")
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSessionID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSessionName(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		want          string
+		wantErr       bool
+		wantAmbiguous bool
+	}{
+		{"exact match beats substring", "fix flaky tests", "3f2a9c1e-1111-4c55-8a2b-000000000001", false, false},
+		{"unique substring", "storage", "3f2a9c1e-2222-4c55-8a2b-000000000002", false, false},
+		{"all words", "CI flaky", "7b81d004-3333-4c55-8a2b-000000000003", false, false},
+		{"subsequence", "rfctr strg", "3f2a9c1e-2222-4c55-8a2b-000000000002", false, false},
+		{"ambiguous substring", "flaky", "", true, true},
+		{"no match", "kubernetes", "", true, false},
+		{"empty query", "  ", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSessionName(testSessionRefs, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSessionName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ambiguous *AmbiguousSessionError
+			if errors.As(err, &ambiguous) != tt.wantAmbiguous {
+				t.Errorf("ResolveSessionName() error = %v, wantAmbiguous %v", err, tt.wantAmbiguous)
+			}
+			if ambiguous != nil && !ambiguous.ByName {
+				t.Errorf("ResolveSessionName() ambiguous error not marked ByName")
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSessionName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSession(t *testing.T) {
+	if _, err := ResolveSession(testSessionRefs, "", ""); err == nil {
+		t.Error("ResolveSession() should require an ID or name")
+	}
+	if _, err := ResolveSession(testSessionRefs, "7b81d004", "flaky"); err == nil {
+		t.Error("ResolveSession() should reject both ID and name")
+	}
+	if got, err := ResolveSession(testSessionRefs, "", "storage"); err != nil || got != testSessionRefs[1].ID {
+		t.Errorf("ResolveSession() = %q, %v, want %q", got, err, testSessionRefs[1].ID)
+	}
+}
+
+func TestAmbiguousSessionError(t *testing.T) {
+	err := &AmbiguousSessionError{Query: "3f2a", Matches: testSessionRefs[:2]}
+	msg := err.Error()
+	for _, want := range []string{`"3f2a"`, "2 sessions match", testSessionRefs[0].ID, "Refactor storage layer"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("AmbiguousSessionError.Error() should contain %q, got: %q", want, msg)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MessageFilter selects messages within a session by time range and actor. The zero
+// value keeps every message.
+type MessageFilter struct {
+	Since time.Time // Keep messages at or after Since; zero means no lower bound
+	Until time.Time // Keep messages at or before Until; zero means no upper bound
+	Actor string    // One of MessageActors; empty keeps every actor
+}
+
+// NewMessageFilter builds a filter from the --since, --until and --actor flag values.
+// Times are RFC3339 timestamps or dates (YYYY-MM-DD) in the display time zone; a date
+// passed as until includes the whole day.
+func NewMessageFilter(since, until, actor string) (MessageFilter, error) {
+	var filter MessageFilter
+	var err error
+
+	if since != "" {
+		if filter.Since, err = parseFilterTime(since, false); err != nil {
+			return filter, fmt.Errorf("invalid --since timestamp %q (expected RFC3339 or YYYY-MM-DD)", since)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = parseFilterTime(until, true); err != nil {
+			return filter, fmt.Errorf("invalid --until timestamp %q (expected RFC3339 or YYYY-MM-DD)", until)
+		}
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return filter, fmt.Errorf("--until (%s) is before --since (%s)", until, since)
+	}
+
+	switch actor = strings.ToLower(actor); {
+	case actor == "", actor == "all":
+	case slices.Contains(MessageActors(), actor):
+		filter.Actor = actor
+	default:
+		return filter, fmt.Errorf("invalid --actor %q (expected %s or all)", actor, strings.Join(MessageActors(), ", "))
+	}
+
+	return filter, nil
+}
+
+// parseFilterTime parses an RFC3339 timestamp or a date. With endOfDay, a date is moved
+// to its last instant so the bound includes the whole day.
+func parseFilterTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, displayLocation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// IsZero reports whether the filter keeps every message
+func (f MessageFilter) IsZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && f.Actor == ""
+}
+
+// Match reports whether msg passes the filter. Messages without a parseable timestamp
+// never match a time range.
+func (f MessageFilter) Match(msg Message) bool {
+	if f.Actor != "" && msg.Actor != f.Actor {
+		return false
+	}
+	if f.Since.IsZero() && f.Until.IsZero() {
+		return true
+	}
+
+	t, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		return false
+	}
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && t.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Apply returns a copy of session holding only the messages that pass the filter, with
+// the message count updated. The original session is not modified.
+func (f MessageFilter) Apply(session *Session) *Session {
+	if f.IsZero() {
+		return session
+	}
+
+	filtered := *session
+	filtered.Messages = make([]Message, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		if f.Match(msg) {
+			filtered.Messages = append(filtered.Messages, msg)
+		}
+	}
+	filtered.Metadata.MessageCount = len(filtered.Messages)
+	return &filtered
+}
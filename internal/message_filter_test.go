@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewMessageFilter(t *testing.T) {
+	defer func() { _ = ConfigureTimeDisplay("", "") }()
+	if err := ConfigureTimeDisplay("UTC", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		since     string
+		until     string
+		actor     string
+		wantSince time.Time
+		wantUntil time.Time
+		wantActor string
+		wantErr   bool
+	}{
+		{name: "empty", actor: "all"},
+		{
+			name:      "rfc3339",
+			since:     "2024-03-01T12:00:00Z",
+			until:     "2024-03-02T12:00:00Z",
+			actor:     "User",
+			wantSince: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+			wantActor: "user",
+		},
+		{
+			name:      "dates cover whole days",
+			since:     "2024-03-01",
+			until:     "2024-03-01",
+			wantSince: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, 3, 1, 23, 59, 59, 999999999, time.UTC),
+		},
+		{name: "invalid since", since: "last week", wantErr: true},
+		{name: "invalid until", until: "03/01/2024", wantErr: true},
+		{name: "until before since", since: "2024-03-02", until: "2024-03-01", wantErr: true},
+		{name: "terminal output", actor: "Terminal", wantActor: "terminal"},
+		{name: "invalid actor", actor: "reviewer", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewMessageFilter(tt.since, tt.until, tt.actor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMessageFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.Since.Equal(tt.wantSince) || !got.Until.Equal(tt.wantUntil) || got.Actor != tt.wantActor {
+				t.Errorf("NewMessageFilter() = %+v, want since %v until %v actor %q", got, tt.wantSince, tt.wantUntil, tt.wantActor)
+			}
+		})
+	}
+}
+
+func TestMessageFilter_Apply(t *testing.T) {
+	session := &Session{
+		ID: "s1",
+		Messages: []Message{
+			{Actor: "user", Content: "old", Timestamp: "2024-02-28T10:00:00Z"},
+			{Actor: "assistant", Content: "old reply", Timestamp: "2024-02-28T10:01:00Z"},
+			{Actor: "user", Content: "new", Timestamp: "2024-03-05T10:00:00Z"},
+			{Actor: "assistant", Content: "new reply", Timestamp: "2024-03-05T10:01:00Z"},
+			{Actor: "user", Content: "undated"},
+		},
+		Metadata: Metadata{MessageCount: 5},
+	}
+
+	tests := []struct {
+		name   string
+		filter MessageFilter
+		want   []string
+	}{
+		{
+			name:   "zero filter keeps everything",
+			filter: MessageFilter{},
+			want:   []string{"old", "old reply", "new", "new reply", "undated"},
+		},
+		{
+			name:   "actor only keeps undated messages",
+			filter: MessageFilter{Actor: "user"},
+			want:   []string{"old", "new", "undated"},
+		},
+		{
+			name:   "since",
+			filter: MessageFilter{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+			want:   []string{"new", "new reply"},
+		},
+		{
+			name:   "until is inclusive",
+			filter: MessageFilter{Until: time.Date(2024, 2, 28, 10, 1, 0, 0, time.UTC)},
+			want:   []string{"old", "old reply"},
+		},
+		{
+			name: "since and actor",
+			filter: MessageFilter{
+				Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				Actor: "assistant",
+			},
+			want: []string{"new reply"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Apply(session)
+			var contents []string
+			for _, msg := range got.Messages {
+				contents = append(contents, msg.Content)
+			}
+			if len(contents) != len(tt.want) {
+				t.Fatalf("Apply() kept %v, want %v", contents, tt.want)
+			}
+			for i := range contents {
+				if contents[i] != tt.want[i] {
+					t.Errorf("Apply() kept %v, want %v", contents, tt.want)
+					break
+				}
+			}
+			if got.Metadata.MessageCount != len(tt.want) {
+				t.Errorf("Apply() MessageCount = %d, want %d", got.Metadata.MessageCount, len(tt.want))
+			}
+		})
+	}
+
+	if len(session.Messages) != 5 || session.Metadata.MessageCount != 5 {
+		t.Error("Apply() should not modify the original session")
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strategies for messages longer than a MessageLimit
+const (
+	OversizeTruncate = "truncate" // Keep the first MaxBytes bytes followed by a marker
+	OversizeDrop     = "drop"     // Replace the content with a marker
+	OversizeSidecar  = "sidecar"  // Move the content to a file next to the export
+)
+
+// OversizeStrategies lists the accepted --oversize-strategy values
+var OversizeStrategies = []string{OversizeTruncate, OversizeDrop, OversizeSidecar}
+
+// Oversize records that a message was cut down by a MessageLimit
+type Oversize struct {
+	OriginalBytes int    `json:"original_bytes"`
+	Strategy      string `json:"strategy"`
+	Sidecar       string `json:"sidecar,omitempty"` // File holding the full content, with the sidecar strategy
+}
+
+// MessageLimit bounds the size of message content in exports. The zero value keeps every
+// message whole.
+type MessageLimit struct {
+	MaxBytes int    // Largest content kept as is; 0 means no limit
+	Strategy string // One of OversizeStrategies
+}
+
+// Sidecar is a file holding the full content of a message moved out of its session
+type Sidecar struct {
+	Name    string
+	Content []byte
+}
+
+// NewMessageLimit builds a limit from the --max-message-bytes and --oversize-strategy
+// flag values
+func NewMessageLimit(maxBytes int, strategy string) (MessageLimit, error) {
+	if maxBytes < 0 {
+		return MessageLimit{}, fmt.Errorf("invalid --max-message-bytes %d (expected 0 or more)", maxBytes)
+	}
+	strategy = strings.ToLower(strategy)
+	for _, s := range OversizeStrategies {
+		if strategy == s {
+			return MessageLimit{MaxBytes: maxBytes, Strategy: strategy}, nil
+		}
+	}
+	return MessageLimit{}, fmt.Errorf("invalid --oversize-strategy %q (expected %s)", strategy, strings.Join(OversizeStrategies, ", "))
+}
+
+// IsZero reports whether the limit keeps every message whole
+func (l MessageLimit) IsZero() bool {
+	return l.MaxBytes == 0
+}
+
+// SidecarFileName returns the name of the file holding the content of a session's
+// message, numbered from 1
+func SidecarFileName(sessionID string, message int) string {
+	return fmt.Sprintf("session_%s.message_%d.txt", sessionID, message)
+}
+
+// Apply returns a copy of session whose messages longer than MaxBytes are handled by the
+// strategy, each recording its original length in Oversize, along with the sidecar files
+// to write for the sidecar strategy. The original session is not modified.
+func (l MessageLimit) Apply(session *Session) (*Session, []Sidecar) {
+	if l.IsZero() {
+		return session, nil
+	}
+
+	var sidecars []Sidecar
+	limited := *session
+	limited.Messages = make([]Message, len(session.Messages))
+	for i, msg := range session.Messages {
+		size := len(msg.Content)
+		if size <= l.MaxBytes {
+			limited.Messages[i] = msg
+			continue
+		}
+
+		msg.Oversize = &Oversize{OriginalBytes: size, Strategy: l.Strategy}
+		switch l.Strategy {
+		case OversizeDrop:
+			msg.Content = fmt.Sprintf("[message content dropped: %d bytes]", size)
+		case OversizeSidecar:
+			name := SidecarFileName(session.ID, i+1)
+			sidecars = append(sidecars, Sidecar{Name: name, Content: []byte(msg.Content)})
+			msg.Oversize.Sidecar = name
+			msg.Content = fmt.Sprintf("[message content moved to %s: %d bytes]", name, size)
+		default:
+			kept := truncateUTF8(msg.Content, l.MaxBytes)
+			msg.Content = fmt.Sprintf("%s\n[truncated: %d of %d bytes]", kept, len(kept), size)
+		}
+		limited.Messages[i] = msg
+	}
+	return &limited, sidecars
+}
+
+// truncateUTF8 returns the longest prefix of s of at most n bytes that does not split a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMessageLimit(t *testing.T) {
+	if limit, err := NewMessageLimit(1024, "Sidecar"); err != nil || limit.MaxBytes != 1024 || limit.Strategy != OversizeSidecar {
+		t.Errorf("NewMessageLimit(1024, Sidecar) = %+v, %v", limit, err)
+	}
+	if _, err := NewMessageLimit(-1, OversizeTruncate); err == nil {
+		t.Error("NewMessageLimit(-1) succeeded, want an error")
+	}
+	if _, err := NewMessageLimit(1024, "compress"); err == nil {
+		t.Error("NewMessageLimit(compress) succeeded, want an error")
+	}
+}
+
+func TestMessageLimit_Apply(t *testing.T) {
+	long := strings.Repeat("é", 10) // 20 bytes
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "user", Content: "short"},
+		{Actor: "assistant", Content: long},
+	})
+
+	tests := []struct {
+		strategy    string
+		wantContent string
+		wantSidecar string
+	}{
+		{OversizeTruncate, "éééé\n[truncated: 8 of 20 bytes]", ""},
+		{OversizeDrop, "[message content dropped: 20 bytes]", ""},
+		{OversizeSidecar, "[message content moved to session_s1.message_2.txt: 20 bytes]", "session_s1.message_2.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			limited, sidecars := MessageLimit{MaxBytes: 9, Strategy: tt.strategy}.Apply(session)
+
+			if limited.Messages[0].Content != "short" || limited.Messages[0].Oversize != nil {
+				t.Errorf("short message = %+v, want it unchanged", limited.Messages[0])
+			}
+			msg := limited.Messages[1]
+			if msg.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", msg.Content, tt.wantContent)
+			}
+			want := Oversize{OriginalBytes: 20, Strategy: tt.strategy, Sidecar: tt.wantSidecar}
+			if msg.Oversize == nil || *msg.Oversize != want {
+				t.Errorf("Oversize = %+v, want %+v", msg.Oversize, want)
+			}
+
+			if tt.wantSidecar == "" {
+				if len(sidecars) != 0 {
+					t.Errorf("sidecars = %v, want none", sidecars)
+				}
+			} else if len(sidecars) != 1 || sidecars[0].Name != tt.wantSidecar || string(sidecars[0].Content) != long {
+				t.Errorf("sidecars = %v, want the full content in %s", sidecars, tt.wantSidecar)
+			}
+
+			if session.Messages[1].Content != long || session.Messages[1].Oversize != nil {
+				t.Error("Apply() modified the original session")
+			}
+		})
+	}
+
+	if got, _ := (MessageLimit{}).Apply(session); got != session {
+		t.Error("zero MessageLimit should return the session as is")
+	}
+}
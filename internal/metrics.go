@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ExportMetrics are the counters of one export run, for monitoring scheduled exports
+// without scraping their logs
+type ExportMetrics struct {
+	SessionsExported int
+	SessionsFailed   int
+	MessagesExported int
+	ThinkingTokens   int // Estimated tokens of the thinking of the messages exported
+	ParseFailures    int
+	Duration         time.Duration
+	FinishedAt       time.Time
+}
+
+// exportMetric is one ExportMetrics value with its name and help text
+type exportMetric struct {
+	name  string
+	help  string
+	value float64
+}
+
+func (m ExportMetrics) metrics() []exportMetric {
+	return []exportMetric{
+		{"sessions_exported", "Sessions written by the last export run.", float64(m.SessionsExported)},
+		{"sessions_failed", "Sessions the last export run failed to write.", float64(m.SessionsFailed)},
+		{"messages_exported", "Messages in the sessions written by the last export run.", float64(m.MessagesExported)},
+		{"thinking_tokens", "Estimated tokens of model thinking in the messages written by the last export run, whether or not --thinking kept it.", float64(m.ThinkingTokens)},
+		{"parse_failures", "Storage records the last export run could not parse.", float64(m.ParseFailures)},
+		{"duration_seconds", "How long the last export run took.", m.Duration.Seconds()},
+		{"last_run_timestamp_seconds", "Unix time the last export run finished.", float64(m.FinishedAt.Unix())},
+	}
+}
+
+// PrometheusText returns the metrics in the Prometheus text exposition format, as gauges
+// named cursor_session_export_<metric>
+func (m ExportMetrics) PrometheusText() []byte {
+	var buf bytes.Buffer
+	for _, metric := range m.metrics() {
+		name := "cursor_session_export_" + metric.name
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, metric.help)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&buf, "%s %g\n", name, metric.value)
+	}
+	return buf.Bytes()
+}
+
+// WriteMetricsFile writes the metrics to a Prometheus textfile, such as one in the
+// node_exporter textfile collector directory. The file is replaced atomically so the
+// collector never reads it half written.
+func WriteMetricsFile(path string, m ExportMetrics) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(m.PrometheusText()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+// StatsdPayload returns the metrics as statsd lines named cursor_session.export.<metric>:
+// counts as counters and the duration as a timer in milliseconds
+func (m ExportMetrics) StatsdPayload() []byte {
+	var buf bytes.Buffer
+	for _, metric := range m.metrics() {
+		switch metric.name {
+		case "duration_seconds":
+			fmt.Fprintf(&buf, "cursor_session.export.duration:%d|ms\n", m.Duration.Milliseconds())
+		case "last_run_timestamp_seconds":
+			// statsd servers record the time of each push themselves
+		default:
+			fmt.Fprintf(&buf, "cursor_session.export.%s:%d|c\n", metric.name, int(metric.value))
+		}
+	}
+	return buf.Bytes()
+}
+
+// PushStatsd sends the metrics to a statsd server over UDP in a single packet
+func PushStatsd(addr string, m ExportMetrics) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to statsd at %s: %w", addr, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write(m.StatsdPayload()); err != nil {
+		return fmt.Errorf("failed to push metrics to statsd at %s: %w", addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func testExportMetrics() ExportMetrics {
+	return ExportMetrics{
+		SessionsExported: 3,
+		SessionsFailed:   1,
+		MessagesExported: 42,
+		ThinkingTokens:   7,
+		ParseFailures:    2,
+		Duration:         1500 * time.Millisecond,
+		FinishedAt:       time.Unix(1718000000, 0),
+	}
+}
+
+func TestExportMetrics_PrometheusText(t *testing.T) {
+	text := string(testExportMetrics().PrometheusText())
+	for _, want := range []string{
+		"# TYPE cursor_session_export_sessions_exported gauge\ncursor_session_export_sessions_exported 3\n",
+		"cursor_session_export_sessions_failed 1\n",
+		"cursor_session_export_messages_exported 42\n",
+		"cursor_session_export_thinking_tokens 7\n",
+		"cursor_session_export_parse_failures 2\n",
+		"cursor_session_export_duration_seconds 1.5\n",
+		"cursor_session_export_last_run_timestamp_seconds 1.718e+09\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PrometheusText() should contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+	path := filepath.Join(dir, "cursor_session.prom")
+	writeFile(t, path, "stale")
+
+	if err := WriteMetricsFile(path, testExportMetrics()); err != nil {
+		t.Fatalf("WriteMetricsFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read metrics file: %v", err)
+	}
+	if string(data) != string(testExportMetrics().PrometheusText()) {
+		t.Errorf("Metrics file = %q", data)
+	}
+
+	// The temporary file is renamed into place
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the metrics file in %s, got %d entries", dir, len(entries))
+	}
+
+	if err := WriteMetricsFile(filepath.Join(dir, "missing", "metrics.prom"), testExportMetrics()); err == nil {
+		t.Error("WriteMetricsFile() into a missing directory should fail")
+	}
+}
+
+func TestPushStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := PushStatsd(conn.LocalAddr().String(), testExportMetrics()); err != nil {
+		t.Fatalf("PushStatsd() error = %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read statsd packet: %v", err)
+	}
+	want := "cursor_session.export.sessions_exported:3|c\n" +
+		"cursor_session.export.sessions_failed:1|c\n" +
+		"cursor_session.export.messages_exported:42|c\n" +
+		"cursor_session.export.thinking_tokens:7|c\n" +
+		"cursor_session.export.parse_failures:2|c\n" +
+		"cursor_session.export.duration:1500|ms\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("statsd packet = %q, want %q", got, want)
+	}
+
+	if err := PushStatsd("not an address", testExportMetrics()); err == nil {
+		t.Error("PushStatsd() with an invalid address should fail")
+	}
+}
@@ -15,6 +15,40 @@ type RawBubble struct {
 	CodeBlocks []CodeBlock `json:"codeBlocks,omitempty"`
 	Timestamp  int64       `json:"timestamp"`
 	Type       int         `json:"type"` // 1=user, 2=assistant
+	// ToolFormerData is the tool call the bubble made, when Cursor recorded one
+	ToolFormerData *ToolFormerData `json:"toolFormerData,omitempty"`
+	// Thinking is the reasoning the model recorded before its reply, when Cursor kept it
+	Thinking *BubbleThinking `json:"thinking,omitempty"`
+	// CheckpointID is the checkpoint Cursor recorded the bubble's file edits under
+	CheckpointID string `json:"checkpointId,omitempty"`
+	// Images are the images attached to the message; Cursor records them on the bubble or
+	// as the selected images of its context
+	Images     []BubbleImage  `json:"images,omitempty"`
+	Context    *BubbleContext `json:"context,omitempty"`
+	Provenance *Provenance    `json:"-"` // Set by the storage backend that loaded the bubble
+}
+
+// ToolFormerData is a tool call as Cursor records it on a bubble
+type ToolFormerData struct {
+	Name    string `json:"name"`
+	RawArgs string `json:"rawArgs,omitempty"`
+	Result  string `json:"result,omitempty"`
+}
+
+// BubbleThinking is the reasoning Cursor records on a bubble
+type BubbleThinking struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// MessageType returns the type of the bubble's message: the type its conversation
+// header records, unless the bubble holds a tool call, whose output makes it a tool or
+// terminal message
+func (b *RawBubble) MessageType(headerType int) int {
+	if b.ToolFormerData != nil && b.ToolFormerData.Name != "" {
+		return toolMessageType(b.ToolFormerData.Name)
+	}
+	return headerType
 }
 
 // CodeBlock represents a code block in a message
@@ -23,6 +57,24 @@ type CodeBlock struct {
 	Content  string `json:"content"`
 }
 
+// UnmarshalJSON reads the code block language from "language" or, as Cursor stores it, "languageId"
+func (cb *CodeBlock) UnmarshalJSON(data []byte) error {
+	type codeBlockAlias CodeBlock
+	var raw struct {
+		codeBlockAlias
+		LanguageID string `json:"languageId"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*cb = CodeBlock(raw.codeBlockAlias)
+	if cb.Language == "" {
+		cb.Language = raw.LanguageID
+	}
+	return nil
+}
+
 // RawComposer represents composer data from the database
 type RawComposer struct {
 	ComposerID                  string               `json:"composerId"`
@@ -30,12 +82,24 @@ type RawComposer struct {
 	FullConversationHeadersOnly []ConversationHeader `json:"fullConversationHeadersOnly,omitempty"`
 	LastUpdatedAt               int64                `json:"lastUpdatedAt,omitempty"`
 	CreatedAt                   int64                `json:"createdAt,omitempty"`
+	// ParentID is the session this one was resumed from, when the storage records it
+	ParentID string `json:"parentId,omitempty"`
+	// WorkspaceHash is the chats/{hash} directory of a cursor-agent session, which names
+	// the workspace it was started in
+	WorkspaceHash string `json:"workspaceHash,omitempty"`
+	// CodeBlockData maps file URIs to the code blocks applied to them, linking diff IDs to bubbles
+	CodeBlockData map[string]json.RawMessage `json:"codeBlockData,omitempty"`
+	// Fields are the metadata fields the normalizer rules map from the composer's fields
+	Fields map[string]interface{} `json:"-"`
 }
 
 // ConversationHeader represents a header in a conversation
 type ConversationHeader struct {
 	BubbleID string `json:"bubbleId"`
 	Type     int    `json:"type"` // 1=user, 2=assistant
+	// ParentBubbleID is the message this one follows, when it isn't the previous header,
+	// as recorded for an edited message that forks the conversation
+	ParentBubbleID string `json:"parentBubbleId,omitempty"`
 }
 
 // MessageContext represents context data for a message
@@ -58,6 +122,8 @@ func ParseRawBubble(key, value string) (*RawBubble, error) {
 		return nil, fmt.Errorf("invalid bubbleId key format: %s", key)
 	}
 
+	// Values written by Windows builds may be UTF-16 or start with a byte order mark
+	value, _ = decodeTextValue(value)
 	var bubble RawBubble
 	if err := json.Unmarshal([]byte(value), &bubble); err != nil {
 		return nil, fmt.Errorf("failed to parse bubble JSON: %w", err)
@@ -77,12 +143,14 @@ func ParseRawComposer(key, value string) (*RawComposer, error) {
 		return nil, fmt.Errorf("invalid composerData key format: %s", key)
 	}
 
+	value, _ = decodeTextValue(value)
 	var composer RawComposer
 	if err := json.Unmarshal([]byte(value), &composer); err != nil {
 		return nil, fmt.Errorf("failed to parse composer JSON: %w", err)
 	}
 
 	composer.ComposerID = parts[1]
+	composer.Fields = normalizerRules.composerFieldsFromJSON(value)
 
 	return &composer, nil
 }
@@ -95,6 +163,7 @@ func ParseMessageContext(key, value string) (*MessageContext, error) {
 		return nil, fmt.Errorf("invalid messageRequestContext key format: %s", key)
 	}
 
+	value, _ = decodeTextValue(value)
 	var context MessageContext
 	if err := json.Unmarshal([]byte(value), &context); err != nil {
 		return nil, fmt.Errorf("failed to parse context JSON: %w", err)
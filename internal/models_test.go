@@ -410,3 +410,55 @@ func TestRawComposer_ToIntermediaryYAML(t *testing.T) {
 		t.Errorf("ToIntermediaryYAML() ComposerID = %q, want %q", decoded.ComposerID, composer.ComposerID)
 	}
 }
+
+func TestCodeBlock_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLang string
+	}{
+		{"language field", `{"language":"go","content":"x"}`, "go"},
+		{"languageId field", `{"languageId":"typescript","content":"x"}`, "typescript"},
+		{"language wins over languageId", `{"language":"go","languageId":"plaintext","content":"x"}`, "go"},
+		{"no language", `{"content":"x"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cb CodeBlock
+			if err := json.Unmarshal([]byte(tt.input), &cb); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if cb.Language != tt.wantLang {
+				t.Errorf("Language = %q, want %q", cb.Language, tt.wantLang)
+			}
+			if cb.Content != "x" {
+				t.Errorf("Content = %q, want x", cb.Content)
+			}
+		})
+	}
+}
+
+func TestRawBubble_MessageType(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{name: "assistant text", value: `{"type":2,"text":"Done"}`, want: MessageTypeAssistant},
+		{name: "tool call", value: `{"type":2,"toolFormerData":{"name":"read_file","result":"package main"}}`, want: MessageTypeTool},
+		{name: "terminal command", value: `{"type":2,"toolFormerData":{"name":"run_terminal_cmd","rawArgs":"{\"command\":\"ls\"}"}}`, want: MessageTypeTerminal},
+		{name: "unnamed tool call", value: `{"type":2,"toolFormerData":{}}`, want: MessageTypeAssistant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bubble, err := ParseRawBubble("bubbleId:chat:b1", tt.value)
+			if err != nil {
+				t.Fatalf("ParseRawBubble() error = %v", err)
+			}
+			if got := bubble.MessageType(bubble.Type); got != tt.want {
+				t.Errorf("MessageType() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,15 +3,23 @@ package internal
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
 // Normalizer converts reconstructed conversations to Session format
-type Normalizer struct{}
+type Normalizer struct {
+	summarizer Summarizer
+}
 
-// NewNormalizer creates a new Normalizer
+// NewNormalizer creates a new Normalizer that titles unnamed sessions after their first user message
 func NewNormalizer() *Normalizer {
-	return &Normalizer{}
+	return &Normalizer{summarizer: FirstMessageSummarizer{}}
+}
+
+// SetSummarizer replaces the summarizer used to title unnamed sessions; nil disables generated titles
+func (n *Normalizer) SetSummarizer(summarizer Summarizer) {
+	n.summarizer = summarizer
 }
 
 // NormalizeConversation converts a ReconstructedConversation to a Session
@@ -49,17 +57,30 @@ func (n *Normalizer) NormalizeConversation(conv *ReconstructedConversation, work
 
 	// Convert to normalized messages
 	messages := make([]Message, 0, len(conv.Messages))
-	for _, mwi := range msgsWithIndex {
+	timestamps := make([]int64, 0, len(conv.Messages))
+	for i, mwi := range msgsWithIndex {
 		normalizedMsg := n.normalizeMessage(mwi.msg)
+		normalizedMsg.Attachments = messageAttachments(mwi.msg.Images, i+1)
 		messages = append(messages, normalizedMsg)
+		timestamps = append(timestamps, mwi.msg.Timestamp)
 	}
+	setResponseTimes(messages, timestamps)
 
 	// Create metadata
 	metadata := Metadata{
 		ComposerID:   conv.ComposerID,
 		Name:         conv.Name,
 		MessageCount: len(messages),
+		ParentID:     conv.ParentID,
+		Git:          sessionGitInfo(conv),
+		Branch:       conv.Branch,
+		Fields:       conv.Fields,
+	}
+	if conv.Extraction.Total() > 0 {
+		extraction := conv.Extraction
+		metadata.Extraction = &extraction
 	}
+	metadata.FilesChanged = sessionFilesChanged(conv)
 
 	if conv.CreatedAt > 0 {
 		metadata.CreatedAt = formatTimestamp(conv.CreatedAt)
@@ -68,15 +89,69 @@ func (n *Normalizer) NormalizeConversation(conv *ReconstructedConversation, work
 		metadata.UpdatedAt = formatTimestamp(conv.UpdatedAt)
 	}
 
+	// Title sessions Cursor left unnamed (most cursor-agent sessions)
+	if metadata.Name == "" && n.summarizer != nil {
+		candidates := messages
+		if placeholder, keep := normalizerRules.keepPlaceholder(); keep {
+			// Messages kept without text don't make a title
+			candidates = make([]Message, 0, len(messages))
+			for _, msg := range messages {
+				if msg.Content != placeholder {
+					candidates = append(candidates, msg)
+				}
+			}
+		}
+		title, err := n.summarizer.Summarize(candidates)
+		if err != nil {
+			LogDebug("Failed to generate title for session %s: %v", sessionID, err)
+		} else if title != "" {
+			metadata.Name = title
+			metadata.GeneratedName = true
+		}
+	}
+
 	return &Session{
-		ID:        sessionID,
-		Workspace: workspace,
-		Source:    "globalStorage",
-		Messages:  messages,
-		Metadata:  metadata,
+		ID:            sessionID,
+		Workspace:     workspace,
+		WorkspaceHash: conv.WorkspaceHash,
+		Source:        sessionSource(messages),
+		Messages:      messages,
+		Metadata:      metadata,
+		Diffs:         conv.Diffs,
 	}, nil
 }
 
+// sessionSource returns the backends a session's messages were read from, joined with
+// "+" when a merged backend combined several, or globalStorage when none recorded one
+func sessionSource(messages []Message) string {
+	seen := make(map[string]bool)
+	var backends []string
+	for _, msg := range messages {
+		if msg.Provenance == nil || msg.Provenance.Backend == "" || seen[msg.Provenance.Backend] {
+			continue
+		}
+		seen[msg.Provenance.Backend] = true
+		backends = append(backends, msg.Provenance.Backend)
+	}
+	if len(backends) == 0 {
+		return BackendGlobalStorage
+	}
+	sort.Strings(backends)
+	return strings.Join(backends, "+")
+}
+
+// sessionFilesChanged returns the files changed by a conversation's messages and by the
+// edits that could not be matched to one
+func sessionFilesChanged(conv *ReconstructedConversation) []string {
+	diffs := append([]CodeDiff(nil), conv.Diffs...)
+	edits := append([]FileEdit(nil), conv.FileEdits...)
+	for _, msg := range conv.Messages {
+		diffs = append(diffs, msg.Diffs...)
+		edits = append(edits, msg.FileEdits...)
+	}
+	return FilesChanged(diffs, edits)
+}
+
 // normalizeMessage converts a ReconstructedMessage to a Message
 func (n *Normalizer) normalizeMessage(msg ReconstructedMessage) Message {
 	actor := n.normalizeActor(msg.Type)
@@ -86,22 +161,48 @@ func (n *Normalizer) normalizeMessage(msg ReconstructedMessage) Message {
 	}
 
 	return Message{
-		Timestamp: timestamp,
-		Actor:     actor,
-		Content:   msg.Text,
+		Timestamp:   timestamp,
+		Actor:       actor,
+		Content:     msg.Text,
+		Thinking:    msg.Thinking,
+		Provenance:  msg.Provenance,
+		Diffs:       msg.Diffs,
+		FileEdits:   msg.FileEdits,
+		Environment: messageEnvironment(msg.Context),
+	}
+}
+
+// messageEnvironment returns the environment recorded in a message's context, or nil if
+// it recorded none
+func messageEnvironment(ctx *MessageContext) *Environment {
+	if ctx == nil {
+		return nil
+	}
+	env := &Environment{Git: ParseGitStatus(ctx.GitStatusRaw), TerminalFiles: ctx.TerminalFiles}
+	if env.Git == nil && len(env.TerminalFiles) == 0 {
+		return nil
+	}
+	return env
+}
+
+// messageAttachments returns the attachments of the images of a message, numbered from 1
+func messageAttachments(images []BubbleImage, message int) []Attachment {
+	var attachments []Attachment
+	for _, img := range images {
+		if att, ok := newAttachment(img, message, len(attachments)+1); ok {
+			attachments = append(attachments, att)
+		}
 	}
+	return attachments
 }
 
-// normalizeActor converts type (1 or 2) to actor string
+// normalizeActor converts a message type to its actor string, as the normalizer rules map
+// it or else as built in
 func (n *Normalizer) normalizeActor(msgType int) string {
-	switch msgType {
-	case 1:
-		return "user"
-	case 2:
-		return "assistant"
-	default:
-		return "user" // Default fallback
+	if actor, ok := normalizerRules.actor(msgType); ok {
+		return actor
 	}
+	return ActorForMessageType(msgType)
 }
 
 // generateSessionID is deprecated - we now use composerId directly as the session ID
@@ -111,10 +212,10 @@ func (n *Normalizer) generateSessionID(conv *ReconstructedConversation) string {
 	return conv.ComposerID
 }
 
-// formatTimestamp formats a Unix timestamp (milliseconds) to ISO8601
+// formatTimestamp formats a Unix timestamp (milliseconds) to RFC3339 in UTC
 func formatTimestamp(ts int64) string {
 	t := time.Unix(0, ts*int64(time.Millisecond))
-	return t.Format(time.RFC3339)
+	return t.UTC().Format(time.RFC3339)
 }
 
 // NormalizeAllConversations normalizes all conversations to sessions
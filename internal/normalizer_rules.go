@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// NormalizerRulesFile is the rules file read from the config directory when no other
+	// is given
+	NormalizerRulesFile = "normalizer.yaml"
+	// EnvNormalizerRules names the rules file, as --rules does
+	EnvNormalizerRules = "CURSOR_SESSION_RULES"
+	// placeholderText is the text of a message whose bubble had none that could be extracted
+	placeholderText = "[Message with no extractable text content]"
+)
+
+// NormalizerRules adjust how conversations are turned into sessions: the actor of each
+// message type, whether messages without text are kept, and composer fields copied into
+// the metadata of sessions
+type NormalizerRules struct {
+	// Actors maps message types to actors, overriding the built-in mapping, so that bubble
+	// types Cursor adds are not taken for user messages
+	Actors map[int]string `yaml:"actors,omitempty" json:"actors,omitempty"`
+	// Placeholders decides what becomes of messages whose bubble had no text to extract
+	Placeholders PlaceholderRule `yaml:"placeholders,omitempty" json:"placeholders,omitempty"`
+	// Metadata maps fields of the sessions' metadata to composer fields, by dotted path
+	// such as modelConfig.modelName
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// PlaceholderRule decides what becomes of messages whose bubble had no text to extract.
+// They are dropped unless Keep is set; kept messages read Text, or a note that they had
+// no text when it is empty.
+type PlaceholderRule struct {
+	Keep bool   `yaml:"keep,omitempty" json:"keep,omitempty"`
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+}
+
+// normalizerRules are the rules in effect, nil for the built-in behavior
+var normalizerRules *NormalizerRules
+
+// SetNormalizerRules sets the rules conversations are normalized with; nil restores the
+// built-in behavior
+func SetNormalizerRules(rules *NormalizerRules) {
+	normalizerRules = rules
+}
+
+// ActiveNormalizerRules returns the rules in effect, nil when there are none
+func ActiveNormalizerRules() *NormalizerRules {
+	return normalizerRules
+}
+
+// ParseNormalizerRules parses and checks rules written in YAML. Unknown keys are errors,
+// so that a misspelled rule isn't silently ignored.
+func ParseNormalizerRules(data []byte) (*NormalizerRules, error) {
+	var rules NormalizerRules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	actors := make(map[string]bool)
+	for _, actor := range MessageActors() {
+		actors[actor] = true
+	}
+	for msgType, actor := range rules.Actors {
+		if !actors[actor] {
+			return nil, fmt.Errorf("actor %q of message type %d is not one of %s", actor, msgType, strings.Join(MessageActors(), ", "))
+		}
+	}
+	for field, path := range rules.Metadata {
+		if strings.TrimSpace(field) == "" {
+			return nil, fmt.Errorf("metadata field mapped to %q has no name", path)
+		}
+		if strings.TrimSpace(path) == "" || strings.Contains(path, "..") || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") {
+			return nil, fmt.Errorf("metadata field %q has an invalid composer path %q", field, path)
+		}
+	}
+	return &rules, nil
+}
+
+// LoadNormalizerRules reads the rules file at path
+func LoadNormalizerRules(path string) (*NormalizerRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rules, err := ParseNormalizerRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// DefaultNormalizerRulesPath returns the rules file in the config directory, whether or
+// not it exists
+func DefaultNormalizerRulesPath() (string, error) {
+	dirs, err := ResolveAppDirs()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dirs.ConfigDir, NormalizerRulesFile), nil
+}
+
+// Fingerprint identifies what the rules change, so that sessions normalized with other
+// rules can be told apart. It is empty when they change nothing.
+func (r *NormalizerRules) Fingerprint() string {
+	if r == nil || (len(r.Actors) == 0 && !r.Placeholders.Keep && len(r.Metadata) == 0) {
+		return ""
+	}
+	// Maps are encoded with sorted keys, so the same rules always give the same fingerprint
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// actor returns the actor the rules give a message type
+func (r *NormalizerRules) actor(msgType int) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	actor, ok := r.Actors[msgType]
+	return actor, ok
+}
+
+// keepPlaceholder returns the text of a message whose bubble had none to extract, and
+// whether the rules keep such messages
+func (r *NormalizerRules) keepPlaceholder() (string, bool) {
+	if r == nil || !r.Placeholders.Keep {
+		return "", false
+	}
+	if r.Placeholders.Text != "" {
+		return r.Placeholders.Text, true
+	}
+	return placeholderText, true
+}
+
+// mapsMetadata reports whether the rules copy composer fields into session metadata
+func (r *NormalizerRules) mapsMetadata() bool {
+	return r != nil && len(r.Metadata) > 0
+}
+
+// composerFields returns the metadata fields the rules map from the fields of a composer's
+// JSON document, leaving out those it doesn't have
+func (r *NormalizerRules) composerFields(data map[string]interface{}) map[string]interface{} {
+	if !r.mapsMetadata() {
+		return nil
+	}
+	var fields map[string]interface{}
+	for field, path := range r.Metadata {
+		value, ok := lookupPath(data, path)
+		if !ok || value == nil {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{}, len(r.Metadata))
+		}
+		fields[field] = value
+	}
+	return fields
+}
+
+// composerFieldsFromJSON returns the metadata fields the rules map from a composer's JSON
+// document, which is only decoded when the rules map any
+func (r *NormalizerRules) composerFieldsFromJSON(value string) map[string]interface{} {
+	if !r.mapsMetadata() {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil
+	}
+	return r.composerFields(data)
+}
+
+// lookupPath returns the value at a dotted path of nested JSON objects
+func lookupPath(data map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// mergeFields adds the fields of extra that fields doesn't have
+func mergeFields(fields, extra map[string]interface{}) map[string]interface{} {
+	for key, value := range extra {
+		if _, ok := fields[key]; ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{}, len(extra))
+		}
+		fields[key] = value
+	}
+	return fields
+}
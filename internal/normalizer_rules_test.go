@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestParseNormalizerRules(t *testing.T) {
+	rules, err := ParseNormalizerRules([]byte(`
+actors:
+  30: system
+  3: terminal
+placeholders:
+  keep: true
+  text: "[no text]"
+metadata:
+  model: modelConfig.modelName
+  mode: unifiedMode
+`))
+	if err != nil {
+		t.Fatalf("ParseNormalizerRules() error = %v", err)
+	}
+	if rules.Actors[30] != ActorSystem || rules.Actors[3] != ActorTerminal {
+		t.Errorf("Actors = %v", rules.Actors)
+	}
+	if text, keep := rules.keepPlaceholder(); !keep || text != "[no text]" {
+		t.Errorf("keepPlaceholder() = %q, %t, want the rule's text", text, keep)
+	}
+	if rules.Metadata["model"] != "modelConfig.modelName" {
+		t.Errorf("Metadata = %v", rules.Metadata)
+	}
+
+	if empty, err := ParseNormalizerRules(nil); err != nil || empty.Fingerprint() != "" {
+		t.Errorf("ParseNormalizerRules() of an empty file = %+v, %v, want rules that change nothing", empty, err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown key", "actor:\n  30: system\n", "field actor not found"},
+		{"unknown actor", "actors:\n  30: robot\n", `actor "robot" of message type 30`},
+		{"type not a number", "actors:\n  bot: system\n", "cannot unmarshal"},
+		{"empty path", "metadata:\n  model: \"\"\n", `metadata field "model"`},
+		{"bad path", "metadata:\n  model: modelConfig..name\n", "invalid composer path"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseNormalizerRules([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseNormalizerRules() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNormalizerRules(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), NormalizerRulesFile)
+	if err := os.WriteFile(path, []byte("placeholders:\n  keep: yes please\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadNormalizerRules(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadNormalizerRules() error = %v, want the invalid file named", err)
+	}
+	if _, err := LoadNormalizerRules(filepath.Join(filepath.Dir(path), "missing.yaml")); err == nil {
+		t.Error("LoadNormalizerRules() of a missing file should fail")
+	}
+}
+
+func TestNormalizerRules_Fingerprint(t *testing.T) {
+	a := &NormalizerRules{Actors: map[int]string{30: ActorSystem, 31: ActorTool}}
+	b := &NormalizerRules{Actors: map[int]string{31: ActorTool, 30: ActorSystem}}
+	if a.Fingerprint() == "" || a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("Fingerprint() = %q and %q, want the same for the same rules", a.Fingerprint(), b.Fingerprint())
+	}
+	if c := (&NormalizerRules{Actors: map[int]string{30: ActorTool}}); c.Fingerprint() == a.Fingerprint() {
+		t.Error("Fingerprint() is the same for different rules")
+	}
+	var none *NormalizerRules
+	if none.Fingerprint() != "" || (&NormalizerRules{}).Fingerprint() != "" {
+		t.Error("Fingerprint() of no rules should be empty")
+	}
+}
+
+func TestNormalizeConversation_Rules(t *testing.T) {
+	defer SetNormalizerRules(nil)
+	SetNormalizerRules(&NormalizerRules{
+		Actors:       map[int]string{30: ActorSystem, MessageTypeTool: ActorTerminal},
+		Placeholders: PlaceholderRule{Keep: true},
+		Metadata:     map[string]string{"model": "modelConfig.modelName", "mode": "unifiedMode", "missing": "nowhere"},
+	})
+
+	composer, err := ParseRawComposer("composerData:composer1", `{"fullConversationHeadersOnly": [{"bubbleId": "b1", "type": 1}, {"bubbleId": "b2", "type": 30}, {"bubbleId": "b3", "type": 3}, {"bubbleId": "b4", "type": 2}], "modelConfig": {"modelName": "gpt-5"}, "unifiedMode": "agent"}`)
+	if err != nil {
+		t.Fatalf("ParseRawComposer() error = %v", err)
+	}
+	bubbleMap := NewBubbleMap()
+	// The first message has no text, so a title is made from none
+	bubbleMap.Set("b1", CreateTestRawBubble("b1", "composer1", "", 1))
+	bubbleMap.Set("b2", CreateTestRawBubble("b2", "composer1", "You are a helpful agent", 30))
+	bubbleMap.Set("b3", CreateTestRawBubble("b3", "composer1", "ls output", 3))
+	bubbleMap.Set("b4", CreateTestRawBubble("b4", "composer1", "Done", 2))
+
+	conv, err := NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+
+	var actors []string
+	for _, msg := range session.Messages {
+		actors = append(actors, msg.Actor)
+	}
+	if got := strings.Join(actors, ","); got != "user,system,terminal,assistant" {
+		t.Errorf("actors = %s, want the placeholder kept and the mapped types", got)
+	}
+	if session.Messages[0].Content != placeholderText {
+		t.Errorf("kept placeholder content = %q", session.Messages[0].Content)
+	}
+	if session.Metadata.Name != "" {
+		t.Errorf("Name = %q, want no title made from the placeholder", session.Metadata.Name)
+	}
+	fields := session.Metadata.Fields
+	if len(fields) != 2 || fields["model"] != "gpt-5" || fields["mode"] != "agent" {
+		t.Errorf("Fields = %v, want the mapped composer fields that exist", fields)
+	}
+
+	// Without rules the placeholder is dropped and unknown types are users
+	SetNormalizerRules(nil)
+	composer, _ = ParseRawComposer("composerData:composer1", `{"fullConversationHeadersOnly": [{"bubbleId": "b1", "type": 1}, {"bubbleId": "b2", "type": 30}], "unifiedMode": "agent"}`)
+	conv, _ = NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+	session, _ = NewNormalizer().NormalizeConversation(conv, "")
+	if len(session.Messages) != 1 || session.Messages[0].Actor != ActorUser || session.Metadata.Fields != nil {
+		t.Errorf("session without rules = %+v", session)
+	}
+}
+
+func TestParseComposerFromData_RulesFields(t *testing.T) {
+	defer SetNormalizerRules(nil)
+	SetNormalizerRules(&NormalizerRules{Metadata: map[string]string{"model": "model.name"}})
+
+	composer, err := parseComposerFromData("key", map[string]interface{}{
+		"composerId": "agent1",
+		"model":      map[string]interface{}{"name": "claude"},
+	})
+	if err != nil {
+		t.Fatalf("parseComposerFromData() error = %v", err)
+	}
+	if composer.Fields["model"] != "claude" {
+		t.Errorf("Fields = %v, want the mapped field", composer.Fields)
+	}
+
+	// Fields the composer has are kept over those of the session's meta
+	merged := mergeFields(composer.Fields, map[string]interface{}{"model": "other", "mode": "ask"})
+	if merged["model"] != "claude" || merged["mode"] != "ask" {
+		t.Errorf("mergeFields() = %v", merged)
+	}
+}
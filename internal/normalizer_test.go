@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -13,8 +14,11 @@ func TestNormalizeActor(t *testing.T) {
 	}{
 		{1, "user"},
 		{2, "assistant"},
+		{3, "tool"},
+		{4, "terminal"},
+		{5, "system"},
 		{0, "user"}, // default
-		{3, "user"}, // default
+		{9, "user"}, // default
 	}
 
 	for _, tt := range tests {
@@ -105,6 +109,144 @@ func TestNormalizeConversation(t *testing.T) {
 				if len(session.Messages) != len(tt.conv.Messages) {
 					t.Errorf("Session.Messages length = %d, want %d", len(session.Messages), len(tt.conv.Messages))
 				}
+				if got := session.Messages[len(session.Messages)-1].ResponseMs; got != 1000 {
+					t.Errorf("reply ResponseMs = %d, want 1000", got)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeConversation_GeneratedTitles(t *testing.T) {
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: 2, Text: "How can I help?"},
+			{Type: 1, Text: "Fix the flaky cache test"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		convName      string
+		summarizer    Summarizer
+		wantName      string
+		wantGenerated bool
+	}{
+		{"unnamed session is titled", "", FirstMessageSummarizer{}, "Fix the flaky cache test", true},
+		{"Cursor name is kept", "Cache fixes", FirstMessageSummarizer{}, "Cache fixes", false},
+		{"titles disabled", "", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizer := NewNormalizer()
+			normalizer.SetSummarizer(tt.summarizer)
+			c := *conv
+			c.Name = tt.convName
+
+			session, err := normalizer.NormalizeConversation(&c, "")
+			if err != nil {
+				t.Fatalf("NormalizeConversation() error = %v", err)
+			}
+			if session.Metadata.Name != tt.wantName || session.Metadata.GeneratedName != tt.wantGenerated {
+				t.Errorf("Metadata name = %q (generated %v), want %q (generated %v)",
+					session.Metadata.Name, session.Metadata.GeneratedName, tt.wantName, tt.wantGenerated)
+			}
+		})
+	}
+}
+
+func TestNormalizeConversation_FilesChanged(t *testing.T) {
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: 1, Text: "Fix it", Timestamp: 1000},
+			{
+				Type: 2, Text: "Fixed", Timestamp: 2000,
+				Diffs:     []CodeDiff{{FilePath: "/repo/b.go"}},
+				FileEdits: []FileEdit{{FilePath: "/repo/a.go", Action: FileEditApply}},
+			},
+		},
+		FileEdits: []FileEdit{{FilePath: "/repo/c.go", Action: FileEditReject}},
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	want := []string{"/repo/a.go", "/repo/b.go"}
+	if !reflect.DeepEqual(session.Metadata.FilesChanged, want) {
+		t.Errorf("FilesChanged = %v, want %v", session.Metadata.FilesChanged, want)
+	}
+	if len(session.Messages[1].FileEdits) != 1 {
+		t.Errorf("message file edits = %+v, want the a.go edit", session.Messages[1].FileEdits)
+	}
+}
+
+func TestNormalizeConversation_Environment(t *testing.T) {
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: 1, Text: "Fix it", Context: &MessageContext{GitStatusRaw: "## main\n M a.go\n", TerminalFiles: []string{"/repo/t1.log"}}},
+			{Type: 2, Text: "Fixed", Context: &MessageContext{ContextID: "bare"}},
+			{Type: 2, Text: "Done"},
+		},
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	want := &Environment{Git: &GitInfo{Branch: "main", DirtyFiles: []string{"a.go"}}, TerminalFiles: []string{"/repo/t1.log"}}
+	if !reflect.DeepEqual(session.Messages[0].Environment, want) {
+		t.Errorf("Environment = %+v, want %+v", session.Messages[0].Environment, want)
+	}
+	for _, msg := range session.Messages[1:] {
+		if msg.Environment != nil {
+			t.Errorf("Environment of %q = %+v, want nil", msg.Content, msg.Environment)
+		}
+	}
+}
+
+func TestNormalizeConversation_Source(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []ReconstructedMessage
+		want     string
+	}{
+		{
+			name:     "no provenance",
+			messages: []ReconstructedMessage{{Type: 1, Text: "Hello"}},
+			want:     BackendGlobalStorage,
+		},
+		{
+			name: "agent storage",
+			messages: []ReconstructedMessage{
+				{Type: 1, Text: "Hello", Provenance: &Provenance{Backend: BackendAgentStorage}},
+				{Type: 2, Text: "Hi", Provenance: &Provenance{Backend: BackendAgentStorage}},
+			},
+			want: BackendAgentStorage,
+		},
+		{
+			name: "merged backends",
+			messages: []ReconstructedMessage{
+				{Type: 1, Text: "Hello", Provenance: &Provenance{Backend: BackendGlobalStorage}},
+				{Type: 2, Text: "Hi", Provenance: &Provenance{Backend: BackendAgentStorage}},
+			},
+			want: "agentStorage+globalStorage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := &ReconstructedConversation{ComposerID: "composer1", Messages: tt.messages}
+			session, err := NewNormalizer().NormalizeConversation(conv, "")
+			if err != nil {
+				t.Fatalf("NormalizeConversation() error = %v", err)
+			}
+			if session.Source != tt.want {
+				t.Errorf("Source = %q, want %q", session.Source, tt.want)
 			}
 		})
 	}
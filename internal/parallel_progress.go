@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// progressLogInterval is how often progress is logged when it can't be drawn
+	progressLogInterval = 2 * time.Second
+	// maxBoardTasks is how many running tasks a progress board shows a line for
+	maxBoardTasks = 8
+	// maxBoardLineWidth keeps the lines of a progress board from wrapping, which would
+	// throw off redrawing them
+	maxBoardLineWidth = 78
+)
+
+var spinnerChars = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+var (
+	// terminalMu serializes the spinners and progress boards drawing on stderr
+	terminalMu sync.Mutex
+	// boardsDrawing counts the progress boards drawing; spinners pause while any is
+	boardsDrawing int
+	// gumSpinners counts the gum spinners running, which can't be paused
+	gumSpinners int
+)
+
+// ShowParallelProgress runs steps on up to workers goroutines (one per step when workers is
+// not positive). On a terminal it draws a header counting the steps finished and a line
+// per running step with how long it has run, pausing any spinner it runs under; elsewhere
+// it logs how many steps are done every few seconds. It ends with a summary line. Every
+// step runs even when others fail, and the errors of those that failed are returned
+// joined, each prefixed with the step's message; steps are expected to log the details of
+// their own failures. Steps not yet started when ctx is done are skipped.
+func ShowParallelProgress(ctx context.Context, message string, steps []ProgressStep, workers int) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	if workers <= 0 || workers > len(steps) {
+		workers = len(steps)
+	}
+
+	terminalMu.Lock()
+	draw := !Quiet() && isTerminal(os.Stderr) && gumSpinners == 0
+	if draw {
+		boardsDrawing++
+	}
+	terminalMu.Unlock()
+	board := newProgressBoard(os.Stderr, message, len(steps), draw)
+	defer func() {
+		if draw {
+			terminalMu.Lock()
+			boardsDrawing--
+			terminalMu.Unlock()
+		}
+	}()
+
+	stopDrawing := make(chan struct{})
+	drawingDone := make(chan struct{})
+	go func() {
+		defer close(drawingDone)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopDrawing:
+				return
+			case <-ticker.C:
+				board.tick()
+			}
+		}
+	}()
+
+	errs := make([]error, len(steps))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				task := board.start(steps[i].Message)
+				err := steps[i].Fn()
+				board.finish(task, err)
+				if err != nil {
+					errs[i] = fmt.Errorf("%s: %w", steps[i].Message, err)
+				}
+			}
+		}()
+	}
+	var ctxErr error
+feed:
+	for i := range steps {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(stopDrawing)
+	<-drawingDone
+
+	board.summary()
+	return errors.Join(append([]error{ctxErr}, errs...)...)
+}
+
+// progressBoard tracks the steps run by ShowParallelProgress and reports their progress,
+// drawn on a terminal or logged
+type progressBoard struct {
+	mu      sync.Mutex
+	w       io.Writer
+	message string
+	draw    bool
+	total   int
+	done    int
+	failed  int
+	running []*boardTask // In the order they started
+	lines   int          // Lines drawn by the last redraw
+	frame   int
+	started time.Time
+	logged  time.Time // When progress was last logged
+}
+
+// boardTask is a running step of a progress board
+type boardTask struct {
+	name    string
+	started time.Time
+}
+
+func newProgressBoard(w io.Writer, message string, total int, draw bool) *progressBoard {
+	now := time.Now()
+	if !draw {
+		LogInfo("%s", message)
+	}
+	return &progressBoard{w: w, message: message, draw: draw, total: total, started: now, logged: now}
+}
+
+// start records that the step named name started
+func (b *progressBoard) start(name string) *boardTask {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	task := &boardTask{name: name, started: time.Now()}
+	b.running = append(b.running, task)
+	b.redraw()
+	return task
+}
+
+// finish records that task finished, failing with err when it isn't nil
+func (b *progressBoard) finish(task *boardTask, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, running := range b.running {
+		if running == task {
+			b.running = append(b.running[:i], b.running[i+1:]...)
+			break
+		}
+	}
+	if err != nil {
+		b.failed++
+	} else {
+		b.done++
+	}
+
+	if b.draw {
+		b.redraw()
+		return
+	}
+	if time.Since(b.logged) >= progressLogInterval && b.done+b.failed < b.total {
+		b.logged = time.Now()
+		LogInfo("%s: %s", b.message, b.counts())
+	}
+}
+
+// tick advances the spinner of a drawn board
+func (b *progressBoard) tick() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.frame++
+	b.redraw()
+}
+
+// counts describes how many steps are finished
+func (b *progressBoard) counts() string {
+	counts := fmt.Sprintf("%d/%d done", b.done+b.failed, b.total)
+	if b.failed > 0 {
+		counts += fmt.Sprintf(", %d failed", b.failed)
+	}
+	return counts
+}
+
+// redraw draws the header and the running steps of a drawn board over its previous lines.
+// It must be called with b.mu held.
+func (b *progressBoard) redraw() {
+	if !b.draw {
+		return
+	}
+	var out strings.Builder
+	b.clear(&out)
+	char := spinnerChars[b.frame%len(spinnerChars)]
+	fmt.Fprintf(&out, "%s %s\n", progressStyle.Render(char), truncateLine(fmt.Sprintf("[%s] %s", b.counts(), b.message)))
+	b.lines = 1
+	for i, task := range b.running {
+		if i == maxBoardTasks {
+			fmt.Fprintf(&out, "  … and %d more\n", len(b.running)-maxBoardTasks)
+			b.lines++
+			break
+		}
+		elapsed := time.Since(task.started).Truncate(time.Second)
+		fmt.Fprintf(&out, "  %s\n", truncateLine(fmt.Sprintf("• %s (%s)", task.name, elapsed)))
+		b.lines++
+	}
+	b.write(out.String())
+}
+
+// clear erases the lines drawn last, starting with any spinner line left unfinished
+func (b *progressBoard) clear(out *strings.Builder) {
+	if b.lines > 0 {
+		fmt.Fprintf(out, "\x1b[%dF", b.lines)
+	}
+	out.WriteString("\r\x1b[J")
+}
+
+// write writes to the terminal, in turn with spinners
+func (b *progressBoard) write(s string) {
+	terminalMu.Lock()
+	defer terminalMu.Unlock()
+	_, _ = io.WriteString(b.w, s)
+}
+
+// summary replaces a drawn board with, or logs, how many steps finished and failed
+func (b *progressBoard) summary() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elapsed := time.Since(b.started).Round(100 * time.Millisecond)
+	line := fmt.Sprintf("%s: %d of %d done in %s", b.message, b.done, b.total, elapsed)
+	if b.failed > 0 {
+		line += fmt.Sprintf(", %d failed", b.failed)
+	}
+	if skipped := b.total - b.done - b.failed; skipped > 0 {
+		line += fmt.Sprintf(", %d skipped", skipped)
+	}
+
+	if !b.draw {
+		if b.failed > 0 {
+			LogWarn("%s", line)
+		} else {
+			LogInfo("%s", line)
+		}
+		return
+	}
+	var out strings.Builder
+	b.clear(&out)
+	symbol := successStyle.Render("✓")
+	if b.failed > 0 {
+		symbol = errorStyle.Render("✗")
+	}
+	fmt.Fprintf(&out, "%s %s\n", symbol, line)
+	b.lines = 0
+	b.write(out.String())
+}
+
+// truncateLine shortens s to fit a progress board's line
+func truncateLine(s string) string {
+	if utf8.RuneCountInString(s) <= maxBoardLineWidth {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxBoardLineWidth-1]) + "…"
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShowParallelProgress(t *testing.T) {
+	var running, maxRunning, ran atomic.Int32
+	steps := make([]ProgressStep, 6)
+	for i := range steps {
+		steps[i] = ProgressStep{
+			Message: []string{"a", "b", "c", "d", "e", "f"}[i],
+			Fn: func() error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				ran.Add(1)
+				if i == 1 || i == 4 {
+					return errors.New("locked")
+				}
+				return nil
+			},
+		}
+	}
+
+	err := ShowParallelProgress(context.Background(), "Parsing", steps, 2)
+	if ran.Load() != 6 {
+		t.Errorf("ShowParallelProgress() ran %d steps, want every step despite the failures", ran.Load())
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("ShowParallelProgress() ran %d steps at once, want at most 2 workers", maxRunning.Load())
+	}
+	if err == nil || err.Error() != "b: locked\ne: locked" {
+		t.Errorf("ShowParallelProgress() error = %v, want the failed steps' errors", err)
+	}
+
+	if err := ShowParallelProgress(context.Background(), "Nothing", nil, 0); err != nil {
+		t.Errorf("ShowParallelProgress() with no steps error = %v", err)
+	}
+}
+
+func TestShowParallelProgress_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ran atomic.Int32
+	steps := make([]ProgressStep, 5)
+	for i := range steps {
+		steps[i] = ProgressStep{Message: "step", Fn: func() error {
+			ran.Add(1)
+			cancel()
+			return nil
+		}}
+	}
+
+	err := ShowParallelProgress(ctx, "Parsing", steps, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ShowParallelProgress() error = %v, want the context's error", err)
+	}
+	if ran.Load() == 5 {
+		t.Error("ShowParallelProgress() ran every step after the context was cancelled")
+	}
+}
+
+func TestProgressBoard_Draw(t *testing.T) {
+	var buf bytes.Buffer
+	board := newProgressBoard(&buf, "Parsing sessions", 3, true)
+
+	first := board.start("first-session")
+	second := board.start("second-session")
+	board.finish(first, nil)
+	drawn := buf.String()
+	if !strings.Contains(drawn, "[1/3 done] Parsing sessions\n") || !strings.Contains(drawn, "• second-session (0s)\n") {
+		t.Errorf("board = %q, want the count and the running step", drawn)
+	}
+	// Each redraw moves back over the 3 lines drawn before
+	if !strings.Contains(drawn, "\x1b[3F\r\x1b[J") {
+		t.Errorf("board = %q, want the previous lines cleared", drawn)
+	}
+
+	buf.Reset()
+	board.finish(second, errors.New("locked"))
+	board.summary()
+	summary := buf.String()
+	if !strings.Contains(summary, "[2/3 done, 1 failed] Parsing sessions") {
+		t.Errorf("board = %q, want the failure counted", summary)
+	}
+	if !strings.Contains(summary, "Parsing sessions: 1 of 3 done in ") || !strings.HasSuffix(summary, ", 1 failed, 1 skipped\n") {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestProgressBoard_ManyRunning(t *testing.T) {
+	var buf bytes.Buffer
+	board := newProgressBoard(&buf, "Parsing", 20, true)
+	for i := 0; i < maxBoardTasks+3; i++ {
+		board.start(strings.Repeat("x", 100))
+	}
+
+	last := buf.String()[strings.LastIndex(buf.String(), "\x1b[J")+len("\x1b[J"):]
+	lines := strings.Split(strings.TrimSuffix(last, "\n"), "\n")
+	if len(lines) != maxBoardTasks+2 || lines[len(lines)-1] != "  … and 3 more" {
+		t.Errorf("board = %q, want %d steps and a line for the rest", last, maxBoardTasks)
+	}
+	for _, line := range lines {
+		if n := len([]rune(line)); n > maxBoardLineWidth+2 {
+			t.Errorf("line %q is %d characters long", line, n)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ParseFailure is a message record that was read from storage but could not be parsed
+type ParseFailure struct {
+	Key        string // Key of the record, such as bubbleId:<composer>:<bubble> or a blob key
+	Reason     string
+	SourcePath string // Database or file the record was read from
+	Backend    string
+}
+
+var (
+	parseFailuresMu sync.Mutex
+	// parseFailures are the message records that failed to parse during the run, by the
+	// ID of the bubble they hold; cleared by ResetParseStats
+	parseFailures = make(map[string]ParseFailure)
+	// includeParseFailures makes reconstruction keep a placeholder message where a
+	// message failed to parse, instead of leaving it out
+	includeParseFailures bool
+)
+
+// SetIncludeParseFailures sets whether reconstructed conversations keep a placeholder
+// message, saying which record failed and why, in place of each message that could not
+// be parsed
+func SetIncludeParseFailures(include bool) {
+	includeParseFailures = include
+}
+
+// RecordBubbleParseFailure records that the record holding a bubble could not be parsed.
+// The bubble ID is taken from the key: the last part of a desktop bubbleId:<composer>:<bubble>
+// key, or the whole key of a cursor-agent blob or message file, which cursor-agent names
+// after the message.
+func RecordBubbleParseFailure(failure ParseFailure) {
+	id := bubbleIDFromKey(failure.Key)
+	if id == "" {
+		return
+	}
+	parseFailuresMu.Lock()
+	defer parseFailuresMu.Unlock()
+	parseFailures[id] = failure
+}
+
+// recordBlobParseFailures records the blobs of a store.db that the warnings of its load
+// say could not be parsed as, or converted to, a message
+func recordBlobParseFailures(warnings *Warnings) {
+	for _, warning := range warnings.List() {
+		if warning.Category != WarningBlobParse && warning.Category != WarningMessage {
+			continue
+		}
+		RecordBubbleParseFailure(ParseFailure{
+			Key:        warning.Key,
+			Reason:     warning.Reason,
+			SourcePath: warning.DBPath,
+			Backend:    BackendAgentStorage,
+		})
+	}
+}
+
+// bubbleParseFailure returns the parse failure recorded for a bubble, if any
+func bubbleParseFailure(bubbleID string) (ParseFailure, bool) {
+	parseFailuresMu.Lock()
+	defer parseFailuresMu.Unlock()
+	failure, ok := parseFailures[bubbleID]
+	return failure, ok
+}
+
+// resetParseFailures clears the recorded parse failures
+func resetParseFailures() {
+	parseFailuresMu.Lock()
+	defer parseFailuresMu.Unlock()
+	parseFailures = make(map[string]ParseFailure)
+}
+
+// bubbleIDFromKey returns the ID of the bubble a record key names
+func bubbleIDFromKey(key string) string {
+	if rest, ok := strings.CutPrefix(key, "bubbleId:"); ok {
+		_, id, found := strings.Cut(rest, ":")
+		if !found {
+			return ""
+		}
+		return id
+	}
+	return key
+}
+
+// parseFailureText is the text of the placeholder message kept for a record that failed
+// to parse
+func parseFailureText(failure ParseFailure) string {
+	return fmt.Sprintf("[unparseable message: key=%s, reason=%s]", failure.Key, failure.Reason)
+}
+
+// parseFailureMessage returns the placeholder message for a header whose bubble failed
+// to parse, when placeholders are included and a failure was recorded for it
+func parseFailureMessage(header ConversationHeader) (ReconstructedMessage, bool) {
+	if !includeParseFailures {
+		return ReconstructedMessage{}, false
+	}
+	failure, ok := bubbleParseFailure(header.BubbleID)
+	if !ok {
+		return ReconstructedMessage{}, false
+	}
+	return ReconstructedMessage{
+		BubbleID: header.BubbleID,
+		Type:     header.Type,
+		Text:     parseFailureText(failure),
+		Provenance: &Provenance{
+			SourcePath: failure.SourcePath,
+			BlobKey:    failure.Key,
+			Backend:    failure.Backend,
+		},
+		ParseFailure: true,
+	}, true
+}
+
+// dateParseFailures gives the placeholders of messages that failed to parse, which have
+// no timestamp of their own, the timestamp of the message before them (or after, for the
+// first), so sorting by timestamp keeps them where they were
+func dateParseFailures(messages []ReconstructedMessage) {
+	var last int64
+	for i := range messages {
+		if !messages[i].ParseFailure {
+			last = messages[i].Timestamp
+			continue
+		}
+		if last == 0 {
+			for _, next := range messages[i+1:] {
+				if !next.ParseFailure {
+					last = next.Timestamp
+					break
+				}
+			}
+		}
+		messages[i].Timestamp = last
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBubbleIDFromKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"bubbleId:composer1:bubble1", "bubble1"},
+		{"bubbleId:composer1", ""},
+		{"027f8b2f-d09c-4a69-98b0-b53f0118605d", "027f8b2f-d09c-4a69-98b0-b53f0118605d"},
+	}
+	for _, tt := range tests {
+		if got := bubbleIDFromKey(tt.key); got != tt.want {
+			t.Errorf("bubbleIDFromKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestReconstructConversation_ParseFailures(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+	defer SetIncludeParseFailures(false)
+
+	bubbleMap := NewBubbleMap()
+	for i, text := range []string{"First question", "", "Second question"} {
+		if text == "" {
+			continue
+		}
+		bubble := CreateTestRawBubble([]string{"b1", "b2", "b3"}[i], "composer1", text, 1)
+		bubble.Timestamp = int64(1000 * (i + 1))
+		bubbleMap.Set(bubble.BubbleID, bubble)
+	}
+	RecordBubbleParseFailure(ParseFailure{Key: "bubbleId:composer1:b2", Reason: "failed to parse bubble JSON: unexpected end of JSON input", SourcePath: "/storage/state.vscdb", Backend: BackendGlobalStorage})
+	// A failure without a header referencing it adds nothing
+	RecordBubbleParseFailure(ParseFailure{Key: "bubbleId:composer1:b9", Reason: "unreferenced"})
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "b1", Type: 1},
+			{BubbleID: "b2", Type: 2},
+			{BubbleID: "b3", Type: 1},
+		},
+	}
+
+	for _, include := range []bool{false, true} {
+		SetIncludeParseFailures(include)
+		conv, err := NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+		if err != nil {
+			t.Fatalf("ReconstructConversation() error = %v", err)
+		}
+		if conv.Extraction.Missing != 1 {
+			t.Errorf("Missing = %d, want 1", conv.Extraction.Missing)
+		}
+		if !include {
+			if len(conv.Messages) != 2 {
+				t.Errorf("ReconstructConversation() = %d messages without placeholders, want 2", len(conv.Messages))
+			}
+			continue
+		}
+
+		if len(conv.Messages) != 3 {
+			t.Fatalf("ReconstructConversation() = %d messages with placeholders, want 3", len(conv.Messages))
+		}
+		placeholder := conv.Messages[1]
+		want := "[unparseable message: key=bubbleId:composer1:b2, reason=failed to parse bubble JSON: unexpected end of JSON input]"
+		if placeholder.Text != want || !placeholder.ParseFailure {
+			t.Errorf("placeholder = %q, want %q", placeholder.Text, want)
+		}
+		if placeholder.Type != 2 || placeholder.Timestamp != 1000 {
+			t.Errorf("placeholder type %d at %d, want the header's type 2 at the time of the message before it", placeholder.Type, placeholder.Timestamp)
+		}
+		if placeholder.Provenance == nil || placeholder.Provenance.SourcePath != "/storage/state.vscdb" {
+			t.Errorf("placeholder provenance = %+v, want the database", placeholder.Provenance)
+		}
+	}
+}
+
+func TestDateParseFailures(t *testing.T) {
+	messages := []ReconstructedMessage{
+		{ParseFailure: true},
+		{Timestamp: 2000},
+		{ParseFailure: true},
+		{Timestamp: 3000},
+	}
+	dateParseFailures(messages)
+	for i, want := range []int64{2000, 2000, 2000, 3000} {
+		if messages[i].Timestamp != want {
+			t.Errorf("message %d timestamp = %d, want %d", i, messages[i].Timestamp, want)
+		}
+	}
+}
+
+func TestLoadSessionFromStoreDB_RecordsParseFailures(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	dbPath := filepath.Join(t.TempDir(), "store.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE blobs (key TEXT PRIMARY KEY, value TEXT)",
+		"CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+	rows := map[string]string{
+		"composer1": `{"composerId":"composer1","fullConversationHeadersOnly":[{"bubbleId":"bubble1","type":1},{"bubbleId":"bubble2","type":2}]}`,
+		"bubble1":   `{"bubbleId":"bubble1","chatId":"composer1","text":"Parsed","timestamp":1000,"type":1}`,
+		"bubble2":   `{"bubbleId":"bubble2","chatId":"composer1","text":"Truncat`,
+	}
+	for key, value := range rows {
+		if _, err := db.Exec("INSERT INTO blobs (key, value) VALUES (?, ?)", key, value); err != nil {
+			t.Fatalf("Failed to insert blob: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	if _, _, _, _, err := LoadSessionFromStoreDB(dbPath); err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+	failure, ok := bubbleParseFailure("bubble2")
+	if !ok {
+		t.Fatal("LoadSessionFromStoreDB() recorded no parse failure for bubble2")
+	}
+	if failure.Key != "bubble2" || failure.SourcePath != dbPath || failure.Backend != BackendAgentStorage || !strings.Contains(failure.Reason, "JSON") {
+		t.Errorf("parse failure = %+v, want bubble2 of %s failing to parse as JSON", failure, dbPath)
+	}
+	if _, ok := bubbleParseFailure("bubble1"); ok {
+		t.Error("LoadSessionFromStoreDB() recorded a parse failure for bubble1, which parsed")
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import "sync"
+
+// ParseStats counts the records read from storage during a run and how many of them could
+// not be used, so --strict can tell a complete run from one that silently skipped data
+type ParseStats struct {
+	Records       int `json:"records"`        // Storage records (blobs, rows or files) examined
+	ParseFailures int `json:"parse_failures"` // Records skipped because they could not be parsed
+	Sessions      int `json:"sessions"`       // Conversations reconstructed
+	EmptySessions int `json:"empty_sessions"` // Conversations with headers that produced no messages
+}
+
+// StoreDBStats describes what was read from one cursor-agent store.db
+type StoreDBStats struct {
+	Path              string   `json:"path"`
+	Schema            string   `json:"schema,omitempty"`    // Layout of the tables, see AgentSchema
+	Blobs             int      `json:"blobs"`               // Rows read from the blobs table
+	BlobParseFailures int      `json:"blob_parse_failures"` // Blobs that could not be parsed
+	MetaEntries       int      `json:"meta_entries"`        // Rows read from the meta table
+	MetaParseFailures int      `json:"meta_parse_failures"` // Meta entries that could not be parsed
+	Bubbles           int      `json:"bubbles"`             // Messages extracted
+	Composers         int      `json:"composers"`           // Conversations extracted
+	Contexts          int      `json:"contexts"`            // Message contexts extracted
+	SessionIDs        []string `json:"session_ids"`         // IDs of the conversations extracted
+	Warnings          []string `json:"warnings,omitempty"`
+	Error             string   `json:"error,omitempty"` // Why the database could not be read
+}
+
+var (
+	parseStatsMu sync.Mutex
+	parseStats   ParseStats
+	storeDBStats []StoreDBStats
+)
+
+// RecordParsed adds records read from storage to the run's statistics, failed of which
+// could not be parsed
+func RecordParsed(records, failed int) {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	parseStats.Records += records
+	parseStats.ParseFailures += failed
+}
+
+// RecordSession adds a reconstructed conversation to the run's statistics
+func RecordSession(empty bool) {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	parseStats.Sessions++
+	if empty {
+		parseStats.EmptySessions++
+	}
+}
+
+// RecordStoreDB records what was read from a store.db. A database read again during the
+// same run replaces its earlier record.
+func RecordStoreDB(stats StoreDBStats) {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	for i := range storeDBStats {
+		if storeDBStats[i].Path == stats.Path {
+			storeDBStats[i] = stats
+			return
+		}
+	}
+	storeDBStats = append(storeDBStats, stats)
+}
+
+// GetStoreDBStats returns the store.db records since the last ResetParseStats, in the
+// order the databases were first read
+func GetStoreDBStats() []StoreDBStats {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	return append([]StoreDBStats(nil), storeDBStats...)
+}
+
+// GetParseStats returns the statistics recorded since the last ResetParseStats
+func GetParseStats() ParseStats {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	return parseStats
+}
+
+// ResetParseStats clears the recorded statistics at the start of a run
+func ResetParseStats() {
+	parseStatsMu.Lock()
+	defer parseStatsMu.Unlock()
+	parseStats = ParseStats{}
+	storeDBStats = nil
+	resetWarnings()
+	resetParseFailures()
+}
+
+// ParseFailureRatio returns the share of records that could not be parsed
+func (s ParseStats) ParseFailureRatio() float64 {
+	if s.Records == 0 {
+		return 0
+	}
+	return float64(s.ParseFailures) / float64(s.Records)
+}
+
+// EmptySessionRatio returns the share of reconstructed conversations that produced no messages
+func (s ParseStats) EmptySessionRatio() float64 {
+	if s.Sessions == 0 {
+		return 0
+	}
+	return float64(s.EmptySessions) / float64(s.Sessions)
+}
@@ -0,0 +1,34 @@
+package internal
+
+import "testing"
+
+func TestParseStats(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	if stats := GetParseStats(); stats != (ParseStats{}) {
+		t.Fatalf("GetParseStats() after reset = %+v, want zero", stats)
+	}
+	if ratio := GetParseStats().ParseFailureRatio(); ratio != 0 {
+		t.Errorf("ParseFailureRatio() with no records = %v, want 0", ratio)
+	}
+
+	RecordParsed(8, 2)
+	RecordParsed(2, 0)
+	RecordSession(false)
+	RecordSession(false)
+	RecordSession(false)
+	RecordSession(true)
+
+	stats := GetParseStats()
+	want := ParseStats{Records: 10, ParseFailures: 2, Sessions: 4, EmptySessions: 1}
+	if stats != want {
+		t.Errorf("GetParseStats() = %+v, want %+v", stats, want)
+	}
+	if ratio := stats.ParseFailureRatio(); ratio != 0.2 {
+		t.Errorf("ParseFailureRatio() = %v, want 0.2", ratio)
+	}
+	if ratio := stats.EmptySessionRatio(); ratio != 0.25 {
+		t.Errorf("EmptySessionRatio() = %v, want 0.25", ratio)
+	}
+}
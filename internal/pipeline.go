@@ -0,0 +1,180 @@
+package internal
+
+import "fmt"
+
+// ReconstructConversations loads raw data from the backend and rebuilds conversations
+func ReconstructConversations(backend StorageBackend) ([]*ReconstructedConversation, error) {
+	bubbleChan, composerChan, contextChan, err := LoadDataAsyncFromBackend(backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Diffs are optional; sessions are still exported without them
+	rawDiffs, err := backend.LoadCodeBlockDiffs()
+	if err != nil {
+		LogWarn("Failed to load code block diffs: %v", err)
+	}
+
+	// Bubbles the headers reference that the backend did not load are looked up again
+	// where it can find them, such as the desktop app database for cursor-agent sessions
+	diffs, edits := ParseCodeDiffs(rawDiffs), LoadFileEdits(backend)
+	finder, _ := backend.(BubbleFinder)
+	conversations, err := reconstructAsync(bubbleChan, composerChan, contextChan, func(r *Reconstructor) {
+		r.SetCodeDiffs(diffs)
+		r.SetFileEdits(edits)
+		r.SetBubbleFinder(finder)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// ReconstructSession rebuilds the session with the given ID (full or unique prefix),
+// loading only the bubbles it references. The backend must implement BubbleLoader; other
+// backends can only be read whole with ReconstructConversations. A non-empty
+// workspaceOverride is assigned to the session.
+func ReconstructSession(backend StorageBackend, paths []StoragePaths, id, workspaceOverride string) (*Session, error) {
+	loader, ok := backend.(BubbleLoader)
+	if !ok {
+		return nil, fmt.Errorf("storage backend cannot load single bubbles")
+	}
+
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load composers: %w", err)
+	}
+	refs := make([]SessionRef, 0, len(composers))
+	for _, c := range composers {
+		refs = append(refs, SessionRef{ID: c.ComposerID, Name: c.Name})
+	}
+	composerID, err := ResolveSessionID(refs, id)
+	if err != nil {
+		return nil, err
+	}
+	var composer *RawComposer
+	for _, c := range composers {
+		if c.ComposerID == composerID {
+			composer = c
+			break
+		}
+	}
+
+	contexts, err := backend.LoadMessageContexts()
+	if err != nil {
+		LogWarn("Failed to load message contexts: %v", err)
+	}
+	rawDiffs, err := backend.LoadCodeBlockDiffs()
+	if err != nil {
+		LogWarn("Failed to load code block diffs: %v", err)
+	}
+
+	reconstructor := NewReconstructorWithResolver(NewLazyBubbleResolver(loader), contexts)
+	reconstructor.SetCodeDiffs(ParseCodeDiffs(rawDiffs))
+	reconstructor.SetFileEdits(LoadFileEdits(backend))
+	if finder, ok := backend.(BubbleFinder); ok {
+		reconstructor.SetBubbleFinder(finder)
+	}
+	conv, err := reconstructor.ReconstructConversation(composer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct session %s: %w", composerID, err)
+	}
+
+	sessions := NormalizeSessions([]*ReconstructedConversation{conv}, backend, paths, workspaceOverride)
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("session %s has no messages", composerID)
+	}
+	return sessions[0], nil
+}
+
+// DetectStorageWorkspaces detects the workspaces of every storage location, for
+// associating sessions with the project they belong to
+func DetectStorageWorkspaces(list []StoragePaths) map[string]*WorkspaceInfo {
+	workspaces := make(map[string]*WorkspaceInfo)
+	for _, paths := range list {
+		// Remote locations have no workspaceStorage to read
+		if paths.Location != "" {
+			continue
+		}
+		detected, _ := DetectWorkspaces(paths.BasePath)
+		for hash, info := range detected {
+			workspaces[hash] = info
+		}
+	}
+	return workspaces
+}
+
+// NormalizeSessions turns conversations into deduplicated sessions, associating each with
+// a workspace. A non-empty workspaceOverride is assigned to every session.
+func NormalizeSessions(conversations []*ReconstructedConversation, backend StorageBackend, paths []StoragePaths, workspaceOverride string) []*Session {
+	loadHistory := func() *WorkspaceHistory { return LoadWorkspaceHistory(paths) }
+	return normalizeSessions(conversations, backend, DetectStorageWorkspaces(paths), loadHistory, workspaceOverride)
+}
+
+// normalizeSessions implements NormalizeSessions with the detected workspaces, and the
+// workspace history loaded by loadHistory when a conversation needs it
+func normalizeSessions(conversations []*ReconstructedConversation, backend StorageBackend, workspaces map[string]*WorkspaceInfo, loadHistory func() *WorkspaceHistory, workspaceOverride string) []*Session {
+	// Desktop bubbles do not always carry timestamps; recover them from the workspace
+	// databases' record of the same composers
+	backfillTimestamps(conversations, loadHistory)
+
+	// Load contexts for workspace association
+	contexts, _ := backend.LoadMessageContexts()
+
+	// Normalize with workspace association
+	normalizer := NewNormalizer()
+	sessions := make([]*Session, 0, len(conversations))
+	for _, conv := range conversations {
+		assignedWorkspace := conversationWorkspace(conv, backend, contexts, workspaces, workspaceOverride)
+		session, err := normalizer.NormalizeConversation(conv, assignedWorkspace)
+		if err != nil {
+			LogWarn("Failed to normalize conversation %s: %v", conv.ComposerID, err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	// Log summary statistics
+	LogInfo("Normalization complete: %d composers processed, %d sessions created", len(conversations), len(sessions))
+
+	// Deduplicate
+	deduplicator := NewDeduplicator()
+	return deduplicator.Deduplicate(sessions)
+}
+
+// conversationWorkspace returns the workspace of a conversation: workspaceOverride when
+// set, else the workspace its contexts reference, else the one the backend recorded, else
+// the folder of the workspace its cursor-agent hash directory names
+func conversationWorkspace(conv *ReconstructedConversation, backend StorageBackend, contexts map[string][]*MessageContext, workspaces map[string]*WorkspaceInfo, workspaceOverride string) string {
+	if workspaceOverride != "" {
+		return workspaceOverride
+	}
+	if workspace := AssociateComposerWithWorkspace(conv.ComposerID, contexts[conv.ComposerID], workspaces); workspace != "" {
+		return workspace
+	}
+	if workspace := BackendSessionWorkspace(backend, conv.ComposerID); workspace != "" {
+		return workspace
+	}
+	return WorkspaceForHash(conv.WorkspaceHash, workspaces)
+}
+
+// backfillTimestamps fills in the timestamps conversations are missing from the history
+// of the workspace databases, which are only read when a conversation needs it
+func backfillTimestamps(conversations []*ReconstructedConversation, loadHistory func() *WorkspaceHistory) {
+	var history *WorkspaceHistory
+	backfilled := 0
+	for _, conv := range conversations {
+		if !NeedsTimestamps(conv) {
+			continue
+		}
+		if history == nil {
+			history = loadHistory()
+		}
+		if history.Backfill(conv) {
+			backfilled++
+		}
+	}
+	if backfilled > 0 {
+		LogDebug("Backfilled timestamps of %d session(s) from workspace history", backfilled)
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var (
+	plainOutput bool
+	// colorProfile is the detected color profile, restored when plain output is turned off
+	colorProfile = lipgloss.ColorProfile()
+)
+
+// plainSymbols are the ASCII replacements for the symbols used in command output
+var plainSymbols = map[rune]string{
+	'✅': "[OK]",
+	'✓': "[OK]",
+	'❌': "[FAIL]",
+	'✗': "[FAIL]",
+	'⚠': "[WARN]",
+	'ℹ': "[INFO]",
+	'•': "-",
+	'→': "->",
+	'━': "-",
+	'─': "-",
+	'═': "=",
+	'│': "|",
+	'┃': "|",
+}
+
+// SetPlainOutput turns plain output on or off. Plain output has no colors or text styles,
+// and writers wrapped with NewPlainWriter replace emoji and symbols with ASCII.
+func SetPlainOutput(plain bool) {
+	plainOutput = plain
+	if plain {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else {
+		lipgloss.SetColorProfile(colorProfile)
+	}
+}
+
+// PlainOutput reports whether plain output is on
+func PlainOutput() bool {
+	return plainOutput
+}
+
+// PlainText replaces the symbols in plainSymbols with ASCII and drops other emoji along
+// with the spaces that follow them. Letters from other scripts are kept.
+func PlainText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	dropSpaces := false
+	for _, r := range s {
+		if r == 0xFE0F || r == 0x200D { // variation selector and zero-width joiner
+			continue
+		}
+		if dropSpaces && r == ' ' {
+			continue
+		}
+		dropSpaces = false
+
+		if replacement, ok := plainSymbols[r]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+		if r >= 0x2500 && r <= 0x257F { // other box drawing corners and joints
+			b.WriteByte('+')
+			continue
+		}
+		if isEmoji(r) {
+			dropSpaces = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEmoji reports whether r is an emoji, a dingbat or a decorative symbol
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // emoji and pictographs
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2800 && r <= 0x28FF: // braille patterns, used by spinners
+		return true
+	}
+	return false
+}
+
+// plainWriter passes everything written through PlainText
+type plainWriter struct {
+	w       io.Writer
+	pending []byte // incomplete UTF-8 sequence at the end of the last write
+}
+
+// NewPlainWriter returns a writer that writes to w with emoji and symbols replaced by ASCII
+func NewPlainWriter(w io.Writer) io.Writer {
+	return &plainWriter{w: w}
+}
+
+func (p *plainWriter) Write(data []byte) (int, error) {
+	buf := append(p.pending, data...)
+	// Hold back a rune split across writes until the rest of it arrives
+	end := len(buf)
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	p.pending = append([]byte(nil), buf[end:]...)
+
+	if _, err := io.WriteString(p.w, PlainText(string(buf[:end]))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
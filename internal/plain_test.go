@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"status symbols", "✅ ok ❌ failed ⚠️  careful", "[OK] ok [FAIL] failed [WARN]  careful"},
+		{"emoji dropped with following spaces", "🔍 Cursor Session Health Check", "Cursor Session Health Check"},
+		{"emoji with variation selector", "🏷️  abc: ci", "abc: ci"},
+		{"bullets and arrows", "  • Desktop app\n    → created on first use", "  - Desktop app\n    -> created on first use"},
+		{"separators", "━━━", "---"},
+		{"box corners", "┌─┐", "+-+"},
+		{"other scripts kept", "Grüße, 日本語", "Grüße, 日本語"},
+		{"ascii unchanged", "plain text", "plain text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.in); got != tt.want {
+				t.Errorf("PlainText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainWriter_SplitRune(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewPlainWriter(&buf)
+
+	check := []byte("✅ done")
+	// Split the 3-byte check mark across two writes
+	if n, err := w.Write(check[:2]); err != nil || n != 2 {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if _, err := w.Write(check[2:]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if got := buf.String(); got != "[OK] done" {
+		t.Errorf("output = %q, want %q", got, "[OK] done")
+	}
+}
+
+func TestSetPlainOutput(t *testing.T) {
+	original := lipgloss.ColorProfile()
+	defer func() {
+		SetPlainOutput(false)
+		lipgloss.SetColorProfile(original)
+	}()
+
+	style := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("42"))
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	if !strings.Contains(style.Render("x"), "\x1b[") {
+		t.Skip("lipgloss does not render escapes in this environment")
+	}
+
+	SetPlainOutput(true)
+	if !PlainOutput() {
+		t.Error("PlainOutput() = false after SetPlainOutput(true)")
+	}
+	if got := style.Render("x"); got != "x" {
+		t.Errorf("Render() with plain output = %q, want %q", got, "x")
+	}
+	if isTerminal(nil) {
+		t.Error("isTerminal() should be false with plain output")
+	}
+}
@@ -37,8 +37,8 @@ type ProgressStep struct {
 
 // ShowProgress runs a spinner with a message using gum if available, otherwise simple output
 func ShowProgress(ctx context.Context, message string, fn func() error) error {
-	// Check if we're in a TTY
-	if !isTerminal(os.Stderr) {
+	// Check if we're in a TTY; quiet runs draw nothing
+	if Quiet() || !isTerminal(os.Stderr) {
 		// Not a TTY, just run the function
 		LogInfo(message)
 		return fn()
@@ -55,7 +55,7 @@ func ShowProgress(ctx context.Context, message string, fn func() error) error {
 
 // ShowProgressWithSteps shows progress for multiple steps
 func ShowProgressWithSteps(ctx context.Context, steps []ProgressStep) error {
-	if !isTerminal(os.Stderr) {
+	if Quiet() || !isTerminal(os.Stderr) {
 		// Not a TTY, just run steps sequentially
 		for _, step := range steps {
 			LogInfo(step.Message)
@@ -80,6 +80,16 @@ func showProgressWithGum(ctx context.Context, message string, fn func() error) e
 	done := make(chan error, 1)
 	spinnerDone := make(chan struct{})
 
+	// gum draws on its own, so progress boards under it log instead
+	terminalMu.Lock()
+	gumSpinners++
+	terminalMu.Unlock()
+	defer func() {
+		terminalMu.Lock()
+		gumSpinners--
+		terminalMu.Unlock()
+	}()
+
 	// Start gum spinner
 	cmd := exec.CommandContext(ctx, "gum", "spin", "--spinner", "dot", "--", "sh", "-c", "while true; do sleep 0.1; done")
 	cmd.Stderr = os.Stderr
@@ -115,8 +125,8 @@ func showProgressWithGum(ctx context.Context, message string, fn func() error) e
 
 // showProgressSimple uses a simple text-based spinner
 func showProgressSimple(ctx context.Context, message string, fn func() error) error {
-	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	done := make(chan error, 1)
+	stopSpinner := make(chan struct{})
 	spinnerDone := make(chan struct{})
 
 	// Start spinner
@@ -129,10 +139,17 @@ func showProgressSimple(ctx context.Context, message string, fn func() error) er
 			select {
 			case <-ctx.Done():
 				return
+			case <-stopSpinner:
+				return
 			case <-ticker.C:
-				char := spinnerChars[i%len(spinnerChars)]
-				fmt.Fprintf(os.Stderr, "\r%s %s", progressStyle.Render(char), message)
-				i++
+				// Pause while a progress board draws the steps run in parallel under it
+				terminalMu.Lock()
+				if boardsDrawing == 0 {
+					char := spinnerChars[i%len(spinnerChars)]
+					fmt.Fprintf(os.Stderr, "\r%s %s", progressStyle.Render(char), message)
+					i++
+				}
+				terminalMu.Unlock()
 			}
 		}
 	}()
@@ -145,6 +162,7 @@ func showProgressSimple(ctx context.Context, message string, fn func() error) er
 	// Wait for function or context
 	select {
 	case err := <-done:
+		close(stopSpinner)
 		<-spinnerDone
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\r%s %s\n", errorStyle.Render("✗"), message)
@@ -186,8 +204,12 @@ func gumAvailable() bool {
 	return err == nil
 }
 
-// isTerminal checks if the writer is a terminal
+// isTerminal checks if the writer is a terminal. With plain output every writer is
+// treated as a non-terminal, so no spinners or symbols are drawn.
 func isTerminal(w io.Writer) bool {
+	if plainOutput {
+		return false
+	}
 	if f, ok := w.(*os.File); ok {
 		stat, err := f.Stat()
 		if err != nil {
@@ -198,8 +220,11 @@ func isTerminal(w io.Writer) bool {
 	return false
 }
 
-// PrintSuccess prints a success message
+// PrintSuccess prints a success message, unless the run is quiet
 func PrintSuccess(message string) {
+	if Quiet() {
+		return
+	}
 	if isTerminal(os.Stdout) {
 		fmt.Printf("%s %s\n", successStyle.Render("✓"), message)
 	} else {
@@ -216,8 +241,11 @@ func PrintError(message string) {
 	}
 }
 
-// PrintInfo prints an info message
+// PrintInfo prints an info message, unless the run is quiet
 func PrintInfo(message string) {
+	if Quiet() {
+		return
+	}
 	if isTerminal(os.Stdout) {
 		fmt.Printf("%s %s\n", progressStyle.Render("ℹ"), message)
 	} else {
@@ -225,8 +253,11 @@ func PrintInfo(message string) {
 	}
 }
 
-// PrintWarning prints a warning message
+// PrintWarning prints a warning message, unless the run is quiet
 func PrintWarning(message string) {
+	if Quiet() {
+		return
+	}
 	if isTerminal(os.Stderr) {
 		fmt.Fprintf(os.Stderr, "%s %s\n", warningStyle.Render("⚠"), message)
 	} else {
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quality flags, raised on sessions whose signals suggest the conversation went wrong
+const (
+	FlagUnanswered   = "unanswered"    // A user message got no reply before the next one
+	FlagToolErrors   = "tool-errors"   // A tool or terminal output looks like an error
+	FlagShortReplies = "short-replies" // At least half the assistant's replies are very short
+	FlagAborted      = "aborted"       // The session ends on a user message left without a reply
+	FlagLoop         = "loop"          // The assistant kept repeating near-identical messages
+)
+
+// QualityFlags lists the quality flags, in the order they are reported
+var QualityFlags = []string{FlagUnanswered, FlagToolErrors, FlagShortReplies, FlagAborted, FlagLoop}
+
+const (
+	// shortReplyRunes is the length under which an assistant reply counts as very short
+	shortReplyRunes = 20
+	// loopWindow is how many earlier assistant messages each one is compared with
+	loopWindow = 3
+	// loopSimilarity is the TextSimilarity from which a message repeats an earlier one
+	loopSimilarity = 0.9
+	// loopRepeats is how many repeated messages make a loop
+	loopRepeats = 2
+	// loopCompareBytes bounds the text compared for loops, as the comparison is quadratic
+	loopCompareBytes = 2000
+)
+
+// toolErrorPattern matches the lines of tool and terminal outputs that report a failure
+var toolErrorPattern = regexp.MustCompile(`(?im)^\s*(?:error|fatal|panic|exception)\b|traceback \(most recent call last\)|exit(?:ed with)? (?:code|status):? [1-9]|command not found|no such file or directory|permission denied|segmentation fault|npm err!`)
+
+// SessionQuality holds the quality signals computed from a session's messages, and the
+// flags they raise
+type SessionQuality struct {
+	UnansweredTurns int      `json:"unanswered_turns"` // User messages followed by another with no reply between
+	ToolErrors      int      `json:"tool_errors"`      // Tool and terminal outputs that look like errors
+	Replies         int      `json:"replies"`          // Assistant replies, not counting tool calls
+	ShortReplies    int      `json:"short_replies"`    // Replies under 20 characters
+	Aborted         bool     `json:"aborted"`          // The last user message has no reply
+	Repeats         int      `json:"repeats"`          // Assistant messages nearly repeating one of the 3 before
+	Flags           []string `json:"flags,omitempty"`
+}
+
+// NewQualityFlags checks the names of quality flags given on the command line
+func NewQualityFlags(names []string) ([]string, error) {
+	flags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		valid := false
+		for _, flag := range QualityFlags {
+			if name == flag {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown quality flag %q (expected %s)", name, strings.Join(QualityFlags, ", "))
+		}
+		flags = append(flags, name)
+	}
+	return flags, nil
+}
+
+// AssessQuality computes the quality signals of a session. A turn is a user message and
+// the messages up to the next one; it is answered when the assistant wrote anything in it.
+// Assistant messages calling tools are not replies, and tool outputs are those of tool and
+// terminal messages and the [Tool Response] sections of the others.
+func AssessQuality(session *Session) SessionQuality {
+	var q SessionQuality
+	inTurn, answered := false, false
+	var recent []string
+	for _, msg := range session.Messages {
+		switch msg.Actor {
+		case ActorUser:
+			if inTurn && !answered {
+				q.UnansweredTurns++
+			}
+			inTurn, answered = true, false
+			continue
+		case ActorAssistant:
+			if strings.TrimSpace(msg.Content) == "" && msg.Thinking == "" {
+				continue
+			}
+			answered = true
+			if strings.TrimSpace(msg.Content) == "" {
+				continue
+			}
+			if !strings.Contains(msg.Content, "[Tool Call]") {
+				q.Replies++
+				if utf8.RuneCountInString(strings.TrimSpace(msg.Content)) < shortReplyRunes {
+					q.ShortReplies++
+				}
+			}
+
+			text := truncateUTF8(msg.Content, loopCompareBytes)
+			for _, earlier := range recent {
+				if TextSimilarity(text, earlier) >= loopSimilarity {
+					q.Repeats++
+					break
+				}
+			}
+			if recent = append(recent, text); len(recent) > loopWindow {
+				recent = recent[1:]
+			}
+		}
+
+		for _, output := range toolOutputs(msg) {
+			if toolErrorPattern.MatchString(output) {
+				q.ToolErrors++
+			}
+		}
+	}
+	q.Aborted = inTurn && !answered
+	q.Flags = q.flags()
+	return q
+}
+
+// flags returns the quality flags the signals raise
+func (q SessionQuality) flags() []string {
+	var flags []string
+	if q.UnansweredTurns > 0 {
+		flags = append(flags, FlagUnanswered)
+	}
+	if q.ToolErrors > 0 {
+		flags = append(flags, FlagToolErrors)
+	}
+	if q.ShortReplies > 0 && q.ShortReplies*2 >= q.Replies {
+		flags = append(flags, FlagShortReplies)
+	}
+	if q.Aborted {
+		flags = append(flags, FlagAborted)
+	}
+	if q.Repeats >= loopRepeats {
+		flags = append(flags, FlagLoop)
+	}
+	return flags
+}
+
+// HasFlags reports whether every one of flags is raised
+func (q SessionQuality) HasFlags(flags []string) bool {
+	for _, flag := range flags {
+		found := false
+		for _, raised := range q.Flags {
+			if raised == flag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// toolOutputs returns the tool outputs a message holds: all of a tool or terminal
+// message, and each [Tool Response] section of the others
+func toolOutputs(msg Message) []string {
+	if msg.Actor == ActorTool || msg.Actor == ActorTerminal {
+		return []string{msg.Content}
+	}
+	parts := strings.Split(msg.Content, "[Tool Response]")
+	if len(parts) < 2 {
+		return nil
+	}
+	outputs := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		// A response runs until the next tool call
+		if end := strings.Index(part, "[Tool Call]"); end >= 0 {
+			part = part[:end]
+		}
+		outputs = append(outputs, part)
+	}
+	return outputs
+}
+
+// FilterSessionsByQuality returns the sessions raising every one of flags
+func FilterSessionsByQuality(sessions []*Session, flags []string) []*Session {
+	if len(flags) == 0 {
+		return sessions
+	}
+	filtered := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session != nil && AssessQuality(session).HasFlags(flags) {
+			filtered = append(filtered, session)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssessQuality(t *testing.T) {
+	longReply := "Here is a detailed explanation of why the build failed and how to fix it."
+	tests := []struct {
+		name     string
+		messages []Message
+		want     SessionQuality
+	}{
+		{
+			name: "healthy",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Why does the build fail?"},
+				{Actor: ActorAssistant, Content: longReply},
+			},
+			want: SessionQuality{Replies: 1},
+		},
+		{
+			name: "unanswered and aborted",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Hello?"},
+				{Actor: ActorUser, Content: "Are you there?"},
+				{Actor: ActorAssistant, Content: longReply},
+				{Actor: ActorUser, Content: "Thanks, one more thing"},
+			},
+			want: SessionQuality{UnansweredTurns: 1, Replies: 1, Aborted: true, Flags: []string{FlagUnanswered, FlagAborted}},
+		},
+		{
+			name: "thinking answers a turn",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Plan it"},
+				{Actor: ActorAssistant, Thinking: "Considering the options"},
+			},
+			want: SessionQuality{},
+		},
+		{
+			name: "tool errors",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Run the tests"},
+				{Actor: ActorAssistant, Content: "[Tool Call]\nTool: run\n\n[Tool Response]\nbash: gotest: command not found"},
+				{Actor: ActorTerminal, Content: "FAIL\nexit status 1"},
+				{Actor: ActorTool, Content: "ok  all tests passed"},
+				{Actor: ActorAssistant, Content: longReply},
+			},
+			want: SessionQuality{ToolErrors: 2, Replies: 1, Flags: []string{FlagToolErrors}},
+		},
+		{
+			name: "short replies",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Fix it"},
+				{Actor: ActorAssistant, Content: "Done."},
+				{Actor: ActorUser, Content: "And the docs?"},
+				{Actor: ActorAssistant, Content: longReply},
+			},
+			want: SessionQuality{Replies: 2, ShortReplies: 1, Flags: []string{FlagShortReplies}},
+		},
+		{
+			name: "loop",
+			messages: []Message{
+				{Actor: ActorUser, Content: "Fix the failing test"},
+				{Actor: ActorAssistant, Content: "Let me look at the failing test in the parser package and fix it"},
+				{Actor: ActorAssistant, Content: "Let me look at the failing test in the parser package and fix it."},
+				{Actor: ActorAssistant, Content: "Let me look at the failing test in the parser package and fix it now"},
+			},
+			want: SessionQuality{Replies: 3, Repeats: 2, Flags: []string{FlagLoop}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AssessQuality(CreateTestSessionWithMessages("s", tt.messages))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AssessQuality() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewQualityFlags(t *testing.T) {
+	flags, err := NewQualityFlags([]string{"Aborted", " loop"})
+	if err != nil || !reflect.DeepEqual(flags, []string{FlagAborted, FlagLoop}) {
+		t.Errorf("NewQualityFlags() = %v, %v", flags, err)
+	}
+	if _, err := NewQualityFlags([]string{"boring"}); err == nil || !strings.Contains(err.Error(), "unanswered") {
+		t.Errorf("NewQualityFlags() of an unknown flag error = %v, want the valid flags listed", err)
+	}
+}
+
+func TestFilterSessionsByQuality(t *testing.T) {
+	aborted := CreateTestSessionWithMessages("aborted", []Message{{Actor: ActorUser, Content: "Anyone?"}})
+	healthy := CreateTestSessionWithMessages("healthy", []Message{
+		{Actor: ActorUser, Content: "Hi"},
+		{Actor: ActorAssistant, Content: "Hello, how can I help you today?"},
+	})
+	sessions := []*Session{aborted, healthy}
+
+	if got := FilterSessionsByQuality(sessions, nil); len(got) != 2 {
+		t.Errorf("FilterSessionsByQuality() without flags kept %d session(s), want all", len(got))
+	}
+	if got := FilterSessionsByQuality(sessions, []string{FlagAborted}); len(got) != 1 || got[0].ID != "aborted" {
+		t.Errorf("FilterSessionsByQuality(aborted) = %v", got)
+	}
+	if got := FilterSessionsByQuality(sessions, []string{FlagAborted, FlagLoop}); len(got) != 0 {
+		t.Errorf("FilterSessionsByQuality(aborted, loop) kept %d session(s), want those raising both", len(got))
+	}
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RawDumpVersion is the version of the RawDump format written by this build
+const RawDumpVersion = 1
+
+// RawDump is everything reconstruction and normalization read from storage, saved by the
+// extract phase so the later phases can be rerun without reading the databases again.
+// It implements StorageBackend, so a dump is reconstructed like any storage.
+type RawDump struct {
+	Version   int                   `json:"version"`
+	Bubbles   map[string]*RawBubble `json:"bubbles"`
+	Composers []*RawComposer        `json:"composers"`
+	// Provenance is where each bubble was read from, keyed like Bubbles
+	Provenance map[string]*Provenance       `json:"provenance,omitempty"`
+	Contexts   map[string][]*MessageContext `json:"contexts,omitempty"`
+	CodeDiffs  map[string][]interface{}     `json:"codeDiffs,omitempty"`
+	FileEdits  map[string][]RawFileEdit     `json:"fileEdits,omitempty"`
+	// Workspaces are the workspaces detected in the storage, keyed by hash
+	Workspaces map[string]*WorkspaceInfo `json:"workspaces,omitempty"`
+	// SessionWorkspaces are the workspaces the backend recorded, keyed by session ID
+	SessionWorkspaces map[string]string `json:"sessionWorkspaces,omitempty"`
+	// History is the timing the workspace databases record, for backfilling timestamps
+	History *WorkspaceHistory `json:"history,omitempty"`
+}
+
+// Ensure RawDump implements StorageBackend
+var _ StorageBackend = (*RawDump)(nil)
+
+// ExtractRawDump reads everything the reconstruction needs from a backend and the
+// workspaces of its storage locations. Contexts, diffs and file edits are optional, as
+// when sessions are loaded directly.
+func ExtractRawDump(backend StorageBackend, paths []StoragePaths) (*RawDump, error) {
+	bubbles, err := backend.LoadBubbles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bubbles: %w", err)
+	}
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load composers: %w", err)
+	}
+	contexts, err := backend.LoadMessageContexts()
+	if err != nil {
+		LogWarn("Failed to load message contexts: %v", err)
+	}
+	diffs, err := backend.LoadCodeBlockDiffs()
+	if err != nil {
+		LogWarn("Failed to load code block diffs: %v", err)
+	}
+
+	dump := &RawDump{
+		Version:           RawDumpVersion,
+		Bubbles:           bubbles,
+		Composers:         composers,
+		Provenance:        make(map[string]*Provenance),
+		Contexts:          contexts,
+		CodeDiffs:         diffs,
+		FileEdits:         LoadFileEdits(backend),
+		Workspaces:        DetectStorageWorkspaces(paths),
+		SessionWorkspaces: make(map[string]string),
+		History:           LoadWorkspaceHistory(paths),
+	}
+
+	// Record the workspace of every session the backend knows, including the sessions
+	// cursor-agent only records on its messages
+	recordWorkspace := func(id string) {
+		if _, done := dump.SessionWorkspaces[id]; id == "" || done {
+			return
+		}
+		if workspace := BackendSessionWorkspace(backend, id); workspace != "" {
+			dump.SessionWorkspaces[id] = workspace
+		}
+	}
+	for _, composer := range composers {
+		recordWorkspace(composer.ComposerID)
+	}
+	for key, bubble := range bubbles {
+		if bubble == nil {
+			continue
+		}
+		recordWorkspace(bubble.ChatID)
+		if bubble.Provenance != nil {
+			dump.Provenance[key] = bubble.Provenance
+		}
+	}
+	return dump, nil
+}
+
+// WriteRawDump writes a dump to path as JSON, creating its directory
+func WriteRawDump(path string, dump *RawDump) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		return fmt.Errorf("failed to encode raw dump: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadRawDump reads a dump written by WriteRawDump
+func ReadRawDump(path string) (*RawDump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw dump: %w", err)
+	}
+	var dump RawDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("failed to parse raw dump %s: %w", path, err)
+	}
+	if dump.Version < 1 || dump.Version > RawDumpVersion {
+		return nil, fmt.Errorf("raw dump %s has unsupported version %d (this build reads up to %d)", path, dump.Version, RawDumpVersion)
+	}
+	for key, provenance := range dump.Provenance {
+		if bubble := dump.Bubbles[key]; bubble != nil {
+			bubble.Provenance = provenance
+		}
+	}
+	return &dump, nil
+}
+
+// LoadBubbles returns the dumped bubbles
+func (d *RawDump) LoadBubbles() (map[string]*RawBubble, error) {
+	return d.Bubbles, nil
+}
+
+// LoadComposers returns the dumped composers
+func (d *RawDump) LoadComposers() ([]*RawComposer, error) {
+	return d.Composers, nil
+}
+
+// LoadMessageContexts returns the dumped message contexts
+func (d *RawDump) LoadMessageContexts() (map[string][]*MessageContext, error) {
+	if d.Contexts == nil {
+		return make(map[string][]*MessageContext), nil
+	}
+	return d.Contexts, nil
+}
+
+// LoadCodeBlockDiffs returns the dumped code block diffs
+func (d *RawDump) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
+	if d.CodeDiffs == nil {
+		return make(map[string][]interface{}), nil
+	}
+	return d.CodeDiffs, nil
+}
+
+// LoadFileEdits returns the dumped file edits
+func (d *RawDump) LoadFileEdits() (map[string][]RawFileEdit, error) {
+	return d.FileEdits, nil
+}
+
+// SessionWorkspace returns the workspace the backend recorded for a session
+func (d *RawDump) SessionWorkspace(composerID string) string {
+	return d.SessionWorkspaces[composerID]
+}
+
+// NormalizeSessions turns conversations reconstructed from the dump into sessions, as
+// the package-level NormalizeSessions does with the workspaces and history the dump holds
+func (d *RawDump) NormalizeSessions(conversations []*ReconstructedConversation, workspaceOverride string) []*Session {
+	loadHistory := func() *WorkspaceHistory {
+		if d.History == nil {
+			return NewWorkspaceHistory()
+		}
+		return d.History
+	}
+	return normalizeSessions(conversations, d, d.Workspaces, loadHistory, workspaceOverride)
+}
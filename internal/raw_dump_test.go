@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRawDump_RoundTrip(t *testing.T) {
+	backend := NewFileBackend(createExportDir(t))
+	direct, err := ReconstructConversations(backend)
+	if err != nil {
+		t.Fatalf("ReconstructConversations() error = %v", err)
+	}
+	want := NormalizeSessions(direct, backend, nil, "")
+
+	dump, err := ExtractRawDump(backend, nil)
+	if err != nil {
+		t.Fatalf("ExtractRawDump() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "phases", "raw.json")
+	if err := WriteRawDump(path, dump); err != nil {
+		t.Fatalf("WriteRawDump() error = %v", err)
+	}
+	read, err := ReadRawDump(path)
+	if err != nil {
+		t.Fatalf("ReadRawDump() error = %v", err)
+	}
+
+	// Conversations reconstructed from the dump survive being written and read back
+	conversations, err := ReconstructConversations(read)
+	if err != nil {
+		t.Fatalf("ReconstructConversations(dump) error = %v", err)
+	}
+	dir := t.TempDir()
+	for _, conv := range conversations {
+		if err := WriteConversation(dir, conv); err != nil {
+			t.Fatalf("WriteConversation() error = %v", err)
+		}
+	}
+	conversations, err = ReadConversations(dir)
+	if err != nil {
+		t.Fatalf("ReadConversations() error = %v", err)
+	}
+	got := read.NormalizeSessions(conversations, "")
+
+	if len(got) != len(want) {
+		t.Fatalf("NormalizeSessions() from the dump returned %d sessions, want %d", len(got), len(want))
+	}
+	byID := make(map[string]*Session)
+	for _, session := range got {
+		byID[session.ID] = session
+	}
+	for _, w := range want {
+		g := byID[w.ID]
+		if g == nil {
+			t.Errorf("session %s is missing from the dump's sessions", w.ID)
+			continue
+		}
+		if g.Workspace != w.Workspace || len(g.Messages) != len(w.Messages) || g.Metadata.Name != w.Metadata.Name {
+			t.Errorf("session %s from the dump = %q in %q with %d messages, want %q in %q with %d",
+				w.ID, g.Metadata.Name, g.Workspace, len(g.Messages), w.Metadata.Name, w.Workspace, len(w.Messages))
+		}
+	}
+	if session := byID["json-session"]; session == nil || session.Messages[0].Provenance == nil || session.Messages[0].Provenance.SourcePath != "/db/state.vscdb" {
+		t.Error("message provenance should survive the dump")
+	}
+	if byID["dumped"] == nil || byID["dumped"].Workspace != "ws-dump" {
+		t.Error("the workspace the backend recorded should survive the dump")
+	}
+}
+
+func TestReadRawDump_Errors(t *testing.T) {
+	dir := t.TempDir()
+	newer := filepath.Join(dir, "newer.json")
+	if err := os.WriteFile(newer, []byte(`{"version":99,"bubbles":{},"composers":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadRawDump(newer); err == nil || !strings.Contains(err.Error(), "unsupported version 99") {
+		t.Errorf("ReadRawDump() error = %v, want the unsupported version", err)
+	}
+	if _, err := ReadRawDump(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("ReadRawDump() should fail for a missing file")
+	}
+}
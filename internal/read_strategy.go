@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Read strategies decide how Cursor's databases are read while Cursor may be writing to them
+const (
+	ReadAuto     = "auto"     // direct, or copy while Cursor is writing to a rollback-journal database
+	ReadCopy     = "copy"     // copy the database, -wal and -shm files and read the copy
+	ReadDirect   = "direct"   // open the live database read-only, including its -wal
+	ReadSnapshot = "snapshot" // read the main database file as immutable, without locks or the -wal
+)
+
+// ReadStrategies lists the valid values of --read-strategy
+var ReadStrategies = []string{ReadAuto, ReadCopy, ReadDirect, ReadSnapshot}
+
+// Journal modes reported by DetectJournalMode
+const (
+	JournalWAL      = "wal"
+	JournalRollback = "rollback"
+)
+
+// readStrategy is the strategy OpenDatabase applies; copy and auto are resolved by the caller
+var readStrategy = ReadAuto
+
+// sqliteHeaderMagic starts every SQLite database file
+var sqliteHeaderMagic = []byte("SQLite format 3\x00")
+
+// ValidateReadStrategy returns an error for an unknown read strategy
+func ValidateReadStrategy(strategy string) error {
+	for _, s := range ReadStrategies {
+		if s == strategy {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid read strategy %q (expected auto, copy, direct or snapshot)", strategy)
+}
+
+// SetReadStrategy sets the read strategy for databases opened with OpenDatabase
+func SetReadStrategy(strategy string) {
+	readStrategy = strategy
+}
+
+// DetectJournalMode reads the header of a SQLite database to tell whether it is in WAL mode,
+// without opening it or taking a lock
+func DetectJournalMode(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return "", fmt.Errorf("failed to read database header: %w", err)
+	}
+	if !bytes.Equal(header[:len(sqliteHeaderMagic)], sqliteHeaderMagic) {
+		return "", fmt.Errorf("%s is not a SQLite database", path)
+	}
+	// Bytes 18 and 19 are the file format write and read versions: 2 for WAL, 1 for legacy
+	if header[18] == 2 && header[19] == 2 {
+		return JournalWAL, nil
+	}
+	return JournalRollback, nil
+}
+
+// ResolveReadStrategy turns auto into the strategy for the databases of the storage
+// locations. In WAL mode readers and Cursor's writer never wait for each other, so
+// databases are read directly, live -wal included. In rollback-journal mode a reader and
+// a writer take turns: while a -journal file shows a write in progress, reading directly
+// would wait for it and then hold up Cursor's next commit, so the databases are copied.
+// A database whose header can't be read is copied too.
+func ResolveReadStrategy(strategy string, list []StoragePaths) string {
+	if strategy != ReadAuto {
+		return strategy
+	}
+	for _, paths := range list {
+		var dbPaths []string
+		if paths.GlobalStorageExists() {
+			dbPaths = append(dbPaths, paths.GetGlobalStorageDBPath())
+		}
+		storeDBs, _ := paths.FindAgentStoreDBs()
+		dbPaths = append(dbPaths, storeDBs...)
+
+		for _, dbPath := range dbPaths {
+			mode, err := DetectJournalMode(dbPath)
+			if err != nil {
+				LogDebug("Could not detect the journal mode of %s, reading a copy: %v", dbPath, err)
+				return ReadCopy
+			}
+			if mode == JournalRollback && hotJournal(dbPath) {
+				LogDebug("%s is being written to, reading a copy", dbPath)
+				return ReadCopy
+			}
+		}
+	}
+	return ReadDirect
+}
+
+// hotJournal reports whether a rollback-journal database has a non-empty -journal file,
+// left by a write transaction in progress (or interrupted)
+func hotJournal(dbPath string) bool {
+	info, err := os.Stat(dbPath + "-journal")
+	return err == nil && info.Size() > 0
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// createJournalFixture creates a globalStorage database in the given journal mode
+func createJournalFixture(t *testing.T, journalMode string) StoragePaths {
+	t.Helper()
+	paths := StoragePathsForBase(testutil.CreateTempDir(t))
+	testutil.CreateSQLiteFixture(t, paths.GetGlobalStorageDBPath())
+	db, err := sql.Open("sqlite", paths.GetGlobalStorageDBPath())
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.Exec("PRAGMA journal_mode=" + journalMode); err != nil {
+		t.Fatalf("Failed to set journal mode: %v", err)
+	}
+	return paths
+}
+
+// writeFile writes content to path, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestDetectJournalMode(t *testing.T) {
+	for _, tt := range []struct{ pragma, want string }{
+		{"WAL", JournalWAL},
+		{"DELETE", JournalRollback},
+	} {
+		paths := createJournalFixture(t, tt.pragma)
+		if got, err := DetectJournalMode(paths.GetGlobalStorageDBPath()); err != nil || got != tt.want {
+			t.Errorf("DetectJournalMode(%s) = %q, %v, want %q", tt.pragma, got, err, tt.want)
+		}
+	}
+
+	notSQLite := filepath.Join(testutil.CreateTempDir(t), "state.vscdb")
+	writeFile(t, notSQLite, "definitely not a database file")
+	if _, err := DetectJournalMode(notSQLite); err == nil {
+		t.Error("DetectJournalMode() should fail for a file that isn't SQLite")
+	}
+}
+
+func TestResolveReadStrategy(t *testing.T) {
+	wal := createJournalFixture(t, "WAL")
+	rollback := createJournalFixture(t, "DELETE")
+	writing := createJournalFixture(t, "DELETE")
+	writeFile(t, writing.GetGlobalStorageDBPath()+"-journal", "pending write")
+	broken := StoragePathsForBase(testutil.CreateTempDir(t))
+	writeFile(t, broken.GetGlobalStorageDBPath(), "short")
+
+	tests := []struct {
+		name     string
+		strategy string
+		list     []StoragePaths
+		want     string
+	}{
+		{"explicit strategy is kept", ReadSnapshot, []StoragePaths{writing}, ReadSnapshot},
+		{"WAL reads directly", ReadAuto, []StoragePaths{wal}, ReadDirect},
+		{"idle rollback journal reads directly", ReadAuto, []StoragePaths{rollback}, ReadDirect},
+		{"write in progress is copied", ReadAuto, []StoragePaths{wal, writing}, ReadCopy},
+		{"unreadable header is copied", ReadAuto, []StoragePaths{broken}, ReadCopy},
+		{"no databases", ReadAuto, []StoragePaths{{ExportDir: os.TempDir()}}, ReadDirect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveReadStrategy(tt.strategy, tt.list); got != tt.want {
+				t.Errorf("ResolveReadStrategy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadStrategy_Snapshot(t *testing.T) {
+	defer SetReadStrategy(ReadAuto)
+	SetReadStrategy(ReadSnapshot)
+
+	paths := createJournalFixture(t, "WAL")
+	db, err := OpenDatabase(paths.GetGlobalStorageDBPath())
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, found, err := QueryCursorDiskKVKey(db, paths.GetGlobalStorageDBPath(), "composerData:composer1"); err != nil || !found {
+		t.Errorf("snapshot read found = %v, error = %v", found, err)
+	}
+}
+
+func TestValidateReadStrategy(t *testing.T) {
+	for _, s := range ReadStrategies {
+		if err := ValidateReadStrategy(s); err != nil {
+			t.Errorf("ValidateReadStrategy(%s) error = %v", s, err)
+		}
+	}
+	if err := ValidateReadStrategy("fast"); err == nil {
+		t.Error("ValidateReadStrategy(fast) should fail")
+	}
+}
+
+func TestCopyStoragePaths_RecordsOrigin(t *testing.T) {
+	paths := createJournalFixture(t, "DELETE")
+	copied, cleanup, err := CopyStoragePaths(paths)
+	if err != nil {
+		t.Fatalf("CopyStoragePaths() error = %v", err)
+	}
+	defer func() { _ = cleanup() }()
+	if copied.CopiedFrom == nil || copied.CopiedFrom.GlobalStorage != paths.GlobalStorage {
+		t.Errorf("CopiedFrom = %+v, want the original paths", copied.CopiedFrom)
+	}
+}
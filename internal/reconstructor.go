@@ -1,10 +1,12 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
-	"time"
 )
 
 // ReconstructedConversation represents a fully reconstructed conversation
@@ -14,31 +16,79 @@ type ReconstructedConversation struct {
 	Messages   []ReconstructedMessage
 	CreatedAt  int64
 	UpdatedAt  int64
+	ParentID   string     // Session this one was resumed from, if recorded
+	Diffs      []CodeDiff // Diffs that could not be matched to a message
+	FileEdits  []FileEdit // File edits that could not be matched to a message
+	Branch     *Branch    // Set on an alternate branch of a forked conversation
+	// Branches are the alternate branches of a forked conversation; the conversation
+	// itself is its active branch
+	Branches []*ReconstructedConversation
+	// Extraction counts the conversation's bubbles by the tier their text came from
+	Extraction ExtractionStats
+	// Fields are the metadata fields the normalizer rules map from the composer's fields
+	Fields map[string]interface{}
+	// WorkspaceHash names the workspace of a cursor-agent session, from its chats/{hash}
+	// directory
+	WorkspaceHash string
 }
 
 // ReconstructedMessage represents a message in a reconstructed conversation
 type ReconstructedMessage struct {
-	BubbleID  string
-	Type      int // 1=user, 2=assistant
-	Text      string
-	Timestamp int64
-	Context   *MessageContext
+	BubbleID   string
+	Type       int // 1=user, 2=assistant, 3=tool, 4=terminal, 5=system
+	Text       string
+	Thinking   string // Reasoning kept apart from Text
+	Timestamp  int64
+	Context    *MessageContext
+	Provenance *Provenance
+	Diffs      []CodeDiff
+	FileEdits  []FileEdit
+	// CheckpointID is the checkpoint the message's file edits were recorded under
+	CheckpointID string
+	Images       []BubbleImage // Images attached to the message
+	// ParseFailure marks the placeholder kept for a message whose record failed to parse
+	ParseFailure bool
 }
 
 // Reconstructor handles conversation reconstruction
 type Reconstructor struct {
-	bubbleMap  *BubbleMap
+	bubbles    BubbleResolver
+	finder     BubbleFinder
 	contextMap map[string][]*MessageContext
+	diffMap    map[string][]CodeDiff
+	editMap    map[string][]RawFileEdit
 }
 
 // NewReconstructor creates a new Reconstructor
 func NewReconstructor(bubbleMap *BubbleMap, contextMap map[string][]*MessageContext) *Reconstructor {
+	return NewReconstructorWithResolver(bubbleMap, contextMap)
+}
+
+// NewReconstructorWithResolver creates a Reconstructor that looks bubbles up through
+// resolver, such as a LazyBubbleResolver loading them on demand
+func NewReconstructorWithResolver(resolver BubbleResolver, contextMap map[string][]*MessageContext) *Reconstructor {
 	return &Reconstructor{
-		bubbleMap:  bubbleMap,
+		bubbles:    resolver,
 		contextMap: contextMap,
 	}
 }
 
+// SetCodeDiffs sets the code block diffs, keyed by composer ID, attached to reconstructed messages
+func (r *Reconstructor) SetCodeDiffs(diffMap map[string][]CodeDiff) {
+	r.diffMap = diffMap
+}
+
+// SetFileEdits sets the file edits, keyed by composer ID, attached to reconstructed messages
+func (r *Reconstructor) SetFileEdits(editMap map[string][]RawFileEdit) {
+	r.editMap = editMap
+}
+
+// SetBubbleFinder sets where bubbles the resolver does not have are looked up before
+// their messages are given up as missing
+func (r *Reconstructor) SetBubbleFinder(finder BubbleFinder) {
+	r.finder = finder
+}
+
 // ReconstructConversation reconstructs a conversation from a composer
 func (r *Reconstructor) ReconstructConversation(composer *RawComposer) (*ReconstructedConversation, error) {
 	if composer == nil {
@@ -46,10 +96,13 @@ func (r *Reconstructor) ReconstructConversation(composer *RawComposer) (*Reconst
 	}
 
 	conv := &ReconstructedConversation{
-		ComposerID: composer.ComposerID,
-		Name:       composer.Name,
-		CreatedAt:  composer.CreatedAt,
-		UpdatedAt:  composer.LastUpdatedAt,
+		ComposerID:    composer.ComposerID,
+		Name:          composer.Name,
+		CreatedAt:     composer.CreatedAt,
+		UpdatedAt:     composer.LastUpdatedAt,
+		ParentID:      composer.ParentID,
+		Fields:        composer.Fields,
+		WorkspaceHash: composer.WorkspaceHash,
 	}
 
 	// Get context for this composer
@@ -59,69 +112,220 @@ func (r *Reconstructor) ReconstructConversation(composer *RawComposer) (*Reconst
 		contextByBubbleID[ctx.BubbleID] = ctx
 	}
 
-	// Reconstruct messages from headers
-	// NOTE: FullConversationHeadersOnly array is already in the correct chronological order.
-	// We preserve this order and only sort by timestamp if timestamps differ.
-	for _, header := range composer.FullConversationHeadersOnly {
-		bubble, ok := r.bubbleMap.Get(header.BubbleID)
+	// A forked conversation is reconstructed from its active branch; the others are
+	// kept as alternates
+	active, alternates := conversationBranches(composer.FullConversationHeadersOnly)
+	conv.Messages, conv.Extraction = r.reconstructMessages(composer, active, contextByBubbleID)
+	if missing := conv.Extraction.Missing; missing > 0 {
+		LogWarn("Session %s: %d of %d message(s) its headers reference were not found in any database", composer.ComposerID, missing, len(active))
+	}
+	r.attachDiffs(conv, composer)
+	r.attachFileEdits(conv, composer)
+
+	for _, headers := range alternates {
+		branch := &ReconstructedConversation{
+			ComposerID:    BranchID(composer.ComposerID, len(conv.Branches)+1),
+			Name:          composer.Name,
+			CreatedAt:     composer.CreatedAt,
+			UpdatedAt:     composer.LastUpdatedAt,
+			ParentID:      composer.ParentID,
+			Fields:        composer.Fields,
+			WorkspaceHash: composer.WorkspaceHash,
+		}
+		branch.Messages, branch.Extraction = r.reconstructMessages(composer, headers, contextByBubbleID)
+		// Edits that could not be matched to a message stay with the active branch
+		r.attachDiffs(branch, composer)
+		r.attachFileEdits(branch, composer)
+		branch.Diffs = nil
+		branch.FileEdits = nil
+		branch.Branch = &Branch{
+			SessionID:   composer.ComposerID,
+			Index:       len(conv.Branches) + 1,
+			Count:       len(alternates),
+			ForkMessage: forkMessage(conv.Messages, branch.Messages),
+		}
+		conv.Branches = append(conv.Branches, branch)
+	}
+
+	return conv, nil
+}
+
+// reconstructMessages builds the messages of a composer's headers. Messages are sorted
+// by timestamp only when their timestamps differ: cursor-agent doesn't store per-message
+// timestamps, so all messages have the same session createdAt, and the order of the
+// headers, already chronological, is kept. The bubbles found are counted by the tier
+// their text was extracted from, and those found nowhere as missing.
+func (r *Reconstructor) reconstructMessages(composer *RawComposer, headers []ConversationHeader, contextByBubbleID map[string]*MessageContext) ([]ReconstructedMessage, ExtractionStats) {
+	var messages []ReconstructedMessage
+	var stats ExtractionStats
+	for _, header := range headers {
+		bubble, ok := r.bubbles.Resolve(composer.ComposerID, header.BubbleID)
+		if !ok {
+			bubble, ok = r.findBubble(composer.ComposerID, header.BubbleID)
+		}
 		if !ok {
 			LogDebug("Bubble %s referenced in composer %s not found in bubble map", header.BubbleID, composer.ComposerID)
+			stats.Missing++
+			if placeholder, ok := parseFailureMessage(header); ok {
+				messages = append(messages, placeholder)
+			}
 			continue
 		}
 
 		// Extract text from bubble
-		text, err := ExtractTextFromBubble(bubble)
+		text, tier, err := ExtractTextWithTier(bubble)
 		if err != nil {
 			// Log error but continue
 			LogDebug("Failed to extract text from bubble %s: %v", header.BubbleID, err)
+			stats.Record(ExtractionPlaceholder)
 			continue
 		}
+		stats.Record(tier)
 
 		// Skip empty messages (matching reference implementation behavior)
-		// Only skip if it's the placeholder, not if it's actual empty content
-		if text == "" || text == "[Message with no extractable text content]" {
-			LogDebug("Skipping empty message bubble %s", header.BubbleID)
-			continue
+		// Only skip if it's the placeholder, not if it's actual empty content.
+		// A message holding nothing but thinking or images is kept, with no text, and
+		// the normalizer rules may keep the others.
+		thinking := ExtractThinking(bubble)
+		images := bubble.AttachedImages()
+		if text == "" || text == placeholderText {
+			placeholder, keep := normalizerRules.keepPlaceholder()
+			switch {
+			case thinking != "" || len(images) > 0:
+				text = ""
+			case keep:
+				text = placeholder
+			default:
+				LogDebug("Skipping empty message bubble %s", header.BubbleID)
+				continue
+			}
 		}
 
-		// Get context for this bubble
-		context := contextByBubbleID[header.BubbleID]
+		messages = append(messages, ReconstructedMessage{
+			BubbleID:   header.BubbleID,
+			Type:       bubble.MessageType(header.Type),
+			Text:       text,
+			Thinking:   thinking,
+			Timestamp:  bubble.Timestamp,
+			Context:    contextByBubbleID[header.BubbleID],
+			Provenance: BubbleProvenance(bubble),
+			// Kept to match the files edited at the checkpoint to the message
+			CheckpointID: bubble.CheckpointID,
+			Images:       images,
+		})
+	}
 
-		msg := ReconstructedMessage{
-			BubbleID:  header.BubbleID,
-			Type:      header.Type,
-			Text:      text,
-			Timestamp: bubble.Timestamp,
-			Context:   context,
+	dateParseFailures(messages)
+	hasDifferentTimestamps := false
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Timestamp != messages[0].Timestamp {
+			hasDifferentTimestamps = true
+			break
 		}
+	}
+	if hasDifferentTimestamps {
+		sort.SliceStable(messages, func(i, j int) bool {
+			return messages[i].Timestamp < messages[j].Timestamp
+		})
+	}
+	return messages, stats
+}
+
+// findBubble looks up a bubble the resolver does not have with the bubble finder, if any
+func (r *Reconstructor) findBubble(composerID, bubbleID string) (*RawBubble, bool) {
+	if r.finder == nil {
+		return nil, false
+	}
+	bubble, err := r.finder.FindBubble(bubbleID)
+	if err != nil {
+		if !errors.Is(err, ErrBubbleNotFound) {
+			LogDebug("Failed to look up bubble %s of composer %s: %v", bubbleID, composerID, err)
+		}
+		return nil, false
+	}
+	LogDebug("Found bubble %s of composer %s outside the storage read", bubbleID, composerID)
+	return bubble, true
+}
 
-		conv.Messages = append(conv.Messages, msg)
+// forkMessage returns the number, from 1, of the first message of a branch that differs
+// from the active branch
+func forkMessage(active, branch []ReconstructedMessage) int {
+	n := 0
+	for n < len(active) && n < len(branch) && active[n].BubbleID == branch[n].BubbleID {
+		n++
 	}
+	return n + 1
+}
 
-	// Sort messages by timestamp ONLY if timestamps differ
-	// cursor-agent doesn't store per-message timestamps, so all messages have the same
-	// session createdAt. In this case, we preserve the order from FullConversationHeadersOnly.
-	hasDifferentTimestamps := false
-	if len(conv.Messages) > 1 {
-		firstTimestamp := conv.Messages[0].Timestamp
-		for i := 1; i < len(conv.Messages); i++ {
-			if conv.Messages[i].Timestamp != firstTimestamp {
-				hasDifferentTimestamps = true
-				break
-			}
+// attachDiffs adds the composer's code block diffs to the messages that applied them.
+// Diffs whose bubble is unknown or was skipped are kept on the conversation.
+func (r *Reconstructor) attachDiffs(conv *ReconstructedConversation, composer *RawComposer) {
+	diffs := r.diffMap[composer.ComposerID]
+	if len(diffs) == 0 {
+		return
+	}
+
+	refs := composer.codeBlockRefs()
+	messageByBubbleID := make(map[string]int, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		messageByBubbleID[msg.BubbleID] = i
+	}
+
+	for _, diff := range diffs {
+		if ref, ok := refs[diff.DiffID]; ok {
+			ref.resolve(&diff)
+		}
+		if i, ok := messageByBubbleID[diff.BubbleID]; ok && diff.BubbleID != "" {
+			conv.Messages[i].Diffs = append(conv.Messages[i].Diffs, diff)
+			continue
 		}
+		conv.Diffs = append(conv.Diffs, diff)
 	}
+}
 
-	if hasDifferentTimestamps {
-		// Timestamps differ - sort by timestamp to ensure chronological order
-		sort.Slice(conv.Messages, func(i, j int) bool {
-			return conv.Messages[i].Timestamp < conv.Messages[j].Timestamp
-		})
+// attachFileEdits adds the composer's file edits to the messages that made them: edits
+// at a checkpoint to the message that recorded it, decisions on a diff to the message
+// that applied the diff. Edits that cannot be matched are kept on the conversation.
+func (r *Reconstructor) attachFileEdits(conv *ReconstructedConversation, composer *RawComposer) {
+	edits := r.editMap[composer.ComposerID]
+	if len(edits) == 0 {
+		return
 	}
-	// If all timestamps are the same, preserve order from FullConversationHeadersOnly array
-	// This is the correct order for cursor-agent sessions
 
-	return conv, nil
+	refs := composer.codeBlockRefs()
+	messageByBubbleID := make(map[string]int, len(conv.Messages))
+	messageByCheckpoint := make(map[string]int)
+	for i, msg := range conv.Messages {
+		messageByBubbleID[msg.BubbleID] = i
+		if msg.CheckpointID != "" {
+			messageByCheckpoint[msg.CheckpointID] = i
+		}
+	}
+
+	for _, edit := range edits {
+		if ref, ok := refs[edit.DiffID]; ok && edit.DiffID != "" {
+			if edit.BubbleID == "" {
+				edit.BubbleID = ref.BubbleID
+			}
+			if edit.FilePath == "" {
+				edit.FilePath = ref.FilePath
+			}
+		}
+		if edit.FilePath == "" {
+			continue
+		}
+
+		i, ok := messageByBubbleID[edit.BubbleID]
+		if !ok || edit.BubbleID == "" {
+			i, ok = messageByCheckpoint[edit.CheckpointID]
+			ok = ok && edit.CheckpointID != ""
+		}
+		if ok {
+			conv.Messages[i].FileEdits = append(conv.Messages[i].FileEdits, edit.FileEdit)
+			continue
+		}
+		conv.FileEdits = append(conv.FileEdits, edit.FileEdit)
+	}
 }
 
 // ReconstructAllConversations reconstructs all conversations from composers
@@ -135,14 +339,21 @@ func (r *Reconstructor) ReconstructAllConversations(composers []*RawComposer) ([
 			continue
 		}
 
-		// Only include conversations with messages
+		// Only include conversations with messages. Composers without headers are chats
+		// that were opened but never used, so they don't count as empty for --strict.
+		headerCount := len(composer.FullConversationHeadersOnly)
+		if headerCount > 0 {
+			RecordSession(len(conv.Messages) == 0)
+		}
 		if len(conv.Messages) == 0 {
-			headerCount := len(composer.FullConversationHeadersOnly)
 			LogWarn("Composer %s produced 0 messages (had %d headers). "+
 				"Possible causes: headers reference non-existent bubbles, or all messages were empty",
 				composer.ComposerID, headerCount)
 			continue
 		}
+		if len(conv.Branches) > 0 {
+			LogDebugAttrs("Composer forked into alternate branches; using the active one", "session", composer.ComposerID, "branches", len(conv.Branches))
+		}
 		conversations = append(conversations, conv)
 	}
 
@@ -154,10 +365,49 @@ func ReconstructAsync(
 	bubbleChan <-chan *RawBubble,
 	composerChan <-chan *RawComposer,
 	contextChan <-chan *MessageContext,
+) ([]*ReconstructedConversation, error) {
+	return ReconstructAsyncWithDiffs(bubbleChan, composerChan, contextChan, nil)
+}
+
+// ReconstructAsyncWithEdits reconstructs conversations using async processing and attaches
+// code block diffs and file edits, both keyed by composer ID, to the messages that made them
+func ReconstructAsyncWithEdits(
+	bubbleChan <-chan *RawBubble,
+	composerChan <-chan *RawComposer,
+	contextChan <-chan *MessageContext,
+	diffMap map[string][]CodeDiff,
+	editMap map[string][]RawFileEdit,
+) ([]*ReconstructedConversation, error) {
+	return reconstructAsync(bubbleChan, composerChan, contextChan, func(r *Reconstructor) {
+		r.SetCodeDiffs(diffMap)
+		r.SetFileEdits(editMap)
+	})
+}
+
+// ReconstructAsyncWithDiffs reconstructs conversations using async processing and attaches
+// code block diffs, keyed by composer ID, to the messages that applied them
+func ReconstructAsyncWithDiffs(
+	bubbleChan <-chan *RawBubble,
+	composerChan <-chan *RawComposer,
+	contextChan <-chan *MessageContext,
+	diffMap map[string][]CodeDiff,
+) ([]*ReconstructedConversation, error) {
+	return reconstructAsync(bubbleChan, composerChan, contextChan, func(r *Reconstructor) {
+		r.SetCodeDiffs(diffMap)
+	})
+}
+
+// reconstructAsync collects the loaded data and reconstructs conversations with a
+// Reconstructor set up by configure
+func reconstructAsync(
+	bubbleChan <-chan *RawBubble,
+	composerChan <-chan *RawComposer,
+	contextChan <-chan *MessageContext,
+	configure func(*Reconstructor),
 ) ([]*ReconstructedConversation, error) {
 	// Build bubble map from channel
 	bubbleMap := BuildBubbleMapFromChannel(bubbleChan)
-	LogInfo("Built bubble map with %d bubbles", bubbleMap.Len())
+	LogDebug("Built bubble map with %d bubbles", bubbleMap.Len())
 
 	// Collect composers
 	var composers []*RawComposer
@@ -165,10 +415,10 @@ func ReconstructAsync(
 		if composer != nil {
 			composers = append(composers, composer)
 			headerCount := len(composer.FullConversationHeadersOnly)
-			LogInfo("Composer %s: %d headers, name='%s'", composer.ComposerID, headerCount, composer.Name)
+			LogDebugAttrs("Collected composer", "session", composer.ComposerID, "headers", headerCount, "name", composer.Name)
 		}
 	}
-	LogInfo("Collected %d composers from channel", len(composers))
+	LogDebug("Collected %d composers from channel", len(composers))
 
 	// Build context map from channel
 	contextMap := make(map[string][]*MessageContext)
@@ -179,7 +429,7 @@ func ReconstructAsync(
 			contextCount++
 		}
 	}
-	LogInfo("Built context map with %d contexts across %d composers", contextCount, len(contextMap))
+	LogDebug("Built context map with %d contexts across %d composers", contextCount, len(contextMap))
 
 	// If no composers but we have bubbles, create composers from bubbles
 	// This handles cursor-agent format where messages are stored as bubbles without explicit composers
@@ -191,6 +441,7 @@ func ReconstructAsync(
 
 	// Reconstruct conversations
 	reconstructor := NewReconstructor(bubbleMap, contextMap)
+	configure(reconstructor)
 	return reconstructor.ReconstructAllConversations(composers)
 }
 
@@ -217,13 +468,13 @@ func LoadDataAsyncFromBackend(backend StorageBackend) (<-chan *RawBubble, <-chan
 			LogWarn("Failed to load bubbles: %v", err)
 			return
 		}
-		LogInfo("Loading %d bubbles into channel", len(bubbles))
+		LogDebug("Loading %d bubbles into channel", len(bubbles))
 		for _, bubble := range bubbles {
 			if bubble != nil {
 				bubbleChan <- bubble
 			}
 		}
-		LogInfo("Finished sending %d bubbles to channel", len(bubbles))
+		LogDebug("Finished sending %d bubbles to channel", len(bubbles))
 	}()
 
 	// Load composers
@@ -265,47 +516,39 @@ func LoadDataAsyncFromBackend(backend StorageBackend) (<-chan *RawBubble, <-chan
 }
 
 // createComposersFromBubbles creates composers from bubbles when no explicit composers exist
-// This handles cursor-agent format where messages are stored as bubbles without composers
+// This handles cursor-agent format where messages are stored as bubbles without composers.
+// The composers and their headers are built in a stable order, so that reading the same
+// data twice gives the same sessions.
 func createComposersFromBubbles(bubbleMap *BubbleMap) []*RawComposer {
-	// Group bubbles by ChatID
+	// Group bubbles by ChatID; bubbles without one are grouped into a session whose ID is
+	// derived from their content
 	bubblesByChatID := make(map[string][]*RawBubble)
-	allBubbles := bubbleMap.GetAll()
-	for _, bubble := range allBubbles {
-		chatID := bubble.ChatID
-		if chatID == "" {
-			// Use a default chatID if not set
-			chatID = "default-session"
+	var unassigned []*RawBubble
+	for _, bubble := range bubbleMap.GetAll() {
+		if bubble.ChatID == "" {
+			unassigned = append(unassigned, bubble)
+			continue
 		}
-		bubblesByChatID[chatID] = append(bubblesByChatID[chatID], bubble)
+		bubblesByChatID[bubble.ChatID] = append(bubblesByChatID[bubble.ChatID], bubble)
+	}
+	if len(unassigned) > 0 {
+		sortBubbles(unassigned)
+		bubblesByChatID[ContentSessionID(unassigned)] = unassigned
 	}
 
+	chatIDs := make([]string, 0, len(bubblesByChatID))
+	for chatID := range bubblesByChatID {
+		chatIDs = append(chatIDs, chatID)
+	}
+	sort.Strings(chatIDs)
+
 	var composers []*RawComposer
-	for chatID, bubbles := range bubblesByChatID {
+	for _, chatID := range chatIDs {
+		bubbles := bubblesByChatID[chatID]
 		// NOTE: cursor-agent doesn't store per-message timestamps, so all bubbles have the same
-		// session createdAt. We cannot sort by timestamp. Instead, we preserve the order from
-		// the database query (which reflects insertion order). The database query should use
-		// ORDER BY rowid to ensure consistent ordering, but even without it, SQLite typically
-		// returns rows in insertion order.
-		//
-		// Only sort by timestamp if timestamps actually differ (shouldn't happen for cursor-agent)
-		hasDifferentTimestamps := false
-		if len(bubbles) > 1 {
-			firstTimestamp := bubbles[0].Timestamp
-			for i := 1; i < len(bubbles); i++ {
-				if bubbles[i].Timestamp != firstTimestamp {
-					hasDifferentTimestamps = true
-					break
-				}
-			}
-		}
-
-		if hasDifferentTimestamps {
-			// Timestamps differ - sort by timestamp
-			sort.Slice(bubbles, func(i, j int) bool {
-				return bubbles[i].Timestamp < bubbles[j].Timestamp
-			})
-		}
-		// Otherwise, preserve the order from database (insertion order)
+		// session createdAt. The bubbles come from a map, so they are ordered by timestamp
+		// and then bubble ID, which at least keeps the order the same from run to run.
+		sortBubbles(bubbles)
 
 		// Create conversation headers from bubbles
 		headers := make([]ConversationHeader, 0, len(bubbles))
@@ -323,13 +566,8 @@ func createComposersFromBubbles(bubbleMap *BubbleMap) []*RawComposer {
 			}
 		}
 
-		// If timestamps are 0, use current time
-		if createdAt == 0 {
-			createdAt = time.Now().UnixMilli()
-		}
-		if lastUpdatedAt == 0 {
-			lastUpdatedAt = time.Now().UnixMilli()
-		}
+		// Timestamps that are unknown stay 0 rather than the time of the run, which would
+		// change every export
 
 		composer := &RawComposer{
 			ComposerID:                  chatID,
@@ -340,8 +578,28 @@ func createComposersFromBubbles(bubbleMap *BubbleMap) []*RawComposer {
 		}
 
 		composers = append(composers, composer)
-		LogInfo("Created composer %s from %d bubbles", chatID, len(headers))
+		LogDebugAttrs("Created composer from bubbles", "session", chatID, "bubbles", len(headers))
 	}
 
 	return composers
 }
+
+// sortBubbles orders bubbles by timestamp and then bubble ID
+func sortBubbles(bubbles []*RawBubble) {
+	sort.SliceStable(bubbles, func(i, j int) bool {
+		if bubbles[i].Timestamp != bubbles[j].Timestamp {
+			return bubbles[i].Timestamp < bubbles[j].Timestamp
+		}
+		return bubbles[i].BubbleID < bubbles[j].BubbleID
+	})
+}
+
+// ContentSessionID derives the ID of a session that storage gives none from its bubbles,
+// in order, so the same messages always get the same ID
+func ContentSessionID(bubbles []*RawBubble) string {
+	h := sha256.New()
+	for _, bubble := range bubbles {
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00%s\x00", bubble.BubbleID, bubble.Type, bubble.Text)
+	}
+	return "session-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -11,8 +13,8 @@ func TestNewReconstructor(t *testing.T) {
 	reconstructor := NewReconstructor(bubbleMap, contextMap)
 	// NewReconstructor always returns a non-nil pointer
 	//nolint:staticcheck // SA5011: false positive - NewReconstructor never returns nil
-	if reconstructor.bubbleMap != bubbleMap {
-		t.Error("NewReconstructor() did not set bubbleMap correctly")
+	if reconstructor.bubbles != BubbleResolver(bubbleMap) {
+		t.Error("NewReconstructor() did not set bubbles correctly")
 	}
 	// Can't compare maps directly, so check they're both non-nil or both nil
 	//nolint:staticcheck // SA5011: false positive
@@ -69,6 +71,60 @@ func TestReconstructor_ReconstructConversation(t *testing.T) {
 	}
 }
 
+func TestReconstructor_KeepsThinking(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("bubble1", CreateTestRawBubble("bubble1", "chat1", "Fix it", 1))
+	thinkingOnly := CreateTestRawBubble("bubble2", "chat1", "", 2)
+	thinkingOnly.Thinking = &BubbleThinking{Text: "Where is the bug?"}
+	bubbleMap.Set("bubble2", thinkingOnly)
+	bubbleMap.Set("bubble3", CreateTestRawBubble("bubble3", "chat1", "", 2))
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "bubble1", Type: 1},
+			{BubbleID: "bubble2", Type: 2},
+			{BubbleID: "bubble3", Type: 2},
+		},
+	}
+	conv, err := NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+	if len(conv.Messages) != 2 {
+		t.Fatalf("ReconstructConversation() returned %d messages, want the empty bubble skipped", len(conv.Messages))
+	}
+	if msg := conv.Messages[1]; msg.Text != "" || msg.Thinking != "Where is the bug?" {
+		t.Errorf("thinking-only message = %+v", msg)
+	}
+}
+
+func TestReconstructor_KeepsImages(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	imageOnly := CreateTestRawBubble("bubble1", "chat1", "", 1)
+	imageOnly.Context = &BubbleContext{SelectedImages: []BubbleImage{{Path: "/tmp/shot.png"}}}
+	bubbleMap.Set("bubble1", imageOnly)
+	bubbleMap.Set("bubble2", CreateTestRawBubble("bubble2", "chat1", "A red button", 2))
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "bubble1", Type: 1},
+			{BubbleID: "bubble2", Type: 2},
+		},
+	}
+	conv, err := NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+	if len(conv.Messages) != 2 {
+		t.Fatalf("ReconstructConversation() returned %d messages, want the image-only message kept", len(conv.Messages))
+	}
+	if images := conv.Messages[0].Images; len(images) != 1 || images[0].Path != "/tmp/shot.png" {
+		t.Errorf("image-only message Images = %+v", images)
+	}
+}
+
 func TestReconstructor_ReconstructConversation_NilComposer(t *testing.T) {
 	bubbleMap := NewBubbleMap()
 	contextMap := make(map[string][]*MessageContext)
@@ -101,6 +157,46 @@ func TestReconstructor_ReconstructConversation_MissingBubble(t *testing.T) {
 	if len(conv.Messages) != 0 {
 		t.Errorf("ReconstructConversation() returned %d messages, want 0 (missing bubble)", len(conv.Messages))
 	}
+	if conv.Extraction.Missing != 1 {
+		t.Errorf("Extraction.Missing = %d, want 1", conv.Extraction.Missing)
+	}
+}
+
+// mapFinder finds the bubbles of a map, as a backend holding them elsewhere
+type mapFinder map[string]*RawBubble
+
+func (f mapFinder) FindBubble(bubbleID string) (*RawBubble, error) {
+	if bubble, ok := f[bubbleID]; ok {
+		return bubble, nil
+	}
+	return nil, ErrBubbleNotFound
+}
+
+func TestReconstructor_FindsMissingBubbles(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("here", &RawBubble{BubbleID: "here", Text: "Resume the refactor", Type: 1})
+	composer := &RawComposer{
+		ComposerID: "resumed",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "elsewhere", Type: 1},
+			{BubbleID: "here", Type: 1},
+			{BubbleID: "gone", Type: 2},
+		},
+	}
+
+	reconstructor := NewReconstructor(bubbleMap, nil)
+	reconstructor.SetBubbleFinder(mapFinder{"elsewhere": {BubbleID: "elsewhere", Text: "Start the refactor", Type: 1}})
+	conv, err := reconstructor.ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+
+	if len(conv.Messages) != 2 || conv.Messages[0].Text != "Start the refactor" {
+		t.Errorf("Messages = %+v, want the bubble found elsewhere first", conv.Messages)
+	}
+	if conv.Extraction.Primary != 2 || conv.Extraction.Missing != 1 {
+		t.Errorf("Extraction = %+v, want 2 bubbles found and 1 missing", conv.Extraction)
+	}
 }
 
 func TestReconstructor_ReconstructAllConversations(t *testing.T) {
@@ -155,3 +251,267 @@ func TestReconstructor_ReconstructAllConversations_Empty(t *testing.T) {
 		t.Errorf("ReconstructAllConversations() returned %d conversations, want 0", len(conversations))
 	}
 }
+
+func TestReconstructor_AttachesCodeDiffs(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("bubble1", CreateTestRawBubble("bubble1", "chat1", "Please fix it", 1))
+	bubbleMap.Set("bubble2", CreateTestRawBubble("bubble2", "chat1", "Fixed", 2))
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "bubble1", Type: 1},
+			{BubbleID: "bubble2", Type: 2},
+		},
+		CodeBlockData: map[string]json.RawMessage{
+			"file:///repo/main.go": json.RawMessage(`[{"diffId":"diff1","bubbleId":"bubble2","status":"accepted"}]`),
+		},
+	}
+
+	hunks := []DiffHunk{{OriginalStart: 1, OriginalEnd: 2, After: []string{"fixed"}}}
+	reconstructor := NewReconstructor(bubbleMap, nil)
+	reconstructor.SetCodeDiffs(map[string][]CodeDiff{
+		"composer1": {
+			{DiffID: "diff1", Hunks: hunks},
+			{DiffID: "orphan", Hunks: hunks},
+		},
+	})
+
+	conv, err := reconstructor.ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+
+	if len(conv.Messages[0].Diffs) != 0 {
+		t.Errorf("user message has %d diffs, want 0", len(conv.Messages[0].Diffs))
+	}
+	if len(conv.Messages[1].Diffs) != 1 {
+		t.Fatalf("assistant message has %d diffs, want 1", len(conv.Messages[1].Diffs))
+	}
+	diff := conv.Messages[1].Diffs[0]
+	if diff.FilePath != "/repo/main.go" || diff.BubbleID != "bubble2" || diff.Status != "accepted" {
+		t.Errorf("attached diff = %+v, want main.go from bubble2 (accepted)", diff)
+	}
+	if len(conv.Diffs) != 1 || conv.Diffs[0].DiffID != "orphan" {
+		t.Errorf("conversation diffs = %+v, want the unmatched orphan diff", conv.Diffs)
+	}
+}
+
+func TestReconstructor_AttachesFileEdits(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("bubble1", CreateTestRawBubble("bubble1", "chat1", "Please fix it", 1))
+	assistant := CreateTestRawBubble("bubble2", "chat1", "Fixed", 2)
+	assistant.CheckpointID = "cp1"
+	bubbleMap.Set("bubble2", assistant)
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "bubble1", Type: 1},
+			{BubbleID: "bubble2", Type: 2},
+		},
+		CodeBlockData: map[string]json.RawMessage{
+			"file:///repo/main.go": json.RawMessage(`[{"diffId":"diff1","bubbleId":"bubble2"}]`),
+		},
+	}
+
+	reconstructor := NewReconstructor(bubbleMap, nil)
+	reconstructor.SetFileEdits(map[string][]RawFileEdit{
+		"composer1": {
+			{FileEdit: FileEdit{FilePath: "/repo/new.go", Action: FileEditCreate}, CheckpointID: "cp1"},
+			{FileEdit: FileEdit{Action: FileEditAccept}, DiffID: "diff1"},
+			{FileEdit: FileEdit{FilePath: "/repo/other.go", Action: FileEditApply}, CheckpointID: "unknown"},
+			{FileEdit: FileEdit{Action: FileEditReject}, DiffID: "no-such-diff"},
+		},
+	})
+
+	conv, err := reconstructor.ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+
+	want := []FileEdit{
+		{FilePath: "/repo/new.go", Action: FileEditCreate},
+		{FilePath: "/repo/main.go", Action: FileEditAccept},
+	}
+	if !reflect.DeepEqual(conv.Messages[1].FileEdits, want) {
+		t.Errorf("assistant message file edits = %+v, want %+v", conv.Messages[1].FileEdits, want)
+	}
+	if len(conv.Messages[0].FileEdits) != 0 {
+		t.Errorf("user message has %d file edits, want 0", len(conv.Messages[0].FileEdits))
+	}
+	// Decisions on a diff with no known file are dropped; other unmatched edits are kept
+	if len(conv.FileEdits) != 1 || conv.FileEdits[0].FilePath != "/repo/other.go" {
+		t.Errorf("conversation file edits = %+v, want the unmatched other.go edit", conv.FileEdits)
+	}
+}
+
+func TestReconstructor_ForkedConversation(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	for i, b := range []struct{ id, text string }{
+		{"u1", "Write a parser"}, {"a1", "Here is a parser"},
+		{"u2", "Make it faster"}, {"a2", "Faster parser"},
+		{"u2-edit", "Make it streaming"}, {"a2-edit", "Streaming parser"},
+	} {
+		bubble := CreateTestRawBubble(b.id, "chat1", b.text, 1+i%2)
+		bubble.Timestamp = int64(1000 + i)
+		bubbleMap.Set(b.id, bubble)
+	}
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		Name:       "Parser",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "u1", Type: 1}, {BubbleID: "a1", Type: 2},
+			{BubbleID: "u2", Type: 1}, {BubbleID: "a2", Type: 2},
+			{BubbleID: "u2-edit", Type: 1, ParentBubbleID: "a1"}, {BubbleID: "a2-edit", Type: 2},
+		},
+		CodeBlockData: map[string]json.RawMessage{
+			"file:///repo/parser.go": json.RawMessage(`[{"diffId":"diff1","bubbleId":"a2"}]`),
+		},
+	}
+	reconstructor := NewReconstructor(bubbleMap, nil)
+	reconstructor.SetCodeDiffs(map[string][]CodeDiff{"composer1": {{DiffID: "diff1"}}})
+
+	conv, err := reconstructor.ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+
+	var texts []string
+	for _, msg := range conv.Messages {
+		texts = append(texts, msg.Text)
+	}
+	if want := []string{"Write a parser", "Here is a parser", "Make it streaming", "Streaming parser"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("active branch = %q, want %q", texts, want)
+	}
+	if len(conv.Diffs) != 1 {
+		t.Errorf("diff of the alternate branch should stay unmatched on the active one, got %+v", conv.Diffs)
+	}
+
+	if len(conv.Branches) != 1 {
+		t.Fatalf("ReconstructConversation() returned %d branches, want 1", len(conv.Branches))
+	}
+	branch := conv.Branches[0]
+	if branch.ComposerID != "composer1.branch_1" || branch.Name != "Parser" {
+		t.Errorf("branch = %s %q, want composer1.branch_1 named Parser", branch.ComposerID, branch.Name)
+	}
+	if want := (Branch{SessionID: "composer1", Index: 1, Count: 1, ForkMessage: 3}); branch.Branch == nil || *branch.Branch != want {
+		t.Errorf("branch.Branch = %+v, want %+v", branch.Branch, want)
+	}
+	if len(branch.Messages) != 4 || branch.Messages[2].Text != "Make it faster" {
+		t.Errorf("branch messages = %+v, want the original second turn", branch.Messages)
+	}
+	if len(branch.Messages[3].Diffs) != 1 || len(branch.Diffs) != 0 {
+		t.Errorf("branch diffs = %+v and %+v, want diff1 on its last message", branch.Messages[3].Diffs, branch.Diffs)
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(branch, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	if session.ID != "composer1.branch_1" || session.Metadata.Branch == nil || session.Metadata.Branch.SessionID != "composer1" {
+		t.Errorf("normalized branch = %s with %+v", session.ID, session.Metadata.Branch)
+	}
+}
+
+func TestReconstructAllConversations_RecordsEmptySessions(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("b1", CreateTestRawBubble("b1", "c1", "Hello", 1))
+
+	withMessages := CreateTestRawComposer("c1", "Has messages")
+	withMessages.FullConversationHeadersOnly = []ConversationHeader{{BubbleID: "b1", Type: 1}}
+	missingBubbles := CreateTestRawComposer("c2", "Missing bubbles")
+	missingBubbles.FullConversationHeadersOnly = []ConversationHeader{{BubbleID: "gone", Type: 1}}
+	unused := CreateTestRawComposer("c3", "Never used")
+
+	reconstructor := NewReconstructor(bubbleMap, nil)
+	if _, err := reconstructor.ReconstructAllConversations([]*RawComposer{withMessages, missingBubbles, unused}); err != nil {
+		t.Fatalf("ReconstructAllConversations() error = %v", err)
+	}
+
+	// The unused composer has no headers and is not counted
+	stats := GetParseStats()
+	if stats.Sessions != 2 || stats.EmptySessions != 1 {
+		t.Errorf("GetParseStats() = %+v, want 2 sessions with 1 empty", stats)
+	}
+}
+
+func TestReconstructor_CountsExtractionTiers(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	bubbleMap.Set("bubble1", CreateTestRawBubble("bubble1", "chat1", "Hello", 1))
+	rich := CreateTestRawBubble("bubble2", "chat1", "", 2)
+	rich.RichText = `{"root":{"children":[{"type":"text","text":"Rich reply"}]}}`
+	bubbleMap.Set("bubble2", rich)
+	bubbleMap.Set("bubble3", CreateTestRawBubble("bubble3", "chat1", "", 2))
+
+	composer := &RawComposer{
+		ComposerID: "composer1",
+		FullConversationHeadersOnly: []ConversationHeader{
+			{BubbleID: "bubble1", Type: 1},
+			{BubbleID: "bubble2", Type: 2},
+			{BubbleID: "bubble3", Type: 2},
+		},
+	}
+	conv, err := NewReconstructor(bubbleMap, nil).ReconstructConversation(composer)
+	if err != nil {
+		t.Fatalf("ReconstructConversation() error = %v", err)
+	}
+	want := ExtractionStats{Primary: 1, RichText: 1, Placeholder: 1}
+	if conv.Extraction != want {
+		t.Errorf("Extraction = %+v, want %+v", conv.Extraction, want)
+	}
+	// The placeholder bubble is counted but not kept as a message
+	if len(conv.Messages) != 2 {
+		t.Errorf("got %d messages, want 2", len(conv.Messages))
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(conv, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	if session.Metadata.Extraction == nil || *session.Metadata.Extraction != want {
+		t.Errorf("Metadata.Extraction = %+v, want %+v", session.Metadata.Extraction, want)
+	}
+}
+
+func TestCreateComposersFromBubbles_Deterministic(t *testing.T) {
+	bubbleMap := NewBubbleMap()
+	for _, b := range []*RawBubble{
+		{BubbleID: "b3", ChatID: "chat-b", Type: 2, Text: "Third"},
+		{BubbleID: "b1", ChatID: "chat-b", Type: 1, Text: "First"},
+		{BubbleID: "b2", ChatID: "chat-a", Type: 1, Text: "Other chat"},
+		{BubbleID: "x2", Type: 2, Text: "Answer"},
+		{BubbleID: "x1", Type: 1, Text: "Question"},
+	} {
+		bubbleMap.Set(b.BubbleID, b)
+	}
+
+	first := createComposersFromBubbles(bubbleMap)
+	for i := 0; i < 10; i++ {
+		if again := createComposersFromBubbles(bubbleMap); !reflect.DeepEqual(again, first) {
+			t.Fatalf("createComposersFromBubbles() = %+v, then %+v", first, again)
+		}
+	}
+
+	var ids []string
+	for _, composer := range first {
+		ids = append(ids, composer.ComposerID)
+		if composer.CreatedAt != 0 {
+			t.Errorf("composer %s CreatedAt = %d, want 0 for bubbles without timestamps", composer.ComposerID, composer.CreatedAt)
+		}
+	}
+	contentID := ContentSessionID([]*RawBubble{{BubbleID: "x1", Type: 1, Text: "Question"}, {BubbleID: "x2", Type: 2, Text: "Answer"}})
+	if want := []string{"chat-a", "chat-b", contentID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("composer IDs = %v, want %v", ids, want)
+	}
+	if headers := first[1].FullConversationHeadersOnly; headers[0].BubbleID != "b1" || headers[1].BubbleID != "b3" {
+		t.Errorf("headers = %+v, want the bubbles ordered by ID", headers)
+	}
+	if other := ContentSessionID([]*RawBubble{{BubbleID: "x1", Type: 1, Text: "Changed"}}); other == contentID {
+		t.Error("ContentSessionID() should change with the content")
+	}
+}
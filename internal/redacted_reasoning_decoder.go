@@ -56,12 +56,7 @@ func decodeRedactedReasoning(encoded string) (string, bool) {
 			}
 			// Log for debugging (only first few fields to avoid spam)
 			if len(fields) <= 5 {
-				valueStr := fmt.Sprintf("%v", value)
-				previewLen := 100
-				if len(valueStr) < previewLen {
-					previewLen = len(valueStr)
-				}
-				LogDebug("Protobuf field %s: type=%T, value_preview=%v", key, value, valueStr[:previewLen])
+				LogDebug("Protobuf field %s: type=%T, value_preview=%s", key, value, logPreview(fmt.Sprintf("%v", value), 100))
 			}
 		}
 		if len(textParts) > 0 {
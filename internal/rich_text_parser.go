@@ -78,6 +78,11 @@ func ExtractTextFromRichText(richTextJSON string) (string, error) {
 		}
 	}
 
+	// Rich text holding nothing but thinking has no text, which is not a parse failure
+	if ExtractThinkingFromRichText(richTextJSON) != "" {
+		return "", nil
+	}
+
 	// If all structured parsing fails, return error
 	return "", fmt.Errorf("failed to parse richText JSON in any known format")
 }
@@ -94,7 +99,10 @@ func extractTextFromChildrenInterface(children []interface{}) string {
 		childType, _ := childMap["type"].(string)
 		childText, _ := childMap["text"].(string)
 
-		if childType == "text" && childText != "" {
+		if childType == "thinking" {
+			// Thinking is kept apart from the text, see ExtractThinkingFromRichText
+			continue
+		} else if childType == "text" && childText != "" {
 			text += childText
 		} else if childType == "code" {
 			// For code blocks, add markdown code fences
@@ -146,6 +154,11 @@ func extractTextFromChildrenInterface(children []interface{}) string {
 func extractTextFromNode(node RichTextNode) string {
 	var text string
 
+	// Thinking is kept apart from the text, see ExtractThinkingFromRichText
+	if node.Type == "thinking" {
+		return ""
+	}
+
 	// Handle different node types
 	switch node.Type {
 	case "text":
@@ -158,11 +171,11 @@ func extractTextFromNode(node RichTextNode) string {
 		if codeText != "" {
 			text += "\n```\n" + codeText + "\n```\n"
 		}
-	case "thinking", "tool", "tool_call", "function_call":
-		// Extract thinking/tool call content
-		thinkingText := extractTextFromChildren(node.Children)
-		if thinkingText != "" {
-			text += fmt.Sprintf("\n[%s]\n%s\n", node.Type, thinkingText)
+	case "tool", "tool_call", "function_call":
+		// Extract tool call content
+		toolText := extractTextFromChildren(node.Children)
+		if toolText != "" {
+			text += fmt.Sprintf("\n[%s]\n%s\n", node.Type, toolText)
 		}
 	case "redacted_reasoning", "redacted-reasoning":
 		// Extract redacted reasoning and format in code block
@@ -230,3 +243,48 @@ func extractTextFromChildren(children []RichTextNode) string {
 	}
 	return text
 }
+
+// ExtractThinkingFromRichText returns the text of the thinking nodes of richText JSON,
+// which ExtractTextFromRichText leaves out, separated by blank lines
+func ExtractThinkingFromRichText(richTextJSON string) string {
+	if richTextJSON == "" {
+		return ""
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(richTextJSON), &data); err != nil {
+		return ""
+	}
+	var parts []string
+	collectThinking(data, &parts)
+	return strings.Join(parts, "\n\n")
+}
+
+// collectThinking appends the text of the thinking nodes found under value to parts
+func collectThinking(value interface{}, parts *[]string) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectThinking(item, parts)
+		}
+	case map[string]interface{}:
+		if nodeType, _ := v["type"].(string); nodeType == "thinking" {
+			var text string
+			if children, ok := v["children"].([]interface{}); ok {
+				text = extractTextFromChildrenInterface(children)
+			}
+			if text == "" {
+				text, _ = v["text"].(string)
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				*parts = append(*parts, text)
+			}
+			return
+		}
+		if root, ok := v["root"]; ok {
+			collectThinking(root, parts)
+		}
+		if children, ok := v["children"]; ok {
+			collectThinking(children, parts)
+		}
+	}
+}
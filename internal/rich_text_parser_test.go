@@ -149,6 +149,31 @@ func TestExtractTextFromRichText_ArrayOfNodes(t *testing.T) {
 	// Note: The current implementation may not handle array format directly
 }
 
+func TestExtractThinkingFromRichText(t *testing.T) {
+	input := `{"root":{"children":[
+		{"type":"thinking","children":[{"type":"text","text":"Check the tests"}]},
+		{"type":"paragraph","children":[{"type":"text","text":"Done"},{"type":"thinking","text":"twice"}]}
+	]}}`
+
+	if got := ExtractThinkingFromRichText(input); got != "Check the tests\n\ntwice" {
+		t.Errorf("ExtractThinkingFromRichText() = %q", got)
+	}
+	text, err := ExtractTextFromRichText(input)
+	if err != nil || text != "Done" {
+		t.Errorf("ExtractTextFromRichText() = %q, %v, want the text without the thinking", text, err)
+	}
+
+	// Rich text holding only thinking has no text, without failing to parse
+	text, err = ExtractTextFromRichText(`{"root":{"children":[{"type":"thinking","children":[{"type":"text","text":"Hmm"}]}]}}`)
+	if err != nil || text != "" {
+		t.Errorf("ExtractTextFromRichText() of thinking only = %q, %v, want no text", text, err)
+	}
+
+	if got := ExtractThinkingFromRichText(`{invalid`); got != "" {
+		t.Errorf("ExtractThinkingFromRichText() of invalid JSON = %q", got)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && strings.Contains(s, substr)
 }
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// RedactSample masks a value read from a database so its shape can be shared without its
+// content. JSON, including hex-encoded JSON, keeps its keys, numbers, booleans and nulls
+// with every string replaced by its length; other values are replaced by their size.
+func RedactSample(value string) string {
+	if value == "" {
+		return value
+	}
+	var data interface{}
+	if json.Unmarshal([]byte(value), &data) == nil {
+		return marshalRedacted(data)
+	}
+	if decoded, err := hex.DecodeString(value); err == nil && json.Unmarshal(decoded, &data) == nil {
+		return "hex:" + marshalRedacted(data)
+	}
+	return fmt.Sprintf("<redacted %d bytes>", len(value))
+}
+
+// marshalRedacted encodes data with its strings replaced by their length
+func marshalRedacted(data interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(data)); err != nil {
+		return "<redacted>"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactValue replaces the strings in a decoded JSON value by their length
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = redactValue(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+		return v
+	case string:
+		return fmt.Sprintf("<%d chars>", utf8.RuneCountInString(v))
+	default:
+		return v
+	}
+}
+
+// SanitizeHome replaces the user's home directory in text with ~, so paths and logs can be
+// shared without the user name
+func SanitizeHome(text string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || len(home) <= 1 {
+		return text
+	}
+	return strings.ReplaceAll(text, strings.TrimRight(home, `/\`), "~")
+}
+
+// redactLogs is set while previews of stored content in logs are masked, see SetRedactLogs
+var redactLogs atomic.Bool
+
+// SetRedactLogs turns on or off the masking, with RedactSample, of the stored content
+// that diagnostics preview, such as blob values and message text
+func SetRedactLogs(redact bool) {
+	redactLogs.Store(redact)
+}
+
+// logPreview shortens stored content to n bytes for a log message, masking it when
+// SetRedactLogs is on
+func logPreview(value string, n int) string {
+	if redactLogs.Load() {
+		value = RedactSample(value)
+	}
+	if len(value) > n {
+		return value[:n] + "..."
+	}
+	return value
+}
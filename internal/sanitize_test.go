@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedactSample(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"json", `{"text":"my secret","type":1,"done":true,"tags":["a","bc"]}`, `{"done":true,"tags":["<1 chars>","<2 chars>"],"text":"<9 chars>","type":1}`},
+		{"hex json", hex.EncodeToString([]byte(`{"name":"Fix it"}`)), `hex:{"name":"<6 chars>"}`},
+		{"text", "plain text", "<redacted 10 bytes>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactSample(tt.value); got != tt.want {
+				t.Errorf("RedactSample(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	path := filepath.Join(home, ".cursor", "chats")
+	if got, want := SanitizeHome("reading "+path), "reading "+filepath.Join("~", ".cursor", "chats"); got != want {
+		t.Errorf("SanitizeHome() = %q, want %q", got, want)
+	}
+	if got := SanitizeHome("/var/data"); got != "/var/data" {
+		t.Errorf("SanitizeHome() changed a path outside the home directory: %q", got)
+	}
+}
+
+func TestLogPreview(t *testing.T) {
+	defer SetRedactLogs(false)
+
+	if got := logPreview("hello world", 5); got != "hello..." {
+		t.Errorf("logPreview() = %q, want the value cut to 5 bytes", got)
+	}
+	SetRedactLogs(true)
+	if got := logPreview(`{"text":"hello world"}`, 100); got != `{"text":"<11 chars>"}` {
+		t.Errorf("logPreview() with redaction = %q, want the value masked", got)
+	}
+}
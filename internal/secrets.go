@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultEntropyThreshold is the Shannon entropy, in bits per character, above which the
+// high-entropy detector reports a token
+const DefaultEntropyThreshold = 4.5
+
+// entropyMinLength is the shortest token the high-entropy detector considers
+const entropyMinLength = 20
+
+// SecretMatch is the position of a detected secret within a text
+type SecretMatch struct {
+	Start, End int
+}
+
+// SecretDetector finds one kind of secret in text
+type SecretDetector interface {
+	Name() string
+	Find(text string) []SecretMatch
+}
+
+// regexpDetector reports every match of a regular expression
+type regexpDetector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+func (d *regexpDetector) Name() string {
+	return d.name
+}
+
+func (d *regexpDetector) Find(text string) []SecretMatch {
+	var matches []SecretMatch
+	for _, loc := range d.re.FindAllStringIndex(text, -1) {
+		matches = append(matches, SecretMatch{Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
+
+// entropyDetector reports long tokens that look random, such as API keys without a
+// recognizable prefix
+type entropyDetector struct {
+	threshold float64
+}
+
+// entropyToken matches the characters keys and tokens are usually made of
+var entropyToken = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{20,}`)
+
+func (d *entropyDetector) Name() string {
+	return "high-entropy"
+}
+
+func (d *entropyDetector) Find(text string) []SecretMatch {
+	var matches []SecretMatch
+	for _, loc := range entropyToken.FindAllStringIndex(text, -1) {
+		token := text[loc[0]:loc[1]]
+		if len(token) < entropyMinLength || !strings.ContainsAny(token, "0123456789") ||
+			strings.ToLower(token) == token || strings.ToUpper(token) == token {
+			continue
+		}
+		if shannonEntropy(token) >= d.threshold {
+			matches = append(matches, SecretMatch{Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// patternDetectors are the detectors for secrets with a recognizable format, in the order
+// they are applied
+var patternDetectors = []*regexpDetector{
+	{name: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-key", re: regexp.MustCompile(`(?i)aws.{0,20}(?:secret|key).{0,20}?['"=:\s]+[A-Za-z0-9/+]{40}\b`)},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+}
+
+// SecretDetectorNames lists the available detectors
+func SecretDetectorNames() []string {
+	names := make([]string, 0, len(patternDetectors)+1)
+	for _, d := range patternDetectors {
+		names = append(names, d.name)
+	}
+	return append(names, "high-entropy")
+}
+
+// NewSecretDetectors returns the named detectors, or every detector when names is empty.
+// entropyThreshold configures the high-entropy detector.
+func NewSecretDetectors(names []string, entropyThreshold float64) ([]SecretDetector, error) {
+	if len(names) == 0 {
+		names = SecretDetectorNames()
+	}
+
+	var detectors []SecretDetector
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "high-entropy" {
+			detectors = append(detectors, &entropyDetector{threshold: entropyThreshold})
+			continue
+		}
+		found := false
+		for _, d := range patternDetectors {
+			if d.name == name {
+				detectors = append(detectors, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown secret detector %q (available: %s)", name, strings.Join(SecretDetectorNames(), ", "))
+		}
+	}
+	return detectors, nil
+}
+
+// SecretFinding is a potential secret found in a session
+type SecretFinding struct {
+	SessionID    string `json:"session_id"`
+	SessionName  string `json:"session_name,omitempty"`
+	MessageIndex int    `json:"message_index"` // 1-based position of the message in the session
+	Actor        string `json:"actor"`
+	Line         int    `json:"line"` // 1-based line within the message
+	Detector     string `json:"detector"`
+	Redacted     string `json:"redacted"` // The match with most of it masked
+}
+
+// ScanSession runs detectors over every message of a session. Where several detectors
+// match the same text, only the first one reports it.
+func ScanSession(session *Session, detectors []SecretDetector) []SecretFinding {
+	var findings []SecretFinding
+	for i, msg := range session.Messages {
+		// Lines are counted in the message as an inline --thinking export writes it
+		content := InlineThinking(msg.Thinking, msg.Content)
+		var seen []SecretMatch
+		var found []SecretFinding
+		for _, detector := range detectors {
+			for _, match := range detector.Find(content) {
+				if overlaps(seen, match) {
+					continue
+				}
+				seen = append(seen, match)
+				found = append(found, SecretFinding{
+					SessionID:    session.ID,
+					SessionName:  session.Metadata.Name,
+					MessageIndex: i + 1,
+					Actor:        msg.Actor,
+					Line:         strings.Count(content[:match.Start], "\n") + 1,
+					Detector:     detector.Name(),
+					Redacted:     RedactSecret(content[match.Start:match.End]),
+				})
+			}
+		}
+		sort.SliceStable(found, func(a, b int) bool { return found[a].Line < found[b].Line })
+		findings = append(findings, found...)
+	}
+	return findings
+}
+
+// overlaps reports whether match overlaps any of matches
+func overlaps(matches []SecretMatch, match SecretMatch) bool {
+	for _, m := range matches {
+		if match.Start < m.End && m.Start < match.End {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecret masks a secret for display, keeping only enough to recognize it
+func RedactSecret(secret string) string {
+	if strings.HasPrefix(secret, "-----BEGIN") {
+		return secret
+	}
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8) + secret[len(secret)-2:]
+}
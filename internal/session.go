@@ -2,18 +2,53 @@ package internal
 
 // Session represents a normalized chat session
 type Session struct {
-	ID        string    `json:"id"`
-	Workspace string    `json:"workspace,omitempty"`
-	Source    string    `json:"source"` // "globalStorage"
-	Messages  []Message `json:"messages"`
-	Metadata  Metadata  `json:"metadata,omitempty"`
+	ID string `json:"id"`
+	// Type is SessionTypeInline for a prompt sent outside the chat panel, such as a Cmd-K
+	// edit, and empty for a chat
+	Type      string `json:"type,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+	// WorkspaceHash is the chats/{hash} directory a cursor-agent session was read from,
+	// which is the same for every session started in one workspace
+	WorkspaceHash string     `json:"workspace_hash,omitempty"`
+	Source        string     `json:"source"` // "globalStorage", "agentStorage", or several joined with "+"
+	Messages      []Message  `json:"messages"`
+	Metadata      Metadata   `json:"metadata,omitempty"`
+	Diffs         []CodeDiff `json:"diffs,omitempty"` // Edits that could not be matched to a message
 }
 
 // Message represents a normalized message
 type Message struct {
-	Timestamp string `json:"timestamp,omitempty"`
-	Actor     string `json:"actor"` // "user", "assistant", "tool"
-	Content   string `json:"content"`
+	Timestamp  string      `json:"timestamp,omitempty"`
+	Actor      string      `json:"actor"` // "user", "assistant", "tool", "terminal" or "system"
+	Content    string      `json:"content"`
+	Thinking   string      `json:"thinking,omitempty"` // Reasoning recorded before the content, kept apart from it
+	Provenance *Provenance `json:"provenance,omitempty"`
+	Diffs      []CodeDiff  `json:"diffs,omitempty"`      // Edits applied by this message
+	FileEdits  []FileEdit  `json:"file_edits,omitempty"` // Files this message edited, or whose edits it decided
+	Oversize   *Oversize   `json:"oversize,omitempty"`   // Set when the content was cut down by a MessageLimit
+	// ResponseMs is the time the assistant took to send this message after the user
+	// message it answers, when both have timestamps
+	ResponseMs int64 `json:"response_ms,omitempty"`
+	// Attachments are the images attached to the message, such as screenshots
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Environment is the state of the workspace Cursor recorded when the message was sent
+	Environment *Environment `json:"environment,omitempty"`
+}
+
+// Environment is what Cursor attached to a message about the workspace around it: the
+// repository's git status and the files open in terminals
+type Environment struct {
+	Git           *GitInfo `json:"git,omitempty"`
+	TerminalFiles []string `json:"terminal_files,omitempty"`
+}
+
+// Provenance records where a message came from in the raw storage, so an
+// exported line can be traced back to the row it was reconstructed from
+type Provenance struct {
+	SourcePath string `json:"source_path,omitempty"`
+	BlobKey    string `json:"blob_key,omitempty"`
+	Backend    string `json:"backend,omitempty"`  // "globalStorage", "agentStorage"
+	Strategy   string `json:"strategy,omitempty"` // "text", "richText", "codeBlocks", "text$uuid" (joined with "+")
 }
 
 // Metadata contains additional session information
@@ -24,4 +59,26 @@ type Metadata struct {
 	MessageCount int    `json:"message_count"`
 	ComposerID   string `json:"composer_id,omitempty"`
 	Name         string `json:"name,omitempty"`
+	// GeneratedName is set when Name was derived from the conversation rather than set in Cursor
+	GeneratedName bool     `json:"generated_name,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	// Duplicates lists the IDs of sessions dropped as copies of this one, or as parts of it
+	Duplicates []string `json:"duplicates,omitempty"`
+	// ParentID is the session this one was resumed from, when the storage records it
+	ParentID string `json:"parent_id,omitempty"`
+	// Git is the state of the repository at the session's latest message, when Cursor recorded it
+	Git *GitInfo `json:"git,omitempty"`
+	// Chunk is set on the parts of a session split by a SessionSplit
+	Chunk *Chunk `json:"chunk,omitempty"`
+	// Branch is set on an alternate branch of a forked conversation
+	Branch *Branch `json:"branch,omitempty"`
+	// Extraction counts the session's bubbles by the tier their text was extracted from,
+	// when the session was reconstructed from Cursor's bubbles
+	Extraction *ExtractionStats `json:"extraction,omitempty"`
+	// FilesChanged lists the files the session's edits changed, sorted; files whose every
+	// edit was rejected are left out
+	FilesChanged []string `json:"files_changed,omitempty"`
+	// Fields are the composer fields the normalizer rules copy into the metadata, by the
+	// names the rules give them
+	Fields map[string]interface{} `json:"fields,omitempty"`
 }
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Groupings of list --group-by
+const (
+	SessionGroupDay       = "day"
+	SessionGroupWeek      = "week"
+	SessionGroupWorkspace = "workspace"
+)
+
+// SessionGroupings are the values of list --group-by
+var SessionGroupings = []string{SessionGroupDay, SessionGroupWeek, SessionGroupWorkspace}
+
+// SessionGroup is a run of index entries sharing a day, week or workspace, with the
+// subtotals shown under its header
+type SessionGroup struct {
+	Label    string
+	Entries  []SessionIndexEntry
+	Messages int
+}
+
+// GroupSessionIndex groups index entries by the day or week they were created on, in the
+// display time zone, or by workspace. Groups come in the order of their first entry, and
+// entries keep their order within each group, so a list sorted by creation time stays
+// sorted. Entries without a creation time, or without a workspace, are grouped last.
+// now is the time today and yesterday are relative to.
+func GroupSessionIndex(entries []SessionIndexEntry, grouping string, now time.Time) ([]SessionGroup, error) {
+	var keyOf func(entry SessionIndexEntry) (string, string)
+	switch grouping {
+	case SessionGroupDay:
+		keyOf = func(entry SessionIndexEntry) (string, string) { return dayGroup(entry.CreatedAt, now) }
+	case SessionGroupWeek:
+		keyOf = func(entry SessionIndexEntry) (string, string) { return weekGroup(entry.CreatedAt, now) }
+	case SessionGroupWorkspace:
+		keyOf = func(entry SessionIndexEntry) (string, string) {
+			if entry.Workspace == "" {
+				return "", "No workspace"
+			}
+			return entry.Workspace, filepath.Base(entry.Workspace)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported grouping %q (expected day, week or workspace)", grouping)
+	}
+
+	var groups []SessionGroup
+	positions := make(map[string]int)
+	for _, entry := range entries {
+		key, label := keyOf(entry)
+		i, ok := positions[key]
+		if !ok {
+			i = len(groups)
+			positions[key] = i
+			groups = append(groups, SessionGroup{Label: label})
+		}
+		groups[i].Entries = append(groups[i].Entries, entry)
+		groups[i].Messages += entry.MessageCount
+	}
+
+	// The group of entries without a key goes last
+	if i, ok := positions[""]; ok {
+		ungrouped := groups[i]
+		groups = append(groups[:i], groups[i+1:]...)
+		groups = append(groups, ungrouped)
+	}
+	return groups, nil
+}
+
+// dayGroup returns the key and label of the day a timestamp falls on, such as
+// "2024-06-03 (Monday)", or "Today, 2024-06-03" and "Yesterday, ..." relative to now
+func dayGroup(createdAt string, now time.Time) (string, string) {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return "", "Unknown date"
+	}
+	day := DisplayTime(t).Format(time.DateOnly)
+	today := DisplayTime(now)
+	switch day {
+	case today.Format(time.DateOnly):
+		return day, "Today, " + day
+	case today.AddDate(0, 0, -1).Format(time.DateOnly):
+		return day, "Yesterday, " + day
+	}
+	return day, fmt.Sprintf("%s (%s)", day, DisplayTime(t).Weekday())
+}
+
+// weekGroup returns the key and label of the week, starting on Monday, a timestamp falls
+// in, such as "Week of 2024-06-03", or "This week, ..." and "Last week, ..." relative to now
+func weekGroup(createdAt string, now time.Time) (string, string) {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return "", "Unknown date"
+	}
+	monday := weekStart(DisplayTime(t)).Format(time.DateOnly)
+	switch monday {
+	case weekStart(DisplayTime(now)).Format(time.DateOnly):
+		return monday, "This week, from " + monday
+	case weekStart(DisplayTime(now).AddDate(0, 0, -7)).Format(time.DateOnly):
+		return monday, "Last week, from " + monday
+	}
+	return monday, "Week of " + monday
+}
+
+// weekStart returns the Monday of the week t falls in
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupSessionIndex(t *testing.T) {
+	if err := ConfigureTimeDisplay("UTC", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ConfigureTimeDisplay("", "") }()
+
+	// A Wednesday
+	now := time.Date(2024, 6, 12, 18, 0, 0, 0, time.UTC)
+	entries := []SessionIndexEntry{
+		{ID: "undated", MessageCount: 4},
+		{ID: "old", CreatedAt: "2024-06-03T09:00:00Z", MessageCount: 1, Workspace: "/src/api"},
+		{ID: "yesterday", CreatedAt: "2024-06-11T09:00:00Z", MessageCount: 2},
+		{ID: "today", CreatedAt: "2024-06-12T09:00:00Z", MessageCount: 3, Workspace: "/src/api"},
+		{ID: "today-too", CreatedAt: "2024-06-12T10:00:00Z", MessageCount: 5, Workspace: "/src/web"},
+	}
+
+	tests := []struct {
+		grouping string
+		want     []string // label and IDs of each group
+	}{
+		{SessionGroupDay, []string{"2024-06-03 (Monday): old", "Yesterday, 2024-06-11: yesterday", "Today, 2024-06-12: today today-too", "Unknown date: undated"}},
+		{SessionGroupWeek, []string{"Last week, from 2024-06-03: old", "This week, from 2024-06-10: yesterday today today-too", "Unknown date: undated"}},
+		{SessionGroupWorkspace, []string{"api: old today", "web: today-too", "No workspace: undated yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grouping, func(t *testing.T) {
+			groups, err := GroupSessionIndex(entries, tt.grouping, now)
+			if err != nil {
+				t.Fatalf("GroupSessionIndex() error = %v", err)
+			}
+			var got []string
+			for _, group := range groups {
+				line := group.Label + ":"
+				messages := 0
+				for _, entry := range group.Entries {
+					line += " " + entry.ID
+					messages += entry.MessageCount
+				}
+				if group.Messages != messages {
+					t.Errorf("group %q has Messages = %d, want %d", group.Label, group.Messages, messages)
+				}
+				got = append(got, line)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GroupSessionIndex() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("group %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := GroupSessionIndex(entries, "month", now); err == nil {
+		t.Error("GroupSessionIndex() should reject an unknown grouping")
+	}
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// SessionInfo is the metadata of a session without its messages, as printed by the info
+// command
+type SessionInfo struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name,omitempty"`
+	Workspace       string           `json:"workspace,omitempty"`
+	CreatedAt       string           `json:"created_at,omitempty"`
+	UpdatedAt       string           `json:"updated_at,omitempty"`
+	MessageCount    int              `json:"message_count"`
+	EstimatedTokens int              `json:"estimated_tokens"`
+	ThinkingTokens  int              `json:"thinking_tokens,omitempty"` // Share of EstimatedTokens taken by thinking
+	Source          string           `json:"source,omitempty"`          // Backend the session was read from
+	Sources         []string         `json:"sources,omitempty"`         // Databases its messages were read from
+	GitBranch       string           `json:"git_branch,omitempty"`
+	Tags            []string         `json:"tags,omitempty"`
+	ParentID        string           `json:"parent_id,omitempty"`
+	Extraction      *ExtractionStats `json:"extraction,omitempty"`
+}
+
+// NewSessionInfo sums up a session's metadata
+func NewSessionInfo(session *Session) SessionInfo {
+	info := SessionInfo{
+		ID:           session.ID,
+		Name:         session.Metadata.Name,
+		Workspace:    session.Workspace,
+		CreatedAt:    UTCTimestamp(session.Metadata.CreatedAt),
+		UpdatedAt:    UTCTimestamp(session.Metadata.UpdatedAt),
+		MessageCount: len(session.Messages),
+		Source:       session.Source,
+		GitBranch:    session.Metadata.Git.GetBranch(),
+		Tags:         session.Metadata.Tags,
+		ParentID:     session.Metadata.ParentID,
+		Extraction:   session.Metadata.Extraction,
+	}
+
+	info.ThinkingTokens = ThinkingTokens(session)
+	info.EstimatedTokens = SessionTokens(session)
+	sources := make(map[string]bool)
+	for _, msg := range session.Messages {
+		if msg.Provenance != nil && msg.Provenance.SourcePath != "" {
+			sources[msg.Provenance.SourcePath] = true
+		}
+	}
+	for source := range sources {
+		info.Sources = append(info.Sources, source)
+	}
+	sort.Strings(info.Sources)
+	return info
+}
+
+// SessionTokens estimates the tokens of a session's messages, thinking included
+func SessionTokens(session *Session) int {
+	tokens := ThinkingTokens(session)
+	for _, msg := range session.Messages {
+		tokens += EstimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// EstimateTokens approximates the number of model tokens in text at four characters per token
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
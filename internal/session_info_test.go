@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSessionInfo(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "user", Content: "12345678", Provenance: &Provenance{SourcePath: "/b/state.vscdb"}},
+		{Actor: "assistant", Content: "1234", Provenance: &Provenance{SourcePath: "/a/state.vscdb"}},
+		{Actor: "assistant", Content: "12", Provenance: &Provenance{SourcePath: "/b/state.vscdb"}},
+	})
+	session.Metadata.Name = "Fix the build"
+	session.Metadata.CreatedAt = "2025-03-01T12:00:00+02:00"
+	session.Metadata.Tags = []string{"ci"}
+	session.Metadata.Git = &GitInfo{Branch: "main"}
+	session.Metadata.Extraction = &ExtractionStats{Primary: 3}
+
+	info := NewSessionInfo(session)
+	if info.ID != "s1" || info.Name != "Fix the build" || info.MessageCount != 3 {
+		t.Errorf("NewSessionInfo() = %+v", info)
+	}
+	if info.CreatedAt != "2025-03-01T10:00:00Z" {
+		t.Errorf("CreatedAt = %q, want it in UTC", info.CreatedAt)
+	}
+	if info.EstimatedTokens != 4 {
+		t.Errorf("EstimatedTokens = %d, want 4", info.EstimatedTokens)
+	}
+	if want := []string{"/a/state.vscdb", "/b/state.vscdb"}; !reflect.DeepEqual(info.Sources, want) {
+		t.Errorf("Sources = %v, want %v", info.Sources, want)
+	}
+	if info.GitBranch != "main" || info.Extraction == nil || info.Extraction.Primary != 3 {
+		t.Errorf("NewSessionInfo() = %+v, want the git branch and extraction counts", info)
+	}
+}
+
+func TestNewSessionInfo_Thinking(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "user", Content: "12345678"},
+		{Actor: "assistant", Content: "1234", Thinking: "123456789012"},
+	})
+
+	info := NewSessionInfo(session)
+	if info.EstimatedTokens != 6 || info.ThinkingTokens != 3 {
+		t.Errorf("EstimatedTokens, ThinkingTokens = %d, %d, want 6, 3", info.EstimatedTokens, info.ThinkingTokens)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2, "héllo wörld": 3} {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SessionMarks holds the sessions users pinned or archived, by session ID. Like tags, the
+// marks are kept in their own file so they survive cache rebuilds and --clear-cache.
+// Pinned sessions are listed first and must be kept by anything removing sessions;
+// archived sessions are left out of listings unless asked for.
+type SessionMarks struct {
+	Pinned   []string `yaml:"pinned,omitempty"`
+	Archived []string `yaml:"archived,omitempty"`
+
+	path string
+}
+
+// LoadSessionMarks loads the session marks from path. A missing file yields no marks.
+func LoadSessionMarks(path string) (*SessionMarks, error) {
+	marks := &SessionMarks{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return marks, nil
+		}
+		return nil, fmt.Errorf("failed to read session marks: %w", err)
+	}
+	if err := yaml.Unmarshal(data, marks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session marks: %w", err)
+	}
+	return marks, nil
+}
+
+// IsPinned reports whether a session is pinned. A nil store has no marks.
+func (m *SessionMarks) IsPinned(sessionID string) bool {
+	return m != nil && slices.Contains(m.Pinned, sessionID)
+}
+
+// IsArchived reports whether a session is archived. A nil store has no marks.
+func (m *SessionMarks) IsArchived(sessionID string) bool {
+	return m != nil && slices.Contains(m.Archived, sessionID)
+}
+
+// SetPinned pins or unpins a session, reporting whether that changed anything
+func (m *SessionMarks) SetPinned(sessionID string, pinned bool) bool {
+	return setMark(&m.Pinned, sessionID, pinned)
+}
+
+// SetArchived archives or restores a session, reporting whether that changed anything
+func (m *SessionMarks) SetArchived(sessionID string, archived bool) bool {
+	return setMark(&m.Archived, sessionID, archived)
+}
+
+// setMark adds sessionID to or removes it from the sorted list of IDs ids
+func setMark(ids *[]string, sessionID string, set bool) bool {
+	i, found := slices.BinarySearch(*ids, sessionID)
+	switch {
+	case set && !found:
+		*ids = slices.Insert(*ids, i, sessionID)
+	case !set && found:
+		*ids = slices.Delete(*ids, i, i+1)
+	default:
+		return false
+	}
+	return true
+}
+
+// Apply sets the Pinned and Archived fields of index entries from the stored marks,
+// leaving out archived entries unless includeArchived is set, and moves the pinned
+// entries to the top, each group keeping its order
+func (m *SessionMarks) Apply(entries []SessionIndexEntry, includeArchived bool) []SessionIndexEntry {
+	marked := make([]SessionIndexEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Pinned = m.IsPinned(entry.ID)
+		entry.Archived = m.IsArchived(entry.ID)
+		if entry.Archived && !includeArchived {
+			continue
+		}
+		marked = append(marked, entry)
+	}
+	sort.SliceStable(marked, func(i, j int) bool { return marked[i].Pinned && !marked[j].Pinned })
+	return marked
+}
+
+// Save writes the session marks back to their file
+func (m *SessionMarks) Save() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session marks: %w", err)
+	}
+	return os.WriteFile(m.path, data, 0644)
+}
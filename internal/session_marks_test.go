@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSessionMarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "marks.yaml")
+	marks, err := LoadSessionMarks(path)
+	if err != nil {
+		t.Fatalf("LoadSessionMarks() error = %v", err)
+	}
+	if marks.IsPinned("a") || marks.IsArchived("a") {
+		t.Error("a missing file should hold no marks")
+	}
+
+	if !marks.SetPinned("c", true) || !marks.SetPinned("a", true) || marks.SetPinned("a", true) {
+		t.Error("SetPinned() should report only the sessions it pinned")
+	}
+	if !marks.SetArchived("b", true) || marks.SetArchived("d", false) {
+		t.Error("SetArchived() should report only the sessions it changed")
+	}
+	if err := marks.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadSessionMarks(path)
+	if err != nil {
+		t.Fatalf("LoadSessionMarks() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Pinned, []string{"a", "c"}) || !reflect.DeepEqual(loaded.Archived, []string{"b"}) {
+		t.Errorf("loaded marks = %+v", loaded)
+	}
+	if !loaded.SetPinned("a", false) || loaded.IsPinned("a") {
+		t.Error("SetPinned(false) should unpin the session")
+	}
+
+	var nilMarks *SessionMarks
+	if nilMarks.IsPinned("a") || nilMarks.IsArchived("b") {
+		t.Error("a nil store should hold no marks")
+	}
+}
+
+func TestSessionMarks_Apply(t *testing.T) {
+	marks := &SessionMarks{Pinned: []string{"c", "e"}, Archived: []string{"b", "e"}}
+	entries := []SessionIndexEntry{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+
+	ids := func(entries []SessionIndexEntry) []string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+	listed := marks.Apply(entries, false)
+	if got := ids(listed); !reflect.DeepEqual(got, []string{"c", "a", "d"}) {
+		t.Errorf("Apply() = %v, want the pinned session first and the archived ones left out", got)
+	}
+	if !listed[0].Pinned || listed[1].Pinned {
+		t.Errorf("Apply() should mark pinned entries, got %+v", listed)
+	}
+
+	listed = marks.Apply(entries, true)
+	if got := ids(listed); !reflect.DeepEqual(got, []string{"c", "e", "a", "b", "d"}) {
+		t.Errorf("Apply() with archived sessions = %v", got)
+	}
+	if !listed[1].Archived || !listed[3].Archived || listed[0].Archived {
+		t.Errorf("Apply() should mark archived entries, got %+v", listed)
+	}
+	if entries[2].Pinned {
+		t.Error("Apply() changed the entries it was given")
+	}
+}
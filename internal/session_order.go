@@ -0,0 +1,19 @@
+package internal
+
+import "sort"
+
+// SortSessions orders sessions by creation time and then ID, so that exports of the same
+// data list and write sessions in the same order. Sessions without a creation time come
+// first; nil sessions are moved to the end.
+func SortSessions(sessions []*Session) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		if ca, cb := parseTimestamp(a.Metadata.CreatedAt), parseTimestamp(b.Metadata.CreatedAt); ca != cb {
+			return ca < cb
+		}
+		return a.ID < b.ID
+	})
+}
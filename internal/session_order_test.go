@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortSessions(t *testing.T) {
+	session := func(id, createdAt string) *Session {
+		return &Session{ID: id, Metadata: Metadata{CreatedAt: createdAt}}
+	}
+	sessions := []*Session{
+		session("c", "2025-03-02T10:00:00Z"),
+		nil,
+		session("b", "2025-03-01T12:00:00+02:00"),
+		session("a", "2025-03-02T10:00:00Z"),
+		session("d", ""),
+	}
+
+	SortSessions(sessions)
+	var ids []string
+	for _, s := range sessions {
+		if s == nil {
+			ids = append(ids, "<nil>")
+			continue
+		}
+		ids = append(ids, s.ID)
+	}
+	if want := []string{"d", "b", "a", "c", "<nil>"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("SortSessions() order = %v, want %v", ids, want)
+	}
+}
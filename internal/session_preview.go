@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"strings"
+)
+
+// MaxPreviewLength is the length, in characters, of the prompt previews kept in the cache
+// index
+const MaxPreviewLength = 100
+
+// FirstUserPrompt returns the first user message of a session with any text, on one line
+// and truncated at a word boundary to maxLength characters, or "" if there is none.
+// Unlike a generated title it keeps code and markup, so sessions asking about the same
+// thing can still be told apart.
+func FirstUserPrompt(messages []Message, maxLength int) string {
+	placeholder, keep := normalizerRules.keepPlaceholder()
+	for _, msg := range messages {
+		if msg.Actor != ActorUser || (keep && msg.Content == placeholder) {
+			continue
+		}
+		text := msg.Content
+		if m := userQueryPattern.FindStringSubmatch(text); m != nil {
+			text = m[1]
+		}
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			return truncateTitle(text, maxLength)
+		}
+	}
+	return ""
+}
+
+// LastResponseAt returns the timestamp of the last assistant message of a session that
+// has one, or "" if there is none
+func LastResponseAt(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Actor == ActorAssistant && messages[i].Timestamp != "" {
+			return messages[i].Timestamp
+		}
+	}
+	return ""
+}
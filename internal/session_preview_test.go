@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirstUserPrompt(t *testing.T) {
+	messages := []Message{
+		{Actor: ActorSystem, Content: "You are an agent"},
+		{Actor: ActorUser, Content: "  \n "},
+		{Actor: ActorUser, Content: "<user_query>\nFix the `login` form:\n\n```go\nfunc submit() {}\n```\n</user_query>"},
+		{Actor: ActorUser, Content: "Later prompt"},
+	}
+	if got := FirstUserPrompt(messages, MaxPreviewLength); got != "Fix the `login` form: ```go func submit() {} ```" {
+		t.Errorf("FirstUserPrompt() = %q, want the first prompt with text on one line", got)
+	}
+	if got := FirstUserPrompt([]Message{{Actor: ActorUser, Content: strings.Repeat("long words ", 20)}}, 30); got != "long words long words long..." {
+		t.Errorf("FirstUserPrompt() = %q, want it truncated at a word boundary", got)
+	}
+	if got := FirstUserPrompt([]Message{{Actor: ActorAssistant, Content: "Hi"}}, MaxPreviewLength); got != "" {
+		t.Errorf("FirstUserPrompt() without user messages = %q", got)
+	}
+
+	// Messages kept without text by the normalizer rules are no prompt
+	defer SetNormalizerRules(nil)
+	SetNormalizerRules(&NormalizerRules{Placeholders: PlaceholderRule{Keep: true}})
+	if got := FirstUserPrompt([]Message{{Actor: ActorUser, Content: placeholderText}, {Actor: ActorUser, Content: "Real"}}, MaxPreviewLength); got != "Real" {
+		t.Errorf("FirstUserPrompt() = %q, want the placeholder skipped", got)
+	}
+}
+
+func TestLastResponseAt(t *testing.T) {
+	messages := []Message{
+		{Actor: ActorUser, Content: "Hi", Timestamp: "2024-06-01T09:00:00Z"},
+		{Actor: ActorAssistant, Content: "Hello", Timestamp: "2024-06-01T09:01:00Z"},
+		{Actor: ActorAssistant, Content: "Untimed"},
+		{Actor: ActorUser, Content: "Thanks", Timestamp: "2024-06-01T09:05:00Z"},
+	}
+	if got := LastResponseAt(messages); got != "2024-06-01T09:01:00Z" {
+		t.Errorf("LastResponseAt() = %q, want the last assistant message with a timestamp", got)
+	}
+	if got := LastResponseAt(messages[:1]); got != "" {
+		t.Errorf("LastResponseAt() without responses = %q", got)
+	}
+
+	entry := NewSessionIndexEntry(CreateTestSessionWithMessages("s1", messages))
+	if entry.Preview != "Hi" || entry.LastResponseAt != "2024-06-01T09:01:00Z" {
+		t.Errorf("NewSessionIndexEntry() = %+v, want the preview and last response", entry)
+	}
+}
+
+func TestNewSessionIndexEntry_Stats(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: ActorUser, Content: "List the files", Timestamp: "2024-06-01T09:00:00Z"},
+		{Actor: ActorAssistant, Content: "[Tool Call]\nTool: list_dir\n\n[Tool Call]\nTool: read_file", Thinking: "Look around", Timestamp: "2024-06-01T09:00:30Z"},
+		{Actor: ActorAssistant, Content: "Done", Timestamp: "2024-06-01T09:02:00Z"},
+	})
+	entry := NewSessionIndexEntry(session)
+
+	wantBytes := 0
+	for _, msg := range session.Messages {
+		wantBytes += len(msg.Content) + len(msg.Thinking)
+	}
+	if entry.Bytes != wantBytes || entry.EstimatedTokens != NewSessionInfo(session).EstimatedTokens {
+		t.Errorf("Bytes, EstimatedTokens = %d, %d, want %d and the info command's estimate", entry.Bytes, entry.EstimatedTokens, wantBytes)
+	}
+	if entry.DurationSeconds != 120 || entry.ToolCalls != 2 {
+		t.Errorf("DurationSeconds, ToolCalls = %d, %d, want 120 and 2", entry.DurationSeconds, entry.ToolCalls)
+	}
+	if entry.Backend != "" || entry.SourcePath != "" {
+		t.Errorf("Backend, SourcePath = %q, %q for messages without provenance, want none", entry.Backend, entry.SourcePath)
+	}
+
+	session.Messages[1].Provenance = &Provenance{SourcePath: "/chats/abc/s1/store.db", Backend: BackendAgentStorage}
+	entry = NewSessionIndexEntry(session)
+	if entry.Backend != BackendAgentStorage || entry.SourcePath != "/chats/abc/s1/store.db" {
+		t.Errorf("Backend, SourcePath = %q, %q, want those of the first message read from storage", entry.Backend, entry.SourcePath)
+	}
+}
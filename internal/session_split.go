@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Chunk links a part of a split session back to the session it came from
+type Chunk struct {
+	SessionID    string `json:"session_id"`    // ID of the session that was split
+	Index        int    `json:"index"`         // Position of this chunk, from 1
+	Count        int    `json:"count"`         // Number of chunks the session was split into
+	FirstMessage int    `json:"first_message"` // Number of the chunk's first message in the session, from 1
+}
+
+// SessionSplit splits long sessions into chunks for training data with a bounded context.
+// A turn is a user message and the replies that follow it. The zero value leaves every
+// session whole.
+type SessionSplit struct {
+	Turns     int  // Largest number of turns in a chunk; 0 means no limit
+	OnNewTask bool // Also start a chunk at user messages that open a new task
+}
+
+// newTaskPattern matches user messages that move on to an unrelated task, such as
+// "New task: ..." or "Moving on, ..."
+var newTaskPattern = regexp.MustCompile(`(?i)^\W*(new task|next task|new topic|different (task|topic|question)|unrelated( question)?|moving on|let'?s move on|switching (to|gears))\b`)
+
+// NewSessionSplit builds a split from the --split-turns and --split-on-task flag values
+func NewSessionSplit(turns int, onNewTask bool) (SessionSplit, error) {
+	if turns < 0 {
+		return SessionSplit{}, fmt.Errorf("invalid --split-turns %d (expected 0 or more)", turns)
+	}
+	return SessionSplit{Turns: turns, OnNewTask: onNewTask}, nil
+}
+
+// IsZero reports whether the split leaves every session whole
+func (s SessionSplit) IsZero() bool {
+	return s.Turns == 0 && !s.OnNewTask
+}
+
+// ChunkID returns the ID of a session's chunk, numbered from 1
+func ChunkID(sessionID string, index int) string {
+	return fmt.Sprintf("%s.chunk_%d", sessionID, index)
+}
+
+// IsNewTask reports whether a user message opens a task unrelated to the previous ones
+func IsNewTask(content string) bool {
+	return newTaskPattern.MatchString(content)
+}
+
+// Apply splits a session into chunks of at most Turns turns, also starting a new chunk at
+// user messages that open a new task with OnNewTask. Each chunk is a copy of the session
+// with its own ID (see ChunkID), its messages, and Metadata.Chunk pointing back at the
+// session; its creation and update times come from its first and last message timestamps
+// when they have them. Edits that could not be matched to a message stay with the first
+// chunk. A session that needs no split is returned as is.
+func (s SessionSplit) Apply(session *Session) []*Session {
+	if s.IsZero() {
+		return []*Session{session}
+	}
+
+	// Find the message each chunk starts at
+	starts := []int{0}
+	turns := 0
+	for i, msg := range session.Messages {
+		if msg.Actor != "user" {
+			continue
+		}
+		turns++
+		if turns == 1 {
+			continue // Replies before the first user message belong to its turn
+		}
+		if (s.Turns > 0 && turns > s.Turns) || (s.OnNewTask && IsNewTask(msg.Content)) {
+			starts = append(starts, i)
+			turns = 1
+		}
+	}
+	if len(starts) == 1 {
+		return []*Session{session}
+	}
+
+	chunks := make([]*Session, 0, len(starts))
+	for n, start := range starts {
+		end := len(session.Messages)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+
+		chunk := *session
+		chunk.ID = ChunkID(session.ID, n+1)
+		chunk.Messages = session.Messages[start:end:end]
+		if n > 0 {
+			chunk.Diffs = nil
+		}
+		chunk.Metadata.MessageCount = len(chunk.Messages)
+		chunk.Metadata.Chunk = &Chunk{
+			SessionID:    session.ID,
+			Index:        n + 1,
+			Count:        len(starts),
+			FirstMessage: start + 1,
+		}
+		if first, last, ok := messageTimeRange(chunk.Messages); ok {
+			chunk.Metadata.CreatedAt = first.Format(time.RFC3339)
+			chunk.Metadata.UpdatedAt = last.Format(time.RFC3339)
+		}
+		chunks = append(chunks, &chunk)
+	}
+	return chunks
+}
+
+// messageTimeRange returns the earliest and latest timestamps of the messages, and false
+// if none has one
+func messageTimeRange(messages []Message) (first, last time.Time, ok bool) {
+	for _, msg := range messages {
+		t, err := time.Parse(time.RFC3339, msg.Timestamp)
+		if err != nil {
+			continue
+		}
+		if !ok || t.Before(first) {
+			first = t
+		}
+		if !ok || t.After(last) {
+			last = t
+		}
+		ok = true
+	}
+	return first, last, ok
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestNewSessionSplit(t *testing.T) {
+	if split, err := NewSessionSplit(4, true); err != nil || split.Turns != 4 || !split.OnNewTask {
+		t.Errorf("NewSessionSplit(4, true) = %+v, %v", split, err)
+	}
+	if _, err := NewSessionSplit(-1, false); err == nil {
+		t.Error("NewSessionSplit(-1) succeeded, want an error")
+	}
+	if split, _ := NewSessionSplit(0, false); !split.IsZero() {
+		t.Error("NewSessionSplit(0, false) should leave sessions whole")
+	}
+}
+
+func TestIsNewTask(t *testing.T) {
+	tests := map[string]bool{
+		"New task: add a health endpoint":     true,
+		"next task - rename the package":      true,
+		"Moving on, can you fix the build?":   true,
+		"Unrelated question: what is GOPATH?": true,
+		"Switching gears to the frontend":     true,
+		"This is a new task for the parser":   false,
+		"Let's add the tests":                 false,
+		"unrelatedly named function":          false,
+	}
+	for content, want := range tests {
+		if got := IsNewTask(content); got != want {
+			t.Errorf("IsNewTask(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestSessionSplit_Apply(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "assistant", Content: "Welcome"},
+		{Actor: "user", Content: "Add a flag", Timestamp: "2024-01-01T10:00:00Z"},
+		{Actor: "assistant", Content: "Done", Timestamp: "2024-01-01T10:01:00Z"},
+		{Actor: "user", Content: "And its docs", Timestamp: "2024-01-01T10:02:00Z"},
+		{Actor: "assistant", Content: "Done", Timestamp: "2024-01-01T10:03:00Z"},
+		{Actor: "user", Content: "New task: fix CI", Timestamp: "2024-01-01T11:00:00Z"},
+		{Actor: "assistant", Content: "Fixed", Timestamp: "2024-01-01T11:05:00Z"},
+	})
+	session.Diffs = []CodeDiff{{FilePath: "main.go"}}
+
+	tests := []struct {
+		name       string
+		split      SessionSplit
+		wantSizes  []int
+		wantStarts []int
+	}{
+		{"zero", SessionSplit{}, []int{7}, nil},
+		{"fits", SessionSplit{Turns: 3}, []int{7}, nil},
+		{"turns", SessionSplit{Turns: 2}, []int{5, 2}, []int{1, 6}},
+		{"one turn", SessionSplit{Turns: 1}, []int{3, 2, 2}, []int{1, 4, 6}},
+		{"new task", SessionSplit{OnNewTask: true}, []int{5, 2}, []int{1, 6}},
+		{"both", SessionSplit{Turns: 1, OnNewTask: true}, []int{3, 2, 2}, []int{1, 4, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := tt.split.Apply(session)
+			if len(chunks) != len(tt.wantSizes) {
+				t.Fatalf("Apply() returned %d chunk(s), want %d", len(chunks), len(tt.wantSizes))
+			}
+			if tt.wantStarts == nil {
+				if chunks[0] != session {
+					t.Error("a session that needs no split should be returned as is")
+				}
+				return
+			}
+			for i, chunk := range chunks {
+				if len(chunk.Messages) != tt.wantSizes[i] || chunk.Metadata.MessageCount != tt.wantSizes[i] {
+					t.Errorf("chunk %d has %d message(s) (count %d), want %d", i+1, len(chunk.Messages), chunk.Metadata.MessageCount, tt.wantSizes[i])
+				}
+				want := Chunk{SessionID: "s1", Index: i + 1, Count: len(chunks), FirstMessage: tt.wantStarts[i]}
+				if chunk.Metadata.Chunk == nil || *chunk.Metadata.Chunk != want {
+					t.Errorf("chunk %d Metadata.Chunk = %+v, want %+v", i+1, chunk.Metadata.Chunk, want)
+				}
+				if chunk.ID != ChunkID("s1", i+1) {
+					t.Errorf("chunk %d ID = %s, want %s", i+1, chunk.ID, ChunkID("s1", i+1))
+				}
+				if (len(chunk.Diffs) > 0) != (i == 0) {
+					t.Errorf("chunk %d has %d unmatched diff(s); only the first chunk should keep them", i+1, len(chunk.Diffs))
+				}
+			}
+		})
+	}
+
+	chunks := SessionSplit{OnNewTask: true}.Apply(session)
+	if got := chunks[1].Metadata.CreatedAt + " " + chunks[1].Metadata.UpdatedAt; got != "2024-01-01T11:00:00Z 2024-01-01T11:05:00Z" {
+		t.Errorf("second chunk times = %s, want its message time range", got)
+	}
+	if session.ID != "s1" || len(session.Messages) != 7 || session.Metadata.Chunk != nil {
+		t.Error("Apply() should not modify the session")
+	}
+}
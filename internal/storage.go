@@ -3,9 +3,11 @@ package internal
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
 // StorageBackend is the interface for storage backends that can load session data
@@ -16,9 +18,32 @@ type StorageBackend interface {
 	LoadCodeBlockDiffs() (map[string][]interface{}, error)
 }
 
+// ComposerMetadataLoader is implemented by backends that can list composers without reading
+// message content, at the cost of leaving out what only the messages hold
+type ComposerMetadataLoader interface {
+	LoadComposerMetadata() ([]*RawComposer, error)
+}
+
+// LoadComposerMetadata lists the composers of a backend as cheaply as it allows. Backends
+// without a ComposerMetadataLoader load their composers as usual; for the desktop app
+// database that already skips the bubbles.
+func LoadComposerMetadata(backend StorageBackend) ([]*RawComposer, error) {
+	if loader, ok := backend.(ComposerMetadataLoader); ok {
+		return loader.LoadComposerMetadata()
+	}
+	return backend.LoadComposers()
+}
+
+// Backend names recorded in message provenance
+const (
+	BackendGlobalStorage = "globalStorage"
+	BackendAgentStorage  = "agentStorage"
+)
+
 // Storage provides methods to extract raw data from cursorDiskKV (desktop app format)
 type Storage struct {
-	db *sql.DB
+	db     *sql.DB
+	dbPath string // Path of the opened database, used for provenance
 }
 
 // Ensure Storage implements StorageBackend
@@ -31,69 +56,148 @@ func NewStorage(db *sql.DB) *Storage {
 
 // LoadBubbles loads all bubbles from the database
 func (s *Storage) LoadBubbles() (map[string]*RawBubble, error) {
-	pairs, err := QueryCursorDiskKV(s.db, "bubbleId:%")
+	pairs, err := QueryCursorDiskKV(s.db, s.dbPath, "bubbleId:%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query bubbles: %w", err)
 	}
 
 	bubbleMap := make(map[string]*RawBubble)
+	failed := 0
 	for _, pair := range pairs {
 		bubble, err := ParseRawBubble(pair.Key, pair.Value)
 		if err != nil {
-			// Log error but continue
+			// Count the failure for --strict and continue
+			failed++
+			s.recordParseFailure(pair.Key, err)
 			continue
 		}
+		bubble.Provenance = &Provenance{
+			SourcePath: s.dbPath,
+			BlobKey:    pair.Key,
+			Backend:    BackendGlobalStorage,
+		}
 		// Use bubbleId as key for lookup
 		bubbleMap[bubble.BubbleID] = bubble
 	}
+	RecordParsed(len(pairs), failed)
 
 	return bubbleMap, nil
 }
 
+// LoadBubble loads a single bubble of a composer by its key, without reading the others
+func (s *Storage) LoadBubble(composerID, bubbleID string) (*RawBubble, error) {
+	key := "bubbleId:" + composerID + ":" + bubbleID
+	value, found, err := QueryCursorDiskKVKey(s.db, s.dbPath, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bubble: %w", err)
+	}
+	if !found {
+		return nil, ErrBubbleNotFound
+	}
+	bubble, err := ParseRawBubble(key, value)
+	if err != nil {
+		RecordParsed(1, 1)
+		s.recordParseFailure(key, err)
+		return nil, err
+	}
+	RecordParsed(1, 0)
+	bubble.Provenance = &Provenance{
+		SourcePath: s.dbPath,
+		BlobKey:    key,
+		Backend:    BackendGlobalStorage,
+	}
+	return bubble, nil
+}
+
+// findBubble loads a bubble by its ID under whichever composer it is stored. It scans the
+// bubble keys, so it is meant for the few bubbles missed elsewhere.
+func (s *Storage) findBubble(bubbleID string) (*RawBubble, error) {
+	pairs, err := QueryCursorDiskKV(s.db, s.dbPath, "bubbleId:%:"+bubbleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bubble: %w", err)
+	}
+	for _, pair := range pairs {
+		if !strings.HasSuffix(pair.Key, ":"+bubbleID) {
+			continue
+		}
+		bubble, err := ParseRawBubble(pair.Key, pair.Value)
+		if err != nil {
+			RecordParsed(1, 1)
+			s.recordParseFailure(pair.Key, err)
+			return nil, err
+		}
+		RecordParsed(1, 0)
+		bubble.Provenance = &Provenance{
+			SourcePath: s.dbPath,
+			BlobKey:    pair.Key,
+			Backend:    BackendGlobalStorage,
+		}
+		return bubble, nil
+	}
+	return nil, ErrBubbleNotFound
+}
+
+// recordParseFailure records a bubble that could not be parsed, for the placeholders of
+// SetIncludeParseFailures
+func (s *Storage) recordParseFailure(key string, err error) {
+	RecordBubbleParseFailure(ParseFailure{
+		Key:        key,
+		Reason:     err.Error(),
+		SourcePath: s.dbPath,
+		Backend:    BackendGlobalStorage,
+	})
+}
+
 // LoadComposers loads all composers from the database
 func (s *Storage) LoadComposers() ([]*RawComposer, error) {
-	pairs, err := QueryCursorDiskKV(s.db, "composerData:%")
+	pairs, err := QueryCursorDiskKV(s.db, s.dbPath, "composerData:%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query composers: %w", err)
 	}
 
 	composers := make([]*RawComposer, 0)
+	failed := 0
 	for _, pair := range pairs {
 		composer, err := ParseRawComposer(pair.Key, pair.Value)
 		if err != nil {
-			// Log error but continue
+			// Count the failure for --strict and continue
+			failed++
 			continue
 		}
 		composers = append(composers, composer)
 	}
+	RecordParsed(len(pairs), failed)
 
 	return composers, nil
 }
 
 // LoadMessageContexts loads all message contexts from the database
 func (s *Storage) LoadMessageContexts() (map[string][]*MessageContext, error) {
-	pairs, err := QueryCursorDiskKV(s.db, "messageRequestContext:%")
+	pairs, err := QueryCursorDiskKV(s.db, s.dbPath, "messageRequestContext:%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query message contexts: %w", err)
 	}
 
 	contextMap := make(map[string][]*MessageContext)
+	failed := 0
 	for _, pair := range pairs {
 		context, err := ParseMessageContext(pair.Key, pair.Value)
 		if err != nil {
-			// Log error but continue
+			// Count the failure for --strict and continue
+			failed++
 			continue
 		}
 		// Group by composerId
 		contextMap[context.ComposerID] = append(contextMap[context.ComposerID], context)
 	}
+	RecordParsed(len(pairs), failed)
 
 	return contextMap, nil
 }
 
 // LoadCodeBlockDiffs loads all code block diffs from the database
 func (s *Storage) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
-	pairs, err := QueryCursorDiskKV(s.db, "codeBlockDiff:%")
+	pairs, err := QueryCursorDiskKV(s.db, s.dbPath, "codeBlockDiff:%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query code block diffs: %w", err)
 	}
@@ -107,11 +211,19 @@ func (s *Storage) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
 		}
 		chatId := parts[1]
 
+		value, _ := decodeTextValue(pair.Value)
 		var diff interface{}
-		if err := json.Unmarshal([]byte(pair.Value), &diff); err != nil {
+		if err := json.Unmarshal([]byte(value), &diff); err != nil {
 			continue
 		}
 
+		// Keep the diff ID from the key so the diff can be matched to the code block that applied it
+		if fields, ok := diff.(map[string]interface{}); ok && len(parts) > 2 {
+			if _, exists := fields["diffId"]; !exists {
+				fields["diffId"] = parts[2]
+			}
+		}
+
 		diffMap[chatId] = append(diffMap[chatId], diff)
 	}
 
@@ -121,17 +233,60 @@ func (s *Storage) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
 // AgentStorage provides methods to extract raw data from cursor-agent CLI store.db files
 type AgentStorage struct {
 	reader *AgentStorageReader
+
+	// globalStoragePath finds the desktop app database that bubbles missing from every
+	// store.db are looked up in, which is opened on the first miss
+	globalStoragePath func() string
+	globalStorageOnce sync.Once
+	globalStorage     *Storage
 }
 
 // NewAgentStorage creates a new AgentStorage instance
 func NewAgentStorage(storeDBPaths []string) *AgentStorage {
 	return &AgentStorage{
-		reader: NewAgentStorageReader(storeDBPaths),
+		reader:            NewAgentStorageReader(storeDBPaths),
+		globalStoragePath: desktopGlobalStorage,
 	}
 }
 
-// Ensure AgentStorage implements StorageBackend
-var _ StorageBackend = (*AgentStorage)(nil)
+// Ensure AgentStorage implements StorageBackend and BubbleFinder
+var (
+	_ StorageBackend = (*AgentStorage)(nil)
+	_ BubbleFinder   = (*AgentStorage)(nil)
+)
+
+// FindBubble looks a bubble that no store.db holds up in the desktop app's globalStorage
+// database, where the messages of a session resumed from the desktop app may live
+func (a *AgentStorage) FindBubble(bubbleID string) (*RawBubble, error) {
+	a.globalStorageOnce.Do(func() {
+		path := a.globalStoragePath()
+		if path == "" {
+			return
+		}
+		db, err := OpenDatabase(path)
+		if err != nil {
+			LogWarn("Failed to open %s to look up missing messages: %v", path, err)
+			return
+		}
+		LogDebug("Looking up messages missing from agent storage in %s", path)
+		a.globalStorage = NewStorage(db)
+		a.globalStorage.dbPath = path
+	})
+	if a.globalStorage == nil {
+		return nil, ErrBubbleNotFound
+	}
+	return a.globalStorage.findBubble(bubbleID)
+}
+
+// desktopGlobalStorage returns the desktop app's globalStorage database on this machine,
+// or "" when there is none
+func desktopGlobalStorage() string {
+	paths, err := DetectStoragePaths()
+	if err != nil || !paths.GlobalStorageExists() {
+		return ""
+	}
+	return paths.GetGlobalStorageDBPath()
+}
 
 // LoadBubbles loads all bubbles from agent storage
 func (a *AgentStorage) LoadBubbles() (map[string]*RawBubble, error) {
@@ -151,6 +306,12 @@ func (a *AgentStorage) LoadComposers() ([]*RawComposer, error) {
 	return composers, nil
 }
 
+// LoadComposerMetadata lists one composer per store.db from its meta table, without parsing
+// the blobs. Composers have names and creation times but no conversation headers.
+func (a *AgentStorage) LoadComposerMetadata() ([]*RawComposer, error) {
+	return a.reader.LoadAllSessionMetadata(), nil
+}
+
 // LoadMessageContexts loads all message contexts from agent storage
 func (a *AgentStorage) LoadMessageContexts() (map[string][]*MessageContext, error) {
 	_, _, contexts, err := a.reader.LoadAllSessionsFromAgentStorage()
@@ -169,9 +330,177 @@ func (a *AgentStorage) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
 	return make(map[string][]interface{}), nil
 }
 
+// MultiBackend merges the data of several storage backends into one
+type MultiBackend struct {
+	backends []StorageBackend
+}
+
+// Ensure MultiBackend implements StorageBackend
+var _ StorageBackend = (*MultiBackend)(nil)
+
+// NewMultiBackend creates a MultiBackend over the given backends. Nil backends are ignored.
+func NewMultiBackend(backends ...StorageBackend) *MultiBackend {
+	mb := &MultiBackend{}
+	for _, b := range backends {
+		if b != nil {
+			mb.backends = append(mb.backends, b)
+		}
+	}
+	return mb
+}
+
+// LoadBubbles loads bubbles from every backend; later backends win on duplicate IDs
+func (m *MultiBackend) LoadBubbles() (map[string]*RawBubble, error) {
+	all := make(map[string]*RawBubble)
+	for _, b := range m.backends {
+		bubbles, err := b.LoadBubbles()
+		if err != nil {
+			LogWarn("Failed to load bubbles from backend: %v", err)
+			continue
+		}
+		for id, bubble := range bubbles {
+			all[id] = bubble
+		}
+	}
+	return all, nil
+}
+
+// LoadComposers loads composers from every backend. When the same composer appears in
+// several backends, such as copies of one database taken at different times, the most
+// recently updated copy is kept.
+func (m *MultiBackend) LoadComposers() ([]*RawComposer, error) {
+	return m.mergeComposers(StorageBackend.LoadComposers)
+}
+
+// LoadComposerMetadata lists composers from every backend as cheaply as each allows,
+// merged like LoadComposers
+func (m *MultiBackend) LoadComposerMetadata() ([]*RawComposer, error) {
+	return m.mergeComposers(LoadComposerMetadata)
+}
+
+// mergeComposers loads composers from every backend with load, keeping the most recently
+// updated copy of a composer found in several backends
+func (m *MultiBackend) mergeComposers(load func(StorageBackend) ([]*RawComposer, error)) ([]*RawComposer, error) {
+	var all []*RawComposer
+	seen := make(map[string]int)
+	for _, b := range m.backends {
+		composers, err := load(b)
+		if err != nil {
+			LogWarn("Failed to load composers from backend: %v", err)
+			continue
+		}
+		for _, composer := range composers {
+			if i, ok := seen[composer.ComposerID]; ok {
+				if composer.LastUpdatedAt > all[i].LastUpdatedAt {
+					all[i] = composer
+				}
+				continue
+			}
+			seen[composer.ComposerID] = len(all)
+			all = append(all, composer)
+		}
+	}
+	return all, nil
+}
+
+// LoadMessageContexts loads message contexts from every backend
+func (m *MultiBackend) LoadMessageContexts() (map[string][]*MessageContext, error) {
+	all := make(map[string][]*MessageContext)
+	for _, b := range m.backends {
+		contexts, err := b.LoadMessageContexts()
+		if err != nil {
+			LogWarn("Failed to load contexts from backend: %v", err)
+			continue
+		}
+		for composerID, ctxList := range contexts {
+			all[composerID] = append(all[composerID], ctxList...)
+		}
+	}
+	return all, nil
+}
+
+// LoadCodeBlockDiffs loads code block diffs from every backend
+func (m *MultiBackend) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
+	all := make(map[string][]interface{})
+	for _, b := range m.backends {
+		diffs, err := b.LoadCodeBlockDiffs()
+		if err != nil {
+			LogWarn("Failed to load code block diffs from backend: %v", err)
+			continue
+		}
+		for chatID, diffList := range diffs {
+			all[chatID] = append(all[chatID], diffList...)
+		}
+	}
+	return all, nil
+}
+
+// FindBubble looks a bubble up in each backend that can find bubbles, in order
+func (m *MultiBackend) FindBubble(bubbleID string) (*RawBubble, error) {
+	for _, b := range m.backends {
+		finder, ok := b.(BubbleFinder)
+		if !ok {
+			continue
+		}
+		bubble, err := finder.FindBubble(bubbleID)
+		if err == nil {
+			return bubble, nil
+		}
+		if !errors.Is(err, ErrBubbleNotFound) {
+			LogDebug("Failed to look up bubble %s: %v", bubbleID, err)
+		}
+	}
+	return nil, ErrBubbleNotFound
+}
+
+// WorkspaceProvider is implemented by backends that already know each session's workspace,
+// such as exported archives, where it cannot be derived from workspaceStorage
+type WorkspaceProvider interface {
+	SessionWorkspace(composerID string) string
+}
+
+// BackendSessionWorkspace returns the workspace a backend recorded for a session, or ""
+func BackendSessionWorkspace(backend StorageBackend, composerID string) string {
+	if provider, ok := backend.(WorkspaceProvider); ok {
+		return provider.SessionWorkspace(composerID)
+	}
+	return ""
+}
+
+// NewStorageBackendForPaths creates a StorageBackend over several storage locations,
+// merging their sessions. A single location is opened with NewStorageBackend.
+func NewStorageBackendForPaths(list []StoragePaths) (StorageBackend, error) {
+	if len(list) == 1 {
+		return NewStorageBackend(list[0])
+	}
+
+	backends := make([]StorageBackend, 0, len(list))
+	for i, paths := range list {
+		backend, err := NewStorageBackend(paths)
+		if err != nil {
+			return nil, fmt.Errorf("storage location %d: %w", i+1, err)
+		}
+		backends = append(backends, backend)
+	}
+	LogInfo("Reading sessions from %d storage locations", len(backends))
+	return NewMultiBackend(backends...), nil
+}
+
 // NewStorageBackend creates a StorageBackend based on available storage formats
-// It prioritizes desktop app format (globalStorage) over agent storage
+// It prioritizes desktop app format (globalStorage) over agent storage; locations given
+// as a URI are opened by the providers registered with RegisterBackend
 func NewStorageBackend(paths StoragePaths) (StorageBackend, error) {
+	// A location with a scheme is opened by the backends registered for it
+	if paths.Location != "" {
+		return openRegisteredBackend(paths.Location)
+	}
+
+	// A directory of exported sessions replaces the databases entirely
+	if paths.ExportDir != "" {
+		LogInfo("Reading exported sessions from %s", paths.ExportDir)
+		return NewFileBackend(paths.ExportDir), nil
+	}
+
 	// First, try desktop app format (globalStorage)
 	if paths.GlobalStorageExists() {
 		dbPath := paths.GetGlobalStorageDBPath()
@@ -179,7 +508,9 @@ func NewStorageBackend(paths StoragePaths) (StorageBackend, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to open globalStorage database: %w", err)
 		}
-		return NewStorage(db), nil
+		storage := NewStorage(db)
+		storage.dbPath = dbPath
+		return storage, nil
 	}
 
 	// Fallback to agent storage if available
@@ -202,7 +533,6 @@ func NewStorageBackend(paths StoragePaths) (StorageBackend, error) {
 
 	// Neither format available - provide detailed error message
 	var errMsg strings.Builder
-	errMsg.WriteString("no Cursor storage found\n\n")
 	errMsg.WriteString("Checked storage locations:\n")
 	errMsg.WriteString(fmt.Sprintf("  • Desktop app: %s (not found)\n", paths.GetGlobalStorageDBPath()))
 
@@ -212,14 +542,14 @@ func NewStorageBackend(paths StoragePaths) (StorageBackend, error) {
 			errMsg.WriteString(fmt.Sprintf("    → Expected pattern: %s/{hash}/{session-id}/store.db\n", paths.AgentStoragePath))
 			errMsg.WriteString("    → Sessions are created when cursor-agent CLI runs with chat interactions\n")
 		} else {
-			errMsg.WriteString("  • Agent CLI: not available on this platform\n")
+			errMsg.WriteString("  • Agent CLI: no agent storage directory configured\n")
 		}
 	} else {
 		if paths.AgentStoragePath != "" {
 			errMsg.WriteString(fmt.Sprintf("  • Agent CLI: %s (directory not found)\n", paths.AgentStoragePath))
 			errMsg.WriteString("    → This directory is created when cursor-agent CLI is first used\n")
 		} else {
-			errMsg.WriteString("  • Agent CLI: not available on this platform\n")
+			errMsg.WriteString("  • Agent CLI: no agent storage directory configured\n")
 		}
 	}
 
@@ -231,15 +561,17 @@ func NewStorageBackend(paths StoragePaths) (StorageBackend, error) {
 		errMsg.WriteString("  • Sessions are created automatically when cursor-agent CLI runs.\n")
 		errMsg.WriteString("  • If you just ran cursor-agent commands, sessions should appear shortly.\n")
 		errMsg.WriteString("  • Check both locations: ~/.config/cursor/chats/ or ~/.cursor/chats/\n")
+		errMsg.WriteString("  • If cursor-agent runs with CURSOR_CONFIG_DIR, set it here too, or set CURSOR_SESSION_AGENT_DIR\n")
 		errMsg.WriteString("  • Each session directory should contain a store.db file.\n")
 	} else {
 		errMsg.WriteString("\n")
 		errMsg.WriteString("To use this tool, you need either:\n")
 		errMsg.WriteString("  • Cursor IDE desktop app with chat history, or\n")
-		errMsg.WriteString("  • cursor-agent CLI with active sessions in ~/.config/cursor/chats/ or ~/.cursor/chats/\n")
+		errMsg.WriteString("  • cursor-agent CLI with active sessions in ~/.cursor/chats/ (or ~/.config/cursor/chats/ on Linux)\n")
+		errMsg.WriteString("  • If cursor-agent runs with CURSOR_CONFIG_DIR, set it here too, or set CURSOR_SESSION_AGENT_DIR\n")
 	}
 
-	return nil, fmt.Errorf("%s", errMsg.String())
+	return nil, fmt.Errorf("%w\n\n%s", ErrNoStorage, errMsg.String())
 }
 
 // IsCIEnvironment checks if we're running in a CI/CD environment
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsStorageArchive reports whether path names a compressed or archived storage location,
+// such as a CI artifact: a gzipped file (store.db.gz), a tarball (.tar, .tar.gz, .tgz)
+// or a zip file
+func IsStorageArchive(path string) bool {
+	return storageArchiveKind(path) != ""
+}
+
+// storageArchiveKind returns how the archive at path is read, from its extension, or ""
+// when it is not an archive
+func storageArchiveKind(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".gz"):
+		return "gz"
+	default:
+		return ""
+	}
+}
+
+// ExtractStorageArchive extracts a storage archive into a temporary directory and returns
+// the storage location to read from it, along with a cleanup function removing the
+// directory. A gzipped file is decompressed under its name without .gz. The location is
+// the directory it was extracted to, or the directory the archive wraps all of its files
+// in, or a globalStorage directory in it, so that a tarball of the chats, globalStorage or
+// User directory is detected like the directory itself.
+func ExtractStorageArchive(path string) (string, func() error, error) {
+	kind := storageArchiveKind(path)
+	if kind == "" {
+		return "", nil, fmt.Errorf("not a storage archive: %s (expected .gz, .tar, .tar.gz, .tgz or .zip)", path)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "cursor-session-archive-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup := func() error {
+		return os.RemoveAll(tmpDir)
+	}
+
+	switch kind {
+	case "gz":
+		err = extractGzipFile(path, tmpDir)
+	case "tar", "tar.gz":
+		err = extractTarFile(path, tmpDir, kind == "tar.gz")
+	case "zip":
+		err = extractZipFile(path, tmpDir)
+	}
+	if err != nil {
+		_ = cleanup()
+		return "", nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+
+	root, err := archiveStorageRoot(tmpDir)
+	if err != nil {
+		_ = cleanup()
+		return "", nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+	LogInfo("Extracted %s to temporary location: %s", path, root)
+	return root, cleanup, nil
+}
+
+// archiveStorageRoot returns the storage location in an extracted archive: the directory
+// its files are all wrapped in, if any, or the globalStorage directory of a User directory
+func archiveStorageRoot(dir string) (string, error) {
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			return "", fmt.Errorf("archive is empty")
+		}
+		if len(entries) != 1 || !entries[0].IsDir() {
+			break
+		}
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "state.vscdb")); err != nil {
+		globalStorage := filepath.Join(dir, "globalStorage")
+		if _, err := os.Stat(filepath.Join(globalStorage, "state.vscdb")); err == nil {
+			return globalStorage, nil
+		}
+	}
+	return dir, nil
+}
+
+// extractGzipFile decompresses a gzipped file into dir
+func extractGzipFile(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = gzr.Close() }()
+
+	name := filepath.Base(path)
+	name = name[:len(name)-len(".gz")]
+	return writeArchiveFile(filepath.Join(dir, name), gzr)
+}
+
+// extractTarFile extracts the directories and regular files of a tarball into dir
+func extractTarFile(path, dir string, gzipped bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if gzipped {
+		gzr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gzr.Close() }()
+		r = gzr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archiveEntryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr); err != nil {
+				return err
+			}
+		default:
+			// Links and devices have no place in a copy of Cursor's storage
+			LogDebug("Skipping %s in %s: not a regular file", header.Name, path)
+		}
+	}
+}
+
+// extractZipFile extracts the directories and regular files of a zip file into dir
+func extractZipFile(path, dir string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zr.Close() }()
+
+	for _, file := range zr.File {
+		target, err := archiveEntryPath(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = writeArchiveFile(target, rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			LogDebug("Skipping %s in %s: not a regular file", file.Name, path)
+		}
+	}
+	return nil
+}
+
+// archiveEntryPath returns where an archive entry is extracted in dir, refusing entries
+// that would be written outside of it
+func archiveEntryPath(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry %q is outside of the archive", name)
+	}
+	return target, nil
+}
+
+// writeArchiveFile writes the content of an archive entry to path
+func writeArchiveFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
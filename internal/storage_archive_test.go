@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+// writeTestTarGz writes a gzipped tarball of files, by slash-separated name
+func writeTestTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gzw, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIsStorageArchive(t *testing.T) {
+	for path, want := range map[string]bool{
+		"store.db.gz":        true,
+		"chats.tar.gz":       true,
+		"chats.TGZ":          true,
+		"chats.tar":          true,
+		"artifact.zip":       true,
+		"store.db":           false,
+		"state.vscdb":        false,
+		"exports/gz":         false,
+		"session_abc.jsonl":  false,
+		"/tmp/archive.zip/x": false,
+	} {
+		if got := IsStorageArchive(path); got != want {
+			t.Errorf("IsStorageArchive(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestExtractStorageArchive_Gzip(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), "store.db.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(f)
+	_, _ = gzw.Write([]byte("database"))
+	_ = gzw.Close()
+	_ = f.Close()
+
+	dir, cleanup, err := ExtractStorageArchive(path)
+	if err != nil {
+		t.Fatalf("ExtractStorageArchive() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "store.db"))
+	if err != nil || string(data) != "database" {
+		t.Errorf("store.db = %q, %v, want the decompressed database", data, err)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup() left %s behind", dir)
+	}
+}
+
+func TestExtractStorageArchive_TarGz(t *testing.T) {
+	// A tarball of a User directory is read from its globalStorage directory
+	path := filepath.Join(testutil.CreateTempDir(t), "user.tar.gz")
+	writeTestTarGz(t, path, map[string]string{
+		"User/globalStorage/state.vscdb":         "database",
+		"User/workspaceStorage/abc/state.vscdb":  "workspace",
+		"User/workspaceStorage/abc/workspace.js": "{}",
+	})
+	dir, cleanup, err := ExtractStorageArchive(path)
+	if err != nil {
+		t.Fatalf("ExtractStorageArchive() error = %v", err)
+	}
+	defer func() { _ = cleanup() }()
+	if filepath.Base(dir) != "globalStorage" {
+		t.Errorf("ExtractStorageArchive() = %s, want the globalStorage directory", dir)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "workspaceStorage", "abc", "state.vscdb")); err != nil {
+		t.Errorf("workspace storage was not extracted: %v", err)
+	}
+
+	// A tarball of the chats directory is read from the directory its files are in
+	path = filepath.Join(testutil.CreateTempDir(t), "chats.tgz")
+	writeTestTarGz(t, path, map[string]string{
+		"chats/hash/one/store.db": "one",
+		"chats/hash/two/store.db": "two",
+	})
+	dir, cleanup2, err := ExtractStorageArchive(path)
+	if err != nil {
+		t.Fatalf("ExtractStorageArchive() error = %v", err)
+	}
+	defer func() { _ = cleanup2() }()
+	if filepath.Base(dir) != "hash" {
+		t.Errorf("ExtractStorageArchive() = %s, want the directory holding the sessions", dir)
+	}
+}
+
+func TestExtractStorageArchive_Zip(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), "artifact.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{"store.db": "database", "store.db-wal": "wal"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	_ = zw.Close()
+	_ = f.Close()
+
+	dir, cleanup, err := ExtractStorageArchive(path)
+	if err != nil {
+		t.Fatalf("ExtractStorageArchive() error = %v", err)
+	}
+	defer func() { _ = cleanup() }()
+	for _, name := range []string{"store.db", "store.db-wal"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not extracted: %v", name, err)
+		}
+	}
+}
+
+func TestExtractStorageArchive_Invalid(t *testing.T) {
+	dir := testutil.CreateTempDir(t)
+
+	escaping := filepath.Join(dir, "escaping.tar.gz")
+	writeTestTarGz(t, escaping, map[string]string{"../../evil": "x"})
+	if _, _, err := ExtractStorageArchive(escaping); err == nil || !strings.Contains(err.Error(), "outside of the archive") {
+		t.Errorf("ExtractStorageArchive() error = %v, want entries outside of the archive refused", err)
+	}
+
+	empty := filepath.Join(dir, "empty.tar.gz")
+	writeTestTarGz(t, empty, nil)
+	if _, _, err := ExtractStorageArchive(empty); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("ExtractStorageArchive() of an empty archive error = %v", err)
+	}
+
+	corrupt := filepath.Join(dir, "store.db.gz")
+	if err := os.WriteFile(corrupt, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ExtractStorageArchive(corrupt); err == nil || !strings.Contains(err.Error(), corrupt) {
+		t.Errorf("ExtractStorageArchive() of a corrupt file error = %v, want it named", err)
+	}
+
+	if _, _, err := ExtractStorageArchive(filepath.Join(dir, "store.db")); err == nil {
+		t.Error("ExtractStorageArchive() of a database should fail")
+	}
+	if _, err := GetStoragePaths(corrupt); err == nil || !strings.Contains(err.Error(), "extracted first") {
+		t.Errorf("GetStoragePaths() of an archive error = %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StreamSessions reconstructs and normalizes the sessions of a backend one at a time,
+// oldest first, passing each to fn as soon as it is ready, so a whole history is exported
+// without being held in memory. Backends that load single bubbles (BubbleLoader) read
+// only the bubbles of the session at hand, and cursor-agent storage reads one store.db at
+// a time, keeping memory bounded by the largest session. Other backends, such as several
+// storage locations combined, are loaded whole first, as ReconstructConversations does,
+// with a warning. Unlike NormalizeSessions, sessions duplicating part of another are not
+// dropped, since that takes every session at once. The first error fn returns stops the
+// stream.
+func StreamSessions(backend StorageBackend, paths []StoragePaths, workspaceOverride string, fn func(*Session) error) error {
+	workspaces := DetectStorageWorkspaces(paths)
+	var history *WorkspaceHistory
+	loadHistory := func() *WorkspaceHistory {
+		if history == nil {
+			history = LoadWorkspaceHistory(paths)
+		}
+		return history
+	}
+
+	normalizer := NewNormalizer()
+	emit := func(conv *ReconstructedConversation, contexts map[string][]*MessageContext) error {
+		backfillTimestamps([]*ReconstructedConversation{conv}, loadHistory)
+		workspace := conversationWorkspace(conv, backend, contexts, workspaces, workspaceOverride)
+		session, err := normalizer.NormalizeConversation(conv, workspace)
+		if err != nil {
+			LogWarn("Failed to normalize conversation %s: %v", conv.ComposerID, err)
+			return nil
+		}
+		return fn(session)
+	}
+
+	if agent, ok := backend.(*AgentStorage); ok {
+		return streamAgentSessions(agent, emit)
+	}
+
+	contexts, err := backend.LoadMessageContexts()
+	if err != nil {
+		LogWarn("Failed to load message contexts: %v", err)
+	}
+
+	loader, ok := backend.(BubbleLoader)
+	if !ok {
+		LogWarn("Storage cannot be read one session at a time; loading every session before streaming")
+		conversations, err := ReconstructConversations(backend)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(conversations, func(i, j int) bool {
+			return conversationBefore(conversations[i].CreatedAt, conversations[i].ComposerID, conversations[j].CreatedAt, conversations[j].ComposerID)
+		})
+		for i, conv := range conversations {
+			// Let each conversation go once its session is written
+			conversations[i] = nil
+			if err := emit(conv, contexts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	composers, err := backend.LoadComposers()
+	if err != nil {
+		return fmt.Errorf("failed to load composers: %w", err)
+	}
+	sort.SliceStable(composers, func(i, j int) bool {
+		return conversationBefore(composers[i].CreatedAt, composers[i].ComposerID, composers[j].CreatedAt, composers[j].ComposerID)
+	})
+	rawDiffs, err := backend.LoadCodeBlockDiffs()
+	if err != nil {
+		LogWarn("Failed to load code block diffs: %v", err)
+	}
+
+	reconstructor := NewReconstructorWithResolver(NewLazyBubbleResolver(loader), contexts)
+	reconstructor.SetCodeDiffs(ParseCodeDiffs(rawDiffs))
+	reconstructor.SetFileEdits(LoadFileEdits(backend))
+	for _, composer := range composers {
+		// Composers without headers are chats that were opened but never used
+		if composer == nil || len(composer.FullConversationHeadersOnly) == 0 {
+			continue
+		}
+		conv, err := reconstructor.ReconstructConversation(composer)
+		if err != nil {
+			LogWarn("Failed to reconstruct session %s: %v", composer.ComposerID, err)
+			continue
+		}
+		RecordSession(len(conv.Messages) == 0)
+		if len(conv.Messages) == 0 {
+			LogWarn("Composer %s produced 0 messages (had %d headers)", composer.ComposerID, len(composer.FullConversationHeadersOnly))
+			continue
+		}
+		if err := emit(conv, contexts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamAgentSessions reconstructs the sessions of cursor-agent storage one store.db at a
+// time, ordered by the creation time in their meta tables, passing each to emit
+func streamAgentSessions(agent *AgentStorage, emit func(*ReconstructedConversation, map[string][]*MessageContext) error) error {
+	type storeDB struct {
+		path      string
+		id        string
+		createdAt int64
+	}
+	storeDBs := make([]storeDB, 0, len(agent.reader.storeDBPaths))
+	for _, path := range agent.reader.storeDBPaths {
+		db := storeDB{path: path, id: extractSessionIDFromPath(path)}
+		// Only the meta table is read here; failures are reported when the file is loaded
+		if meta, err := LoadSessionMetadataFromStoreDB(path); err == nil {
+			db.createdAt = meta.CreatedAt
+		}
+		storeDBs = append(storeDBs, db)
+	}
+	sort.SliceStable(storeDBs, func(i, j int) bool {
+		return conversationBefore(storeDBs[i].createdAt, storeDBs[i].id, storeDBs[j].createdAt, storeDBs[j].id)
+	})
+
+	for _, db := range storeDBs {
+		bubbles, composers, contexts, loadWarnings, err := LoadSessionFromStoreDB(db.path)
+		if !recordStoreDBLoad(db.path, loadWarnings, err) {
+			continue
+		}
+		bubbleMap := NewBubbleMap()
+		for id, bubble := range bubbles {
+			bubbleMap.Set(id, bubble)
+		}
+		if len(composers) == 0 && bubbleMap.Len() > 0 {
+			composers = createComposersFromBubbles(bubbleMap)
+		}
+		sort.SliceStable(composers, func(i, j int) bool {
+			return conversationBefore(composers[i].CreatedAt, composers[i].ComposerID, composers[j].CreatedAt, composers[j].ComposerID)
+		})
+
+		reconstructor := NewReconstructor(bubbleMap, contexts)
+		reconstructor.SetBubbleFinder(agent)
+		conversations, err := reconstructor.ReconstructAllConversations(composers)
+		if err != nil {
+			LogWarn("Failed to reconstruct sessions of %s: %v", db.path, err)
+			continue
+		}
+		for _, conv := range conversations {
+			if err := emit(conv, contexts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conversationBefore orders conversations as SortSessions orders their sessions: by
+// creation time, those without one first, and then by ID
+func conversationBefore(createdA int64, idA string, createdB int64, idB string) bool {
+	if createdA != createdB {
+		return createdA < createdB
+	}
+	return idA < idB
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestStreamSessions(t *testing.T) {
+	db := twoSessionDB(t)
+	defer func() { _ = db.Close() }()
+	storage := NewStorage(db)
+
+	// Storage loads the bubbles of one session at a time; the multi backend is read whole
+	for name, backend := range map[string]StorageBackend{"lazy": storage, "whole": NewMultiBackend(storage)} {
+		t.Run(name, func(t *testing.T) {
+			var ids []string
+			err := StreamSessions(backend, nil, "/code/app", func(session *Session) error {
+				ids = append(ids, session.ID)
+				if len(session.Messages) != 2 || session.Messages[1].Content != "answer from "+session.ID {
+					t.Errorf("session %s messages = %+v", session.ID, session.Messages)
+				}
+				if session.Workspace != "/code/app" {
+					t.Errorf("session %s workspace = %q, want the override", session.ID, session.Workspace)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamSessions() error = %v", err)
+			}
+			if len(ids) != 2 || ids[0] != "alpha" || ids[1] != "beta" {
+				t.Errorf("streamed %v, want alpha then beta", ids)
+			}
+		})
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := StreamSessions(storage, nil, "", func(*Session) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("StreamSessions() = %v after %d call(s), want the callback's error after the first", err, calls)
+	}
+}
+
+func TestStreamSessions_AgentStorage(t *testing.T) {
+	const messages = 6
+	result, err := testutil.GenerateFixture(t.TempDir(), testutil.FixtureOptions{Sessions: 3, Messages: messages, Backend: testutil.FixtureAgent, Seed: 5})
+	if err != nil {
+		t.Fatalf("GenerateFixture() error = %v", err)
+	}
+	storeDBs, err := filepath.Glob(filepath.Join(result.AgentStorage, "*", "*", "store.db"))
+	if err != nil || len(storeDBs) != 3 {
+		t.Fatalf("generated store.db files = %v, %v", storeDBs, err)
+	}
+
+	var created []string
+	err = StreamSessions(NewAgentStorage(storeDBs), nil, "", func(session *Session) error {
+		created = append(created, session.Metadata.CreatedAt)
+		if len(session.Messages) != messages {
+			t.Errorf("session %s has %d message(s), want %d", session.ID, len(session.Messages), messages)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamSessions() error = %v", err)
+	}
+	if len(created) != 3 {
+		t.Fatalf("streamed %d session(s), want 3", len(created))
+	}
+	for i := 1; i < len(created); i++ {
+		if created[i] < created[i-1] {
+			t.Errorf("streamed sessions created %v, want oldest first", created)
+		}
+	}
+}
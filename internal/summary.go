@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SummaryFormats are the export formats a list of session summaries can be written in
+var SummaryFormats = []string{"jsonl", "json", "yaml"}
+
+// SessionSummary is a compact record of a session for dashboards, in place of its transcript
+type SessionSummary struct {
+	ID              string         `json:"id" yaml:"id"`
+	Name            string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Workspace       string         `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	CreatedAt       string         `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt       string         `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	FirstPrompt     string         `json:"first_prompt,omitempty" yaml:"first_prompt,omitempty"`
+	FinalAnswer     string         `json:"final_answer,omitempty" yaml:"final_answer,omitempty"`
+	MessageCount    int            `json:"message_count" yaml:"message_count"`
+	DurationSeconds int64          `json:"duration_seconds" yaml:"duration_seconds"`
+	FilesTouched    []string       `json:"files_touched,omitempty" yaml:"files_touched,omitempty"`
+	ToolUsage       map[string]int `json:"tool_usage,omitempty" yaml:"tool_usage,omitempty"`
+}
+
+// toolCallPattern matches the tool calls rendered into message text, capturing the tool name
+var toolCallPattern = regexp.MustCompile(`\[Tool Call\](?:\nTool: ([^\n]+))?`)
+
+// SummarizeSession builds the summary of a session. The duration spans the first and last
+// message timestamps, or the session's creation and update times when messages have none.
+// Files touched are those edited by the session's diffs and those its git status reported
+// as changed.
+func SummarizeSession(session *Session) *SessionSummary {
+	summary := &SessionSummary{
+		ID:           session.ID,
+		Name:         session.Metadata.Name,
+		Workspace:    session.Workspace,
+		CreatedAt:    session.Metadata.CreatedAt,
+		UpdatedAt:    session.Metadata.UpdatedAt,
+		MessageCount: len(session.Messages),
+	}
+
+	files := make(map[string]bool)
+	addDiffs := func(diffs []CodeDiff) {
+		for _, diff := range diffs {
+			if diff.FilePath != "" {
+				files[diff.FilePath] = true
+			}
+		}
+	}
+	addDiffs(session.Diffs)
+	if session.Metadata.Git != nil {
+		for _, file := range session.Metadata.Git.DirtyFiles {
+			files[file] = true
+		}
+	}
+
+	for _, msg := range session.Messages {
+		if summary.FirstPrompt == "" && msg.Actor == "user" {
+			summary.FirstPrompt = msg.Content
+		}
+		if msg.Actor == "assistant" {
+			summary.FinalAnswer = msg.Content
+		}
+		addDiffs(msg.Diffs)
+
+		for _, match := range toolCallPattern.FindAllStringSubmatch(msg.Content, -1) {
+			name := match[1]
+			if name == "" {
+				name = "unknown"
+			}
+			if summary.ToolUsage == nil {
+				summary.ToolUsage = make(map[string]int)
+			}
+			summary.ToolUsage[name]++
+		}
+	}
+	summary.DurationSeconds = int64(SessionDuration(session).Seconds())
+
+	for file := range files {
+		summary.FilesTouched = append(summary.FilesTouched, file)
+	}
+	sort.Strings(summary.FilesTouched)
+	return summary
+}
+
+// SessionDuration returns the time between the first and last message timestamps of a
+// session, or between its creation and update times when messages have none
+func SessionDuration(session *Session) time.Duration {
+	var first, last time.Time
+	for _, msg := range session.Messages {
+		if t, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+			if t.After(last) {
+				last = t
+			}
+		}
+	}
+	if first.IsZero() {
+		first, _ = time.Parse(time.RFC3339, session.Metadata.CreatedAt)
+		last, _ = time.Parse(time.RFC3339, session.Metadata.UpdatedAt)
+	}
+	if first.IsZero() || !last.After(first) {
+		return 0
+	}
+	return last.Sub(first)
+}
+
+// CountToolCalls returns the number of tool calls rendered into a session's messages
+func CountToolCalls(session *Session) int {
+	calls := 0
+	for _, msg := range session.Messages {
+		calls += len(toolCallPattern.FindAllStringIndex(msg.Content, -1))
+	}
+	return calls
+}
+
+// SummaryFileName returns the name of the file session summaries are written to
+func SummaryFileName(format string) string {
+	return "summaries." + format
+}
+
+// MarshalSummaries encodes summaries as one JSON object per line (jsonl), a JSON array
+// (json) or a YAML list (yaml)
+func MarshalSummaries(summaries []*SessionSummary, format string) ([]byte, error) {
+	if summaries == nil {
+		summaries = []*SessionSummary{}
+	}
+	switch format {
+	case "jsonl":
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, summary := range summaries {
+			if err := encoder.Encode(summary); err != nil {
+				return nil, fmt.Errorf("failed to encode summary of %s: %w", summary.ID, err)
+			}
+		}
+		return buf.Bytes(), nil
+	case "json":
+		data, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode summaries: %w", err)
+		}
+		return append(data, '\n'), nil
+	case "yaml":
+		data, err := yaml.Marshal(summaries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode summaries: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported summary format: %s (expected jsonl, json or yaml)", format)
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func summaryTestSession() *Session {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "user", Content: "Fix the failing test", Timestamp: "2025-01-01T10:00:00Z"},
+		{Actor: "assistant", Content: "[Tool Call]\nTool: read_file\nID: call1", Timestamp: "2025-01-01T10:01:00Z",
+			Diffs: []CodeDiff{{FilePath: "pkg/b.go"}}},
+		{Actor: "tool", Content: "[Tool Response]\nTool: read_file\nContent: ok"},
+		{Actor: "assistant", Content: "[Tool Call]\nTool: read_file\n\n[Tool Call]\nTool: run_terminal_cmd\n\n[Tool Call]"},
+		{Actor: "user", Content: "Thanks"},
+		{Actor: "assistant", Content: "The test passes now.", Timestamp: "2025-01-01T10:05:30Z"},
+	})
+	session.Metadata.Name = "Fix test"
+	session.Diffs = []CodeDiff{{FilePath: "pkg/a.go"}}
+	session.Metadata.Git = &GitInfo{Branch: "main", DirtyFiles: []string{"pkg/b.go", "go.mod"}}
+	return session
+}
+
+func TestSummarizeSession(t *testing.T) {
+	summary := SummarizeSession(summaryTestSession())
+
+	if summary.FirstPrompt != "Fix the failing test" {
+		t.Errorf("FirstPrompt = %q", summary.FirstPrompt)
+	}
+	if summary.FinalAnswer != "The test passes now." {
+		t.Errorf("FinalAnswer = %q", summary.FinalAnswer)
+	}
+	if summary.MessageCount != 6 || summary.Name != "Fix test" {
+		t.Errorf("MessageCount = %d, Name = %q", summary.MessageCount, summary.Name)
+	}
+	if summary.DurationSeconds != 330 {
+		t.Errorf("DurationSeconds = %d, want 330", summary.DurationSeconds)
+	}
+	if got := strings.Join(summary.FilesTouched, ","); got != "go.mod,pkg/a.go,pkg/b.go" {
+		t.Errorf("FilesTouched = %s, want go.mod,pkg/a.go,pkg/b.go", got)
+	}
+	want := map[string]int{"read_file": 2, "run_terminal_cmd": 1, "unknown": 1}
+	if len(summary.ToolUsage) != len(want) {
+		t.Errorf("ToolUsage = %v, want %v", summary.ToolUsage, want)
+	}
+	for name, n := range want {
+		if summary.ToolUsage[name] != n {
+			t.Errorf("ToolUsage[%s] = %d, want %d", name, summary.ToolUsage[name], n)
+		}
+	}
+}
+
+func TestSummarizeSession_DurationFromMetadata(t *testing.T) {
+	session := CreateTestSessionWithMessages("s2", []Message{{Actor: "user", Content: "hi"}})
+	session.Metadata.CreatedAt = "2025-01-01T10:00:00Z"
+	session.Metadata.UpdatedAt = "2025-01-01T11:00:00Z"
+
+	summary := SummarizeSession(session)
+	if summary.DurationSeconds != 3600 {
+		t.Errorf("DurationSeconds = %d, want 3600", summary.DurationSeconds)
+	}
+	if summary.FinalAnswer != "" || summary.ToolUsage != nil || summary.FilesTouched != nil {
+		t.Errorf("summary of a session without answers = %+v", summary)
+	}
+}
+
+func TestMarshalSummaries(t *testing.T) {
+	summaries := []*SessionSummary{SummarizeSession(summaryTestSession()), {ID: "s2"}}
+
+	data, err := MarshalSummaries(summaries, "jsonl")
+	if err != nil {
+		t.Fatalf("MarshalSummaries(jsonl) error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("jsonl has %d lines, want 2", len(lines))
+	}
+	var first SessionSummary
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ToolUsage["read_file"] != 2 {
+		t.Errorf("jsonl line = %s, err = %v", lines[0], err)
+	}
+
+	data, err = MarshalSummaries(summaries, "json")
+	if err != nil {
+		t.Fatalf("MarshalSummaries(json) error = %v", err)
+	}
+	var list []SessionSummary
+	if err := json.Unmarshal(data, &list); err != nil || len(list) != 2 {
+		t.Errorf("json = %s, err = %v", data, err)
+	}
+
+	data, err = MarshalSummaries(summaries, "yaml")
+	if err != nil {
+		t.Fatalf("MarshalSummaries(yaml) error = %v", err)
+	}
+	list = nil
+	if err := yaml.Unmarshal(data, &list); err != nil || len(list) != 2 || list[0].FirstPrompt != "Fix the failing test" {
+		t.Errorf("yaml = %s, err = %v", data, err)
+	}
+
+	if data, err := MarshalSummaries(nil, "json"); err != nil || strings.TrimSpace(string(data)) != "[]" {
+		t.Errorf("MarshalSummaries(nil) = %s, %v, want []", data, err)
+	}
+	if _, err := MarshalSummaries(summaries, "md"); err == nil {
+		t.Error("MarshalSummaries(md) should fail")
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tagPattern is the set of characters allowed in a tag after lowercasing
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:/-]*$`)
+
+// TagStore holds the tags users attach to sessions, keyed by session ID. It is kept
+// in its own file so tags survive cache rebuilds and --clear-cache.
+type TagStore struct {
+	Sessions map[string][]string `yaml:"sessions"`
+
+	path string
+}
+
+// LoadTagStore loads the tag store from path. A missing file yields an empty store.
+func LoadTagStore(path string) (*TagStore, error) {
+	store := &TagStore{
+		Sessions: make(map[string][]string),
+		path:     path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	}
+	if store.Sessions == nil {
+		store.Sessions = make(map[string][]string)
+	}
+
+	return store, nil
+}
+
+// NormalizeTag lowercases and trims a tag, rejecting tags with spaces or punctuation
+// other than . _ : / and -
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid tag %q: use letters, digits and . _ : / - only", tag)
+	}
+	return normalized, nil
+}
+
+// Tags returns the sorted tags of a session. A nil store has no tags.
+func (s *TagStore) Tags(sessionID string) []string {
+	if s == nil {
+		return nil
+	}
+	return s.Sessions[sessionID]
+}
+
+// Add attaches tags to a session and returns its updated tags
+func (s *TagStore) Add(sessionID string, tags ...string) ([]string, error) {
+	set := make(map[string]bool)
+	for _, tag := range s.Sessions[sessionID] {
+		set[tag] = true
+	}
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		set[normalized] = true
+	}
+	return s.set(sessionID, set), nil
+}
+
+// Remove detaches tags from a session and returns its remaining tags
+func (s *TagStore) Remove(sessionID string, tags ...string) []string {
+	set := make(map[string]bool)
+	for _, tag := range s.Sessions[sessionID] {
+		set[tag] = true
+	}
+	for _, tag := range tags {
+		delete(set, strings.ToLower(strings.TrimSpace(tag)))
+	}
+	return s.set(sessionID, set)
+}
+
+// set stores the tags in set as the sorted tags of a session, dropping sessions without tags
+func (s *TagStore) set(sessionID string, set map[string]bool) []string {
+	if len(set) == 0 {
+		delete(s.Sessions, sessionID)
+		return nil
+	}
+
+	tags := make([]string, 0, len(set))
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	s.Sessions[sessionID] = tags
+	return tags
+}
+
+// HasTag reports whether a session carries tag
+func (s *TagStore) HasTag(sessionID, tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range s.Tags(sessionID) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply copies the stored tags onto each session's metadata
+func (s *TagStore) Apply(sessions []*Session) {
+	for _, session := range sessions {
+		if session != nil {
+			session.Metadata.Tags = s.Tags(session.ID)
+		}
+	}
+}
+
+// Save writes the tag store back to its file
+func (s *TagStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
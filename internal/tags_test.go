@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "bug-hunt", want: "bug-hunt"},
+		{tag: "  Prod-Incident ", want: "prod-incident"},
+		{tag: "team/backend:q3", want: "team/backend:q3"},
+		{tag: "", wantErr: true},
+		{tag: "two words", wantErr: true},
+		{tag: "-leading", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := NormalizeTag(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagStore(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), "tags.yaml")
+
+	store, err := LoadTagStore(path)
+	if err != nil {
+		t.Fatalf("LoadTagStore() error = %v", err)
+	}
+	if tags := store.Tags("s1"); tags != nil {
+		t.Errorf("Tags() on an empty store = %v, want nil", tags)
+	}
+
+	tags, err := store.Add("s1", "prod-incident", "Bug-Hunt", "bug-hunt")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if want := []string{"bug-hunt", "prod-incident"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Add() = %v, want %v", tags, want)
+	}
+	if _, err := store.Add("s1", "not valid"); err == nil {
+		t.Error("Add() should reject invalid tags")
+	}
+	if !store.HasTag("s1", "BUG-HUNT") || store.HasTag("s2", "bug-hunt") {
+		t.Error("HasTag() should match tags case-insensitively and per session")
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadTagStore(path)
+	if err != nil {
+		t.Fatalf("LoadTagStore() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Tags("s1"), tags) {
+		t.Errorf("loaded Tags() = %v, want %v", loaded.Tags("s1"), tags)
+	}
+
+	if remaining := loaded.Remove("s1", "bug-hunt", "prod-incident"); remaining != nil {
+		t.Errorf("Remove() = %v, want nil", remaining)
+	}
+	if _, ok := loaded.Sessions["s1"]; ok {
+		t.Error("Remove() should drop sessions without tags")
+	}
+
+	session := CreateTestSession("s1")
+	store.Apply([]*Session{session, nil})
+	if !reflect.DeepEqual(session.Metadata.Tags, tags) {
+		t.Errorf("Apply() set Tags = %v, want %v", session.Metadata.Tags, tags)
+	}
+
+	var nilStore *TagStore
+	if nilStore.Tags("s1") != nil || nilStore.HasTag("s1", "bug-hunt") {
+		t.Error("a nil store should have no tags")
+	}
+}
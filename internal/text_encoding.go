@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings that values are converted from before they are parsed
+const (
+	EncodingUTF8BOM = "UTF-8 with BOM"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+)
+
+// utf16SniffBytes is how much of a value without a byte order mark is looked at to
+// recognize UTF-16 text
+const utf16SniffBytes = 1024
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// decodeTextEncoding returns a value as UTF-8 without a byte order mark, and the encoding
+// it was converted from, so that values written by Windows builds parse as JSON. UTF-16
+// is recognized by its byte order mark or, without one, by the zero high bytes of ASCII
+// text; a UTF-8 byte order mark is dropped. Other values, binary ones included, are
+// returned as they are, with no encoding.
+func decodeTextEncoding(value []byte) ([]byte, string) {
+	var text []byte
+	var encoding string
+	switch {
+	case bytes.HasPrefix(value, utf8BOM):
+		if rest := value[len(utf8BOM):]; utf8.Valid(rest) {
+			text, encoding = rest, EncodingUTF8BOM
+		}
+	case bytes.HasPrefix(value, utf16LEBOM):
+		if decoded, ok := decodeUTF16(value[len(utf16LEBOM):], binary.LittleEndian); ok {
+			text, encoding = decoded, EncodingUTF16LE
+		}
+	case bytes.HasPrefix(value, utf16BEBOM):
+		if decoded, ok := decodeUTF16(value[len(utf16BEBOM):], binary.BigEndian); ok {
+			text, encoding = decoded, EncodingUTF16BE
+		}
+	default:
+		if order, sniffed := sniffUTF16(value); order != nil {
+			if decoded, ok := decodeUTF16(value, order); ok {
+				text, encoding = decoded, sniffed
+			}
+		}
+	}
+	// Binary data can start with what looks like a byte order mark too
+	if encoding == "" || !isPlainText(text) {
+		return value, ""
+	}
+	return text, encoding
+}
+
+// decodeTextValue is decodeTextEncoding for values read as strings. Nearly every value
+// is plain UTF-8, so it is only copied to be decoded when its first byte may start a byte
+// order mark or its start holds a NUL, as UTF-16 text does.
+func decodeTextValue(value string) (string, string) {
+	if value == "" {
+		return value, ""
+	}
+	switch value[0] {
+	case utf8BOM[0], utf16LEBOM[0], utf16BEBOM[0]:
+	default:
+		if strings.IndexByte(value[:min(len(value), utf16SniffBytes)], 0) < 0 {
+			return value, ""
+		}
+	}
+	decoded, encoding := decodeTextEncoding([]byte(value))
+	if encoding == "" {
+		return value, ""
+	}
+	return string(decoded), encoding
+}
+
+// sniffUTF16 guesses the byte order of UTF-16 text without a byte order mark from the
+// start of a value: mostly ASCII text, as JSON is, has a zero byte in nearly every
+// other position. It returns nil for values that do not look like UTF-16.
+func sniffUTF16(value []byte) (binary.ByteOrder, string) {
+	if len(value) < 4 || len(value)%2 != 0 {
+		return nil, ""
+	}
+	sample := value[:min(len(value), utf16SniffBytes)]
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	units := len(sample) / 2
+	switch {
+	case oddZeros*10 >= units*9 && evenZeros == 0:
+		return binary.LittleEndian, EncodingUTF16LE
+	case evenZeros*10 >= units*9 && oddZeros == 0:
+		return binary.BigEndian, EncodingUTF16BE
+	}
+	return nil, ""
+}
+
+// decodeUTF16 converts UTF-16 to UTF-8, failing on an odd number of bytes or a
+// surrogate without its pair
+func decodeUTF16(value []byte, order binary.ByteOrder) ([]byte, bool) {
+	if len(value)%2 != 0 {
+		return nil, false
+	}
+	units := make([]uint16, len(value)/2)
+	for i := range units {
+		units[i] = order.Uint16(value[2*i:])
+	}
+	text := make([]byte, 0, len(units))
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		if utf16.IsSurrogate(r) {
+			if i+1 == len(units) {
+				return nil, false
+			}
+			r = utf16.DecodeRune(r, rune(units[i+1]))
+			if r == unicode.ReplacementChar {
+				return nil, false
+			}
+			i++
+		}
+		text = utf8.AppendRune(text, r)
+	}
+	return text, true
+}
+
+// isPlainText reports whether text holds no control characters other than whitespace,
+// as binary data read as UTF-16 would
+func isPlainText(text []byte) bool {
+	for _, r := range string(text) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
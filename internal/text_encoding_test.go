@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes text as UTF-16 in the given byte order, after bom
+func encodeUTF16(text string, order binary.AppendByteOrder, bom []byte) []byte {
+	data := append([]byte(nil), bom...)
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = order.AppendUint16(data, unit)
+	}
+	return data
+}
+
+func TestDecodeTextEncoding(t *testing.T) {
+	const doc = `{"text":"Grüße 👋","type":1}`
+	tests := []struct {
+		name         string
+		value        []byte
+		want         string
+		wantEncoding string
+	}{
+		{"UTF-8", []byte(doc), doc, ""},
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, doc...), doc, EncodingUTF8BOM},
+		{"UTF-16LE with BOM", encodeUTF16(doc, binary.LittleEndian, utf16LEBOM), doc, EncodingUTF16LE},
+		{"UTF-16BE with BOM", encodeUTF16(doc, binary.BigEndian, utf16BEBOM), doc, EncodingUTF16BE},
+		{"UTF-16LE without BOM", encodeUTF16(doc, binary.LittleEndian, nil), doc, EncodingUTF16LE},
+		{"UTF-16BE without BOM", encodeUTF16(doc, binary.BigEndian, nil), doc, EncodingUTF16BE},
+		{"protobuf", []byte{0x0a, 0x00, 0x12, 0x00, 0x1a, 0x00, 0x22, 0x00}, "\x0a\x00\x12\x00\x1a\x00\x22\x00", ""},
+		{"unpaired surrogate after BOM", []byte{0xFF, 0xFE, 0x00, 0xD8}, "\xFF\xFE\x00\xD8", ""},
+		{"odd length", []byte{'{', 0, '}', 0, ' '}, "{\x00}\x00 ", ""},
+		{"binary after UTF-16LE BOM", []byte{0xFF, 0xFE, 0x01, 0x00, 0x02, 0x00}, "\xFF\xFE\x01\x00\x02\x00", ""},
+		{"binary after UTF-16BE BOM", []byte{0xFE, 0xFF, 0x00, 0x1b, 0x00, 0x7f}, "\xFE\xFF\x00\x1b\x00\x7f", ""},
+		{"binary after UTF-8 BOM", []byte{0xEF, 0xBB, 0xBF, 0x08, 0x00}, "\xEF\xBB\xBF\x08\x00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding := decodeTextEncoding(tt.value)
+			if string(got) != tt.want || encoding != tt.wantEncoding {
+				t.Errorf("decodeTextEncoding() = %q, %q, want %q, %q", got, encoding, tt.want, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestDecodeTextValue(t *testing.T) {
+	const doc = `{"text":"Grüße 👋","type":1}`
+	if got, encoding := decodeTextValue(string(encodeUTF16(doc, binary.LittleEndian, nil))); got != doc || encoding != EncodingUTF16LE {
+		t.Errorf("decodeTextValue() = %q, %q, want %q, %q", got, encoding, doc, EncodingUTF16LE)
+	}
+
+	// Plain UTF-8 values are returned without being copied
+	allocs := testing.AllocsPerRun(100, func() {
+		if got, encoding := decodeTextValue(doc); got != doc || encoding != "" {
+			t.Errorf("decodeTextValue() = %q, %q, want the value unchanged", got, encoding)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("decodeTextValue() of plain UTF-8 made %v allocations, want 0", allocs)
+	}
+}
+
+func TestParseRawBubble_UTF16(t *testing.T) {
+	value := encodeUTF16(`{"text":"Héllo from Windows","type":1}`, binary.LittleEndian, utf16LEBOM)
+	bubble, err := ParseRawBubble("bubbleId:chat1:bubble1", string(value))
+	if err != nil {
+		t.Fatalf("ParseRawBubble() error = %v", err)
+	}
+	if bubble.Text != "Héllo from Windows" {
+		t.Errorf("Text = %q", bubble.Text)
+	}
+}
@@ -11,7 +11,15 @@ import (
 // 2. Fallback: Parse bubble.richText JSON structure (including thinking/tool calls)
 // 3. Enhancement: Append bubble.codeBlocks[] as markdown code fences
 func ExtractTextFromBubble(bubble *RawBubble) (string, error) {
+	text, _, err := ExtractTextWithTier(bubble)
+	return text, err
+}
+
+// ExtractTextWithTier extracts text from a bubble as ExtractTextFromBubble does, and also
+// returns the tier the text came from, for measuring extraction quality
+func ExtractTextWithTier(bubble *RawBubble) (string, ExtractionTier, error) {
 	var textParts []string
+	tier := ExtractionFallback
 
 	// Tier 1: Primary text field
 	if bubble.Text != "" {
@@ -19,12 +27,14 @@ func ExtractTextFromBubble(bubble *RawBubble) (string, error) {
 		text := reformatRedactedReasoning(bubble.Text)
 		if text != "" {
 			textParts = append(textParts, text)
+			tier = ExtractionPrimary
 		}
 	}
 
 	// Tier 2: Parse richText JSON structure (even if text exists, richText may have additional info)
 	if bubble.RichText != "" {
 		richText, err := ExtractTextFromRichText(bubble.RichText)
+		parsed := err == nil
 		if err != nil {
 			// If richText parsing fails, try fallback extraction
 			LogDebug("Failed to parse richText JSON: %v, trying fallback extraction", err)
@@ -39,6 +49,9 @@ func ExtractTextFromBubble(bubble *RawBubble) (string, error) {
 			if bubble.Text == "" || !strings.Contains(bubble.Text, richText) {
 				textParts = append(textParts, richText)
 			}
+			if parsed && tier != ExtractionPrimary {
+				tier = ExtractionRichText
+			}
 		}
 	}
 
@@ -46,24 +59,76 @@ func ExtractTextFromBubble(bubble *RawBubble) (string, error) {
 	if len(bubble.CodeBlocks) > 0 {
 		for _, codeBlock := range bubble.CodeBlocks {
 			if codeBlock.Content != "" {
-				lang := codeBlock.Language
-				if lang == "" {
-					lang = ""
-				}
-				textParts = append(textParts, fmt.Sprintf("```%s\n%s\n```", lang, codeBlock.Content))
+				textParts = append(textParts, fmt.Sprintf("```%s\n%s\n```", codeBlock.Language, codeBlock.Content))
 			}
 		}
 	}
 
+	// Tier 4: Append the tool call and its output
+	if call := bubble.ToolFormerData; call != nil && call.Name != "" {
+		toolParts := []string{"[Tool Call]", fmt.Sprintf("Tool: %s", call.Name)}
+		if call.RawArgs != "" {
+			toolParts = append(toolParts, fmt.Sprintf("Arguments: %s", call.RawArgs))
+		}
+		textParts = append(textParts, strings.Join(toolParts, "\n"))
+		if call.Result != "" {
+			textParts = append(textParts, fmt.Sprintf("[Tool Response]\n%s", call.Result))
+		}
+	}
+
 	// Combine all parts
 	result := strings.Join(textParts, "\n\n")
 
 	// If we still have no text, return a placeholder to indicate the message exists
 	if result == "" {
-		return "[Message with no extractable text content]", nil
+		return placeholderText, ExtractionPlaceholder, nil
+	}
+
+	return result, tier, nil
+}
+
+// ExtractThinking returns the reasoning a bubble recorded, from its thinking field and the
+// thinking nodes of its rich text, which the text extraction leaves out
+func ExtractThinking(bubble *RawBubble) string {
+	var parts []string
+	if bubble.Thinking != nil {
+		if text := strings.TrimSpace(bubble.Thinking.Text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if thinking := ExtractThinkingFromRichText(bubble.RichText); thinking != "" {
+		parts = append(parts, thinking)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// BubbleProvenance returns the provenance for a bubble with the reconstruction
+// strategy filled in. The strategy lists the extraction tiers that contributed
+// text, unless the storage parser already recorded a more specific one.
+func BubbleProvenance(bubble *RawBubble) *Provenance {
+	var prov Provenance
+	if bubble.Provenance != nil {
+		prov = *bubble.Provenance
+	}
+
+	if prov.Strategy == "" {
+		var tiers []string
+		if bubble.Text != "" {
+			tiers = append(tiers, "text")
+		}
+		if bubble.RichText != "" {
+			tiers = append(tiers, "richText")
+		}
+		if len(bubble.CodeBlocks) > 0 {
+			tiers = append(tiers, "codeBlocks")
+		}
+		if bubble.ToolFormerData != nil && bubble.ToolFormerData.Name != "" {
+			tiers = append(tiers, "toolFormerData")
+		}
+		prov.Strategy = strings.Join(tiers, "+")
 	}
 
-	return result, nil
+	return &prov
 }
 
 // extractFallbackText tries to extract any readable text from a JSON string
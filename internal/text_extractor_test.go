@@ -91,6 +91,14 @@ func TestExtractTextFromBubble(t *testing.T) {
 			want:    "[Message with no extractable text content]",
 			wantErr: false,
 		},
+		{
+			name: "tool call",
+			bubble: &RawBubble{
+				ToolFormerData: &ToolFormerData{Name: "run_terminal_cmd", RawArgs: `{"command":"go test"}`, Result: "ok"},
+			},
+			want:    "[Tool Call]\nTool: run_terminal_cmd\nArguments: {\"command\":\"go test\"}\n\n[Tool Response]\nok",
+			wantErr: false,
+		},
 		{
 			name: "rich text with fallback extraction",
 			bubble: &RawBubble{
@@ -232,3 +240,92 @@ func TestExtractFromRawJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractThinking(t *testing.T) {
+	bubble := &RawBubble{
+		Text:     "Done",
+		Thinking: &BubbleThinking{Text: "Plan the fix", Signature: "sig"},
+		RichText: `{"root":{"children":[{"type":"thinking","children":[{"type":"text","text":"Then test"}]},{"type":"text","text":"Done"}]}}`,
+	}
+	if got := ExtractThinking(bubble); got != "Plan the fix\n\nThen test" {
+		t.Errorf("ExtractThinking() = %q", got)
+	}
+	if text, _ := ExtractTextFromBubble(bubble); text != "Done" {
+		t.Errorf("ExtractTextFromBubble() = %q, want the text without the thinking", text)
+	}
+	if got := ExtractThinking(&RawBubble{Text: "Hello"}); got != "" {
+		t.Errorf("ExtractThinking() without thinking = %q", got)
+	}
+}
+
+func TestBubbleProvenance(t *testing.T) {
+	tests := []struct {
+		name         string
+		bubble       *RawBubble
+		wantStrategy string
+		wantKey      string
+	}{
+		{
+			name:         "text only",
+			bubble:       &RawBubble{Text: "Hello"},
+			wantStrategy: "text",
+		},
+		{
+			name: "text, richText and code blocks",
+			bubble: &RawBubble{
+				Text:       "Hello",
+				RichText:   `{"root":{}}`,
+				CodeBlocks: []CodeBlock{{Content: "x := 1"}},
+			},
+			wantStrategy: "text+richText+codeBlocks",
+		},
+		{
+			name: "strategy recorded by parser is kept",
+			bubble: &RawBubble{
+				Text:       "hello",
+				Provenance: &Provenance{BlobKey: "abc123", Strategy: "text$uuid"},
+			},
+			wantStrategy: "text$uuid",
+			wantKey:      "abc123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BubbleProvenance(tt.bubble)
+			if got.Strategy != tt.wantStrategy {
+				t.Errorf("BubbleProvenance().Strategy = %q, want %q", got.Strategy, tt.wantStrategy)
+			}
+			if got.BlobKey != tt.wantKey {
+				t.Errorf("BubbleProvenance().BlobKey = %q, want %q", got.BlobKey, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestExtractTextWithTier(t *testing.T) {
+	tests := []struct {
+		name   string
+		bubble *RawBubble
+		want   ExtractionTier
+	}{
+		{"text field", &RawBubble{Text: "Hello", RichText: `{"root":{"children":[{"type":"text","text":"Rich"}]}}`}, ExtractionPrimary},
+		{"rich text", &RawBubble{RichText: `{"root":{"children":[{"type":"text","text":"Rich"}]}}`}, ExtractionRichText},
+		{"malformed rich text", &RawBubble{RichText: `{"root": {"text": "recovered text", broken`}, ExtractionFallback},
+		{"code blocks only", &RawBubble{CodeBlocks: []CodeBlock{{Language: "go", Content: "package main"}}}, ExtractionFallback},
+		{"tool call only", &RawBubble{ToolFormerData: &ToolFormerData{Name: "read_file"}}, ExtractionFallback},
+		{"empty", &RawBubble{}, ExtractionPlaceholder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := ExtractTextWithTier(tt.bubble)
+			if err != nil {
+				t.Fatalf("ExtractTextWithTier() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractTextWithTier() tier = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ways of exporting the reasoning a message recorded before its content
+const (
+	ThinkingInline   = "inline"   // Merge it into the content as a [thinking] section
+	ThinkingSeparate = "separate" // Keep it in the message's thinking field
+	ThinkingStrip    = "strip"    // Leave it out
+)
+
+// ThinkingModes lists the accepted --thinking values
+var ThinkingModes = []string{ThinkingInline, ThinkingSeparate, ThinkingStrip}
+
+// ThinkingMode is how an export writes the thinking of messages
+type ThinkingMode string
+
+// NewThinkingMode builds a mode from the --thinking flag value
+func NewThinkingMode(mode string) (ThinkingMode, error) {
+	mode = strings.ToLower(mode)
+	for _, m := range ThinkingModes {
+		if mode == m {
+			return ThinkingMode(mode), nil
+		}
+	}
+	return "", fmt.Errorf("invalid --thinking %q (expected %s)", mode, strings.Join(ThinkingModes, ", "))
+}
+
+// Apply returns a copy of session whose thinking is written as the mode asks. Messages
+// that held nothing but thinking are dropped by ThinkingStrip, with the message count
+// updated. The original session is not modified.
+func (m ThinkingMode) Apply(session *Session) *Session {
+	if m == ThinkingSeparate || !HasThinking(session) {
+		return session
+	}
+
+	applied := *session
+	applied.Messages = make([]Message, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		if msg.Thinking != "" {
+			if m == ThinkingStrip {
+				if msg.Content == "" && len(msg.Attachments) == 0 {
+					continue
+				}
+			} else {
+				msg.Content = InlineThinking(msg.Thinking, msg.Content)
+			}
+			msg.Thinking = ""
+		}
+		applied.Messages = append(applied.Messages, msg)
+	}
+	applied.Metadata.MessageCount = len(applied.Messages)
+	return &applied
+}
+
+// HasThinking reports whether any message of a session recorded thinking
+func HasThinking(session *Session) bool {
+	for _, msg := range session.Messages {
+		if msg.Thinking != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// InlineThinking returns content preceded by a [thinking] section holding thinking, as
+// the Markdown exporter collapses it. Content is returned as is when there is no thinking.
+func InlineThinking(thinking, content string) string {
+	if thinking == "" {
+		return content
+	}
+	section := "[thinking]\n" + thinking
+	if content == "" {
+		return section
+	}
+	return section + "\n\n" + content
+}
+
+// ThinkingTokens estimates the tokens the thinking of a session's messages takes
+func ThinkingTokens(session *Session) int {
+	tokens := 0
+	for _, msg := range session.Messages {
+		if msg.Thinking != "" {
+			tokens += EstimateTokens(msg.Thinking)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,59 @@
+package internal
+
+import "testing"
+
+func TestNewThinkingMode(t *testing.T) {
+	for _, mode := range []string{"inline", "separate", "STRIP"} {
+		if _, err := NewThinkingMode(mode); err != nil {
+			t.Errorf("NewThinkingMode(%q) error = %v", mode, err)
+		}
+	}
+	if _, err := NewThinkingMode("hidden"); err == nil {
+		t.Error("NewThinkingMode(hidden) should fail")
+	}
+}
+
+func TestThinkingMode_Apply(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "user", Content: "Fix the build"},
+		{Actor: "assistant", Thinking: "Look at the Makefile"},
+		{Actor: "assistant", Content: "Fixed", Thinking: "The flags are wrong"},
+	})
+
+	inline := ThinkingMode(ThinkingInline).Apply(session)
+	if got := inline.Messages[1].Content; got != "[thinking]\nLook at the Makefile" {
+		t.Errorf("inline content = %q", got)
+	}
+	if got := inline.Messages[2].Content; got != "[thinking]\nThe flags are wrong\n\nFixed" {
+		t.Errorf("inline content = %q", got)
+	}
+	if HasThinking(inline) {
+		t.Error("inline should merge the thinking into the content")
+	}
+
+	if separate := ThinkingMode(ThinkingSeparate).Apply(session); separate != session {
+		t.Error("separate should keep the session as is")
+	}
+
+	strip := ThinkingMode(ThinkingStrip).Apply(session)
+	if len(strip.Messages) != 2 || strip.Metadata.MessageCount != 2 || HasThinking(strip) {
+		t.Errorf("strip = %+v, want the thinking-only message dropped", strip.Messages)
+	}
+	if strip.Messages[1].Content != "Fixed" {
+		t.Errorf("strip content = %q, want Fixed", strip.Messages[1].Content)
+	}
+
+	if session.Messages[2].Thinking == "" || len(session.Messages) != 3 {
+		t.Error("Apply() should not modify the original session")
+	}
+}
+
+func TestThinkingTokens(t *testing.T) {
+	session := CreateTestSessionWithMessages("s1", []Message{
+		{Actor: "assistant", Content: "12345678", Thinking: "1234"},
+		{Actor: "assistant", Thinking: "12345"},
+	})
+	if got := ThinkingTokens(session); got != 3 {
+		t.Errorf("ThinkingTokens() = %d, want 3", got)
+	}
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Named layouts accepted by ConfigureTimeDisplay in addition to Go layout strings
+var timeLayoutNames = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"iso":      time.RFC3339,
+	"datetime": time.DateTime,
+	"date":     time.DateOnly,
+	"time":     time.TimeOnly,
+	"kitchen":  time.Kitchen,
+	"rfc1123":  time.RFC1123,
+}
+
+var (
+	// displayLocation is the time zone timestamps are shown in (list, show, Markdown)
+	displayLocation = time.Local
+	// displayLayout overrides the per-command default layout when set
+	displayLayout string
+	// displayConfigured is set once a time zone or layout has been chosen explicitly
+	displayConfigured bool
+)
+
+// ConfigureTimeDisplay sets the time zone (IANA name, "UTC" or "Local"; empty keeps the
+// machine time zone) and layout (a name such as "datetime" or a Go layout; empty keeps
+// each command's default) used to render timestamps for people
+func ConfigureTimeDisplay(timezone, layout string) error {
+	location := time.Local
+	if timezone != "" && !strings.EqualFold(timezone, "local") {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %s (use an IANA name such as Europe/Berlin, UTC or Local)", timezone)
+		}
+		location = loc
+	}
+
+	if named, ok := timeLayoutNames[strings.ToLower(layout)]; ok {
+		layout = named
+	}
+
+	displayLocation = location
+	displayLayout = layout
+	displayConfigured = timezone != "" || layout != ""
+	return nil
+}
+
+// DisplayTime converts t to the configured display time zone
+func DisplayTime(t time.Time) time.Time {
+	return t.In(displayLocation)
+}
+
+// FormatDisplayTime renders t in the display time zone, using the configured layout
+// or defaultLayout if none was set
+func FormatDisplayTime(t time.Time, defaultLayout string) string {
+	layout := defaultLayout
+	if displayLayout != "" {
+		layout = displayLayout
+	}
+	return DisplayTime(t).Format(layout)
+}
+
+// HasDisplayLayout reports whether a layout was configured explicitly
+func HasDisplayLayout() bool {
+	return displayLayout != ""
+}
+
+// FormatDisplayTimestamp renders a stored RFC3339 timestamp for people. Without an
+// explicit time zone or layout the stored value is returned unchanged.
+func FormatDisplayTimestamp(ts string) string {
+	if !displayConfigured || ts == "" {
+		return ts
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return FormatDisplayTime(t, time.RFC3339)
+}
+
+// UTCTimestamp rewrites an RFC3339 timestamp in UTC, so exports do not depend on the
+// time zone of the machine that produced them. Unparseable values are returned unchanged.
+func UTCTimestamp(ts string) string {
+	if ts == "" {
+		return ts
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigureTimeDisplay(t *testing.T) {
+	defer func() { _ = ConfigureTimeDisplay("", "") }()
+
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		timezone      string
+		layout        string
+		defaultLayout string
+		want          string
+		wantErr       bool
+	}{
+		{"UTC with default layout", "UTC", "", time.DateTime, "2024-03-01 12:30:00", false},
+		{"IANA zone", "Asia/Tokyo", "", time.DateTime, "2024-03-01 21:30:00", false},
+		{"named layout", "UTC", "kitchen", time.DateTime, "12:30PM", false},
+		{"Go layout", "UTC", "02/01/2006", time.DateTime, "01/03/2024", false},
+		{"rfc3339 keeps offset", "America/New_York", "rfc3339", "", "2024-03-01T07:30:00-05:00", false},
+		{"invalid zone", "Mars/Olympus", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfigureTimeDisplay(tt.timezone, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureTimeDisplay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := FormatDisplayTime(ts, tt.defaultLayout); got != tt.want {
+				t.Errorf("FormatDisplayTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDisplayTimestamp(t *testing.T) {
+	defer func() { _ = ConfigureTimeDisplay("", "") }()
+
+	_ = ConfigureTimeDisplay("", "")
+	if got := FormatDisplayTimestamp("2024-03-01T12:30:00Z"); got != "2024-03-01T12:30:00Z" {
+		t.Errorf("FormatDisplayTimestamp() without configuration = %q, want stored value", got)
+	}
+
+	_ = ConfigureTimeDisplay("Europe/Berlin", "datetime")
+	if got := FormatDisplayTimestamp("2024-03-01T12:30:00Z"); got != "2024-03-01 13:30:00" {
+		t.Errorf("FormatDisplayTimestamp() = %q, want 2024-03-01 13:30:00", got)
+	}
+	if got := FormatDisplayTimestamp("not a time"); got != "not a time" {
+		t.Errorf("FormatDisplayTimestamp() = %q, want unparseable value unchanged", got)
+	}
+}
+
+func TestUTCTimestamp(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024-03-01T14:30:00+02:00", "2024-03-01T12:30:00Z"},
+		{"2024-03-01T12:30:00Z", "2024-03-01T12:30:00Z"},
+		{"", ""},
+		{"yesterday", "yesterday"},
+	}
+
+	for _, tt := range tests {
+		if got := UTCTimestamp(tt.input); got != tt.want {
+			t.Errorf("UTCTimestamp(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
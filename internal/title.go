@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Summarizer derives a title for a session that has no name. Implementations may call
+// out to a model; the default derives the title from the first user message.
+type Summarizer interface {
+	Summarize(messages []Message) (string, error)
+}
+
+// defaultTitleLength is the maximum title length, in characters, when none is configured
+const defaultTitleLength = 60
+
+var (
+	// userQueryPattern extracts the prompt cursor-agent wraps in <user_query> tags
+	userQueryPattern = regexp.MustCompile(`(?s)<user_query>(.*?)</user_query>`)
+	// titleTagPattern matches XML-style tags and @-mentions of files, which make poor titles
+	titleTagPattern = regexp.MustCompile(`</?[A-Za-z_][\w-]*>|@\S+`)
+	// titleMarkupPattern matches leading Markdown markup: headings, quotes, list bullets and numbers
+	titleMarkupPattern = regexp.MustCompile(`^(#+|>|[-*+]|\d+[.)])\s+`)
+)
+
+// FirstMessageSummarizer titles a session after the first line of prose in its first
+// user message, dropping code blocks and markup
+type FirstMessageSummarizer struct {
+	MaxLength int // Maximum title length in characters; 0 uses the default of 60
+}
+
+// Summarize returns a cleaned, truncated title, or "" if no user message has any prose
+func (s FirstMessageSummarizer) Summarize(messages []Message) (string, error) {
+	maxLength := s.MaxLength
+	if maxLength <= 0 {
+		maxLength = defaultTitleLength
+	}
+
+	for _, msg := range messages {
+		if msg.Actor != "user" {
+			continue
+		}
+		if title := titleFromText(msg.Content, maxLength); title != "" {
+			return title, nil
+		}
+	}
+	return "", nil
+}
+
+// titleFromText returns the first line of prose in text, cleaned and truncated at a word boundary
+func titleFromText(text string, maxLength int) string {
+	if m := userQueryPattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+
+	inCodeBlock := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || collapsibleLine(trimmed) {
+			continue
+		}
+
+		trimmed = titleTagPattern.ReplaceAllString(trimmed, "")
+		trimmed = titleMarkupPattern.ReplaceAllString(strings.TrimSpace(trimmed), "")
+		trimmed = strings.NewReplacer("**", "", "__", "", "`", "").Replace(trimmed)
+		trimmed = strings.Join(strings.Fields(trimmed), " ")
+		if trimmed != "" {
+			return truncateTitle(trimmed, maxLength)
+		}
+	}
+	return ""
+}
+
+// collapsibleLine reports whether a line is a [thinking] or [tool] marker written by the rich text parser
+func collapsibleLine(line string) bool {
+	switch line {
+	case "[thinking]", "[tool]", "[tool_call]", "[function_call]":
+		return true
+	}
+	return false
+}
+
+// truncateTitle shortens title to at most maxLength characters, cutting at the last space
+// when there is one and marking the cut with "..."
+func truncateTitle(title string, maxLength int) string {
+	if utf8.RuneCountInString(title) <= maxLength {
+		return title
+	}
+
+	cut := string([]rune(title)[:maxLength-3])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.-") + "..."
+}
+
+// GenerateComposerTitles derives titles for composers without a name from the first user
+// bubble of each, keyed by composer ID. It is used where sessions are listed straight from
+// storage without being normalized.
+func GenerateComposerTitles(composers []*RawComposer, bubbles map[string]*RawBubble, summarizer Summarizer) map[string]string {
+	titles := make(map[string]string)
+	for _, composer := range composers {
+		if composer.Name != "" {
+			continue
+		}
+
+		var messages []Message
+		for _, header := range composer.FullConversationHeadersOnly {
+			bubble, ok := bubbles[header.BubbleID]
+			if !ok || header.Type != 1 {
+				continue
+			}
+			text, err := ExtractTextFromBubble(bubble)
+			if err != nil || text == placeholderText {
+				continue
+			}
+			messages = append(messages, Message{Actor: "user", Content: text})
+			break
+		}
+
+		title, err := summarizer.Summarize(messages)
+		if err != nil {
+			LogDebug("Failed to generate title for composer %s: %v", composer.ComposerID, err)
+			continue
+		}
+		if title != "" {
+			titles[composer.ComposerID] = title
+		}
+	}
+	return titles
+}
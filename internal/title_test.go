@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestFirstMessageSummarizer_Summarize(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		messages  []Message
+		want      string
+	}{
+		{
+			name:     "first user message",
+			messages: []Message{{Actor: "assistant", Content: "Hi"}, {Actor: "user", Content: "Add a --json flag\nto list"}, {Actor: "user", Content: "Later"}},
+			want:     "Add a --json flag",
+		},
+		{
+			name:     "markup and code are dropped",
+			messages: []Message{{Actor: "user", Content: "```go\nfunc main() {}\n```\n## Why does **this** `panic`?"}},
+			want:     "Why does this panic?",
+		},
+		{
+			name:     "cursor-agent query tags",
+			messages: []Message{{Actor: "user", Content: "<user_info>\nOS: linux\n</user_info>\n<user_query>\nExplain @main.go please\n</user_query>"}},
+			want:     "Explain please",
+		},
+		{
+			name:      "truncated at a word boundary",
+			maxLength: 20,
+			messages:  []Message{{Actor: "user", Content: "Refactor the storage backend, then update tests"}},
+			want:      "Refactor the...",
+		},
+		{
+			name:     "no prose",
+			messages: []Message{{Actor: "user", Content: "```\nls\n```"}, {Actor: "assistant", Content: "Done"}},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FirstMessageSummarizer{MaxLength: tt.maxLength}.Summarize(tt.messages)
+			if err != nil {
+				t.Fatalf("Summarize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Summarize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateComposerTitles(t *testing.T) {
+	composers := []*RawComposer{
+		{ComposerID: "named", Name: "Kept", FullConversationHeadersOnly: []ConversationHeader{{BubbleID: "b1", Type: 1}}},
+		{ComposerID: "unnamed", FullConversationHeadersOnly: []ConversationHeader{{BubbleID: "b2", Type: 2}, {BubbleID: "b3", Type: 1}, {BubbleID: "b4", Type: 1}}},
+		{ComposerID: "empty", FullConversationHeadersOnly: []ConversationHeader{{BubbleID: "missing", Type: 1}}},
+	}
+	bubbles := map[string]*RawBubble{
+		"b1": CreateTestRawBubble("b1", "named", "Ignored", 1),
+		"b2": CreateTestRawBubble("b2", "unnamed", "Assistant text", 2),
+		"b3": CreateTestRawBubble("b3", "unnamed", "Write a migration", 1),
+		"b4": CreateTestRawBubble("b4", "unnamed", "Second prompt", 1),
+	}
+
+	titles := GenerateComposerTitles(composers, bubbles, FirstMessageSummarizer{})
+	if len(titles) != 1 || titles["unnamed"] != "Write a migration" {
+		t.Errorf("GenerateComposerTitles() = %v, want only unnamed titled \"Write a migration\"", titles)
+	}
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// UpdateCheckFile is the file in the state directory recording the last check for a
+	// newer release
+	UpdateCheckFile = "update-check.json"
+	// UpdateCheckInterval is how long the result of a check for a newer release is reused
+	// before checking again
+	UpdateCheckInterval = 24 * time.Hour
+	// EnvNoUpdateNotifier turns off the notice of a newer release when set to any value
+	EnvNoUpdateNotifier = "CURSOR_SESSION_NO_UPDATE_NOTIFIER"
+)
+
+// UpdateCheck records the last check for a newer release, so commands check at most once
+// per UpdateCheckInterval
+type UpdateCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"` // Tag of the latest release
+
+	path string
+}
+
+// LoadUpdateCheck loads the last check from path. A missing or corrupt file yields a
+// check that is due.
+func LoadUpdateCheck(path string) *UpdateCheck {
+	check := &UpdateCheck{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return check
+	}
+	if err := json.Unmarshal(data, check); err != nil {
+		LogDebug("Ignoring corrupt update check %s: %v", path, err)
+		return &UpdateCheck{path: path}
+	}
+	return check
+}
+
+// Due reports whether the last check is older than UpdateCheckInterval at now
+func (c *UpdateCheck) Due(now time.Time) bool {
+	return c.CheckedAt.IsZero() || now.Sub(c.CheckedAt) >= UpdateCheckInterval || now.Before(c.CheckedAt)
+}
+
+// Record sets the result of a check made at now
+func (c *UpdateCheck) Record(latest string, now time.Time) {
+	c.Latest = latest
+	c.CheckedAt = now.UTC()
+}
+
+// Save writes the check back to its file, replacing it in one rename
+func (c *UpdateCheck) Save() error {
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal update check: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".update-check_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write update check: %w", err)
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write update check: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write update check: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write update check: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+func TestUpdateCheck_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(testutil.CreateTempDir(t), "state", UpdateCheckFile)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	check := LoadUpdateCheck(path)
+	if !check.Due(now) {
+		t.Error("Due() = false without a previous check, want true")
+	}
+
+	check.Record("v1.4.0", now)
+	if err := check.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded := LoadUpdateCheck(path)
+	if loaded.Latest != "v1.4.0" || !loaded.CheckedAt.Equal(now) {
+		t.Errorf("LoadUpdateCheck() = %+v, want v1.4.0 checked at %v", loaded, now)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"an hour later", now.Add(time.Hour), false},
+		{"a day later", now.Add(UpdateCheckInterval), true},
+		{"before the check", now.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		if got := loaded.Due(tt.at); got != tt.want {
+			t.Errorf("Due() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if corrupt := LoadUpdateCheck(path); !corrupt.Due(now) {
+		t.Error("Due() = false for a corrupt file, want true")
+	}
+}
@@ -0,0 +1,28 @@
+package internal
+
+// Verbosity levels, set from the global --quiet and --verbose flags
+const (
+	VerbosityQuiet   = -1 // Errors and command results only
+	VerbosityNormal  = 0  // Status lines, progress and info logs
+	VerbosityVerbose = 1  // Debug logs and details in command output (-v)
+	VerbosityTrace   = 2  // Also every record skipped while loading (-vv)
+)
+
+var verbosity = VerbosityNormal
+
+// SetVerbosity sets how much is reported besides command results. Levels below
+// VerbosityQuiet or above VerbosityTrace are clamped.
+func SetVerbosity(level int) {
+	verbosity = min(max(level, VerbosityQuiet), VerbosityTrace)
+}
+
+// Verbosity returns the verbosity set with SetVerbosity
+func Verbosity() int {
+	return verbosity
+}
+
+// Quiet reports whether only errors and command results are written: no progress,
+// status messages or warnings
+func Quiet() bool {
+	return verbosity <= VerbosityQuiet
+}
@@ -0,0 +1,17 @@
+package internal
+
+import "testing"
+
+func TestSetVerbosity(t *testing.T) {
+	defer SetVerbosity(VerbosityNormal)
+
+	for level, want := range map[int]int{-3: VerbosityQuiet, -1: VerbosityQuiet, 0: VerbosityNormal, 2: VerbosityTrace, 5: VerbosityTrace} {
+		SetVerbosity(level)
+		if got := Verbosity(); got != want {
+			t.Errorf("SetVerbosity(%d): Verbosity() = %d, want %d", level, got, want)
+		}
+		if Quiet() != (want == VerbosityQuiet) {
+			t.Errorf("SetVerbosity(%d): Quiet() = %t", level, Quiet())
+		}
+	}
+}
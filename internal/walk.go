@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SkipAll can be returned by a WalkSessions callback to stop the walk without an error
+var SkipAll = errors.New("skip all sessions")
+
+// WalkOptions selects the storage WalkSessions reads and the sessions it visits
+type WalkOptions struct {
+	StoragePaths []string      // Storage locations to combine, as accepted by --storage; empty auto-detects
+	Copy         bool          // Read copies of the databases to avoid locking the running editor
+	Workspace    string        // Only visit sessions associated with this workspace
+	Filter       MessageFilter // Messages to keep in each session; the zero value keeps all of them
+	SkipEmpty    bool          // Skip sessions with no messages left after filtering
+}
+
+// WalkSessions reconstructs the sessions in the storage selected by opts and calls fn with
+// each one in turn. The walk stops at the first error returned by fn, which WalkSessions
+// returns unless it is SkipAll, or when ctx is done. The cache is neither read nor written.
+func WalkSessions(ctx context.Context, opts WalkOptions, fn func(*Session) error) error {
+	paths, err := GetStoragePathsList(opts.StoragePaths)
+	if err != nil {
+		return fmt.Errorf("failed to get storage paths: %w", err)
+	}
+
+	if opts.Copy {
+		var cleanup func() error
+		paths, cleanup, err = CopyStoragePathsList(paths)
+		if err != nil {
+			return fmt.Errorf("failed to copy database files: %w", err)
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				LogWarn("Failed to cleanup temporary files: %v", err)
+			}
+		}()
+	}
+
+	backend, err := NewStorageBackendForPaths(paths)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	conversations, err := ReconstructConversations(backend)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, session := range NormalizeSessions(conversations, backend, paths, "") {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if opts.Workspace != "" && session.Workspace != opts.Workspace {
+			continue
+		}
+		session = opts.Filter.Apply(session)
+		if opts.SkipEmpty && len(session.Messages) == 0 {
+			continue
+		}
+		if err := fn(session); err != nil {
+			if errors.Is(err, SkipAll) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
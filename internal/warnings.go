@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Warning categories, as recorded in Warning.Category
+const (
+	WarningBlobParse   = "blob_parse"   // A blob could not be decoded as a message or composer
+	WarningBlobPayload = "blob_payload" // Payloads of a blob over the size limit were not loaded
+	WarningMetaParse   = "meta_parse"   // A meta entry could not be decoded
+	WarningMessage     = "message"      // A decoded message could not be converted to a bubble
+	WarningMessageFile = "message_file" // A message file next to a store.db could not be read
+	WarningComposer    = "composer"     // A decoded composer was invalid
+	WarningDatabase    = "database"     // A database could not be read at all
+)
+
+// Warning is a problem met while loading sessions that did not stop the load, such as a
+// malformed record that was skipped
+type Warning struct {
+	Category string `json:"category"`
+	Key      string `json:"key,omitempty"`     // Key of the record, when the warning is about one
+	DBPath   string `json:"db_path,omitempty"` // Database the record was read from
+	Reason   string `json:"reason"`
+}
+
+// String formats the warning for logs
+func (w Warning) String() string {
+	var b strings.Builder
+	b.WriteString(w.Category)
+	if w.DBPath != "" {
+		fmt.Fprintf(&b, " %s", w.DBPath)
+	}
+	if w.Key != "" {
+		fmt.Fprintf(&b, " key=%s", w.Key)
+	}
+	fmt.Fprintf(&b, ": %s", w.Reason)
+	return b.String()
+}
+
+// Warnings collects the warnings of a load so callers can inspect them, instead of
+// finding them in the logs. It is safe for concurrent use, and a nil *Warnings discards
+// what is added to it.
+type Warnings struct {
+	mu    sync.Mutex
+	items []Warning
+}
+
+// NewWarnings creates an empty collector
+func NewWarnings() *Warnings {
+	return &Warnings{}
+}
+
+// Add records a warning
+func (w *Warnings) Add(category, dbPath, key, reason string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.items = append(w.items, Warning{Category: category, Key: key, DBPath: dbPath, Reason: reason})
+}
+
+// Merge adds the warnings of other
+func (w *Warnings) Merge(other *Warnings) {
+	if w == nil || other == nil || w == other {
+		return
+	}
+	items := other.List()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.items = append(w.items, items...)
+}
+
+// List returns the warnings in the order they were added
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.items...)
+}
+
+// Len returns the number of warnings
+func (w *Warnings) Len() int {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.items)
+}
+
+// Counts returns the number of warnings per category
+func (w *Warnings) Counts() map[string]int {
+	return CountWarnings(w.List())
+}
+
+// Summary sums the warnings up by category, such as "3 blob_parse, 1 meta_parse", or
+// returns "" when there are none
+func (w *Warnings) Summary() string {
+	return SummarizeWarnings(w.List())
+}
+
+// CountWarnings returns the number of warnings per category
+func CountWarnings(warnings []Warning) map[string]int {
+	counts := make(map[string]int)
+	for _, warning := range warnings {
+		counts[warning.Category]++
+	}
+	return counts
+}
+
+// SummarizeWarnings sums warnings up by category, as Warnings.Summary
+func SummarizeWarnings(warnings []Warning) string {
+	counts := CountWarnings(warnings)
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	parts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[category], category))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runWarnings collects the load warnings of the run, cleared by ResetParseStats
+var runWarnings = NewWarnings()
+
+// RecordWarnings adds load warnings to the run's warnings
+func RecordWarnings(w *Warnings) {
+	runWarnings.Merge(w)
+}
+
+// GetWarnings returns the load warnings recorded since the last ResetParseStats
+func GetWarnings() []Warning {
+	return runWarnings.List()
+}
+
+// resetWarnings clears the run's warnings
+func resetWarnings() {
+	runWarnings.mu.Lock()
+	defer runWarnings.mu.Unlock()
+	runWarnings.items = nil
+}
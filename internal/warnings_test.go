@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	w := NewWarnings()
+	w.Add(WarningBlobParse, "/chats/a/store.db", "k1", "failed JSON parse")
+	w.Add(WarningMetaParse, "/chats/a/store.db", "0", "failed JSON parse")
+	w.Add(WarningBlobParse, "/chats/a/store.db", "k2", "hex encoded but contains no JSON")
+
+	if w.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", w.Len())
+	}
+	if want := map[string]int{WarningBlobParse: 2, WarningMetaParse: 1}; !reflect.DeepEqual(w.Counts(), want) {
+		t.Errorf("Counts() = %v, want %v", w.Counts(), want)
+	}
+	if got, want := w.Summary(), "2 blob_parse, 1 meta_parse"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := w.List()[0].String(); got != "blob_parse /chats/a/store.db key=k1: failed JSON parse" {
+		t.Errorf("String() = %q", got)
+	}
+
+	other := NewWarnings()
+	other.Add(WarningDatabase, "/chats/b/store.db", "", "failed to open store.db")
+	w.Merge(other)
+	if list := w.List(); len(list) != 4 || list[3].Category != WarningDatabase {
+		t.Errorf("List() after Merge() = %v, want the merged warning last", list)
+	}
+
+	// A nil collector discards warnings
+	var discard *Warnings
+	discard.Add(WarningBlobParse, "", "", "ignored")
+	if discard.Len() != 0 || discard.List() != nil || discard.Summary() != "" {
+		t.Error("nil *Warnings should hold no warnings")
+	}
+}
+
+func TestLoadSessionFromStoreDB_Warnings(t *testing.T) {
+	ResetParseStats()
+	defer ResetParseStats()
+
+	dbPath := createStoreDB(t, "warn-session",
+		`CREATE TABLE blobs (key TEXT PRIMARY KEY, value TEXT)`,
+		`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)`,
+		`INSERT INTO blobs VALUES ('b1', '{"bubbleId":"b1","chatId":"warn-session","text":"Hello","type":1}')`,
+		`INSERT INTO blobs VALUES ('b2', '{"composerId":"warn-session","name":"Test","createdAt":1000}')`,
+		`INSERT INTO blobs VALUES ('broken', 'not json, not a message!')`,
+		`INSERT INTO meta VALUES ('1', '{"truncated":')`,
+	)
+
+	_, composers, _, warnings, err := LoadSessionFromStoreDB(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSessionFromStoreDB() error = %v", err)
+	}
+	if len(composers) != 1 {
+		t.Errorf("LoadSessionFromStoreDB() returned %d composers, want 1", len(composers))
+	}
+	counts := warnings.Counts()
+	if counts[WarningBlobParse] != 1 || counts[WarningMetaParse] != 1 {
+		t.Fatalf("warnings = %v, want one blob_parse and one meta_parse", warnings.List())
+	}
+	for _, warning := range warnings.List() {
+		if warning.DBPath != dbPath || warning.Key == "" || warning.Reason == "" {
+			t.Errorf("warning %+v should name its database, key and reason", warning)
+		}
+	}
+
+	// Loading through the reader records the warnings for the run, and a database that
+	// cannot be read as a warning of its own
+	ResetParseStats()
+	missing := filepath.Join(t.TempDir(), "missing", "store.db")
+	if _, _, _, err := NewAgentStorageReader([]string{dbPath, missing}).LoadAllSessionsFromAgentStorage(); err != nil {
+		t.Fatalf("LoadAllSessionsFromAgentStorage() error = %v", err)
+	}
+	recorded := GetWarnings()
+	if got := SummarizeWarnings(recorded); got != "1 blob_parse, 1 database, 1 meta_parse" {
+		t.Errorf("recorded warnings = %q, want the skipped records and the unreadable database", got)
+	}
+	if last := recorded[len(recorded)-1]; last.DBPath != missing || !strings.Contains(last.Reason, "store.db") {
+		t.Errorf("database warning = %+v, want the missing database and why", last)
+	}
+
+	ResetParseStats()
+	if len(GetWarnings()) != 0 {
+		t.Error("ResetParseStats() should clear the recorded warnings")
+	}
+}
@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // WorkspaceInfo represents workspace information
@@ -29,24 +33,14 @@ func DetectWorkspaces(basePath string) (map[string]*WorkspaceInfo, error) {
 		}
 
 		hash := entry.Name()
-		workspaceJSONPath := filepath.Join(workspaceStorage, hash, "workspace.json")
 
 		info := &WorkspaceInfo{
 			Hash: hash,
+			Path: readWorkspaceFolder(filepath.Join(workspaceStorage, hash)),
 		}
-
-		// Try to read workspace.json
-		if data, err := os.ReadFile(workspaceJSONPath); err == nil {
-			var workspaceData struct {
-				Folder string `json:"folder"`
-			}
-			if err := json.Unmarshal(data, &workspaceData); err == nil {
-				info.Path = workspaceData.Folder
-				// Extract name from path
-				if info.Path != "" {
-					info.Name = filepath.Base(info.Path)
-				}
-			}
+		// Extract name from path
+		if info.Path != "" {
+			info.Name = filepath.Base(info.Path)
 		}
 
 		workspaces[hash] = info
@@ -55,6 +49,22 @@ func DetectWorkspaces(basePath string) (map[string]*WorkspaceInfo, error) {
 	return workspaces, nil
 }
 
+// readWorkspaceFolder returns the folder recorded in a workspace directory's
+// workspace.json, or an empty string if it can't be read
+func readWorkspaceFolder(workspaceDir string) string {
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "workspace.json"))
+	if err != nil {
+		return ""
+	}
+	var workspaceData struct {
+		Folder string `json:"folder"`
+	}
+	if err := json.Unmarshal(data, &workspaceData); err != nil {
+		return ""
+	}
+	return workspaceData.Folder
+}
+
 // AssociateComposerWithWorkspace attempts to associate a composer with a workspace
 func AssociateComposerWithWorkspace(composerID string, contexts []*MessageContext, workspaces map[string]*WorkspaceInfo) string {
 	// Try to get projectLayouts from context
@@ -73,3 +83,41 @@ func AssociateComposerWithWorkspace(composerID string, contexts []*MessageContex
 
 	return ""
 }
+
+// WorkspaceForHash returns the folder of the workspace a cursor-agent chats/{hash}
+// directory names, or "" when none of the workspaces is known to match it. A hash matches
+// the workspaceStorage directory of the same name, or a workspace whose folder path has it
+// as its MD5 sum, which is how cursor-agent names the directory of the folder it was
+// started in.
+func WorkspaceForHash(hash string, workspaces map[string]*WorkspaceInfo) string {
+	if hash == "" {
+		return ""
+	}
+	if info, ok := workspaces[hash]; ok && info.Path != "" {
+		return info.Path
+	}
+	for _, info := range workspaces {
+		folder := workspaceFolderPath(info.Path)
+		if folder == "" {
+			continue
+		}
+		sum := md5.Sum([]byte(folder))
+		if strings.EqualFold(hex.EncodeToString(sum[:]), hash) {
+			return info.Path
+		}
+	}
+	return ""
+}
+
+// workspaceFolderPath returns the file system path of a workspace folder, which
+// workspace.json records as a file:// URI
+func workspaceFolderPath(folder string) string {
+	if !strings.HasPrefix(folder, "file://") {
+		return folder
+	}
+	u, err := url.Parse(folder)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
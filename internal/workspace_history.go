@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ItemTable keys of a workspace database that record when composers and prompts were used
+const (
+	// workspaceComposerDataKey lists the composers opened in the workspace, with their
+	// creation and last update times
+	workspaceComposerDataKey = "composer.composerData"
+	// workspaceGenerationsKey lists the prompts sent from the workspace, with the time each
+	// was sent
+	workspaceGenerationsKey = "aiService.generations"
+)
+
+// workspaceComposerData is the value stored under workspaceComposerDataKey
+type workspaceComposerData struct {
+	AllComposers []struct {
+		ComposerID    string `json:"composerId"`
+		CreatedAt     int64  `json:"createdAt"`
+		LastUpdatedAt int64  `json:"lastUpdatedAt"`
+	} `json:"allComposers"`
+}
+
+// workspaceGeneration is an entry of the value stored under workspaceGenerationsKey
+type workspaceGeneration struct {
+	UnixMs          int64  `json:"unixMs"`
+	TextDescription string `json:"textDescription"`
+}
+
+// ComposerTiming is when a composer was created and last updated, in Unix milliseconds
+type ComposerTiming struct {
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// PromptTiming is a prompt sent from a workspace and when it was sent, in Unix milliseconds
+type PromptTiming struct {
+	Text      string
+	Timestamp int64
+}
+
+// WorkspaceHistory is the timing the workspace databases record for composers and prompts.
+// Desktop bubbles do not always carry timestamps, while the workspace a conversation was
+// held in keeps its own record, so sessions are matched to it by composer ID and prompt text.
+type WorkspaceHistory struct {
+	Composers map[string]ComposerTiming // Keyed by composer ID
+	Prompts   []PromptTiming            // Ordered by time
+}
+
+// NewWorkspaceHistory creates an empty WorkspaceHistory
+func NewWorkspaceHistory() *WorkspaceHistory {
+	return &WorkspaceHistory{Composers: make(map[string]ComposerTiming)}
+}
+
+// LoadWorkspaceHistory reads the history of every workspace database of the storage
+// locations. Databases that cannot be read are skipped.
+func LoadWorkspaceHistory(list []StoragePaths) *WorkspaceHistory {
+	history := NewWorkspaceHistory()
+	for _, paths := range list {
+		dbPaths, err := paths.FindWorkspaceStateDBs()
+		if err != nil {
+			LogDebug("Failed to find workspace databases: %v", err)
+			continue
+		}
+		for _, dbPath := range dbPaths {
+			if err := history.loadDB(dbPath); err != nil {
+				LogDebug("Failed to read workspace history from %s: %v", dbPath, err)
+			}
+		}
+	}
+	return history
+}
+
+// loadDB adds the history of a workspace database
+func (h *WorkspaceHistory) loadDB(dbPath string) error {
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := addItem(db, dbPath, workspaceComposerDataKey, h.AddComposerData); err != nil {
+		return err
+	}
+	return addItem(db, dbPath, workspaceGenerationsKey, h.AddGenerations)
+}
+
+// addItem passes the value of an ItemTable key, when present, to add
+func addItem(db *sql.DB, dbPath, key string, add func(string) error) error {
+	value, found, err := QueryItemTable(db, dbPath, key)
+	if err != nil || !found {
+		return err
+	}
+	return add(value)
+}
+
+// AddComposerData adds the composer timing of a composer.composerData value. A composer
+// recorded by several workspaces keeps its earliest creation and latest update.
+func (h *WorkspaceHistory) AddComposerData(value string) error {
+	var data workspaceComposerData
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return &ParseError{Source: BackendWorkspaceStorage, Key: workspaceComposerDataKey, Err: err}
+	}
+	for _, c := range data.AllComposers {
+		if c.ComposerID == "" {
+			continue
+		}
+		timing := h.Composers[c.ComposerID]
+		if c.CreatedAt > 0 && (timing.CreatedAt == 0 || c.CreatedAt < timing.CreatedAt) {
+			timing.CreatedAt = c.CreatedAt
+		}
+		if c.LastUpdatedAt > timing.UpdatedAt {
+			timing.UpdatedAt = c.LastUpdatedAt
+		}
+		h.Composers[c.ComposerID] = timing
+	}
+	return nil
+}
+
+// AddGenerations adds the prompts of an aiService.generations value
+func (h *WorkspaceHistory) AddGenerations(value string) error {
+	var generations []workspaceGeneration
+	if err := json.Unmarshal([]byte(value), &generations); err != nil {
+		return &ParseError{Source: BackendWorkspaceStorage, Key: workspaceGenerationsKey, Err: err}
+	}
+	for _, g := range generations {
+		text := normalizePromptText(g.TextDescription)
+		if g.UnixMs <= 0 || text == "" {
+			continue
+		}
+		h.Prompts = append(h.Prompts, PromptTiming{Text: text, Timestamp: g.UnixMs})
+	}
+	sort.SliceStable(h.Prompts, func(i, j int) bool {
+		return h.Prompts[i].Timestamp < h.Prompts[j].Timestamp
+	})
+	return nil
+}
+
+// normalizePromptText collapses whitespace so prompts match message text however it
+// was wrapped
+func normalizePromptText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// NeedsTimestamps reports whether a conversation is missing its creation time or the
+// timestamp of any message
+func NeedsTimestamps(conv *ReconstructedConversation) bool {
+	if conv.CreatedAt == 0 || conv.UpdatedAt == 0 {
+		return true
+	}
+	for _, msg := range conv.Messages {
+		if msg.Timestamp == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Backfill fills in the timestamps a conversation and its branches are missing, and
+// reports whether it changed any. The creation and update times come from the workspace
+// record of the composer. A user message without a timestamp gets the time of the
+// earliest prompt with its text sent no earlier than the messages before it, within the
+// composer's lifetime; any other message without one gets the time of the message before
+// it, so the order of the conversation is kept.
+func (h *WorkspaceHistory) Backfill(conv *ReconstructedConversation) bool {
+	composerID := conv.ComposerID
+	if conv.Branch != nil {
+		composerID = conv.Branch.SessionID
+	}
+
+	changed := false
+	if timing, ok := h.Composers[composerID]; ok {
+		if conv.CreatedAt == 0 && timing.CreatedAt > 0 {
+			conv.CreatedAt = timing.CreatedAt
+			changed = true
+		}
+		if conv.UpdatedAt == 0 && timing.UpdatedAt > 0 {
+			conv.UpdatedAt = timing.UpdatedAt
+			changed = true
+		}
+	}
+
+	last := conv.CreatedAt
+	used := make(map[int]bool)
+	for i := range conv.Messages {
+		msg := &conv.Messages[i]
+		if msg.Timestamp > 0 {
+			last = msg.Timestamp
+			continue
+		}
+		if msg.Type == MessageTypeUser {
+			if j := h.matchPrompt(msg.Text, last, conv.UpdatedAt, used); j >= 0 {
+				used[j] = true
+				last = h.Prompts[j].Timestamp
+			}
+		}
+		if last > 0 {
+			msg.Timestamp = last
+			changed = true
+		}
+	}
+
+	for _, branch := range conv.Branches {
+		if h.Backfill(branch) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// matchPrompt returns the index of the earliest unused prompt that begins the text of a
+// message and was sent between after and before (when non-zero), or -1
+func (h *WorkspaceHistory) matchPrompt(text string, after, before int64, used map[int]bool) int {
+	text = normalizePromptText(text)
+	if text == "" {
+		return -1
+	}
+	for i, prompt := range h.Prompts {
+		if prompt.Timestamp < after || used[i] {
+			continue
+		}
+		if before > 0 && prompt.Timestamp > before {
+			break
+		}
+		if strings.HasPrefix(text, prompt.Text) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+const testComposerData = `{"allComposers":[
+	{"type":"head","composerId":"composer1","name":"Fix flaky tests","createdAt":1700000000000,"lastUpdatedAt":1700000900000},
+	{"type":"head","composerId":"composer2"}
+]}`
+
+const testGenerations = `[
+	{"unixMs":1700000600000,"generationUUID":"g2","type":"composer","textDescription":"Now add a test"},
+	{"unixMs":1700000100000,"generationUUID":"g1","type":"composer","textDescription":"Why does  this\nfail?"},
+	{"unixMs":1600000000000,"generationUUID":"g0","type":"composer","textDescription":"Now add a test"}
+]`
+
+func TestWorkspaceHistory_Backfill(t *testing.T) {
+	history := NewWorkspaceHistory()
+	if err := history.AddComposerData(testComposerData); err != nil {
+		t.Fatalf("AddComposerData() error = %v", err)
+	}
+	if err := history.AddGenerations(testGenerations); err != nil {
+		t.Fatalf("AddGenerations() error = %v", err)
+	}
+
+	conv := &ReconstructedConversation{
+		ComposerID: "composer1",
+		Messages: []ReconstructedMessage{
+			{Type: MessageTypeUser, Text: "Why does this fail?"},
+			{Type: MessageTypeAssistant, Text: "Because of a race."},
+			{Type: MessageTypeUser, Text: "Now add a test\n\n```go\nfunc TestRace(t *testing.T) {}\n```"},
+			{Type: MessageTypeAssistant, Text: "Done.", Timestamp: 1700000700000},
+			{Type: MessageTypeUser, Text: "Thanks"},
+		},
+	}
+	if !NeedsTimestamps(conv) {
+		t.Fatal("NeedsTimestamps() = false, want true")
+	}
+	if !history.Backfill(conv) {
+		t.Fatal("Backfill() = false, want true")
+	}
+
+	if conv.CreatedAt != 1700000000000 || conv.UpdatedAt != 1700000900000 {
+		t.Errorf("CreatedAt, UpdatedAt = %d, %d, want the composer's timing", conv.CreatedAt, conv.UpdatedAt)
+	}
+	var got []int64
+	for _, msg := range conv.Messages {
+		got = append(got, msg.Timestamp)
+	}
+	// Prompts match by text within the composer's lifetime; other messages take the time
+	// of the message before them
+	want := []int64{1700000100000, 1700000100000, 1700000600000, 1700000700000, 1700000700000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("message timestamps = %v, want %v", got, want)
+	}
+	if NeedsTimestamps(conv) {
+		t.Error("NeedsTimestamps() after Backfill() = true, want false")
+	}
+}
+
+func TestWorkspaceHistory_BackfillUnknownComposer(t *testing.T) {
+	history := NewWorkspaceHistory()
+	if err := history.AddGenerations(testGenerations); err != nil {
+		t.Fatalf("AddGenerations() error = %v", err)
+	}
+	conv := &ReconstructedConversation{
+		ComposerID: "unknown",
+		Messages:   []ReconstructedMessage{{Type: MessageTypeAssistant, Text: "Hello"}},
+	}
+	if history.Backfill(conv) {
+		t.Errorf("Backfill() = true, want false for a conversation with nothing to match")
+	}
+	if conv.CreatedAt != 0 || conv.Messages[0].Timestamp != 0 {
+		t.Errorf("Backfill() changed %+v", conv)
+	}
+}
+
+func TestWorkspaceHistory_InvalidJSON(t *testing.T) {
+	history := NewWorkspaceHistory()
+	if err := history.AddComposerData("{"); err == nil {
+		t.Error("AddComposerData() error = nil, want a parse error")
+	}
+	if err := history.AddGenerations("{"); err == nil {
+		t.Error("AddGenerations() error = nil, want a parse error")
+	}
+}
+
+func TestLoadWorkspaceHistory(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	workspaceStorage := filepath.Join(tmpDir, "workspaceStorage")
+	for hash, items := range map[string]map[string]string{
+		"ws1": {workspaceComposerDataKey: testComposerData},
+		"ws2": {workspaceGenerationsKey: testGenerations},
+		"ws3": {workspaceGenerationsKey: "not json"},
+	} {
+		dir := filepath.Join(workspaceStorage, hash)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create workspace directory: %v", err)
+		}
+		createWorkspaceStateDB(t, filepath.Join(dir, "state.vscdb"), "")
+		db, err := sql.Open("sqlite", filepath.Join(dir, "state.vscdb"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		for key, value := range items {
+			if _, err := db.Exec(`INSERT INTO ItemTable (key, value) VALUES (?, ?)`, key, value); err != nil {
+				t.Fatalf("Failed to insert %s: %v", key, err)
+			}
+		}
+		_ = db.Close()
+	}
+
+	history := LoadWorkspaceHistory([]StoragePaths{{WorkspaceStorage: workspaceStorage}})
+	if timing := history.Composers["composer1"]; timing.CreatedAt != 1700000000000 || timing.UpdatedAt != 1700000900000 {
+		t.Errorf("Composers[composer1] = %+v, want its timing", timing)
+	}
+	// The unreadable database is skipped
+	if len(history.Prompts) != 3 || history.Prompts[0].Timestamp != 1600000000000 {
+		t.Errorf("Prompts = %+v, want 3 prompts in time order", history.Prompts)
+	}
+	if history.Prompts[1].Text != "Why does this fail?" {
+		t.Errorf("Prompts[1].Text = %q, want whitespace collapsed", history.Prompts[1].Text)
+	}
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// BackendWorkspaceStorage is the provenance backend name for per-workspace state.vscdb files
+const BackendWorkspaceStorage = "workspaceStorage"
+
+// workspaceChatDataKey is the ItemTable key holding legacy chat pane tabs in a workspace database
+const workspaceChatDataKey = "workbench.panel.aichat.view.aichat.chatdata"
+
+// legacyChatData is the value stored under workspaceChatDataKey
+type legacyChatData struct {
+	Tabs []legacyChatTab `json:"tabs"`
+}
+
+// legacyChatTab is a single chat pane tab (one conversation)
+type legacyChatTab struct {
+	TabID        string             `json:"tabId"`
+	ChatTitle    string             `json:"chatTitle,omitempty"`
+	LastSendTime float64            `json:"lastSendTime,omitempty"`
+	Bubbles      []legacyChatBubble `json:"bubbles"`
+}
+
+// legacyChatBubble is a message inside a legacy chat pane tab
+type legacyChatBubble struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"` // "user" or "ai"
+	Text    string `json:"text,omitempty"`
+	RawText string `json:"rawText,omitempty"`
+}
+
+// WorkspaceStorage provides methods to extract legacy chat pane data from
+// workspaceStorage/{hash}/state.vscdb files. The databases are read once, on the first
+// load, and every later load reuses what it read.
+type WorkspaceStorage struct {
+	dbPaths []string
+
+	loadOnce  sync.Once
+	bubbles   map[string]*RawBubble
+	composers []*RawComposer
+	contexts  map[string][]*MessageContext
+}
+
+// Ensure WorkspaceStorage implements StorageBackend
+var _ StorageBackend = (*WorkspaceStorage)(nil)
+
+// NewWorkspaceStorage creates a new WorkspaceStorage instance for the given state.vscdb paths
+func NewWorkspaceStorage(dbPaths []string) *WorkspaceStorage {
+	return &WorkspaceStorage{dbPaths: dbPaths}
+}
+
+// NewWorkspaceStorageBackend creates a WorkspaceStorage for all workspace databases found under paths
+func NewWorkspaceStorageBackend(paths StoragePaths) (*WorkspaceStorage, error) {
+	dbPaths, err := paths.FindWorkspaceStateDBs()
+	if err != nil {
+		return nil, err
+	}
+	LogInfo("Found %d workspace database(s) in workspace storage", len(dbPaths))
+	return NewWorkspaceStorage(dbPaths), nil
+}
+
+// LoadBubbles loads all legacy chat bubbles from workspace storage
+func (w *WorkspaceStorage) LoadBubbles() (map[string]*RawBubble, error) {
+	w.load()
+	return w.bubbles, nil
+}
+
+// LoadComposers loads all legacy chat tabs from workspace storage as composers
+func (w *WorkspaceStorage) LoadComposers() ([]*RawComposer, error) {
+	w.load()
+	return w.composers, nil
+}
+
+// LoadMessageContexts returns one context per tab pointing at the workspace folder,
+// so AssociateComposerWithWorkspace can match legacy chats to their workspace
+func (w *WorkspaceStorage) LoadMessageContexts() (map[string][]*MessageContext, error) {
+	w.load()
+	return w.contexts, nil
+}
+
+// LoadCodeBlockDiffs returns an empty map; legacy chat pane data has no code block diffs
+func (w *WorkspaceStorage) LoadCodeBlockDiffs() (map[string][]interface{}, error) {
+	return make(map[string][]interface{}), nil
+}
+
+// load reads the workspace databases on the first call
+func (w *WorkspaceStorage) load() {
+	w.loadOnce.Do(func() {
+		w.bubbles, w.composers, w.contexts = w.loadAll()
+	})
+}
+
+// loadAll reads every workspace database, skipping ones that fail to open or parse
+func (w *WorkspaceStorage) loadAll() (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext) {
+	allBubbles := make(map[string]*RawBubble)
+	var allComposers []*RawComposer
+	allContexts := make(map[string][]*MessageContext)
+
+	for _, dbPath := range w.dbPaths {
+		bubbles, composers, contexts, err := LoadSessionsFromWorkspaceDB(dbPath)
+		if err != nil {
+			LogWarn("Failed to load chat data from %s: %v", dbPath, err)
+			continue
+		}
+
+		for id, bubble := range bubbles {
+			allBubbles[id] = bubble
+		}
+		allComposers = append(allComposers, composers...)
+		for composerID, ctxList := range contexts {
+			allContexts[composerID] = append(allContexts[composerID], ctxList...)
+		}
+	}
+
+	return allBubbles, allComposers, allContexts
+}
+
+// LoadSessionsFromWorkspaceDB loads legacy chat pane tabs from a single workspace state.vscdb
+func LoadSessionsFromWorkspaceDB(dbPath string) (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext, error) {
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open workspace database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	value, found, err := QueryItemTable(db, dbPath, workspaceChatDataKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !found {
+		return map[string]*RawBubble{}, nil, map[string][]*MessageContext{}, nil
+	}
+
+	folder := readWorkspaceFolder(filepath.Dir(dbPath))
+	return ParseWorkspaceChatData(value, dbPath, folder)
+}
+
+// ParseWorkspaceChatData converts a legacy chatdata value into bubbles, composers and contexts.
+// folder is the workspace folder from workspace.json and may be empty.
+func ParseWorkspaceChatData(value, dbPath, folder string) (map[string]*RawBubble, []*RawComposer, map[string][]*MessageContext, error) {
+	value, _ = decodeTextValue(value)
+	var data legacyChatData
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, nil, nil, &ParseError{Source: BackendWorkspaceStorage, Key: workspaceChatDataKey, Err: err}
+	}
+
+	bubbles := make(map[string]*RawBubble)
+	var composers []*RawComposer
+	contexts := make(map[string][]*MessageContext)
+
+	for _, tab := range data.Tabs {
+		if tab.TabID == "" || len(tab.Bubbles) == 0 {
+			continue
+		}
+
+		// Legacy bubbles carry no timestamps, so every message inherits the tab's last send time
+		timestamp := int64(tab.LastSendTime)
+		composer := &RawComposer{
+			ComposerID:    tab.TabID,
+			Name:          tab.ChatTitle,
+			CreatedAt:     timestamp,
+			LastUpdatedAt: timestamp,
+		}
+
+		for i, b := range tab.Bubbles {
+			bubbleID := b.ID
+			if bubbleID == "" {
+				bubbleID = fmt.Sprintf("%s-%d", tab.TabID, i)
+			}
+
+			text := b.Text
+			if text == "" {
+				text = b.RawText
+			}
+
+			msgType := 2
+			if b.Type == "user" {
+				msgType = 1
+			}
+
+			bubbles[bubbleID] = &RawBubble{
+				BubbleID:  bubbleID,
+				ChatID:    tab.TabID,
+				Text:      text,
+				Timestamp: timestamp,
+				Type:      msgType,
+				Provenance: &Provenance{
+					SourcePath: dbPath,
+					BlobKey:    workspaceChatDataKey,
+					Backend:    BackendWorkspaceStorage,
+				},
+			}
+			composer.FullConversationHeadersOnly = append(composer.FullConversationHeadersOnly, ConversationHeader{
+				BubbleID: bubbleID,
+				Type:     msgType,
+			})
+		}
+
+		if folder != "" {
+			contexts[tab.TabID] = append(contexts[tab.TabID], &MessageContext{
+				ComposerID:     tab.TabID,
+				ContextID:      "workspace",
+				ProjectLayouts: []string{folder},
+			})
+		}
+
+		composers = append(composers, composer)
+	}
+
+	return bubbles, composers, contexts, nil
+}
+
+// FindWorkspaceStateDBs returns the state.vscdb paths of all workspaces in workspaceStorage
+func (sp StoragePaths) FindWorkspaceStateDBs() ([]string, error) {
+	entries, err := os.ReadDir(sp.WorkspaceStorage)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return []string{}, fmt.Errorf("failed to read workspace storage directory: %w", err)
+	}
+
+	dbPaths := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dbPath := filepath.Join(sp.WorkspaceStorage, entry.Name(), "state.vscdb")
+		if _, err := os.Stat(dbPath); err == nil {
+			dbPaths = append(dbPaths, dbPath)
+		}
+	}
+
+	return dbPaths, nil
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iksnae/cursor-session/testutil"
+)
+
+const testChatData = `{"tabs":[
+	{"tabId":"tab1","chatTitle":"Fix flaky tests","lastSendTime":1700000000000,"bubbles":[
+		{"id":"b1","type":"user","text":"Why does this fail?"},
+		{"id":"b2","type":"ai","rawText":"Because of a race."}
+	]},
+	{"tabId":"tab2","bubbles":[]}
+]}`
+
+func createWorkspaceStateDB(t *testing.T, dbPath, chatData string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.Exec(`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)`); err != nil {
+		t.Fatalf("Failed to create ItemTable: %v", err)
+	}
+	if chatData != "" {
+		if _, err := db.Exec(`INSERT INTO ItemTable (key, value) VALUES (?, ?)`, workspaceChatDataKey, chatData); err != nil {
+			t.Fatalf("Failed to insert chat data: %v", err)
+		}
+	}
+}
+
+func TestParseWorkspaceChatData(t *testing.T) {
+	bubbles, composers, contexts, err := ParseWorkspaceChatData(testChatData, "/ws/state.vscdb", "/path/to/workspace")
+	if err != nil {
+		t.Fatalf("ParseWorkspaceChatData() error = %v", err)
+	}
+
+	// Empty tabs are skipped
+	if len(composers) != 1 {
+		t.Fatalf("ParseWorkspaceChatData() returned %d composers, want 1", len(composers))
+	}
+	composer := composers[0]
+	if composer.ComposerID != "tab1" || composer.Name != "Fix flaky tests" {
+		t.Errorf("Composer = %+v, want tab1 / Fix flaky tests", composer)
+	}
+	if len(composer.FullConversationHeadersOnly) != 2 {
+		t.Errorf("Composer has %d headers, want 2", len(composer.FullConversationHeadersOnly))
+	}
+
+	if len(bubbles) != 2 {
+		t.Fatalf("ParseWorkspaceChatData() returned %d bubbles, want 2", len(bubbles))
+	}
+	if bubbles["b1"].Type != 1 || bubbles["b2"].Type != 2 {
+		t.Errorf("Bubble types = %d/%d, want 1/2", bubbles["b1"].Type, bubbles["b2"].Type)
+	}
+	if bubbles["b2"].Text != "Because of a race." {
+		t.Errorf("Bubble b2 text = %q, want rawText fallback", bubbles["b2"].Text)
+	}
+	if bubbles["b1"].Provenance == nil || bubbles["b1"].Provenance.Backend != BackendWorkspaceStorage {
+		t.Errorf("Bubble b1 provenance = %+v, want workspaceStorage backend", bubbles["b1"].Provenance)
+	}
+
+	if len(contexts["tab1"]) != 1 || contexts["tab1"][0].ProjectLayouts[0] != "/path/to/workspace" {
+		t.Errorf("Contexts = %+v, want workspace folder layout for tab1", contexts)
+	}
+}
+
+func TestParseWorkspaceChatData_InvalidJSON(t *testing.T) {
+	_, _, _, err := ParseWorkspaceChatData("not json", "/ws/state.vscdb", "")
+	if err == nil {
+		t.Error("ParseWorkspaceChatData() should return error for invalid JSON")
+	}
+}
+
+func TestWorkspaceStorage_LoadFromDirectory(t *testing.T) {
+	tmpDir := testutil.CreateTempDir(t)
+	basePath := filepath.Join(tmpDir, "User")
+
+	withChats := testutil.CreateWorkspaceFixture(t, basePath, "workspace1")
+	createWorkspaceStateDB(t, filepath.Join(withChats, "state.vscdb"), testChatData)
+	withoutChats := testutil.CreateWorkspaceFixture(t, basePath, "workspace2")
+	createWorkspaceStateDB(t, filepath.Join(withoutChats, "state.vscdb"), "")
+	// Workspace directory without a database is ignored
+	withoutDB := testutil.CreateWorkspaceFixture(t, basePath, "workspace3")
+	// Give the other workspaces their own folders so only workspace1 matches the chat context
+	for dir, folder := range map[string]string{withoutChats: "/path/to/other", withoutDB: "/path/to/third"} {
+		data := fmt.Sprintf(`{"folder":%q}`, folder)
+		if err := os.WriteFile(filepath.Join(dir, "workspace.json"), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write workspace.json: %v", err)
+		}
+	}
+
+	paths := StoragePaths{
+		BasePath:         basePath,
+		WorkspaceStorage: filepath.Join(basePath, "workspaceStorage"),
+	}
+
+	dbPaths, err := paths.FindWorkspaceStateDBs()
+	if err != nil {
+		t.Fatalf("FindWorkspaceStateDBs() error = %v", err)
+	}
+	if len(dbPaths) != 2 {
+		t.Fatalf("FindWorkspaceStateDBs() returned %d paths, want 2", len(dbPaths))
+	}
+
+	backend, err := NewWorkspaceStorageBackend(paths)
+	if err != nil {
+		t.Fatalf("NewWorkspaceStorageBackend() error = %v", err)
+	}
+	composers, _ := backend.LoadComposers()
+	if len(composers) != 1 {
+		t.Errorf("LoadComposers() returned %d composers, want 1", len(composers))
+	}
+
+	contexts, _ := backend.LoadMessageContexts()
+	workspaces, _ := DetectWorkspaces(basePath)
+	if got := AssociateComposerWithWorkspace("tab1", contexts["tab1"], workspaces); got != "workspace1" {
+		t.Errorf("AssociateComposerWithWorkspace() = %q, want workspace1", got)
+	}
+}
+
+func TestWorkspaceStorage_ReadsOnce(t *testing.T) {
+	dbPath := filepath.Join(testutil.CreateWorkspaceFixture(t, filepath.Join(testutil.CreateTempDir(t), "User"), "workspace1"), "state.vscdb")
+	createWorkspaceStateDB(t, dbPath, testChatData)
+	backend := NewWorkspaceStorage([]string{dbPath})
+
+	composers, _ := backend.LoadComposers()
+	if len(composers) != 1 {
+		t.Fatalf("LoadComposers() returned %d composers, want 1", len(composers))
+	}
+
+	// Later loads reuse what the first one read, without opening the database again
+	if err := os.Remove(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	bubbles, _ := backend.LoadBubbles()
+	contexts, _ := backend.LoadMessageContexts()
+	if len(bubbles) == 0 || len(contexts["tab1"]) == 0 {
+		t.Errorf("later loads returned %d bubbles and %d contexts, want those read first", len(bubbles), len(contexts["tab1"]))
+	}
+}
+
+func TestFindWorkspaceStateDBs_MissingDirectory(t *testing.T) {
+	paths := StoragePaths{WorkspaceStorage: filepath.Join(os.TempDir(), "does-not-exist-workspaceStorage")}
+	dbPaths, err := paths.FindWorkspaceStateDBs()
+	if err != nil {
+		t.Errorf("FindWorkspaceStateDBs() error = %v, want nil", err)
+	}
+	if len(dbPaths) != 0 {
+		t.Errorf("FindWorkspaceStateDBs() returned %d paths, want 0", len(dbPaths))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/iksnae/cursor-session/testutil"
@@ -208,3 +209,53 @@ func TestAssociateComposerWithWorkspace(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspaceForHash(t *testing.T) {
+	// md5("/home/me/api")
+	const apiHash = "bf004745b1cd6704ab4afdf56449bd5b"
+	workspaces := map[string]*WorkspaceInfo{
+		"d41d8cd98f00b204e9800998ecf8427e": {Hash: "d41d8cd98f00b204e9800998ecf8427e", Path: "file:///home/me/web"},
+		"f00":                              {Hash: "f00", Path: "file:///home/me/api"},
+	}
+
+	tests := []struct {
+		hash string
+		want string
+	}{
+		{"d41d8cd98f00b204e9800998ecf8427e", "file:///home/me/web"},
+		{apiHash, "file:///home/me/api"},
+		{strings.ToUpper(apiHash), "file:///home/me/api"},
+		{"00000000000000000000000000000000", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := WorkspaceForHash(tt.hash, workspaces); got != tt.want {
+			t.Errorf("WorkspaceForHash(%q) = %q, want %q", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestConversationWorkspace_Hash(t *testing.T) {
+	workspaces := map[string]*WorkspaceInfo{"abc": {Hash: "abc", Path: "file:///home/me/api"}}
+	conv := &ReconstructedConversation{ComposerID: "agent1", WorkspaceHash: "abc"}
+	backend := NewAgentStorage(nil)
+
+	if got := conversationWorkspace(conv, backend, nil, workspaces, ""); got != "file:///home/me/api" {
+		t.Errorf("conversationWorkspace() = %q, want the workspace of the hash", got)
+	}
+	if got := conversationWorkspace(conv, backend, nil, workspaces, "/override"); got != "/override" {
+		t.Errorf("conversationWorkspace() with an override = %q", got)
+	}
+
+	session, err := NewNormalizer().NormalizeConversation(&ReconstructedConversation{
+		ComposerID:    "agent1",
+		WorkspaceHash: "abc",
+		Messages:      []ReconstructedMessage{{BubbleID: "b1", Type: 1, Text: "Hello"}},
+	}, "")
+	if err != nil {
+		t.Fatalf("NormalizeConversation() error = %v", err)
+	}
+	if session.WorkspaceHash != "abc" {
+		t.Errorf("WorkspaceHash = %q, want the conversation's", session.WorkspaceHash)
+	}
+}
@@ -0,0 +1,211 @@
+// Package cursorsession lets applications read Cursor chat sessions and export them with
+// the reconstruction and exporters behind the cursor-session command, customizing the
+// output with hooks instead of re-implementing either.
+package cursorsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+)
+
+type (
+	// Session is a normalized chat session
+	Session = internal.Session
+	// Message is a message in a session
+	Message = internal.Message
+	// Metadata describes a session
+	Metadata = internal.Metadata
+	// GitInfo is the repository state recorded with a session
+	GitInfo = internal.GitInfo
+	// MessageFilter selects messages by time range and actor
+	MessageFilter = internal.MessageFilter
+	// WalkOptions selects the storage to read and the sessions to visit
+	WalkOptions = internal.WalkOptions
+	// Exporter writes a session in one export format
+	Exporter = export.Exporter
+	// Hooks customize an export: see export.Hooks for each hook
+	Hooks = export.Hooks
+	// HealthOptions selects the storage RunHealthCheck inspects
+	HealthOptions = internal.HealthOptions
+	// AgentRunOptions controls how RunHealthCheck runs cursor-agent when TriggerAgent is set
+	AgentRunOptions = internal.AgentRunOptions
+	// HealthReport is the result of RunHealthCheck
+	HealthReport = internal.HealthReport
+	// HealthStatus is the overall outcome of a health check
+	HealthStatus = internal.HealthStatus
+	// Warning is a record skipped while loading sessions, as listed in HealthReport
+	Warning = internal.Warning
+	// StorageBackend is a source of raw session data: see RegisterBackend
+	StorageBackend = internal.StorageBackend
+	// BackendProvider opens storage backends for locations with a URI scheme
+	BackendProvider = internal.BackendProvider
+	// RawBubble is a message as a StorageBackend loads it
+	RawBubble = internal.RawBubble
+	// RawComposer is a conversation as a StorageBackend loads it, listing its bubbles
+	RawComposer = internal.RawComposer
+	// ConversationHeader references a bubble of a RawComposer
+	ConversationHeader = internal.ConversationHeader
+	// MessageContext is the context a StorageBackend recorded with a message
+	MessageContext = internal.MessageContext
+	// RawDump is the raw data of a storage saved by Extract. It is a StorageBackend.
+	RawDump = internal.RawDump
+	// ReconstructedConversation is a conversation rebuilt by Reconstruct, before it is
+	// normalized into a Session
+	ReconstructedConversation = internal.ReconstructedConversation
+	// NormalizerRules adjust how conversations are normalized into sessions: see
+	// SetNormalizerRules
+	NormalizerRules = internal.NormalizerRules
+	// PlaceholderRule decides what becomes of messages without text to extract
+	PlaceholderRule = internal.PlaceholderRule
+)
+
+// Outcomes of a health check
+const (
+	HealthOK         = internal.HealthOK
+	HealthNoSessions = internal.HealthNoSessions
+	HealthFailed     = internal.HealthFailed
+)
+
+var (
+	// SkipAll can be returned by a WalkSessions callback to stop the walk without an error
+	SkipAll = internal.SkipAll
+	// ErrSkipSession can be returned by a BeforeExport hook to leave a session out
+	ErrSkipSession = export.ErrSkipSession
+)
+
+// NewMessageFilter builds a filter from since and until times (RFC3339 or YYYY-MM-DD) and
+// an actor (user, assistant, tool, terminal, system or all), as accepted by the export command
+func NewMessageFilter(since, until, actor string) (MessageFilter, error) {
+	return internal.NewMessageFilter(since, until, actor)
+}
+
+// WalkSessions reconstructs the sessions in the storage selected by opts and calls fn with
+// each one. Returning SkipAll from fn stops the walk early; any other error stops it and
+// is returned.
+func WalkSessions(ctx context.Context, opts WalkOptions, fn func(*Session) error) error {
+	return internal.WalkSessions(ctx, opts, fn)
+}
+
+// NewExporter returns the exporter for format (jsonl, md, yaml, json, parquet, csv, mermaid,
+// dot, specstory-md or events-jsonl), running hooks around every session it exports
+func NewExporter(format string, hooks ...Hooks) (Exporter, error) {
+	exporter, err := export.NewExporter(format)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) == 0 {
+		return exporter, nil
+	}
+	return export.WithHooks(exporter, hooks...), nil
+}
+
+// ExportSessions writes every session selected by opts to session_<id>.<ext> in dir, the
+// layout of the export command, and returns the number of sessions written. Sessions
+// skipped by a hook are not counted. The first failure stops the export; an AfterExport
+// hook can return nil instead to carry on.
+func ExportSessions(ctx context.Context, opts WalkOptions, exporter Exporter, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	exported := 0
+	err := WalkSessions(ctx, opts, func(session *Session) error {
+		if err := export.WriteSessionFile(exporter, dir, session); err != nil {
+			if errors.Is(err, ErrSkipSession) {
+				return nil
+			}
+			return fmt.Errorf("failed to export session %s: %w", session.ID, err)
+		}
+		exported++
+		return nil
+	})
+	return exported, err
+}
+
+// RunHealthCheck checks that session data can be located and read, as the healthcheck
+// command does, and returns what each step found. Problems with the storage are
+// recorded in the report, whose Status sums them up; an error is returned only when the
+// check could not run.
+func RunHealthCheck(opts HealthOptions) (HealthReport, error) {
+	return internal.RunHealthCheck(opts)
+}
+
+// RegisterBackend registers a provider of storage backends for locations with its URI
+// scheme, such as s3://bucket/path. Storage locations with the scheme, in WalkOptions or
+// the --storage flag, are opened by the registered providers from the highest priority
+// down, using the first backend one of them opens. Register providers before reading
+// sessions, typically from an init function.
+func RegisterBackend(provider BackendProvider) error {
+	return internal.RegisterBackend(provider)
+}
+
+// OpenStorage opens the storage backend for a location as the --storage flag reads it: a
+// database file, a storage directory, a directory of exported sessions or a URI with a
+// registered scheme. Providers can use it to read a local copy of remote storage.
+func OpenStorage(location string) (StorageBackend, error) {
+	paths, err := internal.GetStoragePaths(location)
+	if err != nil {
+		return nil, err
+	}
+	return internal.NewStorageBackend(paths)
+}
+
+// ExtractStorageArchive extracts a storage archive (.gz, .tar, .tar.gz, .tgz or .zip), such
+// as a CI artifact, into a temporary directory. It returns the location to pass to
+// OpenStorage and a function removing the directory once the storage is no longer read.
+func ExtractStorageArchive(path string) (string, func() error, error) {
+	return internal.ExtractStorageArchive(path)
+}
+
+// Extract reads the raw data of the storage at location, as OpenStorage opens it, into a
+// dump that WriteRawDump saves. It is the first phase of Extract, Reconstruct and
+// Normalize, which together do what WalkSessions does, with results that can be saved
+// between phases so a later phase can be rerun without reading the databases.
+func Extract(location string) (*RawDump, error) {
+	paths, err := internal.GetStoragePaths(location)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := internal.NewStorageBackend(paths)
+	if err != nil {
+		return nil, err
+	}
+	return internal.ExtractRawDump(backend, []internal.StoragePaths{paths})
+}
+
+// WriteRawDump saves a dump to path as JSON
+func WriteRawDump(path string, dump *RawDump) error {
+	return internal.WriteRawDump(path, dump)
+}
+
+// ReadRawDump reads a dump saved by WriteRawDump
+func ReadRawDump(path string) (*RawDump, error) {
+	return internal.ReadRawDump(path)
+}
+
+// Reconstruct rebuilds the conversations of a backend, such as a RawDump
+func Reconstruct(backend StorageBackend) ([]*ReconstructedConversation, error) {
+	return internal.ReconstructConversations(backend)
+}
+
+// Normalize turns the conversations reconstructed from a dump into deduplicated sessions
+// associated with the workspaces the dump recorded
+func Normalize(dump *RawDump, conversations []*ReconstructedConversation) []*Session {
+	return dump.NormalizeSessions(conversations, "")
+}
+
+// LoadNormalizerRules reads a rules file in the YAML format of the --rules flag
+func LoadNormalizerRules(path string) (*NormalizerRules, error) {
+	return internal.LoadNormalizerRules(path)
+}
+
+// SetNormalizerRules sets the rules that loading, reconstructing and normalizing sessions
+// follow from then on, process-wide; nil restores the built-in behavior
+func SetNormalizerRules(rules *NormalizerRules) {
+	internal.SetNormalizerRules(rules)
+}
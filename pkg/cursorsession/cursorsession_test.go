@@ -0,0 +1,184 @@
+package cursorsession
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iksnae/cursor-session/internal"
+	"github.com/iksnae/cursor-session/internal/export"
+)
+
+// writeArchive exports sessions as JSON into a temporary directory, which WalkSessions
+// reads as a storage location
+func writeArchive(t *testing.T, sessions ...*Session) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, session := range sessions {
+		if err := export.WriteSessionFile(&export.JSONExporter{}, dir, session); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestWalkSessions(t *testing.T) {
+	first := internal.CreateTestSessionWithMessages("first", []Message{{Actor: "user", Content: "fix the bug"}})
+	first.Workspace = "/work/api"
+	second := internal.CreateTestSessionWithMessages("second", []Message{{Actor: "assistant", Content: "added tests"}})
+	second.Workspace = "/work/web"
+	dir := writeArchive(t, first, second)
+
+	var visited []string
+	err := WalkSessions(context.Background(), WalkOptions{StoragePaths: []string{dir}}, func(s *Session) error {
+		visited = append(visited, s.ID)
+		return nil
+	})
+	if err != nil || len(visited) != 2 {
+		t.Fatalf("WalkSessions() visited %v, error = %v, want both sessions", visited, err)
+	}
+
+	filter, err := NewMessageFilter("", "", "user")
+	if err != nil {
+		t.Fatalf("NewMessageFilter() error = %v", err)
+	}
+	visited = nil
+	err = WalkSessions(context.Background(), WalkOptions{StoragePaths: []string{dir}, Filter: filter, SkipEmpty: true}, func(s *Session) error {
+		visited = append(visited, s.ID)
+		return nil
+	})
+	if err != nil || len(visited) != 1 || visited[0] != "first" {
+		t.Errorf("WalkSessions() with a user filter visited %v, error = %v, want [first]", visited, err)
+	}
+
+	visited = nil
+	err = WalkSessions(context.Background(), WalkOptions{StoragePaths: []string{dir}, Workspace: "/work/web"}, func(s *Session) error {
+		visited = append(visited, s.ID)
+		return SkipAll
+	})
+	if err != nil || len(visited) != 1 || visited[0] != "second" {
+		t.Errorf("WalkSessions() with a workspace visited %v, error = %v, want [second]", visited, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WalkSessions(ctx, WalkOptions{StoragePaths: []string{dir}}, func(*Session) error { return nil }); err != context.Canceled {
+		t.Errorf("WalkSessions() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestExportSessions(t *testing.T) {
+	dir := writeArchive(t,
+		internal.CreateTestSessionWithMessages("keep", []Message{{Actor: "user", Content: "fix the bug"}}),
+		internal.CreateTestSessionWithMessages("drop", []Message{{Actor: "user", Content: "private notes"}}),
+	)
+	out := t.TempDir()
+
+	exporter, err := NewExporter("md", Hooks{
+		BeforeExport: func(s *Session) (*Session, error) {
+			if s.ID == "drop" {
+				return nil, ErrSkipSession
+			}
+			annotated := *s
+			annotated.Metadata.Name = "Acme: " + s.Metadata.Name
+			return &annotated, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+
+	exported, err := ExportSessions(context.Background(), WalkOptions{StoragePaths: []string{dir}}, exporter, out)
+	if err != nil || exported != 1 {
+		t.Fatalf("ExportSessions() = %d, %v, want 1 session", exported, err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "session_keep.md"))
+	if err != nil || !strings.Contains(string(data), "Acme: ") {
+		t.Errorf("session_keep.md = %q, %v, want the name set by the hook", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "session_drop.md")); !os.IsNotExist(err) {
+		t.Errorf("skipped session was exported: %v", err)
+	}
+
+	if _, err := NewExporter("xml"); err == nil {
+		t.Error("NewExporter(xml) succeeded, want an error")
+	}
+}
+
+func TestRunHealthCheck(t *testing.T) {
+	dir := writeArchive(t, internal.CreateTestSessionWithMessages("first", []Message{{Actor: "user", Content: "fix the bug"}}))
+
+	report, err := RunHealthCheck(HealthOptions{StoragePath: dir})
+	if err != nil {
+		t.Fatalf("RunHealthCheck() error = %v", err)
+	}
+	if report.Status != HealthOK || report.Backend != internal.BackendExportDir {
+		t.Errorf("RunHealthCheck() = %s from %s, want ok from exported sessions", report.Status, report.Backend)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0].ID != "first" {
+		t.Errorf("RunHealthCheck() sessions = %+v, want first", report.Sessions)
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	t.Cleanup(func() { internal.UnregisterBackends("synced") })
+
+	// A provider reading a local copy of the remote storage
+	dir := writeArchive(t, internal.CreateTestSessionWithMessages("remote", []Message{{Actor: "user", Content: "from the bucket"}}))
+	err := RegisterBackend(BackendProvider{
+		Name:   "synced copy",
+		Scheme: "synced",
+		Open: func(location string) (StorageBackend, error) {
+			return OpenStorage(dir)
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterBackend() error = %v", err)
+	}
+
+	var visited []string
+	err = WalkSessions(context.Background(), WalkOptions{StoragePaths: []string{"synced://bucket/sessions"}}, func(s *Session) error {
+		visited = append(visited, s.ID)
+		return nil
+	})
+	if err != nil || len(visited) != 1 || visited[0] != "remote" {
+		t.Errorf("WalkSessions() over a registered backend visited %v, error = %v, want [remote]", visited, err)
+	}
+
+	if _, err := OpenStorage("unknown://bucket"); err == nil {
+		t.Error("OpenStorage() with an unregistered scheme should fail")
+	}
+}
+
+func TestExtractReconstructNormalize(t *testing.T) {
+	session := internal.CreateTestSessionWithMessages("phased", []Message{{Actor: "user", Content: "fix the bug"}})
+	session.Workspace = "/work/api"
+	dir := writeArchive(t, session)
+
+	dump, err := Extract(dir)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "raw.json")
+	if err := WriteRawDump(path, dump); err != nil {
+		t.Fatalf("WriteRawDump() error = %v", err)
+	}
+	// The storage is no longer needed once the dump is saved
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if dump, err = ReadRawDump(path); err != nil {
+		t.Fatalf("ReadRawDump() error = %v", err)
+	}
+
+	conversations, err := Reconstruct(dump)
+	if err != nil {
+		t.Fatalf("Reconstruct() error = %v", err)
+	}
+	sessions := Normalize(dump, conversations)
+	if len(sessions) != 1 || sessions[0].ID != "phased" || sessions[0].Workspace != "/work/api" || len(sessions[0].Messages) != 1 {
+		t.Errorf("Normalize() = %+v, want the phased session in /work/api", sessions)
+	}
+}
@@ -0,0 +1,476 @@
+package testutil
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Storage backends GenerateFixture can write
+const (
+	FixtureDesktop = "desktop"
+	FixtureAgent   = "agent"
+	FixtureAll     = "all"
+)
+
+// fixtureEpoch is when the first generated session starts, so fixtures are the same
+// whenever they are generated
+var fixtureEpoch = time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+// fixtureProjects are the workspaces generated sessions are spread over
+var fixtureProjects = []string{"api", "web", "cli"}
+
+// FixtureOptions controls the storage GenerateFixture writes
+type FixtureOptions struct {
+	// Sessions is how many sessions to write, split between the backends
+	Sessions int
+	// Messages is how many messages each session holds
+	Messages int
+	// Backend is FixtureDesktop, FixtureAgent or FixtureAll
+	Backend string
+	// Seed seeds the generator; the same seed writes the same sessions
+	Seed uint64
+}
+
+// FixtureResult is where GenerateFixture wrote the storage, and what it holds
+type FixtureResult struct {
+	// GlobalStorage is the globalStorage directory holding the desktop state.vscdb, when
+	// desktop sessions were written
+	GlobalStorage string `json:"global_storage,omitempty"`
+	// AgentStorage is the chats directory holding the cursor-agent store.db files, when
+	// agent sessions were written
+	AgentStorage    string `json:"agent_storage,omitempty"`
+	DesktopSessions int    `json:"desktop_sessions"`
+	AgentSessions   int    `json:"agent_sessions"`
+	Messages        int    `json:"messages"`
+}
+
+// Sessions returns how many sessions were written
+func (r *FixtureResult) Sessions() int {
+	return r.DesktopSessions + r.AgentSessions
+}
+
+// GenerateFixture writes synthetic Cursor storage under dir, laid out as Cursor lays it
+// out: User/globalStorage/state.vscdb and User/workspaceStorage/<hash> for the desktop
+// app, and chats/<hash>/<session id>/store.db for cursor-agent. The databases hold the
+// encodings the parsers have to handle: JSON and binary values, rich text, thinking and
+// tool calls in desktop bubbles; JSON, protobuf-framed, record-prefixed and text$uuid
+// messages and hex-encoded metadata in store.db files. Every database is in WAL mode;
+// the global database and every other store.db have their last rows in the -wal file
+// only, as when Cursor is still running.
+func GenerateFixture(dir string, opts FixtureOptions) (*FixtureResult, error) {
+	if opts.Sessions < 1 {
+		return nil, fmt.Errorf("fixture needs at least one session, got %d", opts.Sessions)
+	}
+	if opts.Messages < 1 {
+		return nil, fmt.Errorf("fixture needs at least one message per session, got %d", opts.Messages)
+	}
+	gen := &fixtureGenerator{rng: rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)), messages: opts.Messages}
+	result := &FixtureResult{}
+	switch opts.Backend {
+	case FixtureDesktop:
+		result.DesktopSessions = opts.Sessions
+	case FixtureAgent:
+		result.AgentSessions = opts.Sessions
+	case FixtureAll, "":
+		result.DesktopSessions = (opts.Sessions + 1) / 2
+		result.AgentSessions = opts.Sessions / 2
+	default:
+		return nil, fmt.Errorf("unsupported fixture backend %q (expected %s, %s or %s)", opts.Backend, FixtureDesktop, FixtureAgent, FixtureAll)
+	}
+
+	if result.DesktopSessions > 0 {
+		result.GlobalStorage = filepath.Join(dir, "User", "globalStorage")
+		if err := gen.writeDesktop(filepath.Join(dir, "User"), result.DesktopSessions); err != nil {
+			return nil, err
+		}
+	}
+	if result.AgentSessions > 0 {
+		result.AgentStorage = filepath.Join(dir, "chats")
+		if err := gen.writeAgent(result.AgentStorage, result.DesktopSessions, result.AgentSessions); err != nil {
+			return nil, err
+		}
+	}
+	result.Messages = result.Sessions() * opts.Messages
+	return result, nil
+}
+
+// fixtureGenerator writes the sessions of a fixture from one random source
+type fixtureGenerator struct {
+	rng      *rand.Rand
+	messages int
+}
+
+// uuid returns a random UUID starting with prefix, which keeps the IDs of a session's
+// messages in order
+func (g *fixtureGenerator) uuid(prefix uint32) string {
+	return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", prefix, g.rng.Uint32N(1<<16), g.rng.Uint32N(1<<12), 0x8000|g.rng.Uint32N(1<<14), g.rng.Uint64N(1<<48))
+}
+
+// sessionStart returns when session n starts: a few hours after the previous one
+func sessionStart(n int) time.Time {
+	return fixtureEpoch.Add(time.Duration(n) * 5 * time.Hour)
+}
+
+// fixtureTopics are what generated sessions are about
+var fixtureTopics = []string{
+	"the flaky integration test in the payment service",
+	"pagination of the search endpoint",
+	"the memory leak in the websocket handler",
+	"migrating the config loader to YAML",
+	"rate limiting on the public API",
+	"the race in the cache invalidation",
+	"dark mode for the settings page",
+	"retries for the webhook dispatcher",
+}
+
+// prompt returns the text of user message turn of a session
+func (g *fixtureGenerator) prompt(session, turn int) string {
+	topic := fixtureTopics[(session+turn)%len(fixtureTopics)]
+	if turn == 0 {
+		return fmt.Sprintf("Session %d: can you help me with %s? It started failing after the last deploy.", session+1, topic)
+	}
+	return fmt.Sprintf("Thanks. Now look at %s, step %d of session %d. %s", topic, turn, session+1, g.filler(1+g.rng.IntN(3)))
+}
+
+// reply returns the text of an assistant message, with a code block every other turn
+func (g *fixtureGenerator) reply(session, turn int) string {
+	text := fmt.Sprintf("Here is what I found for step %d of session %d. %s", turn, session+1, g.filler(2+g.rng.IntN(6)))
+	if turn%2 == 0 {
+		text += fmt.Sprintf("\n\n```go\nfunc step%d() error {\n\treturn retry(%d, time.Second)\n}\n```", turn, 1+g.rng.IntN(5))
+	}
+	return text
+}
+
+// fixtureSentences pad messages to realistic lengths
+var fixtureSentences = []string{
+	"The handler reads the request body twice, so the second read returns nothing.",
+	"I added a test that reproduces it with a slow client.",
+	"The timeout is shorter than the retry backoff, which explains the intermittent failures.",
+	"Moving the lock out of the loop keeps the critical section small.",
+	"The query plan shows a sequential scan, so an index on created_at should help.",
+	"Both callers expect the error to be wrapped, so I kept fmt.Errorf with %w.",
+}
+
+// filler returns n sentences of padding
+func (g *fixtureGenerator) filler(n int) string {
+	sentences := make([]string, n)
+	for i := range sentences {
+		sentences[i] = fixtureSentences[g.rng.IntN(len(fixtureSentences))]
+	}
+	return strings.Join(sentences, " ")
+}
+
+// fixtureRow is a statement writing one row of a fixture database
+type fixtureRow struct {
+	query string
+	args  []interface{}
+}
+
+// writeDesktop writes the global and workspace databases of the desktop app under user,
+// the User directory, with sessions 0 to count-1
+func (g *fixtureGenerator) writeDesktop(user string, count int) error {
+	const insertKV = "INSERT INTO cursorDiskKV (key, value) VALUES (?, ?)"
+	var rows []fixtureRow
+	workspaceComposers := make(map[string][]map[string]interface{})
+	for n := 0; n < count; n++ {
+		composerID := g.uuid(uint32(n))
+		start := sessionStart(n)
+		var headers []map[string]interface{}
+		for i := 0; i < g.messages; i++ {
+			bubbleID := g.uuid(uint32(i))
+			bubble := map[string]interface{}{
+				"bubbleId":  bubbleID,
+				"timestamp": start.Add(time.Duration(i) * 45 * time.Second).UnixMilli(),
+			}
+			turn := i / 2
+			if i%2 == 0 {
+				bubble["type"] = 1
+				bubble["text"] = g.prompt(n, turn)
+			} else {
+				bubble["type"] = 2
+				text := g.reply(n, turn)
+				switch turn % 4 {
+				case 1:
+					// Text kept only as rich text, as older builds do
+					bubble["richText"] = richText(text)
+				case 2:
+					bubble["text"] = text
+					bubble["thinking"] = map[string]string{"text": "Let me check how " + fixtureTopics[turn%len(fixtureTopics)] + " is handled first."}
+				case 3:
+					bubble["text"] = text
+					bubble["toolFormerData"] = map[string]string{
+						"name":    "run_terminal_cmd",
+						"rawArgs": fmt.Sprintf(`{"command":"go test ./... -run TestStep%d"}`, turn),
+						"result":  "ok  \texample.com/service\t0.42s",
+					}
+				default:
+					bubble["text"] = text
+					bubble["codeBlocks"] = []map[string]string{{"languageId": "go", "content": fmt.Sprintf("func step%d() error { return nil }", turn)}}
+				}
+			}
+			headers = append(headers, map[string]interface{}{"bubbleId": bubbleID, "type": bubble["type"]})
+			value, err := json.Marshal(bubble)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, fixtureRow{insertKV, []interface{}{"bubbleId:" + composerID + ":" + bubbleID, g.encodeValue(value)}})
+		}
+
+		updated := start.Add(time.Duration(g.messages) * 45 * time.Second)
+		composer := map[string]interface{}{
+			"composerId":                  composerID,
+			"name":                        fmt.Sprintf("Session %d: %s", n+1, fixtureTopics[n%len(fixtureTopics)]),
+			"createdAt":                   start.UnixMilli(),
+			"lastUpdatedAt":               updated.UnixMilli(),
+			"fullConversationHeadersOnly": headers,
+		}
+		value, err := json.Marshal(composer)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, fixtureRow{insertKV, []interface{}{"composerData:" + composerID, g.encodeValue(value)}})
+
+		project := fixtureProjects[n%len(fixtureProjects)]
+		workspaceComposers[project] = append(workspaceComposers[project], map[string]interface{}{
+			"composerId":    composerID,
+			"createdAt":     start.UnixMilli(),
+			"lastUpdatedAt": updated.UnixMilli(),
+		})
+	}
+
+	schema := []string{
+		"CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+		"CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+	}
+	// The last session is only in the -wal
+	pending := rows[len(rows)-g.messages-1:]
+	if err := writeWALDatabase(filepath.Join(user, "globalStorage", "state.vscdb"), schema, rows[:len(rows)-len(pending)], pending); err != nil {
+		return err
+	}
+
+	for _, project := range fixtureProjects {
+		composers, ok := workspaceComposers[project]
+		if !ok {
+			continue
+		}
+		folder := "file:///home/dev/src/" + project
+		dir := filepath.Join(user, "workspaceStorage", fixtureHash(folder))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+		workspaceJSON, _ := json.Marshal(map[string]string{"folder": folder})
+		if err := os.WriteFile(filepath.Join(dir, "workspace.json"), workspaceJSON, 0644); err != nil {
+			return fmt.Errorf("failed to write workspace.json: %w", err)
+		}
+		composerData, _ := json.Marshal(map[string]interface{}{"allComposers": composers})
+		item := fixtureRow{"INSERT INTO ItemTable (key, value) VALUES (?, ?)", []interface{}{"composer.composerData", string(composerData)}}
+		if err := writeWALDatabase(filepath.Join(dir, "state.vscdb"), schema[:1], []fixtureRow{item}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeValue returns a desktop value as text, or every third one as a binary blob, as
+// Cursor stores both
+func (g *fixtureGenerator) encodeValue(value []byte) interface{} {
+	if g.rng.IntN(3) == 0 {
+		return value
+	}
+	return string(value)
+}
+
+// richText returns text as the Lexical rich text document Cursor records
+func richText(text string) string {
+	var paragraphs []interface{}
+	for _, line := range strings.Split(text, "\n") {
+		paragraphs = append(paragraphs, map[string]interface{}{
+			"type":     "paragraph",
+			"children": []interface{}{map[string]interface{}{"type": "text", "text": line}},
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"root": map[string]interface{}{"type": "root", "children": paragraphs}})
+	return string(data)
+}
+
+// writeAgent writes a store.db for each of count cursor-agent sessions under chats,
+// numbering them from first
+func (g *fixtureGenerator) writeAgent(chats string, first, count int) error {
+	schema := []string{
+		"CREATE TABLE blobs (id TEXT PRIMARY KEY, data BLOB)",
+		"CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT)",
+	}
+	const insertBlob = "INSERT INTO blobs (id, data) VALUES (?, ?)"
+	for n := first; n < first+count; n++ {
+		sessionID := g.uuid(uint32(n))
+		start := sessionStart(n)
+		meta, _ := json.Marshal(map[string]interface{}{
+			"agentId":   sessionID,
+			"name":      fmt.Sprintf("Session %d: %s", n+1, fixtureTopics[n%len(fixtureTopics)]),
+			"createdAt": start.UnixMilli(),
+			"mode":      "default",
+		})
+		rows := []fixtureRow{{"INSERT INTO meta (key, value) VALUES (?, ?)", []interface{}{"0", hex.EncodeToString(meta)}}}
+
+		// The composer lists the messages by the bubble IDs the reader gives them: the
+		// UUID of a text$uuid message, and the message ID and start of the blob key of
+		// the others
+		var headers []map[string]interface{}
+		for i := 0; i < g.messages; i++ {
+			turn := i / 2
+			id := fmt.Sprintf("%08x", i)
+			var value []byte
+			bubbleID := ""
+			if i%2 == 0 {
+				text := g.prompt(n, turn)
+				if turn%3 == 2 {
+					// The plain text$uuid form of user messages
+					bubbleID = g.uuid(uint32(i))
+					value = []byte(text + "$" + bubbleID)
+				} else {
+					value = agentMessage(id, "user", []map[string]string{{"type": "text", "text": text}})
+				}
+			} else {
+				content := []map[string]string{{"type": "text", "text": g.reply(n, turn)}}
+				switch turn % 3 {
+				case 0:
+					content = append([]map[string]string{{"type": "reasoning", "text": "Reading the code around step " + id + " first."}}, content...)
+					value = agentMessage(id, "assistant", content)
+				case 1:
+					value = protobufField(1, agentMessage(id, "assistant", content))
+				default:
+					// A binary record header before the JSON
+					value = append([]byte{0x00, 0x01, 0x8f, 0x02}, agentMessage(id, "assistant", content)...)
+				}
+			}
+			key := fixtureHash(string(value)) + fixtureHash(id)
+			if bubbleID == "" {
+				bubbleID = id + "-" + key[:8]
+			}
+			headers = append(headers, map[string]interface{}{"bubbleId": bubbleID, "type": 1 + i%2})
+			rows = append(rows, fixtureRow{insertBlob, []interface{}{key, value}})
+		}
+		composer, _ := json.Marshal(map[string]interface{}{
+			"composerId":                  sessionID,
+			"createdAt":                   start.UnixMilli(),
+			"fullConversationHeadersOnly": headers,
+		})
+		rows = append(rows[:1], append([]fixtureRow{{insertBlob, []interface{}{fixtureHash(sessionID) + fixtureHash("composer"), composer}}}, rows[1:]...)...)
+
+		project := fixtureProjects[n%len(fixtureProjects)]
+		path := filepath.Join(chats, fixtureHash("/home/dev/src/"+project), sessionID, "store.db")
+		// Every other session has its last message only in the -wal
+		pending := 0
+		if n%2 == 1 {
+			pending = 1
+		}
+		if err := writeWALDatabase(path, schema, rows[:len(rows)-pending], rows[len(rows)-pending:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// agentMessage returns a cursor-agent message as JSON
+func agentMessage(id, role string, content []map[string]string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"id": id, "role": role, "content": content})
+	return data
+}
+
+// protobufField returns data framed as the length-delimited protobuf field number field
+func protobufField(field int, data []byte) []byte {
+	framed := binary.AppendUvarint(nil, uint64(field<<3|2))
+	framed = binary.AppendUvarint(framed, uint64(len(data)))
+	return append(framed, data...)
+}
+
+// fixtureHash returns the first 32 hex digits of the SHA-256 of s, as the directory and
+// blob key hashes Cursor uses look
+func fixtureHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+// writeWALDatabase writes a database in WAL mode with the tables of schema. The rows of
+// committed are checkpointed into the database file, while those of pending are left in
+// its -wal file, as in a database another process is still writing to.
+func writeWALDatabase(path string, schema []string, committed, pending []fixtureRow) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to replace %s: %w", path+suffix, err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range append([]string{"PRAGMA journal_mode=WAL", "PRAGMA wal_autocheckpoint=0"}, schema...) {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to set up %s: %w", path, err)
+		}
+	}
+	if err := insertFixtureRows(db, committed); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", path, err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := insertFixtureRows(db, pending); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// Closing the last connection checkpoints the -wal into the database and removes it,
+	// so the files are kept as they are while it is open and put back once it is closed
+	files := make(map[string][]byte)
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path+suffix, err)
+		}
+		files[path+suffix] = data
+	}
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// insertFixtureRows writes rows in one transaction
+func insertFixtureRows(db *sql.DB, rows []fixtureRow) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := tx.Exec(row.query, row.args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
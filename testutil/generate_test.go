@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateFixture(t *testing.T) {
+	dir := t.TempDir()
+	result, err := GenerateFixture(dir, FixtureOptions{Sessions: 3, Messages: 4, Seed: 7})
+	if err != nil {
+		t.Fatalf("GenerateFixture() error = %v", err)
+	}
+	if result.DesktopSessions != 2 || result.AgentSessions != 1 || result.Messages != 12 {
+		t.Errorf("GenerateFixture() = %+v, want 2 desktop and 1 agent session(s) with 12 message(s)", result)
+	}
+	if result.GlobalStorage != filepath.Join(dir, "User", "globalStorage") || result.AgentStorage != filepath.Join(dir, "chats") {
+		t.Errorf("GenerateFixture() wrote the storage to %s and %s", result.GlobalStorage, result.AgentStorage)
+	}
+	storeDBs, err := filepath.Glob(filepath.Join(dir, "chats", "*", "*", "store.db"))
+	if err != nil || len(storeDBs) != 1 {
+		t.Errorf("GenerateFixture() wrote store.db files %v, want 1", storeDBs)
+	}
+	workspaces, _ := filepath.Glob(filepath.Join(dir, "User", "workspaceStorage", "*", "workspace.json"))
+	if len(workspaces) != 2 {
+		t.Errorf("GenerateFixture() wrote %d workspace(s), want 2", len(workspaces))
+	}
+
+	for _, opts := range []FixtureOptions{
+		{Sessions: 0, Messages: 1},
+		{Sessions: 1, Messages: 0},
+		{Sessions: 1, Messages: 1, Backend: "web"},
+	} {
+		if _, err := GenerateFixture(t.TempDir(), opts); err == nil {
+			t.Errorf("GenerateFixture(%+v) should fail", opts)
+		}
+	}
+}
@@ -14,6 +14,9 @@ func CreateInMemoryDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatalf("Failed to create in-memory database: %v", err)
 	}
+	// Every connection to :memory: opens a database of its own, so backends loading in
+	// parallel must share the one holding the table
+	db.SetMaxOpenConns(1)
 
 	// Create cursorDiskKV table
 	createTableSQL := `